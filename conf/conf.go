@@ -52,6 +52,8 @@ const (
 	defaultAncestorLimit         = 25
 	defaultMempoolExpiry         = 336
 	defaultMaxMempoolSize        = 300
+	// blocks only nodes only keep their own transactions in the mempool
+	defaultBlocksOnlyMaxMempoolSize = 5
 )
 
 var (
@@ -147,6 +149,17 @@ func InitConfig(args []string) *Configuration {
 	config.RPC.RPCKey = filepath.Join(defaultDataDir, "rpc.key")
 	config.RPC.RPCCert = filepath.Join(defaultDataDir, "rpc.cert")
 	config.P2PNet.Discover = discover == 1
+	if len(opts.ReindexIndex) > 0 {
+		config.Chain.ReindexIndex = opts.ReindexIndex
+	}
+	if opts.BlocksOnly {
+		config.P2PNet.BlocksOnly = true
+	}
+	config.Log.Module = append(config.Log.Module, opts.Debug...)
+	// unless configured otherwise, shrink the mempool of blocks only nodes
+	if config.P2PNet.BlocksOnly && config.Mempool.MaxPoolSize == defaultMaxMempoolSize*1000000 {
+		config.Mempool.MaxPoolSize = defaultBlocksOnlyMaxMempoolSize * 1000000
+	}
 	return config
 }
 
@@ -166,12 +179,25 @@ type Configuration struct {
 		RPCPass              string   // Password for RPC connections
 		RPCLimitUser         string   //Username for limited RPC connections
 		RPCLimitPass         string   //Password for limited RPC connections
+		RPCAuth              []string //Users allowed to connect, as <user>:<salt>$<hash> with hash the hex HMAC-SHA256 of the password keyed with salt
+		RPCCookieFile        string   //Authentication cookie file, generated when no RPCPass is set, relative to the data dir (default: .cookie)
 		RPCCert              string   `default:""` //File containing the certificate file
 		RPCKey               string   //File containing the certificate key
 		RPCMaxClients        int      //Max number of RPC clients for standard connections
-		RPCMaxWebsockets     int      //Max number of RPC websocket connections
+		RPCMaxWebsockets     int      `default:"25"` //Max number of RPC websocket connections, served on /ws
 		RPCMaxConcurrentReqs int      //Max number of concurrent RPC requests that may be processed concurrently
 		RPCQuirks            bool     //Mirror some JSON-RPC quirks of Bitcoin Core -- NOTE: Discouraged unless interoperability issues need to be worked around
+		RPCStrictVersion     bool     //Reject requests whose jsonrpc field is not "1.0" or "2.0" instead of treating them as 1.0
+		RPCThreads           int      `default:"4"`  //Number of threads executing RPC requests
+		RPCWorkQueue         int      `default:"16"` //Max number of RPC requests waiting for a thread before new ones are refused
+		RPCServerTimeout     int      `default:"30"` //Seconds an idle keep-alive RPC connection is kept open
+		RPCAllowRollback     bool     //Enable the rollbackchain command, which is always refused on mainnet
+		RPCPlainListeners    []string //Interfaces/ports to listen for RPC connections without TLS, for a reverse proxy terminating TLS
+		RPCAllowIPs          []string //IPs or subnets allowed to use the RPC server, all of them when empty
+		RPCTrustedProxies    []string //IPs or subnets of reverse proxies whose X-Forwarded-For header gives the client IP
+		RPCCorsDomains       []string //Origins of the web pages allowed to call the RPC server from a browser, "*" for any without credentials
+		RPCRateLimit         int      //Max number of requests per second of every client IP, no limit when 0
+		RPCRest              bool     //Serve the public REST interface under /rest/ on the RPC listeners, without authentication
 	}
 	Log struct {
 		Level    string   //description:"Define level of log,include trace, debug, info, warn, error"
@@ -179,13 +205,14 @@ type Configuration struct {
 		FileName string   // the name of log file
 	}
 	Mempool struct {
-		MinFeeRate           int64 //
-		LimitAncestorCount   int   // Default for -limitancestorcount, max number of in-mempool ancestors
-		LimitAncestorSize    int   // Default for -limitancestorsize, maximum kilobytes of tx + all in-mempool ancestors
-		LimitDescendantCount int   // Default for -limitdescendantcount, max number of in-mempool descendants
-		LimitDescendantSize  int   // Default for -limitdescendantsize, maximum kilobytes of in-mempool descendants
-		MaxPoolSize          int64 `default:"300000000"` // Default for MaxPoolSize, maximum megabytes of mempool memory usage
-		MaxPoolExpiry        int   // Default for -mempoolexpiry, expiration time for mempool transactions in hours
+		MinFeeRate           int64  //
+		LimitAncestorCount   int    // Default for -limitancestorcount, max number of in-mempool ancestors
+		LimitAncestorSize    int    // Default for -limitancestorsize, maximum kilobytes of tx + all in-mempool ancestors
+		LimitDescendantCount int    // Default for -limitdescendantcount, max number of in-mempool descendants
+		LimitDescendantSize  int    // Default for -limitdescendantsize, maximum kilobytes of in-mempool descendants
+		MaxPoolSize          int64  `default:"300000000"` // Default for MaxPoolSize, maximum megabytes of mempool memory usage
+		MaxPoolExpiry        int    // Default for -mempoolexpiry, expiration time for mempool transactions in hours
+		WarmFrom             string // URL of the raw transactions stream of a trusted node to warm the mempool from at startup
 	}
 	P2PNet struct {
 		ListenAddrs         []string `validate:"require" default:"1234"`
@@ -196,7 +223,7 @@ type Configuration struct {
 		BanThreshold        uint32
 		SimNet              bool          `default:"false"`
 		DisableListen       bool          `default:"true"`
-		BlocksOnly          bool          `default:"true"` //Do not accept transactions from remote peers.
+		BlocksOnly          bool          `default:"false"` //Do not accept transactions from remote peers.
 		BanDuration         time.Duration // How long to ban misbehaving peers
 		Proxy               string        // Connect via SOCKS5 proxy (eg. 127.0.0.1:9050)
 		UserAgentComments   []string      // Comment to add to the user agent -- See BIP 14 for more information.
@@ -209,6 +236,13 @@ type Configuration struct {
 		ExternalIPs         []string // Add an ip to the list of local addresses we claim to listen on to peers
 		//AddCheckpoints      []model.Checkpoint
 		Discover bool // Is our peer's addrLocal potentially useful as an external IP source
+		// MaxUploadTarget tries to keep the traffic sent under this many MiB
+		// per 24h, by not serving blocks older than a week to non-whitelisted
+		// peers once it is close. 0 means no limit.
+		MaxUploadTarget uint64
+		// MempoolSync lets whitelisted peers reconcile their mempool with
+		// this node, see the syncmempool RPC.
+		MempoolSync bool `default:"false"`
 	}
 	AddrMgr struct {
 		SimNet       bool
@@ -221,22 +255,53 @@ type Configuration struct {
 	Script struct {
 		AcceptDataCarrier   bool `default:"true"`
 		MaxDatacarrierBytes uint `default:"223"`
-		IsBareMultiSigStd   bool `default:"true"`
+		// LargeDataCarrier opts in to accepting and relaying data-carrier
+		// outputs of up to MaxLargeDatacarrierBytes, exchanged only with
+		// peers which opted in too
+		LargeDataCarrier         bool `default:"false"`
+		MaxLargeDatacarrierBytes uint `default:"100000"`
+		IsBareMultiSigStd        bool `default:"true"`
 		//use promiscuousMempoolFlags to make more or less check of script, the type of value is uint
 		PromiscuousMempoolFlags string
+		// MempoolScriptWorkers is the number of workers checking the
+		// scripts of the transactions entering the mempool, apart from
+		// block validation; 0 uses half of the CPUs
+		MempoolScriptWorkers int `default:"0"`
+		// MempoolScriptTimeout is the number of seconds the script checks
+		// of a transaction entering the mempool may take, waiting for a
+		// worker included; 0 disables the timeout
+		MempoolScriptTimeout int `default:"10"`
 	}
 	TxOut struct {
 		DustRelayFee int64 `default:"83"`
 	}
 	Chain struct {
-		AssumeValid    string
-		StartLogHeight int32 `default:"2147483647"`
+		AssumeValid        string
+		StartLogHeight     int32  `default:"2147483647"`
+		ExcessiveBlockSize uint64 `default:"32000000"` // Do not accept blocks larger than this limit, in bytes
+		TxIndex            bool   `default:"false"`    // Maintain a full transaction index, used by the getrawtransaction rpc call
+		AddressIndex       bool   `default:"false"`    // Maintain an address index, used by the getaddress* rpc calls
+		SpentIndex         bool   `default:"false"`    // Maintain a spent index, used by the getspentinfo rpc call
+		BlockCacheSize     int    `default:"10"`       // Number of recently used blocks kept deserialized in memory, 0 disables the cache
+		ReindexIndex       string // Wipe and rebuild this optional index at startup: txindex, addressindex or spentindex
 	}
 	Mining struct {
 		BlockMinTxFee int64  // default DefaultBlockMinTxFee
 		BlockMaxSize  uint64 // default DefaultMaxGeneratedBlockSize
 		BlockVersion  int32  `default:"-1"`
 		Strategy      string `default:"ancestorfeerate"` // option:ancestorfee/ancestorfeerate
+		MiningAddr    string // Address the coinbase transactions of getblocktemplate pay to
+	}
+	ZMQ struct {
+		PubHashTx    string // Publish the hashes of the transactions on this endpoint, as tcp://<host>:<port>
+		PubRawTx     string // Publish the raw transactions on this endpoint
+		PubHashBlock string // Publish the hashes of the blocks connected on this endpoint
+		PubRawBlock  string // Publish the raw blocks connected on this endpoint
+		PubHWM       int    `default:"1000"` // Max number of messages queued for a subscriber, the newer ones being dropped
+	}
+	Notify struct {
+		BlockNotify string // Command run when the best block changes, %s being replaced by its hash
+		AlertNotify string // Command run once when a warning is raised, %s being replaced by the message
 	}
 	PProf struct {
 		IP   string `default:"localhost"`
@@ -5,7 +5,9 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/spf13/viper"
@@ -168,3 +170,50 @@ func TestExistDataDir(t *testing.T) {
 		t.Errorf("the fileFalse file shouldn't exist!")
 	}
 }
+
+func TestInitConfigBlocksOnly(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "blocksonly")
+	if err != nil {
+		t.Fatalf("generate temp dir failed: %s\n", err)
+	}
+	defer os.RemoveAll(dataDir)
+	content, err := ioutil.ReadFile(defaultConfigFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// leave BlocksOnly to its default, and MaxPoolSize too when unset
+	var lines []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if !strings.Contains(line, "BlocksOnly:") && !strings.Contains(line, "MaxPoolSize:") {
+			lines = append(lines, line)
+		}
+	}
+	writeConf := func(extra string) {
+		conf := strings.Replace(strings.Join(lines, "\n"), "Mempool:\n", "Mempool:\n"+extra, 1)
+		if err := ioutil.WriteFile(filepath.Join(dataDir, defaultConfigFilename), []byte(conf), 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		maxPoolSize string
+		args        []string
+		blocksOnly  bool
+		poolSize    int64
+	}{
+		{"default", "", nil, false, defaultMaxMempoolSize * 1000000},
+		{"blocks only", "", []string{"--blocksonly"}, true, defaultBlocksOnlyMaxMempoolSize * 1000000},
+		{"blocks only with the default pool size", "  MaxPoolSize: 300000000\n", []string{"--blocksonly"}, true,
+			defaultBlocksOnlyMaxMempoolSize * 1000000},
+		{"blocks only with a pool size", "  MaxPoolSize: 20000000\n", []string{"--blocksonly"}, true, 20000000},
+	}
+	for _, test := range tests {
+		writeConf(test.maxPoolSize)
+		config := InitConfig(append([]string{"--datadir=" + dataDir}, test.args...))
+		if config.P2PNet.BlocksOnly != test.blocksOnly || config.Mempool.MaxPoolSize != test.poolSize {
+			t.Errorf("%s: blocks only %t with a pool of %d, expect %t and %d", test.name,
+				config.P2PNet.BlocksOnly, config.Mempool.MaxPoolSize, test.blocksOnly, test.poolSize)
+		}
+	}
+}
@@ -10,6 +10,12 @@ type Opts struct {
 
 	//Set -discover=0 in regtest framework
 	Discover int `long:"discover" default:"1" description:"Discover own IP addresses (default: 1 when listening and no -externalip or -proxy) "`
+
+	BlocksOnly bool `long:"blocksonly" description:"Do not request or relay transactions of remote peers, only blocks"`
+
+	ReindexIndex string `long:"reindex-index" description:"Wipe and rebuild a single optional index (txindex, addressindex or spentindex) from the blocks on disk"`
+
+	Debug []string `long:"debug" description:"Output the debug log of a module, e.g. bench for block connection timings; may be repeated"`
 }
 
 func InitArgs(args []string) (*Opts, error) {
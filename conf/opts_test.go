@@ -5,6 +5,8 @@ import "testing"
 var args = []string{
 	"--datadir=/test",
 	"--discover", "1",
+	"--debug", "bench",
+	"--debug=net",
 }
 
 var empty []string
@@ -22,6 +24,9 @@ func TestInitArgs(t *testing.T) {
 	if opts.DataDir != "/test" {
 		t.Errorf("format error ")
 	}
+	if len(opts.Debug) != 2 || opts.Debug[0] != "bench" || opts.Debug[1] != "net" {
+		t.Errorf("got debug modules %v", opts.Debug)
+	}
 
 }
 
@@ -7,6 +7,7 @@ package conf
 import (
 	"bytes"
 	"fmt"
+	"runtime"
 	"strings"
 )
 
@@ -30,6 +31,50 @@ const (
 // contain characters from semanticAlphabet per the semantic versioning spec.
 var appBuild string
 
+// The build information below is set during the build process with
+// '-ldflags "-X github.com/copernet/copernicus/conf.GitCommit=..."', see the
+// Makefile. BuildDate is the committer date of GitCommit rather than the time
+// of the build, so that building a commit twice gives the same binary.
+var (
+	GitCommit string
+	BuildDate string
+
+	// buildFeatures is the comma separated list of the optional features
+	// compiled into the binary, such as wallet and zmq.
+	buildFeatures string
+)
+
+// OptionalFeatures lists the features which may be left out of a build.
+var OptionalFeatures = []string{"wallet", "zmq"}
+
+// HasFeature returns whether the optional feature name was compiled in.
+func HasFeature(name string) bool {
+	for _, feature := range strings.Split(buildFeatures, ",") {
+		if strings.TrimSpace(feature) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GoVersion returns the version of the Go toolchain the binary was built with.
+func GoVersion() string {
+	return runtime.Version()
+}
+
+// BuildComment returns the user agent comment identifying the commit the
+// binary was built from, or an empty string for builds without one.
+func BuildComment() string {
+	commit := normalizeVerString(GitCommit)
+	if commit == "" {
+		return ""
+	}
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	return "commit-" + commit
+}
+
 // Version returns the application version as a properly formed string per the
 // semantic versioning 2.0.0 spec (http://semver.org/).
 func Version() string {
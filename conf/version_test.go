@@ -0,0 +1,26 @@
+package conf
+
+import "testing"
+
+func TestBuildInfo(t *testing.T) {
+	defer func(commit, features string) {
+		GitCommit, buildFeatures = commit, features
+	}(GitCommit, buildFeatures)
+
+	GitCommit, buildFeatures = "", ""
+	if comment := BuildComment(); comment != "" {
+		t.Errorf("build without commit has user agent comment %q", comment)
+	}
+	if HasFeature("wallet") {
+		t.Errorf("build without features reports the wallet")
+	}
+
+	GitCommit = "bc9c7761f0a4e5d3c2b1a09f8e7d6c5b4a392817"
+	buildFeatures = "zmq, wallet"
+	if comment := BuildComment(); comment != "commit-bc9c7761f0a4" {
+		t.Errorf("unexpected user agent comment %q", comment)
+	}
+	if !HasFeature("wallet") || !HasFeature("zmq") || HasFeature("txindex") {
+		t.Errorf("features %q are parsed wrongly", buildFeatures)
+	}
+}
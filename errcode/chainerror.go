@@ -15,12 +15,47 @@ const (
 	ErrorBadBlkLength
 	ErrorBadBlkTxSize
 	ErrorBadBlkTx
+	ErrorBadDiffBits
+	ErrorTimeTooOld
+	ErrorTimeTooNew
+	ErrorBadVersion
+	ErrorBadPrevBlock
+	ErrorCheckpointMismatch
 )
 
 var ChainErrString = map[ChainErr]string{
 	ErrorBlockHeaderNoValid:  "The block header is not valid",
 	ErrorBlockHeaderNoParent: "Can not find this block header's father ",
-	ErrorPowCheckErr:         "ErrorPowCheckErr",
+	ErrorPowCheckErr:         "high-hash",
+	ErrorBadDiffBits:         "bad-diffbits",
+	ErrorTimeTooOld:          "time-too-old",
+	ErrorTimeTooNew:          "time-too-new",
+	ErrorBadVersion:          "bad-version",
+	ErrorBadPrevBlock:        "bad-prevblk",
+	ErrorCheckpointMismatch:  "bad-fork-prior-to-checkpoint",
+}
+
+// chainErrBanScore is the ban score of a peer sending a header or a block
+// failing validation with the error. A header too far in the future may be
+// the fault of our own clock, it is not punished.
+var chainErrBanScore = map[ChainErr]uint32{
+	ErrorBlockHeaderNoParent: 10,
+	ErrorPowCheckErr:         50,
+	ErrorBadDiffBits:         100,
+	ErrorTimeTooOld:          100,
+	ErrorBadVersion:          100,
+	ErrorBadPrevBlock:        100,
+	ErrorCheckpointMismatch:  100,
+}
+
+// GetChainErrBanScore returns the ban score of a peer which sent a header or
+// a block rejected with err, 0 when the peer is not to blame.
+func GetChainErrBanScore(err error) uint32 {
+	e, ok := err.(ProjectError)
+	if !ok || e.Module != "chain" {
+		return 0
+	}
+	return chainErrBanScore[ChainErr(e.Code)]
 }
 
 func (chainerr ChainErr) String() string {
@@ -15,6 +15,8 @@ const (
 	ErrorOpenUndoFileFailed
 	FailedToReadBlock
 	DisconnectTipUndoFailed
+	ErrorBlockUndoInconsistent
+	ErrorUnknownIndex
 	// ErrorBadBlkLength
 	// ErrorBadBlkTxSize
 	// ErrorBadBlkTx
@@ -13,6 +13,9 @@ const (
 	Nomature
 	ManyUnspendDepend
 	TooMinFeeRate
+	UnsupportedDumpVersion
+	OversizedStreamTx
+	AbsurdFee
 )
 
 var merrToString = map[MemPoolErr]string{
@@ -22,6 +25,10 @@ var merrToString = map[MemPoolErr]string{
 	Nomature:          "non-BIP68-final",
 	ManyUnspendDepend: "the transaction depend many unspend transaction",
 	TooMinFeeRate:     "the transaction's feerate is too minimal",
+
+	UnsupportedDumpVersion: "unsupported mempool.dat version",
+	OversizedStreamTx:      "raw transaction stream entry exceeds the maximum transaction size",
+	AbsurdFee:              "absurdly-high-fee",
 }
 
 func (me MemPoolErr) String() string {
@@ -13,6 +13,7 @@ const (
 	TxErrRejectDust            TxErr = 0x41
 	TxErrRejectInsufficientFee TxErr = 0x42
 	TxErrRejectCheckPoint      TxErr = 0x43
+	TxErrRejectHighFee         TxErr = 0x100
 	TxErrRejectAlreadyKnown    TxErr = 0x101
 	TxErrRejectConflict        TxErr = 0x102
 
@@ -36,10 +37,54 @@ const (
 	TxErrSignRawTransaction
 	TxErrInvalidIndexOfIn
 	TxErrPubKeyType
+	TxErrEmptyOutputs
+	TxErrBadCoinBaseLength
+	TxErrIsCoinBase
+	TxErrScriptCheckTimeout
+	TxErrPrematureCoinbase
 )
 
 var txErrorToString = map[TxErr]string{
-	TxErrNoPreviousOut: "There is no previousout",
+	TxErrNoPreviousOut:      "There is no previousout",
+	TxErrNullPreOut:         "bad-txns-prevout-null",
+	TxErrNotCoinBase:        "bad-cb-missing",
+	TxErrEmptyInputs:        "bad-txns-vin-empty",
+	TxErrEmptyOutputs:       "bad-txns-vout-empty",
+	TxErrTotalMoneyTooLarge: "bad-txns-txouttotal-toolarge",
+	TxErrTooManySigOps:      "bad-txn-sigops",
+	TxErrDupIns:             "bad-txns-inputs-duplicate",
+	TxErrOverSize:           "bad-txns-oversize",
+	TxErrBadCoinBaseLength:  "bad-cb-length",
+	TxErrIsCoinBase:         "coinbase",
+	TxErrOutAlreadHave:      "bad-txns-BIP30",
+	TxErrScriptCheckTimeout: "mempool-script-verify-timeout",
+	TxErrPrematureCoinbase:  "bad-txns-premature-spend-of-coinbase",
+}
+
+// txErrToRejectCode maps the stateless transaction check errors onto the
+// reject code sent to peers in a reject message.
+var txErrToRejectCode = map[TxErr]TxErr{
+	TxErrNullPreOut:         TxErrRejectInvalid,
+	TxErrNotCoinBase:        TxErrRejectInvalid,
+	TxErrEmptyInputs:        TxErrRejectInvalid,
+	TxErrEmptyOutputs:       TxErrRejectInvalid,
+	TxErrTotalMoneyTooLarge: TxErrRejectInvalid,
+	TxErrTooManySigOps:      TxErrRejectInvalid,
+	TxErrDupIns:             TxErrRejectInvalid,
+	TxErrOverSize:           TxErrRejectInvalid,
+	TxErrBadCoinBaseLength:  TxErrRejectInvalid,
+	TxErrIsCoinBase:         TxErrRejectInvalid,
+	TxErrOutAlreadHave:      TxErrRejectInvalid,
+	TxErrPrematureCoinbase:  TxErrRejectInvalid,
+}
+
+// mempoolErrToRejectCode maps the mempool acceptance errors onto reject codes.
+var mempoolErrToRejectCode = map[MemPoolErr]TxErr{
+	AlreadHaveTx:      TxErrRejectAlreadyKnown,
+	Nomature:          TxErrRejectInvalid,
+	ManyUnspendDepend: TxErrRejectNonstandard,
+	TooMinFeeRate:     TxErrRejectInsufficientFee,
+	AbsurdFee:         TxErrRejectHighFee,
 }
 
 func (te TxErr) String() string {
@@ -48,3 +93,41 @@ func (te TxErr) String() string {
 	}
 	return fmt.Sprintf("Unknown code (%d)", te)
 }
+
+// IsTxCheckTimeout reports whether err tells that checking the transaction
+// took too long on a busy node rather than that it failed a check.  Such a
+// transaction is neither remembered as rejected nor answered with a reject
+// message, it may be accepted once it is announced again.
+func IsTxCheckTimeout(err error) bool {
+	return IsErrorCode(err, TxErrScriptCheckTimeout)
+}
+
+// GetTxRejectCode returns the reject code and reason for an error produced by
+// the transaction checks, so mempool acceptance, block validation and the RPC
+// layer report the same code. Errors which don't originate from a transaction
+// check are reported as invalid.
+func GetTxRejectCode(err error) (TxErr, string) {
+	e, ok := err.(ProjectError)
+	if !ok {
+		return TxErrRejectInvalid, err.Error()
+	}
+
+	switch e.Module {
+	case "transaction":
+		if e.Code < TxErrorBase {
+			return TxErr(e.Code), e.Desc
+		}
+		if code, ok := txErrToRejectCode[TxErr(e.Code)]; ok {
+			return code, e.Desc
+		}
+		if e.Code == int(TxOutErrNegativeValue) || e.Code == int(TxOutErrTooLargeValue) {
+			return TxErrRejectInvalid, e.Desc
+		}
+	case "mempool":
+		if code, ok := mempoolErrToRejectCode[MemPoolErr(e.Code)]; ok {
+			return code, e.Desc
+		}
+	}
+
+	return TxErrRejectInvalid, e.Desc
+}
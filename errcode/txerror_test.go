@@ -0,0 +1,39 @@
+package errcode
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetTxRejectCode(t *testing.T) {
+	tests := []struct {
+		err    error
+		code   TxErr
+		reason string
+	}{
+		{New(TxErrRejectDust), TxErrRejectDust, TxErrRejectDust.String()},
+		{New(TxErrNoPreviousOut), TxErrRejectInvalid, TxErrNoPreviousOut.String()},
+		{New(TxOutErrNegativeValue), TxErrRejectInvalid, TxOutErrNegativeValue.String()},
+		{New(AlreadHaveTx), TxErrRejectAlreadyKnown, AlreadHaveTx.String()},
+		{New(Nomature), TxErrRejectInvalid, "non-BIP68-final"},
+		{New(TooMinFeeRate), TxErrRejectInsufficientFee, TooMinFeeRate.String()},
+		{New(AbsurdFee), TxErrRejectHighFee, "absurdly-high-fee"},
+		{New(MissParent), TxErrRejectInvalid, MissParent.String()},
+		{errors.New("boom"), TxErrRejectInvalid, "boom"},
+	}
+	for _, test := range tests {
+		code, reason := GetTxRejectCode(test.err)
+		if code != test.code || reason != test.reason {
+			t.Errorf("GetTxRejectCode(%v) = %v, %q, expect %v, %q", test.err, code, reason, test.code, test.reason)
+		}
+	}
+}
+
+func TestIsTxCheckTimeout(t *testing.T) {
+	if !IsTxCheckTimeout(New(TxErrScriptCheckTimeout)) {
+		t.Error("a script check timeout is not reported")
+	}
+	if IsTxCheckTimeout(New(TxErrRejectNonstandard)) || IsTxCheckTimeout(errors.New("boom")) {
+		t.Error("a failed check is reported as a timeout")
+	}
+}
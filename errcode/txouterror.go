@@ -10,7 +10,8 @@ const (
 )
 
 var txOutErrorToString = map[TxOutErr]string{
-	TxOutErrNegativeValue: "Tx out's value is negative",
+	TxOutErrNegativeValue: "bad-txns-vout-negative",
+	TxOutErrTooLargeValue: "bad-txns-vout-toolarge",
 }
 
 func (te TxOutErr) String() string {
@@ -2,12 +2,17 @@ package main
 
 import (
 	"fmt"
+	"path/filepath"
+
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/crypto"
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/logic/lblockindex"
 	"github.com/copernet/copernicus/logic/lchain"
+	"github.com/copernet/copernicus/logic/lmempool"
+	"github.com/copernet/copernicus/model/block"
 	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/consensus"
 	"github.com/copernet/copernicus/model/mempool"
 	"github.com/copernet/copernicus/model/utxo"
 	"github.com/copernet/copernicus/persist"
@@ -15,7 +20,7 @@ import (
 	"github.com/copernet/copernicus/persist/db"
 )
 
-func appInitMain(args []string) {
+func appInitMain(args []string) error {
 	//init config
 	conf.Cfg = conf.InitConfig(args)
 	fmt.Println("Current data dir:\033[0;32m", conf.DataDir, "\033[0m")
@@ -23,6 +28,11 @@ func appInitMain(args []string) {
 	//init log
 	log.Init()
 
+	if conf.Cfg.Chain.ExcessiveBlockSize <= consensus.LegacyMaxBlockSize {
+		return fmt.Errorf("excessive block size must be larger than %d", consensus.LegacyMaxBlockSize)
+	}
+	block.SetMaxBlockSize(conf.Cfg.Chain.ExcessiveBlockSize)
+
 	// Init UTXO DB
 	utxoConfig := utxo.UtxoConfig{Do: &db.DBOption{FilePath: conf.Cfg.DataDir + "/chainstate", CacheSize: (1 << 20) * 8}}
 	utxo.InitUtxoLruTip(&utxoConfig)
@@ -38,7 +48,31 @@ func appInitMain(args []string) {
 	// Load blockindex DB
 	lblockindex.LoadBlockIndexDB()
 	lchain.InitGenesisChain()
+	if name := conf.Cfg.Chain.ReindexIndex; len(name) > 0 {
+		if err := lchain.ReindexIndex(name); err != nil {
+			panic(fmt.Sprintf("Failed to rebuild the %s index: %v", name, err))
+		}
+	}
+	if err := lchain.InitTxIndex(); err != nil {
+		panic(fmt.Sprintf("Failed to build the transaction index: %v", err))
+	}
+	if err := lchain.InitAddressIndex(); err != nil {
+		panic(fmt.Sprintf("Failed to build the address index: %v", err))
+	}
+	if err := lchain.InitSpentIndex(); err != nil {
+		panic(fmt.Sprintf("Failed to build the spent index: %v", err))
+	}
 
 	mempool.InitMempool()
 	crypto.InitSecp256()
+
+	if err := lmempool.LoadMempool(filepath.Join(conf.Cfg.DataDir, lmempool.MempoolFileName)); err != nil {
+		log.Error("Failed to load mempool from disk: %v", err)
+	}
+	if url := conf.Cfg.Mempool.WarmFrom; url != "" {
+		if _, _, err := lmempool.WarmMempool(url); err != nil {
+			log.Error("Failed to warm the mempool from another node: %v", err)
+		}
+	}
+	return nil
 }
@@ -115,6 +115,11 @@ func GetLogger() *logs.BeeLogger {
 	return logs.GetBeeLogger()
 }
 
+// FilePath returns the path of the log file.
+func FilePath() string {
+	return filepath.Join(conf.DataDir, defaultLogDirname, conf.Cfg.Log.FileName+".log")
+}
+
 func Init() {
 	logDir := filepath.Join(conf.DataDir, defaultLogDirname)
 	if !conf.ExistDataDir(logDir) {
@@ -129,7 +134,7 @@ func Init() {
 		Level    int    `json:"level"`
 		Daily    bool   `json:"daily"`
 	}{
-		FileName: logDir + "/" + conf.Cfg.Log.FileName + ".log",
+		FileName: FilePath(),
 		Level:    getLevel(conf.Cfg.Log.Level),
 		Daily:    false,
 	}
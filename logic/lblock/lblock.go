@@ -96,7 +96,7 @@ func CheckBlock(pblock *block.Block) error {
 	}
 
 	// size limits
-	nMaxBlockSize := consensus.DefaultMaxBlockSize
+	nMaxBlockSize := int(block.GetMaxBlockSize())
 	// Bail early if there is no way this lblock is of reasonable size.
 	minTransactionSize := tx.NewEmptyTx().EncodeSize()
 	if len(pblock.Txs)*int(minTransactionSize) > nMaxBlockSize {
@@ -281,15 +281,15 @@ func AcceptBlockHeader(bh *block.BlockHeader) (*blockindex.BlockIndex, error) {
 		}
 		if bIndex.Prev.IsInvalid() {
 			log.Debug("AcceptBlockHeader Invalid Pre index")
-			return nil, errcode.ProjectError{Code: 3100}
+			return nil, errcode.New(errcode.ErrorBadPrevBlock)
 		}
 		if !lblockindex.CheckIndexAgainstCheckpoint(bIndex.Prev) {
-			log.Debug("AcceptBlockHeader err:%d", 3100)
-			return nil, errcode.ProjectError{Code: 3100}
+			log.Debug("AcceptBlockHeader fork prior to the last checkpoint")
+			return nil, errcode.New(errcode.ErrorCheckpointMismatch)
 		}
-		if !ContextualCheckBlockHeader(bh, bIndex.Prev, util.GetAdjustedTime()) {
-			log.Debug("AcceptBlockHeader err:%d", 3101)
-			return nil, errcode.ProjectError{Code: 3101}
+		if err := ContextualCheckBlockHeader(bh, bIndex.Prev, util.GetAdjustedTime()); err != nil {
+			log.Debug("AcceptBlockHeader err:%v", err)
+			return nil, err
 		}
 	}
 
@@ -9,6 +9,10 @@ import (
 	"github.com/copernet/copernicus/model/pow"
 )
 
+// MaxFutureBlockTime is the number of seconds a block time may be ahead of
+// the network adjusted time.
+const MaxFutureBlockTime = 2 * 60 * 60
+
 func CheckBlockHeader(bh *block.BlockHeader) error {
 	hash := bh.GetHash()
 	params := chain.GetInstance().GetParams()
@@ -21,7 +25,11 @@ func CheckBlockHeader(bh *block.BlockHeader) error {
 	return nil
 }
 
-func ContextualCheckBlockHeader(header *block.BlockHeader, preIndex *blockindex.BlockIndex, adjustTime int64) bool {
+// ContextualCheckBlockHeader checks a header against its parent: the
+// difficulty must follow the retargeting rules, the time must be greater than
+// the median time past of the parent and at most two hours ahead of the
+// network adjusted time adjustTime, and the version must not be obsolete.
+func ContextualCheckBlockHeader(header *block.BlockHeader, preIndex *blockindex.BlockIndex, adjustTime int64) error {
 	nHeight := int32(0)
 	if preIndex != nil {
 		nHeight = preIndex.Height + 1
@@ -32,20 +40,20 @@ func ContextualCheckBlockHeader(header *block.BlockHeader, preIndex *blockindex.
 	p := new(pow.Pow)
 	if header.Bits != p.GetNextWorkRequired(preIndex, header, params) {
 		log.Error("ContextualCheckBlockHeader.GetNextWorkRequired err")
-		return false
+		return errcode.New(errcode.ErrorBadDiffBits)
 	}
 	blocktime := int64(header.Time)
 	if blocktime <= preIndex.GetMedianTimePast() {
 		log.Error("ContextualCheckBlockHeader.GetMedianTimePast err")
-		return false
+		return errcode.New(errcode.ErrorTimeTooOld)
 	}
-	if blocktime > adjustTime+2*60*60 {
+	if blocktime > adjustTime+MaxFutureBlockTime {
 		log.Error("ContextualCheckBlockHeader > adjustTime err")
-		return false
+		return errcode.New(errcode.ErrorTimeTooNew)
 	}
 	if (header.Version < 2 && nHeight >= params.BIP34Height) || (header.Version < 3 && nHeight >= params.BIP66Height) || (header.Version < 4 && nHeight >= params.BIP65Height) {
 		log.Error("block.version: %d, nheight :%d", header.Version, nHeight)
-		return false
+		return errcode.New(errcode.ErrorBadVersion)
 	}
-	return true
+	return nil
 }
@@ -4,7 +4,12 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/pow"
 	"github.com/copernet/copernicus/util"
 )
 
@@ -84,3 +89,48 @@ func TestBlockHeaderGetHash(t *testing.T) {
 		}
 	}
 }
+
+func TestContextualCheckBlockHeaderTime(t *testing.T) {
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	chain.InitGlobalChain()
+
+	var prev *blockindex.BlockIndex
+	for i := 0; i < 11; i++ {
+		index := new(blockindex.BlockIndex)
+		index.Height = int32(i)
+		index.Header.Time = uint32(1500000000 + i*600)
+		index.Header.Bits = 0x1d00ffff
+		index.Prev = prev
+		prev = index
+	}
+	mtp := prev.GetMedianTimePast()
+	adjustTime := int64(prev.Header.Time) + 600
+
+	header := block.NewBlockHeader()
+	header.Version = 4
+	header.Bits = new(pow.Pow).GetNextWorkRequired(prev, header, chain.GetInstance().GetParams())
+
+	tests := []struct {
+		time int64
+		want error
+	}{
+		{mtp, errcode.New(errcode.ErrorTimeTooOld)},
+		{mtp + 1, nil},
+		{adjustTime + MaxFutureBlockTime, nil},
+		{adjustTime + MaxFutureBlockTime + 1, errcode.New(errcode.ErrorTimeTooNew)},
+	}
+	for _, test := range tests {
+		header.Time = uint32(test.time)
+		if err := ContextualCheckBlockHeader(header, prev, adjustTime); err != test.want {
+			t.Errorf("header time %d: got error %v, want %v", test.time, err, test.want)
+		}
+	}
+
+	header.Time = uint32(mtp + 1)
+	header.Bits--
+	if err := ContextualCheckBlockHeader(header, prev, adjustTime); !errcode.IsErrorCode(err, errcode.ErrorBadDiffBits) {
+		t.Errorf("header with wrong bits: got error %v, want bad-diffbits", err)
+	}
+}
@@ -0,0 +1,174 @@
+package lchain
+
+import (
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/errcode"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/undo"
+	"github.com/copernet/copernicus/persist/blkdb"
+	"github.com/copernet/copernicus/persist/disk"
+)
+
+// AddressIndexEnabled reports whether the node maintains the address index.
+func AddressIndexEnabled() bool {
+	return conf.Cfg != nil && conf.Cfg.Chain.AddressIndex
+}
+
+// AddressKeyFromScript returns the address index key of a pay-to-pubkey-hash
+// or pay-to-script-hash scriptPubKey. Other scripts are not indexed.
+func AddressKeyFromScript(scriptPubKey *script.Script) (*blkdb.AddressKey, bool) {
+	data := scriptPubKey.GetData()
+	key := &blkdb.AddressKey{}
+	switch {
+	case len(data) == 25 && data[0] == opcodes.OP_DUP && data[1] == opcodes.OP_HASH160 &&
+		data[2] == script.Hash160BytesLength && data[23] == opcodes.OP_EQUALVERIFY &&
+		data[24] == opcodes.OP_CHECKSIG:
+		key.Type = blkdb.AddressTypePubKeyHash
+		copy(key.Hash160[:], data[3:23])
+	case scriptPubKey.IsPayToScriptHash():
+		key.Type = blkdb.AddressTypeScriptHash
+		copy(key.Hash160[:], data[2:22])
+	default:
+		return nil, false
+	}
+	return key, true
+}
+
+// AddressKeyFromAddress returns the address index key of an address.
+func AddressKeyFromAddress(addr *script.Address) (*blkdb.AddressKey, error) {
+	key := &blkdb.AddressKey{}
+	switch addr.GetVersion() {
+	case script.AddressVerPubKey():
+		key.Type = blkdb.AddressTypePubKeyHash
+	case script.AddressVerScript():
+		key.Type = blkdb.AddressTypeScriptHash
+	default:
+		return nil, errcode.New(errcode.ScriptErrNonStandard)
+	}
+	copy(key.Hash160[:], addr.EncodeToPubKeyHash())
+	return key, nil
+}
+
+// blockAddressIndex collects the address deltas of a block, the outputs it
+// creates which are still unspent at its end, and the outputs of earlier
+// blocks it spends.
+func blockAddressIndex(pblock *block.Block, height int32, blockUndo *undo.BlockUndo) (
+	deltas []*blkdb.AddressDelta, created, spent []*blkdb.AddressUnspent, err error) {
+
+	txUndos := blockUndo.GetTxundo()
+	if len(txUndos)+1 != len(pblock.Txs) {
+		return nil, nil, nil, errcode.New(errcode.ErrorBlockUndoInconsistent)
+	}
+	unspents := make(map[outpoint.OutPoint]*blkdb.AddressUnspent)
+	order := make([]outpoint.OutPoint, 0)
+	for i, transaction := range pblock.Txs {
+		txHash := transaction.GetHash()
+		if i > 0 {
+			coins := txUndos[i-1].GetUndoCoins()
+			for j, in := range transaction.GetIns() {
+				coin := coins[j]
+				key, ok := AddressKeyFromScript(coin.GetScriptPubKey())
+				if !ok {
+					continue
+				}
+				deltas = append(deltas, &blkdb.AddressDelta{
+					Address:  *key,
+					Height:   height,
+					TxHash:   txHash,
+					Index:    uint32(j),
+					Spending: true,
+					Amount:   -coin.GetAmount(),
+				})
+				if _, ok := unspents[*in.PreviousOutPoint]; ok {
+					delete(unspents, *in.PreviousOutPoint)
+					continue
+				}
+				spent = append(spent, &blkdb.AddressUnspent{
+					Address:      *key,
+					TxHash:       in.PreviousOutPoint.Hash,
+					Index:        in.PreviousOutPoint.Index,
+					Amount:       coin.GetAmount(),
+					ScriptPubKey: coin.GetScriptPubKey().GetData(),
+					Height:       coin.GetHeight(),
+				})
+			}
+		}
+		for j, out := range transaction.GetOuts() {
+			key, ok := AddressKeyFromScript(out.GetScriptPubKey())
+			if !ok {
+				continue
+			}
+			deltas = append(deltas, &blkdb.AddressDelta{
+				Address: *key,
+				Height:  height,
+				TxHash:  txHash,
+				Index:   uint32(j),
+				Amount:  out.GetValue(),
+			})
+			op := outpoint.OutPoint{Hash: txHash, Index: uint32(j)}
+			unspents[op] = &blkdb.AddressUnspent{
+				Address:      *key,
+				TxHash:       txHash,
+				Index:        uint32(j),
+				Amount:       out.GetValue(),
+				ScriptPubKey: out.GetScriptPubKey().GetData(),
+				Height:       height,
+			}
+			order = append(order, op)
+		}
+	}
+	for _, op := range order {
+		if u, ok := unspents[op]; ok {
+			created = append(created, u)
+		}
+	}
+	return deltas, created, spent, nil
+}
+
+// WriteBlockAddressIndex adds the deltas of a connected block to the address
+// index and updates the unspent outputs of the addresses.
+func WriteBlockAddressIndex(pblock *block.Block, pindex *blockindex.BlockIndex, blockUndo *undo.BlockUndo) error {
+	deltas, created, spent, err := blockAddressIndex(pblock, pindex.Height, blockUndo)
+	if err != nil {
+		return err
+	}
+	if err := blkdb.GetInstance().UpdateAddressIndex(deltas, nil, created, spent); err != nil {
+		log.Error("WriteBlockAddressIndex: write address index of block %s failed: %v",
+			pindex.GetBlockHash().String(), err)
+		return err
+	}
+	return nil
+}
+
+// EraseBlockAddressIndex reverts WriteBlockAddressIndex for a disconnected
+// block.
+func EraseBlockAddressIndex(pblock *block.Block, pindex *blockindex.BlockIndex, blockUndo *undo.BlockUndo) error {
+	deltas, created, spent, err := blockAddressIndex(pblock, pindex.Height, blockUndo)
+	if err != nil {
+		return err
+	}
+	if err := blkdb.GetInstance().UpdateAddressIndex(nil, deltas, spent, created); err != nil {
+		log.Error("EraseBlockAddressIndex: erase address index of block %s failed: %v",
+			pindex.GetBlockHash().String(), err)
+		return err
+	}
+	return nil
+}
+
+// InitAddressIndex brings the address index in line with the configuration.
+func InitAddressIndex() error {
+	return initIndex(blkdb.AddressIndexName, AddressIndexEnabled(), func(blk *block.Block, index *blockindex.BlockIndex) error {
+		pos := index.GetUndoPos()
+		blockUndo, ok := disk.UndoReadFromDisk(&pos, *index.Prev.GetBlockHash())
+		if !ok {
+			log.Error("InitAddressIndex: failure reading undo data of block %s", index.GetBlockHash().String())
+			return errcode.New(errcode.ErrorOpenUndoFileFailed)
+		}
+		return WriteBlockAddressIndex(blk, index, blockUndo)
+	})
+}
@@ -0,0 +1,97 @@
+package lchain
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/undo"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/persist/blkdb"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+)
+
+func TestAddressKeyFromScript(t *testing.T) {
+	hash160 := bytes.Repeat([]byte{7}, 20)
+	p2pkh, _ := script.NewScriptPubKeyHash(hash160)
+	p2sh, _ := script.NewScriptHash(hash160)
+
+	key, ok := AddressKeyFromScript(p2pkh)
+	if !ok || key.Type != blkdb.AddressTypePubKeyHash || !bytes.Equal(key.Hash160[:], hash160) {
+		t.Errorf("unexpected p2pkh key %v", key)
+	}
+	key, ok = AddressKeyFromScript(p2sh)
+	if !ok || key.Type != blkdb.AddressTypeScriptHash || !bytes.Equal(key.Hash160[:], hash160) {
+		t.Errorf("unexpected p2sh key %v", key)
+	}
+	if _, ok = AddressKeyFromScript(script.NewScriptRaw([]byte{opcodes.OP_TRUE})); ok {
+		t.Errorf("non standard scripts should not be indexed")
+	}
+
+	addr, _ := script.AddressFromHash160(hash160, script.AddressVerScript())
+	key, err := AddressKeyFromAddress(addr)
+	if err != nil || key.Type != blkdb.AddressTypeScriptHash {
+		t.Errorf("unexpected address key %v, %v", key, err)
+	}
+}
+
+func TestBlockAddressIndex(t *testing.T) {
+	addrA, _ := script.NewScriptPubKeyHash(bytes.Repeat([]byte{1}, 20))
+	addrB, _ := script.NewScriptHash(bytes.Repeat([]byte{2}, 20))
+
+	coinbase := tx.NewTx(0, tx.TxVersion)
+	coinbase.AddTxIn(txin.NewTxIn(nil, script.NewScriptRaw([]byte{opcodes.OP_0}), script.SequenceFinal))
+	coinbase.AddTxOut(txout.NewTxOut(50, addrA))
+
+	// spends an earlier output of A, pays B, and B is spent in the same block
+	prevOut := &outpoint.OutPoint{Hash: util.HashOne, Index: 3}
+	pay := tx.NewTx(0, tx.TxVersion)
+	pay.AddTxIn(txin.NewTxIn(prevOut, script.NewEmptyScript(), script.SequenceFinal))
+	pay.AddTxOut(txout.NewTxOut(30, addrB))
+	spend := tx.NewTx(0, tx.TxVersion)
+	spend.AddTxIn(txin.NewTxIn(&outpoint.OutPoint{Hash: pay.GetHash(), Index: 0},
+		script.NewEmptyScript(), script.SequenceFinal))
+	spend.AddTxOut(txout.NewTxOut(20, addrA))
+
+	blk := block.NewBlock()
+	blk.Txs = []*tx.Tx{coinbase, pay, spend}
+	blockUndo := undo.NewBlockUndo(2)
+	for _, coin := range []*utxo.Coin{
+		utxo.NewCoin(txout.NewTxOut(40, addrA), 5, false),
+		utxo.NewCoin(txout.NewTxOut(30, addrB), 10, false),
+	} {
+		txUndo := undo.NewTxUndo()
+		txUndo.SetUndoCoins([]*utxo.Coin{coin})
+		blockUndo.AddTxUndo(txUndo)
+	}
+
+	deltas, created, spent, err := blockAddressIndex(blk, 10, blockUndo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum amount.Amount
+	for _, d := range deltas {
+		sum += d.Amount
+	}
+	// +50 +30 +20 received, -40 -30 spent
+	if len(deltas) != 5 || sum != 30 {
+		t.Errorf("got %d deltas summing to %d", len(deltas), sum)
+	}
+	if len(created) != 2 || created[0].Amount != 50 || created[1].Amount != 20 {
+		t.Errorf("outputs spent within the block should not be created: %v", created)
+	}
+	if len(spent) != 1 || spent[0].TxHash != util.HashOne || spent[0].Index != 3 || spent[0].Height != 5 {
+		t.Errorf("unexpected spent outputs %v", spent)
+	}
+
+	if _, _, _, err = blockAddressIndex(blk, 10, undo.NewBlockUndo(0)); err == nil {
+		t.Errorf("inconsistent undo data should be rejected")
+	}
+}
@@ -0,0 +1,84 @@
+package lchain
+
+import (
+	"sync"
+	"time"
+
+	"github.com/copernet/copernicus/log"
+)
+
+// Stages of connecting a block to the active chain, timed for -debug=bench.
+const (
+	BenchLoad        = "load"        // reading the block from disk
+	BenchCheck       = "check"       // sanity checks of ConnectBlock
+	BenchForks       = "forks"       // BIP30/BIP34 and script flag selection
+	BenchScripts     = "scripts"     // applying the transactions, verifying scripts
+	BenchFlush       = "flush"       // moving the coins view into the utxo cache
+	BenchChainState  = "chainstate"  // flushing the chain state to disk
+	BenchPostConnect = "postconnect" // mempool cleanup and index update
+)
+
+// BenchStages lists the stages in the order they run.
+var BenchStages = []string{
+	BenchLoad, BenchCheck, BenchForks, BenchScripts, BenchFlush, BenchChainState, BenchPostConnect,
+}
+
+// BlockBench is the time spent connecting blocks since startup, by stage.
+type BlockBench struct {
+	Blocks int64
+	Stages map[string]time.Duration
+	Total  time.Duration
+}
+
+var blockBench = struct {
+	sync.Mutex
+	blocks int64
+	stages map[string]time.Duration
+	total  time.Duration
+}{stages: make(map[string]time.Duration)}
+
+// benchStage adds the time elapsed since start to stage and logs it along
+// with the total of the stage. It returns the current time, the start of the
+// next stage.
+func benchStage(stage string, start time.Time) time.Time {
+	now := time.Now()
+	elapsed := now.Sub(start)
+
+	blockBench.Lock()
+	blockBench.stages[stage] += elapsed
+	total := blockBench.stages[stage]
+	blockBench.Unlock()
+
+	log.Print("bench", "debug", " - %s: %.2fms [%.2fs]", stage,
+		float64(elapsed)/float64(time.Millisecond), total.Seconds())
+	return now
+}
+
+// benchBlock records a block connected in elapsed time.
+func benchBlock(elapsed time.Duration) {
+	blockBench.Lock()
+	blockBench.blocks++
+	blockBench.total += elapsed
+	blocks, total := blockBench.blocks, blockBench.total
+	blockBench.Unlock()
+
+	log.Print("bench", "debug", " - Connect block: %.2fms [%.2fs (%.2fms/blk)]",
+		float64(elapsed)/float64(time.Millisecond), total.Seconds(),
+		float64(total)/float64(time.Millisecond)/float64(blocks))
+}
+
+// GetBlockBench returns a copy of the block connection timings.
+func GetBlockBench() BlockBench {
+	blockBench.Lock()
+	defer blockBench.Unlock()
+
+	bench := BlockBench{
+		Blocks: blockBench.blocks,
+		Stages: make(map[string]time.Duration, len(blockBench.stages)),
+		Total:  blockBench.total,
+	}
+	for stage, d := range blockBench.stages {
+		bench.Stages[stage] = d
+	}
+	return bench
+}
@@ -0,0 +1,34 @@
+package lchain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBlockBench(t *testing.T) {
+	before := GetBlockBench()
+
+	start := time.Now().Add(-10 * time.Millisecond)
+	next := benchStage(BenchScripts, start)
+	if next.Before(start) {
+		t.Errorf("the next stage starts at %v, before %v", next, start)
+	}
+	benchBlock(20 * time.Millisecond)
+
+	after := GetBlockBench()
+	if after.Blocks != before.Blocks+1 {
+		t.Errorf("got %d blocks, want %d", after.Blocks, before.Blocks+1)
+	}
+	if d := after.Stages[BenchScripts] - before.Stages[BenchScripts]; d < 10*time.Millisecond {
+		t.Errorf("scripts stage grew by %v, want at least 10ms", d)
+	}
+	if d := after.Total - before.Total; d != 20*time.Millisecond {
+		t.Errorf("total grew by %v, want 20ms", d)
+	}
+
+	// the snapshot must not alias the counters
+	after.Stages[BenchScripts] = 0
+	if GetBlockBench().Stages[BenchScripts] == 0 {
+		t.Error("modifying a snapshot changed the counters")
+	}
+}
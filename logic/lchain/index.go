@@ -0,0 +1,88 @@
+package lchain
+
+import (
+	"github.com/copernet/copernicus/errcode"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/persist/blkdb"
+	"github.com/copernet/copernicus/persist/disk"
+)
+
+// initIndex brings the optional index called name in line with the
+// configuration. When the index is enabled but does not cover the active
+// chain yet, it is wiped and the blocks of the active chain are read back from
+// disk and passed to indexBlock: the entries left behind may be of blocks no
+// longer in the active chain. Disabling the index only drops its completeness
+// mark, so that enabling it later rebuilds it.
+func initIndex(name string, enabled bool, indexBlock func(*block.Block, *blockindex.BlockIndex) error) error {
+	blockTree := blkdb.GetInstance()
+	complete := blockTree.ReadIndexComplete(name)
+	if !enabled {
+		if complete {
+			log.Info("%s disabled, it will be rebuilt when enabled again", name)
+			return blockTree.WriteIndexComplete(name, false)
+		}
+		return nil
+	}
+	if complete {
+		return nil
+	}
+
+	log.Info("Wiping %s", name)
+	if err := blockTree.WipeIndex(name); err != nil {
+		return err
+	}
+
+	gChain := chain.GetInstance()
+	params := gChain.GetParams()
+	tipHeight := gChain.Height()
+	// the genesis block is never connected, so it is not indexed either
+	log.Info("Building %s of %d blocks", name, tipHeight)
+	for height := int32(1); height <= tipHeight; height++ {
+		index := gChain.GetIndex(height)
+		if !index.HasData() {
+			log.Error("initIndex: block %s at height %d is not available",
+				index.GetBlockHash().String(), height)
+			return errcode.New(errcode.FailedToReadBlock)
+		}
+		blk, ok := disk.ReadBlockFromDisk(index, params)
+		if !ok {
+			return errcode.New(errcode.FailedToReadBlock)
+		}
+		if err := indexBlock(blk, index); err != nil {
+			return err
+		}
+		if height%10000 == 0 {
+			log.Info("%s built up to height %d", name, height)
+		}
+	}
+	log.Info("%s built", name)
+	return blockTree.WriteIndexComplete(name, true)
+}
+
+// indexInits maps the name of each optional index to the function bringing it
+// in line with the configuration. There is no block filter index to rebuild,
+// so "filters" is rejected as an unknown index.
+var indexInits = map[string]func() error{
+	blkdb.TxIndexName:      InitTxIndex,
+	blkdb.AddressIndexName: InitAddressIndex,
+	blkdb.SpentIndexName:   InitSpentIndex,
+}
+
+// ReindexIndex wipes the optional index called name and, when it is enabled,
+// rebuilds it from the blocks on disk. This is far cheaper than reindexing the
+// whole chain when a single index got corrupted.
+func ReindexIndex(name string) error {
+	initFunc, ok := indexInits[name]
+	if !ok {
+		log.Error("ReindexIndex: unknown index %s", name)
+		return errcode.New(errcode.ErrorUnknownIndex)
+	}
+	log.Info("Wiping %s", name)
+	if err := blkdb.GetInstance().WipeIndex(name); err != nil {
+		return err
+	}
+	return initFunc()
+}
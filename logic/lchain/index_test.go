@@ -0,0 +1,96 @@
+package lchain
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/errcode"
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/persist/blkdb"
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/copernet/copernicus/util"
+)
+
+func TestReindexUnknownIndex(t *testing.T) {
+	for _, name := range []string{"filters", "", "TxIndex"} {
+		if err := ReindexIndex(name); !errcode.IsErrorCode(err, errcode.ErrorUnknownIndex) {
+			t.Errorf("ReindexIndex(%q) error %v, expect an unknown index", name, err)
+		}
+	}
+}
+
+// TestInitIndexDropsDisconnectedBlocks disconnects a block while the spent
+// index is disabled: enabling the index again must not keep its entries.
+func TestInitIndexDropsDisconnectedBlocks(t *testing.T) {
+	path, err := ioutil.TempDir("", "initindex")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	defer func(enabled bool) { conf.Cfg.Chain.SpentIndex = enabled }(conf.Cfg.Chain.SpentIndex)
+	blkdb.InitBlockTreeDB(&blkdb.BlockTreeDBConfig{Do: &db.DBOption{FilePath: path, CacheSize: 1 << 20}})
+	chain.InitGlobalChain()
+
+	genesis := blockindex.NewBlockIndex(&model.ActiveNetParams.GenesisBlock.Header)
+	header := block.BlockHeader{
+		Version:       1,
+		HashPrevBlock: *genesis.GetBlockHash(),
+		Time:          genesis.Header.Time + 600,
+		Bits:          model.ActiveNetParams.PowLimitBits,
+	}
+	tip := blockindex.NewBlockIndex(&header)
+	tip.Height = 1
+	tip.Prev = genesis
+	gChain := chain.GetInstance()
+	gChain.InitLoad(map[util.Hash]*blockindex.BlockIndex{
+		*genesis.GetBlockHash(): genesis,
+		*tip.GetBlockHash():     tip,
+	}, nil)
+	gChain.SetTip(genesis)
+
+	conf.Cfg.Chain.SpentIndex = true
+	if err := InitSpentIndex(); err != nil {
+		t.Fatal(err)
+	}
+	spent := outpoint.NewOutPoint(util.HashOne, 0)
+	spend := tx.NewTx(0, tx.TxVersion)
+	spend.AddTxIn(txin.NewTxIn(spent, script.NewEmptyScript(), script.SequenceFinal))
+	blk := block.NewBlock()
+	blk.Txs = []*tx.Tx{spend}
+	gChain.SetTip(tip)
+	if err := WriteBlockSpentIndex(blk, tip); err != nil {
+		t.Fatal(err)
+	}
+	if err := blkdb.GetInstance().WriteIndexComplete(blkdb.SpentIndexName, true); err != nil {
+		t.Fatal(err)
+	}
+
+	conf.Cfg.Chain.SpentIndex = false
+	if err := InitSpentIndex(); err != nil {
+		t.Fatal(err)
+	}
+	gChain.SetTip(genesis)
+	conf.Cfg.Chain.SpentIndex = true
+	if err := InitSpentIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	if info, err := blkdb.GetInstance().ReadSpentIndex(spent); err != nil || info != nil {
+		t.Errorf("the spent index kept %v of a disconnected block, error %v", info, err)
+	}
+	if !blkdb.GetInstance().ReadIndexComplete(blkdb.SpentIndexName) {
+		t.Errorf("the spent index was not marked complete once rebuilt")
+	}
+}
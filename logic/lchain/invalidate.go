@@ -0,0 +1,130 @@
+package lchain
+
+import (
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/lmempool"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/persist"
+	"github.com/copernet/copernicus/persist/disk"
+)
+
+// descendsFrom reports whether index is ancestor itself or builds on it.
+func descendsFrom(index, ancestor *blockindex.BlockIndex) bool {
+	return index.GetAncestor(ancestor.Height) == ancestor
+}
+
+// isBranchCandidate reports whether index could become the active tip, that
+// is it is valid, all of its blocks are on disk and it has at least as much
+// work as the active tip.
+func isBranchCandidate(index *blockindex.BlockIndex) bool {
+	if !index.IsValid(blockindex.BlockValidTransactions) || !index.HasData() || index.ChainTxCount == 0 {
+		return false
+	}
+	tip := chain.GetInstance().Tip()
+	return tip == nil || index.ChainWork.Cmp(&tip.ChainWork) >= 0
+}
+
+// InvalidateBlock marks index as invalid and the blocks building on it as
+// having an invalid parent, even though they passed validation. When index is
+// part of the active chain, the chain is rewound with the undo data to the
+// parent of index, putting the transactions of the disconnected blocks back
+// into the mempool. The caller activates the best remaining chain afterwards.
+//
+// The caller holds persist.CsMain, which serializes the rewind with block
+// processing.
+func InvalidateBlock(index *blockindex.BlockIndex) error {
+	gChain := chain.GetInstance()
+	gPersist := persist.GetInstance()
+
+	index.AddStatus(blockindex.BlockFailed)
+	gPersist.AddDirtyBlockIndex(index)
+	gChain.RemoveFromBranch(index)
+
+	disconnected := false
+	for gChain.Contains(index) {
+		tip := gChain.Tip()
+		if tip != index {
+			tip.AddStatus(blockindex.BlockFailedParent)
+			gPersist.AddDirtyBlockIndex(tip)
+			gChain.RemoveFromBranch(tip)
+		}
+		if err := DisconnectTip(false); err != nil {
+			return err
+		}
+		disconnected = true
+	}
+
+	gChain.ForEachIndex(func(bi *blockindex.BlockIndex) {
+		if bi != index && descendsFrom(bi, index) {
+			if !bi.IsInvalid() {
+				bi.AddStatus(blockindex.BlockFailedParent)
+				gPersist.AddDirtyBlockIndex(bi)
+			}
+			gChain.RemoveFromBranch(bi)
+		}
+	})
+
+	// The blocks the rewind fell back to may now be the best ones to build on.
+	gChain.ForEachIndex(func(bi *blockindex.BlockIndex) {
+		if isBranchCandidate(bi) && !gChain.InBranch(bi) {
+			gChain.AddToBranch(bi)
+		}
+	})
+
+	if disconnected {
+		lmempool.RemoveForReorg(gChain.Tip().Height+1, int(tx.StandardLockTimeVerifyFlags))
+		log.Info("InvalidateBlock: rewound the active chain to height %d", gChain.Height())
+	}
+	return disk.FlushStateToDisk(disk.FlushStateAlways, 0)
+}
+
+// RollbackChain disconnects the tip of the active chain with the undo data
+// until its height is height, putting the transactions of the disconnected
+// blocks back into the mempool. Unlike InvalidateBlock, the disconnected blocks
+// stay valid candidates, so the next activation of the best chain connects
+// them again. Nothing is done when the chain is not above height.
+//
+// Like InvalidateBlock, it runs with persist.CsMain held.
+func RollbackChain(height int32) error {
+	gChain := chain.GetInstance()
+	if gChain.Height() <= height {
+		return nil
+	}
+	for gChain.Height() > height {
+		if err := DisconnectTip(false); err != nil {
+			return err
+		}
+	}
+
+	lmempool.RemoveForReorg(height+1, int(tx.StandardLockTimeVerifyFlags))
+	log.Info("RollbackChain: rolled the active chain back to height %d", height)
+	return disk.FlushStateToDisk(disk.FlushStateAlways, 0)
+}
+
+// ResetBlockFailureFlags removes the invalidity status from index, from the
+// blocks building on it and from its ancestors, making them candidates for
+// the active chain again. It undoes InvalidateBlock, also with persist.CsMain
+// held, and the caller activates the best chain afterwards.
+func ResetBlockFailureFlags(index *blockindex.BlockIndex) {
+	gChain := chain.GetInstance()
+	gPersist := persist.GetInstance()
+
+	gChain.ForEachIndex(func(bi *blockindex.BlockIndex) {
+		if bi.IsInvalid() && descendsFrom(bi, index) {
+			bi.SubStatus(blockindex.BlockInvalidMask)
+			gPersist.AddDirtyBlockIndex(bi)
+			if isBranchCandidate(bi) && !gChain.InBranch(bi) {
+				gChain.AddToBranch(bi)
+			}
+		}
+	})
+
+	for bi := index.Prev; bi != nil; bi = bi.Prev {
+		if bi.IsInvalid() {
+			bi.SubStatus(blockindex.BlockInvalidMask)
+			gPersist.AddDirtyBlockIndex(bi)
+		}
+	}
+}
@@ -57,11 +57,12 @@ func ActivateBestChain(pblock *block.Block) error {
 		var nullBlockPtr *block.Block
 		var tmpBlock *block.Block
 		hashA := pindexMostWork.GetBlockHash()
-		newHash := pblock.GetHash()
-		if pblock != nil && bytes.Equal(newHash[:], hashA[:]) {
-			tmpBlock = pblock
-		} else {
-			tmpBlock = nullBlockPtr
+		tmpBlock = nullBlockPtr
+		if pblock != nil {
+			newHash := pblock.GetHash()
+			if bytes.Equal(newHash[:], hashA[:]) {
+				tmpBlock = pblock
+			}
 		}
 
 		if err := ActivateBestChainStep(pindexMostWork, tmpBlock, &fInvalidFound, connTrace); err != nil {
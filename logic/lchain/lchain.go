@@ -33,15 +33,6 @@ import (
 )
 
 func ConnectBlock(pblock *block.Block, pindex *blockindex.BlockIndex, view *utxo.CoinsMap, fJustCheck bool) error {
-	gChain := chain.GetInstance()
-	tip := gChain.Tip()
-	nTimeStart := util.GetMicrosTime()
-	params := gChain.GetParams()
-	// Check it again in case a previous version let a bad lblock in
-	if err := lblock.CheckBlock(pblock); err != nil {
-		return err
-	}
-
 	// Verify that the view's current state corresponds to the previous lblock
 	var hashPrevBlock *util.Hash
 	if pindex.Prev == nil {
@@ -57,14 +48,77 @@ func ConnectBlock(pblock *block.Block, pindex *blockindex.BlockIndex, view *utxo
 		panic("error: hashPrevBlock not equal view.GetBestBlock()")
 	}
 
+	blockUndo, err := connectBlockTransactions(pblock, pindex, view)
+	if err != nil {
+		return err
+	}
 	// Special case for the genesis lblock, skipping connection of its
 	// transactions (its coinbase is unspendable)
+	if blockUndo == nil || fJustCheck {
+		return nil
+	}
+	params := chain.GetInstance().GetParams()
+	gPersist := persist.GetInstance()
+	// Write undo information to disk
+	UndoPos := pindex.GetUndoPos()
+	if UndoPos.IsNull() || !pindex.IsValid(blockindex.BlockValidScripts) {
+		if UndoPos.IsNull() {
+			pos := block.NewDiskBlockPos(pindex.File, 0)
+			//blockUndo size + hash size + 4bytes len
+			if err := disk.FindUndoPos(pindex.File, pos, blockUndo.SerializeSize()+36); err != nil {
+				return err
+			}
+			if err := disk.UndoWriteToDisk(blockUndo, pos, *pindex.Prev.GetBlockHash(), params.BitcoinNet); err != nil {
+				return err
+			}
+
+			// update nUndoPos in block index
+			pindex.UndoPos = pos.Pos
+			pindex.AddStatus(blockindex.BlockHaveUndo)
+		}
+		pindex.RaiseValidity(blockindex.BlockValidScripts)
+		gPersist.AddDirtyBlockIndex(pindex)
+	}
+	if TxIndexEnabled() {
+		if err := WriteBlockTxIndex(pblock, pindex); err != nil {
+			return err
+		}
+	}
+	if AddressIndexEnabled() {
+		if err := WriteBlockAddressIndex(pblock, pindex, blockUndo); err != nil {
+			return err
+		}
+	}
+	if SpentIndexEnabled() {
+		if err := WriteBlockSpentIndex(pblock, pindex); err != nil {
+			return err
+		}
+	}
+	//if (pindex.IsReplayProtectionEnabled(params) &&
+	//	!pindex.Prev.IsReplayProtectionEnabled(params)) {
+	//	lmempool.clear();
+	//}
+
+	log.Debug("Connect block heigh:%d, hash:%s", pindex.Height, pindex.GetBlockHash().String())
+	return nil
+}
+
+// connectBlockTransactions checks pblock and spends and adds the coins of its
+// transactions in view, which is expected to be at the state of the parent of
+// pindex. It returns the undo data of the block, nil for the genesis block.
+func connectBlockTransactions(pblock *block.Block, pindex *blockindex.BlockIndex, view *utxo.CoinsMap) (*undo.BlockUndo, error) {
+	gChain := chain.GetInstance()
+	tip := gChain.Tip()
+	benchStart := time.Now()
+	params := gChain.GetParams()
+	// Check it again in case a previous version let a bad lblock in
+	if err := lblock.CheckBlock(pblock); err != nil {
+		return nil, err
+	}
+
 	blockHash := pblock.GetHash()
 	if blockHash.IsEqual(params.GenesisHash) {
-		if !fJustCheck {
-			//view.SetBestBlock(*pindex.GetBlockHash())
-		}
-		return nil
+		return nil, nil
 	}
 
 	fScriptChecks := true
@@ -97,11 +151,7 @@ func ConnectBlock(pblock *block.Block, pindex *blockindex.BlockIndex, view *utxo
 		}
 	}
 
-	nTime1 := util.GetMicrosTime()
-	gPersist := persist.GetInstance()
-	gPersist.GlobalTimeCheck += nTime1 - nTimeStart
-	log.Print("bench", "debug", " - Sanity checks: %.2fms [%.2fsn",
-		0.001*float64(nTime1-nTimeStart), float64(gPersist.GlobalTimeCheck)*0.000001)
+	benchStart = benchStage(BenchCheck, benchStart)
 
 	// Do not allow blocks that contain transactions which 'overwrite' older
 	// transactions, unless those are already completely spent. If such
@@ -141,46 +191,41 @@ func ConnectBlock(pblock *block.Block, pindex *blockindex.BlockIndex, view *utxo
 
 	flags := lblock.GetBlockScriptFlags(pindex.Prev)
 	blockSubSidy := lblock.GetBlockSubsidy(pindex.Height, params)
-	nTime2 := util.GetMicrosTime()
-	gPersist.GlobalTimeForks += nTime2 - nTime1
-	log.Print("bench", "debug", " - Fork checks: %.2fms [%.2fs]",
-		0.001*float64(nTime2-nTime1), float64(gPersist.GlobalTimeForks)*0.000001)
+	benchStart = benchStage(BenchForks, benchStart)
 
-	var coinsMap, blockUndo, err = ltx.ApplyBlockTransactions(pblock.Txs, fEnforceBIP30, flags,
+	blockUndo, err := ltx.ApplyBlockTransactions(view, pblock.Txs, fEnforceBIP30, flags,
 		fScriptChecks, blockSubSidy, pindex.Height, consensus.GetMaxBlockSigOpsCount(uint64(pblock.EncodeSize())))
-	if err != nil {
-		return err
-	}
-	// Write undo information to disk
-	UndoPos := pindex.GetUndoPos()
-	if UndoPos.IsNull() || !pindex.IsValid(blockindex.BlockValidScripts) {
-		if UndoPos.IsNull() {
-			pos := block.NewDiskBlockPos(pindex.File, 0)
-			//blockUndo size + hash size + 4bytes len
-			if err := disk.FindUndoPos(pindex.File, pos, blockUndo.SerializeSize()+36); err != nil {
-				return err
-			}
-			if err := disk.UndoWriteToDisk(blockUndo, pos, *pindex.Prev.GetBlockHash(), params.BitcoinNet); err != nil {
-				return err
-			}
+	benchStage(BenchScripts, benchStart)
+	return blockUndo, err
+}
 
-			// update nUndoPos in block index
-			pindex.UndoPos = pos.Pos
-			pindex.AddStatus(blockindex.BlockHaveUndo)
-		}
-		pindex.RaiseValidity(blockindex.BlockValidScripts)
-		gPersist.AddDirtyBlockIndex(pindex)
+// TestBlockValidity checks a block built on the tip of the active chain as if
+// it was connected, without writing anything: the block is applied to an empty
+// coins map reading through to the coins cache, and the map is dropped
+// afterwards. The caller holds persist.CsMain, so the tip stays put meanwhile.
+func TestBlockValidity(pblock *block.Block, indexPrev *blockindex.BlockIndex) error {
+	if indexPrev != chain.GetInstance().Tip() {
+		return errcode.New(errcode.ErrorBlockHeaderNoParent)
 	}
-	// add this block to the view's block chain
-	*view = *coinsMap
-
-	//if (pindex.IsReplayProtectionEnabled(params) &&
-	//	!pindex.Prev.IsReplayProtectionEnabled(params)) {
-	//	lmempool.clear();
-	//}
+	// ConnectBlock panics on coins at another block
+	bestHash, err := utxo.GetUtxoCacheInstance().GetBestBlock()
+	if err != nil || !bestHash.IsEqual(indexPrev.GetBlockHash()) {
+		return errcode.New(errcode.ErrorBlockHeaderNoParent)
+	}
+	index := blockindex.NewBlockIndex(&pblock.Header)
+	index.Prev = indexPrev
+	index.Height = indexPrev.Height + 1
 
-	log.Debug("Connect block heigh:%d, hash:%s", pindex.Height, blockHash.String())
-	return nil
+	if err := lblock.ContextualCheckBlockHeader(&pblock.Header, indexPrev, util.GetAdjustedTime()); err != nil {
+		return err
+	}
+	if err := lblock.CheckBlock(pblock); err != nil {
+		return err
+	}
+	if err := lblock.ContextualCheckBlock(pblock, indexPrev); err != nil {
+		return err
+	}
+	return ConnectBlock(pblock, index, utxo.NewEmptyCoinsMap(), true)
 }
 
 //InvalidBlockFound the found block is invalid
@@ -209,7 +254,7 @@ func ConnectTip(pIndexNew *blockindex.BlockIndex,
 		panic("error: try to connect to inactive chain!!!")
 	}
 	// Read block from disk.
-	nTime1 := time.Now().UnixNano()
+	connectStart := time.Now()
 	if block == nil {
 		blockNew, err := disk.ReadBlockFromDisk(pIndexNew, gChain.GetParams())
 		if !err || blockNew == nil {
@@ -224,12 +269,9 @@ func ConnectTip(pIndexNew *blockindex.BlockIndex,
 	}
 	blockConnecting := block
 	indexHash := blockConnecting.GetHash()
-	// Apply the block atomically to the chain state.
-	nTime2 := time.Now().UnixNano()
-	gPersist := persist.GetInstance()
-	gPersist.GlobalTimeReadFromDisk += nTime2 - nTime1
-	log.Info("Load block from disk: %#v ms total: [%#v s]\n", (nTime2-nTime1)/1000, gPersist.GlobalTimeReadFromDisk/1000000)
+	benchStart := benchStage(BenchLoad, connectStart)
 
+	// Apply the block atomically to the chain state.
 	view := utxo.NewEmptyCoinsMap()
 	err := ConnectBlock(blockConnecting, pIndexNew, view, false)
 	if err != nil {
@@ -238,19 +280,13 @@ func ConnectTip(pIndexNew *blockindex.BlockIndex,
 		return err
 	}
 
-	nTime3 := util.GetMicrosTime()
-	gPersist.GlobalTimeConnectTotal += nTime3 - nTime2
-	log.Debug("Connect total: %.2fms [%.2fs]\n",
-		float64(nTime3-nTime2)*0.001, float64(gPersist.GlobalTimeConnectTotal)*0.000001)
+	benchStart = time.Now()
 	//flushed := view.Flush(indexHash)
 	err = utxo.GetUtxoCacheInstance().UpdateCoins(view, &indexHash)
 	if err != nil {
 		panic("here should be true when view flush state")
 	}
-	nTime4 := util.GetMicrosTime()
-	gPersist.GlobalTimeFlush += nTime4 - nTime3
-	log.Print("bench", "debug", " - Flush: %.2fms [%.2fs]\n",
-		float64(nTime4-nTime3)*0.001, float64(gPersist.GlobalTimeFlush)*0.000001)
+	benchStart = benchStage(BenchFlush, benchStart)
 	// Write the chain state to disk, if necessary.
 	if err := disk.FlushStateToDisk(disk.FlushStateAlways, 0); err != nil {
 		return err
@@ -274,21 +310,13 @@ func ConnectTip(pIndexNew *blockindex.BlockIndex,
 		}
 	}
 
-	nTime5 := util.GetMicrosTime()
-	gPersist.GlobalTimeChainState += nTime5 - nTime4
-	log.Print("bench", "debug", " - Writing chainstate: %.2fms [%.2fs]\n",
-		float64(nTime5-nTime4)*0.001, float64(gPersist.GlobalTimeChainState)*0.000001)
+	benchStart = benchStage(BenchChainState, benchStart)
 	// Remove conflicting transactions from the mempool.;
 	mempool.GetInstance().RemoveTxSelf(blockConnecting.Txs)
 	// Update chainActive & related variables.
 	UpdateTip(pIndexNew)
-	nTime6 := util.GetMicrosTime()
-	gPersist.GlobalTimePostConnect += nTime6 - nTime5
-	gPersist.GlobalTimeTotal += nTime6 - nTime1
-	log.Print("bench", "debug", " - Connect postprocess: %.2fms [%.2fs]\n",
-		float64(nTime6-nTime5)*0.001, float64(gPersist.GlobalTimePostConnect)*0.000001)
-	log.Print("bench", "debug", " - Connect block: %.2fms [%.2fs]\n",
-		float64(nTime6-nTime1)*0.001, float64(gPersist.GlobalTimeTotal)*0.000001)
+	benchStage(BenchPostConnect, benchStart)
+	benchBlock(time.Since(connectStart))
 
 	return nil
 }
@@ -489,7 +517,18 @@ func DisconnectBlock(pblock *block.Block, pindex *blockindex.BlockIndex, view *u
 		return undo.DisconnectFailed
 	}
 
-	return lundo.ApplyBlockUndo(blockUndo, pblock, view)
+	res := lundo.ApplyBlockUndo(blockUndo, pblock, view)
+	if res != undo.DisconnectFailed && AddressIndexEnabled() {
+		if err := EraseBlockAddressIndex(pblock, pindex, blockUndo); err != nil {
+			return undo.DisconnectFailed
+		}
+	}
+	if res != undo.DisconnectFailed && SpentIndexEnabled() {
+		if err := EraseBlockSpentIndex(pblock, pindex); err != nil {
+			return undo.DisconnectFailed
+		}
+	}
+	return res
 }
 
 func InitGenesisChain() error {
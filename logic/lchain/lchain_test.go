@@ -1 +1,60 @@
 package lchain
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/errcode"
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/copernet/copernicus/util"
+)
+
+// TestBlockValidityCoinsBehindTip checks a proposal while the coins cache is
+// still at the parent of the tip, as when a block is being connected: the
+// proposal is refused before any check, ConnectBlock would panic on it.
+func TestBlockValidityCoinsBehindTip(t *testing.T) {
+	path, err := ioutil.TempDir("", "lchain")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	utxo.InitUtxoLruTip(&utxo.UtxoConfig{Do: &db.DBOption{FilePath: path, CacheSize: 1 << 20}})
+	chain.InitGlobalChain()
+
+	genesis := blockindex.NewBlockIndex(&model.ActiveNetParams.GenesisBlock.Header)
+	header := block.BlockHeader{
+		Version:       1,
+		HashPrevBlock: *genesis.GetBlockHash(),
+		Time:          genesis.Header.Time + 600,
+		Bits:          model.ActiveNetParams.PowLimitBits,
+	}
+	tip := blockindex.NewBlockIndex(&header)
+	tip.Height = 1
+	tip.Prev = genesis
+	gChain := chain.GetInstance()
+	gChain.InitLoad(map[util.Hash]*blockindex.BlockIndex{
+		*genesis.GetBlockHash(): genesis,
+		*tip.GetBlockHash():     tip,
+	}, nil)
+	gChain.SetTip(tip)
+
+	if err := utxo.GetUtxoCacheInstance().UpdateCoins(utxo.NewEmptyCoinsMap(), genesis.GetBlockHash()); err != nil {
+		t.Fatal(err)
+	}
+	proposal := block.NewBlock()
+	proposal.Header.HashPrevBlock = *tip.GetBlockHash()
+	err = TestBlockValidity(proposal, tip)
+	if !errcode.IsErrorCode(err, errcode.ErrorBlockHeaderNoParent) {
+		t.Errorf("a proposal was checked against the coins of another block: %v", err)
+	}
+}
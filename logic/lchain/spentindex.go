@@ -0,0 +1,61 @@
+package lchain
+
+import (
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/persist/blkdb"
+)
+
+// SpentIndexEnabled reports whether the node maintains the spent index.
+func SpentIndexEnabled() bool {
+	return conf.Cfg != nil && conf.Cfg.Chain.SpentIndex
+}
+
+// blockSpentIndex returns the outputs spent by the inputs of a block.
+func blockSpentIndex(pblock *block.Block, height int32) map[outpoint.OutPoint]*blkdb.SpentInfo {
+	spent := make(map[outpoint.OutPoint]*blkdb.SpentInfo)
+	for _, transaction := range pblock.Txs {
+		if transaction.IsCoinBase() {
+			continue
+		}
+		txHash := transaction.GetHash()
+		for i, in := range transaction.GetIns() {
+			spent[*in.PreviousOutPoint] = &blkdb.SpentInfo{TxHash: txHash, Index: uint32(i), Height: height}
+		}
+	}
+	return spent
+}
+
+// WriteBlockSpentIndex records the outputs spent by a connected block.
+func WriteBlockSpentIndex(pblock *block.Block, pindex *blockindex.BlockIndex) error {
+	if err := blkdb.GetInstance().UpdateSpentIndex(blockSpentIndex(pblock, pindex.Height), nil); err != nil {
+		log.Error("WriteBlockSpentIndex: write spent index of block %s failed: %v",
+			pindex.GetBlockHash().String(), err)
+		return err
+	}
+	return nil
+}
+
+// EraseBlockSpentIndex forgets the outputs spent by a disconnected block.
+func EraseBlockSpentIndex(pblock *block.Block, pindex *blockindex.BlockIndex) error {
+	spent := blockSpentIndex(pblock, pindex.Height)
+	outs := make([]*outpoint.OutPoint, 0, len(spent))
+	for out := range spent {
+		out := out
+		outs = append(outs, &out)
+	}
+	if err := blkdb.GetInstance().UpdateSpentIndex(nil, outs); err != nil {
+		log.Error("EraseBlockSpentIndex: erase spent index of block %s failed: %v",
+			pindex.GetBlockHash().String(), err)
+		return err
+	}
+	return nil
+}
+
+// InitSpentIndex brings the spent index in line with the configuration.
+func InitSpentIndex() error {
+	return initIndex(blkdb.SpentIndexName, SpentIndexEnabled(), WriteBlockSpentIndex)
+}
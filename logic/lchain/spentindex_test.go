@@ -0,0 +1,33 @@
+package lchain
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/util"
+)
+
+func TestBlockSpentIndex(t *testing.T) {
+	coinbase := tx.NewTx(0, tx.TxVersion)
+	coinbase.AddTxIn(txin.NewTxIn(nil, script.NewScriptRaw([]byte{opcodes.OP_0}), script.SequenceFinal))
+	spend := tx.NewTx(0, tx.TxVersion)
+	for i := uint32(0); i < 2; i++ {
+		spend.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.HashOne, i), script.NewEmptyScript(), script.SequenceFinal))
+	}
+	blk := block.NewBlock()
+	blk.Txs = []*tx.Tx{coinbase, spend}
+
+	spent := blockSpentIndex(blk, 7)
+	if len(spent) != 2 {
+		t.Fatalf("the coinbase input should not be indexed, got %d entries", len(spent))
+	}
+	info := spent[*outpoint.NewOutPoint(util.HashOne, 1)]
+	if info == nil || info.TxHash != spend.GetHash() || info.Index != 1 || info.Height != 7 {
+		t.Errorf("unexpected spent info %v", info)
+	}
+}
@@ -0,0 +1,45 @@
+package lchain
+
+import (
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/persist/blkdb"
+	"github.com/copernet/copernicus/util"
+)
+
+// TxIndexEnabled reports whether the node maintains the transaction index.
+func TxIndexEnabled() bool {
+	return conf.Cfg != nil && conf.Cfg.Chain.TxIndex
+}
+
+// BlockTxIndex returns the disk position of every transaction of a block
+// stored at pindex's block position. Offsets count from the end of the block
+// header, the way disk.ReadTxFromDisk reads them.
+func BlockTxIndex(pblock *block.Block, pindex *blockindex.BlockIndex) map[util.Hash]block.DiskTxPos {
+	blockPos := pindex.GetBlockPos()
+	offset := util.VarIntSerializeSize(uint64(len(pblock.Txs)))
+	txIndexes := make(map[util.Hash]block.DiskTxPos, len(pblock.Txs))
+	for _, transaction := range pblock.Txs {
+		pos := blockPos
+		txIndexes[transaction.GetHash()] = *block.NewDiskTxPos(&pos, offset)
+		offset += transaction.SerializeSize()
+	}
+	return txIndexes
+}
+
+// WriteBlockTxIndex adds the transactions of a connected block to the index.
+func WriteBlockTxIndex(pblock *block.Block, pindex *blockindex.BlockIndex) error {
+	if err := blkdb.GetInstance().WriteTxIndex(BlockTxIndex(pblock, pindex)); err != nil {
+		log.Error("WriteBlockTxIndex: write tx index of block %s failed: %v",
+			pindex.GetBlockHash().String(), err)
+		return err
+	}
+	return nil
+}
+
+// InitTxIndex brings the transaction index in line with the configuration.
+func InitTxIndex() error {
+	return initIndex(blkdb.TxIndexName, TxIndexEnabled(), WriteBlockTxIndex)
+}
@@ -0,0 +1,139 @@
+package lchain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/lblock"
+	"github.com/copernet/copernicus/logic/lundo"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/undo"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/persist/disk"
+)
+
+const (
+	// DefaultCheckLevel is how thoroughly VerifyDB checks blocks by default.
+	DefaultCheckLevel = 3
+	// DefaultCheckBlocks is how many blocks VerifyDB checks by default.
+	DefaultCheckBlocks = 288
+	// MaxCheckLevel is the most thorough check level of VerifyDB.
+	MaxCheckLevel = 4
+
+	// maxVerifyCoins bounds the coins held in memory while rewinding the
+	// chain at check level 3, older blocks are only checked up to level 2.
+	maxVerifyCoins = 1 << 21
+)
+
+// ErrVerifyInterrupted is returned by VerifyDB when it was interrupted.
+var ErrVerifyInterrupted = errors.New("verification interrupted")
+
+// VerifyDB checks the last checkDepth blocks of the active chain, all of them
+// when checkDepth is not positive, as thoroughly as checkLevel asks:
+//
+//	0: the blocks can be read from disk
+//	1: the blocks are valid by themselves
+//	2: the undo data of the blocks can be read from disk
+//	3: the blocks disconnect cleanly from the UTXO set with their undo data
+//	4: the disconnected blocks connect again, validating their transactions
+//
+// Disconnecting and connecting the blocks is done in memory on a coins map
+// stacked on the UTXO set, which is left untouched.
+func VerifyDB(checkLevel, checkDepth int32, interrupt <-chan struct{}) error {
+	gChain := chain.GetInstance()
+	tip := gChain.Tip()
+	if tip == nil || tip.Prev == nil {
+		return nil
+	}
+	if checkLevel < 0 {
+		checkLevel = 0
+	} else if checkLevel > MaxCheckLevel {
+		checkLevel = MaxCheckLevel
+	}
+	if checkDepth <= 0 || checkDepth > tip.Height {
+		checkDepth = tip.Height
+	}
+	log.Info("Verifying last %d blocks at level %d", checkDepth, checkLevel)
+
+	params := gChain.GetParams()
+	coins := utxo.NewEmptyCoinsMap()
+	indexState := tip
+	var indexFailure *blockindex.BlockIndex
+	goodTransactions := 0
+	for index := tip; index != nil && index.Prev != nil; index = index.Prev {
+		if index.Height <= tip.Height-checkDepth {
+			break
+		}
+		select {
+		case <-interrupt:
+			return ErrVerifyInterrupted
+		default:
+		}
+		if disk.GetPruneState().HavePruned && !index.HasData() {
+			// the blocks below were pruned, stop here
+			log.Info("VerifyDB: block data pruned below height %d", index.Height)
+			break
+		}
+
+		blk, ok := disk.ReadBlockFromDisk(index, params)
+		if !ok {
+			return fmt.Errorf("*** ReadBlockFromDisk failed at %d, hash=%s", index.Height, index.GetBlockHash())
+		}
+		if checkLevel >= 1 {
+			if err := lblock.CheckBlock(blk); err != nil {
+				return fmt.Errorf("*** found bad block at %d, hash=%s (%v)", index.Height, index.GetBlockHash(), err)
+			}
+		}
+		var blockUndo *undo.BlockUndo
+		if checkLevel >= 2 {
+			pos := index.GetUndoPos()
+			if !pos.IsNull() {
+				blockUndo, ok = disk.UndoReadFromDisk(&pos, *index.Prev.GetBlockHash())
+				if !ok {
+					return fmt.Errorf("*** found bad undo data at %d, hash=%s", index.Height, index.GetBlockHash())
+				}
+			}
+		}
+		if checkLevel >= 3 && index == indexState && blockUndo != nil && len(coins.GetMap()) <= maxVerifyCoins {
+			res := lundo.ApplyBlockUndo(blockUndo, blk, coins)
+			if res == undo.DisconnectFailed {
+				return fmt.Errorf("*** irrecoverable inconsistency in block data at %d, hash=%s",
+					index.Height, index.GetBlockHash())
+			}
+			indexState = index.Prev
+			if res == undo.DisconnectUnclean {
+				goodTransactions = 0
+				indexFailure = index
+			} else {
+				goodTransactions += len(blk.Txs)
+			}
+		}
+	}
+	if indexFailure != nil {
+		return fmt.Errorf("*** coin database inconsistencies found (last %d blocks, %d good transactions before that)",
+			tip.Height-indexFailure.Height+1, goodTransactions)
+	}
+
+	if checkLevel >= 4 {
+		for index := indexState; index != tip; {
+			select {
+			case <-interrupt:
+				return ErrVerifyInterrupted
+			default:
+			}
+			index = gChain.Next(index)
+			blk, ok := disk.ReadBlockFromDisk(index, params)
+			if !ok {
+				return fmt.Errorf("*** ReadBlockFromDisk failed at %d, hash=%s", index.Height, index.GetBlockHash())
+			}
+			if _, err := connectBlockTransactions(blk, index, coins); err != nil {
+				return fmt.Errorf("*** found unconnectable block at %d, hash=%s (%v)", index.Height, index.GetBlockHash(), err)
+			}
+		}
+	}
+
+	log.Info("No coin database inconsistencies in last %d blocks (%d transactions)", tip.Height-indexState.Height, goodTransactions)
+	return nil
+}
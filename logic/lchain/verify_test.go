@@ -0,0 +1,132 @@
+package lchain
+
+import (
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/logic/lmerkleroot"
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/pow"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/persist"
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/copernet/copernicus/persist/disk"
+	"github.com/copernet/copernicus/util"
+)
+
+func TestVerifyDB(t *testing.T) {
+	path, err := ioutil.TempDir("", "verifydb")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	defer func(dataDir string) { conf.Cfg.DataDir = dataDir }(conf.Cfg.DataDir)
+	conf.Cfg.DataDir = path
+	utxo.InitUtxoLruTip(&utxo.UtxoConfig{Do: &db.DBOption{FilePath: path, CacheSize: 1 << 20}})
+	chain.InitGlobalChain()
+	persist.InitPersistGlobal()
+
+	// the blocks are read back from disk with their proof of work checked,
+	// let them be mined at the regtest limit
+	gChain := chain.GetInstance()
+	params := gChain.GetParams()
+	defer func(powLimit *big.Int) { params.PowLimit = powLimit }(params.PowLimit)
+	params.PowLimit = model.RegressionNetParams.PowLimit
+
+	genesis := blockindex.NewBlockIndex(&params.GenesisBlock.Header)
+	storeBlock := func(badMerkleRoot bool, pos uint32) *blockindex.BlockIndex {
+		coinbase := tx.NewTx(0, tx.TxVersion)
+		coinbase.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.Hash{}, math.MaxUint32),
+			script.NewScriptRaw([]byte{opcodes.OP_1, opcodes.OP_1}), script.SequenceFinal))
+		coinbase.AddTxOut(txout.NewTxOut(5000000000, script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+		blk := block.NewBlock()
+		blk.Txs = []*tx.Tx{coinbase}
+		blk.Header = block.BlockHeader{
+			Version:       1,
+			HashPrevBlock: *genesis.GetBlockHash(),
+			MerkleRoot:    lmerkleroot.BlockMerkleRoot(blk.Txs, nil),
+			Time:          genesis.Header.Time + 600,
+			Bits:          0x207fffff,
+		}
+		if badMerkleRoot {
+			blk.Header.MerkleRoot = util.HashOne
+		}
+		for hash := blk.GetHash(); !new(pow.Pow).CheckProofOfWork(&hash, blk.Header.Bits, params); hash = blk.GetHash() {
+			blk.Header.Nonce++
+		}
+		if !disk.WriteBlockToDisk(blk, block.NewDiskBlockPos(0, pos)) {
+			t.Fatal("write block to disk failed")
+		}
+		index := blockindex.NewBlockIndex(&blk.Header)
+		index.Height = 1
+		index.Prev = genesis
+		index.File = 0
+		index.DataPos = pos
+		index.AddStatus(blockindex.BlockHaveData)
+		return index
+	}
+	good := storeBlock(false, 0)
+	bad := storeBlock(true, 4096)
+	// an index of a block which is not where its data is said to be
+	missing := blockindex.NewBlockIndex(&good.Header)
+	missing.Header.Nonce++
+	missing.Height = 1
+	missing.Prev = genesis
+	missing.DataPos = 1 << 20
+	missing.AddStatus(blockindex.BlockHaveData)
+	gChain.InitLoad(map[util.Hash]*blockindex.BlockIndex{
+		*genesis.GetBlockHash(): genesis,
+		*good.GetBlockHash():    good,
+		*bad.GetBlockHash():     bad,
+		*missing.GetBlockHash(): missing,
+	}, nil)
+
+	interrupted := make(chan struct{})
+	close(interrupted)
+	tests := []struct {
+		name       string
+		tip        *blockindex.BlockIndex
+		checkLevel int32
+		interrupt  <-chan struct{}
+		errText    string
+	}{
+		{"genesis", genesis, MaxCheckLevel, nil, ""},
+		{"good block", good, MaxCheckLevel, nil, ""},
+		{"good block beyond the max level", good, MaxCheckLevel + 1, nil, ""},
+		{"interrupted", good, MaxCheckLevel, interrupted, ErrVerifyInterrupted.Error()},
+		{"bad block read", bad, 0, nil, ""},
+		{"bad block checked", bad, 1, nil, "found bad block"},
+		{"missing block", missing, 0, nil, "ReadBlockFromDisk failed"},
+		{"missing block below level 0", missing, -1, nil, "ReadBlockFromDisk failed"},
+	}
+	for _, test := range tests {
+		gChain.SetTip(test.tip)
+		err := VerifyDB(test.checkLevel, DefaultCheckBlocks, test.interrupt)
+		if test.errText == "" {
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.errText) {
+			t.Errorf("%s: got error %v, expect %q", test.name, err, test.errText)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package lchain
+
+import (
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/versionbits"
+)
+
+// More than versionWarningThreshold of the last versionWarningWindow blocks
+// have to signal unknown version bits for GetWarnings to warn about them.
+const (
+	versionWarningWindow    = 100
+	versionWarningThreshold = versionWarningWindow / 2
+)
+
+// GetWarnings returns a warning when many recent blocks have a version this
+// node does not expect, which means rules it does not know may be in effect.
+func GetWarnings() string {
+	params := chain.GetInstance().GetParams()
+	upgraded := 0
+	index := chain.GetInstance().Tip()
+	for i := 0; i < versionWarningWindow && index != nil && index.Prev != nil; i++ {
+		expected := versionbits.ComputeBlockVersion(index.Prev, params, versionbits.VBCache)
+		if index.Header.Version > versionbits.VersionBitsLastOldBlockVersion &&
+			int(index.Header.Version)&^expected != 0 {
+			upgraded++
+		}
+		index = index.Prev
+	}
+	if upgraded > versionWarningThreshold {
+		return "Warning: Unknown block versions being mined! It's possible unknown rules are in effect"
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package lmempool
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/mempool"
+)
+
+// FeeEstimatesFileName is the file the fee estimator is persisted to in the
+// data dir.
+const FeeEstimatesFileName = "fee_estimates.dat"
+
+// LoadFeeEstimator restores the fee estimator saved to path by
+// SaveFeeEstimator. A new fee estimator is returned when there is no file or
+// it cannot be restored, estimates are then available again after a few
+// blocks.
+func LoadFeeEstimator(path string) *mempool.FeeEstimator {
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		var ef *mempool.FeeEstimator
+		ef, err = mempool.RestoreFeeEstimator(data)
+		if err == nil {
+			log.Info("Loaded fee estimates from %s", path)
+			return ef
+		}
+	}
+	if !os.IsNotExist(err) {
+		log.Warn("Failed to restore fee estimates, starting over: %v", err)
+	}
+	return mempool.NewFeeEstimator(mempool.DefaultEstimateFeeMaxRollback,
+		mempool.DefaultEstimateFeeMinRegisteredBlocks)
+}
+
+// SaveFeeEstimator writes the state of ef to path. The file is written aside
+// and renamed like DumpMempool does.
+func SaveFeeEstimator(ef *mempool.FeeEstimator, path string) error {
+	tmpPath := path + ".new"
+	if err := ioutil.WriteFile(tmpPath, ef.Save(), 0600); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/logic/ltx"
+	"github.com/copernet/copernicus/logic/lutxo"
 	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/model/consensus"
 	"github.com/copernet/copernicus/model/mempool"
@@ -20,6 +21,19 @@ import (
 
 // AcceptTxToMemPool add one check corret transaction to mempool.
 func AcceptTxToMemPool(tx *tx.Tx) error {
+	return acceptTxToMemPool(tx, util.GetTime(), 0)
+}
+
+// AcceptTxToMemPoolWithAbsurdFee is AcceptTxToMemPool rejecting a transaction
+// paying a fee over absurdFee, zero allowing any fee. The fee is computed
+// under the mempool lock, from the inputs the transaction is accepted with.
+func AcceptTxToMemPoolWithAbsurdFee(tx *tx.Tx, absurdFee int64) error {
+	return acceptTxToMemPool(tx, util.GetTime(), absurdFee)
+}
+
+// acceptTxToMemPool is AcceptTxToMemPool recording the given entry time,
+// which differs from now when reloading a saved mempool.
+func acceptTxToMemPool(tx *tx.Tx, acceptTime int64, absurdFee int64) error {
 
 	//first : check whether enter mempool.
 	pool := mempool.GetInstance()
@@ -41,7 +55,8 @@ func AcceptTxToMemPool(tx *tx.Tx) error {
 				spendCoinbase = true
 			}
 		} else {
-			if coin := pool.GetCoin(&preout); coin != nil {
+			// the pool lock is held, GetCoin would block on it forever
+			if coin := pool.GetCoinWithoutLock(&preout); coin != nil {
 				coins[i] = coin
 				inputValue += int64(coin.GetAmount())
 				if coin.IsCoinBase() {
@@ -55,13 +70,19 @@ func AcceptTxToMemPool(tx *tx.Tx) error {
 		}
 	}
 	txfee = inputValue - int64(tx.GetValueOut())
-	ancestors, lp, err := isTxAcceptable(tx, txfee)
+	feeDelta := pool.GetFeeDeltaWithoutLock(tx.GetHash())
+	ancestors, lp, err := isTxAcceptable(tx, txfee+feeDelta)
 	if err != nil {
 		return err
 	}
+	if absurdFee != 0 && txfee > absurdFee {
+		log.Debug("transaction %s pays an absurdly high fee: %d > %d", tx.GetHash(), txfee, absurdFee)
+		return errcode.New(errcode.AbsurdFee)
+	}
 	//second : add transaction to mempool.
-	txentry := mempool.NewTxentry(tx, txfee, util.GetTime(), gChain.Height(), *lp,
+	txentry := mempool.NewTxentry(tx, txfee, acceptTime, gChain.Height(), *lp,
 		tx.GetSigOpCountWithoutP2SH(), spendCoinbase)
+	txentry.UpdateFeeDelta(feeDelta)
 	pool.AddTx(txentry, ancestors)
 
 	return nil
@@ -100,10 +121,15 @@ func ProcessOrphan(transaction *tx.Tx) []*tx.Tx {
 					break
 				}
 
+				// nothing was found wrong with an orphan whose check timed
+				// out, keep it and the orphans spending it
+				if errcode.IsTxCheckTimeout(err) {
+					break
+				}
 				if !errcode.IsErrorCode(err, errcode.TxErrNoPreviousOut) {
 					pool.EraseOrphanTx(iOrphanTx.Tx.GetHash(), true)
 					if errcode.IsErrorCode(err, errcode.RejectTx) {
-						pool.RecentRejects[iOrphanTx.Tx.GetHash()] = struct{}{}
+						AddRecentReject(iOrphanTx.Tx.GetHash())
 					}
 					break
 				}
@@ -172,9 +198,8 @@ func RemoveForReorg(nMemPoolHeight int32, flag int) {
 	txToRemove := make(map[*mempool.TxEntry]struct{})
 	allEntry := pool.GetAllTxEntryWithoutLock()
 	for _, entry := range allEntry {
-		//lp := entry.GetLockPointFromTxEntry()
-		//validLP := entry.CheckLockPointValidity(gChain)
-		//state := NewValidationState()
+		lp := entry.GetLockPointFromTxEntry()
+		validLP := entry.CheckLockPointValidity(chain.GetInstance())
 
 		tx := entry.Tx
 		allPreout := tx.GetAllPreviousOut()
@@ -183,7 +208,8 @@ func RemoveForReorg(nMemPoolHeight int32, flag int) {
 			if coin := view.GetCoin(&preout); coin != nil {
 				coins[i] = coin
 			} else {
-				if coin := pool.GetCoin(&preout); coin != nil {
+				// the pool lock is held, GetCoin would block on it forever
+				if coin := pool.GetCoinWithoutLock(&preout); coin != nil {
 					coins[i] = coin
 				} else {
 					panic("the transaction in mempool, not found its parent " +
@@ -191,9 +217,15 @@ func RemoveForReorg(nMemPoolHeight int32, flag int) {
 				}
 			}
 		}
-		tlp := ltx.CalculateLockPoints(tx, uint32(flag))
-		if tlp == nil {
-			panic("nil lockpoint, the transaction has no preout")
+		// Lock points cached at acceptance time remain usable while the block
+		// holding their highest input is still active; otherwise the inputs
+		// may have moved and the locks must be evaluated again.
+		tlp := &lp
+		if !validLP {
+			tlp = ltx.CalculateLockPoints(tx, uint32(flag))
+			if tlp == nil {
+				panic("nil lockpoint, the transaction has no preout")
+			}
 		}
 		if ltx.ContextualCheckTransactionForCurrentBlock(tx, flag) != nil ||
 			!ltx.CheckSequenceLocks(tlp.Height, tlp.Time) {
@@ -211,17 +243,16 @@ func RemoveForReorg(nMemPoolHeight int32, flag int) {
 					}
 				}
 
-				if coin.IsSpent() || (coin.IsCoinBase() &&
-					nMemPoolHeight-coin.GetHeight() < consensus.CoinbaseMaturity) {
+				if coin.IsSpent() || !lutxo.IsCoinMature(coin, nMemPoolHeight) {
 					txToRemove[entry] = struct{}{}
 					break
 				}
 			}
 		}
 
-		//if !validLP {
-		entry.SetLockPointFromTxEntry(*tlp)
-		//}
+		if !validLP {
+			entry.SetLockPointFromTxEntry(*tlp)
+		}
 	}
 
 	allRemoves := make(map[*mempool.TxEntry]struct{})
@@ -299,11 +330,11 @@ func CheckMempool() {
 		nCountCheck := int64(len(setAncestors)) + 1
 		nSizeCheck := int64(entry.TxSize)
 		nSigOpCheck := int64(entry.SigOpCount)
-		nFeesCheck := entry.TxFee
+		nFeesCheck := entry.GetModifiedFee()
 		for ancestorIt := range setAncestors {
 			nSizeCheck += int64(ancestorIt.TxSize)
 			nSigOpCheck += int64(ancestorIt.SigOpCount)
-			nFeesCheck += ancestorIt.TxFee
+			nFeesCheck += ancestorIt.GetModifiedFee()
 		}
 		if entry.SumTxCountWithAncestors != nCountCheck {
 			panic("the txentry's ancestors number is incorrect .")
@@ -360,7 +391,9 @@ func CheckMempool() {
 			isCoinBase := entry.Tx.IsCoinBase()
 			for i := 0; i < entry.Tx.GetOutsCount(); i++ {
 				a := outpoint.OutPoint{Hash: entry.Tx.GetHash(), Index: uint32(i)}
-				mempoolDuplicate.AddCoin(&a, utxo.NewCoin(entry.Tx.GetTxOut(i), 1000000, isCoinBase), isCoinBase)
+				if err := mempoolDuplicate.AddCoin(&a, utxo.NewCoin(entry.Tx.GetTxOut(i), 1000000, isCoinBase), isCoinBase); err != nil {
+					panic("the mempool transaction overwrites an unspent coin...")
+				}
 			}
 		}
 	}
@@ -403,7 +436,9 @@ func CheckMempool() {
 			isCoinBase := entry.Tx.IsCoinBase()
 			for i := 0; i < entry.Tx.GetOutsCount(); i++ {
 				a := outpoint.OutPoint{Hash: entry.Tx.GetHash(), Index: uint32(i)}
-				mempoolDuplicate.AddCoin(&a, utxo.NewCoin(entry.Tx.GetTxOut(i), 1000000, isCoinBase), isCoinBase)
+				if err := mempoolDuplicate.AddCoin(&a, utxo.NewCoin(entry.Tx.GetTxOut(i), 1000000, isCoinBase), isCoinBase); err != nil {
+					panic("the mempool transaction overwrites an unspent coin...")
+				}
 			}
 			stepsSinceLastRemove = 0
 		}
@@ -441,12 +476,21 @@ func FindOrphanTxInMemPool(hash util.Hash) *tx.Tx {
 	return nil
 }
 
+// FindRejectTxInMempool returns whether the transaction was rejected since
+// the tip of the active chain last changed.
 func FindRejectTxInMempool(hash util.Hash) bool {
-	pool := mempool.GetInstance()
-	pool.RLock()
-	defer pool.RUnlock()
-	if _, ok := pool.RecentRejects[hash]; ok {
-		return ok
+	return mempool.GetInstance().HaveRecentReject(hash, activeTipHash())
+}
+
+// AddRecentReject remembers a transaction rejected by policy, so that it is
+// not validated again until the tip of the active chain changes.
+func AddRecentReject(hash util.Hash) {
+	mempool.GetInstance().AddRecentReject(hash, activeTipHash())
+}
+
+func activeTipHash() util.Hash {
+	if tip := chain.GetInstance().Tip(); tip != nil {
+		return *tip.GetBlockHash()
 	}
-	return false
+	return util.Hash{}
 }
@@ -0,0 +1,181 @@
+package lmempool
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/ltx"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/util"
+)
+
+// MempoolFileName is the file the mempool is persisted to in the data dir.
+const MempoolFileName = "mempool.dat"
+
+// ErrMempoolNotLoaded is returned by SaveMempool before LoadMempool finished.
+var ErrMempoolNotLoaded = errors.New("the mempool was not loaded yet")
+
+// warmMempoolTimeout bounds the fetch of the transactions of another node by
+// WarmMempool, the startup waits for it.
+const warmMempoolTimeout = 5 * time.Minute
+
+// mempoolLoaded is set once LoadMempool succeeded, until then saving the
+// mempool would overwrite mempool.dat with a partial mempool.
+var mempoolLoaded int32
+
+// IsMempoolLoaded reports whether the mempool saved on disk was loaded.
+func IsMempoolLoaded() bool {
+	return atomic.LoadInt32(&mempoolLoaded) != 0
+}
+
+// SaveMempool dumps the mempool to path like DumpMempool, once the mempool
+// was loaded.
+func SaveMempool(path string) error {
+	if !IsMempoolLoaded() {
+		return ErrMempoolNotLoaded
+	}
+	return DumpMempool(path)
+}
+
+// DumpMempool writes the mempool, its fee deltas and unbroadcast set to path.
+// The file is written aside and renamed, so a crash never leaves a truncated
+// mempool.dat behind.
+func DumpMempool(path string) error {
+	nStart := util.GetMockTimeInMicros()
+	d := mempool.GetInstance().Dump()
+
+	tmpPath := path + ".new"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	if err = d.Serialize(w); err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	log.Info("Dumped mempool: %d transactions, %d deltas, %d unbroadcast, %.3fs",
+		len(d.Txs), len(d.Deltas), len(d.Unbroadcast),
+		float64(util.GetMockTimeInMicros()-nStart)/1000000)
+	return nil
+}
+
+// acceptSavedTx checks a transaction read back from a dump as one relayed by
+// a peer, then accepts it with its original entry time.
+func acceptSavedTx(transaction *tx.Tx, acceptTime int64) error {
+	if err := ltx.CheckRegularTransaction(transaction); err != nil {
+		return err
+	}
+	return acceptTxToMemPool(transaction, acceptTime, 0)
+}
+
+// LoadMempool re-accepts the transactions saved by DumpMempool with their
+// original entry time and restores the fee deltas and the unbroadcast set.
+// The file may be stale or tampered with, so the transactions are validated
+// again. A missing file is not an error.
+func LoadMempool(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		atomic.StoreInt32(&mempoolLoaded, 1)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	d := mempool.MempoolDump{}
+	if err := d.Unserialize(bufio.NewReader(file)); err != nil {
+		return err
+	}
+
+	pool := mempool.GetInstance()
+	expiry := int64(conf.Cfg.Mempool.MaxPoolExpiry) * 60 * 60
+	now := util.GetTime()
+	accepted, failed, expired := 0, 0, 0
+	for _, dtx := range d.Txs {
+		if dtx.FeeDelta != 0 {
+			pool.PrioritiseTransaction(dtx.Tx.GetHash(), dtx.FeeDelta)
+		}
+		if expiry > 0 && dtx.Time+expiry < now {
+			expired++
+			continue
+		}
+		if err := acceptSavedTx(dtx.Tx, dtx.Time); err != nil {
+			failed++
+			continue
+		}
+		accepted++
+	}
+	for hash, delta := range d.Deltas {
+		pool.PrioritiseTransaction(hash, delta)
+	}
+	for hash := range d.Unbroadcast {
+		pool.AddUnbroadcastTx(hash)
+	}
+
+	log.Info("Imported mempool transactions from disk: %d succeeded, %d failed, %d expired",
+		accepted, failed, expired)
+	atomic.StoreInt32(&mempoolLoaded, 1)
+	return nil
+}
+
+// ImportRawTxs accepts the transactions of a stream written by
+// MempoolDump.WriteRawTxs, typically fetched from a trusted node, keeping
+// their entry time. The fee deltas of the stream are ignored, prioritisation
+// stays local. Transactions failing validation are counted and skipped, a
+// malformed stream aborts the import.
+func ImportRawTxs(r io.Reader) (accepted, failed int, err error) {
+	pool := mempool.GetInstance()
+	err = mempool.ReadRawTxs(r, func(dtx *mempool.DumpedTx) error {
+		if pool.FindTx(dtx.Tx.GetHash()) != nil {
+			return nil
+		}
+		if acceptSavedTx(dtx.Tx, dtx.Time) != nil {
+			failed++
+			return nil
+		}
+		accepted++
+		return nil
+	})
+	log.Info("Imported raw mempool transactions: %d succeeded, %d failed", accepted, failed)
+	return accepted, failed, err
+}
+
+// WarmMempool fetches the raw transactions stream of a trusted node at url,
+// its /mempool/rawtxs path with the RPC credentials as user info, and imports
+// the transactions.
+func WarmMempool(url string) (accepted, failed int, err error) {
+	client := http.Client{Timeout: warmMempoolTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("fetching the mempool transactions failed: %s", resp.Status)
+	}
+	return ImportRawTxs(bufio.NewReader(resp.Body))
+}
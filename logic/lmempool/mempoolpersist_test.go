@@ -0,0 +1,86 @@
+package lmempool
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/util"
+)
+
+func serveStream(stream []byte, status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write(stream)
+	}))
+}
+
+func TestWarmMempool(t *testing.T) {
+	mempool.InitMempool()
+	pool := mempool.GetInstance()
+
+	transaction := tx.NewTx(0, tx.TxVersion)
+	transaction.AddTxIn(txin.NewTxIn(&outpoint.OutPoint{Hash: util.HashOne, Index: 0},
+		script.NewScriptRaw([]byte{opcodes.OP_11}), script.SequenceFinal))
+	transaction.AddTxOut(txout.NewTxOut(10000, script.NewScriptRaw([]byte{opcodes.OP_11, opcodes.OP_EQUAL})))
+	entry := mempool.NewTxentry(transaction, 1000, 100, 1, mempool.LockPoints{}, 0, false)
+	if err := pool.AddTx(entry, map[*mempool.TxEntry]struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if err := pool.Dump().WriteRawTxs(buf); err != nil {
+		t.Fatal(err)
+	}
+	stream := buf.Bytes()
+
+	tests := []struct {
+		name    string
+		stream  []byte
+		status  int
+		wantErr bool
+	}{
+		{"known transactions are skipped", stream, http.StatusOK, false},
+		{"empty stream", []byte{0}, http.StatusOK, false},
+		{"unauthorized", nil, http.StatusUnauthorized, true},
+		{"truncated stream", stream[:len(stream)-1], http.StatusOK, true},
+	}
+	for _, test := range tests {
+		server := serveStream(test.stream, test.status)
+		accepted, failed, err := WarmMempool(server.URL)
+		server.Close()
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: error %v, expect error %t", test.name, err, test.wantErr)
+		}
+		if accepted != 0 || failed != 0 {
+			t.Errorf("%s: %d accepted, %d failed, expect none", test.name, accepted, failed)
+		}
+	}
+	if pool.Size() != 1 || pool.GetFeeDeltas()[transaction.GetHash()] != 0 {
+		t.Errorf("warming changed the transactions already in the mempool")
+	}
+
+	invalid := tx.NewTx(0, tx.TxVersion)
+	invalid.AddTxIn(txin.NewTxIn(&outpoint.OutPoint{Hash: util.HashOne, Index: 1},
+		script.NewScriptRaw([]byte{opcodes.OP_11}), script.SequenceFinal))
+	d := mempool.MempoolDump{Txs: []mempool.DumpedTx{{Tx: invalid, Time: 1, FeeDelta: 5000}}}
+	buf.Reset()
+	if err := d.WriteRawTxs(buf); err != nil {
+		t.Fatal(err)
+	}
+	accepted, failed, err := ImportRawTxs(buf)
+	if err != nil || accepted != 0 || failed != 1 {
+		t.Errorf("a transaction without outputs: %d accepted, %d failed, error %v, expect it to fail",
+			accepted, failed, err)
+	}
+	if _, ok := pool.GetFeeDeltas()[invalid.GetHash()]; ok {
+		t.Errorf("the fee delta of the warm source was applied")
+	}
+}
@@ -27,10 +27,11 @@ func NewMerkleBlock(bk *block.Block, txids *set.Set) *MerkleBlock {
 	match := make([]bool, 0, len(bk.Txs))
 	hashes := make([]util.Hash, 0, len(bk.Txs))
 
-	for _, transaction := range bk.Txs {
+	for i, transaction := range bk.Txs {
 		txid := transaction.GetHash()
 		if txids.Has(txid) {
 			match = append(match, true)
+			ret.MatchedTxn = append(ret.MatchedTxn, Matched{N: i, H: txid})
 		} else {
 			match = append(match, false)
 		}
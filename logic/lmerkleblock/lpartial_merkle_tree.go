@@ -2,9 +2,10 @@ package lmerkleblock
 
 import (
 	"crypto/sha256"
+	"errors"
 	"io"
-	"math"
 
+	"github.com/copernet/copernicus/model/block"
 	"github.com/copernet/copernicus/util"
 )
 
@@ -95,7 +96,9 @@ func (pmt *PartialMerkleTree) ExtractMatches(matches *[]util.Hash, items *[]int)
 		return &util.Hash{}
 	}
 	// Check for excessively high numbers of transactions.
-	// FIXME: Track the maximum block size we've seen and use it here.
+	if uint64(pmt.txs) > block.GetMaxTxCount() {
+		return &util.Hash{}
+	}
 
 	// There can never be more hashes provided than one for every txid.
 	if len(pmt.hashes) > pmt.txs {
@@ -191,8 +194,11 @@ func (pmt *PartialMerkleTree) TraverseAndExtract(height uint, pos uint, bitUsed
 	return &h
 }
 
+// Serialize writes the tree in the wire format used by the merkleblock message
+// and by gettxoutproof: the transaction count as uint32, the hashes and the
+// flag bits packed into bytes.
 func (pmt *PartialMerkleTree) Serialize(w io.Writer) (err error) {
-	err = util.WriteVarInt(w, uint64(pmt.txs))
+	err = util.WriteElements(w, uint32(pmt.txs))
 	if err != nil {
 		return
 	}
@@ -222,17 +228,29 @@ func (pmt *PartialMerkleTree) Serialize(w io.Writer) (err error) {
 	return
 }
 
+// Unserialize reads a tree written by Serialize. The hash and flag counts are
+// bounded by the transaction count before anything is allocated, so a bogus
+// proof can not make us allocate more than a block of the excessive block size
+// would need.
 func (pmt *PartialMerkleTree) Unserialize(r io.Reader) (err error) {
-	txs, err := util.ReadVarInt(r)
+	var txs uint32
+	err = util.ReadElements(r, &txs)
 	if err != nil {
 		return
 	}
+	if uint64(txs) > block.GetMaxTxCount() {
+		return errors.New("partial merkle tree has too many transactions")
+	}
 	pmt.txs = int(txs)
 
 	length, err := util.ReadVarInt(r)
 	if err != nil {
 		return
 	}
+	// There can never be more hashes provided than one for every txid.
+	if length > uint64(txs) {
+		return errors.New("partial merkle tree has more hashes than transactions")
+	}
 
 	hashes := make([]util.Hash, length)
 	for i := uint64(0); i < length; i++ {
@@ -243,7 +261,8 @@ func (pmt *PartialMerkleTree) Unserialize(r io.Reader) (err error) {
 	}
 	pmt.hashes = hashes
 
-	bs, err := util.ReadVarBytes(r, math.MaxUint32, "")
+	// A tree over n txids has less than 2n+32 nodes, one flag bit each.
+	bs, err := util.ReadVarBytes(r, txs/4+8, "partial merkle tree flags")
 	if err != nil {
 		return
 	}
@@ -0,0 +1,99 @@
+package lmerkleblock
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/copernet/copernicus/logic/lmerkleroot"
+	"github.com/copernet/copernicus/util"
+)
+
+func TestPartialMerkleTree(t *testing.T) {
+	txCounts := []int{1, 4, 7, 17, 56, 100, 127, 256, 312, 513, 1000, 4095}
+	r := rand.New(rand.NewSource(1))
+
+	for _, txCount := range txCounts {
+		txids := make([]util.Hash, txCount)
+		for i := range txids {
+			txids[i][0] = byte(i)
+			txids[i][1] = byte(i >> 8)
+			txids[i][31] = 0xaa
+		}
+		merkleRoot := lmerkleroot.ComputeMerkleRoot(txids, nil)
+
+		// build trees matching roughly 1/2, 1/8, 1/64 ... of the txids
+		for att := 1; att < 15; att++ {
+			matches := make([]bool, txCount)
+			matchedTxids := make([]util.Hash, 0)
+			matchedItems := make([]int, 0)
+			for i := 0; i < txCount; i++ {
+				if r.Intn(1<<uint(att/2)) == 0 {
+					matches[i] = true
+					matchedTxids = append(matchedTxids, txids[i])
+					matchedItems = append(matchedItems, i)
+				}
+			}
+
+			built := NewPartialMerkleTree(txids, matches)
+			buf := bytes.NewBuffer(nil)
+			if err := built.Serialize(buf); err != nil {
+				t.Fatal(err)
+			}
+
+			pmt := PartialMerkleTree{}
+			if err := pmt.Unserialize(bytes.NewReader(buf.Bytes())); err != nil {
+				t.Fatalf("txs=%d: unserialize failed: %v", txCount, err)
+			}
+
+			extracted := make([]util.Hash, 0)
+			items := make([]int, 0)
+			root := pmt.ExtractMatches(&extracted, &items)
+			if !root.IsEqual(&merkleRoot) {
+				t.Fatalf("txs=%d: root %s, expect %s", txCount, root, merkleRoot)
+			}
+			if len(extracted) != len(matchedTxids) {
+				t.Fatalf("txs=%d: extracted %d matches, expect %d", txCount, len(extracted), len(matchedTxids))
+			}
+			for i := range extracted {
+				if extracted[i] != matchedTxids[i] || items[i] != matchedItems[i] {
+					t.Fatalf("txs=%d: match %d mismatch", txCount, i)
+				}
+			}
+
+			// flipping any single flag bit, padding aside, must either
+			// invalidate the proof or, for a leaf, change the matched set
+			flipped := pmt
+			flipped.bits = append([]bool(nil), pmt.bits...)
+			bit := r.Intn(len(built.bits))
+			flipped.bits[bit] = !flipped.bits[bit]
+			root = flipped.ExtractMatches(&extracted, &items)
+			if root.IsEqual(&merkleRoot) && len(extracted) == len(matchedTxids) {
+				t.Errorf("txs=%d: proof with flipped bit %d should not verify", txCount, bit)
+			}
+		}
+	}
+}
+
+func TestPartialMerkleTreeUnserializeLimits(t *testing.T) {
+	txids := []util.Hash{{1}, {2}, {3}}
+	buf := bytes.NewBuffer(nil)
+	if err := NewPartialMerkleTree(txids, []bool{false, true, false}).Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	// the transaction count is a little-endian uint32
+	if !bytes.Equal(buf.Bytes()[:4], []byte{3, 0, 0, 0}) {
+		t.Errorf("unexpected tx count encoding %x", buf.Bytes()[:4])
+	}
+
+	tooManyHashes := append([]byte{1, 0, 0, 0, 2}, make([]byte, 64)...)
+	pmt := PartialMerkleTree{}
+	if err := pmt.Unserialize(bytes.NewReader(tooManyHashes)); err == nil {
+		t.Errorf("more hashes than transactions should be rejected")
+	}
+
+	tooManyTxs := []byte{0xff, 0xff, 0xff, 0xff, 0}
+	if err := pmt.Unserialize(bytes.NewReader(tooManyTxs)); err == nil {
+		t.Errorf("excessive transaction count should be rejected")
+	}
+}
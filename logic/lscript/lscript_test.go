@@ -146,16 +146,19 @@ func TestScriptJsonTests(t *testing.T) {
 		t.Errorf("error new scriptErrChecker")
 		return
 	}
+	failed := 0
 	for i, itest := range tests {
 		test, ok := itest.([]interface{})
 		if ok {
 			if err := doScriptJSONTest(t, test, *sec); err != nil {
+				failed++
 				t.Errorf("%dth test error: itest:%#v", i, itest)
 			}
 		} else {
 			t.Errorf("test is not []interface{}")
 		}
 	}
+	t.Logf("%d script test vectors run, %d failed", len(tests), failed)
 }
 
 func newScriptErrChecker() (*scriptErrChecker, error) {
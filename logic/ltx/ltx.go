@@ -3,6 +3,7 @@ package ltx
 import (
 	"bytes"
 	"github.com/copernet/copernicus/logic/lscript"
+	"github.com/copernet/copernicus/logic/lutxo"
 	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/model/utxo"
 
@@ -22,7 +23,6 @@ import (
 	"github.com/copernet/copernicus/model/undo"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
-	"github.com/pkg/errors"
 )
 
 var ScriptVerifyChan chan struct {
@@ -134,9 +134,7 @@ func CheckRegularTransaction(transaction *tx.Tx) error {
 	}
 	scriptVerifyFlags |= extraFlags
 
-	// Check against previous transactions. This is done last to help
-	// prevent CPU exhaustion denial-of-service attacks.
-	err = checkInputs(transaction, tempCoinsMap, scriptVerifyFlags)
+	err = checkInputsMoneyAtTip(transaction, tempCoinsMap)
 	if err != nil {
 		return err
 	}
@@ -157,21 +155,35 @@ func CheckRegularTransaction(transaction *tx.Tx) error {
 	// invalid blocks (using TestBlockValidity), however allowing such
 	// transactions into the mempool can be exploited as a DoS attack.
 	var currentBlockScriptVerifyFlags = chain.GetInstance().GetBlockScriptFlags(tip)
-	err = checkInputs(transaction, tempCoinsMap, currentBlockScriptVerifyFlags)
-	if err != nil {
-		if ((^scriptVerifyFlags) & currentBlockScriptVerifyFlags) == 0 {
-			return errcode.New(errcode.ScriptCheckInputsBug)
-		}
-		err = checkInputs(transaction, tempCoinsMap, uint32(script.MandatoryScriptVerifyFlags)|extraFlags)
+
+	// Check against previous transactions. This is done last to help
+	// prevent CPU exhaustion denial-of-service attacks, on the pool of the
+	// mempool so that relayed transactions can't hold up block validation.
+	return mempoolScriptChecks().Check(func(cancelled func() bool) error {
+		err := verifyInputScripts(transaction, tempCoinsMap, scriptVerifyFlags, cancelled)
 		if err != nil {
 			return err
 		}
 
-		log.Debug("Warning: -promiscuousmempool flags set to not include currently enforced soft forks, " +
-			"this may break mining or otherwise cause instability!\n")
-	}
+		err = verifyInputScripts(transaction, tempCoinsMap, currentBlockScriptVerifyFlags, cancelled)
+		if err != nil {
+			if errcode.IsErrorCode(err, errcode.TxErrScriptCheckTimeout) {
+				return err
+			}
+			if ((^scriptVerifyFlags) & currentBlockScriptVerifyFlags) == 0 {
+				return errcode.New(errcode.ScriptCheckInputsBug)
+			}
+			err = verifyInputScripts(transaction, tempCoinsMap,
+				uint32(script.MandatoryScriptVerifyFlags)|extraFlags, cancelled)
+			if err != nil {
+				return err
+			}
 
-	return nil
+			log.Debug("Warning: -promiscuousmempool flags set to not include currently enforced soft forks, " +
+				"this may break mining or otherwise cause instability!\n")
+		}
+		return nil
+	})
 }
 
 // CheckBlockTransactions block service use these 3 func to check transactions or to apply transaction while connecting block to active chain
@@ -246,11 +258,11 @@ func ContextureCheckBlockTransactions(txs []*tx.Tx, blockHeight int32, blockLock
 //	return
 //}
 
-func ApplyBlockTransactions(txs []*tx.Tx, bip30Enable bool, scriptCheckFlags uint32, needCheckScript bool,
-	blockSubSidy amount.Amount, blockHeight int32, blockMaxSigOpsCount uint64) (coinMap *utxo.CoinsMap, bundo *undo.BlockUndo, err error) {
-	// make view
-	coinsMap := utxo.NewEmptyCoinsMap()
-	utxo := utxo.GetUtxoCacheInstance()
+// ApplyBlockTransactions checks the transactions of a block against coinsMap
+// and applies them to it, returning the undo information of the block. On
+// error coinsMap is left half updated, so callers pass a view they can drop.
+func ApplyBlockTransactions(coinsMap *utxo.CoinsMap, txs []*tx.Tx, bip30Enable bool, scriptCheckFlags uint32, needCheckScript bool,
+	blockSubSidy amount.Amount, blockHeight int32, blockMaxSigOpsCount uint64) (bundo *undo.BlockUndo, err error) {
 	sigOpsCount := uint64(0)
 	var fees amount.Amount
 	bundo = undo.NewBlockUndo(0)
@@ -261,9 +273,9 @@ func ApplyBlockTransactions(txs []*tx.Tx, bip30Enable bool, scriptCheckFlags uin
 		if bip30Enable {
 			outs := transaction.GetOuts()
 			for i := range outs {
-				if utxo.HaveCoin(outpoint.NewOutPoint(transaction.GetHash(), uint32(i))) {
+				if coinsMap.HaveCoin(outpoint.NewOutPoint(transaction.GetHash(), uint32(i))) {
 					log.Debug("tried to overwrite transaction")
-					return nil, nil, errcode.New(errcode.TxErrRejectInvalid)
+					return nil, errcode.New(errcode.TxErrOutAlreadHave)
 				}
 			}
 		}
@@ -274,53 +286,61 @@ func ApplyBlockTransactions(txs []*tx.Tx, bip30Enable bool, scriptCheckFlags uin
 				coin := coinsMap.FetchCoin(in.PreviousOutPoint)
 				if coin == nil || coin.IsSpent() {
 					log.Debug("can't find coin or has been spent out before apply transaction")
-					return nil, nil, errcode.New(errcode.TxErrRejectInvalid)
+					return nil, errcode.New(errcode.TxErrRejectInvalid)
 				}
 				valueIn += coin.GetAmount()
 			}
 			coinHeight, coinTime := CalculateSequenceLocks(transaction, coinsMap, scriptCheckFlags)
 			if !CheckSequenceLocks(coinHeight, coinTime) {
 				log.Debug("block contains a non-bip68-final transaction")
-				return nil, nil, errcode.New(errcode.TxErrRejectInvalid)
+				return nil, errcode.New(errcode.TxErrRejectInvalid)
 			}
 		}
 		//check sigops
 		sigsCount := GetTransactionSigOpCount(transaction, scriptCheckFlags, coinsMap)
 		if sigsCount > tx.MaxTxSigOpsCounts {
 			log.Debug("transaction has too many sigops")
-			return nil, nil, errcode.New(errcode.TxErrRejectInvalid)
+			return nil, errcode.New(errcode.TxErrRejectInvalid)
 		}
 		sigOpsCount += uint64(sigsCount)
 		if sigOpsCount > blockMaxSigOpsCount {
 			log.Debug("block has too many sigops at %d transaction", i)
-			return nil, nil, errcode.New(errcode.TxErrRejectInvalid)
+			return nil, errcode.New(errcode.TxErrRejectInvalid)
 		}
 		if transaction.IsCoinBase() {
-			UpdateTxCoins(transaction, coinsMap, nil, blockHeight)
+			if err := UpdateTxCoins(transaction, coinsMap, nil, blockHeight); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
 		fees += valueIn - transaction.GetValueOut()
+		// the inputs are checked even when the scripts are assumed valid
+		err := CheckInputsMoney(transaction, coinsMap, lutxo.GetBlockSpendHeight(blockHeight))
+		if err != nil {
+			return nil, err
+		}
 		if needCheckScript {
-			//check inputs
-			err := checkInputs(transaction, coinsMap, scriptCheckFlags)
+			err := verifyInputScripts(transaction, coinsMap, scriptCheckFlags, nil)
 			if err != nil {
-				return nil, nil, err
+				return nil, err
 			}
 		}
 
 		//update temp coinsMap
 		txundo := undo.NewTxUndo()
-		UpdateTxCoins(transaction, coinsMap, txundo, blockHeight)
+		if err := UpdateTxCoins(transaction, coinsMap, txundo, blockHeight); err != nil {
+			return nil, err
+		}
 		txUndoList = append(txUndoList, txundo)
 	}
 	bundo.SetTxUndo(txUndoList)
 	//check blockReward
 	if txs[0].GetValueOut() > fees+blockSubSidy {
 		log.Debug("coinbase pays too much")
-		return nil, nil, errcode.New(errcode.TxErrRejectInvalid)
+		return nil, errcode.New(errcode.TxErrRejectInvalid)
 	}
-	return coinsMap, bundo, nil
+	return bundo, nil
 }
 
 // check coinbase with height
@@ -404,7 +424,8 @@ func areInputsAvailable(transaction *tx.Tx, coinMap *utxo.CoinsMap) bool {
 			log.Debug("inpute coin is already spent out")
 			return false
 		}
-		coinMap.AddCoin(e.PreviousOutPoint, coin, coin.IsCoinBase())
+		// the coin is copied into the view, not created
+		coinMap.AddCoin(e.PreviousOutPoint, coin, true)
 	}
 
 	return true
@@ -508,23 +529,25 @@ func checkInputsStandard(transaction *tx.Tx, coinsMap *utxo.CoinsMap) error {
 	return nil
 }
 
-func checkInputs(tx *tx.Tx, tempCoinMap *utxo.CoinsMap, flags uint32) error {
-	//check inputs money range
-	bestBlockHash, _ := utxo.GetUtxoCacheInstance().GetBestBlock()
-	spendHeight := chain.GetInstance().GetSpendHeight(&bestBlockHash)
-	if spendHeight == -1 {
-		return errors.New("indexMap can`t find block")
-	}
-
-	err := CheckInputsMoney(tx, tempCoinMap, spendHeight)
-	if err != nil {
-		return err
-	}
+// checkInputsMoneyAtTip checks the money range of the inputs as spent at the
+// height following the tip of the utxo set.
+func checkInputsMoneyAtTip(tx *tx.Tx, tempCoinMap *utxo.CoinsMap) error {
+	spendHeight, err := lutxo.GetMempoolSpendHeight()
 	if err != nil {
 		return err
 	}
+	return CheckInputsMoney(tx, tempCoinMap, spendHeight)
+}
+
+// verifyInputScripts verifies the script of every input of tx.  Once
+// cancelled, when not nil, returns true, the remaining inputs are left
+// unchecked and TxErrScriptCheckTimeout is returned.
+func verifyInputScripts(tx *tx.Tx, tempCoinMap *utxo.CoinsMap, flags uint32, cancelled func() bool) error {
 	ins := tx.GetIns()
 	for i, in := range ins {
+		if cancelled != nil && cancelled() {
+			return errcode.New(errcode.TxErrScriptCheckTimeout)
+		}
 		coin := tempCoinMap.GetCoin(in.PreviousOutPoint)
 		if coin == nil {
 			panic("can't find coin in temp coinsmap")
@@ -724,7 +747,14 @@ func calculateSequenceLockPair(transaction *tx.Tx, preHeight []int32, flags uint
 
 		coinHeight := preHeight[i]
 		if e.Sequence&script.SequenceLockTimeTypeFlag == script.SequenceLockTimeTypeFlag {
-			coinTime := activeChain.GetAncestor(coinHeight - 1).GetMedianTimePast()
+			// The time of a coin is the median time past of the block before
+			// it, so the one of the tip for the coins of the mempool, and the
+			// coins of the genesis block use its own.
+			ancestorHeight := coinHeight - 1
+			if ancestorHeight < 0 {
+				ancestorHeight = 0
+			}
+			coinTime := activeChain.GetAncestor(ancestorHeight).GetMedianTimePast()
 			// NOTE: Subtract 1 to maintain nLockTime semantics.
 			// BIP 68 relative lock times have the semantics of calculating the
 			// first block or time at which the transaction would be valid. When
@@ -770,11 +800,9 @@ func CheckInputsMoney(transaction *tx.Tx, coinsMap *utxo.CoinsMap, spendHeight i
 			log.Debug("CheckInputsMoney can't find coin")
 			panic("CheckInputsMoney can't find coin")
 		}
-		if coin.IsCoinBase() {
-			if spendHeight-coin.GetHeight() < consensus.CoinbaseMaturity {
-				log.Debug("CheckInputsMoney coinbase can't spend now")
-				return errcode.New(errcode.TxErrRejectInvalid)
-			}
+		if !lutxo.IsCoinMature(coin, spendHeight) {
+			log.Debug("CheckInputsMoney coinbase can't spend now")
+			return errcode.New(errcode.TxErrPrematureCoinbase)
 		}
 		txOut := coin.GetTxOut()
 		if !amount.MoneyRange(txOut.GetValue()) {
@@ -825,66 +853,82 @@ func CheckInputsMoney(transaction *tx.Tx, coinsMap *utxo.CoinsMap, spendHeight i
 //}
 
 // SignRawTransaction txs, preouts, private key, hash type
-func SignRawTransaction(transaction *tx.Tx, redeemScripts map[string]string, keys map[string]*crypto.PrivateKey,
-	hashType uint32) (err error) {
-	coinMap := utxo.NewEmptyCoinsMap()
+// SignRawTransaction signs the inputs of transactions[0] with the given keys and
+// redeem scripts, both indexed by their hash160. Previous outputs are looked up
+// in coinsMap only. The new signatures are combined with the scriptSigs already
+// present in every transaction of the list, and transactions[0] is updated in
+// place. The returned slice holds one entry per input: nil if the input now
+// verifies, the reason otherwise.
+func SignRawTransaction(transactions []*tx.Tx, coinsMap *utxo.CoinsMap, redeemScripts map[string]string,
+	keys map[string]*crypto.PrivateKey, hashType uint32) []error {
+	transaction := transactions[0]
+	hashSingle := hashType&(^(uint32(crypto.SigHashAnyoneCanpay) | crypto.SigHashForkID)) == crypto.SigHashSingle
+	flags := uint32(script.StandardScriptVerifyFlags)
+	checker := lscript.NewScriptRealChecker()
+
 	ins := transaction.GetIns()
+	errs := make([]error, len(ins))
 	for i, in := range ins {
-		coin := coinMap.FetchCoin(in.PreviousOutPoint)
+		coin := coinsMap.GetCoin(in.PreviousOutPoint)
 		if coin == nil || coin.IsSpent() {
-			log.Debug("TxErrNoPreviousOut")
-			return errcode.New(errcode.TxErrNoPreviousOut)
+			errs[i] = errcode.New(errcode.TxErrNoPreviousOut)
+			continue
 		}
 		prevPubKey := coin.GetScriptPubKey()
-		var scriptSig *script.Script
-		var sigData [][]byte
-		var scriptType int
-		if hashType&(^(uint32(crypto.SigHashAnyoneCanpay)|crypto.SigHashForkID)) != crypto.SigHashSingle ||
-			i < transaction.GetOutsCount() {
-			sigData, scriptType, err = transaction.SignStep(redeemScripts, keys, hashType, prevPubKey,
-				i, coin.GetAmount())
-			if err != nil {
-				return err
+		value := coin.GetAmount()
+
+		// Only sign SIGHASH_SINGLE if there's a corresponding output
+		scriptSig := script.NewEmptyScript()
+		if !hashSingle || i < transaction.GetOutsCount() {
+			scriptSig = produceSignature(transaction, redeemScripts, keys, hashType, prevPubKey, i, value)
+		}
+
+		// and merge in any signatures already present in the given transactions
+		var err error
+		for _, other := range transactions {
+			if i >= len(other.GetIns()) {
+				continue
 			}
-			// get signatures and redeemscript
-			if scriptType == script.ScriptHash {
-				redeemScriptPubKey := script.NewScriptRaw(sigData[0])
-				var redeemScriptType int
-				sigData, redeemScriptType, err = transaction.SignStep(redeemScripts, keys, hashType,
-					redeemScriptPubKey, i, coin.GetAmount())
-				if err != nil {
-					return err
-				}
-				if redeemScriptType == script.ScriptHash {
-					log.Debug("TxErrSignRawTransaction")
-					return errcode.New(errcode.TxErrSignRawTransaction)
-				}
-				sigData = append(sigData, redeemScriptPubKey.GetData())
+			scriptSig, err = combineSignature(transaction, prevPubKey, scriptSig,
+				other.GetIns()[i].GetScriptSig(), i, value, flags, checker)
+			if err != nil {
+				break
 			}
 		}
-		scriptSig = script.NewEmptyScript()
-		scriptSig.PushMultData(sigData)
-		err = lscript.VerifyScript(transaction, scriptSig, prevPubKey, i, coin.GetAmount(),
-			uint32(script.StandardScriptVerifyFlags), lscript.NewScriptRealChecker())
-		if err != nil {
-			return err
-		}
-		scriptSig, err = combineSignature(transaction, prevPubKey, scriptSig, transaction.GetIns()[i].GetScriptSig(),
-			i, coin.GetAmount(), uint32(script.StandardScriptVerifyFlags), lscript.NewScriptRealChecker())
 		if err != nil {
-			return err
-		}
-		err = transaction.UpdateInScript(i, scriptSig)
-		if err != nil {
-			return err
+			errs[i] = err
+			continue
 		}
-		err = lscript.VerifyScript(transaction, scriptSig, prevPubKey, i, coin.GetAmount(),
-			uint32(script.StandardScriptVerifyFlags), lscript.NewScriptRealChecker())
-		if err != nil {
-			return err
+		if err = transaction.UpdateInScript(i, scriptSig); err != nil {
+			errs[i] = err
+			continue
 		}
+		errs[i] = lscript.VerifyScript(transaction, scriptSig, prevPubKey, i, value, flags, checker)
 	}
-	return
+	return errs
+}
+
+// produceSignature returns the scriptSig for the nIn'th input built from the
+// signatures we are able to make. It may be incomplete or empty.
+func produceSignature(transaction *tx.Tx, redeemScripts map[string]string, keys map[string]*crypto.PrivateKey,
+	hashType uint32, prevPubKey *script.Script, nIn int, value amount.Amount) *script.Script {
+	scriptSig := script.NewEmptyScript()
+	sigData, scriptType, err := transaction.SignStep(redeemScripts, keys, hashType, prevPubKey, nIn, value)
+	if err != nil && sigData == nil {
+		return scriptSig
+	}
+	if scriptType == script.ScriptHash {
+		redeemScript := script.NewScriptRaw(sigData[0])
+		var redeemScriptType int
+		sigData, redeemScriptType, _ = transaction.SignStep(redeemScripts, keys, hashType,
+			redeemScript, nIn, value)
+		if redeemScriptType == script.ScriptHash {
+			return scriptSig
+		}
+		sigData = append(sigData, redeemScript.GetData())
+	}
+	scriptSig.PushMultData(sigData)
+	return scriptSig
 }
 
 func combineSignature(transaction *tx.Tx, prevPubKey *script.Script, scriptSig *script.Script,
@@ -912,10 +956,11 @@ func combineSignature(transaction *tx.Tx, prevPubKey *script.Script, scriptSig *
 	if pubKeyType == script.ScriptMultiSig {
 		sigData := make([][]byte, 0, len(scriptSig.ParsedOpCodes))
 		okSigs := make(map[string][]byte, len(scriptSig.ParsedOpCodes))
-		var parsedOpCodes = scriptSig.ParsedOpCodes
+		parsedOpCodes := make([]opcodes.ParsedOpCode, 0, len(scriptSig.ParsedOpCodes)+len(txOldScriptSig.ParsedOpCodes))
+		parsedOpCodes = append(parsedOpCodes, scriptSig.ParsedOpCodes...)
 		parsedOpCodes = append(parsedOpCodes, txOldScriptSig.ParsedOpCodes...)
 		for _, opCode := range parsedOpCodes {
-			for _, pubKey := range pubKeys[1 : len(pubKeys)-2] {
+			for _, pubKey := range pubKeys[1 : len(pubKeys)-1] {
 				if okSigs[string(pubKey)] != nil {
 					continue
 				}
@@ -929,9 +974,11 @@ func combineSignature(transaction *tx.Tx, prevPubKey *script.Script, scriptSig *
 				}
 			}
 		}
+		// the leading empty push works around the CHECKMULTISIG off-by-one
+		sigData = append(sigData, []byte{})
 		sigN := 0
 		sigsRequired := int(pubKeys[0][0])
-		for _, pubKey := range pubKeys {
+		for _, pubKey := range pubKeys[1 : len(pubKeys)-1] {
 			if okSigs[string(pubKey)] != nil {
 				sigData = append(sigData, okSigs[string(pubKey)])
 				sigN++
@@ -941,9 +988,7 @@ func combineSignature(transaction *tx.Tx, prevPubKey *script.Script, scriptSig *
 			}
 		}
 		for sigN < sigsRequired {
-			data := make([]byte, 0, 1)
-			data = append(data, byte(opcodes.OP_0))
-			sigData = append(sigData, data)
+			sigData = append(sigData, []byte{})
 			sigN++
 		}
 		scriptResult := script.NewEmptyScript()
@@ -951,16 +996,14 @@ func combineSignature(transaction *tx.Tx, prevPubKey *script.Script, scriptSig *
 		return scriptResult, nil
 	}
 	if pubKeyType == script.ScriptHash {
-		if len(scriptSig.ParsedOpCodes) == 0 {
+		// the redeem script is the last push, the first one may well be the
+		// empty CHECKMULTISIG dummy of a P2SH multisig
+		if len(scriptSig.ParsedOpCodes) == 0 ||
+			len(scriptSig.ParsedOpCodes[len(scriptSig.ParsedOpCodes)-1].Data) == 0 {
 			return txOldScriptSig, nil
 		}
-		if len(scriptSig.ParsedOpCodes[0].Data) == 0 {
-			return txOldScriptSig, nil
-		}
-		if len(txOldScriptSig.ParsedOpCodes) == 0 {
-			return scriptSig, nil
-		}
-		if len(txOldScriptSig.ParsedOpCodes[0].Data) == 0 {
+		if len(txOldScriptSig.ParsedOpCodes) == 0 ||
+			len(txOldScriptSig.ParsedOpCodes[len(txOldScriptSig.ParsedOpCodes)-1].Data) == 0 {
 			return scriptSig, nil
 		}
 		redeemScript := script.NewScriptRaw(scriptSig.ParsedOpCodes[len(scriptSig.ParsedOpCodes)-1].Data)
@@ -6,12 +6,20 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/logic/lscript"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/consensus"
 	"github.com/copernet/copernicus/model/opcodes"
 	"github.com/copernet/copernicus/model/outpoint"
 	"github.com/copernet/copernicus/model/script"
 	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/utxo"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
 	"io/ioutil"
@@ -261,6 +269,7 @@ func TestTxValidTests(t *testing.T) {
 		t.Fatalf("TestTxValidTests unmarshal err:%v\n", err)
 	}
 
+	checked := 0
 testloop:
 	for i, test := range tests {
 		inputs, ok := test[0].([]interface{})
@@ -365,6 +374,16 @@ testloop:
 			prevOuts[*outpoint.NewOutPoint(*prevhash, idx)] = v
 		}
 
+		// the vectors include coinbase transactions, checked as such
+		check := newTx.CheckRegularTransaction
+		if newTx.IsCoinBase() {
+			check = newTx.CheckCoinbaseTransaction
+		}
+		if err := check(); err != nil {
+			t.Errorf("check transaction error: %v, %dth test, test=%v", err, i, test)
+			continue
+		}
+
 		for k, txin := range newTx.GetIns() {
 			prevOut, ok := prevOuts[*txin.PreviousOutPoint]
 			if !ok {
@@ -381,7 +400,9 @@ testloop:
 				t.Errorf("verifyScript error: %v, %dth test, test=%v", err, i, test)
 			}
 		}
+		checked++
 	}
+	t.Logf("%d valid transaction vectors checked", checked)
 }
 
 func TestTxInvalidTests(t *testing.T) {
@@ -401,6 +422,7 @@ func TestTxInvalidTests(t *testing.T) {
 	// or:
 	//   [[[previous hash, previous index, previous scriptPubKey]...,]
 	//	serializedTransaction, verifyFlags]
+	checked := 0
 testloop:
 	for i, test := range tests {
 		inputs, ok := test[0].([]interface{})
@@ -512,6 +534,7 @@ testloop:
 			}
 			prevOuts[*outpoint.NewOutPoint(*prevhash, idx)] = v
 		}
+		checked++
 		err = newTx.CheckRegularTransaction()
 		if err != nil {
 			continue
@@ -534,6 +557,7 @@ testloop:
 		t.Errorf("test (%d:%v) succeeded when should fail",
 			i, test)
 	}
+	t.Logf("%d invalid transaction vectors checked", checked)
 }
 
 func NewPrivateKey() crypto.PrivateKey {
@@ -841,3 +865,182 @@ func NewPrivateKey() crypto.PrivateKey {
 //		t.Errorf("combined should be equal to partial3c")
 //	}
 //}
+
+func buildLockPointChain(count int) []*blockindex.BlockIndex {
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	chain.InitGlobalChain()
+
+	indexes := make([]*blockindex.BlockIndex, count)
+	for i := range indexes {
+		index := new(blockindex.BlockIndex)
+		index.Height = int32(i)
+		index.Header.Time = uint32(1500000000 + i*600)
+		index.Header.Nonce = uint32(i)
+		if i > 0 {
+			index.Prev = indexes[i-1]
+		}
+		indexes[i] = index
+	}
+	chain.GetInstance().SetTip(indexes[count-1])
+	return indexes
+}
+
+func TestCalculateSequenceLockPair(t *testing.T) {
+	indexes := buildLockPointChain(20)
+	tipHeight := chain.GetInstance().Height()
+	flags := uint32(consensus.LocktimeVerifySequence)
+
+	newSeqTx := func(version int32, sequences ...uint32) *tx.Tx {
+		transaction := tx.NewTx(0, version)
+		for i, sequence := range sequences {
+			transaction.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.Hash{byte(i + 1)}, 0),
+				script.NewEmptyScript(), sequence))
+		}
+		return transaction
+	}
+
+	// BIP68 is not enforced for version 1 transactions.
+	height, lockTime := calculateSequenceLockPair(newSeqTx(1, 5), []int32{10}, flags)
+	if height != -1 || lockTime != -1 {
+		t.Errorf("version 1 tx got lock (%d, %d), want (-1, -1)", height, lockTime)
+	}
+
+	// A height lock is relative to the height of the confirmed parent.
+	height, _ = calculateSequenceLockPair(newSeqTx(2, 5), []int32{10}, flags)
+	if height != 14 {
+		t.Errorf("height lock got %d, want 14", height)
+	}
+
+	// The disable flag turns the relative lock off.
+	height, _ = calculateSequenceLockPair(newSeqTx(2, 5|script.SequenceLockTimeDisableFlag), []int32{10}, flags)
+	if height != -1 {
+		t.Errorf("disabled lock got %d, want -1", height)
+	}
+
+	// Unconfirmed parents are treated as if they get mined in the next block,
+	// so a time lock counts from the tip's median time past.
+	_, lockTime = calculateSequenceLockPair(newSeqTx(2, 1|script.SequenceLockTimeTypeFlag),
+		[]int32{tipHeight + 1}, flags)
+	want := indexes[tipHeight].GetMedianTimePast() + (1 << script.SequenceLockTimeGranularity) - 1
+	if lockTime != want {
+		t.Errorf("time lock on unconfirmed parent got %d, want %d", lockTime, want)
+	}
+
+	// A time lock on a confirmed parent counts from the MTP of its previous block.
+	_, lockTime = calculateSequenceLockPair(newSeqTx(2, 1|script.SequenceLockTimeTypeFlag), []int32{10}, flags)
+	want = indexes[9].GetMedianTimePast() + (1 << script.SequenceLockTimeGranularity) - 1
+	if lockTime != want {
+		t.Errorf("time lock on confirmed parent got %d, want %d", lockTime, want)
+	}
+}
+
+func cloneTx(t *testing.T, transaction *tx.Tx) *tx.Tx {
+	buf := bytes.NewBuffer(nil)
+	if err := transaction.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	clone := tx.NewTx(0, tx.TxVersion)
+	if err := clone.Unserialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	return clone
+}
+
+func TestSignRawTransaction(t *testing.T) {
+	keys := make([]crypto.PrivateKey, 3)
+	pubKeys := make([][]byte, 3)
+	for i := range keys {
+		keys[i] = NewPrivateKey()
+		pubKeys[i] = keys[i].PubKey().ToBytes()
+	}
+	keyMap := func(indexes ...int) map[string]*crypto.PrivateKey {
+		m := make(map[string]*crypto.PrivateKey)
+		for _, i := range indexes {
+			m[string(util.Hash160(pubKeys[i]))] = &keys[i]
+		}
+		return m
+	}
+
+	p2pkh, err := script.NewScriptPubKeyHash(util.Hash160(pubKeys[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	multiSig, err := script.NewScriptMultiSig(2, pubKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redeemScript, err := script.NewScriptPubKey(pubKeys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2sh, err := script.NewScriptHash(util.Hash160(redeemScript.GetData()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2shMultiSig, err := script.NewScriptHash(util.Hash160(multiSig.GetData()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	redeemScripts := map[string]string{
+		string(util.Hash160(redeemScript.GetData())): string(redeemScript.GetData()),
+		string(util.Hash160(multiSig.GetData())):     string(multiSig.GetData()),
+	}
+
+	coinsMap := utxo.NewEmptyCoinsMap()
+	unsigned := tx.NewTx(0, tx.TxVersion)
+	for i, pkScript := range []*script.Script{p2pkh, multiSig, p2sh, p2shMultiSig} {
+		out := outpoint.NewOutPoint(util.Hash{1}, uint32(i))
+		coinsMap.AddCoin(out, utxo.NewCoin(txout.NewTxOut(amount.Amount(1000), pkScript), 1, false), true)
+		unsigned.AddTxIn(txin.NewTxIn(out, script.NewEmptyScript(), script.SequenceFinal))
+	}
+	unsigned.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.Hash{2}, 0), script.NewEmptyScript(), script.SequenceFinal))
+	unsigned.AddTxOut(txout.NewTxOut(amount.Amount(2000), p2pkh))
+
+	hashType := uint32(crypto.SigHashAll | crypto.SigHashForkID)
+
+	first := cloneTx(t, unsigned)
+	errs := SignRawTransaction([]*tx.Tx{first}, coinsMap, redeemScripts, keyMap(0), hashType)
+	if len(errs) != 5 {
+		t.Fatalf("expect one result per input, got %d", len(errs))
+	}
+	if errs[0] != nil {
+		t.Errorf("p2pkh input should be signed: %v", errs[0])
+	}
+	if errs[1] == nil {
+		t.Errorf("2-of-3 multisig input should not be complete with one key")
+	}
+	if errs[2] != nil {
+		t.Errorf("p2sh input should be signed: %v", errs[2])
+	}
+	if errs[3] == nil {
+		t.Errorf("p2sh 2-of-3 multisig input should not be complete with one key")
+	}
+	if !errcode.IsErrorCode(errs[4], errcode.TxErrNoPreviousOut) {
+		t.Errorf("missing input should report TxErrNoPreviousOut, got %v", errs[4])
+	}
+
+	second := cloneTx(t, unsigned)
+	errs = SignRawTransaction([]*tx.Tx{second}, coinsMap, redeemScripts, keyMap(2), hashType)
+	if errs[1] == nil {
+		t.Errorf("2-of-3 multisig input should not be complete with one key")
+	}
+
+	// combining both partial signatures completes the multisig inputs
+	errs = SignRawTransaction([]*tx.Tx{first, second}, coinsMap, nil, keyMap(), hashType)
+	for i := 0; i < 4; i++ {
+		if errs[i] != nil {
+			t.Errorf("input %d should be complete after combining: %v", i, errs[i])
+		}
+	}
+
+	// signing a partially signed p2sh multisig input with a second key keeps
+	// the first signature
+	third := cloneTx(t, unsigned)
+	SignRawTransaction([]*tx.Tx{third}, coinsMap, redeemScripts, keyMap(0), hashType)
+	errs = SignRawTransaction([]*tx.Tx{third}, coinsMap, redeemScripts, keyMap(1), hashType)
+	if errs[3] != nil {
+		t.Errorf("p2sh 2-of-3 multisig input should be complete with two keys: %v", errs[3])
+	}
+}
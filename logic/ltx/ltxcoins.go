@@ -9,7 +9,7 @@ import (
 )
 
 //UpdateTxCoins update coins about tx
-func UpdateTxCoins(tx *tx.Tx, coinMap *utxo.CoinsMap, txundo *undo.TxUndo, height int32) {
+func UpdateTxCoins(tx *tx.Tx, coinMap *utxo.CoinsMap, txundo *undo.TxUndo, height int32) error {
 	txHash := tx.GetHash()
 	if !tx.IsCoinBase() {
 		undoCoins := make([]*utxo.Coin, len(tx.GetIns()))
@@ -24,19 +24,27 @@ func UpdateTxCoins(tx *tx.Tx, coinMap *utxo.CoinsMap, txundo *undo.TxUndo, heigh
 		}
 		txundo.SetUndoCoins(undoCoins)
 	}
-	txAddCoins(tx, coinMap, height)
+	if err := txAddCoins(tx, coinMap, height); err != nil {
+		return err
+	}
 	if txHash.String() == "7e621eeb02874ab039a8566fd36f4591e65eca65313875221842c53de6907d6c" {
 		fmt.Println("after add coin*************************")
 		utxo.DisplayCoinMap(coinMap)
 	}
+	return nil
 }
 
-func txAddCoins(tx *tx.Tx, coinMap *utxo.CoinsMap, height int32) {
+// txAddCoins adds the outputs of tx to coinMap. Only coinbases may overwrite
+// an unspent coin, as the two duplicate coinbases of the BIP30 exceptions did.
+func txAddCoins(tx *tx.Tx, coinMap *utxo.CoinsMap, height int32) error {
 	isCoinbase := tx.IsCoinBase()
 	txid := tx.GetHash()
 	for idx, out := range tx.GetOuts() {
 		op := outpoint.NewOutPoint(txid, uint32(idx))
 		coin := utxo.NewCoin(out, height, isCoinbase)
-		coinMap.AddCoin(op, coin, isCoinbase)
+		if err := coinMap.AddCoin(op, coin, isCoinbase); err != nil {
+			return err
+		}
 	}
+	return nil
 }
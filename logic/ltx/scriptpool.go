@@ -0,0 +1,124 @@
+package ltx
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/errcode"
+)
+
+// ScriptCheckPool runs the script checks of the transactions entering the
+// mempool on a bounded number of workers, apart from block validation, so that
+// a burst of transactions with expensive scripts can't take more than its
+// share of the CPUs.  A check is submitted only once a worker is free, the
+// waiting and the check together being bounded by the timeout of the pool.
+type ScriptCheckPool struct {
+	jobs    chan *scriptCheckJob
+	timeout time.Duration
+	wg      sync.WaitGroup
+}
+
+type scriptCheckJob struct {
+	check     func(cancelled func() bool) error
+	result    chan error
+	cancelled int32
+}
+
+func (job *scriptCheckJob) isCancelled() bool {
+	return atomic.LoadInt32(&job.cancelled) != 0
+}
+
+// NewScriptCheckPool starts a pool of workers checking scripts, whose checks
+// time out after timeout, or never when it is not positive.
+func NewScriptCheckPool(workers int, timeout time.Duration) *ScriptCheckPool {
+	if workers < 1 {
+		workers = 1
+	}
+	p := &ScriptCheckPool{
+		jobs:    make(chan *scriptCheckJob),
+		timeout: timeout,
+	}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.worker()
+	}
+	return p
+}
+
+func (p *ScriptCheckPool) worker() {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		job.result <- job.check(job.isCancelled)
+	}
+}
+
+// Check runs check on a worker and returns its result.  Once the timeout
+// elapses, Check returns TxErrScriptCheckTimeout and the function cancelled
+// passed to check starts returning true, check being expected to give up
+// between two scripts.
+func (p *ScriptCheckPool) Check(check func(cancelled func() bool) error) error {
+	job := &scriptCheckJob{
+		check:  check,
+		result: make(chan error, 1),
+	}
+
+	var timeout <-chan time.Time
+	if p.timeout > 0 {
+		timer := time.NewTimer(p.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case p.jobs <- job:
+	case <-timeout:
+		return errcode.New(errcode.TxErrScriptCheckTimeout)
+	}
+	select {
+	case err := <-job.result:
+		return err
+	case <-timeout:
+		atomic.StoreInt32(&job.cancelled, 1)
+		return errcode.New(errcode.TxErrScriptCheckTimeout)
+	}
+}
+
+// Stop stops the workers once their checks are done.  No check may be
+// submitted afterwards.
+func (p *ScriptCheckPool) Stop() {
+	close(p.jobs)
+	p.wg.Wait()
+}
+
+var (
+	mempoolScriptPool     *ScriptCheckPool
+	mempoolScriptPoolOnce sync.Once
+)
+
+// mempoolScriptChecks returns the pool checking the scripts of the
+// transactions entering the mempool, started on first use as configured.
+func mempoolScriptChecks() *ScriptCheckPool {
+	mempoolScriptPoolOnce.Do(func() {
+		workers := conf.Cfg.Script.MempoolScriptWorkers
+		if workers <= 0 {
+			workers = runtime.NumCPU() / 2
+		}
+		timeout := time.Duration(conf.Cfg.Script.MempoolScriptTimeout) * time.Second
+		mempoolScriptPool = NewScriptCheckPool(workers, timeout)
+	})
+	return mempoolScriptPool
+}
+
+// StopMempoolScriptChecks stops the pool checking the scripts of the
+// transactions entering the mempool, if it was started.  No transaction may
+// enter the mempool afterwards.
+func StopMempoolScriptChecks() {
+	// a pool started later would never be stopped
+	mempoolScriptPoolOnce.Do(func() {})
+	if mempoolScriptPool != nil {
+		mempoolScriptPool.Stop()
+	}
+}
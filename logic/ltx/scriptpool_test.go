@@ -0,0 +1,105 @@
+package ltx
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/copernet/copernicus/errcode"
+)
+
+func TestScriptCheckPoolResult(t *testing.T) {
+	p := NewScriptCheckPool(2, 0)
+	defer p.Stop()
+
+	if err := p.Check(func(func() bool) error { return nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := errors.New("bad script")
+	if err := p.Check(func(func() bool) error { return want }); err != want {
+		t.Fatalf("got error %v, want %v", err, want)
+	}
+}
+
+func TestScriptCheckPoolBoundsConcurrency(t *testing.T) {
+	const workers = 3
+	p := NewScriptCheckPool(workers, 0)
+	defer p.Stop()
+
+	var running, highest int32
+	var wg sync.WaitGroup
+	for i := 0; i < 4*workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Check(func(func() bool) error {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					h := atomic.LoadInt32(&highest)
+					if n <= h || atomic.CompareAndSwapInt32(&highest, h, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	if highest > workers {
+		t.Fatalf("%d checks ran at once on %d workers", highest, workers)
+	}
+}
+
+func TestScriptCheckPoolTimeout(t *testing.T) {
+	p := NewScriptCheckPool(1, 20*time.Millisecond)
+	defer p.Stop()
+
+	observed := make(chan struct{})
+	err := p.Check(func(cancelled func() bool) error {
+		for !cancelled() {
+			time.Sleep(time.Millisecond)
+		}
+		close(observed)
+		return nil
+	})
+	if !errcode.IsErrorCode(err, errcode.TxErrScriptCheckTimeout) {
+		t.Fatalf("got error %v, want a script check timeout", err)
+	}
+	select {
+	case <-observed:
+	case <-time.After(time.Second):
+		t.Fatal("the timed out check wasn't cancelled")
+	}
+}
+
+func TestScriptCheckPoolTimeoutWaitingForWorker(t *testing.T) {
+	p := NewScriptCheckPool(1, 20*time.Millisecond)
+	defer p.Stop()
+
+	release := make(chan struct{})
+	busy := make(chan struct{})
+	go p.Check(func(func() bool) error {
+		close(busy)
+		<-release
+		return nil
+	})
+	<-busy
+
+	ran := false
+	err := p.Check(func(func() bool) error {
+		ran = true
+		return nil
+	})
+	close(release)
+	if !errcode.IsErrorCode(err, errcode.TxErrScriptCheckTimeout) {
+		t.Fatalf("got error %v, want a script check timeout", err)
+	}
+	if ran {
+		t.Fatal("the check ran although no worker was free before the timeout")
+	}
+}
@@ -1,7 +1,6 @@
 package lundo
 
 import (
-	"fmt"
 	"sync/atomic"
 
 	"github.com/copernet/copernicus/log"
@@ -50,16 +49,19 @@ func IsInitialBlockDownload() bool {
 	return false
 }
 
+// ApplyBlockUndo reverts the changes of blk to the coins map with its undo
+// data: the outputs the block created are spent, and the coins its
+// transactions spent are restored.
 func ApplyBlockUndo(blockUndo *undo.BlockUndo, blk *block.Block,
 	cm *utxo.CoinsMap) undo.DisconnectResult {
 	clean := true
 	txUndos := blockUndo.GetTxundo()
 	if len(txUndos)+1 != len(blk.Txs) {
-		fmt.Println("DisconnectBlock(): block and undo data inconsistent")
+		log.Error("DisconnectBlock(): block and undo data inconsistent")
 		return undo.DisconnectFailed
 	}
 	// Undo transactions in reverse order.
-	for i := len(blk.Txs) - 1; i > 0; i-- {
+	for i := len(blk.Txs) - 1; i >= 0; i-- {
 		tx := blk.Txs[i]
 		txid := tx.GetHash()
 
@@ -70,36 +72,42 @@ func ApplyBlockUndo(blockUndo *undo.BlockUndo, blk *block.Block,
 				continue
 			}
 			out := outpoint.NewOutPoint(txid, uint32(j))
-			coin := cm.SpendGlobalCoin(out)
+			coin := cm.FetchCoin(out)
+			if coin == nil {
+				// transaction output missing
+				clean = false
+				continue
+			}
 			coinOut := coin.GetTxOut()
-			if coin == nil || !tx.GetTxOut(j).IsEqual(&coinOut) {
+			if !tx.GetTxOut(j).IsEqual(&coinOut) {
 				// transaction output mismatch
 				clean = false
 			}
+			cm.SpendCoin(out)
+		}
 
-			// Restore inputs
-			if i < 1 {
-				// Skip the coinbase
-				break
-			}
+		// Restore inputs
+		if i < 1 {
+			// Skip the coinbase
+			continue
+		}
 
-			txundo := txUndos[i-1]
-			ins := tx.GetIns()
-			insLen := len(ins)
-			if len(txundo.GetUndoCoins()) != insLen {
-				log.Error("DisconnectBlock(): transaction and undo data inconsistent")
-				return undo.DisconnectFailed
-			}
+		txundo := txUndos[i-1]
+		ins := tx.GetIns()
+		insLen := len(ins)
+		if len(txundo.GetUndoCoins()) != insLen {
+			log.Error("DisconnectBlock(): transaction and undo data inconsistent")
+			return undo.DisconnectFailed
+		}
 
-			for k := insLen - 1; k > 0; k-- {
-				outpoint := ins[k].PreviousOutPoint
-				undoCoin := txundo.GetUndoCoins()[k]
-				res := CoinSpend(undoCoin, cm, outpoint)
-				if res == undo.DisconnectFailed {
-					return undo.DisconnectFailed
-				}
-				clean = clean && (res != undo.DisconnectUnclean)
+		for k := insLen - 1; k >= 0; k-- {
+			outpoint := ins[k].PreviousOutPoint
+			undoCoin := txundo.GetUndoCoins()[k]
+			res := CoinSpend(undoCoin, cm, outpoint)
+			if res == undo.DisconnectFailed {
+				return undo.DisconnectFailed
 			}
+			clean = clean && (res != undo.DisconnectUnclean)
 		}
 	}
 
@@ -111,8 +119,12 @@ func ApplyBlockUndo(blockUndo *undo.BlockUndo, blk *block.Block,
 
 //CoinSpend undo coin of spend
 func CoinSpend(coin *utxo.Coin, cm *utxo.CoinsMap, out *outpoint.OutPoint) undo.DisconnectResult {
+	if coin.IsSpent() || coin.GetScriptPubKey() == nil {
+		log.Error("DisconnectBlock(): missing undo data of %s", out.String())
+		return undo.DisconnectFailed
+	}
 	clean := true
-	if cm.FetchCoin(out) != nil {
+	if cm.HaveCoin(out) {
 		// Overwriting transaction output.
 		clean = false
 	}
@@ -133,7 +145,7 @@ func CoinSpend(coin *utxo.Coin, cm *utxo.CoinsMap, out *outpoint.OutPoint) undo.
 	//	// the correct information in there doesn't hurt.
 	//	coin = utxo.NewCoin(coin.GetTxOut(), alternate.GetHeight(), alternate.IsCoinBase())
 	//}
-	cm.AddCoin(out, coin, coin.IsCoinBase())
+	cm.AddCoin(out, coin, !clean)
 	if clean {
 		return undo.DisconnectOk
 	}
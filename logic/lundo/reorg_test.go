@@ -0,0 +1,177 @@
+package lundo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/copernet/copernicus/logic/ltx"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/undo"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+)
+
+// reorgChain is a chain of random blocks connected to a coins view, with the
+// undo data needed to disconnect them again.
+type reorgChain struct {
+	r      *rand.Rand
+	view   *utxo.CoinsMap
+	blocks []*block.Block
+	undos  []*undo.BlockUndo
+}
+
+func newReorgChain(r *rand.Rand) *reorgChain {
+	return &reorgChain{r: r, view: utxo.NewEmptyCoinsMap()}
+}
+
+// unspents returns the unspent outpoints of the view in a stable order, so
+// a seed always builds the same chain.
+func unspents(view *utxo.CoinsMap) []outpoint.OutPoint {
+	outs := make([]outpoint.OutPoint, 0, len(view.GetMap()))
+	for out, coin := range view.GetMap() {
+		if !coin.IsSpent() {
+			outs = append(outs, out)
+		}
+	}
+	sort.Slice(outs, func(i, j int) bool {
+		if c := bytes.Compare(outs[i].Hash[:], outs[j].Hash[:]); c != 0 {
+			return c < 0
+		}
+		return outs[i].Index < outs[j].Index
+	})
+	return outs
+}
+
+// utxoSetHash hashes the unspent coins of the view.
+func utxoSetHash(view *utxo.CoinsMap) util.Hash {
+	h := sha256.New()
+	for _, out := range unspents(view) {
+		coin := view.GetCoin(&out)
+		h.Write(out.Hash[:])
+		binary.Write(h, binary.LittleEndian, out.Index)
+		binary.Write(h, binary.LittleEndian, coin.GetHeight())
+		binary.Write(h, binary.LittleEndian, coin.IsCoinBase())
+		binary.Write(h, binary.LittleEndian, int64(coin.GetAmount()))
+		h.Write(coin.GetScriptPubKey().GetData())
+	}
+	var hash util.Hash
+	copy(hash[:], h.Sum(nil))
+	return hash
+}
+
+func (c *reorgChain) randomOuts(tx *tx.Tx) {
+	for n := 1 + c.r.Intn(3); n > 0; n-- {
+		value := amount.Amount(1 + c.r.Int63n(1000000))
+		tx.AddTxOut(txout.NewTxOut(value, script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+	}
+}
+
+// connectRandomBlock builds a block spending random coins of the view,
+// including coins created earlier in the same block, and connects it.
+func (c *reorgChain) connectRandomBlock() {
+	height := int32(len(c.blocks))
+	blk := block.NewBlock()
+	bundo := undo.NewBlockUndo(0)
+
+	// a random coinbase tag makes the coinbase of competing blocks differ
+	tag := make([]byte, 12)
+	binary.LittleEndian.PutUint32(tag, uint32(height))
+	c.r.Read(tag[4:])
+	coinbase := tx.NewTx(0, 1)
+	coinbase.AddTxIn(txin.NewTxIn(nil, script.NewScriptRaw(tag), script.SequenceFinal))
+	c.randomOuts(coinbase)
+	blk.Txs = append(blk.Txs, coinbase)
+	ltx.UpdateTxCoins(coinbase, c.view, nil, height)
+
+	for n := c.r.Intn(5); n > 0; n-- {
+		outs := unspents(c.view)
+		if len(outs) == 0 {
+			break
+		}
+		ins := 1 + c.r.Intn(3)
+		if ins > len(outs) {
+			ins = len(outs)
+		}
+		transaction := tx.NewTx(0, 1)
+		for _, i := range c.r.Perm(len(outs))[:ins] {
+			out := outs[i]
+			transaction.AddTxIn(txin.NewTxIn(&out, script.NewScriptRaw([]byte{opcodes.OP_TRUE}), script.SequenceFinal))
+		}
+		c.randomOuts(transaction)
+		blk.Txs = append(blk.Txs, transaction)
+
+		txundo := undo.NewTxUndo()
+		ltx.UpdateTxCoins(transaction, c.view, txundo, height)
+		bundo.AddTxUndo(txundo)
+	}
+
+	c.blocks = append(c.blocks, blk)
+	c.undos = append(c.undos, bundo)
+}
+
+// disconnectTip disconnects the last block of the chain.
+func (c *reorgChain) disconnectTip() undo.DisconnectResult {
+	last := len(c.blocks) - 1
+	res := ApplyBlockUndo(c.undos[last], c.blocks[last], c.view)
+	c.blocks, c.undos = c.blocks[:last], c.undos[:last]
+	return res
+}
+
+// replay connects the blocks of the chain to a fresh view and returns it.
+func (c *reorgChain) replay() *utxo.CoinsMap {
+	view := utxo.NewEmptyCoinsMap()
+	for height, blk := range c.blocks {
+		for i, transaction := range blk.Txs {
+			if i == 0 {
+				ltx.UpdateTxCoins(transaction, view, nil, int32(height))
+				continue
+			}
+			ltx.UpdateTxCoins(transaction, view, undo.NewTxUndo(), int32(height))
+		}
+	}
+	return view
+}
+
+// TestRandomReorgs builds random chains, reorganizes them to competing
+// branches of random depth and checks after every disconnection that the
+// UTXO set equals the one of the remaining chain replayed from scratch, so
+// that connecting and disconnecting a block are exact inverses.
+func TestRandomReorgs(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		c := newReorgChain(r)
+		for i := 0; i < 5; i++ {
+			c.connectRandomBlock()
+		}
+
+		for round := 0; round < 10; round++ {
+			depth := 1 + r.Intn(len(c.blocks))
+			for d := 0; d < depth; d++ {
+				if res := c.disconnectTip(); res != undo.DisconnectOk {
+					t.Fatalf("seed %d: disconnecting block %d returned %v", seed, len(c.blocks), res)
+				}
+				if got, want := utxoSetHash(c.view), utxoSetHash(c.replay()); got != want {
+					t.Fatalf("seed %d: UTXO set after disconnecting to height %d differs from the replayed chain",
+						seed, len(c.blocks)-1)
+				}
+			}
+			for n := depth + r.Intn(3); n > 0; n-- {
+				c.connectRandomBlock()
+			}
+			if got, want := utxoSetHash(c.view), utxoSetHash(c.replay()); got != want {
+				t.Fatalf("seed %d: UTXO set at height %d differs from the replayed chain", seed, len(c.blocks)-1)
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package lutxo
+
+import (
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"runtime"
+	"sort"
+
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+)
+
+// ErrInterrupted is returned by WalkCoins when the walk was interrupted.
+var ErrInterrupted = errors.New("interrupted")
+
+// walkYieldInterval is the number of coins walked between yields and
+// interruption checks.
+const walkYieldInterval = 10000
+
+// WalkCoins calls fn for every coin from the position of cursor on, in key
+// order. The walk gives way to other goroutines regularly so the node stays
+// responsive, stops with ErrInterrupted once interrupt is closed, and stops
+// with the error of fn when fn fails.
+func WalkCoins(cursor *utxo.CoinsCursor, interrupt <-chan struct{},
+	fn func(out *outpoint.OutPoint, coin *utxo.Coin) error) error {
+
+	walked := 0
+	for ; cursor.Valid(); cursor.Next() {
+		walked++
+		if walked%walkYieldInterval == 0 {
+			select {
+			case <-interrupt:
+				return ErrInterrupted
+			default:
+			}
+			runtime.Gosched()
+		}
+
+		out, err := cursor.GetKey()
+		if err != nil {
+			return err
+		}
+		coin, err := cursor.GetValue()
+		if err != nil {
+			return err
+		}
+		if err := fn(out, coin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CoinsStats holds statistics about the UTXO set.
+type CoinsStats struct {
+	BestBlock      util.Hash
+	Transactions   uint64
+	TxOuts         uint64
+	BogoSize       uint64
+	HashSerialized util.Hash
+	DiskSize       uint64
+	TotalAmount    amount.Amount
+}
+
+// GetCoinsStats walks the coins database and computes the statistics of the
+// UTXO set, including a hash of the serialized set compatible with the
+// hash_serialized of Bitcoin ABC. Coins not flushed to the database yet are
+// not accounted.
+func GetCoinsStats(coinsDB *utxo.CoinsDB, interrupt <-chan struct{}) (*CoinsStats, error) {
+	cursor, err := coinsDB.Cursor()
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	stats := &CoinsStats{BestBlock: cursor.GetBestBlock()}
+	hasher := sha256.New()
+	hasher.Write(stats.BestBlock[:])
+
+	var prevHash util.Hash
+	outputs := make(map[uint32]*utxo.Coin)
+	err = WalkCoins(cursor, interrupt, func(out *outpoint.OutPoint, coin *utxo.Coin) error {
+		if len(outputs) > 0 && out.Hash != prevHash {
+			applyStats(stats, hasher, &prevHash, outputs)
+			outputs = make(map[uint32]*utxo.Coin)
+		}
+		prevHash = out.Hash
+		outputs[out.Index] = coin
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(outputs) > 0 {
+		applyStats(stats, hasher, &prevHash, outputs)
+	}
+
+	stats.HashSerialized = util.Sha256Hash(hasher.Sum(nil))
+	stats.DiskSize = coinsDB.EstimateSize()
+	return stats, nil
+}
+
+// applyStats accounts the unspent outputs of one transaction.
+func applyStats(stats *CoinsStats, hasher hash.Hash, txHash *util.Hash, outputs map[uint32]*utxo.Coin) {
+	indexes := make([]uint32, 0, len(outputs))
+	for index := range outputs {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	first := outputs[indexes[0]]
+	heightAndIsCoinBase := uint64(first.GetHeight()) * 2
+	if first.IsCoinBase() {
+		heightAndIsCoinBase++
+	}
+	hasher.Write(txHash[:])
+	util.WriteVarLenInt(hasher, heightAndIsCoinBase)
+	stats.Transactions++
+	for _, index := range indexes {
+		coin := outputs[index]
+		scriptPubKey := coin.GetScriptPubKey().GetData()
+		util.WriteVarLenInt(hasher, uint64(index)+1)
+		util.WriteVarBytes(hasher, scriptPubKey)
+		util.WriteVarLenInt(hasher, uint64(coin.GetAmount()))
+		stats.TxOuts++
+		stats.TotalAmount += coin.GetAmount()
+		// txid, vout index, height and coinbase, amount, script length, script
+		stats.BogoSize += 32 + 4 + 4 + 8 + 2 + uint64(len(scriptPubKey))
+	}
+	util.WriteVarLenInt(hasher, 0)
+}
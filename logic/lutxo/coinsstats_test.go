@@ -0,0 +1,79 @@
+package lutxo
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+)
+
+func TestGetCoinsStats(t *testing.T) {
+	path, err := ioutil.TempDir("", "coinsstats")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	utxo.InitUtxoLruTip(&utxo.UtxoConfig{Do: &db.DBOption{FilePath: path, CacheSize: 1 << 20}})
+	view := utxo.GetUtxoCacheInstance()
+
+	scriptPubKey := script.NewScriptRaw([]byte{opcodes.OP_TRUE})
+	hash1 := util.HashFromString("01")
+	hash2 := util.HashFromString("02")
+	necm := utxo.NewEmptyCoinsMap()
+	necm.AddCoin(&outpoint.OutPoint{Hash: *hash1, Index: 0}, utxo.NewCoin(txout.NewTxOut(50, scriptPubKey), 1, true), true)
+	necm.AddCoin(&outpoint.OutPoint{Hash: *hash2, Index: 0}, utxo.NewCoin(txout.NewTxOut(20, scriptPubKey), 2, false), true)
+	necm.AddCoin(&outpoint.OutPoint{Hash: *hash2, Index: 200}, utxo.NewCoin(txout.NewTxOut(10, scriptPubKey), 2, false), true)
+	bestBlock := util.HashFromString("03")
+	if err := view.UpdateCoins(necm, bestBlock); err != nil {
+		t.Fatal(err)
+	}
+	view.Flush()
+
+	coinsDB := view.GetCoinsDB()
+	stats, err := GetCoinsStats(&coinsDB, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.BestBlock != *bestBlock {
+		t.Errorf("got best block %s, want %s", stats.BestBlock.String(), bestBlock.String())
+	}
+	if stats.Transactions != 2 || stats.TxOuts != 3 {
+		t.Errorf("got %d transactions and %d outputs, want 2 and 3", stats.Transactions, stats.TxOuts)
+	}
+	if stats.TotalAmount != amount.Amount(80) {
+		t.Errorf("got total amount %d, want 80", stats.TotalAmount)
+	}
+	if stats.BogoSize != 3*(50+1) {
+		t.Errorf("got bogosize %d, want %d", stats.BogoSize, 3*(50+1))
+	}
+
+	again, err := GetCoinsStats(&coinsDB, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.HashSerialized != stats.HashSerialized {
+		t.Error("hash_serialized of the same UTXO set should not change")
+	}
+
+	necm = utxo.NewEmptyCoinsMap()
+	necm.AddCoin(&outpoint.OutPoint{Hash: *hash2, Index: 1}, utxo.NewCoin(txout.NewTxOut(1, scriptPubKey), 4, false), true)
+	if err := view.UpdateCoins(necm, util.HashFromString("04")); err != nil {
+		t.Fatal(err)
+	}
+	view.Flush()
+	changed, err := GetCoinsStats(&coinsDB, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.HashSerialized == stats.HashSerialized || changed.TxOuts != 4 || changed.Transactions != 2 {
+		t.Errorf("stats should account the new output, got %+v", changed)
+	}
+}
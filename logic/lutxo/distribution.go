@@ -0,0 +1,106 @@
+package lutxo
+
+import (
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+)
+
+// ValueBucketBounds are the inclusive upper bounds of the value buckets of a
+// CoinsDistribution, from the dust limit up to 100 BCH. A last bucket holds
+// the outputs above them.
+var ValueBucketBounds = []amount.Amount{
+	546, 1000, 10000, 100000, 1000000, 10000000, 100000000, 1000000000, 10000000000,
+}
+
+// AgeBucketBounds are the inclusive upper bounds of the age buckets of a
+// CoinsDistribution, in blocks: an hour, a day, a week, a month, a year, two
+// and five years. A last bucket holds the older outputs.
+var AgeBucketBounds = []int32{6, 144, 1008, 4320, 52560, 105120, 262800}
+
+// CoinsBucket counts the unspent outputs of one bucket of a distribution.
+type CoinsBucket struct {
+	Count  uint64
+	Amount amount.Amount
+}
+
+func (b *CoinsBucket) add(coin *utxo.Coin) {
+	b.Count++
+	b.Amount += coin.GetAmount()
+}
+
+// CoinsDistribution holds how the unspent outputs are distributed by value,
+// by age and by script type.
+type CoinsDistribution struct {
+	BestBlock util.Hash
+	// TxOuts is the number of outputs walked, Sampled the number of them
+	// accounted in the buckets.
+	TxOuts       uint64
+	Sampled      uint64
+	ByValue      []CoinsBucket
+	ByAge        []CoinsBucket
+	ByScriptType map[string]*CoinsBucket
+}
+
+// sampled reports whether out is part of a sample of one output in
+// sampleRate. The choice depends on the outpoint only, so the same outputs are
+// picked on every run.
+func sampled(out *outpoint.OutPoint, sampleRate uint32) bool {
+	if sampleRate <= 1 {
+		return true
+	}
+	return util.SipHashExtra(0, 0, out.Hash[:], out.Index)%uint64(sampleRate) == 0
+}
+
+// GetCoinsDistribution walks the coins of cursor and accounts one coin in
+// sampleRate in the buckets of the distribution, all of them when sampleRate
+// is 0 or 1. Ages are counted from height, the height of the best block of
+// the cursor. Only the buckets are kept in memory, however large the UTXO
+// set is. Coins not flushed to the database yet are not accounted.
+func GetCoinsDistribution(cursor *utxo.CoinsCursor, height int32, sampleRate uint32,
+	interrupt <-chan struct{}) (*CoinsDistribution, error) {
+
+	dist := &CoinsDistribution{
+		BestBlock:    cursor.GetBestBlock(),
+		ByValue:      make([]CoinsBucket, len(ValueBucketBounds)+1),
+		ByAge:        make([]CoinsBucket, len(AgeBucketBounds)+1),
+		ByScriptType: make(map[string]*CoinsBucket),
+	}
+	err := WalkCoins(cursor, interrupt, func(out *outpoint.OutPoint, coin *utxo.Coin) error {
+		dist.TxOuts++
+		if !sampled(out, sampleRate) {
+			return nil
+		}
+		dist.Sampled++
+
+		value := coin.GetAmount()
+		i := 0
+		for i < len(ValueBucketBounds) && value > ValueBucketBounds[i] {
+			i++
+		}
+		dist.ByValue[i].add(coin)
+
+		age := height - coin.GetHeight()
+		i = 0
+		for i < len(AgeBucketBounds) && age > AgeBucketBounds[i] {
+			i++
+		}
+		dist.ByAge[i].add(coin)
+
+		scriptType, _, _ := coin.GetScriptPubKey().CheckScriptPubKeyStandard()
+		name := script.ScriptTypeName(scriptType)
+		bucket, ok := dist.ByScriptType[name]
+		if !ok {
+			bucket = new(CoinsBucket)
+			dist.ByScriptType[name] = bucket
+		}
+		bucket.add(coin)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dist, nil
+}
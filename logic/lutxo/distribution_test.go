@@ -0,0 +1,97 @@
+package lutxo
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+)
+
+func TestGetCoinsDistribution(t *testing.T) {
+	path, err := ioutil.TempDir("", "coinsdistribution")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	utxo.InitUtxoLruTip(&utxo.UtxoConfig{Do: &db.DBOption{FilePath: path, CacheSize: 1 << 20}})
+	view := utxo.GetUtxoCacheInstance()
+
+	p2sh := script.NewScriptRaw(append(append([]byte{opcodes.OP_HASH160, 20}, make([]byte, 20)...), opcodes.OP_EQUAL))
+	p2pkh := script.NewScriptRaw(append(append([]byte{opcodes.OP_DUP, opcodes.OP_HASH160, 20},
+		make([]byte, 20)...), opcodes.OP_EQUALVERIFY, opcodes.OP_CHECKSIG))
+	hash1 := util.HashFromString("01")
+	hash2 := util.HashFromString("02")
+	necm := utxo.NewEmptyCoinsMap()
+	necm.AddCoin(&outpoint.OutPoint{Hash: *hash1, Index: 0}, utxo.NewCoin(txout.NewTxOut(5000000000, p2pkh), 1, true), true)
+	necm.AddCoin(&outpoint.OutPoint{Hash: *hash2, Index: 0}, utxo.NewCoin(txout.NewTxOut(546, p2pkh), 1000, false), true)
+	necm.AddCoin(&outpoint.OutPoint{Hash: *hash2, Index: 1}, utxo.NewCoin(txout.NewTxOut(700, p2sh), 1000, false), true)
+	bestBlock := util.HashFromString("03")
+	if err := view.UpdateCoins(necm, bestBlock); err != nil {
+		t.Fatal(err)
+	}
+	view.Flush()
+
+	coinsDB := view.GetCoinsDB()
+	distribution := func(sampleRate uint32) (*CoinsDistribution, error) {
+		cursor, err := coinsDB.Cursor()
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close()
+		return GetCoinsDistribution(cursor, 1001, sampleRate, nil)
+	}
+	dist, err := distribution(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dist.BestBlock != *bestBlock {
+		t.Errorf("got best block %s, want %s", dist.BestBlock.String(), bestBlock.String())
+	}
+	if dist.TxOuts != 3 || dist.Sampled != 3 {
+		t.Errorf("got %d outputs and %d sampled, want 3 and 3", dist.TxOuts, dist.Sampled)
+	}
+	if b := dist.ByValue[0]; b.Count != 1 || b.Amount != 546 {
+		t.Errorf("got dust value bucket %+v", b)
+	}
+	if b := dist.ByValue[1]; b.Count != 1 || b.Amount != 700 {
+		t.Errorf("got second value bucket %+v", b)
+	}
+	if b := dist.ByValue[len(ValueBucketBounds)-1]; b.Count != 1 || b.Amount != amount.Amount(5000000000) {
+		t.Errorf("got 50 BCH value bucket %+v", b)
+	}
+	if b := dist.ByAge[0]; b.Count != 2 {
+		t.Errorf("got first age bucket %+v", b)
+	}
+	if b := dist.ByAge[2]; b.Count != 1 || b.Amount != amount.Amount(5000000000) {
+		t.Errorf("got week age bucket %+v", b)
+	}
+	if b := dist.ByScriptType["pubkeyhash"]; b == nil || b.Count != 2 || b.Amount != 5000000546 {
+		t.Errorf("got pubkeyhash bucket %+v", b)
+	}
+	if b := dist.ByScriptType["scripthash"]; b == nil || b.Count != 1 {
+		t.Errorf("got scripthash bucket %+v", b)
+	}
+
+	sample, err := distribution(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sample.TxOuts != 3 || sample.Sampled > sample.TxOuts {
+		t.Errorf("got %d outputs and %d sampled", sample.TxOuts, sample.Sampled)
+	}
+	again, err := distribution(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Sampled != sample.Sampled {
+		t.Error("sampling the same UTXO set should pick the same outputs")
+	}
+}
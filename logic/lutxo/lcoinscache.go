@@ -1,19 +1,44 @@
 package lutxo
 
 import (
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/model/outpoint"
 	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/persist/blkdb"
+	"github.com/copernet/copernicus/persist/disk"
 	"github.com/copernet/copernicus/util"
 )
 
+// AccessByTxid returns an unspent coin of the transaction, or nil if all its
+// outputs are spent or the transaction is unknown.
 func AccessByTxid(coinsCache utxo.CacheView, hash *util.Hash) *utxo.Coin {
-	out := outpoint.OutPoint{Hash: *hash, Index: 0}
-	for int(out.Index) < 11000 { // todo modify to be precise
-		alternate := coinsCache.GetCoin(&out)
-		if !alternate.IsSpent() {
-			return alternate
+	for _, index := range txOutIndexes(coinsCache, hash) {
+		coin := coinsCache.GetCoin(&outpoint.OutPoint{Hash: *hash, Index: index})
+		if coin != nil && !coin.IsSpent() {
+			return coin
 		}
-		out.Index++
 	}
 	return nil
 }
+
+// txOutIndexes returns the output indexes of the transaction worth looking up:
+// all its outputs when the transaction index knows it, or else the ones the
+// coins view still holds.
+func txOutIndexes(coinsCache utxo.CacheView, hash *util.Hash) []uint32 {
+	if conf.Cfg != nil && conf.Cfg.Chain.TxIndex {
+		pos, err := blkdb.GetInstance().ReadTxIndex(hash)
+		if err != nil {
+			log.Error("AccessByTxid: read tx index of %s failed: %v", hash.String(), err)
+		} else if pos != nil {
+			if transaction, _, err := disk.ReadTxFromDisk(pos); err == nil {
+				indexes := make([]uint32, transaction.GetOutsCount())
+				for i := range indexes {
+					indexes[i] = uint32(i)
+				}
+				return indexes
+			}
+		}
+	}
+	return coinsCache.GetTxOutIndexes(hash)
+}
@@ -0,0 +1,41 @@
+package lutxo
+
+import (
+	"errors"
+
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/consensus"
+	"github.com/copernet/copernicus/model/utxo"
+)
+
+// The heights the coins are spent at.  The mempool and block connection
+// must agree on them, or a transaction accepted to the mempool could make
+// the block mined with it invalid, and the other way round.
+
+// GetMempoolSpendHeight returns the height the transactions of the mempool
+// spend their coins at: the height of the block following the best block of
+// the coins cache, the one they would be mined in.
+func GetMempoolSpendHeight() (int32, error) {
+	bestBlockHash, err := utxo.GetUtxoCacheInstance().GetBestBlock()
+	if err != nil {
+		return -1, err
+	}
+	spendHeight := chain.GetInstance().GetSpendHeight(&bestBlockHash)
+	if spendHeight == -1 {
+		return -1, errors.New("indexMap can`t find block")
+	}
+	return spendHeight, nil
+}
+
+// GetBlockSpendHeight returns the height the transactions of a block connected
+// at blockHeight spend their coins at, the height of the block itself.
+func GetBlockSpendHeight(blockHeight int32) int32 {
+	return blockHeight
+}
+
+// IsCoinMature returns whether coin can be spent at spendHeight: the outputs
+// of a coinbase need CoinbaseMaturity blocks on top of the block which
+// created them, counting the block they are spent in.
+func IsCoinMature(coin *utxo.Coin, spendHeight int32) bool {
+	return !coin.IsCoinBase() || spendHeight-coin.GetHeight() >= consensus.CoinbaseMaturity
+}
@@ -0,0 +1,147 @@
+package lutxo
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/consensus"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/copernet/copernicus/util"
+)
+
+func newTestCoin(height int32, isCoinBase bool) *utxo.Coin {
+	scriptPubKey := script.NewScriptRaw([]byte{opcodes.OP_TRUE})
+	return utxo.NewCoin(txout.NewTxOut(50, scriptPubKey), height, isCoinBase)
+}
+
+func TestIsCoinMature(t *testing.T) {
+	tests := []struct {
+		name        string
+		coin        *utxo.Coin
+		spendHeight int32
+		mature      bool
+	}{
+		{"regular output in the next block", newTestCoin(10, false), 11, true},
+		{"coinbase in the next block", newTestCoin(10, true), 11, false},
+		{"coinbase one block short", newTestCoin(10, true), 10 + consensus.CoinbaseMaturity - 1, false},
+		{"coinbase at maturity", newTestCoin(10, true), 10 + consensus.CoinbaseMaturity, true},
+		{"coinbase past maturity", newTestCoin(10, true), 10 + consensus.CoinbaseMaturity + 1, true},
+		{"genesis coinbase at maturity", newTestCoin(0, true), consensus.CoinbaseMaturity, true},
+	}
+	for _, test := range tests {
+		if got := IsCoinMature(test.coin, test.spendHeight); got != test.mature {
+			t.Errorf("%s: IsCoinMature at %d = %v, want %v", test.name, test.spendHeight, got, test.mature)
+		}
+	}
+}
+
+// buildTestChain loads a chain of n blocks into the global chain, returning
+// their indexes by height.
+func buildTestChain(n int) []*blockindex.BlockIndex {
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	chain.InitGlobalChain()
+	indexes := make([]*blockindex.BlockIndex, n)
+	indexMap := make(map[util.Hash]*blockindex.BlockIndex, n)
+	for height := range indexes {
+		header := model.ActiveNetParams.GenesisBlock.Header
+		if height > 0 {
+			header = block.BlockHeader{
+				Version:       1,
+				HashPrevBlock: *indexes[height-1].GetBlockHash(),
+				Time:          indexes[height-1].Header.Time + 600,
+				Bits:          model.ActiveNetParams.PowLimitBits,
+			}
+		}
+		index := blockindex.NewBlockIndex(&header)
+		index.Height = int32(height)
+		if height > 0 {
+			index.Prev = indexes[height-1]
+		}
+		indexes[height] = index
+		indexMap[*index.GetBlockHash()] = index
+	}
+	chain.GetInstance().InitLoad(indexMap, nil)
+	return indexes
+}
+
+// TestSpendHeightsAcrossReorg follows an immature coinbase through a chain
+// growing past its maturity, reorganized below it and extended again: the
+// mempool and the blocks must agree whether the coin can be spent.
+func TestSpendHeightsAcrossReorg(t *testing.T) {
+	path, err := ioutil.TempDir("", "maturity")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	utxo.InitUtxoLruTip(&utxo.UtxoConfig{Do: &db.DBOption{FilePath: path, CacheSize: 1 << 20}})
+	view := utxo.GetUtxoCacheInstance()
+
+	coinbaseHeight := int32(1)
+	indexes := buildTestChain(int(coinbaseHeight+consensus.CoinbaseMaturity) + 1)
+	coin := newTestCoin(coinbaseHeight, true)
+
+	setTip := func(height int32) {
+		if err := view.UpdateCoins(utxo.NewEmptyCoinsMap(), indexes[height].GetBlockHash()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	steps := []struct {
+		name   string
+		tip    int32
+		mature bool
+	}{
+		{"one block short", coinbaseHeight + consensus.CoinbaseMaturity - 2, false},
+		{"mature in the next block", coinbaseHeight + consensus.CoinbaseMaturity - 1, true},
+		{"past maturity", coinbaseHeight + consensus.CoinbaseMaturity, true},
+		{"reorganized below maturity", coinbaseHeight + consensus.CoinbaseMaturity - 2, false},
+		{"extended again", coinbaseHeight + consensus.CoinbaseMaturity - 1, true},
+	}
+	for _, step := range steps {
+		setTip(step.tip)
+		mempoolHeight, err := GetMempoolSpendHeight()
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		blockHeight := GetBlockSpendHeight(step.tip + 1)
+		if mempoolHeight != blockHeight {
+			t.Errorf("%s: the mempool spends at %d and the next block at %d",
+				step.name, mempoolHeight, blockHeight)
+		}
+		if got := IsCoinMature(coin, mempoolHeight); got != step.mature {
+			t.Errorf("%s: coinbase mature for the mempool = %v, want %v", step.name, got, step.mature)
+		}
+		if got := IsCoinMature(coin, blockHeight); got != step.mature {
+			t.Errorf("%s: coinbase mature for the next block = %v, want %v", step.name, got, step.mature)
+		}
+	}
+}
+
+func TestGetMempoolSpendHeightUnknownBlock(t *testing.T) {
+	path, err := ioutil.TempDir("", "maturity")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	utxo.InitUtxoLruTip(&utxo.UtxoConfig{Do: &db.DBOption{FilePath: path, CacheSize: 1 << 20}})
+	buildTestChain(1)
+
+	view := utxo.GetUtxoCacheInstance()
+	if err := view.UpdateCoins(utxo.NewEmptyCoinsMap(), util.HashFromString("ff")); err != nil {
+		t.Fatal(err)
+	}
+	if height, err := GetMempoolSpendHeight(); err == nil {
+		t.Errorf("got spend height %d for a best block not in the index", height)
+	}
+}
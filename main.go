@@ -11,14 +11,22 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"path/filepath"
 	"runtime"
 	"runtime/debug"
 
 	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/lmempool"
+	"github.com/copernet/copernicus/logic/ltx"
 	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/net/limits"
 	"github.com/copernet/copernicus/net/server"
+	"github.com/copernet/copernicus/net/zmq"
+	"github.com/copernet/copernicus/persist/disk"
 	"github.com/copernet/copernicus/rpc"
+	"github.com/copernet/copernicus/service/notify"
 	"net"
 )
 
@@ -34,7 +42,9 @@ const (
 func bchMain(ctx context.Context, args []string) error {
 	// Load configuration and parse command line.  This function also
 	// initializes logging and configures it accordingly.
-	appInitMain(args)
+	if err := appInitMain(args); err != nil {
+		return err
+	}
 	go func() {
 		listenAddr := net.JoinHostPort(conf.Cfg.PProf.IP, conf.Cfg.PProf.Port)
 		fmt.Printf("Profile server listening on %s\n", listenAddr)
@@ -50,7 +60,7 @@ func bchMain(ctx context.Context, args []string) error {
 	}
 	var rpcServer *rpc.Server
 	if !conf.Cfg.P2PNet.DisableRPC {
-		rpcServer, err = rpc.InitRPCServer()
+		rpcServer, err = rpc.InitRPCServer(s.FeeEstimator())
 		if err != nil {
 			return errors.New("failed to init rpc")
 		}
@@ -60,26 +70,90 @@ func bchMain(ctx context.Context, args []string) error {
 		rpcServer.Start()
 	}
 
+	var notifier *zmq.Notifier
+	if endpoints := zmqEndpoints(); len(endpoints) > 0 {
+		notifier, err = zmq.NewNotifier(chain.GetInstance(), endpoints, conf.Cfg.ZMQ.PubHWM)
+		if err != nil {
+			log.Error("Failed to start the zmq notifications: %v", err)
+			if rpcServer != nil {
+				rpcServer.Stop()
+			}
+			return err
+		}
+	}
+
+	var hooks *notify.Notifier
+	if conf.Cfg.Notify.BlockNotify != "" || conf.Cfg.Notify.AlertNotify != "" {
+		hooks = notify.NewNotifier(chain.GetInstance(), conf.Cfg.Notify.BlockNotify,
+			conf.Cfg.Notify.AlertNotify)
+	}
+
 	server.SetMsgHandle(context.TODO(), s.MsgChan, s)
 	if interruptRequested(interrupt) {
 		return nil
 	}
 	s.Start()
-	defer func() {
-		s.Stop()
-		// Shutdown the RPC server if it's not disabled.
-		if !conf.Cfg.P2PNet.DisableRPC {
-			rpcServer.Stop()
-		}
-	}()
-	go func() {
-		<-rpcServer.RequestedProcessShutdown()
-		shutdownRequestChannel <- struct{}{}
-	}()
+	defer shutdown(s, rpcServer, notifier, hooks)
+	if rpcServer != nil {
+		go func() {
+			<-rpcServer.RequestedProcessShutdown()
+			shutdownRequestChannel <- struct{}{}
+		}()
+	}
 	<-interrupt
 	return nil
 }
 
+// zmqEndpoints returns the endpoints of the configured zmq topics.
+func zmqEndpoints() map[string]string {
+	endpoints := make(map[string]string)
+	for topic, endpoint := range map[string]string{
+		zmq.TopicHashTx:    conf.Cfg.ZMQ.PubHashTx,
+		zmq.TopicRawTx:     conf.Cfg.ZMQ.PubRawTx,
+		zmq.TopicHashBlock: conf.Cfg.ZMQ.PubHashBlock,
+		zmq.TopicRawBlock:  conf.Cfg.ZMQ.PubRawBlock,
+	} {
+		if endpoint != "" {
+			endpoints[topic] = endpoint
+		}
+	}
+	return endpoints
+}
+
+// shutdown tears the subsystems down in order, so that the node restarts
+// where it stopped: the RPC server first, waiting for the calls in flight,
+// then the peers, whose addresses are saved, and the zmq notifications and
+// the notification commands once no more blocks come in, before the mempool
+// is persisted and the UTXO cache and block files are flushed to disk.
+func shutdown(s *server.Server, rpcServer *rpc.Server, notifier *zmq.Notifier,
+	hooks *notify.Notifier) {
+	if rpcServer != nil {
+		rpcServer.Stop()
+	}
+	s.Stop()
+	s.WaitForShutdown()
+	if notifier != nil {
+		notifier.Stop()
+	}
+	if hooks != nil {
+		hooks.Stop()
+	}
+	ltx.StopMempoolScriptChecks()
+
+	if err := lmempool.SaveMempool(filepath.Join(conf.Cfg.DataDir, lmempool.MempoolFileName)); err != nil {
+		log.Error("Failed to dump mempool: %v", err)
+	}
+	feeEstimatesPath := filepath.Join(conf.Cfg.DataDir, lmempool.FeeEstimatesFileName)
+	if err := lmempool.SaveFeeEstimator(s.FeeEstimator(), feeEstimatesPath); err != nil {
+		log.Error("Failed to save fee estimates: %v", err)
+	}
+
+	if err := disk.FlushStateToDisk(disk.FlushStateAlways, 0); err != nil {
+		log.Error("Failed to flush the chain state: %v", err)
+	}
+	log.Info("Shutdown complete")
+}
+
 func main() {
 	// Use all processor cores.
 	runtime.GOMAXPROCS(runtime.NumCPU())
@@ -99,6 +173,7 @@ func main() {
 	args := os.Args
 	// Work around defer not working after os.Exit()
 	if err := bchMain(context.Background(), args); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
 }
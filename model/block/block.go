@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sync/atomic"
 
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/logic/lmerkleroot"
@@ -22,6 +23,28 @@ type Block struct {
 
 const MinBlocksToKeep = int32(288)
 
+// maxPreallocTxs bounds the transaction slice allocated from the count a
+// block claims, before any of its transactions has actually been read.
+const maxPreallocTxs = 1 << 14
+
+// maxBlockSize is the excessive block size: blocks larger than this are
+// rejected, already while they are unserialized.
+var maxBlockSize uint64 = consensus.DefaultMaxBlockSize
+
+func GetMaxBlockSize() uint64 {
+	return atomic.LoadUint64(&maxBlockSize)
+}
+
+func SetMaxBlockSize(size uint64) {
+	atomic.StoreUint64(&maxBlockSize, size)
+}
+
+// GetMaxTxCount returns the most transactions a block within the excessive
+// block size can hold.
+func GetMaxTxCount() uint64 {
+	return GetMaxBlockSize() / consensus.MinTxSize
+}
+
 func (bl *Block) GetBlockHeader() BlockHeader {
 	return bl.Header
 }
@@ -74,27 +97,58 @@ func (bl *Block) EncodeSize() int {
 
 func (bl *Block) Unserialize(r io.Reader) error {
 	log.Trace("block Unserialize.... ")
-	if err := bl.Header.Unserialize(r); err != nil {
+	maxSize := GetMaxBlockSize()
+	lr := &sizeLimitedReader{r: r, remain: maxSize}
+	if err := bl.Header.Unserialize(lr); err != nil {
 		return err
 	}
-	ntx, err := util.ReadVarInt(r)
+	ntx, err := util.ReadVarInt(lr)
 	if err != nil {
 		return err
 	}
-	if ntx > consensus.MaxTxCount {
-		return fmt.Errorf("recv %d transactions, but allow max %d", ntx, consensus.MaxTxCount)
+	if maxTxCount := GetMaxTxCount(); ntx > maxTxCount {
+		return fmt.Errorf("recv %d transactions, but allow max %d", ntx, maxTxCount)
 	}
-	bl.Txs = make([]*tx.Tx, ntx)
-	for i := 0; i < int(ntx); i++ {
+	prealloc := ntx
+	if prealloc > maxPreallocTxs {
+		prealloc = maxPreallocTxs
+	}
+	bl.Txs = make([]*tx.Tx, 0, prealloc)
+	for i := uint64(0); i < ntx; i++ {
 		tx := tx.NewTx(0, 0)
-		if err := tx.Unserialize(r); err != nil {
+		if err := tx.Unserialize(lr); err != nil {
+			if lr.exceeded {
+				return fmt.Errorf("block exceeds the max block size %d", maxSize)
+			}
 			return err
 		}
-		bl.Txs[i] = tx
+		bl.Txs = append(bl.Txs, tx)
 	}
 	return nil
 }
 
+// sizeLimitedReader fails reads past remain bytes, so a block claiming huge
+// transactions is given up once it outgrows the max block size instead of
+// after it has been read completely.
+type sizeLimitedReader struct {
+	r        io.Reader
+	remain   uint64
+	exceeded bool
+}
+
+func (lr *sizeLimitedReader) Read(p []byte) (int, error) {
+	if lr.remain == 0 {
+		lr.exceeded = true
+		return 0, io.ErrUnexpectedEOF
+	}
+	if uint64(len(p)) > lr.remain {
+		p = p[:lr.remain]
+	}
+	n, err := lr.r.Read(p)
+	lr.remain -= uint64(n)
+	return n, err
+}
+
 func (bl *Block) GetHash() util.Hash {
 	bh := bl.Header
 	return bh.GetHash()
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/hex"
 	"testing"
+
+	"github.com/copernet/copernicus/model/consensus"
 )
 
 func TestBlockEncodeAndDecode(t *testing.T) {
@@ -25,6 +27,36 @@ func TestBlockEncodeAndDecode(t *testing.T) {
 	}
 }
 
+func TestBlockUnserializeLimits(t *testing.T) {
+	rawBlockBytes, err := hex.DecodeString(rawBlock)
+	if err != nil {
+		panic(err)
+	}
+	defer SetMaxBlockSize(GetMaxBlockSize())
+
+	// the sample block fits exactly
+	SetMaxBlockSize(uint64(len(rawBlockBytes)))
+	bk := Block{}
+	if err := bk.Unserialize(bytes.NewReader(rawBlockBytes)); err != nil {
+		t.Errorf("block of the max size should be accepted: %v", err)
+	}
+
+	SetMaxBlockSize(uint64(len(rawBlockBytes) - 1))
+	if err := bk.Unserialize(bytes.NewReader(rawBlockBytes)); err == nil {
+		t.Errorf("block larger than the max size should be rejected")
+	}
+
+	// a header claiming more transactions than the max size can hold
+	SetMaxBlockSize(consensus.OneMegaByte)
+	if GetMaxTxCount() != consensus.OneMegaByte/consensus.MinTxSize {
+		t.Errorf("max transaction count %d does not follow the max block size", GetMaxTxCount())
+	}
+	tooManyTxs := append(append([]byte{}, rawBlockBytes[:80]...), 0xfe, 0xff, 0xff, 0xff, 0xff)
+	if err := bk.Unserialize(bytes.NewReader(tooManyTxs)); err == nil {
+		t.Errorf("excessive transaction count should be rejected")
+	}
+}
+
 var rawBlock = "00000020d2d497201d88978b326a12f04f609a6f1f1425ae63c46c8ac72a200000000000dd241423caab3" +
 	"a32cdcd498a0762293787c3db01710812b901af822e2b019c5410afe05affff001d70baa7f70d0100000001000000000" +
 	"0000000000000000000000000000000000000000000000000000000ffffffff0a031dc012055374617368ffffffff013" +
@@ -65,8 +65,8 @@ type BlockIndex struct {
 	//status of this block. See enum
 	Status uint32
 	// (memory only) Sequential id assigned to distinguish order in which
-	// blocks are received.
-	SequenceID uint64
+	// blocks are received. Blocks made precious get negative ids.
+	SequenceID int64
 	// (memory only) Maximum time in the chain upto and including this block.
 	TimeMax   uint32
 	isGenesis bool
@@ -252,6 +252,23 @@ func (bIndex *BlockIndex) GetAncestor(height int32) *BlockIndex {
 	return indexWalk
 }
 
+// LastCommonAncestor returns the last block the branches of a and b share,
+// nil when either is nil.
+func LastCommonAncestor(a, b *BlockIndex) *BlockIndex {
+	if a == nil || b == nil {
+		return nil
+	}
+	if a.Height > b.Height {
+		a = a.GetAncestor(b.Height)
+	} else if b.Height > a.Height {
+		b = b.GetAncestor(a.Height)
+	}
+	for a != b && a != nil && b != nil {
+		a, b = a.Prev, b.Prev
+	}
+	return a
+}
+
 func (bIndex *BlockIndex) String() string {
 	hash := bIndex.GetBlockHash()
 	return fmt.Sprintf("BlockIndex(pprev=%p, height=%d, merkle=%s, hashBlock=%s)\n", bIndex.Prev,
@@ -74,6 +74,48 @@ func TestBlockIndexGetAncestor(t *testing.T) {
 
 }
 
+func TestLastCommonAncestor(t *testing.T) {
+	// a branch of 10 blocks and a side branch of 5 forking at height 3
+	active := make([]BlockIndex, 10)
+	side := make([]BlockIndex, 5)
+	for i := range active {
+		active[i].Height = int32(i)
+		if i > 0 {
+			active[i].Prev = &active[i-1]
+		}
+	}
+	for i := range side {
+		side[i].Height = int32(i + 4)
+		side[i].Prev = &active[3]
+		if i > 0 {
+			side[i].Prev = &side[i-1]
+		}
+	}
+
+	tests := []struct {
+		a, b     *BlockIndex
+		ancestor *BlockIndex
+	}{
+		{&active[9], &side[4], &active[3]},
+		{&side[2], &active[5], &active[3]},
+		{&active[9], &active[6], &active[6]},
+		{&active[2], &side[0], &active[2]},
+		{&active[4], nil, nil},
+		{nil, &active[4], nil},
+	}
+	for i, test := range tests {
+		if ancestor := LastCommonAncestor(test.a, test.b); ancestor != test.ancestor {
+			t.Errorf("test %d: got ancestor %p, expect %p", i, ancestor, test.ancestor)
+		}
+	}
+
+	// branches of different chains share no block
+	other := BlockIndex{Height: 0}
+	if ancestor := LastCommonAncestor(&active[5], &other); ancestor != nil {
+		t.Errorf("got ancestor %p of branches of different chains", ancestor)
+	}
+}
+
 func TestGetBlockTimeMax(t *testing.T) {
 	var bIndex BlockIndex
 	testValue := uint32(1324)
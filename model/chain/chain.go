@@ -2,6 +2,8 @@ package chain
 
 import (
 	"github.com/copernet/copernicus/model"
+	"math"
+	"math/big"
 	"sort"
 	"sync"
 
@@ -21,19 +23,34 @@ import (
 
 // Chain An in-memory blIndexed chain of blocks.
 type Chain struct {
+	// active holds the blocks of the active chain by height, so that height
+	// lookups are a slice index. activeLock guards it, as it is updated on
+	// tip changes while RPC and network handlers look blocks up.
+	activeLock  sync.RWMutex
 	active      []*blockindex.BlockIndex
 	branch      []*blockindex.BlockIndex
 	waitForTx   map[util.Hash]*blockindex.BlockIndex
 	orphan      map[util.Hash][]*blockindex.BlockIndex // preHash : *index
 	indexMap    map[util.Hash]*blockindex.BlockIndex   // selfHash :*index
 	newestBlock *blockindex.BlockIndex
-	receiveID   uint64
+	bestHeader  *blockindex.BlockIndex
+	receiveID   int64
 	params      *model.BitcoinParams
 
+	// indexLock guards indexMap, which headers are added to while RPC
+	// handlers look blocks up and walk it.
+	indexLock sync.RWMutex
+
+	// preciousID is the sequence id given to the next precious block, it
+	// restarts whenever the tip gains work over lastPreciousWork.
+	preciousID       int64
+	lastPreciousWork big.Int
+
 	// The notifications field stores a slice of callbacks to be executed on
 	// certain blockchain events.
 	notificationsLock sync.RWMutex
 	notifications     []NotificationCallback
+	subscriptions     []*Subscription
 }
 
 var globalChain *Chain
@@ -72,13 +89,41 @@ func (c *Chain) GetParams() *model.BitcoinParams {
 
 //InitLoad load the maps of the chain
 func (c *Chain) InitLoad(indexMap map[util.Hash]*blockindex.BlockIndex, branch []*blockindex.BlockIndex) {
+	c.indexLock.Lock()
+	defer c.indexLock.Unlock()
 	c.indexMap = indexMap
 	c.branch = branch
+	c.bestHeader = nil
+	for _, index := range indexMap {
+		c.updateBestHeader(index)
+	}
+}
+
+// updateBestHeader makes index the best header when it has more work.
+func (c *Chain) updateBestHeader(index *blockindex.BlockIndex) {
+	if index.IsInvalid() {
+		return
+	}
+	if c.bestHeader == nil || index.ChainWork.Cmp(&c.bestHeader.ChainWork) > 0 {
+		c.bestHeader = index
+	}
+}
+
+// BestHeader returns the header with the most work known, which is ahead of
+// the tip while the blocks of a better chain are downloaded.
+func (c *Chain) BestHeader() *blockindex.BlockIndex {
+	if c.bestHeader == nil || c.bestHeader.IsInvalid() {
+		return c.Tip()
+	}
+	return c.bestHeader
 }
 
 // Genesis Returns the blIndex entry for the genesis block of this chain,
 // or nullptr if none.
 func (c *Chain) Genesis() *blockindex.BlockIndex {
+	c.activeLock.RLock()
+	defer c.activeLock.RUnlock()
+
 	if len(c.active) > 0 {
 		return c.active[0]
 	}
@@ -86,14 +131,16 @@ func (c *Chain) Genesis() *blockindex.BlockIndex {
 	return nil
 }
 
-func (c *Chain) GetReceivedID() uint64 {
+func (c *Chain) GetReceivedID() int64 {
 	c.receiveID++
 	return c.receiveID - 1
 }
 
 // FindHashInActive finds blockindex from active
 func (c *Chain) FindHashInActive(hash util.Hash) *blockindex.BlockIndex {
+	c.indexLock.RLock()
 	bi, ok := c.indexMap[hash]
+	c.indexLock.RUnlock()
 	if ok {
 		if c.Contains(bi) {
 			return bi
@@ -106,6 +153,8 @@ func (c *Chain) FindHashInActive(hash util.Hash) *blockindex.BlockIndex {
 
 // FindBlockIndex finds blockindex from blockIndexMap
 func (c *Chain) FindBlockIndex(hash util.Hash) *blockindex.BlockIndex {
+	c.indexLock.RLock()
+	defer c.indexLock.RUnlock()
 	bi, ok := c.indexMap[hash]
 	if ok {
 		//log.Trace("current chain Tip header height : %d", bi.Height)
@@ -117,6 +166,9 @@ func (c *Chain) FindBlockIndex(hash util.Hash) *blockindex.BlockIndex {
 
 // Tip Returns the blIndex entry for the tip of this chain, or nullptr if none.
 func (c *Chain) Tip() *blockindex.BlockIndex {
+	c.activeLock.RLock()
+	defer c.activeLock.RUnlock()
+
 	if len(c.active) > 0 {
 		return c.active[len(c.active)-1]
 	}
@@ -125,6 +177,9 @@ func (c *Chain) Tip() *blockindex.BlockIndex {
 }
 
 func (c *Chain) TipHeight() int32 {
+	c.activeLock.RLock()
+	defer c.activeLock.RUnlock()
+
 	if len(c.active) > 0 {
 		return c.active[len(c.active)-1].Height
 	}
@@ -133,6 +188,8 @@ func (c *Chain) TipHeight() int32 {
 }
 
 func (c *Chain) GetSpendHeight(hash *util.Hash) int32 {
+	c.indexLock.RLock()
+	defer c.indexLock.RUnlock()
 	index, ok := c.indexMap[*hash]
 	if ok {
 		return index.Height + 1
@@ -218,6 +275,9 @@ func (c *Chain) GetBlockScriptFlags(pindex *blockindex.BlockIndex) uint32 {
 // GetIndex Returns the blIndex entry at a particular height in this chain, or nullptr
 // if no such height exists.
 func (c *Chain) GetIndex(height int32) *blockindex.BlockIndex {
+	c.activeLock.RLock()
+	defer c.activeLock.RUnlock()
+
 	if height < 0 || height >= int32(len(c.active)) {
 		return nil
 	}
@@ -231,8 +291,7 @@ func (c *Chain) Equal(dst *Chain) bool {
 	if dst == nil {
 		return false
 	}
-	return len(c.active) == len(dst.active) &&
-		c.active[len(c.active)-1] == dst.active[len(dst.active)-1]
+	return c.Height() == dst.Height() && c.Tip() == dst.Tip()
 }
 
 // Contains /** Efficiently check whether a block is present in this chain
@@ -258,6 +317,9 @@ func (c *Chain) Next(index *blockindex.BlockIndex) *blockindex.BlockIndex {
 // Height Return the maximal height in the chain. Is equal to chain.Tip() ?
 // chain.Tip()->nHeight : -1.
 func (c *Chain) Height() int32 {
+	c.activeLock.RLock()
+	defer c.activeLock.RUnlock()
+
 	chainLen := int32(len(c.active))
 	if chainLen > 0 {
 		return chainLen - 1
@@ -265,16 +327,30 @@ func (c *Chain) Height() int32 {
 	return 0
 }
 
-// SetTip Set/initialize a chain with a given tip.
+// SetTip Set/initialize a chain with a given tip. Only the blocks above the
+// fork point with the previous active chain are written, and the storage is
+// grown geometrically, so connecting or disconnecting a tip is O(1).
 func (c *Chain) SetTip(index *blockindex.BlockIndex) {
+	c.activeLock.Lock()
+	defer c.activeLock.Unlock()
+
 	if index == nil {
 		c.active = []*blockindex.BlockIndex{}
 		return
 	}
 
-	tmp := make([]*blockindex.BlockIndex, index.Height+1)
-	copy(tmp, c.active)
-	c.active = tmp
+	length := int(index.Height) + 1
+	if length > cap(c.active) {
+		tmp := make([]*blockindex.BlockIndex, len(c.active), 2*length)
+		copy(tmp, c.active)
+		c.active = tmp
+	}
+	// Forget the blocks above the new tip, the slots past the length must
+	// be empty so that a later extension cannot match a stale block.
+	for i := length; i < len(c.active); i++ {
+		c.active[i] = nil
+	}
+	c.active = c.active[:length]
 	for index != nil && c.active[index.Height] != index {
 		c.active[index.Height] = index
 		index = index.Prev
@@ -306,7 +382,10 @@ func (c *Chain) SetTip(index *blockindex.BlockIndex) {
 
 // GetAncestor gets ancestor from active chain.
 func (c *Chain) GetAncestor(height int32) *blockindex.BlockIndex {
-	if len(c.active) >= int(height) {
+	c.activeLock.RLock()
+	defer c.activeLock.RUnlock()
+
+	if height >= 0 && int(height) < len(c.active) {
 		return c.active[height]
 	}
 	return nil
@@ -389,12 +468,16 @@ func (c *Chain) InBranch(pindex *blockindex.BlockIndex) bool {
 	return false
 }
 
-// blocks in the branch ranks in the order of 'proof of work'
+// blocks in the branch ranks in the order of 'proof of work', among blocks
+// of equal work the one received first ranks higher
 func (c *Chain) insertToBranch(bis *blockindex.BlockIndex) {
 	c.branch = append(c.branch, bis)
 	sort.SliceStable(c.branch, func(i, j int) bool {
 		jWork := c.branch[j].ChainWork
-		return c.branch[i].ChainWork.Cmp(&jWork) == -1
+		if cmp := c.branch[i].ChainWork.Cmp(&jWork); cmp != 0 {
+			return cmp == -1
+		}
+		return c.branch[i].SequenceID > c.branch[j].SequenceID
 	})
 }
 
@@ -438,21 +521,41 @@ func (c *Chain) RemoveFromBranch(bis *blockindex.BlockIndex) error {
 	if bis == nil {
 		return errors.New("nil blockIndex")
 	}
-	branchLen := len(c.branch)
-	branch := make([]*blockindex.BlockIndex, 0, branchLen)
+	bh := bis.GetBlockHash()
 	for i, bi := range c.branch {
-		bh := bis.GetBlockHash()
 		if bi.GetBlockHash().IsEqual(bh) {
-			branch = c.branch[0:i]
-			if branchLen-1 > i {
-				c.branch = append(branch, c.branch[i+1:]...)
-			}
+			c.branch = append(c.branch[:i], c.branch[i+1:]...)
 			return nil
 		}
 	}
 	return nil
 }
 
+// PreciousBlock treats index as if it was received before any other block of
+// the same work, so that it wins the tie when selecting the best chain. Later
+// precious blocks win over earlier ones until the tip gains work. Blocks with
+// less work than the tip are left alone.
+func (c *Chain) PreciousBlock(index *blockindex.BlockIndex) {
+	tip := c.Tip()
+	if tip == nil || index.ChainWork.Cmp(&tip.ChainWork) < 0 {
+		return
+	}
+	if tip.ChainWork.Cmp(&c.lastPreciousWork) > 0 {
+		// the chain has grown since the last precious block, start over
+		c.preciousID = -1
+	}
+	c.lastPreciousWork.Set(&tip.ChainWork)
+
+	c.RemoveFromBranch(index)
+	index.SequenceID = c.preciousID
+	if c.preciousID > math.MinInt64 {
+		c.preciousID--
+	}
+	if index.IsValid(blockindex.BlockValidTransactions) && index.ChainTxCount > 0 {
+		c.insertToBranch(index)
+	}
+}
+
 func (c *Chain) FindMostWorkChain() *blockindex.BlockIndex {
 	if len(c.branch) > 0 {
 		return c.branch[len(c.branch)-1]
@@ -460,6 +563,60 @@ func (c *Chain) FindMostWorkChain() *blockindex.BlockIndex {
 	return nil
 }
 
+// ForEachIndex calls fn for every block of the block index, in no particular
+// order.  fn is called on a copy taken under the lock, so it may look blocks
+// up, and misses the blocks added meanwhile.
+func (c *Chain) ForEachIndex(fn func(index *blockindex.BlockIndex)) {
+	for _, index := range c.indexSnapshot() {
+		fn(index)
+	}
+}
+
+// indexSnapshot returns the blocks of the block index.
+func (c *Chain) indexSnapshot() []*blockindex.BlockIndex {
+	c.indexLock.RLock()
+	defer c.indexLock.RUnlock()
+	indexes := make([]*blockindex.BlockIndex, 0, len(c.indexMap))
+	for _, index := range c.indexMap {
+		indexes = append(indexes, index)
+	}
+	return indexes
+}
+
+// ChainTips returns the blocks of the block index no other block builds on,
+// which are the tips of the active chain and of every known fork, the highest
+// first.
+func (c *Chain) ChainTips() []*blockindex.BlockIndex {
+	indexes := c.indexSnapshot()
+	parents := make(map[*blockindex.BlockIndex]struct{}, len(indexes))
+	for _, index := range indexes {
+		if index.Prev != nil {
+			parents[index.Prev] = struct{}{}
+		}
+	}
+
+	tips := make([]*blockindex.BlockIndex, 0)
+	for _, index := range indexes {
+		if _, ok := parents[index]; !ok {
+			tips = append(tips, index)
+		}
+	}
+	// the active tip is reported even when headers build on it
+	if tip := c.Tip(); tip != nil {
+		if _, ok := parents[tip]; ok {
+			tips = append(tips, tip)
+		}
+	}
+
+	sort.Slice(tips, func(i, j int) bool {
+		if tips[i].Height != tips[j].Height {
+			return tips[i].Height > tips[j].Height
+		}
+		return tips[i].GetBlockHash().Cmp(tips[j].GetBlockHash()) < 0
+	})
+	return tips
+}
+
 func (c *Chain) AddToIndexMap(bi *blockindex.BlockIndex) error {
 	// We assign the sequence id to blocks only when the full data is available,
 	// to avoid miners withholding blocks but broadcasting headers, to get a
@@ -473,8 +630,8 @@ func (c *Chain) AddToIndexMap(bi *blockindex.BlockIndex) error {
 	bi.ChainWork = *blockProof
 	hash := bi.GetBlockHash()
 
-	c.indexMap[*hash] = bi
-	log.Debug("AddToIndexMap:%s", hash.String())
+	// the block is linked to its parent before others can find it
+	c.indexLock.Lock()
 	pre, ok := c.indexMap[bi.Header.HashPrevBlock]
 	if ok {
 		bi.Prev = pre
@@ -487,6 +644,10 @@ func (c *Chain) AddToIndexMap(bi *blockindex.BlockIndex) error {
 		bi.ChainWork = *bi.ChainWork.Add(&bi.ChainWork, &pre.ChainWork)
 	}
 	bi.RaiseValidity(blockindex.BlockValidTree)
+	c.indexMap[*hash] = bi
+	c.indexLock.Unlock()
+	log.Debug("AddToIndexMap:%s", hash.String())
+	c.updateBestHeader(bi)
 	gPersist := persist.GetInstance()
 	gPersist.AddDirtyBlockIndex(bi)
 	return nil
@@ -183,6 +183,12 @@ func TestChain_AddToBranch(t *testing.T) {
 	if testChain.RemoveFromBranch(blockIdx[0]); testChain.InBranch(blockIdx[0]) {
 		t.Errorf("block should not in branch")
 	}
+	if testChain.RemoveFromBranch(blockIdx[10]); testChain.InBranch(blockIdx[10]) {
+		t.Errorf("most work block should not in branch")
+	}
+	if testChain.FindMostWorkChain() != blockIdx[9] {
+		t.Errorf("most work chain should fall back to its parent")
+	}
 }
 
 func TestChain_GetBlockScriptFlags(t *testing.T) {
@@ -243,3 +249,186 @@ func TestChain_GetBlockScriptFlags(t *testing.T) {
 		t.Errorf("GetBlockScriptFlags wrong: %d", flag)
 	}
 }
+
+func TestChainTips(t *testing.T) {
+	c := NewChain()
+	c.indexMap = make(map[util.Hash]*blockindex.BlockIndex)
+	genesis := blockindex.NewBlockIndex(&model.ActiveNetParams.GenesisBlock.Header)
+	bits := model.ActiveNetParams.PowLimitBits
+
+	// main: genesis - 1 - 2 - 3 - 4, fork: 2 - 3' - 4' - 5', stale: 1 - 2''
+	main := []*blockindex.BlockIndex{genesis}
+	for i := 1; i <= 4; i++ {
+		main = append(main, getBlockIndex(main[i-1], 600, bits))
+	}
+	fork := getBlockIndex(main[2], 601, bits)
+	forkTip := getBlockIndex(getBlockIndex(fork, 600, bits), 600, bits)
+	stale := getBlockIndex(main[1], 602, bits)
+	for _, index := range append(main, fork, forkTip.Prev, forkTip, stale) {
+		c.indexMap[*index.GetBlockHash()] = index
+	}
+	c.SetTip(main[4])
+
+	tips := c.ChainTips()
+	if len(tips) != 3 || tips[0] != forkTip || tips[1] != main[4] || tips[2] != stale {
+		t.Fatalf("unexpected chain tips %v", tips)
+	}
+
+	// the active tip stays a tip while headers build on it
+	header := getBlockIndex(main[4], 600, bits)
+	c.indexMap[*header.GetBlockHash()] = header
+	tips = c.ChainTips()
+	if len(tips) != 4 || tips[2] != main[4] || (tips[0] != header && tips[1] != header) {
+		t.Errorf("active tip missing from chain tips %v", tips)
+	}
+}
+
+// TestIndexMapConcurrentAccess walks the block index from RPC like
+// handlers while headers are added to it.
+func TestIndexMapConcurrentAccess(t *testing.T) {
+	c := NewChain()
+	genesis := blockindex.NewBlockIndex(&model.ActiveNetParams.GenesisBlock.Header)
+	c.InitLoad(map[util.Hash]*blockindex.BlockIndex{*genesis.GetBlockHash(): genesis}, nil)
+	c.SetTip(genesis)
+
+	const headers = 500
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		prev := genesis
+		for i := 0; i < headers; i++ {
+			header := block.NewBlockHeader()
+			header.HashPrevBlock = *prev.GetBlockHash()
+			header.Time = prev.Header.Time + 600
+			header.Bits = model.ActiveNetParams.PowLimitBits
+			index := blockindex.NewBlockIndex(header)
+			if err := c.AddToIndexMap(index); err != nil {
+				t.Error(err)
+				return
+			}
+			prev = index
+		}
+	}()
+
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+		count := 0
+		c.ForEachIndex(func(index *blockindex.BlockIndex) {
+			c.FindBlockIndex(*index.GetBlockHash())
+			count++
+		})
+		if tips := c.ChainTips(); len(tips) == 0 || count == 0 {
+			t.Fatalf("walked %d blocks and %d tips", count, len(tips))
+		}
+	}
+
+	if tips := c.ChainTips(); len(tips) != 2 || tips[0].Height != headers {
+		t.Errorf("unexpected chain tips %v", tips)
+	}
+}
+
+func TestBestHeader(t *testing.T) {
+	c := NewChain()
+	genesis := blockindex.NewBlockIndex(&model.ActiveNetParams.GenesisBlock.Header)
+	bits := model.ActiveNetParams.PowLimitBits
+	first := getBlockIndex(genesis, 600, bits)
+	second := getBlockIndex(first, 600, bits)
+	c.InitLoad(map[util.Hash]*blockindex.BlockIndex{
+		*genesis.GetBlockHash(): genesis,
+		*first.GetBlockHash():   first,
+		*second.GetBlockHash():  second,
+	}, nil)
+	c.SetTip(first)
+
+	if c.BestHeader() != second {
+		t.Errorf("best header is at height %d, expect the header ahead of the tip", c.BestHeader().Height)
+	}
+	second.AddStatus(blockindex.BlockFailed)
+	if c.BestHeader() != first {
+		t.Errorf("an invalid best header should fall back to the tip")
+	}
+}
+
+func TestPreciousBlock(t *testing.T) {
+	c := NewChain()
+	c.params = model.ActiveNetParams
+	genesis := blockindex.NewBlockIndex(&model.ActiveNetParams.GenesisBlock.Header)
+	bits := model.ActiveNetParams.PowLimitBits
+	first := getBlockIndex(genesis, 600, bits)
+	second := getBlockIndex(genesis, 601, bits)
+	third := getBlockIndex(genesis, 602, bits)
+	for _, index := range []*blockindex.BlockIndex{first, second, third} {
+		index.TxCount = 1
+		index.RaiseValidity(blockindex.BlockValidTransactions)
+		c.AddToBranch(index)
+	}
+	c.SetTip(first)
+
+	if c.FindMostWorkChain() != first {
+		t.Fatalf("the block received first should win the tie")
+	}
+	c.PreciousBlock(third)
+	if c.FindMostWorkChain() != third {
+		t.Errorf("precious block should win the tie")
+	}
+	c.PreciousBlock(second)
+	if c.FindMostWorkChain() != second {
+		t.Errorf("later precious block should win over the earlier one")
+	}
+
+	c.PreciousBlock(genesis)
+	if c.InBranch(genesis) || genesis.SequenceID != 0 {
+		t.Errorf("block with less work than the tip should be left alone")
+	}
+}
+
+func TestSetTipReorg(t *testing.T) {
+	c := NewChain()
+	initBits := model.ActiveNetParams.PowLimitBits
+	timePerBlock := int64(model.ActiveNetParams.TargetTimePerBlock)
+
+	a := make([]*blockindex.BlockIndex, 11)
+	a[0] = blockindex.NewBlockIndex(&model.ActiveNetParams.GenesisBlock.Header)
+	for i := 1; i < len(a); i++ {
+		a[i] = getBlockIndex(a[i-1], timePerBlock, initBits)
+	}
+	b := make([]*blockindex.BlockIndex, 9)
+	copy(b, a[:4])
+	for i := 4; i < len(b); i++ {
+		b[i] = getBlockIndex(b[i-1], timePerBlock+1, initBits)
+	}
+
+	checkActive := func(blocks []*blockindex.BlockIndex) {
+		t.Helper()
+		tip := blocks[len(blocks)-1]
+		if c.Tip() != tip || c.Height() != tip.Height {
+			t.Fatalf("got tip at height %d, want %d", c.Height(), tip.Height)
+		}
+		for i, index := range blocks {
+			if c.GetIndex(int32(i)) != index {
+				t.Fatalf("wrong block at height %d with tip at height %d", i, tip.Height)
+			}
+		}
+		if c.GetIndex(tip.Height+1) != nil {
+			t.Fatalf("got a block above the tip at height %d", tip.Height)
+		}
+	}
+
+	c.SetTip(a[10])
+	checkActive(a)
+	c.SetTip(a[5])
+	checkActive(a[:6])
+	c.SetTip(b[8])
+	checkActive(b)
+	// the blocks of a above b's tip must not be taken for the active ones
+	c.SetTip(a[10])
+	checkActive(a)
+	c.SetTip(nil)
+	if c.Tip() != nil || c.GetIndex(0) != nil {
+		t.Error("expected an empty chain")
+	}
+}
@@ -29,6 +29,14 @@ const (
 
 	// NTChainTipUpdated indicates the associated blocks leads to the new main chain.
 	NTChainTipUpdated
+
+	// NTMempoolTxAdded indicates the associated transaction was added to the
+	// mempool.
+	NTMempoolTxAdded
+
+	// NTMempoolTxRemoved indicates the associated transaction was removed
+	// from the mempool.
+	NTMempoolTxRemoved
 )
 
 // notificationTypeStrings is a map of notification types back to their constant
@@ -37,6 +45,9 @@ var notificationTypeStrings = map[NotificationType]string{
 	NTBlockAccepted:     "NTBlockAccepted",
 	NTBlockConnected:    "NTBlockConnected",
 	NTBlockDisconnected: "NTBlockDisconnected",
+	NTChainTipUpdated:   "NTChainTipUpdated",
+	NTMempoolTxAdded:    "NTMempoolTxAdded",
+	NTMempoolTxRemoved:  "NTMempoolTxRemoved",
 }
 
 // String returns the NotificationType in human-readable form.
@@ -59,6 +70,9 @@ type TipUpdatedEvent struct {
 // 	- NTBlockAccepted:     *btcutil.Block
 // 	- NTBlockConnected:    *btcutil.Block
 // 	- NTBlockDisconnected: *btcutil.Block
+// 	- NTChainTipUpdated:   *TipUpdatedEvent
+// 	- NTMempoolTxAdded:    *tx.Tx
+// 	- NTMempoolTxRemoved:  *mempool.TxRemovedEvent
 type Notification struct {
 	Type NotificationType
 	Data interface{}
@@ -83,5 +97,16 @@ func (c *Chain) SendNotification(typ NotificationType, data interface{}) {
 	for _, callback := range c.notifications {
 		callback(&n)
 	}
+	for _, s := range c.subscriptions {
+		s.deliver(&n)
+	}
 	c.notificationsLock.RUnlock()
 }
+
+// SendGlobalNotification sends a notification on the global chain, if it is
+// initialized.
+func SendGlobalNotification(typ NotificationType, data interface{}) {
+	if globalChain != nil {
+		globalChain.SendNotification(typ, data)
+	}
+}
@@ -0,0 +1,141 @@
+package chain
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// SlowConsumerPolicy decides what a Subscription does with a notification
+// when its buffer is full because the consumer does not keep up.
+type SlowConsumerPolicy int
+
+const (
+	// DropNewest discards the notification which does not fit, the consumer
+	// missing the events past its buffer rather than holding up block
+	// connection.
+	DropNewest SlowConsumerPolicy = iota
+
+	// DropOldest discards the oldest buffered notification to make room, so
+	// the consumer always sees the latest events.
+	DropOldest
+
+	// CloseOnOverflow closes the subscription. The consumer notices the
+	// closed channel and has to resubscribe and resynchronize, which suits
+	// consumers that can't afford to miss a single event.
+	CloseOnOverflow
+)
+
+// Subscription delivers chain and mempool notifications over a buffered
+// channel, for embedders which prefer channels to callbacks. Notifications
+// are never sent blocking, so a slow consumer can't stall block connection;
+// the policy of the subscription decides what is lost instead.
+type Subscription struct {
+	chain   *Chain
+	ch      chan *Notification
+	policy  SlowConsumerPolicy
+	types   map[NotificationType]struct{}
+	dropped uint64
+
+	mtx    sync.Mutex
+	closed bool
+}
+
+// SubscribeChan returns a subscription buffering up to bufferSize
+// notifications of the given types, or of all types when none is given.
+// The subscription must be closed when no longer used.
+func (c *Chain) SubscribeChan(bufferSize int, policy SlowConsumerPolicy, types ...NotificationType) *Subscription {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	s := &Subscription{
+		chain:  c,
+		ch:     make(chan *Notification, bufferSize),
+		policy: policy,
+	}
+	if len(types) > 0 {
+		s.types = make(map[NotificationType]struct{}, len(types))
+		for _, typ := range types {
+			s.types[typ] = struct{}{}
+		}
+	}
+
+	c.notificationsLock.Lock()
+	c.subscriptions = append(c.subscriptions, s)
+	c.notificationsLock.Unlock()
+	return s
+}
+
+// Notifications returns the channel the notifications are delivered on. It is
+// closed when the subscription is closed.
+func (s *Subscription) Notifications() <-chan *Notification {
+	return s.ch
+}
+
+// Dropped returns the number of notifications lost because the consumer did
+// not keep up.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close stops the delivery of notifications and closes the channel.
+func (s *Subscription) Close() {
+	c := s.chain
+	c.notificationsLock.Lock()
+	for i, sub := range c.subscriptions {
+		if sub == s {
+			c.subscriptions = append(c.subscriptions[:i], c.subscriptions[i+1:]...)
+			break
+		}
+	}
+	c.notificationsLock.Unlock()
+	s.close()
+}
+
+func (s *Subscription) close() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+// deliver hands n to the consumer without blocking, applying the slow
+// consumer policy when the buffer is full.
+func (s *Subscription) deliver(n *Notification) {
+	if s.types != nil {
+		if _, ok := s.types[n.Type]; !ok {
+			return
+		}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.closed {
+		return
+	}
+	for {
+		select {
+		case s.ch <- n:
+			return
+		default:
+		}
+
+		switch s.policy {
+		case DropOldest:
+			select {
+			case <-s.ch:
+				atomic.AddUint64(&s.dropped, 1)
+			default:
+			}
+		case CloseOnOverflow:
+			atomic.AddUint64(&s.dropped, 1)
+			s.closed = true
+			close(s.ch)
+			return
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+			return
+		}
+	}
+}
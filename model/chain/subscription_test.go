@@ -0,0 +1,63 @@
+package chain
+
+import (
+	"testing"
+)
+
+func receiveAll(s *Subscription) []interface{} {
+	data := make([]interface{}, 0)
+	for {
+		select {
+		case n, ok := <-s.Notifications():
+			if !ok {
+				return data
+			}
+			data = append(data, n.Data)
+		default:
+			return data
+		}
+	}
+}
+
+func TestSubscriptionPolicies(t *testing.T) {
+	c := NewChain()
+	dropNewest := c.SubscribeChan(2, DropNewest)
+	dropOldest := c.SubscribeChan(2, DropOldest)
+	closeOnOverflow := c.SubscribeChan(2, CloseOnOverflow)
+	mempoolOnly := c.SubscribeChan(2, DropNewest, NTMempoolTxAdded)
+
+	for i := 1; i <= 3; i++ {
+		c.SendNotification(NTChainTipUpdated, i)
+	}
+	c.SendNotification(NTMempoolTxAdded, 4)
+
+	if got := receiveAll(dropNewest); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("DropNewest delivered %v, want [1 2]", got)
+	}
+	if dropNewest.Dropped() != 2 {
+		t.Errorf("DropNewest dropped %d notifications, want 2", dropNewest.Dropped())
+	}
+	if got := receiveAll(dropOldest); len(got) != 2 || got[0] != 3 || got[1] != 4 {
+		t.Errorf("DropOldest delivered %v, want [3 4]", got)
+	}
+	if got := receiveAll(closeOnOverflow); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("CloseOnOverflow delivered %v, want [1 2]", got)
+	}
+	if _, ok := <-closeOnOverflow.Notifications(); ok {
+		t.Error("CloseOnOverflow subscription should be closed")
+	}
+	if got := receiveAll(mempoolOnly); len(got) != 1 || got[0] != 4 {
+		t.Errorf("filtered subscription delivered %v, want [4]", got)
+	}
+
+	for _, s := range []*Subscription{dropNewest, dropOldest, closeOnOverflow, mempoolOnly} {
+		s.Close()
+	}
+	if len(c.subscriptions) != 0 {
+		t.Errorf("closed subscriptions should be unregistered, %d left", len(c.subscriptions))
+	}
+	c.SendNotification(NTChainTipUpdated, 5)
+	if _, ok := <-dropNewest.Notifications(); ok {
+		t.Error("a closed subscription should not deliver")
+	}
+}
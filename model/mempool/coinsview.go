@@ -0,0 +1,63 @@
+package mempool
+
+import (
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/util"
+)
+
+// CoinsGetter looks coins up, like the UTXO cache and the coins maps do.
+type CoinsGetter interface {
+	GetCoin(outpoint *outpoint.OutPoint) *utxo.Coin
+}
+
+// CoinsViewMempool is the UTXO set as the transactions of a mempool see it:
+// the outputs they create are available and the outputs they spend are
+// spent.  Without a mempool it is the base set alone, the confirmed coins.
+type CoinsViewMempool struct {
+	base CoinsGetter
+	pool *TxMempool
+}
+
+// NewCoinsViewMempool returns the view of base overlaid with the
+// transactions of pool, or of base alone when pool is nil.
+func NewCoinsViewMempool(base CoinsGetter, pool *TxMempool) *CoinsViewMempool {
+	return &CoinsViewMempool{base: base, pool: pool}
+}
+
+// GetCoin returns the unspent coin of the outpoint, or nil.  The coins of
+// the mempool transactions are flagged as mempool coins.
+func (v *CoinsViewMempool) GetCoin(out *outpoint.OutPoint) *utxo.Coin {
+	if v.pool != nil {
+		v.pool.RLock()
+		defer v.pool.RUnlock()
+		if v.pool.HasSPentOutWithoutLock(out) != nil {
+			return nil
+		}
+	}
+
+	coin := v.base.GetCoin(out)
+	if coin != nil && !coin.IsSpent() {
+		return coin
+	}
+	if v.pool != nil {
+		return v.pool.GetCoinWithoutLock(out)
+	}
+	return nil
+}
+
+// HaveCoin returns whether the outpoint is unspent.
+func (v *CoinsViewMempool) HaveCoin(out *outpoint.OutPoint) bool {
+	return v.GetCoin(out) != nil
+}
+
+// HaveOutputs returns whether any of the first n outputs of the transaction
+// is unspent.
+func (v *CoinsViewMempool) HaveOutputs(hash util.Hash, n int) bool {
+	for i := 0; i < n; i++ {
+		if v.HaveCoin(outpoint.NewOutPoint(hash, uint32(i))) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/util"
+)
+
+func TestCoinsViewMempool(t *testing.T) {
+	pool := NewTxMempool()
+	parent, child := addChain(t, pool)
+
+	// the parent spends a confirmed coin, another one stays unspent
+	base := utxo.NewEmptyCoinsMap()
+	scriptPubKey := script.NewScriptRaw([]byte{opcodes.OP_TRUE})
+	spentByPool := outpoint.NewOutPoint(util.HashOne, 0)
+	unspent := outpoint.NewOutPoint(util.HashOne, 1)
+	for _, out := range []*outpoint.OutPoint{spentByPool, unspent} {
+		base.AddCoin(out, utxo.NewCoin(txout.NewTxOut(5000, scriptPubKey), 1, false), true)
+	}
+	parentOut := outpoint.NewOutPoint(parent.Tx.GetHash(), 0)
+	childOut := outpoint.NewOutPoint(child.Tx.GetHash(), 0)
+	unknown := outpoint.NewOutPoint(util.HashZero, 0)
+
+	tests := []struct {
+		name      string
+		out       *outpoint.OutPoint
+		confirmed bool
+		mempool   bool
+	}{
+		{"confirmed coin spent by the mempool", spentByPool, true, false},
+		{"confirmed coin", unspent, true, true},
+		{"mempool output spent by the mempool", parentOut, false, false},
+		{"mempool output", childOut, false, true},
+		{"unknown outpoint", unknown, false, false},
+	}
+	confirmedView := NewCoinsViewMempool(base, nil)
+	mempoolView := NewCoinsViewMempool(base, pool)
+	for _, test := range tests {
+		if got := confirmedView.HaveCoin(test.out); got != test.confirmed {
+			t.Errorf("%s: confirmed view has coin = %v, want %v", test.name, got, test.confirmed)
+		}
+		if got := mempoolView.HaveCoin(test.out); got != test.mempool {
+			t.Errorf("%s: mempool view has coin = %v, want %v", test.name, got, test.mempool)
+		}
+	}
+
+	if coin := mempoolView.GetCoin(childOut); coin == nil || !coin.IsMempoolCoin() ||
+		coin.GetAmount() != child.Tx.GetTxOut(0).GetValue() {
+		t.Errorf("unexpected coin %v for the output of the child", coin)
+	}
+	if coin := mempoolView.GetCoin(unspent); coin == nil || coin.IsMempoolCoin() {
+		t.Errorf("unexpected coin %v for the confirmed output", coin)
+	}
+
+	if !confirmedView.HaveOutputs(util.HashOne, 2) || !mempoolView.HaveOutputs(util.HashOne, 2) {
+		t.Error("the confirmed transaction has an unspent output")
+	}
+	if mempoolView.HaveOutputs(util.HashOne, 1) {
+		t.Error("the first output of the confirmed transaction is spent in the mempool")
+	}
+	if confirmedView.HaveOutputs(child.Tx.GetHash(), 1) || !mempoolView.HaveOutputs(child.Tx.GetHash(), 1) {
+		t.Error("the outputs of the child are only in the mempool")
+	}
+}
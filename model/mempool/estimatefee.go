@@ -18,7 +18,6 @@ import (
 
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/model/block"
-	"github.com/copernet/copernicus/model/blockindex"
 	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
@@ -206,7 +205,7 @@ func NewFeeEstimator(maxRollback, minRegisteredBlocks uint32) *FeeEstimator {
 }
 
 // ObserveTransaction is called when a new transaction is observed in the mempool.
-func (ef *FeeEstimator) ObserveTransaction(t *tx.Tx) {
+func (ef *FeeEstimator) ObserveTransaction(entry *TxEntry) {
 	ef.mtx.Lock()
 	defer ef.mtx.Unlock()
 
@@ -216,30 +215,26 @@ func (ef *FeeEstimator) ObserveTransaction(t *tx.Tx) {
 		return
 	}
 
-	// todo: need mempool support, please fix me
-	//hash := t.GetHash()
-	//if _, ok := ef.observed[hash]; !ok {
-	//	size := uint32(GetTxVirtualSize(t.Tx))
-	//
-	//	ef.observed[hash] = &observedTransaction{
-	//		hash:     hash,
-	//		feeRate:  NewSatoshiPerByte(amount.Amount(t.Fee), size),
-	//		observed: t.Height,
-	//		mined:    UnminedHeight,
-	//	}
-	//}
+	hash := entry.Tx.GetHash()
+	if _, ok := ef.observed[hash]; !ok {
+		ef.observed[hash] = &observedTransaction{
+			hash:     hash,
+			feeRate:  NewSatoshiPerByte(amount.Amount(entry.TxFee), uint32(entry.TxSize)),
+			observed: entry.TxHeight,
+			mined:    UnminedHeight,
+		}
+	}
 }
 
-// RegisterBlock informs the fee estimator of a new block to take into account.
-func (ef *FeeEstimator) RegisterBlock(block *block.Block) error {
+// RegisterBlock informs the fee estimator of a new block to take into account,
+// connected to the active chain at height.
+func (ef *FeeEstimator) RegisterBlock(block *block.Block, height int32) error {
 	ef.mtx.Lock()
 	defer ef.mtx.Unlock()
 
 	// The previous sorted list is invalid, so delete it.
 	ef.cached = nil
 
-	blkIdx := blockindex.NewBlockIndex(&block.Header)
-	height := blkIdx.Height
 	if height != ef.lastKnownHeight+1 && ef.lastKnownHeight != UnminedHeight {
 		return fmt.Errorf("intermediate block not recorded; current height is %d; new height is %d",
 			ef.lastKnownHeight, height)
@@ -346,6 +341,21 @@ func (ef *FeeEstimator) LastKnownHeight() int32 {
 	return ef.lastKnownHeight
 }
 
+// Reset forgets everything the FeeEstimator learnt, keeping its parameters.
+// It is used when the estimator entered an invalid state it cannot recover
+// from.
+func (ef *FeeEstimator) Reset() {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	ef.lastKnownHeight = UnminedHeight
+	ef.numBlocksRegistered = 0
+	ef.observed = make(map[util.Hash]*observedTransaction)
+	ef.bin = [estimateFeeDepth][]*observedTransaction{}
+	ef.cached = nil
+	ef.dropped = make([]*registeredBlock, 0, ef.maxRollback)
+}
+
 // Rollback unregisters a recently registered block from the FeeEstimator.
 // This can be used to reverse the effect of an orphaned block on the fee
 // estimator. The maximum number of rollbacks allowed is given by
@@ -586,6 +596,39 @@ func (ef *FeeEstimator) EstimateFee(numBlocks uint32) (BtcPerKilobyte, error) {
 	return ef.cached[int(numBlocks)-1].ToBtcPerKb(), nil
 }
 
+// EstimateSmartFee estimates the fee per byte to have a tx confirmed within
+// numBlocks blocks from now, like EstimateFee. When there is no estimate for
+// numBlocks, the estimate of the nearest longer target is returned instead,
+// along with the number of blocks it is valid for. Targets beyond the depth
+// tracked are answered for the longest target tracked.
+func (ef *FeeEstimator) EstimateSmartFee(numBlocks uint32) (BtcPerKilobyte, uint32, error) {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	if ef.numBlocksRegistered < ef.minRegisteredBlocks {
+		return -1, 0, errors.New("not enough blocks have been observed")
+	}
+
+	if numBlocks == 0 {
+		return -1, 0, errors.New("cannot confirm transaction in zero blocks")
+	}
+
+	if numBlocks > estimateFeeDepth {
+		numBlocks = estimateFeeDepth
+	}
+
+	if ef.cached == nil {
+		ef.cached = ef.estimates()
+	}
+
+	for target := numBlocks; target <= estimateFeeDepth; target++ {
+		if rate := ef.cached[int(target)-1]; rate > 0 {
+			return rate.ToBtcPerKb(), target, nil
+		}
+	}
+	return -1, 0, errors.New("insufficient data or no feerate found")
+}
+
 // In case the format for the serialized version of the FeeEstimator changes,
 // we use a version number. If the version number changes, it does not make
 // sense to try to upgrade a previous version to a new version. Instead, just
@@ -0,0 +1,104 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+)
+
+func newEstimateFeeEntries(n int, height int32) []*TxEntry {
+	entries := make([]*TxEntry, n)
+	for i := range entries {
+		t := tx.NewTx(uint32(i), tx.TxVersion)
+		t.AddTxIn(txin.NewTxIn(&outpoint.OutPoint{Hash: util.HashOne, Index: uint32(i)},
+			script.NewScriptRaw([]byte{opcodes.OP_TRUE}), script.SequenceFinal))
+		entries[i] = NewTestMemPoolEntry().SetHeight(height).
+			SetFee(amount.Amount(1000 * (i + 1))).FromTxToEntry(t)
+	}
+	return entries
+}
+
+func newEstimateFeeBlock(entries []*TxEntry) *block.Block {
+	blk := block.NewBlock()
+	for _, entry := range entries {
+		blk.Txs = append(blk.Txs, entry.Tx)
+	}
+	return blk
+}
+
+func TestFeeEstimator(t *testing.T) {
+	ef := NewFeeEstimator(DefaultEstimateFeeMaxRollback, DefaultEstimateFeeMinRegisteredBlocks)
+	if err := ef.RegisterBlock(newEstimateFeeBlock(nil), 100); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ef.EstimateFee(1); err == nil {
+		t.Error("expected an error before enough blocks were registered")
+	}
+
+	entries := newEstimateFeeEntries(10, 100)
+	for _, entry := range entries {
+		ef.ObserveTransaction(entry)
+	}
+	if err := ef.RegisterBlock(newEstimateFeeBlock(entries[5:]), 101); err != nil {
+		t.Fatal(err)
+	}
+	if err := ef.RegisterBlock(newEstimateFeeBlock(entries[:5]), 102); err != nil {
+		t.Fatal(err)
+	}
+	if err := ef.RegisterBlock(newEstimateFeeBlock(nil), 104); err == nil {
+		t.Error("expected an error registering a block out of order")
+	}
+
+	fast, err := ef.EstimateFee(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slow, err := ef.EstimateFee(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fast <= slow {
+		t.Errorf("got %v for the next block and %v for two blocks", fast, slow)
+	}
+
+	rate, blocks, err := ef.EstimateSmartFee(1)
+	if err != nil || rate != fast || blocks != 1 {
+		t.Errorf("got smart fee %v for %d blocks (%v), want %v for 1 block", rate, blocks, err, fast)
+	}
+	if _, blocks, err = ef.EstimateSmartFee(1000); err != nil || blocks != estimateFeeDepth {
+		t.Errorf("got smart fee for %d blocks (%v), want %d blocks", blocks, err, estimateFeeDepth)
+	}
+
+	restored, err := RestoreFeeEstimator(ef.Save())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate, err := restored.EstimateFee(1); err != nil || rate != fast {
+		t.Errorf("got %v (%v) from the restored estimator, want %v", rate, err, fast)
+	}
+
+	ef.Reset()
+	if _, _, err := ef.EstimateSmartFee(1); err == nil {
+		t.Error("expected an error after reset")
+	}
+	if err := ef.RegisterBlock(newEstimateFeeBlock(nil), 200); err != nil {
+		t.Errorf("a reset estimator should accept any height: %v", err)
+	}
+}
+
+func TestEstimateSmartFeeNoData(t *testing.T) {
+	ef := NewFeeEstimator(DefaultEstimateFeeMaxRollback, 1)
+	if err := ef.RegisterBlock(newEstimateFeeBlock(nil), 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ef.EstimateSmartFee(2); err == nil {
+		t.Error("expected an error without any confirmed transaction")
+	}
+}
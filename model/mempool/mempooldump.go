@@ -0,0 +1,245 @@
+package mempool
+
+import (
+	"bytes"
+	"io"
+	"sort"
+
+	"github.com/copernet/copernicus/errcode"
+	"github.com/copernet/copernicus/model/consensus"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/util"
+)
+
+// Versions of the mempool.dat format. Every version extends the previous one
+// at its end, so a reader only has to know up to which section to read.
+const (
+	// MempoolDumpVersion1 holds the transactions with their entry time and
+	// fee delta, followed by the deltas of transactions not in the mempool.
+	MempoolDumpVersion1 uint64 = 1
+	// MempoolDumpVersion2 appends the unbroadcast transaction set.
+	MempoolDumpVersion2 uint64 = 2
+
+	MempoolDumpVersion = MempoolDumpVersion2
+)
+
+type DumpedTx struct {
+	Tx       *tx.Tx
+	Time     int64
+	FeeDelta int64
+}
+
+// MempoolDump is the content of mempool.dat.
+type MempoolDump struct {
+	Version uint64
+	Txs     []DumpedTx
+	// Deltas are the prioritisetransaction deltas of transactions which are
+	// not among Txs.
+	Deltas      map[util.Hash]int64
+	Unbroadcast map[util.Hash]struct{}
+}
+
+// Dump takes a snapshot of the mempool for persisting it. Transactions are
+// ordered so that parents always come before their children.
+func (m *TxMempool) Dump() *MempoolDump {
+	m.RLock()
+	defer m.RUnlock()
+
+	entries := make([]*TxEntry, 0, len(m.poolData))
+	for _, entry := range m.poolData {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].SumTxCountWithAncestors == entries[j].SumTxCountWithAncestors {
+			return entries[i].Less(entries[j])
+		}
+		return entries[i].SumTxCountWithAncestors < entries[j].SumTxCountWithAncestors
+	})
+
+	d := &MempoolDump{
+		Version:     MempoolDumpVersion,
+		Txs:         make([]DumpedTx, len(entries)),
+		Deltas:      make(map[util.Hash]int64),
+		Unbroadcast: make(map[util.Hash]struct{}, len(m.unbroadcastTxs)),
+	}
+	for i, entry := range entries {
+		d.Txs[i] = DumpedTx{Tx: entry.Tx, Time: entry.time, FeeDelta: entry.feeDelta}
+	}
+	for hash, delta := range m.deltas {
+		if _, ok := m.poolData[hash]; !ok {
+			d.Deltas[hash] = delta
+		}
+	}
+	for hash := range m.unbroadcastTxs {
+		d.Unbroadcast[hash] = struct{}{}
+	}
+	return d
+}
+
+func (d *MempoolDump) Serialize(w io.Writer) error {
+	if err := util.WriteElements(w, d.Version, uint64(len(d.Txs))); err != nil {
+		return err
+	}
+	for _, dtx := range d.Txs {
+		if err := dtx.Tx.Serialize(w); err != nil {
+			return err
+		}
+		if err := util.WriteElements(w, dtx.Time, dtx.FeeDelta); err != nil {
+			return err
+		}
+	}
+
+	if err := util.WriteVarInt(w, uint64(len(d.Deltas))); err != nil {
+		return err
+	}
+	hashes := make([]util.Hash, 0, len(d.Deltas))
+	for hash := range d.Deltas {
+		hashes = append(hashes, hash)
+	}
+	for _, hash := range sortHashes(hashes) {
+		if err := util.WriteElements(w, &hash, d.Deltas[hash]); err != nil {
+			return err
+		}
+	}
+	if d.Version < MempoolDumpVersion2 {
+		return nil
+	}
+
+	hashes = make([]util.Hash, 0, len(d.Unbroadcast))
+	for hash := range d.Unbroadcast {
+		hashes = append(hashes, hash)
+	}
+	if err := util.WriteVarInt(w, uint64(len(hashes))); err != nil {
+		return err
+	}
+	for _, hash := range sortHashes(hashes) {
+		if err := util.WriteElements(w, &hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unserialize reads every format version up to MempoolDumpVersion, files
+// written by a newer version are rejected rather than half understood.
+func (d *MempoolDump) Unserialize(r io.Reader) error {
+	var count uint64
+	if err := util.ReadElements(r, &d.Version, &count); err != nil {
+		return err
+	}
+	if d.Version < MempoolDumpVersion1 || d.Version > MempoolDumpVersion {
+		return errcode.New(errcode.UnsupportedDumpVersion)
+	}
+
+	d.Txs = make([]DumpedTx, 0)
+	for i := uint64(0); i < count; i++ {
+		dtx := DumpedTx{Tx: tx.NewEmptyTx()}
+		if err := dtx.Tx.Unserialize(r); err != nil {
+			return err
+		}
+		if err := util.ReadElements(r, &dtx.Time, &dtx.FeeDelta); err != nil {
+			return err
+		}
+		d.Txs = append(d.Txs, dtx)
+	}
+
+	count, err := util.ReadVarInt(r)
+	if err != nil {
+		return err
+	}
+	d.Deltas = make(map[util.Hash]int64)
+	for i := uint64(0); i < count; i++ {
+		var hash util.Hash
+		var delta int64
+		if err := util.ReadElements(r, &hash, &delta); err != nil {
+			return err
+		}
+		d.Deltas[hash] = delta
+	}
+
+	d.Unbroadcast = make(map[util.Hash]struct{})
+	if d.Version < MempoolDumpVersion2 {
+		return nil
+	}
+	count, err = util.ReadVarInt(r)
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < count; i++ {
+		var hash util.Hash
+		if err := util.ReadElements(r, &hash); err != nil {
+			return err
+		}
+		d.Unbroadcast[hash] = struct{}{}
+	}
+	return nil
+}
+
+// WriteRawTxs streams the transactions of the dump, for another node to warm
+// its mempool: the varint count of transactions followed, for every one in
+// dependency order, by a compact header made of the varint size of the
+// transaction, its entry time and its fee delta, and the serialized
+// transaction.
+func (d *MempoolDump) WriteRawTxs(w io.Writer) error {
+	if err := util.WriteVarInt(w, uint64(len(d.Txs))); err != nil {
+		return err
+	}
+	for _, dtx := range d.Txs {
+		err := util.WriteVarInt(w, uint64(dtx.Tx.SerializeSize()))
+		if err == nil {
+			err = util.WriteElements(w, dtx.Time, dtx.FeeDelta)
+		}
+		if err == nil {
+			err = dtx.Tx.Serialize(w)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadRawTxs reads a stream written by WriteRawTxs, handing every transaction
+// to fn as soon as it is read. Reading stops at the first error of fn.
+func ReadRawTxs(r io.Reader, fn func(dtx *DumpedTx) error) error {
+	count, err := util.ReadVarInt(r)
+	if err != nil {
+		return err
+	}
+	var buf []byte
+	for i := uint64(0); i < count; i++ {
+		size, err := util.ReadVarInt(r)
+		if err != nil {
+			return err
+		}
+		if size > consensus.MaxTxSize {
+			return errcode.New(errcode.OversizedStreamTx)
+		}
+		dtx := DumpedTx{Tx: tx.NewEmptyTx()}
+		if err := util.ReadElements(r, &dtx.Time, &dtx.FeeDelta); err != nil {
+			return err
+		}
+		if uint64(cap(buf)) < size {
+			buf = make([]byte, size)
+		}
+		buf = buf[:size]
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return err
+		}
+		if err := dtx.Tx.Unserialize(bytes.NewReader(buf)); err != nil {
+			return err
+		}
+		if err := fn(&dtx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortHashes makes the file content deterministic.
+func sortHashes(hashes []util.Hash) []util.Hash {
+	sort.Slice(hashes, func(i, j int) bool {
+		return hashes[i].Cmp(&hashes[j]) < 0
+	})
+	return hashes
+}
@@ -0,0 +1,209 @@
+package mempool
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/copernet/copernicus/errcode"
+	"github.com/copernet/copernicus/model/consensus"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/util"
+)
+
+func newChainedTx(prevHash util.Hash) *tx.Tx {
+	transaction := tx.NewTx(0, tx.TxVersion)
+	transaction.AddTxIn(txin.NewTxIn(&outpoint.OutPoint{Hash: prevHash, Index: 0},
+		script.NewScriptRaw([]byte{opcodes.OP_11}), script.SequenceFinal))
+	transaction.AddTxOut(txout.NewTxOut(10000, script.NewScriptRaw([]byte{opcodes.OP_11, opcodes.OP_EQUAL})))
+	return transaction
+}
+
+// addChain adds a parent with a child and returns both entries.
+func addChain(t *testing.T, pool *TxMempool) (*TxEntry, *TxEntry) {
+	noLimit := uint64(math.MaxUint64)
+	helper := NewTestMemPoolEntry().SetFee(1000)
+
+	parentTx := newChainedTx(util.HashOne)
+	childTx := newChainedTx(parentTx.GetHash())
+	entries := make([]*TxEntry, 0, 2)
+	for i, transaction := range []*tx.Tx{parentTx, childTx} {
+		ancestors, err := pool.CalculateMemPoolAncestors(transaction, noLimit, noLimit, noLimit, noLimit, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := helper.SetTime(int64(100 - i)).FromTxToEntry(transaction)
+		if err := pool.AddTx(entry, ancestors); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries[0], entries[1]
+}
+
+func TestPrioritiseTransaction(t *testing.T) {
+	pool := NewTxMempool()
+	parent, child := addChain(t, pool)
+
+	pool.PrioritiseTransaction(parent.Tx.GetHash(), 500)
+	if parent.GetModifiedFee() != 1500 || parent.SumTxFeeWithDescendants != 2500 {
+		t.Errorf("parent modified fee %d, with descendants %d", parent.GetModifiedFee(),
+			parent.SumTxFeeWithDescendants)
+	}
+	if child.SumTxFeeWithAncestors != 2500 {
+		t.Errorf("child fee with ancestors %d, expect 2500", child.SumTxFeeWithAncestors)
+	}
+	if pool.txByAncestorFeeRateSort.Len() != 2 {
+		t.Errorf("fee rate index has %d entries, expect 2", pool.txByAncestorFeeRateSort.Len())
+	}
+
+	// deltas accumulate, and are removed once they cancel out
+	pool.PrioritiseTransaction(parent.Tx.GetHash(), -500)
+	if _, ok := pool.GetFeeDeltas()[parent.Tx.GetHash()]; ok || child.SumTxFeeWithAncestors != 2000 {
+		t.Errorf("delta not cancelled out, child fee with ancestors %d", child.SumTxFeeWithAncestors)
+	}
+
+	// removing the child for a block leaves the remaining stats consistent
+	pool.PrioritiseTransaction(child.Tx.GetHash(), 300)
+	pool.RemoveStaged(map[*TxEntry]struct{}{child: {}}, false, BLOCK)
+	if parent.SumTxFeeWithDescendants != 1000 {
+		t.Errorf("parent fee with descendants %d, expect 1000", parent.SumTxFeeWithDescendants)
+	}
+	if len(pool.GetFeeDeltas()) != 0 {
+		t.Errorf("delta of a mined transaction should be cleared")
+	}
+}
+
+func TestMempoolDump(t *testing.T) {
+	pool := NewTxMempool()
+	parent, child := addChain(t, pool)
+	pool.PrioritiseTransaction(child.Tx.GetHash(), 700)
+	pool.PrioritiseTransaction(util.HashOne, -200)
+	pool.AddUnbroadcastTx(parent.Tx.GetHash())
+	pool.AddUnbroadcastTx(util.HashZero)
+
+	d := pool.Dump()
+	// the child entered first, but it has to be reloaded after its parent
+	if len(d.Txs) != 2 || d.Txs[0].Tx != parent.Tx || d.Txs[1].Tx != child.Tx {
+		t.Fatalf("transactions are not in dependency order")
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := d.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := MempoolDump{}
+	if err := loaded.Unserialize(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Version != MempoolDumpVersion || len(loaded.Txs) != 2 {
+		t.Fatalf("version %d with %d txs", loaded.Version, len(loaded.Txs))
+	}
+	if loaded.Txs[1].Tx.GetHash() != child.Tx.GetHash() || loaded.Txs[1].Time != 99 ||
+		loaded.Txs[1].FeeDelta != 700 {
+		t.Errorf("unexpected child %+v", loaded.Txs[1])
+	}
+	if len(loaded.Deltas) != 1 || loaded.Deltas[util.HashOne] != -200 {
+		t.Errorf("unexpected deltas %v", loaded.Deltas)
+	}
+	if _, ok := loaded.Unbroadcast[parent.Tx.GetHash()]; !ok || len(loaded.Unbroadcast) != 1 {
+		t.Errorf("unexpected unbroadcast set %v", loaded.Unbroadcast)
+	}
+
+	// version 1 files carry no unbroadcast set
+	d.Version = MempoolDumpVersion1
+	buf.Reset()
+	if err := d.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded = MempoolDump{}
+	if err := loaded.Unserialize(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Txs) != 2 || len(loaded.Deltas) != 1 || len(loaded.Unbroadcast) != 0 {
+		t.Errorf("unexpected version 1 content %+v", loaded)
+	}
+
+	d.Version = MempoolDumpVersion + 1
+	buf.Reset()
+	if err := d.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	err := loaded.Unserialize(bytes.NewReader(buf.Bytes()))
+	if !errcode.IsErrorCode(err, errcode.UnsupportedDumpVersion) {
+		t.Errorf("newer version should be rejected, got %v", err)
+	}
+}
+
+func TestMempoolRawTxsStream(t *testing.T) {
+	pool := NewTxMempool()
+	parent, child := addChain(t, pool)
+	pool.PrioritiseTransaction(child.Tx.GetHash(), 700)
+
+	buf := bytes.NewBuffer(nil)
+	if err := pool.Dump().WriteRawTxs(buf); err != nil {
+		t.Fatal(err)
+	}
+	stream := buf.Bytes()
+
+	read := make([]*DumpedTx, 0)
+	err := ReadRawTxs(bytes.NewReader(stream), func(dtx *DumpedTx) error {
+		read = append(read, dtx)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 2 || read[0].Tx.GetHash() != parent.Tx.GetHash() ||
+		read[1].Tx.GetHash() != child.Tx.GetHash() {
+		t.Fatalf("transactions are not streamed in dependency order")
+	}
+	if read[1].Time != 99 || read[1].FeeDelta != 700 {
+		t.Errorf("unexpected child header %+v", read[1])
+	}
+
+	if err := ReadRawTxs(bytes.NewReader(stream[:len(stream)-1]), func(*DumpedTx) error {
+		return nil
+	}); err == nil {
+		t.Error("a truncated stream should fail")
+	}
+
+	oversized := bytes.NewBuffer(nil)
+	util.WriteVarInt(oversized, 1)
+	util.WriteVarInt(oversized, consensus.MaxTxSize+1)
+	err = ReadRawTxs(oversized, func(*DumpedTx) error { return nil })
+	if !errcode.IsErrorCode(err, errcode.OversizedStreamTx) {
+		t.Errorf("oversized entry should be rejected, got %v", err)
+	}
+}
+
+func TestUnbroadcastTx(t *testing.T) {
+	pool := NewTxMempool()
+	parent, child := addChain(t, pool)
+
+	// only transactions in the pool can be unbroadcast
+	pool.AddUnbroadcastTx(util.HashZero)
+	pool.AddUnbroadcastTx(parent.Tx.GetHash())
+	pool.AddUnbroadcastTx(child.Tx.GetHash())
+	if pool.IsUnbroadcastWithoutLock(util.HashZero) {
+		t.Error("a transaction not in the pool was recorded as unbroadcast")
+	}
+	if !pool.IsUnbroadcastWithoutLock(parent.Tx.GetHash()) {
+		t.Error("parent should be unbroadcast")
+	}
+
+	pool.RemoveUnbroadcastTx(parent.Tx.GetHash())
+	if pool.IsUnbroadcastWithoutLock(parent.Tx.GetHash()) {
+		t.Error("parent was requested by a peer")
+	}
+
+	pool.RemoveTxRecursive(child.Tx, UNKNOWN)
+	if pool.IsUnbroadcastWithoutLock(child.Tx.GetHash()) {
+		t.Error("a transaction removed from the pool is still unbroadcast")
+	}
+}
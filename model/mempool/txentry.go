@@ -13,7 +13,9 @@ type TxEntry struct {
 	Tx     *tx.Tx
 	TxSize int
 	// txFee tis transaction fee
-	TxFee    int64
+	TxFee int64
+	// feeDelta is the fee adjustment set by prioritisetransaction
+	feeDelta int64
 	TxHeight int32
 	// sigOpCount sigop plus P2SH sigops count
 	SigOpCount int
@@ -72,6 +74,24 @@ func (t *TxEntry) GetTime() int64 {
 	return t.time
 }
 
+func (t *TxEntry) GetFeeDelta() int64 {
+	return t.feeDelta
+}
+
+// GetModifiedFee returns the fee plus the prioritisetransaction delta, which
+// is what the ancestor and descendant statistics are accounted with.
+func (t *TxEntry) GetModifiedFee() int64 {
+	return t.TxFee + t.feeDelta
+}
+
+// UpdateFeeDelta replaces the entry's fee delta, adjusting its own ancestor
+// and descendant fee sums; the caller updates the related entries.
+func (t *TxEntry) UpdateFeeDelta(feeDelta int64) {
+	t.SumTxFeeWithDescendants += feeDelta - t.feeDelta
+	t.SumTxFeeWithAncestors += feeDelta - t.feeDelta
+	t.feeDelta = feeDelta
+}
+
 // UpdateParent update the tx's parent transaction.
 func (t *TxEntry) UpdateParent(parent *TxEntry, add bool) {
 	if add {
@@ -0,0 +1,44 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/tx"
+)
+
+func TestCheckLockPointValidity(t *testing.T) {
+	indexes := make([]*blockindex.BlockIndex, 20)
+	for i := range indexes {
+		index := new(blockindex.BlockIndex)
+		index.Height = int32(i)
+		index.Header.Nonce = uint32(i)
+		if i > 0 {
+			index.Prev = indexes[i-1]
+		}
+		indexes[i] = index
+	}
+	activeChain := chain.NewChain()
+	activeChain.SetTip(indexes[19])
+
+	entry := NewTxentry(tx.NewTx(0, tx.TxVersion), 0, 0, 1, LockPoints{}, 0, false)
+	if !entry.CheckLockPointValidity(activeChain) {
+		t.Errorf("lock points without an input block stay valid")
+	}
+
+	entry.SetLockPointFromTxEntry(LockPoints{Height: 11, MaxInputBlock: indexes[10]})
+	if !entry.CheckLockPointValidity(activeChain) {
+		t.Errorf("lock points anchored in the active chain must stay valid")
+	}
+
+	// reorg away the block holding the inputs
+	fork := new(blockindex.BlockIndex)
+	fork.Height = 10
+	fork.Prev = indexes[9]
+	fork.Header.Nonce = 1000
+	activeChain.SetTip(fork)
+	if entry.CheckLockPointValidity(activeChain) {
+		t.Errorf("lock points anchored in a disconnected block must be recalculated")
+	}
+}
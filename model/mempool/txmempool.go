@@ -5,11 +5,13 @@ import (
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/model/consensus"
 	"github.com/copernet/copernicus/model/outpoint"
 	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/model/utxo"
 	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/bloom"
 	"github.com/google/btree"
 	"math"
 	"sync"
@@ -51,6 +53,13 @@ const (
 	REPLACED
 )
 
+// TxRemovedEvent is the data of the notification of a transaction removed
+// from the mempool.
+type TxRemovedEvent struct {
+	Tx     *tx.Tx
+	Reason PoolRemovalReason
+}
+
 // TxMempool is safe for concurrent write And read access.
 type TxMempool struct {
 	sync.RWMutex
@@ -73,7 +82,17 @@ type TxMempool struct {
 	TransactionsUpdated      uint64
 	OrphanTransactionsByPrev map[outpoint.OutPoint]map[util.Hash]OrphanTx
 	OrphanTransactions       map[util.Hash]OrphanTx
-	RecentRejects            map[util.Hash]struct{}
+	// recentRejects are the transactions rejected by policy since the tip
+	// recentRejectsTip, so that peers announcing them again don't trigger
+	// their validation.
+	recentRejects    *bloom.RollingFilter
+	recentRejectsTip util.Hash
+	// deltas are the prioritisetransaction fee deltas, kept for transactions
+	// which are not in the mempool yet too.
+	deltas map[util.Hash]int64
+	// unbroadcastTxs are the transactions submitted locally which no peer has
+	// requested yet.
+	unbroadcastTxs map[util.Hash]struct{}
 
 	nextSweep int
 
@@ -88,13 +107,21 @@ func (m *TxMempool) GetCheckFrequency() float64 {
 	return m.checkFrequency
 }
 
+// GetMinFeeRate returns the feerate a transaction needs to enter the mempool.
+// It takes the write lock, GetMinFee decays the rolling minimum.
 func (m *TxMempool) GetMinFeeRate() util.FeeRate {
-	m.RLock()
+	m.Lock()
 	feeRate := m.GetMinFee(conf.Cfg.Mempool.MaxPoolSize)
-	m.RUnlock()
+	m.Unlock()
 	return feeRate
 }
 
+// GetIncrementalRelayFee returns the feerate by which the minimum feerate of
+// the mempool rises when it is full.
+func (m *TxMempool) GetIncrementalRelayFee() util.FeeRate {
+	return m.incrementalRelayFee
+}
+
 // AddTx operator is safe for concurrent write And read access.
 // this function is used to add tx to the memPool, and now the tx should
 // be passed all appropriate checks.
@@ -124,6 +151,7 @@ func (m *TxMempool) AddTx(txEntry *TxEntry, ancestors map[*TxEntry]struct{}) err
 	if txEntry.SumTxCountWithAncestors == 1 {
 		m.rootTx[txEntry.Tx.GetHash()] = txEntry
 	}
+	chain.SendGlobalNotification(chain.NTMempoolTxAdded, txEntry.Tx)
 	return nil
 }
 
@@ -218,13 +246,13 @@ func (m *TxMempool) GetAllTxEntry() map[util.Hash]*TxEntry {
 	return ret
 }
 
+// GetAllTxEntryWithoutLock is GetAllTxEntry for callers already holding the
+// mempool lock.
 func (m *TxMempool) GetAllTxEntryWithoutLock() map[util.Hash]*TxEntry {
-	m.RLock()
 	ret := make(map[util.Hash]*TxEntry, len(m.poolData))
 	for k, v := range m.poolData {
 		ret[k] = v
 	}
-	m.RUnlock()
 	return ret
 }
 
@@ -264,6 +292,11 @@ func (m *TxMempool) RemoveTxSelf(txs []*tx.Tx) {
 func (m *TxMempool) FindTx(hash util.Hash) *TxEntry {
 	m.RLock()
 	defer m.RUnlock()
+	return m.FindTxWithoutLock(hash)
+}
+
+// FindTxWithoutLock is FindTx for callers already holding the mempool lock.
+func (m *TxMempool) FindTxWithoutLock(hash util.Hash) *TxEntry {
 	if find, ok := m.poolData[hash]; ok {
 		return find
 	}
@@ -274,6 +307,11 @@ func (m *TxMempool) GetCoin(outpoint *outpoint.OutPoint) *utxo.Coin {
 	m.RLock()
 	defer m.RUnlock()
 
+	return m.GetCoinWithoutLock(outpoint)
+}
+
+// GetCoinWithoutLock is GetCoin for callers already holding the mempool lock.
+func (m *TxMempool) GetCoinWithoutLock(outpoint *outpoint.OutPoint) *utxo.Coin {
 	txMempoolEntry, ok := m.poolData[outpoint.Hash]
 	if !ok {
 		return nil
@@ -287,6 +325,104 @@ func (m *TxMempool) GetCoin(outpoint *outpoint.OutPoint) *utxo.Coin {
 	return nil
 }
 
+// PrioritiseTransaction adds feeDelta to the fee the transaction is accounted
+// with, whether it is in the mempool yet or not.
+func (m *TxMempool) PrioritiseTransaction(hash util.Hash, feeDelta int64) {
+	m.Lock()
+	defer m.Unlock()
+
+	delta := m.deltas[hash] + feeDelta
+	if delta == 0 {
+		delete(m.deltas, hash)
+	} else {
+		m.deltas[hash] = delta
+	}
+
+	entry, ok := m.poolData[hash]
+	if !ok {
+		return
+	}
+	nNoLimit := uint64(math.MaxUint64)
+	ancestors, err := m.CalculateMemPoolAncestors(entry.Tx, nNoLimit, nNoLimit, nNoLimit, nNoLimit, false)
+	if err != nil {
+		return
+	}
+	descendants := make(map[*TxEntry]struct{})
+	m.CalculateDescendants(entry, descendants)
+
+	// the ancestor feerate of the entry and its descendants changes, so they
+	// have to be re-sorted.
+	for desc := range descendants {
+		m.txByAncestorFeeRateSort.Delete(EntryAncestorFeeRateSort(*desc))
+	}
+	entry.UpdateFeeDelta(delta)
+	for ancestor := range ancestors {
+		ancestor.UpdateDescendantState(0, 0, feeDelta)
+	}
+	for desc := range descendants {
+		if desc != entry {
+			desc.UpdateAncestorState(0, 0, 0, feeDelta)
+		}
+		m.txByAncestorFeeRateSort.ReplaceOrInsert(EntryAncestorFeeRateSort(*desc))
+	}
+	m.TransactionsUpdated++
+}
+
+// GetFeeDeltaWithoutLock returns the prioritisetransaction delta of the
+// transaction, the caller holds the mempool lock.
+func (m *TxMempool) GetFeeDeltaWithoutLock(hash util.Hash) int64 {
+	return m.deltas[hash]
+}
+
+// GetFeeDeltas returns a copy of all the prioritisetransaction deltas.
+func (m *TxMempool) GetFeeDeltas() map[util.Hash]int64 {
+	m.RLock()
+	defer m.RUnlock()
+
+	ret := make(map[util.Hash]int64, len(m.deltas))
+	for hash, delta := range m.deltas {
+		ret[hash] = delta
+	}
+	return ret
+}
+
+// AddUnbroadcastTx records a locally submitted mempool transaction which has
+// to be announced until a peer requests it.
+func (m *TxMempool) AddUnbroadcastTx(hash util.Hash) {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.poolData[hash]; ok {
+		m.unbroadcastTxs[hash] = struct{}{}
+	}
+}
+
+// RemoveUnbroadcastTx is called once a peer has requested the transaction.
+func (m *TxMempool) RemoveUnbroadcastTx(hash util.Hash) {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.unbroadcastTxs, hash)
+}
+
+// IsUnbroadcastWithoutLock reports whether no peer has requested the locally
+// submitted transaction yet. The caller holds the mempool lock.
+func (m *TxMempool) IsUnbroadcastWithoutLock(hash util.Hash) bool {
+	_, ok := m.unbroadcastTxs[hash]
+	return ok
+}
+
+func (m *TxMempool) GetUnbroadcastTxs() []util.Hash {
+	m.RLock()
+	defer m.RUnlock()
+
+	ret := make([]util.Hash, 0, len(m.unbroadcastTxs))
+	for hash := range m.unbroadcastTxs {
+		ret = append(ret, hash)
+	}
+	return ret
+}
+
 // LimitMempoolSize limit mempool size with time And limit size. when the noSpendsRemaining
 // set, the function will return these have removed transaction's txin from mempool which use
 // TrimToSize rule. Later, caller will remove these txin from uxto cache.
@@ -320,7 +456,9 @@ func (m *TxMempool) GetMinFee(sizeLimit int64) util.FeeRate {
 		} else if m.usageSize < sizeLimit/2 {
 			halfLife /= 2
 		}
-		m.rollingMinimumFeeRate = m.rollingMinimumFeeRate / int64(math.Pow(2.0, float64(timeTmp-m.lastRollingFeeUpdate))/float64(halfLife))
+		// the rolling minimum halves every halfLife seconds
+		decay := math.Pow(2.0, float64(timeTmp-m.lastRollingFeeUpdate)/float64(halfLife))
+		m.rollingMinimumFeeRate = int64(float64(m.rollingMinimumFeeRate) / decay)
 		m.lastRollingFeeUpdate = timeTmp
 		if m.rollingMinimumFeeRate < m.incrementalRelayFee.GetFeePerK()/2 {
 			m.rollingMinimumFeeRate = 0
@@ -437,7 +575,7 @@ func (m *TxMempool) updateForRemoveFromMempool(entriesToRemove map[*TxEntry]stru
 			m.CalculateDescendants(removeIt, setDescendants)
 			delete(setDescendants, removeIt)
 			modifySize := -removeIt.TxSize
-			modifyFee := -removeIt.TxFee
+			modifyFee := -removeIt.GetModifiedFee()
 			modifySigOps := -removeIt.SigOpCount
 
 			for dit := range setDescendants {
@@ -539,7 +677,7 @@ func (m *TxMempool) updateAncestors(add bool, txEntry *TxEntry, ancestors map[*T
 		updateCount = 1
 	}
 	updateSize := updateCount * txEntry.TxSize
-	updateFee := int64(updateCount) * txEntry.TxFee
+	updateFee := int64(updateCount) * txEntry.GetModifiedFee()
 	// update each of ancestors transaction state;
 	for ancestorit := range ancestors {
 		ancestorit.UpdateDescendantState(updateCount, updateSize, updateFee)
@@ -553,7 +691,7 @@ func (m *TxMempool) updateEntryForAncestors(entry *TxEntry, setAncestors map[*Tx
 	updateSigOpsCount := 0
 
 	for ancestorIt := range setAncestors {
-		updateFee += ancestorIt.TxFee
+		updateFee += ancestorIt.GetModifiedFee()
 		updateSigOpsCount += ancestorIt.SigOpCount
 		updateSize += ancestorIt.TxSize
 	}
@@ -629,7 +767,7 @@ func (m *TxMempool) CalculateMemPoolAncestors(tx *tx.Tx, limitAncestorCount uint
 }
 
 func (m *TxMempool) delTxentry(removeEntry *TxEntry, reason PoolRemovalReason) {
-	// todo add signal for any subscriber
+	chain.SendGlobalNotification(chain.NTMempoolTxRemoved, &TxRemovedEvent{Tx: removeEntry.Tx, Reason: reason})
 
 	for _, preout := range removeEntry.Tx.GetAllPreviousOut() {
 		delete(m.nextTx, preout)
@@ -642,6 +780,10 @@ func (m *TxMempool) delTxentry(removeEntry *TxEntry, reason PoolRemovalReason) {
 	m.TransactionsUpdated++
 	m.totalTxSize -= uint64(removeEntry.TxSize)
 	delete(m.poolData, removeEntry.Tx.GetHash())
+	delete(m.unbroadcastTxs, removeEntry.Tx.GetHash())
+	if reason == BLOCK {
+		delete(m.deltas, removeEntry.Tx.GetHash())
+	}
 	m.timeSortData.Delete(removeEntry)
 	m.txByAncestorFeeRateSort.Delete(EntryAncestorFeeRateSort(*removeEntry))
 }
@@ -674,7 +816,34 @@ func NewTxMempool() *TxMempool {
 
 		OrphanTransactionsByPrev: make(map[outpoint.OutPoint]map[util.Hash]OrphanTx),
 		OrphanTransactions:       make(map[util.Hash]OrphanTx),
-		RecentRejects:            make(map[util.Hash]struct{}),
+		recentRejects:            bloom.NewRollingFilter(maxRecentRejects, 0.000001),
+		deltas:                   make(map[util.Hash]int64),
+		unbroadcastTxs:           make(map[util.Hash]struct{}),
+	}
+}
+
+// AddRecentReject remembers a transaction rejected since the tip tipHash.
+func (m *TxMempool) AddRecentReject(hash util.Hash, tipHash util.Hash) {
+	m.Lock()
+	defer m.Unlock()
+	m.updateRecentRejectsTip(tipHash)
+	m.recentRejects.AddHash(&hash)
+}
+
+// HaveRecentReject returns whether the transaction was probably rejected
+// since the tip tipHash. The rejects are forgotten when the tip changes, as a
+// transaction rejected for being premature may be valid on the new tip.
+func (m *TxMempool) HaveRecentReject(hash util.Hash, tipHash util.Hash) bool {
+	m.Lock()
+	defer m.Unlock()
+	m.updateRecentRejectsTip(tipHash)
+	return m.recentRejects.ContainsHash(&hash)
+}
+
+func (m *TxMempool) updateRecentRejectsTip(tipHash util.Hash) {
+	if tipHash != m.recentRejectsTip {
+		m.recentRejectsTip = tipHash
+		m.recentRejects.Reset()
 	}
 }
 
@@ -683,6 +852,10 @@ func InitMempool() {
 }
 
 const (
+	// maxRecentRejects is the number of rejected transactions remembered
+	// at least.
+	maxRecentRejects = 120000
+
 	OrphanTxExpireTime          = 20 * 60
 	OrphanTxExpireInterval      = 5 * 60
 	DefaultMaxOrphanTransaction = 100
@@ -304,3 +304,44 @@ func TestTxMempoolTrimToSize(t *testing.T) {
 	}
 	fmt.Printf("============= end ============\n")
 }
+
+func TestGetMinFeeDecay(t *testing.T) {
+	testPool := NewTxMempool()
+	testPool.rollingMinimumFeeRate = 10000
+	testPool.blockSinceLastRollingFeeBump = true
+	testPool.lastRollingFeeUpdate = util.GetTime() - RollingFeeHalfLife
+
+	// a full mempool decays with the full half life
+	rate := testPool.GetMinFee(0)
+	if rate.SataoshisPerK < 4999 || rate.SataoshisPerK > 5000 {
+		t.Errorf("the rolling minimum fee should halve after a half life, got %d", rate.SataoshisPerK)
+	}
+
+	// a few seconds later the rate barely moves
+	testPool.lastRollingFeeUpdate = util.GetTime() - 11
+	rate = testPool.GetMinFee(0)
+	if rate.SataoshisPerK < 4990 || rate.SataoshisPerK > 5000 {
+		t.Errorf("the rolling minimum fee should decay slowly, got %d", rate.SataoshisPerK)
+	}
+}
+
+func TestRecentRejects(t *testing.T) {
+	testPool := NewTxMempool()
+	tip := util.HashFromString("00000000000000000000000000000000000000000000000000000000000000aa")
+	rejected := util.HashFromString("00000000000000000000000000000000000000000000000000000000000000bb")
+	other := util.HashFromString("00000000000000000000000000000000000000000000000000000000000000cc")
+
+	testPool.AddRecentReject(*rejected, *tip)
+	if !testPool.HaveRecentReject(*rejected, *tip) {
+		t.Errorf("the rejected transaction should be remembered")
+	}
+	if testPool.HaveRecentReject(*other, *tip) {
+		t.Errorf("a transaction never rejected should not be remembered")
+	}
+
+	// a new tip gives the rejected transactions a second chance
+	newTip := util.HashFromString("00000000000000000000000000000000000000000000000000000000000000dd")
+	if testPool.HaveRecentReject(*rejected, *newTip) {
+		t.Errorf("the rejects should be forgotten after a tip change")
+	}
+}
@@ -7,6 +7,7 @@ package pow
 import (
 	"math"
 	"math/big"
+	"sync"
 
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/blockindex"
@@ -21,6 +22,15 @@ var (
 	// oneLsh256 is 1 shifted left 256 bits.  It is defined here to avoid
 	// the overhead of creating it multiple times.
 	oneLsh256 = new(big.Int).Lsh(bigOne, 256)
+
+	// tipWork caches the work of the last tip passed to
+	// GetBlockProofEquivalentTime, which is asked for the same tip for every
+	// block connected on top of it.  A new tip replaces the cached one.
+	tipWork struct {
+		sync.Mutex
+		tip  *blockindex.BlockIndex
+		work *big.Int
+	}
 )
 
 // HashToBig converts a chainHash.Hash into a big.Int that can be used to
@@ -130,6 +140,71 @@ func BigToCompact(n *big.Int) uint32 {
 	return compact
 }
 
+// DecodeCompact converts the compact representation of a target to a big.Int
+// in the same way as CompactToBig, and additionally reports whether the encoded
+// number is negative or overflows 256 bits.  This mirrors arith_uint256's
+// SetCompact, which is what header validation and work calculation rely on.
+func DecodeCompact(compact uint32) (target *big.Int, isNegative bool, isOverflow bool) {
+	mantissa := compact & 0x007fffff
+	exponent := uint(compact >> 24)
+	if exponent <= 3 {
+		mantissa >>= 8 * (3 - exponent)
+	}
+
+	isNegative = mantissa != 0 && compact&0x00800000 != 0
+	isOverflow = mantissa != 0 && (exponent > 34 ||
+		(mantissa > 0xff && exponent > 33) ||
+		(mantissa > 0xffff && exponent > 32))
+
+	return CompactToBig(compact), isNegative, isOverflow
+}
+
+// TargetToWork returns the expected number of hashes needed to find a hash
+// below target, computed as 2^256 / (target + 1).  A non-positive target
+// yields zero work.
+func TargetToWork(target *big.Int) *big.Int {
+	if target.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+
+	// (1 << 256) / (target + 1)
+	denominator := new(big.Int).Add(target, bigOne)
+	return new(big.Int).Div(oneLsh256, denominator)
+}
+
+// CalcWork calculates the work value represented by the compact difficulty
+// bits.  Bits that decode to a negative, zero or overflowing target carry no
+// work.  The result is a fresh big.Int which the caller is free to modify.
+func CalcWork(bits uint32) *big.Int {
+	target, isNegative, isOverflow := DecodeCompact(bits)
+	if isNegative || isOverflow {
+		return big.NewInt(0)
+	}
+	return TargetToWork(target)
+}
+
+// GetDifficulty returns the proof-of-work difficulty of the compact bits as a
+// multiple of the minimum difficulty (a target of 0x1d00ffff).
+func GetDifficulty(bits uint32) float64 {
+	shift := (bits >> 24) & 0xff
+	mantissa := bits & 0x00ffffff
+	if mantissa == 0 {
+		return 0
+	}
+	diff := float64(0x0000ffff) / float64(mantissa)
+
+	for shift < 29 {
+		diff *= 256.0
+		shift++
+	}
+	for shift > 29 {
+		diff /= 256.0
+		shift--
+	}
+
+	return diff
+}
+
 // GetBlockProof calculates a work value from difficulty bits.  Bitcoin increases
 // the difficulty for generating a block by decreasing the value which the
 // generated hash must be less than.  This difficulty target is stored in each
@@ -141,18 +216,19 @@ func BigToCompact(n *big.Int) uint32 {
 // potential division by zero and really small floating point numbers, the
 // result adds 1 to the denominator and multiplies the numerator by 2^256.
 func GetBlockProof(blIn *blockindex.BlockIndex) *big.Int {
-	// Return a work value of zero if the passed difficulty bits represent
-	// a negative number. Note this should not happen in practice with valid
-	// blocks, but an invalid block could trigger it
-	difficultyNum := CompactToBig(blIn.Header.Bits)
-	//difficultyNum := CompactToBig(blIn.Bits)
-	if difficultyNum.Sign() <= 0 {
-		return big.NewInt(0)
-	}
+	return CalcWork(blIn.Header.Bits)
+}
 
-	// (1 << 256) / (difficultyNum + 1)
-	denominator := new(big.Int).Add(difficultyNum, bigOne)
-	return new(big.Int).Div(oneLsh256, denominator)
+// getTipProof returns the work of tip, computing it only when tip is not the
+// cached one.  The result must not be modified.
+func getTipProof(tip *blockindex.BlockIndex) *big.Int {
+	tipWork.Lock()
+	defer tipWork.Unlock()
+	if tipWork.tip != tip {
+		tipWork.tip = tip
+		tipWork.work = GetBlockProof(tip)
+	}
+	return tipWork.work
 }
 
 // GetBlockProofEquivalentTime Return the time it would take to redo the work difference
@@ -167,7 +243,7 @@ func GetBlockProofEquivalentTime(to, from, tip *blockindex.BlockIndex, params *m
 		ret = ret.Sub(&from.ChainWork, &to.ChainWork)
 		sign = -1
 	}
-	ret.Mul(ret, big.NewInt(int64(params.TargetTimePerBlock))).Div(ret, GetBlockProof(tip))
+	ret.Mul(ret, big.NewInt(int64(params.TargetTimePerBlock))).Div(ret, getTipProof(tip))
 	if bits(ret.Bits()) > 63 {
 		return sign * (math.MaxInt64)
 	}
@@ -6,6 +6,7 @@ package pow
 
 import (
 	"github.com/copernet/copernicus/model/blockindex"
+	"math"
 	"math/big"
 	"testing"
 )
@@ -77,3 +78,142 @@ func TestGetBlockProof(t *testing.T) {
 		}
 	}
 }
+
+// TestDecodeCompact ensures the sign and overflow flags are reported the same
+// way as arith_uint256::SetCompact.
+func TestDecodeCompact(t *testing.T) {
+	tests := []struct {
+		in       uint32
+		out      string
+		negative bool
+		overflow bool
+	}{
+		{0x00000000, "0", false, false},
+		{0x00123456, "0", false, false},
+		{0x01003456, "0", false, false},
+		{0x02000056, "0", false, false},
+		{0x03000000, "0", false, false},
+		{0x04000000, "0", false, false},
+		{0x00923456, "0", false, false},
+		{0x01803456, "0", false, false},
+		{0x02800056, "0", false, false},
+		{0x03800000, "0", false, false},
+		{0x04800000, "0", false, false},
+		{0x01123456, "12", false, false},
+		{0x01fedcba, "-7e", true, false},
+		{0x02123456, "1234", false, false},
+		{0x03123456, "123456", false, false},
+		{0x04123456, "12345600", false, false},
+		{0x04923456, "-12345600", true, false},
+		{0x05009234, "92340000", false, false},
+		{0x20123456, "1234560000000000000000000000000000000000000000000000000000000000", false, false},
+		{0xff123456, "", false, true},
+		{0x23000001, "", false, true},
+		{0x22000001, "", false, false},
+		{0x23000100, "", false, true},
+		{0x22010000, "", false, true},
+		{0x21000100, "", false, false},
+	}
+
+	for x, test := range tests {
+		n, negative, overflow := DecodeCompact(test.in)
+		if negative != test.negative || overflow != test.overflow {
+			t.Errorf("TestDecodeCompact test #%d failed: got negative %v overflow %v, "+
+				"want negative %v overflow %v", x, negative, overflow, test.negative, test.overflow)
+			continue
+		}
+		if test.out != "" && n.Text(16) != test.out {
+			t.Errorf("TestDecodeCompact test #%d failed: got %s want %s",
+				x, n.Text(16), test.out)
+		}
+	}
+}
+
+// TestCalcWork ensures CalcWork returns zero for invalid targets, matches the
+// expected value for valid ones and hands out independent copies.
+func TestCalcWork(t *testing.T) {
+	tests := []struct {
+		in  uint32
+		out string
+	}{
+		{0x00000000, "0"},
+		{0x04923456, "0"},
+		{0xff123456, "0"},
+		{0x1d00ffff, "100010001"},
+		{0x207fffff, "2"},
+		{0x1b0404cb, "3fb3ab764c00"},
+	}
+
+	for x, test := range tests {
+		for i := 0; i < 2; i++ {
+			r := CalcWork(test.in)
+			if r.Text(16) != test.out {
+				t.Errorf("TestCalcWork test #%d failed: got %s want %s",
+					x, r.Text(16), test.out)
+			}
+			r.Add(r, bigOne)
+		}
+	}
+}
+
+// TestGetTipProof ensures the work of the tip is cached until the tip changes.
+func TestGetTipProof(t *testing.T) {
+	tip := new(blockindex.BlockIndex)
+	tip.SetNull()
+	tip.Header.Bits = 0x1d00ffff
+	work := getTipProof(tip)
+	if work.Text(16) != "100010001" {
+		t.Fatalf("work of the tip %s, expect 100010001", work.Text(16))
+	}
+	if getTipProof(tip) != work {
+		t.Errorf("the work of an unchanged tip is computed again")
+	}
+
+	next := new(blockindex.BlockIndex)
+	next.SetNull()
+	next.Header.Bits = 0x207fffff
+	if work := getTipProof(next); work.Text(16) != "2" {
+		t.Errorf("work of the new tip %s, expect 2", work.Text(16))
+	}
+	if tipWork.tip != next {
+		t.Errorf("a new tip does not replace the cached one")
+	}
+	if work := getTipProof(tip); work.Text(16) != "100010001" {
+		t.Errorf("work of the former tip %s, expect 100010001", work.Text(16))
+	}
+}
+
+// TestGetDifficulty ensures difficulty is expressed relative to 0x1d00ffff.
+func TestGetDifficulty(t *testing.T) {
+	tests := []struct {
+		in  uint32
+		out float64
+	}{
+		{0x1d00ffff, 1},
+		{0x1c00ffff, 256},
+		{0x1d01fffe, 0.5},
+		{0x1b0404cb, 16307.420938523983},
+		{0x1d000000, 0},
+	}
+
+	for x, test := range tests {
+		r := GetDifficulty(test.in)
+		if math.Abs(r-test.out) > 1e-9 {
+			t.Errorf("TestGetDifficulty test #%d failed: got %v want %v",
+				x, r, test.out)
+		}
+	}
+}
+
+// TestTargetToWork ensures the work of a target round trips through the
+// compact representation used by GetBlockProof.
+func TestTargetToWork(t *testing.T) {
+	target := CompactToBig(0x1d00ffff)
+	if TargetToWork(target).Cmp(CalcWork(0x1d00ffff)) != 0 {
+		t.Errorf("TestTargetToWork failed: got %s want %s",
+			TargetToWork(target).Text(16), CalcWork(0x1d00ffff).Text(16))
+	}
+	if TargetToWork(big.NewInt(-1)).Sign() != 0 {
+		t.Errorf("TestTargetToWork failed: negative target must carry no work")
+	}
+}
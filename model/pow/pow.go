@@ -246,8 +246,8 @@ func (pow *Pow) getSuitableBlock(index *blockindex.BlockIndex) *blockindex.Block
 }
 
 func (pow *Pow) CheckProofOfWork(hash *util.Hash, bits uint32, params *model.BitcoinParams) bool {
-	target := CompactToBig(bits)
-	if target.Sign() <= 0 || target.Cmp(params.PowLimit) > 0 ||
+	target, isNegative, isOverflow := DecodeCompact(bits)
+	if isNegative || isOverflow || target.Sign() <= 0 || target.Cmp(params.PowLimit) > 0 ||
 		HashToBig(hash).Cmp(target) > 0 {
 		return false
 	}
@@ -32,6 +32,12 @@ func (addr *Address) EncodeToPubKeyHash() []byte {
 	return addr.hash160[:]
 }
 
+// GetVersion returns the version byte, which tells pay-to-pubkey-hash and
+// pay-to-script-hash addresses apart.
+func (addr *Address) GetVersion() byte {
+	return addr.version
+}
+
 func (addr *Address) String() string {
 	if addr.addressStr != "" {
 		return addr.addressStr
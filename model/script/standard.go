@@ -0,0 +1,117 @@
+package script
+
+import (
+	"github.com/copernet/copernicus/errcode"
+	"github.com/copernet/copernicus/model/opcodes"
+)
+
+// NewScriptPubKeyHash returns the standard pay-to-pubkey-hash script:
+// OP_DUP OP_HASH160 <hash160> OP_EQUALVERIFY OP_CHECKSIG
+func NewScriptPubKeyHash(hash160 []byte) (*Script, error) {
+	if len(hash160) != Hash160BytesLength {
+		return nil, errcode.New(errcode.ScriptErrNonStandard)
+	}
+	s := NewEmptyScript()
+	s.data = append(s.data, opcodes.OP_DUP, opcodes.OP_HASH160, Hash160BytesLength)
+	s.data = append(s.data, hash160...)
+	s.data = append(s.data, opcodes.OP_EQUALVERIFY, opcodes.OP_CHECKSIG)
+	return s, s.convertOPS()
+}
+
+// NewScriptHash returns the standard pay-to-script-hash script:
+// OP_HASH160 <hash160> OP_EQUAL
+func NewScriptHash(hash160 []byte) (*Script, error) {
+	if len(hash160) != Hash160BytesLength {
+		return nil, errcode.New(errcode.ScriptErrNonStandard)
+	}
+	s := NewEmptyScript()
+	s.data = append(s.data, opcodes.OP_HASH160, Hash160BytesLength)
+	s.data = append(s.data, hash160...)
+	s.data = append(s.data, opcodes.OP_EQUAL)
+	return s, s.convertOPS()
+}
+
+// NewScriptPubKey returns the pay-to-pubkey script: <pubkey> OP_CHECKSIG
+func NewScriptPubKey(pubKey []byte) (*Script, error) {
+	if len(pubKey) != 33 && len(pubKey) != 65 {
+		return nil, errcode.New(errcode.ScriptErrPubKeyType)
+	}
+	s := NewEmptyScript()
+	if err := s.PushSingleData(pubKey); err != nil {
+		return nil, err
+	}
+	return s, s.PushOpCode(opcodes.OP_CHECKSIG)
+}
+
+// NewScriptMultiSig returns a bare multisig script requiring nRequired of the
+// given public keys: OP_m <pubkey>... OP_n OP_CHECKMULTISIG
+func NewScriptMultiSig(nRequired int, pubKeys [][]byte) (*Script, error) {
+	if nRequired < 1 || nRequired > len(pubKeys) {
+		return nil, errcode.New(errcode.ScriptErrSigCount)
+	}
+	if len(pubKeys) > 16 {
+		return nil, errcode.New(errcode.ScriptErrPubKeyCount)
+	}
+	opM, _ := EncodeOPN(nRequired)
+	opN, _ := EncodeOPN(len(pubKeys))
+	s := NewEmptyScript()
+	s.data = append(s.data, byte(opM))
+	for _, pubKey := range pubKeys {
+		if len(pubKey) != 33 && len(pubKey) != 65 {
+			return nil, errcode.New(errcode.ScriptErrPubKeyType)
+		}
+		s.data = append(s.data, byte(len(pubKey)))
+		s.data = append(s.data, pubKey...)
+	}
+	s.data = append(s.data, byte(opN), opcodes.OP_CHECKMULTISIG)
+	return s, s.convertOPS()
+}
+
+// NewScriptNullData returns an unspendable data carrier script:
+// OP_RETURN <data>
+func NewScriptNullData(data []byte) (*Script, error) {
+	if len(data) > MaxScriptElementSize {
+		return nil, errcode.New(errcode.ScriptErrPushSize)
+	}
+	s := NewEmptyScript()
+	if err := s.PushOpCode(opcodes.OP_RETURN); err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return s, nil
+	}
+	return s, s.PushSingleData(data)
+}
+
+// PayToAddrScript returns the script paying to the given address, which is
+// pay-to-script-hash for script addresses and pay-to-pubkey-hash otherwise.
+func PayToAddrScript(addr *Address) (*Script, error) {
+	if addr.version == AddressVerScript() {
+		return NewScriptHash(addr.hash160[:])
+	}
+	if addr.version == AddressVerPubKey() {
+		return NewScriptPubKeyHash(addr.hash160[:])
+	}
+	return nil, errcode.New(errcode.ScriptErrNonStandard)
+}
+
+// ScriptTypeName returns the name of a script type as reported by
+// CheckScriptPubKeyStandard, using the names of Bitcoin ABC's RPC interface.
+func ScriptTypeName(sType int) string {
+	switch sType {
+	case ScriptNonStandard:
+		return "nonstandard"
+	case ScriptPubkey:
+		return "pubkey"
+	case ScriptPubkeyHash:
+		return "pubkeyhash"
+	case ScriptHash:
+		return "scripthash"
+	case ScriptMultiSig:
+		return "multisig"
+	case ScriptNullData:
+		return "nulldata"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,186 @@
+package script
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/copernet/copernicus/model/opcodes"
+)
+
+func TestNewScriptPubKeyHash(t *testing.T) {
+	s, err := NewScriptPubKeyHash(p2PKHScript[3:23])
+	if err != nil {
+		t.Fatalf("NewScriptPubKeyHash failed: %v", err)
+	}
+	if !bytes.Equal(s.GetData(), p2PKHScript[:]) {
+		t.Errorf("NewScriptPubKeyHash got %x, want %x", s.GetData(), p2PKHScript[:])
+	}
+	if sType, _, err := s.CheckScriptPubKeyStandard(); err != nil || sType != ScriptPubkeyHash {
+		t.Errorf("NewScriptPubKeyHash script type got %d (%v), want %d", sType, err, ScriptPubkeyHash)
+	}
+
+	if _, err := NewScriptPubKeyHash(make([]byte, 19)); err == nil {
+		t.Errorf("NewScriptPubKeyHash accepted a short hash")
+	}
+}
+
+func TestNewScriptHash(t *testing.T) {
+	s, err := NewScriptHash(p2SHScript[2:22])
+	if err != nil {
+		t.Fatalf("NewScriptHash failed: %v", err)
+	}
+	if !bytes.Equal(s.GetData(), p2SHScript[:]) {
+		t.Errorf("NewScriptHash got %x, want %x", s.GetData(), p2SHScript[:])
+	}
+	if !s.IsPayToScriptHash() {
+		t.Errorf("NewScriptHash didn't produce a P2SH script")
+	}
+}
+
+func TestNewScriptPubKey(t *testing.T) {
+	pubKey, _ := hex.DecodeString("0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	s, err := NewScriptPubKey(pubKey)
+	if err != nil {
+		t.Fatalf("NewScriptPubKey failed: %v", err)
+	}
+	if sType, _, err := s.CheckScriptPubKeyStandard(); err != nil || sType != ScriptPubkey {
+		t.Errorf("NewScriptPubKey script type got %d (%v), want %d", sType, err, ScriptPubkey)
+	}
+
+	if _, err := NewScriptPubKey(pubKey[:32]); err == nil {
+		t.Errorf("NewScriptPubKey accepted a malformed key")
+	}
+}
+
+func TestNewScriptMultiSig(t *testing.T) {
+	pubKey1, _ := hex.DecodeString("0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	pubKey2, _ := hex.DecodeString("02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5")
+	keys := [][]byte{pubKey1, pubKey2}
+
+	s, err := NewScriptMultiSig(1, keys)
+	if err != nil {
+		t.Fatalf("NewScriptMultiSig failed: %v", err)
+	}
+	data := s.GetData()
+	if data[0] != opcodes.OP_1 || data[len(data)-2] != opcodes.OP_2 ||
+		data[len(data)-1] != opcodes.OP_CHECKMULTISIG {
+		t.Errorf("NewScriptMultiSig got %x", data)
+	}
+	if sType, _, err := s.CheckScriptPubKeyStandard(); err != nil || sType != ScriptMultiSig {
+		t.Errorf("NewScriptMultiSig script type got %d (%v), want %d", sType, err, ScriptMultiSig)
+	}
+
+	tests := []struct {
+		nRequired int
+		keys      [][]byte
+	}{
+		{0, keys},
+		{3, keys},
+		{1, [][]byte{pubKey1[:20]}},
+		{1, make([][]byte, 17)},
+	}
+	for i, test := range tests {
+		if _, err := NewScriptMultiSig(test.nRequired, test.keys); err == nil {
+			t.Errorf("NewScriptMultiSig test #%d should fail", i)
+		}
+	}
+}
+
+func TestNewScriptNullData(t *testing.T) {
+	s, err := NewScriptNullData([]byte("hello"))
+	if err != nil {
+		t.Fatalf("NewScriptNullData failed: %v", err)
+	}
+	want := append([]byte{opcodes.OP_RETURN, 5}, []byte("hello")...)
+	if !bytes.Equal(s.GetData(), want) {
+		t.Errorf("NewScriptNullData got %x, want %x", s.GetData(), want)
+	}
+	if !s.IsUnspendable() {
+		t.Errorf("NewScriptNullData must be unspendable")
+	}
+
+	s, err = NewScriptNullData(nil)
+	if err != nil || !bytes.Equal(s.GetData(), []byte{opcodes.OP_RETURN}) {
+		t.Errorf("NewScriptNullData with no data got %x (%v)", s.GetData(), err)
+	}
+
+	if _, err := NewScriptNullData(make([]byte, MaxScriptElementSize+1)); err == nil {
+		t.Errorf("NewScriptNullData accepted oversized data")
+	}
+}
+
+func TestPayToAddrScript(t *testing.T) {
+	pkhAddr, err := AddressFromHash160(p2PKHScript[3:23], AddressVerPubKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := PayToAddrScript(pkhAddr)
+	if err != nil || !bytes.Equal(s.GetData(), p2PKHScript[:]) {
+		t.Errorf("PayToAddrScript for pubkey hash address got %x (%v)", s.GetData(), err)
+	}
+
+	shAddr, err := AddressFromHash160(p2SHScript[2:22], AddressVerScript())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = PayToAddrScript(shAddr)
+	if err != nil || !bytes.Equal(s.GetData(), p2SHScript[:]) {
+		t.Errorf("PayToAddrScript for script hash address got %x (%v)", s.GetData(), err)
+	}
+
+	badAddr, err := AddressFromHash160(p2SHScript[2:22], 0x42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PayToAddrScript(badAddr); err == nil {
+		t.Errorf("PayToAddrScript accepted an unknown address version")
+	}
+}
+
+func TestExtractDestinations(t *testing.T) {
+	pubKey1, _ := hex.DecodeString("0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	pubKey2, _ := hex.DecodeString("02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5")
+	addr1, _ := AddressFromPublicKey(pubKey1)
+	addr2, _ := AddressFromPublicKey(pubKey2)
+	shAddr, _ := AddressFromHash160(p2SHScript[2:22], AddressVerScript())
+	pkhAddr, _ := AddressFromHash160(p2PKHScript[3:23], AddressVerPubKey())
+
+	p2pk, _ := NewScriptPubKey(pubKey1)
+	multiSig, _ := NewScriptMultiSig(1, [][]byte{pubKey1, pubKey2})
+	nullData, _ := NewScriptNullData([]byte("copernicus"))
+
+	tests := []struct {
+		name      string
+		script    *Script
+		typeName  string
+		required  int
+		addresses []*Address
+	}{
+		{"pubkey", p2pk, "pubkey", 1, []*Address{addr1}},
+		{"pubkeyhash", NewScriptRaw(p2PKHScript[:]), "pubkeyhash", 1, []*Address{pkhAddr}},
+		{"scripthash", NewScriptRaw(p2SHScript[:]), "scripthash", 1, []*Address{shAddr}},
+		{"multisig", multiSig, "multisig", 1, []*Address{addr1, addr2}},
+		{"nulldata", nullData, "nulldata", 0, nil},
+		{"nonstandard", NewScriptRaw([]byte{opcodes.OP_TRUE}), "nonstandard", 0, nil},
+	}
+
+	for _, test := range tests {
+		sType, addresses, required, _ := test.script.ExtractDestinations()
+		if name := ScriptTypeName(sType); name != test.typeName {
+			t.Errorf("%s: type got %s, want %s", test.name, name, test.typeName)
+		}
+		if required != test.required {
+			t.Errorf("%s: required sigs got %d, want %d", test.name, required, test.required)
+		}
+		if len(addresses) != len(test.addresses) {
+			t.Errorf("%s: got %d addresses, want %d", test.name, len(addresses), len(test.addresses))
+			continue
+		}
+		for i := range addresses {
+			if addresses[i].String() != test.addresses[i].String() {
+				t.Errorf("%s: address %d got %s, want %s", test.name, i, addresses[i], test.addresses[i])
+			}
+		}
+	}
+}
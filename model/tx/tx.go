@@ -41,7 +41,10 @@ const (
 	MaxMessagePayload = 32 * 1024 * 1024
 	MinTxInPayload    = 9 + util.Hash256Size
 	MaxTxInPerMessage = (MaxMessagePayload / MinTxInPayload) + 1
-	TxVersion         = 1
+	// MinTxOutPayload is the 8 bytes value plus a 1 byte empty script length
+	MinTxOutPayload    = 9
+	MaxTxOutPerMessage = (MaxMessagePayload / MinTxOutPayload) + 1
+	TxVersion          = 1
 )
 
 const (
@@ -220,7 +223,7 @@ func (tx *Tx) Decode(reader io.Reader) error {
 	}
 	if count > uint64(MaxTxInPerMessage) {
 		log.Error("too many input txs to fit into max message size [count %d , max %d]", count, MaxTxInPerMessage)
-		return err
+		return errcode.New(errcode.TxErrOverSize)
 	}
 
 	tx.version = int32(version)
@@ -239,6 +242,10 @@ func (tx *Tx) Decode(reader io.Reader) error {
 	if err != nil {
 		return err
 	}
+	if count > uint64(MaxTxOutPerMessage) {
+		log.Error("too many output txs to fit into max message size [count %d , max %d]", count, MaxTxOutPerMessage)
+		return errcode.New(errcode.TxErrOverSize)
+	}
 
 	tx.outs = make([]*txout.TxOut, count)
 	for i := uint64(0); i < count; i++ {
@@ -287,7 +294,7 @@ func (tx *Tx) GetVersion() int32 {
 func (tx *Tx) CheckRegularTransaction() error {
 	if tx.IsCoinBase() {
 		log.Debug("tx should not be coinbase")
-		return errcode.New(errcode.TxErrRejectInvalid)
+		return errcode.New(errcode.TxErrIsCoinBase)
 	}
 
 	err := tx.checkTransactionCommon(true)
@@ -298,7 +305,7 @@ func (tx *Tx) CheckRegularTransaction() error {
 	for _, in := range tx.ins {
 		if in.PreviousOutPoint.IsNull() {
 			log.Debug("tx input prevout null")
-			return errcode.New(errcode.TxErrRejectInvalid)
+			return errcode.New(errcode.TxErrNullPreOut)
 		}
 	}
 
@@ -308,7 +315,7 @@ func (tx *Tx) CheckRegularTransaction() error {
 func (tx *Tx) CheckCoinbaseTransaction() error {
 	if !tx.IsCoinBase() {
 		log.Warn("CheckCoinBaseTransaction: TxErrNotCoinBase")
-		return errcode.New(errcode.TxErrRejectInvalid)
+		return errcode.New(errcode.TxErrNotCoinBase)
 	}
 	err := tx.checkTransactionCommon(false)
 	if err != nil {
@@ -318,7 +325,7 @@ func (tx *Tx) CheckCoinbaseTransaction() error {
 	// coinbase in script check
 	if tx.ins[0].GetScriptSig().Size() < 2 || tx.ins[0].GetScriptSig().Size() > 100 {
 		log.Debug("coinbash input hash err script size")
-		return errcode.New(errcode.TxErrRejectInvalid)
+		return errcode.New(errcode.TxErrBadCoinBaseLength)
 	}
 
 	return nil
@@ -328,36 +335,40 @@ func (tx *Tx) checkTransactionCommon(checkDupInput bool) error {
 	//check inputs and outputs
 	if len(tx.ins) == 0 {
 		log.Warn("bad tx, empty ins")
-		return errcode.New(errcode.TxErrRejectInvalid)
+		return errcode.New(errcode.TxErrEmptyInputs)
 	}
 	if len(tx.outs) == 0 {
 		log.Warn("bad tx, empty out")
-		return errcode.New(errcode.TxErrRejectInvalid)
+		return errcode.New(errcode.TxErrEmptyOutputs)
 	}
 
 	if tx.EncodeSize() > consensus.MaxTxSize {
 		log.Warn("tx is oversize, tx:%v, tx size:%d, MaxTxSize:%d", tx, tx.EncodeSize(), consensus.MaxTxSize)
-		return errcode.New(errcode.TxErrRejectInvalid)
+		return errcode.New(errcode.TxErrOverSize)
 	}
 
 	// check outputs money
 	totalOut := amount.Amount(0)
 	for _, out := range tx.outs {
-		err := out.CheckValue()
-		if err != nil {
-			return err
+		if out.GetValue() < 0 {
+			log.Debug("bad tx out value :%d", out.GetValue())
+			return errcode.New(errcode.TxOutErrNegativeValue)
+		}
+		if out.GetValue() > amount.Amount(util.MaxMoney) {
+			log.Debug("bad tx out value :%d", out.GetValue())
+			return errcode.New(errcode.TxOutErrTooLargeValue)
 		}
 		totalOut += out.GetValue()
 		if !amount.MoneyRange(totalOut) {
 			log.Debug("bad tx totalOut value :%d", totalOut)
-			return errcode.New(errcode.TxErrRejectInvalid)
+			return errcode.New(errcode.TxErrTotalMoneyTooLarge)
 		}
 	}
 
 	// check sigopcount
 	if tx.GetSigOpCountWithoutP2SH() > MaxTxSigOpsCounts {
 		log.Debug("bad tx sigops :%d", tx.GetSigOpCountWithoutP2SH())
-		return errcode.New(errcode.TxErrRejectInvalid)
+		return errcode.New(errcode.TxErrTooManySigOps)
 	}
 
 	// check dup input
@@ -377,11 +388,23 @@ func (tx *Tx) CheckDuplicateIns(outpoints *map[outpoint.OutPoint]bool) error {
 			(*outpoints)[*(in.PreviousOutPoint)] = true
 		} else {
 			log.Debug("bad tx, duplicate inputs")
-			return errcode.New(errcode.TxErrRejectInvalid)
+			return errcode.New(errcode.TxErrDupIns)
 		}
 	}
 	return nil
 }
+
+// HasLargeDataCarrier reports whether the transaction has a data-carrier
+// output larger than the standard limit, see TxOut.IsLargeDataCarrier.
+func (tx *Tx) HasLargeDataCarrier() bool {
+	for _, out := range tx.outs {
+		if out.IsLargeDataCarrier() {
+			return true
+		}
+	}
+	return false
+}
+
 func (tx *Tx) CheckStandard() error {
 	// check version
 	if tx.version > MaxStandardVersion || tx.version < 1 {
@@ -490,6 +513,9 @@ func (tx *Tx) SignStep(redeemScripts map[string]string, keys map[string]*crypto.
 	if pubKeyType == script.ScriptPubkey {
 		pubKeyHashString := string(util.Hash160(pubKeys[0]))
 		privateKey := keys[pubKeyHashString]
+		if privateKey == nil {
+			return nil, pubKeyType, errcode.New(errcode.TxErrSignRawTransaction)
+		}
 		signature, err := tx.signOne(scriptPubKey, privateKey, hashType, nIn, value)
 		if err != nil {
 			return nil, pubKeyType, err
@@ -503,6 +529,9 @@ func (tx *Tx) SignStep(redeemScripts map[string]string, keys map[string]*crypto.
 	if pubKeyType == script.ScriptPubkeyHash {
 		pubKeyHashString := string(pubKeys[0])
 		privateKey := keys[pubKeyHashString]
+		if privateKey == nil {
+			return nil, pubKeyType, errcode.New(errcode.TxErrSignRawTransaction)
+		}
 		signature, err := tx.signOne(scriptPubKey, privateKey, hashType, nIn, value)
 		if err != nil {
 			return nil, pubKeyType, err
@@ -514,29 +543,33 @@ func (tx *Tx) SignStep(redeemScripts map[string]string, keys map[string]*crypto.
 		sigData = append(sigData, pkBytes)
 		return sigData, pubKeyType, nil
 	}
-	// signature1|hashType signature2|hashType...signatureM|hashType
+	// OP_0 signature1|hashType signature2|hashType...signatureM|hashType
+	// The signatures produced so far are returned along with the error when
+	// fewer than M keys are known, so they can be combined with other signers'.
 	if pubKeyType == script.ScriptMultiSig {
-		sigBytes := make([]byte, 0)
-		sigBytes = append(sigBytes, byte(0))
 		requiredSigs := int(pubKeys[0][0])
 		signed := 0
-		emptyBytes := []byte{}
-		sigData = append(sigData, emptyBytes)
-		for _, e := range pubKeys[1 : len(pubKeys)-2] {
-			pubKeyHashString := string(util.Hash160(e))
-			privateKey := keys[pubKeyHashString]
+		sigData = append(sigData, []byte{})
+		for _, e := range pubKeys[1 : len(pubKeys)-1] {
+			if signed >= requiredSigs {
+				break
+			}
+			privateKey := keys[string(util.Hash160(e))]
+			if privateKey == nil {
+				continue
+			}
 			signature, err := tx.signOne(scriptPubKey, privateKey, hashType, nIn, value)
 			if err != nil {
 				continue
 			}
-			sigBytes = append(sigBytes, signature.Serialize()...)
+			sigBytes := signature.Serialize()
 			sigBytes = append(sigBytes, byte(hashType))
 			sigData = append(sigData, sigBytes)
 			signed++
 		}
 		if signed != requiredSigs {
 			log.Debug("SignStep signed not equal requiredSigs")
-			return nil, pubKeyType, errcode.New(errcode.TxErrSignRawTransaction)
+			return sigData, pubKeyType, errcode.New(errcode.TxErrSignRawTransaction)
 		}
 		return sigData, pubKeyType, nil
 	}
@@ -6,11 +6,13 @@ import (
 	"testing"
 
 	"fmt"
+	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/model/outpoint"
 	"github.com/copernet/copernicus/model/script"
 	"github.com/copernet/copernicus/model/txin"
 	"github.com/copernet/copernicus/model/txout"
 	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
 )
 
 var tests = []struct {
@@ -170,3 +172,96 @@ func TestTxSerializeAndTxUnserialize(t *testing.T) {
 		t.Error("Serialize or Unserialize error")
 	}
 }
+
+func TestCheckTransactionRejectReasons(t *testing.T) {
+	prevHash := util.Hash{0x01}
+	p2pkh := script.NewScriptRaw([]byte{0x76, 0xa9, 0x14,
+		0xc3, 0x98, 0xef, 0xa9, 0xc3, 0x92, 0xba, 0x60, 0x13, 0xc5,
+		0xe0, 0x4e, 0xe7, 0x29, 0x75, 0x5e, 0xf7, 0xf5, 0x8b, 0x32,
+		0x88, 0xac})
+
+	newRegular := func(prevOuts []*outpoint.OutPoint, values ...int64) *Tx {
+		transaction := NewTx(0, TxVersion)
+		for _, prevOut := range prevOuts {
+			transaction.AddTxIn(txin.NewTxIn(prevOut, script.NewEmptyScript(), 0xffffffff))
+		}
+		for _, value := range values {
+			transaction.AddTxOut(txout.NewTxOut(amount.Amount(value), p2pkh))
+		}
+		return transaction
+	}
+	newCoinbase := func(scriptSig []byte) *Tx {
+		transaction := NewTx(0, TxVersion)
+		transaction.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.Hash{}, 0xffffffff),
+			script.NewScriptRaw(scriptSig), 0xffffffff))
+		transaction.AddTxOut(txout.NewTxOut(amount.Amount(50*util.COIN), p2pkh))
+		return transaction
+	}
+
+	regularTests := []struct {
+		name string
+		tx   *Tx
+		want fmt.Stringer
+	}{
+		{"valid", newRegular([]*outpoint.OutPoint{outpoint.NewOutPoint(prevHash, 0)}, 1000), nil},
+		{"empty vin", newRegular(nil, 1000), errcode.TxErrEmptyInputs},
+		{"empty vout", newRegular([]*outpoint.OutPoint{outpoint.NewOutPoint(prevHash, 0)}), errcode.TxErrEmptyOutputs},
+		{"negative vout", newRegular([]*outpoint.OutPoint{outpoint.NewOutPoint(prevHash, 0)}, -1),
+			errcode.TxOutErrNegativeValue},
+		{"vout too large", newRegular([]*outpoint.OutPoint{outpoint.NewOutPoint(prevHash, 0)}, util.MaxMoney+1),
+			errcode.TxOutErrTooLargeValue},
+		{"total out too large", newRegular([]*outpoint.OutPoint{outpoint.NewOutPoint(prevHash, 0)},
+			util.MaxMoney, 1), errcode.TxErrTotalMoneyTooLarge},
+		{"duplicate inputs", newRegular([]*outpoint.OutPoint{outpoint.NewOutPoint(prevHash, 0),
+			outpoint.NewOutPoint(prevHash, 0)}, 1000), errcode.TxErrDupIns},
+		{"null prevout", newRegular([]*outpoint.OutPoint{outpoint.NewOutPoint(prevHash, 0),
+			outpoint.NewOutPoint(util.Hash{}, 0xffffffff)}, 1000), errcode.TxErrNullPreOut},
+		{"coinbase", newCoinbase([]byte{0x01, 0x01}), errcode.TxErrIsCoinBase},
+	}
+
+	for _, test := range regularTests {
+		err := test.tx.CheckRegularTransaction()
+		if test.want == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", test.name, err)
+			}
+			continue
+		}
+		if !errcode.IsErrorCode(err, test.want) {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.want)
+		}
+		if code, _ := errcode.GetTxRejectCode(err); code != errcode.TxErrRejectInvalid {
+			t.Errorf("%s: got reject code %d, want %d", test.name, code, errcode.TxErrRejectInvalid)
+		}
+	}
+
+	coinbaseTests := []struct {
+		name string
+		tx   *Tx
+		want fmt.Stringer
+	}{
+		{"valid", newCoinbase([]byte{0x01, 0x01}), nil},
+		{"script too short", newCoinbase([]byte{0x01}), errcode.TxErrBadCoinBaseLength},
+		{"script too long", newCoinbase(make([]byte, 101)), errcode.TxErrBadCoinBaseLength},
+		{"not coinbase", newRegular([]*outpoint.OutPoint{outpoint.NewOutPoint(prevHash, 0)}, 1000),
+			errcode.TxErrNotCoinBase},
+	}
+
+	for _, test := range coinbaseTests {
+		err := test.tx.CheckCoinbaseTransaction()
+		if test.want == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", test.name, err)
+			}
+			continue
+		}
+		if !errcode.IsErrorCode(err, test.want) {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	_, reason := errcode.GetTxRejectCode(errcode.New(errcode.TxErrDupIns))
+	if reason != "bad-txns-inputs-duplicate" {
+		t.Errorf("got reject reason %s, want bad-txns-inputs-duplicate", reason)
+	}
+}
@@ -1,8 +1,11 @@
 package txout
 
 import (
+	"bytes"
+	"encoding/hex"
 	"testing"
 
+	"github.com/copernet/copernicus/model/script"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
 )
@@ -80,3 +83,50 @@ func TestCompressAmount(t *testing.T) {
 		}
 	}
 }
+
+// the public key of the genesis coinbase output
+const genesisPubKey = "04678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6" +
+	"bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5f"
+
+func TestTxoutCompressor(t *testing.T) {
+	hash160 := "1234567890123456789012345678901234567890"
+	tests := []struct {
+		name   string
+		script string
+		// size of the serialized output with a compressed amount of one byte
+		size int
+	}{
+		{"p2pkh", "76a914" + hash160 + "88ac", 1 + 21},
+		{"p2sh", "a914" + hash160 + "87", 1 + 21},
+		{"p2pk compressed", "21" + "03" + genesisPubKey[2:66] + "ac", 1 + 33},
+		{"p2pk uncompressed", "41" + genesisPubKey + "ac", 1 + 33},
+		{"op_return", "6a0401020304", 1 + 1 + 6},
+		{"empty", "", 1 + 1},
+	}
+
+	for _, test := range tests {
+		raw, err := hex.DecodeString(test.script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := NewTxOut(amount.Amount(util.COIN), script.NewScriptRaw(raw))
+
+		buf := bytes.NewBuffer(nil)
+		if err := NewTxoutCompressor(out).Serialize(buf); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if buf.Len() != test.size {
+			t.Errorf("%s: got %d bytes, want %d", test.name, buf.Len(), test.size)
+		}
+
+		got := NewTxOut(0, nil)
+		if err := NewTxoutCompressor(got).Unserialize(buf); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got.GetValue() != out.GetValue() ||
+			!bytes.Equal(got.GetScriptPubKey().GetData(), raw) {
+			t.Errorf("%s: got %v %x, want %v %x", test.name, got.GetValue(),
+				got.GetScriptPubKey().GetData(), out.GetValue(), raw)
+		}
+	}
+}
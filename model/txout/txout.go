@@ -100,7 +100,11 @@ func (txOut *TxOut) CheckStandard() (pubKeyType int, err error) { //4
 			return pubKeyType, errcode.New(errcode.TxErrRejectNonstandard)
 		}
 	} else if pubKeyType == script.ScriptNullData {
-		if !conf.Cfg.Script.AcceptDataCarrier || uint(txOut.scriptPubKey.Size()) > conf.Cfg.Script.MaxDatacarrierBytes {
+		maxBytes := conf.Cfg.Script.MaxDatacarrierBytes
+		if conf.Cfg.Script.LargeDataCarrier && conf.Cfg.Script.MaxLargeDatacarrierBytes > maxBytes {
+			maxBytes = conf.Cfg.Script.MaxLargeDatacarrierBytes
+		}
+		if !conf.Cfg.Script.AcceptDataCarrier || uint(txOut.scriptPubKey.Size()) > maxBytes {
 			log.Debug("ScriptErrNullData")
 			return pubKeyType, errcode.New(errcode.TxErrRejectNonstandard)
 		}
@@ -109,6 +113,16 @@ func (txOut *TxOut) CheckStandard() (pubKeyType int, err error) { //4
 	return
 }
 
+// IsLargeDataCarrier reports whether the output is a data carrier larger than
+// MaxDatacarrierBytes, which only peers opted in to large data carriers relay.
+func (txOut *TxOut) IsLargeDataCarrier() bool {
+	if uint(txOut.scriptPubKey.Size()) <= conf.Cfg.Script.MaxDatacarrierBytes {
+		return false
+	}
+	pubKeyType, _, _ := txOut.scriptPubKey.CheckScriptPubKeyStandard()
+	return pubKeyType == script.ScriptNullData
+}
+
 func (txOut *TxOut) GetPubKeyType() (pubKeyType int, err error) {
 	pubKeyType, _, err = txOut.scriptPubKey.CheckScriptPubKeyStandard()
 	return
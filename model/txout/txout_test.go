@@ -230,3 +230,31 @@ func TestIsEqual(t *testing.T) {
 	//}
 
 }
+
+func TestLargeDataCarrier(t *testing.T) {
+	conf.Cfg = conf.InitConfig([]string{})
+	defer func(max uint) {
+		conf.Cfg.Script.LargeDataCarrier = false
+		conf.Cfg.Script.MaxLargeDatacarrierBytes = max
+	}(conf.Cfg.Script.MaxLargeDatacarrierBytes)
+
+	data := make([]byte, 500)
+	blob := NewTxOut(0, script.NewScriptRaw(append([]byte{opcodes.OP_RETURN, opcodes.OP_PUSHDATA2, 0xf4, 0x01}, data...)))
+	small := NewTxOut(0, script.NewScriptRaw([]byte{opcodes.OP_RETURN, 0x01, 0x00}))
+
+	if !blob.IsLargeDataCarrier() || small.IsLargeDataCarrier() {
+		t.Error("only the output above MaxDatacarrierBytes is a large data carrier")
+	}
+	if _, err := blob.CheckStandard(); err == nil {
+		t.Error("a large data carrier should not be standard without opting in")
+	}
+
+	conf.Cfg.Script.LargeDataCarrier = true
+	if _, err := blob.CheckStandard(); err != nil {
+		t.Errorf("a large data carrier should be standard once opted in: %v", err)
+	}
+	conf.Cfg.Script.MaxLargeDatacarrierBytes = 300
+	if _, err := blob.CheckStandard(); err == nil {
+		t.Error("a data carrier above MaxLargeDatacarrierBytes should not be standard")
+	}
+}
@@ -23,9 +23,12 @@ func GetUtxoCacheInstance() CacheView {
 type CacheView interface {
 	GetCoin(outpoint *outpoint.OutPoint) *Coin
 	HaveCoin(point *outpoint.OutPoint) bool
+	GetTxOutIndexes(hash *util.Hash) []uint32
 	GetBestBlock() (util.Hash, error)
+	GetCoinsDB() CoinsDB
 	UpdateCoins(tempCacheCoins *CoinsMap, hash *util.Hash) error
 	DynamicMemoryUsage() int64
 	GetCacheSize() int
+	GetStats() CacheStats
 	Flush() bool
 }
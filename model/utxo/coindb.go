@@ -11,6 +11,14 @@ import (
 	"github.com/syndtr/goleveldb/leveldb"
 )
 
+// SchemaVersion is the version of the coins database layout written by this
+// release. Bump it together with a new entry of migrations when the layout
+// changes, e.g. to store token data in the coins.
+const SchemaVersion uint32 = 1
+
+// migrations upgrade coins databases of older schema versions.
+var migrations []db.Migration
+
 type CoinsDB struct {
 	dbw *db.DBWrapper
 }
@@ -51,6 +59,23 @@ func (coinsViewDB *CoinsDB) HaveCoin(outpoint *outpoint.OutPoint) bool {
 	return coinsViewDB.dbw.Exists(buf.Bytes())
 }
 
+// GetTxOutIndexes returns the indexes of the outputs of a transaction which
+// have a coin in the database, in key order.
+func (coinsViewDB *CoinsDB) GetTxOutIndexes(hash *util.Hash) ([]uint32, error) {
+	cursor := coinsViewDB.dbw.Prefix(append([]byte{db.DbCoin}, hash[:]...))
+	defer cursor.Close()
+
+	indexes := make([]uint32, 0)
+	for cursor.SeekToFirst(); cursor.Valid(); cursor.Next() {
+		out := outpoint.OutPoint{}
+		if err := NewCoinKey(&out).Unserialize(bytes.NewReader(cursor.GetKey())); err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, out.Index)
+	}
+	return indexes, nil
+}
+
 func (coinsViewDB *CoinsDB) GetBestBlock() (*util.Hash, error) {
 	v, err := coinsViewDB.dbw.Read([]byte{db.DbBestBlock})
 	if err == leveldb.ErrNotFound {
@@ -131,6 +156,9 @@ func newCoinsDB(do *db.DBOption) *CoinsDB {
 	if err != nil {
 		panic("init CoinsDB failed..." + err.Error())
 	}
+	if err := dbw.Upgrade(SchemaVersion, migrations); err != nil {
+		panic("upgrade CoinsDB failed..." + err.Error())
+	}
 
 	return &CoinsDB{
 		dbw: dbw,
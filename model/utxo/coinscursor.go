@@ -0,0 +1,78 @@
+package utxo
+
+import (
+	"bytes"
+
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/copernet/copernicus/util"
+	lvlutil "github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// CoinsCursor walks the coins of the database in key order, so the coins of a
+// transaction come one after the other. The best block and the coins are read
+// from one consistent view of the database, unaffected by later flushes.
+type CoinsCursor struct {
+	iter      *db.IterWrapper
+	hashBlock util.Hash
+}
+
+// Cursor returns a cursor positioned at the first coin of the database. The
+// cursor must be closed after use.
+func (coinsViewDB *CoinsDB) Cursor() (*CoinsCursor, error) {
+	// the best block key sorts right before the coins, so a single iterator
+	// covers both
+	iter := coinsViewDB.dbw.Iterator(&lvlutil.Range{
+		Start: []byte{db.DbBestBlock},
+		Limit: []byte{db.DbCoin + 1},
+	})
+	cursor := &CoinsCursor{iter: iter}
+	for iter.SeekToFirst(); iter.Valid(); iter.Next() {
+		key := iter.GetKey()
+		if len(key) == 1 && key[0] == db.DbBestBlock {
+			if _, err := cursor.hashBlock.Unserialize(bytes.NewReader(iter.GetVal())); err != nil {
+				iter.Close()
+				return nil, err
+			}
+		}
+		if key[0] == db.DbCoin {
+			break
+		}
+	}
+	return cursor, nil
+}
+
+// GetBestBlock returns the block the walked coins set is the state at.
+func (cursor *CoinsCursor) GetBestBlock() util.Hash {
+	return cursor.hashBlock
+}
+
+func (cursor *CoinsCursor) Valid() bool {
+	return cursor.iter.Valid()
+}
+
+func (cursor *CoinsCursor) Next() {
+	cursor.iter.Next()
+}
+
+// GetKey returns the outpoint of the current coin.
+func (cursor *CoinsCursor) GetKey() (*outpoint.OutPoint, error) {
+	out := &outpoint.OutPoint{}
+	if err := NewCoinKey(out).Unserialize(bytes.NewReader(cursor.iter.GetKey())); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetValue returns the current coin.
+func (cursor *CoinsCursor) GetValue() (*Coin, error) {
+	coin := NewEmptyCoin()
+	if err := coin.Unserialize(bytes.NewReader(cursor.iter.GetVal())); err != nil {
+		return nil, err
+	}
+	return coin, nil
+}
+
+func (cursor *CoinsCursor) Close() {
+	cursor.iter.Close()
+}
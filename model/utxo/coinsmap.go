@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"fmt"
 
+	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/model/outpoint"
 	"github.com/copernet/copernicus/util"
@@ -12,6 +13,9 @@ import (
 type CoinsMap struct {
 	cacheCoins map[outpoint.OutPoint]*Coin
 	hashBlock  util.Hash
+	// parent is the view the coins map is an overlay of, the global cache
+	// when nil
+	parent *CoinsMap
 }
 
 func (cm *CoinsMap) GetMap() map[outpoint.OutPoint]*Coin {
@@ -24,6 +28,15 @@ func NewEmptyCoinsMap() *CoinsMap {
 	return cm
 }
 
+// NewCoinsMapOverlay returns an empty coins map stacked on parent. Coins are
+// fetched through parent, but changes stay in the overlay until it is flushed
+// into parent, so a validation can be tried and simply dropped on failure.
+func NewCoinsMapOverlay(parent *CoinsMap) *CoinsMap {
+	cm := NewEmptyCoinsMap()
+	cm.parent = parent
+	return cm
+}
+
 func (cm *CoinsMap) AccessCoin(outpoint *outpoint.OutPoint) *Coin {
 	entry := cm.GetCoin(outpoint)
 	if entry == nil {
@@ -54,31 +67,104 @@ func DisplayCoinMap(cm *CoinsMap) {
 	}
 }
 
+// Flush writes the changes of the coins map to its parent, the global cache
+// unless it is an overlay, and empties it.
 func (cm *CoinsMap) Flush(hashBlock util.Hash) bool {
+	if cm.parent != nil {
+		cm.flushToParent()
+		cm.parent.hashBlock = hashBlock
+		return true
+	}
 	ok := GetUtxoCacheInstance().UpdateCoins(cm, &hashBlock)
 	cm.cacheCoins = make(map[outpoint.OutPoint]*Coin)
 	return ok == nil
 }
 
-func (cm *CoinsMap) AddCoin(point *outpoint.OutPoint, coin *Coin, possibleOverwrite bool) {
+// flushToParent moves the added and spent coins of an overlay to its parent,
+// keeping the fresh and dirty flags meaningful for the parent: a coin the
+// parent knows about is not fresh, and a spent coin the parent created
+// there is simply forgotten.
+func (cm *CoinsMap) flushToParent() {
+	parent := cm.parent
+	for point, coin := range cm.cacheCoins {
+		if !coin.dirty && !coin.fresh {
+			continue
+		}
+		parentCoin, ok := parent.cacheCoins[point]
+		if coin.IsSpent() {
+			if !ok && coin.fresh {
+				continue
+			}
+			if ok && parentCoin.fresh {
+				delete(parent.cacheCoins, point)
+				continue
+			}
+			coin.fresh = false
+			coin.dirty = true
+		} else if ok {
+			coin.fresh = parentCoin.fresh
+			coin.dirty = !parentCoin.fresh
+		}
+		parent.cacheCoins[point] = coin
+	}
+	cm.cacheCoins = make(map[outpoint.OutPoint]*Coin)
+}
+
+// HaveCoin reports whether an unspent coin exists for out in the view, which
+// includes the coins of the parents of an overlay.
+func (cm *CoinsMap) HaveCoin(out *outpoint.OutPoint) bool {
+	return cm.lookupCoin(out) != nil
+}
+
+// lookupCoin returns the unspent coin for out seen through the coins map and
+// its parents, without caching it.
+func (cm *CoinsMap) lookupCoin(out *outpoint.OutPoint) *Coin {
+	for view := cm; view != nil; view = view.parent {
+		if coin, ok := view.cacheCoins[*out]; ok {
+			if coin.IsSpent() {
+				return nil
+			}
+			return coin
+		}
+		if view.parent == nil {
+			coin := GetUtxoCacheInstance().GetCoin(out)
+			if coin == nil || coin.IsSpent() {
+				return nil
+			}
+			return coin
+		}
+	}
+	return nil
+}
+
+// AddCoin adds coin at point to the coins map. Unless possibleOverwrite is
+// set, which is only legitimate for the two historical duplicate coinbases
+// and when undoing or copying coins, an unspent coin at point in the map or
+// its parents is an error: overwriting it would silently destroy it.
+func (cm *CoinsMap) AddCoin(point *outpoint.OutPoint, coin *Coin, possibleOverwrite bool) error {
 	if coin.IsSpent() {
 		panic("add a spent coin")
 	}
 	//script is not spend
 	if !coin.IsSpendable() {
-		return
+		return nil
 	}
 
-	if !possibleOverwrite {
-		oldcoin := cm.FetchCoin(point)
-		if oldcoin != nil {
-			panic("Adding new coin that is in coincache or db")
-		}
+	if !possibleOverwrite && cm.HaveCoin(point) {
+		log.Error("AddCoin(): would overwrite the unspent coin %s", point.String())
+		return errcode.New(errcode.TxErrOutAlreadHave)
+	}
+	// a coin spent in the map but not in its parent yet is only modified
+	// by being added again, the parent has to overwrite its own coin
+	if oldcoin, ok := cm.cacheCoins[*point]; ok && oldcoin.dirty && !oldcoin.fresh {
+		coin.dirty = true
+		coin.fresh = false
+	} else {
+		coin.dirty = false
+		coin.fresh = true
 	}
-	coin.dirty = false
-	coin.fresh = true
 	cm.cacheCoins[*point] = coin
-
+	return nil
 }
 
 func (cm *CoinsMap) SetBestBlock(hash util.Hash) {
@@ -115,6 +201,19 @@ func (cm *CoinsMap) FetchCoin(out *outpoint.OutPoint) *Coin {
 	if coin != nil {
 		return coin
 	}
+	if cm.parent != nil {
+		coin = cm.parent.lookupCoin(out)
+		if coin == nil {
+			log.Error("not found coin by outpoint(%v)", out)
+			return nil
+		}
+		// the copy is unmodified as far as the overlay is concerned
+		newCoin := coin.DeepCopy()
+		newCoin.dirty = false
+		newCoin.fresh = false
+		cm.cacheCoins[*out] = newCoin
+		return newCoin
+	}
 	coin = GetUtxoCacheInstance().GetCoin(out)
 	if coin == nil {
 		log.Error("not found coin by outpoint(%v)", out)
@@ -1,10 +1,14 @@
 package utxo
 
 import (
+	"io/ioutil"
+	"os"
+
 	"github.com/copernet/copernicus/model/opcodes"
 	"github.com/copernet/copernicus/model/outpoint"
 	"github.com/copernet/copernicus/model/script"
 	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/persist/db"
 	"github.com/copernet/copernicus/util"
 	"reflect"
 	"testing"
@@ -142,3 +146,116 @@ func TestCoinsMap(t *testing.T) {
 		t.Error("get coin should nil, because the coin has been uncache ")
 	}
 }
+
+func TestCoinsMapOverlay(t *testing.T) {
+	path, err := ioutil.TempDir("", "dbtestoverlay")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	InitUtxoLruTip(&UtxoConfig{Do: &db.DBOption{
+		FilePath:  path,
+		CacheSize: 1 << 20,
+	}})
+
+	txOut := txout.NewTxOut(3, script.NewScriptRaw([]byte{opcodes.OP_11, opcodes.OP_EQUAL}))
+	hash := util.HashFromString("000000002dd5588a74784eaa7ab0507a18ad16a236e7b1ce69f00d7ddfb5d0c9")
+	outA := outpoint.OutPoint{Hash: *hash, Index: 0}
+	outB := outpoint.OutPoint{Hash: *hash, Index: 1}
+
+	global := NewEmptyCoinsMap()
+	global.AddCoin(&outA, NewCoin(txOut, 10, false), false)
+	if !global.Flush(*hash) {
+		t.Fatal("flush to the global cache failed")
+	}
+
+	base := NewEmptyCoinsMap()
+	overlay := NewCoinsMapOverlay(base)
+	if overlay.FetchCoin(&outA) == nil {
+		t.Fatal("the overlay should see the coin of the global cache")
+	}
+	if overlay.SpendCoin(&outA) == nil {
+		t.Fatal("spend coin failed")
+	}
+	overlay.AddCoin(&outB, NewCoin(txOut, 11, false), false)
+
+	if overlay.HaveCoin(&outA) || !overlay.HaveCoin(&outB) {
+		t.Error("the overlay should see its own changes")
+	}
+	if !base.HaveCoin(&outA) || base.HaveCoin(&outB) {
+		t.Error("the changes of the overlay should not reach the base before flush")
+	}
+
+	overlay.Flush(*hash)
+	if len(overlay.GetMap()) != 0 {
+		t.Error("the overlay should be empty after flush")
+	}
+	if base.HaveCoin(&outA) || !base.HaveCoin(&outB) {
+		t.Error("the changes of the overlay should reach the base after flush")
+	}
+	if GetUtxoCacheInstance().GetCoin(&outA) == nil {
+		t.Error("the global cache should not be modified by an overlay flush")
+	}
+
+	if !base.Flush(*hash) {
+		t.Fatal("flush to the global cache failed")
+	}
+	if c := GetUtxoCacheInstance().GetCoin(&outA); c != nil && !c.IsSpent() {
+		t.Error("the spent coin should be removed from the global cache")
+	}
+	if GetUtxoCacheInstance().GetCoin(&outB) == nil {
+		t.Error("the added coin should be in the global cache")
+	}
+}
+
+func TestAddCoinOverwrite(t *testing.T) {
+	path, err := ioutil.TempDir("", "dbtestoverwrite")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	InitUtxoLruTip(&UtxoConfig{Do: &db.DBOption{
+		FilePath:  path,
+		CacheSize: 1 << 20,
+	}})
+
+	txOut := txout.NewTxOut(3, script.NewScriptRaw([]byte{opcodes.OP_11, opcodes.OP_EQUAL}))
+	hash := util.HashFromString("000000002dd5588a74784eaa7ab0507a18ad16a236e7b1ce69f00d7ddfb5d0d1")
+	outA := outpoint.OutPoint{Hash: *hash, Index: 0}
+	outB := outpoint.OutPoint{Hash: *hash, Index: 1}
+
+	global := NewEmptyCoinsMap()
+	if err := global.AddCoin(&outA, NewCoin(txOut, 10, false), false); err != nil {
+		t.Fatal(err)
+	}
+	if !global.Flush(*hash) {
+		t.Fatal("flush to the global cache failed")
+	}
+
+	cm := NewEmptyCoinsMap()
+	if err := cm.AddCoin(&outA, NewCoin(txOut, 11, false), false); err == nil {
+		t.Error("overwriting a coin of the global cache should fail")
+	}
+	if err := cm.AddCoin(&outB, NewCoin(txOut, 11, false), false); err != nil {
+		t.Errorf("adding a new coin failed: %v", err)
+	}
+	if err := cm.AddCoin(&outB, NewCoin(txOut, 12, false), false); err == nil {
+		t.Error("overwriting a coin of the map should fail")
+	}
+	if coin := cm.GetCoin(&outB); coin == nil || coin.GetHeight() != 11 {
+		t.Error("a refused coin should not replace the unspent one")
+	}
+
+	overlay := NewCoinsMapOverlay(cm)
+	if err := overlay.AddCoin(&outB, NewCoin(txOut, 12, false), false); err == nil {
+		t.Error("overwriting a coin of the parent should fail")
+	}
+	if err := overlay.AddCoin(&outB, NewCoin(txOut, 12, true), true); err != nil {
+		t.Errorf("a possible overwrite should be allowed: %v", err)
+	}
+
+	cm.SpendCoin(&outB)
+	if err := cm.AddCoin(&outB, NewCoin(txOut, 13, false), false); err != nil {
+		t.Errorf("adding a coin over a spent one failed: %v", err)
+	}
+}
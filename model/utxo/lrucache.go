@@ -2,6 +2,9 @@ package utxo
 
 import (
 	//"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/copernet/copernicus/log"
@@ -11,11 +14,41 @@ import (
 	"github.com/syndtr/goleveldb/leveldb"
 )
 
+// coinsCacheCapacity is the number of coins the cache keeps at most.
+const coinsCacheCapacity = 1000000
+
 type CoinsLruCache struct {
 	db         CoinsDB
 	hashBlock  util.Hash
 	cacheCoins *lru.Cache
 	dirtyCoins map[outpoint.OutPoint]*Coin //write database temporary cache
+
+	// dirtyOutputs indexes the outputs in dirtyCoins by transaction.  Both
+	// are guarded by dirtyLock, since RPC lookups read them while blocks are
+	// connected.
+	dirtyOutputs map[util.Hash]map[uint32]struct{}
+	dirtyLock    sync.RWMutex
+	// dirtyCount is the number of coins in dirtyCoins, read by the
+	// statistics without waiting for a flush to release dirtyLock.
+	dirtyCount int64
+
+	// hits, misses and notFound count the lookups answered by the cache, the
+	// ones read through to the database and, among the latter, those which
+	// found no coin there.
+	hits     uint64
+	misses   uint64
+	notFound uint64
+}
+
+// CacheStats are the lookup counters and the size of a coins cache, for
+// sizing it.
+type CacheStats struct {
+	Hits       uint64
+	Misses     uint64
+	NotFound   uint64
+	Coins      int
+	DirtyCoins int
+	Capacity   int
 }
 
 func (coinsCache *CoinsLruCache) GetCoinsDB() CoinsDB {
@@ -34,25 +67,58 @@ func InitUtxoLruTip(uc *UtxoConfig) {
 func newCoinsLruCache(db CoinsDB) CacheView {
 	c := new(CoinsLruCache)
 	c.db = db
-	cache, err := lru.New(1000000)
+	cache, err := lru.New(coinsCacheCapacity)
 	if err != nil {
 		log.Error("Error: NewCoinsLruCache err %#v", err)
 		panic("Error: NewCoinsLruCache err")
 	}
 	c.cacheCoins = cache
 	c.dirtyCoins = make(map[outpoint.OutPoint]*Coin)
+	c.dirtyOutputs = make(map[util.Hash]map[uint32]struct{})
 	return c
 }
 
+// setDirty records coin as not flushed yet.  dirtyLock must be held.
+func (coinsCache *CoinsLruCache) setDirty(point outpoint.OutPoint, coin *Coin) {
+	if _, ok := coinsCache.dirtyCoins[point]; !ok {
+		atomic.AddInt64(&coinsCache.dirtyCount, 1)
+	}
+	coinsCache.dirtyCoins[point] = coin
+	outputs, ok := coinsCache.dirtyOutputs[point.Hash]
+	if !ok {
+		outputs = make(map[uint32]struct{})
+		coinsCache.dirtyOutputs[point.Hash] = outputs
+	}
+	outputs[point.Index] = struct{}{}
+}
+
+// deleteDirty forgets the coin of point not flushed yet.  dirtyLock must be
+// held.
+func (coinsCache *CoinsLruCache) deleteDirty(point outpoint.OutPoint) {
+	if _, ok := coinsCache.dirtyCoins[point]; ok {
+		atomic.AddInt64(&coinsCache.dirtyCount, -1)
+	}
+	delete(coinsCache.dirtyCoins, point)
+	if outputs, ok := coinsCache.dirtyOutputs[point.Hash]; ok {
+		delete(outputs, point.Index)
+		if len(outputs) == 0 {
+			delete(coinsCache.dirtyOutputs, point.Hash)
+		}
+	}
+}
+
 func (coinsCache *CoinsLruCache) GetCoin(outpoint *outpoint.OutPoint) *Coin {
 	c, ok := coinsCache.cacheCoins.Get(*outpoint)
 	if ok {
+		atomic.AddUint64(&coinsCache.hits, 1)
 		log.Info("getCoin from cache")
 		return c.(*Coin)
 	}
+	atomic.AddUint64(&coinsCache.misses, 1)
 	db := coinsCache.db
 	coin, err := db.GetCoin(outpoint)
 	if err != nil && err == leveldb.ErrNotFound {
+		atomic.AddUint64(&coinsCache.notFound, 1)
 		return nil
 	}
 	if err != nil {
@@ -77,6 +143,40 @@ func (coinsCache *CoinsLruCache) HaveCoin(point *outpoint.OutPoint) bool {
 	return coin != nil && !coin.IsSpent()
 }
 
+// GetTxOutIndexes returns, in ascending order, the indexes of the outputs of a
+// transaction which may still be unspent: those with a coin in the database
+// and those with a coin not flushed yet. Callers must check the coins with
+// GetCoin, since some of them may have been spent in the meantime.
+func (coinsCache *CoinsLruCache) GetTxOutIndexes(hash *util.Hash) []uint32 {
+	// the coins not flushed are read first: a flush in between moves them
+	// to the database, where they are found next
+	coinsCache.dirtyLock.RLock()
+	dirty := make([]uint32, 0, len(coinsCache.dirtyOutputs[*hash]))
+	for index := range coinsCache.dirtyOutputs[*hash] {
+		coin := coinsCache.dirtyCoins[outpoint.OutPoint{Hash: *hash, Index: index}]
+		if !coin.IsSpent() {
+			dirty = append(dirty, index)
+		}
+	}
+	coinsCache.dirtyLock.RUnlock()
+
+	indexes, err := coinsCache.db.GetTxOutIndexes(hash)
+	if err != nil {
+		log.Error("CoinsLruCache.GetTxOutIndexes: read coins of tx %s failed: %v", hash.String(), err)
+		indexes = make([]uint32, 0)
+	}
+	indexes = append(indexes, dirty...)
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	unique := indexes[:0]
+	for i, index := range indexes {
+		if i == 0 || index != indexes[i-1] {
+			unique = append(unique, index)
+		}
+	}
+	return unique
+}
+
 func (coinsCache *CoinsLruCache) GetBestBlock() (util.Hash, error) {
 	if coinsCache.hashBlock.IsNull() {
 		hashBlock, err := coinsCache.db.GetBestBlock()
@@ -97,6 +197,9 @@ func (coinsCache *CoinsLruCache) GetBestBlock() (util.Hash, error) {
 }
 
 func (coinsCache *CoinsLruCache) UpdateCoins(cm *CoinsMap, hash *util.Hash) error {
+	coinsCache.dirtyLock.Lock()
+	defer coinsCache.dirtyLock.Unlock()
+
 	tempCacheCoins := cm.cacheCoins
 	for point, tempCacheCoin := range tempCacheCoins {
 		// Ignore non-dirty entries (optimization).
@@ -115,7 +218,7 @@ func (coinsCache *CoinsLruCache) UpdateCoins(cm *CoinsMap, hash *util.Hash) erro
 					//if !ret {
 					//	log.Error("lruCache:add coin failed, please check")
 					//}
-					coinsCache.dirtyCoins[point] = tempCacheCoin
+					coinsCache.setDirty(point, tempCacheCoin)
 				} else {
 					panic("newcoin is dirty and not fresh, but oldcoin is not exist")
 				}
@@ -130,14 +233,11 @@ func (coinsCache *CoinsLruCache) UpdateCoins(cm *CoinsMap, hash *util.Hash) erro
 					// modified and being pruned. This means we can just delete
 					// it from the parent.
 					coinsCache.cacheCoins.Remove(point)
-					_, ok = coinsCache.dirtyCoins[point]
-					if ok {
-						delete(coinsCache.dirtyCoins, point)
-					}
+					coinsCache.deleteDirty(point)
 				} else {
 					tempCacheCoin.dirty = true
 					coinsCache.cacheCoins.Add(point, tempCacheCoin)
-					coinsCache.dirtyCoins[point] = tempCacheCoin
+					coinsCache.setDirty(point, tempCacheCoin)
 				}
 			}
 		}
@@ -151,9 +251,14 @@ func (coinsCache *CoinsLruCache) UpdateCoins(cm *CoinsMap, hash *util.Hash) erro
 func (coinsCache *CoinsLruCache) Flush() bool {
 	log.Debug("flush utxo: bestblockhash:%s", coinsCache.hashBlock.String())
 
+	coinsCache.dirtyLock.Lock()
+	defer coinsCache.dirtyLock.Unlock()
 	if len(coinsCache.dirtyCoins) > 0 || !coinsCache.hashBlock.IsNull() {
+		// BatchWrite empties the dirty coins it writes
 		ok := coinsCache.db.BatchWrite(coinsCache.dirtyCoins, coinsCache.hashBlock)
+		atomic.StoreInt64(&coinsCache.dirtyCount, int64(len(coinsCache.dirtyCoins)))
 		if ok == nil {
+			coinsCache.dirtyOutputs = make(map[util.Hash]map[uint32]struct{})
 			coinsCache.cacheCoins.Purge()
 		} else {
 			panic("CoinsLruCache.flush err:")
@@ -225,6 +330,18 @@ func (coinsCache *CoinsLruCache) GetCacheSize() int {
 	return coinsCache.cacheCoins.Len()
 }
 
+// GetStats returns the lookup counters of the cache since startup and its size.
+func (coinsCache *CoinsLruCache) GetStats() CacheStats {
+	return CacheStats{
+		Hits:       atomic.LoadUint64(&coinsCache.hits),
+		Misses:     atomic.LoadUint64(&coinsCache.misses),
+		NotFound:   atomic.LoadUint64(&coinsCache.notFound),
+		Coins:      coinsCache.cacheCoins.Len(),
+		DirtyCoins: int(atomic.LoadInt64(&coinsCache.dirtyCount)),
+		Capacity:   coinsCacheCapacity,
+	}
+}
+
 func (coinsCache *CoinsLruCache) DynamicMemoryUsage() int64 {
 	return int64(unsafe.Sizeof(coinsCache.cacheCoins))
 }
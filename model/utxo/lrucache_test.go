@@ -7,6 +7,9 @@ import (
 	"github.com/copernet/copernicus/model/txout"
 	"github.com/copernet/copernicus/persist/db"
 	"github.com/copernet/copernicus/util"
+	"io/ioutil"
+	"os"
+	"reflect"
 	"testing"
 )
 
@@ -98,3 +101,134 @@ func TestLRUCache(t *testing.T) {
 	}
 
 }
+
+func TestGetTxOutIndexes(t *testing.T) {
+	path, err := ioutil.TempDir("", "dbtestindexes")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	uc := &UtxoConfig{Do: &db.DBOption{
+		FilePath:  path,
+		CacheSize: 1 << 20,
+	}}
+	InitUtxoLruTip(uc)
+
+	hash := util.HashFromString("000000002dd5588a74784eaa7ab0507a18ad16a236e7b1ce69f00d7ddfb5d0c8")
+	txOut := txout.NewTxOut(3, script.NewScriptRaw([]byte{opcodes.OP_11, opcodes.OP_EQUAL}))
+	if indexes := utxoTip.GetTxOutIndexes(hash); len(indexes) != 0 {
+		t.Fatalf("unknown tx should have no outputs, got %v", indexes)
+	}
+
+	necm := NewEmptyCoinsMap()
+	for _, index := range []uint32{0, 300} {
+		necm.AddCoin(&outpoint.OutPoint{Hash: *hash, Index: index}, NewCoin(txOut, 10, false), true)
+	}
+	if err := utxoTip.UpdateCoins(necm, hash); err != nil {
+		t.Fatal(err)
+	}
+	utxoTip.Flush()
+
+	// an output still in the cache only
+	necm = NewEmptyCoinsMap()
+	necm.AddCoin(&outpoint.OutPoint{Hash: *hash, Index: 1}, NewCoin(txOut, 11, false), true)
+	if err := utxoTip.UpdateCoins(necm, hash); err != nil {
+		t.Fatal(err)
+	}
+
+	indexes := utxoTip.GetTxOutIndexes(hash)
+	if !reflect.DeepEqual(indexes, []uint32{0, 1, 300}) {
+		t.Errorf("got output indexes %v, want [0 1 300]", indexes)
+	}
+}
+
+// TestGetTxOutIndexesConcurrent looks outputs up while blocks add and flush
+// coins: the lookups must not race the writes, nor lose the coins a flush
+// moves to the database.
+func TestGetTxOutIndexesConcurrent(t *testing.T) {
+	path, err := ioutil.TempDir("", "dbtestconcurrent")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	InitUtxoLruTip(&UtxoConfig{Do: &db.DBOption{
+		FilePath:  path,
+		CacheSize: 1 << 20,
+	}})
+
+	const outputs = 1000
+	hash := util.HashFromString("000000002dd5588a74784eaa7ab0507a18ad16a236e7b1ce69f00d7ddfb5d0e1")
+	txOut := txout.NewTxOut(3, script.NewScriptRaw([]byte{opcodes.OP_11, opcodes.OP_EQUAL}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := uint32(0); i < outputs; i++ {
+			necm := NewEmptyCoinsMap()
+			necm.AddCoin(&outpoint.OutPoint{Hash: *hash, Index: i}, NewCoin(txOut, 10, false), true)
+			if err := utxoTip.UpdateCoins(necm, hash); err != nil {
+				t.Error(err)
+				return
+			}
+			if i%50 == 49 {
+				utxoTip.Flush()
+			}
+		}
+	}()
+
+	// outputs are only added, so a lookup never sees fewer than the last one
+	seen := 0
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+		indexes := utxoTip.GetTxOutIndexes(hash)
+		if len(indexes) < seen {
+			t.Fatalf("saw %d outputs after %d", len(indexes), seen)
+		}
+		seen = len(indexes)
+	}
+	if seen != outputs {
+		t.Errorf("saw %d outputs, want %d", seen, outputs)
+	}
+}
+
+func TestCoinsLruCacheStats(t *testing.T) {
+	path, err := ioutil.TempDir("", "dbteststats")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	InitUtxoLruTip(&UtxoConfig{Do: &db.DBOption{
+		FilePath:  path,
+		CacheSize: 1 << 20,
+	}})
+
+	hash := util.HashFromString("000000002dd5588a74784eaa7ab0507a18ad16a236e7b1ce69f00d7ddfb5d0d9")
+	txOut := txout.NewTxOut(3, script.NewScriptRaw([]byte{opcodes.OP_11, opcodes.OP_EQUAL}))
+	stored := outpoint.OutPoint{Hash: *hash, Index: 0}
+	missing := outpoint.OutPoint{Hash: *hash, Index: 1}
+
+	necm := NewEmptyCoinsMap()
+	necm.AddCoin(&stored, NewCoin(txOut, 10, false), true)
+	if err := utxoTip.UpdateCoins(necm, hash); err != nil {
+		t.Fatal(err)
+	}
+	if dirty := utxoTip.GetStats().DirtyCoins; dirty != 1 {
+		t.Errorf("got %d dirty coins before the flush, want 1", dirty)
+	}
+	utxoTip.Flush()
+
+	// read through to the database, then from the cache
+	utxoTip.GetCoin(&stored)
+	utxoTip.GetCoin(&stored)
+	utxoTip.GetCoin(&missing)
+
+	stats := utxoTip.GetStats()
+	want := CacheStats{Hits: 1, Misses: 2, NotFound: 1, Coins: 1, Capacity: coinsCacheCapacity}
+	if stats != want {
+		t.Errorf("got stats %+v, want %+v", stats, want)
+	}
+}
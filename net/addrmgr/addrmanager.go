@@ -17,6 +17,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -58,6 +59,9 @@ type serializedKnownAddress struct {
 	TimeStamp   int64
 	LastAttempt int64
 	LastSuccess int64
+	Connects    int
+	Blocks      uint64
+	Misbehavior uint32
 	// no refcount or tried, that is available from context.
 }
 
@@ -159,6 +163,14 @@ const (
 
 	// serialisationVersion is the current version of the on-disk format.
 	serialisationVersion = 1
+
+	// maxQualityConnects is the number of successful connections above which
+	// an address is not preferred any further.
+	maxQualityConnects = 8
+
+	// maxQualityBlocks is the number of delivered blocks above which an
+	// address is not preferred any further.
+	maxQualityBlocks = 1000
 )
 
 // updateAddress is a helper function to either update an address already known
@@ -390,6 +402,9 @@ func (a *AddrManager) savePeers() {
 		ska.Attempts = v.attempts
 		ska.LastAttempt = v.lastattempt.Unix()
 		ska.LastSuccess = v.lastsuccess.Unix()
+		ska.Connects = v.connects
+		ska.Blocks = v.blocks
+		ska.Misbehavior = v.misbehavior
 		// Tried and refs are implicit in the rest of the structure
 		// and will be worked out from context on unserialisation.
 		sam.Addresses[i] = ska
@@ -487,6 +502,9 @@ func (a *AddrManager) deserializePeers(filePath string) error {
 		ka.attempts = v.Attempts
 		ka.lastattempt = time.Unix(v.LastAttempt, 0)
 		ka.lastsuccess = time.Unix(v.LastSuccess, 0)
+		ka.connects = v.Connects
+		ka.blocks = v.Blocks
+		ka.misbehavior = v.Misbehavior
 		a.addrIndex[NetAddressKey(ka.na)] = ka
 	}
 
@@ -882,6 +900,7 @@ func (a *AddrManager) Good(addr *wire.NetAddress) {
 	ka.lastsuccess = now
 	ka.lastattempt = now
 	ka.attempts = 0
+	ka.connects++
 
 	// move to tried set, optionally evicting other addresses if neeed.
 	if ka.tried {
@@ -953,6 +972,29 @@ func (a *AddrManager) Good(addr *wire.NetAddress) {
 	a.addrNew[newBucket][rmkey] = rmka
 }
 
+// BlockDelivered records that the peer at the given address sent us a block.
+// If the address is unknown to the address manager it will be ignored.
+func (a *AddrManager) BlockDelivered(addr *wire.NetAddress) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if ka := a.find(addr); ka != nil {
+		ka.blocks++
+	}
+}
+
+// Misbehaved records that the peer at the given address increased its ban
+// score by score.  If the address is unknown to the address manager it will
+// be ignored.
+func (a *AddrManager) Misbehaved(addr *wire.NetAddress, score uint32) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if ka := a.find(addr); ka != nil {
+		ka.misbehavior += score
+	}
+}
+
 // AddLocalAddress adds na to the list of known local addresses to advertise
 // with the given priority.
 func (a *AddrManager) AddLocalAddress(na *wire.NetAddress, priority AddressPriority) error {
@@ -978,6 +1020,33 @@ func (a *AddrManager) AddLocalAddress(na *wire.NetAddress, priority AddressPrior
 	return nil
 }
 
+// LocalAddress is a local address advertised to peers along with its score,
+// the priority of the method by which it was discovered.
+type LocalAddress struct {
+	NA    *wire.NetAddress
+	Score AddressPriority
+}
+
+// LocalAddresses returns the local addresses advertised to peers, ordered by
+// address.
+func (a *AddrManager) LocalAddresses() []LocalAddress {
+	a.lamtx.Lock()
+	defer a.lamtx.Unlock()
+
+	keys := make([]string, 0, len(a.localAddresses))
+	for key := range a.localAddresses {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	addrs := make([]LocalAddress, 0, len(keys))
+	for _, key := range keys {
+		la := a.localAddresses[key]
+		addrs = append(addrs, LocalAddress{NA: la.na, Score: la.score})
+	}
+	return addrs
+}
+
 // getReachabilityFrom returns the relative reachability of the provided local
 // address to the provided remote address.
 func getReachabilityFrom(localAddr, remoteAddr *wire.NetAddress) int {
@@ -7,7 +7,9 @@ package addrmgr_test
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"os"
 	"reflect"
 	"testing"
 	"time"
@@ -208,6 +210,32 @@ func TestAddLocalAddress(t *testing.T) {
 	}
 }
 
+func TestLocalAddresses(t *testing.T) {
+	amgr := addrmgr.New("testlocaladdresses", nil)
+	if addrs := amgr.LocalAddresses(); len(addrs) != 0 {
+		t.Fatalf("got %d local addresses, want none", len(addrs))
+	}
+
+	amgr.AddLocalAddress(&wire.NetAddress{IP: net.ParseIP("204.124.1.1"), Port: 8333}, addrmgr.InterfacePrio)
+	amgr.AddLocalAddress(&wire.NetAddress{IP: net.ParseIP("192.168.0.100")}, addrmgr.ManualPrio)
+	amgr.AddLocalAddress(&wire.NetAddress{IP: net.ParseIP("173.194.115.66"), Port: 8333}, addrmgr.ManualPrio)
+	amgr.AddLocalAddress(&wire.NetAddress{IP: net.ParseIP("204.124.1.1"), Port: 8333}, addrmgr.BoundPrio)
+
+	addrs := amgr.LocalAddresses()
+	if len(addrs) != 2 {
+		t.Fatalf("got %d local addresses, want 2", len(addrs))
+	}
+	if !addrs[0].NA.IP.Equal(net.ParseIP("173.194.115.66")) || addrs[0].Score != addrmgr.ManualPrio {
+		t.Errorf("got %v with score %d, want 173.194.115.66 with score %d",
+			addrs[0].NA.IP, addrs[0].Score, addrmgr.ManualPrio)
+	}
+	// a second discovery of an address raises its score
+	if !addrs[1].NA.IP.Equal(net.ParseIP("204.124.1.1")) || addrs[1].Score != addrmgr.BoundPrio+1 {
+		t.Errorf("got %v with score %d, want 204.124.1.1 with score %d",
+			addrs[1].NA.IP, addrs[1].Score, addrmgr.BoundPrio+1)
+	}
+}
+
 func TestAttempt(t *testing.T) {
 	n := addrmgr.New("testattempt", lookupFunc)
 
@@ -470,3 +498,34 @@ func TestNetAddressKey(t *testing.T) {
 	}
 
 }
+
+func TestPeerHistoryPersists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "testpeerhistory")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	n := addrmgr.New(dir, lookupFunc)
+	if err := n.AddAddressByIP(someIP + ":8333"); err != nil {
+		t.Fatalf("Adding address failed: %v", err)
+	}
+	addr := n.GetAddress().NetAddress()
+	n.Good(addr)
+	n.Good(addr)
+	n.BlockDelivered(addr)
+	n.Misbehaved(addr, 20)
+	addrmgr.TstSavePeers(n)
+
+	n = addrmgr.New(dir, lookupFunc)
+	addrmgr.TstLoadPeers(n)
+	ka := n.GetAddress()
+	if ka == nil {
+		t.Fatalf("the saved address was not loaded")
+	}
+	connects, blocks, misbehavior := addrmgr.TstKnownAddressHistory(ka)
+	if connects != 2 || blocks != 1 || misbehavior != 20 {
+		t.Errorf("history got %d connects, %d blocks, %d misbehavior, want 2, 1 and 20",
+			connects, blocks, misbehavior)
+	}
+}
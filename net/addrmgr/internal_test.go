@@ -23,3 +23,19 @@ func TstNewKnownAddress(na *wire.NetAddress, attempts int,
 	return &KnownAddress{na: na, attempts: attempts, lastattempt: lastattempt,
 		lastsuccess: lastsuccess, tried: tried, refs: refs}
 }
+
+func TstKnownAddressSetHistory(ka *KnownAddress, connects int, blocks uint64, misbehavior uint32) {
+	ka.connects, ka.blocks, ka.misbehavior = connects, blocks, misbehavior
+}
+
+func TstKnownAddressHistory(ka *KnownAddress) (connects int, blocks uint64, misbehavior uint32) {
+	return ka.connects, ka.blocks, ka.misbehavior
+}
+
+func TstSavePeers(a *AddrManager) {
+	a.savePeers()
+}
+
+func TstLoadPeers(a *AddrManager) {
+	a.loadPeers()
+}
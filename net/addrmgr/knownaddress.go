@@ -20,6 +20,12 @@ type KnownAddress struct {
 	lastsuccess time.Time
 	tried       bool
 	refs        int // reference count of new buckets
+
+	// The history of the address across restarts, which makes proven peers
+	// preferred when picking outbound connections.
+	connects    int    // successful connections and version exchanges
+	blocks      uint64 // blocks received from the peer
+	misbehavior uint32 // ban score the peer accumulated
 }
 
 // NetAddress returns the underlying wire.NetAddress associated with the
@@ -56,7 +62,30 @@ func (ka *KnownAddress) chance() float64 {
 		c /= 1.5
 	}
 
-	return c
+	return c * ka.quality()
+}
+
+// quality returns the factor the history of the address applies to its
+// selection probability.  Successful connections and delivered blocks raise
+// it up to a bound, so old peers cannot crowd out new ones, misbehavior
+// lowers it.
+func (ka *KnownAddress) quality() float64 {
+	q := 1.0
+
+	connects := ka.connects
+	if connects > maxQualityConnects {
+		connects = maxQualityConnects
+	}
+	q *= 1 + 0.25*float64(connects)
+
+	blocks := ka.blocks
+	if blocks > maxQualityBlocks {
+		blocks = maxQualityBlocks
+	}
+	q *= 1 + float64(blocks)/maxQualityBlocks
+
+	q /= 1 + float64(ka.misbehavior)/10
+	return q
 }
 
 // isBad returns true if the address in question has not been tried in the last
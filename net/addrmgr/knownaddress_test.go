@@ -112,3 +112,33 @@ func TestIsBad(t *testing.T) {
 		t.Errorf("test case 10: This should be a valid address.")
 	}
 }
+
+func TestChanceHistory(t *testing.T) {
+	now := time.Unix(time.Now().Unix(), 0)
+	newAddr := func(connects int, blocks uint64, misbehavior uint32) *addrmgr.KnownAddress {
+		ka := addrmgr.TstNewKnownAddress(&wire.NetAddress{Timestamp: now.Add(-35 * time.Second)},
+			0, time.Now().Add(-30*time.Minute), time.Now(), true, 0)
+		addrmgr.TstKnownAddressSetHistory(ka, connects, blocks, misbehavior)
+		return ka
+	}
+	var tests = []struct {
+		addr     *addrmgr.KnownAddress
+		expected float64
+	}{
+		{newAddr(0, 0, 0), 1.0},
+		{newAddr(4, 0, 0), 2.0},
+		// the preference is bounded
+		{newAddr(100, 5000, 0), 3.0 * 2.0},
+		{newAddr(0, 500, 0), 1.5},
+		{newAddr(0, 0, 10), 0.5},
+		{newAddr(8, 1000, 100), 3.0 * 2.0 / 11},
+	}
+
+	err := .0001
+	for i, test := range tests {
+		chance := addrmgr.TstKnownAddressChance(test.addr)
+		if math.Abs(test.expected-chance) >= err {
+			t.Errorf("case %d: got %f, expected %f", i, chance, test.expected)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+// Package banman keeps the list of banned IP subnets and saves it to the data
+// directory, so that the bans survive a restart.
+package banman
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/copernet/copernicus/log"
+)
+
+// serialisationVersion is the version of the ban list file format.
+const serialisationVersion = 1
+
+// ErrInvalidSubnet is returned when parsing a string which is neither an IP
+// address nor a subnet.
+var ErrInvalidSubnet = errors.New("invalid IP/Subnet")
+
+// BanReason tells why a subnet was banned.
+type BanReason int
+
+const (
+	BanReasonUnknown BanReason = iota
+	BanReasonNodeMisbehaving
+	BanReasonManuallyAdded
+)
+
+var banReasonStrings = map[BanReason]string{
+	BanReasonUnknown:         "unknown",
+	BanReasonNodeMisbehaving: "node misbehaving",
+	BanReasonManuallyAdded:   "manually added",
+}
+
+// String returns the BanReason in human-readable form.
+func (r BanReason) String() string {
+	if s, ok := banReasonStrings[r]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown BanReason (%d)", int(r))
+}
+
+// BanEntry is a banned subnet.
+type BanEntry struct {
+	Subnet     *net.IPNet
+	CreateTime time.Time
+	BanUntil   time.Time
+	Reason     BanReason
+}
+
+type serializedBanEntry struct {
+	Subnet     string
+	CreateTime int64
+	BanUntil   int64
+	Reason     BanReason
+}
+
+type serializedBanList struct {
+	Version int
+	Entries []*serializedBanEntry
+}
+
+// BanManager provides a concurrency safe list of banned subnets. Every change
+// of the list is saved to the ban list file.
+type BanManager struct {
+	mtx     sync.Mutex
+	banFile string
+	banned  map[string]*BanEntry
+}
+
+// New returns a ban manager holding the bans saved in dataDir.
+func New(dataDir string) *BanManager {
+	bm := &BanManager{
+		banFile: filepath.Join(dataDir, "banlist.dat"),
+		banned:  make(map[string]*BanEntry),
+	}
+	bm.load()
+	return bm
+}
+
+// ParseSubnet parses an IP address, banning the single address, or a subnet
+// in CIDR notation.
+func ParseSubnet(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, subnet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, ErrInvalidSubnet
+		}
+		return subnet, nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, ErrInvalidSubnet
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// Ban bans subnet until banUntil. The ban of a subnet banned already is only
+// extended.
+func (bm *BanManager) Ban(subnet *net.IPNet, banUntil time.Time, reason BanReason) {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	key := subnet.String()
+	if entry, ok := bm.banned[key]; ok && !entry.BanUntil.Before(banUntil) {
+		return
+	}
+	bm.banned[key] = &BanEntry{
+		Subnet:     subnet,
+		CreateTime: time.Now(),
+		BanUntil:   banUntil,
+		Reason:     reason,
+	}
+	bm.save()
+}
+
+// Unban lifts the ban of subnet, returning whether it was banned.
+func (bm *BanManager) Unban(subnet *net.IPNet) bool {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	key := subnet.String()
+	if _, ok := bm.banned[key]; !ok {
+		return false
+	}
+	delete(bm.banned, key)
+	bm.save()
+	return true
+}
+
+// IsBanned returns whether ip is in a banned subnet.
+func (bm *BanManager) IsBanned(ip net.IP) bool {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	now := time.Now()
+	for _, entry := range bm.banned {
+		if entry.Subnet.Contains(ip) && now.Before(entry.BanUntil) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSubnetBanned returns whether subnet itself is banned.
+func (bm *BanManager) IsSubnetBanned(subnet *net.IPNet) bool {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	entry, ok := bm.banned[subnet.String()]
+	return ok && time.Now().Before(entry.BanUntil)
+}
+
+// BanList returns the bans in force, sorted by subnet.
+func (bm *BanManager) BanList() []BanEntry {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	if bm.sweep() {
+		bm.save()
+	}
+	list := make([]BanEntry, 0, len(bm.banned))
+	for _, entry := range bm.banned {
+		list = append(list, *entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Subnet.String() < list[j].Subnet.String()
+	})
+	return list
+}
+
+// Clear lifts all the bans.
+func (bm *BanManager) Clear() {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	bm.banned = make(map[string]*BanEntry)
+	bm.save()
+}
+
+// sweep removes the expired bans, returning whether there were any. It must
+// be called with the lock held.
+func (bm *BanManager) sweep() bool {
+	now := time.Now()
+	swept := false
+	for key, entry := range bm.banned {
+		if !now.Before(entry.BanUntil) {
+			log.Debug("Removed expired ban of %s", key)
+			delete(bm.banned, key)
+			swept = true
+		}
+	}
+	return swept
+}
+
+// save writes the bans in force to the ban list file. It must be called with
+// the lock held.
+func (bm *BanManager) save() {
+	bm.sweep()
+
+	sbl := serializedBanList{
+		Version: serialisationVersion,
+		Entries: make([]*serializedBanEntry, 0, len(bm.banned)),
+	}
+	for key, entry := range bm.banned {
+		sbl.Entries = append(sbl.Entries, &serializedBanEntry{
+			Subnet:     key,
+			CreateTime: entry.CreateTime.Unix(),
+			BanUntil:   entry.BanUntil.Unix(),
+			Reason:     entry.Reason,
+		})
+	}
+
+	if err := writeBanList(bm.banFile, &sbl); err != nil {
+		log.Error("Failed to write file %s: %v", bm.banFile, err)
+	}
+}
+
+// writeBanList writes the ban list aside and renames it to path, so a crash
+// or a full disk never leaves a truncated ban list behind.
+func writeBanList(path string, sbl *serializedBanList) error {
+	tmpPath := path + ".new"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(file).Encode(sbl)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// load reads the bans from the ban list file. A malformed file is removed
+// and no bans are loaded.
+func (bm *BanManager) load() {
+	bm.mtx.Lock()
+	defer bm.mtx.Unlock()
+
+	err := bm.deserializeBanList()
+	if err != nil {
+		log.Error("Failed to parse file %s: %v", bm.banFile, err)
+		err = os.Remove(bm.banFile)
+		if err != nil {
+			log.Warn("Failed to remove corrupt ban list file %s: %v",
+				bm.banFile, err)
+		}
+		bm.banned = make(map[string]*BanEntry)
+		return
+	}
+	bm.sweep()
+	log.Info("Loaded %d banned subnets from file '%s'", len(bm.banned),
+		bm.banFile)
+}
+
+func (bm *BanManager) deserializeBanList() error {
+	r, err := os.Open(bm.banFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s error opening file: %v", bm.banFile, err)
+	}
+	defer r.Close()
+
+	var sbl serializedBanList
+	if err := json.NewDecoder(r).Decode(&sbl); err != nil {
+		return fmt.Errorf("error reading %s: %v", bm.banFile, err)
+	}
+	if sbl.Version != serialisationVersion {
+		return fmt.Errorf("unknown version %v in serialized ban list",
+			sbl.Version)
+	}
+
+	for _, v := range sbl.Entries {
+		subnet, err := ParseSubnet(v.Subnet)
+		if err != nil {
+			return fmt.Errorf("failed to deserialize subnet %s: %v",
+				v.Subnet, err)
+		}
+		bm.banned[subnet.String()] = &BanEntry{
+			Subnet:     subnet,
+			CreateTime: time.Unix(v.CreateTime, 0),
+			BanUntil:   time.Unix(v.BanUntil, 0),
+			Reason:     v.Reason,
+		}
+	}
+	return nil
+}
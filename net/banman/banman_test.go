@@ -0,0 +1,129 @@
+package banman
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseSubnet(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"192.168.0.6", "192.168.0.6/32"},
+		{"192.168.0.6/24", "192.168.0.0/24"},
+		{"::ffff:192.168.0.6", "192.168.0.6/32"},
+		{"2001:db8::1", "2001:db8::1/128"},
+		{"2001:db8::/32", "2001:db8::/32"},
+	}
+	for _, test := range tests {
+		subnet, err := ParseSubnet(test.in)
+		if err != nil {
+			t.Errorf("ParseSubnet(%q): unexpected error %v", test.in, err)
+			continue
+		}
+		if subnet.String() != test.want {
+			t.Errorf("ParseSubnet(%q) = %s, want %s", test.in, subnet, test.want)
+		}
+	}
+
+	for _, in := range []string{"", "192.168.0", "192.168.0.6/33", "host.example"} {
+		if _, err := ParseSubnet(in); err != ErrInvalidSubnet {
+			t.Errorf("ParseSubnet(%q): got error %v, want %v", in, err, ErrInvalidSubnet)
+		}
+	}
+}
+
+func mustParseSubnet(t *testing.T, s string) *net.IPNet {
+	subnet, err := ParseSubnet(s)
+	if err != nil {
+		t.Fatalf("ParseSubnet(%q): %v", s, err)
+	}
+	return subnet
+}
+
+func TestBanManager(t *testing.T) {
+	dir, err := ioutil.TempDir("", "banman")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bm := New(dir)
+	subnet := mustParseSubnet(t, "10.0.0.0/8")
+	bm.Ban(subnet, time.Now().Add(time.Hour), BanReasonManuallyAdded)
+	bm.Ban(mustParseSubnet(t, "192.168.0.6"), time.Now().Add(-time.Second),
+		BanReasonNodeMisbehaving)
+
+	if !bm.IsBanned(net.ParseIP("10.1.2.3")) {
+		t.Errorf("an address in a banned subnet should be banned")
+	}
+	if bm.IsBanned(net.ParseIP("192.168.0.6")) {
+		t.Errorf("an expired ban should not be in force")
+	}
+	if !bm.IsSubnetBanned(subnet) || bm.IsSubnetBanned(mustParseSubnet(t, "10.0.0.0/16")) {
+		t.Errorf("only the banned subnet itself should be reported banned")
+	}
+
+	// the bans are saved, without the expired ones
+	list := New(dir).BanList()
+	if len(list) != 1 || list[0].Subnet.String() != "10.0.0.0/8" ||
+		list[0].Reason != BanReasonManuallyAdded {
+		t.Fatalf("unexpected ban list after reloading: %v", list)
+	}
+
+	if bm.Unban(mustParseSubnet(t, "192.168.0.6")) {
+		t.Errorf("unbanning a subnet which is not banned should fail")
+	}
+	if !bm.Unban(subnet) || bm.IsBanned(net.ParseIP("10.1.2.3")) {
+		t.Errorf("the subnet should not be banned after unbanning it")
+	}
+
+	bm.Ban(subnet, time.Now().Add(time.Hour), BanReasonManuallyAdded)
+	bm.Clear()
+	if len(bm.BanList()) != 0 || len(New(dir).BanList()) != 0 {
+		t.Errorf("no subnet should be banned after clearing the bans")
+	}
+}
+
+func TestBanManagerCorruptFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "banman")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bm := New(dir)
+	if err := ioutil.WriteFile(bm.banFile, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if len(New(dir).BanList()) != 0 {
+		t.Errorf("no subnet should be banned after reading a corrupt file")
+	}
+	if _, err := os.Stat(bm.banFile); !os.IsNotExist(err) {
+		t.Errorf("the corrupt file should be removed")
+	}
+}
+
+func TestBanManagerFailedSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "banman")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bm := New(dir)
+	bm.Ban(mustParseSubnet(t, "10.0.0.0/8"), time.Now().Add(time.Hour), BanReasonManuallyAdded)
+
+	// a ban list which can't be written leaves the saved one intact
+	if err := os.Mkdir(bm.banFile+".new", 0700); err != nil {
+		t.Fatal(err)
+	}
+	bm.Ban(mustParseSubnet(t, "192.168.0.0/16"), time.Now().Add(time.Hour), BanReasonManuallyAdded)
+	if list := New(dir).BanList(); len(list) != 1 || list[0].Subnet.String() != "10.0.0.0/8" {
+		t.Errorf("unexpected ban list after a failed save: %v", list)
+	}
+}
@@ -58,7 +58,8 @@ const (
 // connection will be retried on disconnection.
 type ConnReq struct {
 	// The following variables must only be used atomically.
-	id uint64
+	id       uint64
+	canceled int32
 
 	Addr      net.Addr
 	Permanent bool
@@ -81,6 +82,17 @@ func (c *ConnReq) ID() uint64 {
 	return atomic.LoadUint64(&c.id)
 }
 
+// Cancel stops the connection manager from dialing or retrying the request.
+// An established connection is left open, the caller disconnects it.
+func (c *ConnReq) Cancel() {
+	atomic.StoreInt32(&c.canceled, 1)
+}
+
+// Canceled returns whether the request was canceled.
+func (c *ConnReq) Canceled() bool {
+	return atomic.LoadInt32(&c.canceled) != 0
+}
+
 // State is the connection state of the requested connection.
 func (c *ConnReq) State() ConnState {
 	c.stateMtx.RLock()
@@ -138,6 +150,11 @@ type Config struct {
 	// connection is disconnected.
 	OnDisconnection func(*ConnReq)
 
+	// OnConnectionFailed is a callback that is fired when an outbound
+	// connection could not be established, or was dropped on establishment
+	// as its request was canceled.
+	OnConnectionFailed func(*ConnReq)
+
 	// GetNewAddress is a way to get an address to make a network connection
 	// to.  If nil, no new connections will be made automatically.
 	GetNewAddress func() (net.Addr, error)
@@ -187,6 +204,10 @@ func (cm *ConnManager) handleFailedConn(c *ConnReq) {
 	if atomic.LoadInt32(&cm.stop) != 0 {
 		return
 	}
+	if c.Canceled() {
+		log.Debug("Not retrying canceled connection to %v", c)
+		return
+	}
 	if c.Permanent {
 		c.retryCount++
 		d := time.Duration(c.retryCount) * cm.cfg.RetryDuration
@@ -228,6 +249,15 @@ out:
 
 			case handleConnected:
 				connReq := msg.c
+				if connReq.Canceled() {
+					connReq.updateState(ConnDisconnected)
+					msg.conn.Close()
+					log.Debug("Dropped connection to canceled %v", connReq)
+					if cm.cfg.OnConnectionFailed != nil {
+						go cm.cfg.OnConnectionFailed(connReq)
+					}
+					continue
+				}
 				connReq.updateState(ConnEstablished)
 				connReq.conn = msg.conn
 				conns[connReq.id] = connReq
@@ -263,6 +293,9 @@ out:
 				connReq := msg.c
 				connReq.updateState(ConnFailed)
 				log.Debug("Failed to connect to %v: %v", connReq, msg.err)
+				if cm.cfg.OnConnectionFailed != nil {
+					go cm.cfg.OnConnectionFailed(connReq)
+				}
 				cm.handleFailedConn(connReq)
 			}
 
@@ -305,6 +338,9 @@ func (cm *ConnManager) Connect(ctx context.Context, c *ConnReq) {
 	if atomic.LoadInt32(&cm.stop) != 0 {
 		return
 	}
+	if c.Canceled() {
+		return
+	}
 	if atomic.LoadUint64(&c.id) == 0 {
 		atomic.StoreUint64(&c.id, atomic.AddUint64(&cm.connReqCount, 1))
 	}
@@ -299,6 +299,59 @@ func TestRetryPermanent(t *testing.T) {
 	cmgr.Stop()
 }
 
+// TestCancelPermanent tests that a canceled permanent connection request is
+// not retried once disconnected.
+func TestCancelPermanent(t *testing.T) {
+	connected := make(chan *ConnReq)
+	disconnected := make(chan *ConnReq)
+	cmgr, err := New(&Config{
+		RetryDuration:  time.Millisecond,
+		TargetOutbound: 1,
+		Dial:           mockDialer,
+		OnConnect: func(c *ConnReq, conn net.Conn) {
+			connected <- c
+		},
+		OnDisconnection: func(c *ConnReq) {
+			disconnected <- c
+		},
+	})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+
+	cr := &ConnReq{
+		Addr: &net.TCPAddr{
+			IP:   net.ParseIP("127.0.0.1"),
+			Port: 18555,
+		},
+		Permanent: true,
+	}
+	go cmgr.Connect(context.TODO(), cr)
+	cmgr.Start(context.TODO())
+	<-connected
+
+	cr.Cancel()
+	cmgr.Disconnect(cr.ID())
+	<-disconnected
+	select {
+	case <-connected:
+		t.Fatalf("cancel: %v - reconnected after the request was canceled", cr.Addr)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// dialing a canceled request is a no-op
+	cmgr.Connect(context.TODO(), cr)
+	select {
+	case <-connected:
+		t.Fatalf("cancel: %v - connected a canceled request", cr.Addr)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if got := cr.State(); got != ConnDisconnected {
+		t.Fatalf("cancel: %v - want state %v, got state %v", cr.Addr, ConnDisconnected, got)
+	}
+	cmgr.Stop()
+}
+
 // TestMaxRetryDuration tests the maximum retry duration.
 //
 // We have a timed dialer which initially returns err but after RetryDuration
@@ -422,6 +475,71 @@ func TestStopFailed(t *testing.T) {
 	cmgr.Wait()
 }
 
+// TestConnectionFailed ensures the failure callback is fired for the requests
+// which did not end up connected, and not for the established ones.
+func TestConnectionFailed(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 18555}
+	down := &ConnReq{Addr: addr}
+	canceled := &ConnReq{Addr: addr}
+	established := &ConnReq{Addr: addr}
+
+	failed := make(chan *ConnReq, 3)
+	connected := make(chan *ConnReq, 3)
+	cmgr, err := New(&Config{
+		Dial: func(ctx context.Context, a net.Addr) (net.Conn, error) {
+			switch ctx.Value(mockAddr{}) {
+			case down:
+				return nil, errors.New("network down")
+			case canceled:
+				// canceled while the connection was being established
+				canceled.Cancel()
+			}
+			return mockDialer(ctx, a)
+		},
+		OnConnect: func(c *ConnReq, conn net.Conn) {
+			connected <- c
+		},
+		OnConnectionFailed: func(c *ConnReq) {
+			failed <- c
+		},
+	})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	cmgr.Start(context.TODO())
+	defer func() {
+		cmgr.Stop()
+		cmgr.Wait()
+	}()
+
+	for _, c := range []*ConnReq{down, canceled, established} {
+		cmgr.Connect(context.WithValue(context.TODO(), mockAddr{}, c), c)
+	}
+	for _, want := range []*ConnReq{down, canceled} {
+		select {
+		case c := <-failed:
+			if c != want {
+				t.Errorf("failure reported for %v, expect %v", c, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("failure of %v not reported", want)
+		}
+	}
+	select {
+	case c := <-connected:
+		if c != established {
+			t.Errorf("connection reported for %v, expect %v", c, established)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("connection of %v not reported", established)
+	}
+	select {
+	case c := <-failed:
+		t.Errorf("unexpected failure reported for %v", c)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
 // mockListener implements the net.Listener interface and is used to test
 // code that deals with net.Listeners without having to actually make any real
 // connections.
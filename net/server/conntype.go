@@ -0,0 +1,26 @@
+package server
+
+// ConnectionType is the kind of a peer connection, which decides what is
+// exchanged over it.
+type ConnectionType string
+
+const (
+	// ConnInbound is a connection initiated by the peer.
+	ConnInbound ConnectionType = "inbound"
+
+	// ConnManual is a connection to a peer added with addnode or on the
+	// command line.
+	ConnManual ConnectionType = "manual"
+
+	// ConnOutboundFullRelay is an outbound connection relaying blocks,
+	// transactions and addresses.
+	ConnOutboundFullRelay ConnectionType = "outbound-full-relay"
+
+	// ConnBlockRelayOnly is an outbound connection relaying blocks only,
+	// neither transactions nor addresses.
+	ConnBlockRelayOnly ConnectionType = "block-relay-only"
+
+	// ConnFeeler is a short lived outbound connection checking the peer is
+	// reachable, it is closed as soon as the handshake completes.
+	ConnFeeler ConnectionType = "feeler"
+)
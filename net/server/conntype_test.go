@@ -0,0 +1,51 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/net/connmgr"
+)
+
+func TestTakeConnType(t *testing.T) {
+	s := &Server{}
+	tests := []struct {
+		name      string
+		permanent bool
+		requested ConnectionType
+		connType  ConnectionType
+	}{
+		{"automatic", false, "", ConnOutboundFullRelay},
+		{"addnode", true, "", ConnManual},
+		{"addconnection full relay", false, ConnOutboundFullRelay, ConnOutboundFullRelay},
+		{"addconnection block relay", false, ConnBlockRelayOnly, ConnBlockRelayOnly},
+		{"addconnection feeler", false, ConnFeeler, ConnFeeler},
+	}
+	for _, test := range tests {
+		req := &connmgr.ConnReq{Permanent: test.permanent}
+		if test.requested != "" {
+			s.connTypes.Store(req, test.requested)
+		}
+		if connType := s.takeConnType(req); connType != test.connType {
+			t.Errorf("%s: connection type %s, expect %s", test.name, connType, test.connType)
+		}
+		if _, ok := s.connTypes.Load(req); ok {
+			t.Errorf("%s: the requested type is kept after the connection", test.name)
+		}
+	}
+}
+
+func TestOutboundPeerFailed(t *testing.T) {
+	s := &Server{}
+	failed := &connmgr.ConnReq{}
+	pending := &connmgr.ConnReq{}
+	s.connTypes.Store(failed, ConnBlockRelayOnly)
+	s.connTypes.Store(pending, ConnFeeler)
+
+	s.outboundPeerFailed(failed)
+	if _, ok := s.connTypes.Load(failed); ok {
+		t.Errorf("the type of a failed connection request is kept")
+	}
+	if connType := s.takeConnType(pending); connType != ConnFeeler {
+		t.Errorf("the type of a pending request was lost, got %s", connType)
+	}
+}
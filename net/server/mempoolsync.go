@@ -0,0 +1,94 @@
+package server
+
+import (
+	"sort"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/net/wire"
+	"github.com/copernet/copernicus/peer"
+	"github.com/copernet/copernicus/util"
+)
+
+// mempoolSyncAllowed returns whether the mempool may be reconciled with the
+// peer: the operator must have enabled it and the peer must be whitelisted.
+func (sp *serverPeer) mempoolSyncAllowed() bool {
+	return conf.Cfg.P2PNet.MempoolSync && sp.isWhitelisted
+}
+
+// pushMempoolSync sends the short ids of the transactions in the mempool to
+// the peer, under fresh random keys.
+func (sp *serverPeer) pushMempoolSync(reply bool) {
+	k0, err := util.RandomUint64()
+	if err != nil {
+		log.Error("Unable to generate the mempool sync keys: %v", err)
+		return
+	}
+	k1, err := util.RandomUint64()
+	if err != nil {
+		log.Error("Unable to generate the mempool sync keys: %v", err)
+		return
+	}
+
+	msg := wire.NewMsgMempoolSync(k0, k1, reply)
+	for hash := range mempool.GetInstance().GetAllTxEntry() {
+		if err := msg.AddShortID(&hash); err != nil {
+			log.Warn("Mempool sync with peer %v is partial: %v", sp, err)
+			break
+		}
+	}
+	log.Debug("Sending the short ids of %d mempool transactions to peer %v",
+		len(msg.ShortIDs), sp)
+	sp.QueueMessage(msg, nil)
+}
+
+// OnMempoolSync is invoked when a peer receives a mempoolsync message.  It
+// announces the transactions of the mempool whose short ids the peer did not
+// send, parents first, so that the peer fetches them.  Unless the message is
+// a reply, the short ids of the mempool are sent back so that the peer
+// announces the transactions missing here in turn.
+//
+// A transaction whose short id collides with one of the peer is not
+// announced, which only delays it until it is relayed again.
+func (sp *serverPeer) OnMempoolSync(_ *peer.Peer, msg *wire.MsgMempoolSync) {
+	if !sp.mempoolSyncAllowed() {
+		log.Debug("Ignoring mempoolsync from peer %v: mempool sync is "+
+			"not enabled for it", sp)
+		return
+	}
+
+	known := make(map[uint64]struct{}, len(msg.ShortIDs))
+	for _, id := range msg.ShortIDs {
+		known[id] = struct{}{}
+	}
+	var missing []*mempool.TxEntry
+	for hash, entry := range mempool.GetInstance().GetAllTxEntry() {
+		if _, ok := known[msg.ShortID(&hash)]; !ok {
+			missing = append(missing, entry)
+		}
+	}
+	// a transaction has more ancestors in the mempool than any of its
+	// parents
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].SumTxCountWithAncestors < missing[j].SumTxCountWithAncestors
+	})
+
+	log.Debug("Peer %v misses %d of the mempool transactions", sp, len(missing))
+	invMsg := wire.NewMsgInvSizeHint(uint(len(missing)))
+	for _, entry := range missing {
+		hash := entry.Tx.GetHash()
+		invMsg.AddInvVect(wire.NewInvVect(wire.InvTypeTx, &hash))
+		if len(invMsg.InvList) == wire.MaxInvPerMsg {
+			sp.QueueMessage(invMsg, nil)
+			invMsg = wire.NewMsgInvSizeHint(uint(len(missing)))
+		}
+	}
+	if len(invMsg.InvList) > 0 {
+		sp.QueueMessage(invMsg, nil)
+	}
+
+	if !msg.Reply {
+		sp.pushMempoolSync(true)
+	}
+}
@@ -7,12 +7,18 @@ package server
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/net/banman"
 	"github.com/copernet/copernicus/net/wire"
 	"github.com/copernet/copernicus/peer"
 	"github.com/copernet/copernicus/rpc/btcjson"
 	"github.com/copernet/copernicus/service"
+	"github.com/copernet/copernicus/util"
 )
 
 type MsgHandle struct {
@@ -170,6 +176,11 @@ out:
 					peerFrom.Cfg.Listeners.OnSendHeaders(peerFrom, data)
 				}
 				msg.Done <- struct{}{}
+			case *wire.MsgMempoolSync:
+				if peerFrom.Cfg.Listeners.OnMempoolSync != nil {
+					peerFrom.Cfg.Listeners.OnMempoolSync(peerFrom, data)
+				}
+				msg.Done <- struct{}{}
 			default:
 				log.Debug("Received unhandled message of type %v "+
 					"from %v", data, data.Command())
@@ -196,6 +207,17 @@ func ProcessForRPC(message interface{}) (rsp interface{}, err error) {
 		msgHandle.BroadcastMessage(m)
 		return nil, nil
 
+	case *wire.InvVect:
+		if m.Type != wire.InvTypeTx {
+			break
+		}
+		entry := mempool.GetInstance().FindTx(m.Hash)
+		if entry == nil {
+			return nil, errors.New("transaction not found in mempool")
+		}
+		msgHandle.RelayInventory(m, entry)
+		return nil, nil
+
 	case *service.GetPeersInfoRequest:
 		return NewRPCConnManager(msgHandle.Server).ConnectedPeers(), nil
 
@@ -216,34 +238,45 @@ func ProcessForRPC(message interface{}) (rsp interface{}, err error) {
 			}
 		}
 
+		return nil, err
+
+	case *btcjson.AddConnectionCmd:
+		err = NewRPCConnManager(msgHandle.Server).AddConnection(m.Address, ConnectionType(m.ConnectionType))
 		if err != nil {
-			return nil, &btcjson.RPCError{
-				Code:    btcjson.ErrRPCInvalidParameter,
-				Message: err.Error(),
-			}
+			return nil, err
 		}
-		return nil, nil
+		return &btcjson.AddConnectionResult{
+			Address:        m.Address,
+			ConnectionType: m.ConnectionType,
+		}, nil
 
 	case *btcjson.DisconnectNodeCmd:
-		return
+		if m.ID != nil {
+			return nil, NewRPCConnManager(msgHandle.Server).DisconnectByID(int32(*m.ID))
+		}
+		return nil, NewRPCConnManager(msgHandle.Server).DisconnectByAddr(*m.Address)
+
+	case *btcjson.SyncMempoolCmd:
+		return nil, NewRPCConnManager(msgHandle.Server).SyncMempool(m.ID)
 
-		//case *btcjson.GetAddedNodeInfoCmd:
-		//	return msgHandle.connManager.PersistentPeers(), nil
+	case *btcjson.GetAddedNodeInfoCmd:
+		return addedNodeInfo(NewRPCConnManager(msgHandle.Server).AddedNodes(), m.Node)
 
 	case *service.GetNetTotalsRequest:
-		return
+		return msgHandle.netTotals(), nil
 
 	case *btcjson.GetNetworkInfoCmd:
-		return
+		return msgHandle.networkInfo(), nil
 
 	case *btcjson.SetBanCmd:
-		return
+		return nil, setBan(NewRPCConnManager(msgHandle.Server), m)
 
 	case *service.ListBannedRequest:
-		return
+		return bannedInfo(NewRPCConnManager(msgHandle.Server).BanList()), nil
 
 	case *service.ClearBannedRequest:
-		return
+		NewRPCConnManager(msgHandle.Server).ClearBanned()
+		return nil, nil
 
 		//case *tx.Tx:
 		//	msgHandle.recvChannel <- m
@@ -269,3 +302,120 @@ func ProcessForRPC(message interface{}) (rsp interface{}, err error) {
 
 	return nil, errors.New("unknown rpc request")
 }
+
+// addedNodeInfo describes the added nodes for getaddednodeinfo, or only node if
+// it is not nil.
+func addedNodeInfo(nodes []AddedNodeInfo, node *string) ([]btcjson.GetAddedNodeInfoResult, error) {
+	results := make([]btcjson.GetAddedNodeInfoResult, 0, len(nodes))
+	for _, info := range nodes {
+		if node != nil && *node != info.Addr {
+			continue
+		}
+		connected := info.Peer != nil
+		result := btcjson.GetAddedNodeInfoResult{
+			AddedNode: info.Addr,
+			Connected: &connected,
+		}
+		addrs := make([]btcjson.GetAddedNodeInfoResultAddr, 0, 1)
+		if connected {
+			addrs = append(addrs, btcjson.GetAddedNodeInfoResultAddr{
+				Address:   info.Peer.ToPeer().Addr(),
+				Connected: "outbound",
+			})
+		}
+		result.Addresses = &addrs
+		results = append(results, result)
+	}
+	if node != nil && len(results) == 0 {
+		return nil, ErrNodeNotAdded
+	}
+	return results, nil
+}
+
+// setBan adds or removes the ban of a subnet for setban. A ban time of zero
+// bans for the configured ban duration.
+func setBan(cm *RPCConnManager, c *btcjson.SetBanCmd) error {
+	subnet, err := banman.ParseSubnet(c.SubNet)
+	if err != nil {
+		return err
+	}
+	if c.Command == "remove" {
+		return cm.Unban(subnet)
+	}
+
+	banUntil := time.Now().Add(conf.Cfg.P2PNet.BanDuration)
+	if c.BanTime != nil && *c.BanTime > 0 {
+		if c.Absolute != nil && *c.Absolute {
+			banUntil = time.Unix(int64(*c.BanTime), 0)
+		} else {
+			banUntil = time.Now().Add(time.Duration(*c.BanTime) * time.Second)
+		}
+	}
+	return cm.Ban(subnet, banUntil)
+}
+
+// bannedInfo describes the bans for listbanned.
+func bannedInfo(bans []banman.BanEntry) []btcjson.BannedINfo {
+	results := make([]btcjson.BannedINfo, 0, len(bans))
+	for _, ban := range bans {
+		results = append(results, btcjson.BannedINfo{
+			Address:     ban.Subnet.String(),
+			BannedUntil: ban.BanUntil.Unix(),
+			BanCreated:  ban.CreateTime.Unix(),
+			BanReason:   ban.Reason.String(),
+		})
+	}
+	return results
+}
+
+// netTotals reports the traffic and the upload target for getnettotals.
+func (mh *MsgHandle) netTotals() *btcjson.GetNetTotalsResult {
+	recv, sent := mh.NetTotals()
+	recvPerMsg, sentPerMsg := mh.NetTotalsPerMsg()
+	ut := mh.UploadTarget()
+	return &btcjson.GetNetTotalsResult{
+		TotalBytesRecv: recv,
+		TotalBytesSent: sent,
+		TimeMillis:     time.Now().UnixNano() / int64(time.Millisecond),
+		Uploadtarget: btcjson.Uploadtarget{
+			TimeFrame:             uint64(ut.Timeframe / time.Second),
+			Target:                ut.Target,
+			TargetReached:         ut.TargetReached,
+			ServeHistoricalBlocks: ut.ServeHistoricalBlocks,
+			BytesLeftInCycle:      ut.BytesLeftInCycle,
+			TimeLeftInCycle:       uint64(ut.TimeLeftInCycle / time.Second),
+		},
+		BytesSentPerMsg: sentPerMsg,
+		BytesRecvPerMsg: recvPerMsg,
+	}
+}
+
+// networkInfo reports the p2p part of getnetworkinfo. The version, fees and
+// warnings are left to the caller.
+func (mh *MsgHandle) networkInfo() *btcjson.GetNetworkInfoResult {
+	proxy := conf.Cfg.P2PNet.Proxy
+	onionReachable := !conf.Cfg.P2PNet.NoOnion && proxy != ""
+	info := &btcjson.GetNetworkInfoResult{
+		SubVersion:      UserAgent(),
+		ProtocolVersion: int32(wire.ProtocolVersion),
+		LocalServices:   fmt.Sprintf("%016x", uint64(mh.services)),
+		LocalRelay:      !conf.Cfg.P2PNet.BlocksOnly,
+		TimeOffset:      util.GetTimeOffset(),
+		NetworkActive:   true,
+		Connections:     mh.ConnectedCount(),
+		Networks: []btcjson.NetworksResult{
+			{Name: "ipv4", Reachable: true, Proxy: proxy},
+			{Name: "ipv6", Reachable: true, Proxy: proxy},
+			{Name: "onion", Limited: !onionReachable, Reachable: onionReachable, Proxy: proxy},
+		},
+		LocalAddresses: make([]btcjson.LocalAddressesResult, 0),
+	}
+	for _, la := range mh.addrManager.LocalAddresses() {
+		info.LocalAddresses = append(info.LocalAddresses, btcjson.LocalAddressesResult{
+			Address: la.NA.IP.String(),
+			Port:    la.NA.Port,
+			Score:   int32(la.Score),
+		})
+	}
+	return info
+}
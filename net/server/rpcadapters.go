@@ -1,12 +1,57 @@
 package server
 
 import (
+	"errors"
+	"net"
 	"sync/atomic"
+	"time"
 
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/net/banman"
+	"github.com/copernet/copernicus/net/syncmanager"
 	"github.com/copernet/copernicus/net/wire"
 	"github.com/copernet/copernicus/peer"
 )
 
+var (
+	// ErrNodeAlreadyAdded is returned when adding a node which is in the
+	// list of added nodes already.
+	ErrNodeAlreadyAdded = errors.New("node already added")
+
+	// ErrNodeNotAdded is returned when removing a node which is not in the
+	// list of added nodes.
+	ErrNodeNotAdded = errors.New("node has not been added")
+
+	// ErrNodeNotConnected is returned when disconnecting a peer which is
+	// not connected.
+	ErrNodeNotConnected = errors.New("node not found in connected nodes")
+
+	// ErrSubnetAlreadyBanned is returned when banning a subnet which is
+	// banned already.
+	ErrSubnetAlreadyBanned = errors.New("subnet already banned")
+
+	// ErrSubnetNotBanned is returned when unbanning a subnet which is not
+	// banned.
+	ErrSubnetNotBanned = errors.New("subnet was not previously banned")
+
+	// ErrMempoolSyncDisabled is returned when reconciling the mempool with
+	// a peer while mempool sync is not enabled.
+	ErrMempoolSyncDisabled = errors.New("mempool sync is not enabled")
+
+	// ErrPeerNotWhitelisted is returned when reconciling the mempool with
+	// a peer which is not whitelisted.
+	ErrPeerNotWhitelisted = errors.New("peer is not whitelisted")
+)
+
+// AddedNodeInfo describes a node added with addnode and its connection.
+type AddedNodeInfo struct {
+	// Addr is the address of the node as it was added.
+	Addr string
+
+	// Peer is the connected peer, or nil if the node is not connected.
+	Peer RPCServerPeer
+}
+
 // RPCServerPeer represents a peer for use with the RPC server.
 //
 // The interface contract requires that all of these methods are safe for
@@ -26,6 +71,16 @@ type RPCServerPeer interface {
 	// FeeFilter returns the requested current minimum fee rate for which
 	// transactions should be announced.
 	FeeFilter() int64
+
+	// ConnectionType returns the type of the connection to the peer.
+	ConnectionType() ConnectionType
+
+	// IsWhitelisted returns whether the peer is exempt from banning and
+	// relay restrictions.
+	IsWhitelisted() bool
+
+	// SyncStats returns how far the peer is in sync with us.
+	SyncStats() *syncmanager.PeerSyncStats
 }
 
 // rpcPeer provides a peer for use with the RPC server and implements the
@@ -72,6 +127,32 @@ func (p *rpcPeer) FeeFilter() int64 {
 	return atomic.LoadInt64(&(*serverPeer)(p).feeFilter)
 }
 
+// ConnectionType returns the type of the connection to the peer.
+//
+// This function is safe for concurrent access and is part of the rpcserverPeer
+// interface implementation.
+func (p *rpcPeer) ConnectionType() ConnectionType {
+	return (*serverPeer)(p).connType
+}
+
+// IsWhitelisted returns whether the peer is exempt from banning and relay
+// restrictions.
+//
+// This function is safe for concurrent access and is part of the rpcserverPeer
+// interface implementation.
+func (p *rpcPeer) IsWhitelisted() bool {
+	return (*serverPeer)(p).isWhitelisted
+}
+
+// SyncStats returns how far the peer is in sync with us.
+//
+// This function is safe for concurrent access and is part of the rpcserverPeer
+// interface implementation.
+func (p *rpcPeer) SyncStats() *syncmanager.PeerSyncStats {
+	sp := (*serverPeer)(p)
+	return sp.server.syncManager.PeerSyncStats(sp.Peer)
+}
+
 // RPCConnManager provides a connection manager for use with the RPC server and
 // implements the rpcserverConnManager interface.
 type RPCConnManager struct {
@@ -94,11 +175,27 @@ func (cm *RPCConnManager) Connect(addr string, permanent bool) error {
 	cm.server.query <- connectNodeMsg{
 		addr:      addr,
 		permanent: permanent,
+		connType:  ConnManual,
 		reply:     replyChan,
 	}
 	return <-replyChan
 }
 
+// AddConnection opens a non-persistent outbound connection of the given type
+// to the peer at addr.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverConnManager interface implementation.
+func (cm *RPCConnManager) AddConnection(addr string, connType ConnectionType) error {
+	replyChan := make(chan error)
+	cm.server.query <- connectNodeMsg{
+		addr:     addr,
+		connType: connType,
+		reply:    replyChan,
+	}
+	return <-replyChan
+}
+
 // RemoveByID removes the peer associated with the provided id from the list of
 // persistent peers.  Attempting to remove an id that does not exist will return
 // an error.
@@ -114,16 +211,16 @@ func (cm *RPCConnManager) RemoveByID(id int32) error {
 	return <-replyChan
 }
 
-// RemoveByAddr removes the peer associated with the provided address from the
-// list of persistent peers.  Attempting to remove an address that does not
-// exist will return an error.
+// RemoveByAddr removes the node added with the provided address from the list
+// of added nodes and disconnects it.  Attempting to remove an address that was
+// not added will return ErrNodeNotAdded.
 //
 // This function is safe for concurrent access and is part of the
 // rpcserverConnManager interface implementation.
 func (cm *RPCConnManager) RemoveByAddr(addr string) error {
 	replyChan := make(chan error)
-	cm.server.query <- removeNodeMsg{
-		cmp:   func(sp *serverPeer) bool { return sp.Addr() == addr },
+	cm.server.query <- removeAddedNodeMsg{
+		addr:  addr,
 		reply: replyChan,
 	}
 	return <-replyChan
@@ -159,6 +256,76 @@ func (cm *RPCConnManager) DisconnectByAddr(addr string) error {
 	return <-replyChan
 }
 
+// SyncMempool starts reconciling the mempool with the peer associated with
+// the provided id, by sending it the short ids of the mempool transactions.
+// Mempool sync must be enabled and the peer whitelisted.
+//
+// This function is safe for concurrent access.
+func (cm *RPCConnManager) SyncMempool(id int32) error {
+	if !conf.Cfg.P2PNet.MempoolSync {
+		return ErrMempoolSyncDisabled
+	}
+	replyChan := make(chan []*serverPeer)
+	cm.server.query <- getPeersMsg{reply: replyChan}
+	for _, sp := range <-replyChan {
+		if sp.ID() != id {
+			continue
+		}
+		if !sp.mempoolSyncAllowed() {
+			return ErrPeerNotWhitelisted
+		}
+		sp.pushMempoolSync(false)
+		return nil
+	}
+	return ErrNodeNotConnected
+}
+
+// Ban bans subnet until banUntil and disconnects the peers in it.
+//
+// This function is safe for concurrent access.
+func (cm *RPCConnManager) Ban(subnet *net.IPNet, banUntil time.Time) error {
+	if cm.server.banManager.IsSubnetBanned(subnet) {
+		return ErrSubnetAlreadyBanned
+	}
+	cm.server.banManager.Ban(subnet, banUntil, banman.BanReasonManuallyAdded)
+
+	inSubnet := func(sp *serverPeer) bool {
+		ip := addrIP(sp.Addr())
+		return ip != nil && subnet.Contains(ip)
+	}
+	for {
+		replyChan := make(chan error)
+		cm.server.query <- disconnectNodeMsg{cmp: inSubnet, reply: replyChan}
+		if <-replyChan != nil {
+			return nil
+		}
+	}
+}
+
+// Unban lifts the ban of subnet.
+//
+// This function is safe for concurrent access.
+func (cm *RPCConnManager) Unban(subnet *net.IPNet) error {
+	if !cm.server.banManager.Unban(subnet) {
+		return ErrSubnetNotBanned
+	}
+	return nil
+}
+
+// BanList returns the bans in force.
+//
+// This function is safe for concurrent access.
+func (cm *RPCConnManager) BanList() []banman.BanEntry {
+	return cm.server.banManager.BanList()
+}
+
+// ClearBanned lifts all the bans.
+//
+// This function is safe for concurrent access.
+func (cm *RPCConnManager) ClearBanned() {
+	cm.server.banManager.Clear()
+}
+
 // ConnectedCount returns the number of currently connected peers.
 //
 // This function is safe for concurrent access and is part of the
@@ -211,6 +378,17 @@ func (cm *RPCConnManager) PersistentPeers() []RPCServerPeer {
 	return peers
 }
 
+// AddedNodes returns the nodes added with addnode or in the configuration,
+// ordered by address, along with their connected peers.
+//
+// This function is safe for concurrent access and is part of the
+// rpcserverConnManager interface implementation.
+func (cm *RPCConnManager) AddedNodes() []AddedNodeInfo {
+	replyChan := make(chan []AddedNodeInfo)
+	cm.server.query <- getAddedNodeInfoMsg{reply: replyChan}
+	return <-replyChan
+}
+
 // BroadcastMessage sends the provided message to all currently connected peers.
 //
 // This function is safe for concurrent access and is part of the
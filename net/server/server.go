@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"math"
 	"net"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strconv"
@@ -25,6 +26,7 @@ import (
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/logic/lblock"
 	"github.com/copernet/copernicus/logic/lchain"
+	"github.com/copernet/copernicus/logic/lmempool"
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/bitcointime"
 	"github.com/copernet/copernicus/model/block"
@@ -33,6 +35,7 @@ import (
 	"github.com/copernet/copernicus/model/mempool"
 	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/net/addrmgr"
+	"github.com/copernet/copernicus/net/banman"
 	"github.com/copernet/copernicus/net/connmgr"
 	"github.com/copernet/copernicus/net/syncmanager"
 	"github.com/copernet/copernicus/net/upnp"
@@ -63,6 +66,14 @@ const (
 
 	// max blocks to announce during inventory relay
 	maxBlocksToAnnounce = 8
+
+	// uploadTargetTimeframe is the length of the cycles of the upload
+	// target.
+	uploadTargetTimeframe = 24 * time.Hour
+
+	// historicalBlockAge is the age from which blocks are no longer served
+	// to non-whitelisted peers past the upload target.
+	historicalBlockAge = 7 * 24 * time.Hour
 )
 
 var (
@@ -76,6 +87,26 @@ var (
 	userAgentVersion = fmt.Sprintf("%d.%d.%d", conf.AppMajor, conf.AppMinor, conf.AppPatch)
 )
 
+// userAgentComments returns the configured user agent comments followed by
+// the commit the binary was built from, if known.
+func userAgentComments() []string {
+	comments := make([]string, 0, len(conf.Cfg.P2PNet.UserAgentComments)+1)
+	comments = append(comments, conf.Cfg.P2PNet.UserAgentComments...)
+	if comment := conf.BuildComment(); comment != "" {
+		comments = append(comments, comment)
+	}
+	return comments
+}
+
+// UserAgent returns the user agent advertised to peers in version messages.
+func UserAgent() string {
+	msg := wire.MsgVersion{UserAgent: wire.DefaultUserAgent}
+	if err := msg.AddUserAgent(userAgentName, userAgentVersion, userAgentComments()...); err != nil {
+		return wire.DefaultUserAgent
+	}
+	return msg.UserAgent
+}
+
 // zeroHash is the zero value hash (all zeros).  It is defined as a convenience.
 var zeroHash util.Hash
 
@@ -158,12 +189,11 @@ type updatePeerHeightsMsg struct {
 }
 
 // peerState maintains state of inbound, persistent, outbound peers as well
-// as banned peers and outbound groups.
+// as outbound groups.
 type peerState struct {
 	inboundPeers    map[int32]*serverPeer
 	outboundPeers   map[int32]*serverPeer
 	persistentPeers map[int32]*serverPeer
-	banned          map[string]time.Time
 	outboundGroups  map[string]int
 }
 
@@ -206,6 +236,7 @@ type Server struct {
 	startupTime          int64
 	chainParams          *model.BitcoinParams
 	addrManager          *addrmgr.AddrManager
+	banManager           *banman.BanManager
 	connManager          *connmgr.ConnManager
 	syncManager          *syncmanager.SyncManager
 	modifyRebroadcastInv chan interface{}
@@ -222,6 +253,28 @@ type Server struct {
 	timeSource           *bitcointime.MedianTime
 	services             wire.ServiceFlag
 
+	// feeEstimator learns how long the transactions announced stay in the
+	// mempool before they are mined.
+	feeEstimator *mempool.FeeEstimator
+
+	// connTypes holds the connection type of the pending connection
+	// requests made with addconnection, keyed by *connmgr.ConnReq, until
+	// they connect or fail.
+	connTypes sync.Map
+
+	// trafficMtx protects the traffic per message command and the upload
+	// target cycle.
+	trafficMtx       sync.Mutex
+	bytesSentPerMsg  map[string]uint64
+	bytesRecvPerMsg  map[string]uint64
+	uploadCycleStart time.Time
+	uploadCycleBytes uint64
+
+	// addedNodes holds the permanent connection requests of the nodes
+	// added with addnode or P2PNet.ConnectPeersOnStart, keyed by the address
+	// as given. It is only accessed by the peer handler once started.
+	addedNodes map[string]*connmgr.ConnReq
+
 	// The following fields are used for optional indexes.  They will be nil
 	// if the associated index is not enabled.  These fields are set during
 	// initial creation of the server and never changed afterwards, so they
@@ -241,6 +294,7 @@ type serverPeer struct {
 	connReq        *connmgr.ConnReq
 	server         *Server
 	persistent     bool
+	connType       ConnectionType
 	continueHash   *util.Hash
 	relayMtx       sync.Mutex
 	disableRelayTx bool
@@ -257,10 +311,11 @@ type serverPeer struct {
 
 // newServerPeer returns a new serverPeer instance. The peer needs to be set by
 // the caller.
-func newServerPeer(s *Server, isPersistent bool) *serverPeer {
+func newServerPeer(s *Server, isPersistent bool, connType ConnectionType) *serverPeer {
 	return &serverPeer{
 		server:         s,
 		persistent:     isPersistent,
+		connType:       connType,
 		filter:         bloom.LoadFilter(nil),
 		knownAddresses: make(map[string]struct{}),
 		quit:           make(chan struct{}),
@@ -330,6 +385,21 @@ func (sp *serverPeer) pushAddrMsg(addresses []*wire.NetAddress) {
 	sp.addKnownAddresses(known)
 }
 
+// blocksOnly returns whether we asked the peer not to relay transactions to
+// us, either because the whole node runs in blocks only mode or because this
+// is a block-relay-only connection.
+func (sp *serverPeer) blocksOnly() bool {
+	return conf.Cfg.P2PNet.BlocksOnly || sp.connType == ConnBlockRelayOnly
+}
+
+// relaysLargeDataCarrier returns whether transactions with data carriers
+// larger than standard may be exchanged with the peer, which needs both
+// sides to advertise SFNodeLargeDataCarrier.
+func (sp *serverPeer) relaysLargeDataCarrier() bool {
+	return sp.server.services&wire.SFNodeLargeDataCarrier != 0 &&
+		sp.Services()&wire.SFNodeLargeDataCarrier != 0
+}
+
 // addBanScore increases the persistent and decaying ban score fields by the
 // values passed as parameters. If the resulting score exceeds half of the ban
 // threshold, a warning is logged including the reason provided. Further, if
@@ -357,6 +427,7 @@ func (sp *serverPeer) addBanScore(persistent, transient uint32, reason string) {
 		return
 	}
 	score := sp.banScore.Increase(persistent, transient)
+	sp.server.addrManager.Misbehaved(sp.NA(), persistent+transient)
 	if score > warnThreshold {
 		log.Warn("Misbehaving peer %s: %s -- ban score increased to %d",
 			sp, reason, score)
@@ -381,8 +452,8 @@ func (sp *serverPeer) OnVersion(_ *peer.Peer, msg *wire.MsgVersion) {
 	sp.server.syncManager.NewPeer(sp.Peer)
 
 	// Choose whether or not to relay transactions before a filter command
-	// is received.
-	sp.setDisableRelayTx(msg.DisableRelayTx)
+	// is received.  Block-relay-only connections never relay them.
+	sp.setDisableRelayTx(msg.DisableRelayTx || sp.connType == ConnBlockRelayOnly)
 
 	// Update the address manager and request known addresses from the
 	// remote peer for outbound connections.  This is skipped when running
@@ -392,11 +463,12 @@ func (sp *serverPeer) OnVersion(_ *peer.Peer, msg *wire.MsgVersion) {
 	if !conf.Cfg.P2PNet.SimNet {
 		addrManager := sp.server.addrManager
 
-		// Outbound connections.
-		if !sp.Inbound() {
+		// Outbound connections.  Addresses are not exchanged over
+		// block-relay-only connections, which keeps them hard to detect.
+		if !sp.Inbound() && sp.connType != ConnBlockRelayOnly {
 			// TODO(davec): Only do this if not doing the initial block
 			// download and the local address is routable.
-			if !conf.Cfg.P2PNet.DisableListen /* && isCurrent? */ {
+			if !conf.Cfg.P2PNet.DisableListen && sp.connType != ConnFeeler /* && isCurrent? */ {
 				// Get address that best matches.
 				lna := addrManager.GetBestLocalAddress(sp.NA())
 				if addrmgr.IsRoutable(lna) {
@@ -411,15 +483,25 @@ func (sp *serverPeer) OnVersion(_ *peer.Peer, msg *wire.MsgVersion) {
 			// include a timestamp with addresses.
 			hasTimestamp := sp.ProtocolVersion() >=
 				wire.NetAddressTimeVersion
-			if addrManager.NeedMoreAddresses() && hasTimestamp {
+			if addrManager.NeedMoreAddresses() && hasTimestamp && sp.connType != ConnFeeler {
 				sp.QueueMessage(wire.NewMsgGetAddr(), nil)
 			}
 
 			// Mark the address as a known good address.
 			addrManager.Good(sp.NA())
+		} else if sp.connType == ConnBlockRelayOnly {
+			sp.server.addrManager.Good(sp.NA())
 		}
 	}
 
+	// A feeler connection only checks the address is reachable, which the
+	// handshake has just proven.
+	if sp.connType == ConnFeeler {
+		log.Debug("Feeler connection to %v succeeded -- disconnecting", sp)
+		sp.Disconnect()
+		return
+	}
+
 	// Add valid peer to the server.
 	sp.server.AddPeer(sp)
 }
@@ -476,9 +558,15 @@ func (sp *serverPeer) OnMemPool(_ *peer.Peer, msg *wire.MsgMemPool) {
 // transactions don't rely on the previous one in a linear fashion like blocks.
 func (sp *serverPeer) OnTx(_ *peer.Peer, msg *wire.MsgTx, done chan<- struct{}) {
 	txn := (*tx.Tx)(msg)
-	if conf.Cfg.P2PNet.BlocksOnly {
-		log.Trace("Ignoring tx %v from %v - blocksonly enabled",
-			txn.GetHash(), sp)
+	// In blocks only mode or over block-relay-only connections
+	// transactions are neither requested nor relayed, and the relay=false
+	// of our version message asks the peer not to send any, so only
+	// whitelisted peers may push them to us.
+	if sp.blocksOnly() && !sp.isWhitelisted {
+		log.Info("Peer %v sent unsolicited tx %v -- blocksonly enabled, "+
+			"disconnecting", sp, txn.GetHash())
+		sp.Disconnect()
+		done <- struct{}{}
 		return
 	}
 
@@ -490,6 +578,14 @@ func (sp *serverPeer) OnTx(_ *peer.Peer, msg *wire.MsgTx, done chan<- struct{})
 	iv := wire.NewInvVect(wire.InvTypeTx, &txhash)
 	sp.AddKnownInventory(iv)
 
+	// Large data carriers are a relay policy between consenting peers, do
+	// not take them from a peer which did not opt in.
+	if txn.HasLargeDataCarrier() && !sp.relaysLargeDataCarrier() {
+		log.Debug("Ignoring tx %v with a large data carrier from peer %v", txhash, sp)
+		done <- struct{}{}
+		return
+	}
+
 	// Queue the transaction up to be handled by the sync manager and
 	// intentionally block further receives until the transaction is fully
 	// processed and known good or bad.  This helps prevent a malicious peer
@@ -522,6 +618,7 @@ func (sp *serverPeer) OnBlock(_ *peer.Peer, msg *wire.MsgBlock, buf []byte, done
 	hash := block.GetHash()
 	iv := wire.NewInvVect(wire.InvTypeBlock, &hash)
 	sp.AddKnownInventory(iv)
+	sp.server.addrManager.BlockDelivered(sp.NA())
 
 	// Queue the block up to be handled by the block
 	// manager and intentionally block further receives
@@ -542,7 +639,7 @@ func (sp *serverPeer) OnBlock(_ *peer.Peer, msg *wire.MsgBlock, buf []byte, done
 // accordingly.  We pass the message down to blockmanager which will call
 // QueueMessage with any appropriate responses.
 func (sp *serverPeer) OnInv(_ *peer.Peer, msg *wire.MsgInv) {
-	if !conf.Cfg.P2PNet.BlocksOnly {
+	if !sp.blocksOnly() {
 		if len(msg.InvList) > 0 {
 			sp.server.syncManager.QueueInv(msg, sp.Peer)
 		}
@@ -895,6 +992,11 @@ func (sp *serverPeer) OnAddr(_ *peer.Peer, msg *wire.MsgAddr) {
 		return
 	}
 
+	// Addresses are not relayed over block-relay-only connections.
+	if sp.connType == ConnBlockRelayOnly {
+		return
+	}
+
 	// A message that has no addresses is invalid.
 	if len(msg.AddrList) == 0 {
 		log.Error("Command [%s] from %s does not contain any addresses",
@@ -933,12 +1035,14 @@ func (sp *serverPeer) OnAddr(_ *peer.Peer, msg *wire.MsgAddr) {
 // the bytes received by the server.
 func (sp *serverPeer) OnRead(_ *peer.Peer, bytesRead int, msg wire.Message, err error) {
 	sp.server.AddBytesReceived(uint64(bytesRead))
+	sp.server.addBytesPerMsg(sp.server.bytesRecvPerMsg, msg, bytesRead)
 }
 
 // OnWrite is invoked when a peer sends a message and it is used to update
 // the bytes sent by the server.
 func (sp *serverPeer) OnWrite(_ *peer.Peer, bytesWritten int, msg wire.Message, err error) {
 	sp.server.AddBytesSent(uint64(bytesWritten))
+	sp.server.addBytesPerMsg(sp.server.bytesSentPerMsg, msg, bytesWritten)
 }
 
 // randomUint16Number returns a random uint16 in a specified input range.  Note
@@ -1000,6 +1104,10 @@ func (s *Server) AnnounceNewTransactions(txns []*mempool.TxEntry) {
 	// transactions.
 	s.relayTransactions(txns)
 
+	for _, txe := range txns {
+		s.feeEstimator.ObserveTransaction(txe)
+	}
+
 	// Notify both websocket and getblocktemplate long poll clients of all
 	// newly accepted transactions.
 	// if s.rpcServer != nil {
@@ -1007,6 +1115,12 @@ func (s *Server) AnnounceNewTransactions(txns []*mempool.TxEntry) {
 	// }
 }
 
+// FeeEstimator returns the fee estimator fed with the transactions announced
+// and the blocks connected.
+func (s *Server) FeeEstimator() *mempool.FeeEstimator {
+	return s.feeEstimator
+}
+
 // TransactionConfirmed marks a Transaction as no longer needing rebroadcasting
 // when it has one confirmation on the main chain.
 func (s *Server) TransactionConfirmed(tx *tx.Tx) {
@@ -1036,6 +1150,16 @@ func (s *Server) pushTxMsg(sp *serverPeer, hash *util.Hash, doneChan chan<- stru
 		return errors.New("Don't find the tx")
 	}
 
+	// Peers which did not opt in to large data carriers never got them
+	// announced, so they do not get them either.
+	if txe.Tx.HasLargeDataCarrier() && !sp.relaysLargeDataCarrier() {
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+
+		return errors.New("Don't relay the tx to the peer")
+	}
+
 	// Once we have fetched data wait for any previous operation to finish.
 	if waitChan != nil {
 		<-waitChan
@@ -1044,6 +1168,8 @@ func (s *Server) pushTxMsg(sp *serverPeer, hash *util.Hash, doneChan chan<- stru
 	//sp.QueueMessageWithEncoding(tx.MsgTx(), doneChan, encoding)
 	msgtx := (*wire.MsgTx)(txe.Tx)
 	sp.QueueMessageWithEncoding(msgtx, doneChan, encoding)
+	// a peer has the transaction now, so it no longer needs announcing
+	pool.RemoveUnbroadcastTx(*hash)
 
 	return nil
 }
@@ -1073,6 +1199,16 @@ func (s *Server) pushBlockMsg(sp *serverPeer, hash *util.Hash, doneChan chan<- s
 		}
 	}
 
+	// Past the upload target, historical blocks are only served to
+	// whitelisted peers.
+	if send && !sp.isWhitelisted && s.OutboundTargetReached(true) &&
+		int64(activeChain.Tip().GetBlockTime())-int64(blkIndex.GetBlockTime()) >
+			int64(historicalBlockAge/time.Second) {
+		log.Debug("historical block serving limit reached, disconnect peer %s", sp)
+		sp.Disconnect()
+		send = false
+	}
+
 	if send && blkIndex.HasData() {
 		// Fetch the raw block bytes from the database.
 		bl, err := lblock.GetBlockByIndex(blkIndex, s.chainParams)
@@ -1222,22 +1358,16 @@ func (s *Server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 	}
 
 	// Disconnect banned peers.
-	host, _, err := net.SplitHostPort(sp.Addr())
-	if err != nil {
-		log.Debug("can't split hostport %v", err)
+	ip := addrIP(sp.Addr())
+	if ip == nil {
+		log.Debug("can't parse the address of peer %s", sp.Addr())
 		sp.Disconnect()
 		return false
 	}
-	if banEnd, ok := state.banned[host]; ok {
-		if time.Now().Before(banEnd) {
-			log.Debug("Peer %s is banned for another %v - disconnecting",
-				host, time.Until(banEnd))
-			sp.Disconnect()
-			return false
-		}
-
-		log.Info("Peer %s is no longer banned", host)
-		delete(state.banned, host)
+	if !sp.isWhitelisted && s.banManager.IsBanned(ip) {
+		log.Debug("Peer %s is banned - disconnecting", ip)
+		sp.Disconnect()
+		return false
 	}
 
 	// TODO: Check for max peers from a single IP.
@@ -1313,14 +1443,24 @@ func (s *Server) handleBanPeerMsg(state *peerState, sp *serverPeer) {
 		log.Debug("can't split ban peer %s %v", sp.Addr(), err)
 		return
 	}
+	subnet, err := banman.ParseSubnet(host)
+	if err != nil {
+		log.Debug("can't parse ban peer %s %v", sp.Addr(), err)
+		return
+	}
 	log.Info("Banned peer %s (inBlund:%v) for %v", host, sp.Inbound(),
 		conf.Cfg.P2PNet.BanDuration)
-	state.banned[host] = time.Now().Add(conf.Cfg.P2PNet.BanDuration)
+	s.banManager.Ban(subnet, time.Now().Add(conf.Cfg.P2PNet.BanDuration),
+		banman.BanReasonNodeMisbehaving)
 }
 
 // handleRelayInvMsg deals with relaying inventory to peers that are not already
 // known to have it.  It is invoked from the peerHandler goroutine.
 func (s *Server) handleRelayInvMsg(state *peerState, msg relayMsg) {
+	largeDataCarrier := false
+	if txD, ok := msg.data.(*mempool.TxEntry); ok && msg.invVect.Type == wire.InvTypeTx {
+		largeDataCarrier = txD.Tx.HasLargeDataCarrier()
+	}
 	state.forAllPeers(func(sp *serverPeer) {
 		if !sp.Connected() {
 			return
@@ -1353,6 +1493,11 @@ func (s *Server) handleRelayInvMsg(state *peerState, msg relayMsg) {
 				return
 			}
 
+			// Large data carriers only go to peers which opted in.
+			if largeDataCarrier && !sp.relaysLargeDataCarrier() {
+				return
+			}
+
 			txD, ok := msg.data.(*mempool.TxEntry)
 			if !ok {
 				log.Warn("Underlying data for tx inv "+
@@ -1429,6 +1574,7 @@ type disconnectNodeMsg struct {
 type connectNodeMsg struct {
 	addr      string
 	permanent bool
+	connType  ConnectionType
 	reply     chan error
 }
 
@@ -1437,6 +1583,21 @@ type removeNodeMsg struct {
 	reply chan error
 }
 
+type removeAddedNodeMsg struct {
+	addr  string
+	reply chan error
+}
+
+type getAddedNodeInfoMsg struct {
+	reply chan []AddedNodeInfo
+}
+
+type misbehavingPeerMsg struct {
+	peer   *peer.Peer
+	score  uint32
+	reason string
+}
+
 // handleQuery is the central handler for all queries and commands from other
 // goroutines related to peer state.
 func (s *Server) handleQuery(state *peerState, querymsg interface{}) {
@@ -1450,6 +1611,13 @@ func (s *Server) handleQuery(state *peerState, querymsg interface{}) {
 		})
 		msg.reply <- nconnected
 
+	case misbehavingPeerMsg:
+		state.forAllPeers(func(sp *serverPeer) {
+			if sp.Peer == msg.peer {
+				sp.addBanScore(msg.score, 0, msg.reason)
+			}
+		})
+
 	case getPeersMsg:
 		peers := make([]*serverPeer, 0, state.Count())
 		state.forAllPeers(func(sp *serverPeer) {
@@ -1461,20 +1629,23 @@ func (s *Server) handleQuery(state *peerState, querymsg interface{}) {
 		msg.reply <- peers
 
 	case connectNodeMsg:
-		// TODO: duplicate oneshots?
-		// Limit max number of total peers.
-		if state.Count() >= conf.Cfg.P2PNet.MaxPeers {
-			msg.reply <- errors.New("max peers reached")
-			return
-		}
-		for _, peer := range state.persistentPeers {
-			if peer.Addr() == msg.addr {
-				if msg.permanent {
-					msg.reply <- errors.New("peer already connected")
-				} else {
+		if msg.permanent {
+			if _, ok := s.addedNodes[msg.addr]; ok {
+				msg.reply <- ErrNodeAlreadyAdded
+				return
+			}
+		} else {
+			// TODO: duplicate oneshots?
+			// Limit max number of total peers.
+			if state.Count() >= conf.Cfg.P2PNet.MaxPeers {
+				msg.reply <- errors.New("max peers reached")
+				return
+			}
+			for _, peer := range state.persistentPeers {
+				if peer.Addr() == msg.addr {
 					msg.reply <- errors.New("peer exists as a permanent peer")
+					return
 				}
-				return
 			}
 		}
 
@@ -1485,11 +1656,55 @@ func (s *Server) handleQuery(state *peerState, querymsg interface{}) {
 		}
 
 		// TODO: if too many, nuke a non-perm peer. fix yongxin
-		go s.connManager.Connect(context.TODO(), &connmgr.ConnReq{
+		req := &connmgr.ConnReq{
 			Addr:      netAddr,
 			Permanent: msg.permanent,
+		}
+		if msg.connType != "" {
+			s.connTypes.Store(req, msg.connType)
+		}
+		if msg.permanent {
+			s.addedNodes[msg.addr] = req
+		}
+		go s.connManager.Connect(context.TODO(), req)
+		msg.reply <- nil
+
+	case removeAddedNodeMsg:
+		req, ok := s.addedNodes[msg.addr]
+		if !ok {
+			msg.reply <- ErrNodeNotAdded
+			return
+		}
+		delete(s.addedNodes, msg.addr)
+
+		// Stop reconnecting first, so the disconnection below is final.
+		req.Cancel()
+		disconnectPeer(state.persistentPeers, func(sp *serverPeer) bool {
+			return sp.connReq == req
+		}, func(sp *serverPeer) {
+			state.outboundGroups[addrmgr.GroupKey(sp.NA())]--
 		})
 		msg.reply <- nil
+
+	case getAddedNodeInfoMsg:
+		peers := make(map[*connmgr.ConnReq]*serverPeer, len(state.persistentPeers))
+		for _, sp := range state.persistentPeers {
+			if sp.Connected() && sp.connReq != nil {
+				peers[sp.connReq] = sp
+			}
+		}
+		infos := make([]AddedNodeInfo, 0, len(s.addedNodes))
+		for addr, req := range s.addedNodes {
+			info := AddedNodeInfo{Addr: addr}
+			if sp, ok := peers[req]; ok {
+				info.Peer = (*rpcPeer)(sp)
+			}
+			infos = append(infos, info)
+		}
+		sort.Slice(infos, func(i, j int) bool {
+			return infos[i].Addr < infos[j].Addr
+		})
+		msg.reply <- infos
 	case removeNodeMsg:
 		found := disconnectPeer(state.persistentPeers, msg.cmp, func(sp *serverPeer) {
 			// Keep group counts ok since we remove from
@@ -1532,6 +1747,12 @@ func (s *Server) handleQuery(state *peerState, querymsg interface{}) {
 			// the list now.
 			state.outboundGroups[addrmgr.GroupKey(sp.NA())]--
 		})
+		if !found {
+			// Added nodes are reconnected to later.
+			found = disconnectPeer(state.persistentPeers, msg.cmp, func(sp *serverPeer) {
+				state.outboundGroups[addrmgr.GroupKey(sp.NA())]--
+			})
+		}
 		if found {
 			// If there are multiple outbound connections to the same
 			// ip:port, continue disconnecting them all until no such
@@ -1545,7 +1766,7 @@ func (s *Server) handleQuery(state *peerState, querymsg interface{}) {
 			return
 		}
 
-		msg.reply <- errors.New("peer not found")
+		msg.reply <- ErrNodeNotConnected
 	}
 }
 
@@ -1592,6 +1813,7 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 			//OnFilterLoad:  sp.OnFilterLoad,
 			OnGetAddr:                  sp.OnGetAddr,
 			OnAddr:                     sp.OnAddr,
+			OnMempoolSync:              sp.OnMempoolSync,
 			OnRead:                     sp.OnRead,
 			OnWrite:                    sp.OnWrite,
 			OnTransferMsgToBusinessPro: sp.TransferMsgToBusinessPro,
@@ -1607,10 +1829,10 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 		Proxy:             conf.Cfg.P2PNet.Proxy,
 		UserAgentName:     userAgentName,
 		UserAgentVersion:  userAgentVersion,
-		UserAgentComments: conf.Cfg.P2PNet.UserAgentComments,
+		UserAgentComments: userAgentComments(),
 		ChainParams:       sp.server.chainParams,
 		Services:          sp.server.services,
-		DisableRelayTx:    conf.Cfg.P2PNet.BlocksOnly,
+		DisableRelayTx:    sp.blocksOnly(),
 		ProtocolVersion:   peer.MaxProtocolVersion,
 	}
 }
@@ -1620,8 +1842,16 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 // instance, associates it with the connection, and starts a goroutine to wait
 // for disconnection.
 func (s *Server) inboundPeerConnected(conn net.Conn) {
-	sp := newServerPeer(s, false)
-	sp.isWhitelisted = isWhitelisted(conn.RemoteAddr())
+	whitelisted := isWhitelisted(conn.RemoteAddr())
+	if ip := addrIP(conn.RemoteAddr().String()); !whitelisted && ip != nil &&
+		s.banManager.IsBanned(ip) {
+		log.Debug("Connection from banned address %s dropped", ip)
+		conn.Close()
+		return
+	}
+
+	sp := newServerPeer(s, false, ConnInbound)
+	sp.isWhitelisted = whitelisted
 	sp.Peer = peer.NewInboundPeer(newPeerConfig(sp))
 	sp.AssociateConnection(conn, s.MsgChan)
 	go s.peerDoneHandler(sp)
@@ -1633,7 +1863,7 @@ func (s *Server) inboundPeerConnected(conn net.Conn) {
 // request instance and the connection itself, and finally notifies the address
 // manager of the attempt.
 func (s *Server) outboundPeerConnected(c *connmgr.ConnReq, conn net.Conn) {
-	sp := newServerPeer(s, c.Permanent)
+	sp := newServerPeer(s, c.Permanent, s.takeConnType(c))
 	p, err := peer.NewOutboundPeer(newPeerConfig(sp), c.Addr.String())
 	if err != nil {
 		log.Debug("Cannot create outbound peer %s: %v", c.Addr, err)
@@ -1647,6 +1877,26 @@ func (s *Server) outboundPeerConnected(c *connmgr.ConnReq, conn net.Conn) {
 	s.addrManager.Attempt(sp.NA())
 }
 
+// outboundPeerFailed is invoked by the connection manager when an outbound
+// connection could not be established.  It forgets the connection type the
+// request was made with.
+func (s *Server) outboundPeerFailed(c *connmgr.ConnReq) {
+	s.connTypes.Delete(c)
+}
+
+// takeConnType returns the type of the connection established for c, and
+// forgets the type c was requested with by addconnection if any.
+func (s *Server) takeConnType(c *connmgr.ConnReq) ConnectionType {
+	if t, ok := s.connTypes.Load(c); ok {
+		s.connTypes.Delete(c)
+		return t.(ConnectionType)
+	}
+	if c.Permanent {
+		return ConnManual
+	}
+	return ConnOutboundFullRelay
+}
+
 // peerDoneHandler handles peer disconnects by notifiying the server that it's
 // done along with other performing other desirable cleanup.
 func (s *Server) peerDoneHandler(sp *serverPeer) {
@@ -1683,7 +1933,6 @@ func (s *Server) cycle() {
 		inboundPeers:    make(map[int32]*serverPeer),
 		persistentPeers: make(map[int32]*serverPeer),
 		outboundPeers:   make(map[int32]*serverPeer),
-		banned:          make(map[string]time.Time),
 		outboundGroups:  make(map[string]int),
 	}
 
@@ -1830,9 +2079,19 @@ func (s *Server) OutboundGroupCount(key string) int {
 }
 
 // AddBytesSent adds the passed number of bytes to the total bytes sent counter
-// for the server.  It is safe for concurrent access.
+// for the server and to the upload target cycle.  It is safe for concurrent
+// access.
 func (s *Server) AddBytesSent(bytesSent uint64) {
 	atomic.AddUint64(&s.bytesSent, bytesSent)
+
+	s.trafficMtx.Lock()
+	now := time.Now()
+	if now.Sub(s.uploadCycleStart) > uploadTargetTimeframe {
+		s.uploadCycleStart = now
+		s.uploadCycleBytes = 0
+	}
+	s.uploadCycleBytes += bytesSent
+	s.trafficMtx.Unlock()
 }
 
 // AddBytesReceived adds the passed number of bytes to the total bytes received
@@ -1848,6 +2107,108 @@ func (s *Server) NetTotals() (uint64, uint64) {
 		atomic.LoadUint64(&s.bytesSent)
 }
 
+// addBytesPerMsg accounts n bytes sent or received for the command of msg.
+func (s *Server) addBytesPerMsg(perMsg map[string]uint64, msg wire.Message, n int) {
+	if n == 0 {
+		return
+	}
+	cmd := peer.OtherMsgCmd
+	if msg != nil {
+		cmd = msg.Command()
+	}
+	s.trafficMtx.Lock()
+	perMsg[cmd] += uint64(n)
+	s.trafficMtx.Unlock()
+}
+
+// NetTotalsPerMsg returns the bytes received and sent across the network for
+// all peers by message command.  It is safe for concurrent access.
+func (s *Server) NetTotalsPerMsg() (map[string]uint64, map[string]uint64) {
+	s.trafficMtx.Lock()
+	defer s.trafficMtx.Unlock()
+
+	recv := make(map[string]uint64, len(s.bytesRecvPerMsg))
+	for cmd, n := range s.bytesRecvPerMsg {
+		recv[cmd] = n
+	}
+	sent := make(map[string]uint64, len(s.bytesSentPerMsg))
+	for cmd, n := range s.bytesSentPerMsg {
+		sent[cmd] = n
+	}
+	return recv, sent
+}
+
+// UploadTarget describes the upload target cycle.
+type UploadTarget struct {
+	Timeframe             time.Duration
+	Target                uint64
+	TargetReached         bool
+	ServeHistoricalBlocks bool
+	BytesLeftInCycle      uint64
+	TimeLeftInCycle       time.Duration
+}
+
+// uploadCycle returns the bytes sent in the upload target cycle and the time
+// left in it.  It must be called with the traffic lock held.
+func (s *Server) uploadCycle() (uint64, time.Duration) {
+	left := s.uploadCycleStart.Add(uploadTargetTimeframe).Sub(time.Now())
+	if left < 0 {
+		// the cycle is over, the next one starts with the next bytes sent
+		return 0, uploadTargetTimeframe
+	}
+	return s.uploadCycleBytes, left
+}
+
+// uploadTargetReached returns whether the bytes sent in the cycle reached
+// target.  For historical blocks, room is kept for serving a block of the
+// largest size every ten minutes until the end of the cycle.  It must be
+// called with the traffic lock held.
+func (s *Server) uploadTargetReached(target uint64, historicalBlocks bool) bool {
+	if target == 0 {
+		return false
+	}
+	sent, left := s.uploadCycle()
+	if historicalBlocks {
+		buffer := block.GetMaxBlockSize() * uint64(left/(10*time.Minute))
+		return buffer >= target || sent >= target-buffer
+	}
+	return sent >= target
+}
+
+// OutboundTargetReached returns whether the upload target is reached, for
+// historical blocks or for any traffic.  It is safe for concurrent access.
+func (s *Server) OutboundTargetReached(historicalBlocks bool) bool {
+	s.trafficMtx.Lock()
+	defer s.trafficMtx.Unlock()
+	return s.uploadTargetReached(conf.Cfg.P2PNet.MaxUploadTarget*1024*1024,
+		historicalBlocks)
+}
+
+// UploadTarget returns the state of the upload target cycle.  It is safe for
+// concurrent access.
+func (s *Server) UploadTarget() *UploadTarget {
+	target := conf.Cfg.P2PNet.MaxUploadTarget * 1024 * 1024
+
+	s.trafficMtx.Lock()
+	defer s.trafficMtx.Unlock()
+
+	ut := &UploadTarget{
+		Timeframe:             uploadTargetTimeframe,
+		Target:                target,
+		TargetReached:         s.uploadTargetReached(target, false),
+		ServeHistoricalBlocks: !s.uploadTargetReached(target, true),
+	}
+	if target == 0 {
+		return ut
+	}
+	sent, left := s.uploadCycle()
+	if sent < target {
+		ut.BytesLeftInCycle = target - sent
+	}
+	ut.TimeLeftInCycle = left
+	return ut
+}
+
 // UpdatePeerHeights updates the heights of all peers who have have announced
 // the latest connected main chain block, or a recognized orphan. These height
 // updates allow us to dynamically refresh peer heights, ensuring sync peer
@@ -1860,6 +2221,16 @@ func (s *Server) UpdatePeerHeights(latestBlkHash *util.Hash, latestHeight int32,
 	}
 }
 
+// Misbehaving increases the ban score of the peer by score, for sending data
+// which failed validation. It implements the syncmanager.PeerNotifier
+// interface. It returns without effect once the server is shutting down.
+func (s *Server) Misbehaving(p *peer.Peer, score uint32, reason string) {
+	select {
+	case s.query <- misbehavingPeerMsg{peer: p, score: score, reason: reason}:
+	case <-s.quit:
+	}
+}
+
 // rebroadcastHandler keeps track of user submitted inventories that we have
 // sent out but have not yet made it into a block. We periodically rebroadcast
 // them in case our peers restarted or otherwise lost track of them.
@@ -2114,6 +2485,9 @@ func NewServer(chainParams *model.BitcoinParams, interrupt <-chan struct{}) (*Se
 	if cfg.Protocal.NoPeerBloomFilters {
 		services &^= wire.SFNodeBloom
 	}
+	if cfg.Script.LargeDataCarrier {
+		services |= wire.SFNodeLargeDataCarrier
+	}
 
 	amgr := addrmgr.New(cfg.DataDir, net.LookupIP)
 
@@ -2134,6 +2508,9 @@ func NewServer(chainParams *model.BitcoinParams, interrupt <-chan struct{}) (*Se
 	s := &Server{
 		chainParams:          chainParams,
 		addrManager:          amgr,
+		banManager:           banman.New(cfg.DataDir),
+		bytesSentPerMsg:      make(map[string]uint64),
+		bytesRecvPerMsg:      make(map[string]uint64),
 		newPeers:             make(chan *serverPeer, cfg.P2PNet.MaxPeers),
 		donePeers:            make(chan *serverPeer, cfg.P2PNet.MaxPeers),
 		banPeers:             make(chan *serverPeer, cfg.P2PNet.MaxPeers),
@@ -2146,6 +2523,7 @@ func NewServer(chainParams *model.BitcoinParams, interrupt <-chan struct{}) (*Se
 		services:             services,
 		nat:                  nat,
 		timeSource:           bitcointime.NewMedianTime(),
+		feeEstimator:         lmempool.LoadFeeEstimator(filepath.Join(cfg.DataDir, lmempool.FeeEstimatesFileName)),
 		MsgChan:              msgChan,
 	}
 
@@ -2172,8 +2550,9 @@ func NewServer(chainParams *model.BitcoinParams, interrupt <-chan struct{}) (*Se
 			var d net.Dialer
 			return d.DialContext(ctx, netaddr.Network(), netaddr.String())
 		},
-		OnAccept:  s.inboundPeerConnected,
-		OnConnect: s.outboundPeerConnected,
+		OnAccept:           s.inboundPeerConnected,
+		OnConnect:          s.outboundPeerConnected,
+		OnConnectionFailed: s.outboundPeerFailed,
 		GetNewAddress: func() (net.Addr, error) {
 			addr, err := amgr.NewAddress(func(groupKey string) bool {
 				return s.OutboundGroupCount(groupKey) != 0
@@ -2184,15 +2563,18 @@ func NewServer(chainParams *model.BitcoinParams, interrupt <-chan struct{}) (*Se
 			return addrStringToNetAddr(addr)
 		},
 	})
+	s.addedNodes = make(map[string]*connmgr.ConnReq, len(cfg.P2PNet.ConnectPeersOnStart))
 	for _, addr := range cfg.P2PNet.ConnectPeersOnStart {
 		netAddr, err := addrStringToNetAddr(addr)
 		if err != nil {
 			return nil, err
 		}
-		go cmgr.Connect(context.TODO(), &connmgr.ConnReq{
+		req := &connmgr.ConnReq{
 			Addr:      netAddr,
 			Permanent: true,
-		})
+		}
+		s.addedNodes[addr] = req
+		go cmgr.Connect(context.TODO(), req)
 	}
 	s.connManager = cmgr
 
@@ -2201,6 +2583,7 @@ func NewServer(chainParams *model.BitcoinParams, interrupt <-chan struct{}) (*Se
 		ChainParams:        s.chainParams,
 		DisableCheckpoints: cfg.Protocal.DisableCheckpoints,
 		MaxPeers:           cfg.P2PNet.MaxPeers,
+		FeeEstimator:       s.feeEstimator,
 	})
 	if err != nil {
 		fmt.Println("new syncManager error ...")
@@ -2407,6 +2790,16 @@ func dynamicTickDuration(remaining time.Duration) time.Duration {
 	return time.Hour
 }
 
+// addrIP returns the IP of the host:port address addr, or nil if the host
+// is not an IP.
+func addrIP(addr string) net.IP {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
+
 // isWhitelisted returns whether the IP address is included in the whitelisted
 // networks and IPs.
 func isWhitelisted(addr net.Addr) bool {
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMisbehavingAfterShutdown(t *testing.T) {
+	s := &Server{
+		query: make(chan interface{}),
+		quit:  make(chan struct{}),
+	}
+	close(s.quit)
+
+	done := make(chan struct{})
+	go func() {
+		s.Misbehaving(nil, 10, "invalid block")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Misbehaving blocked after the server stopped handling queries")
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/model/block"
+)
+
+func TestUploadTargetReached(t *testing.T) {
+	defer block.SetMaxBlockSize(block.GetMaxBlockSize())
+	block.SetMaxBlockSize(1000)
+
+	// 71 ten minute slots are left in a cycle started 12h05m ago, so room is
+	// kept for 71000 bytes of historical blocks
+	halfCycle := 12*time.Hour + 5*time.Minute
+	tests := []struct {
+		name       string
+		elapsed    time.Duration
+		sent       uint64
+		target     uint64
+		historical bool
+		reached    bool
+	}{
+		{"no target", halfCycle, 1 << 40, 0, false, false},
+		{"no target for historical blocks", halfCycle, 1 << 40, 0, true, false},
+		{"under the target", halfCycle, 99999, 100000, false, false},
+		{"at the target", halfCycle, 100000, 100000, false, true},
+		{"under the historical limit", halfCycle, 28999, 100000, true, false},
+		{"at the historical limit", halfCycle, 29000, 100000, true, true},
+		{"buffer at the target", halfCycle, 0, 71000, true, true},
+		{"buffer over the target", halfCycle, 0, 50000, true, true},
+		{"buffer under the target", halfCycle, 0, 71001, true, false},
+		// bytes of an ended cycle do not count, and the next one is whole
+		{"ended cycle", 25 * time.Hour, 1 << 40, 100000, false, false},
+		{"ended cycle for historical blocks", 25 * time.Hour, 1 << 40, 144001, true, false},
+		{"ended cycle buffer", 25 * time.Hour, 0, 144000, true, true},
+	}
+	for _, test := range tests {
+		s := &Server{
+			uploadCycleStart: time.Now().Add(-test.elapsed),
+			uploadCycleBytes: test.sent,
+		}
+		if reached := s.uploadTargetReached(test.target, test.historical); reached != test.reached {
+			t.Errorf("%s: target reached %t, expect %t", test.name, reached, test.reached)
+		}
+	}
+}
+
+func TestUploadTarget(t *testing.T) {
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	defer func(target uint64) { conf.Cfg.P2PNet.MaxUploadTarget = target }(conf.Cfg.P2PNet.MaxUploadTarget)
+	defer block.SetMaxBlockSize(block.GetMaxBlockSize())
+	block.SetMaxBlockSize(1000)
+
+	s := &Server{uploadCycleStart: time.Now().Add(-time.Hour), uploadCycleBytes: 1 << 20}
+	conf.Cfg.P2PNet.MaxUploadTarget = 0
+	ut := s.UploadTarget()
+	if ut.Target != 0 || ut.TargetReached || !ut.ServeHistoricalBlocks || ut.BytesLeftInCycle != 0 ||
+		ut.TimeLeftInCycle != 0 {
+		t.Errorf("no target got %+v", ut)
+	}
+
+	conf.Cfg.P2PNet.MaxUploadTarget = 3
+	ut = s.UploadTarget()
+	if ut.Target != 3<<20 || ut.TargetReached || !ut.ServeHistoricalBlocks || ut.BytesLeftInCycle != 2<<20 {
+		t.Errorf("target of 3 MiB got %+v", ut)
+	}
+	if ut.TimeLeftInCycle > 23*time.Hour || ut.TimeLeftInCycle < 23*time.Hour-time.Minute {
+		t.Errorf("%v left in the cycle, expect 23h", ut.TimeLeftInCycle)
+	}
+
+	s.AddBytesSent(2 << 20)
+	ut = s.UploadTarget()
+	if !ut.TargetReached || ut.ServeHistoricalBlocks || ut.BytesLeftInCycle != 0 {
+		t.Errorf("reached target got %+v", ut)
+	}
+}
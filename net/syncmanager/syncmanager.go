@@ -7,6 +7,7 @@ package syncmanager
 import (
 	"container/list"
 	"net"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -34,10 +35,6 @@ const (
 	// more.
 	minInFlightBlocks = 10
 
-	// maxRejectedTxns is the maximum number of rejected transactions
-	// hashes to store in memory.
-	maxRejectedTxns = 1000
-
 	// maxRequestedBlocks is the maximum number of requested block
 	// hashes to store in memory.
 	maxRequestedBlocks = wire.MaxInvPerMsg
@@ -120,6 +117,25 @@ type getSyncPeerMsg struct {
 	reply chan int32
 }
 
+// getPeerSyncStatsMsg is a message type to be sent across the message channel
+// for retrieving the sync state of a peer.
+type getPeerSyncStatsMsg struct {
+	peer  *peer.Peer
+	reply chan *PeerSyncStats
+}
+
+// PeerSyncStats describes how far a peer is in sync with us.
+type PeerSyncStats struct {
+	// SyncedHeaders is the height of the best block the peer announced,
+	// or -1 if we do not know the block.
+	SyncedHeaders int32
+	// SyncedBlocks is the height of the last block of the active chain
+	// the peer has too, or -1 if unknown.
+	SyncedBlocks int32
+	// Inflight holds the heights of the blocks requested from the peer.
+	Inflight []int32
+}
+
 // processBlockResponse is a response sent to the reply channel of a
 // processBlockMsg.
 type processBlockResponse struct {
@@ -185,7 +201,6 @@ type SyncManager struct {
 	quit                chan struct{}
 
 	// These fields should only be accessed from the messagesHandler
-	rejectedTxns    map[util.Hash]struct{}
 	requestedTxns   map[util.Hash]struct{}
 	requestedBlocks map[util.Hash]struct{}
 	syncPeer        *peer.Peer
@@ -456,13 +471,10 @@ func (sm *SyncManager) handleTxMsg(tmsg *txMsg) {
 	// interoperability.
 	txHash := tmsg.tx.GetHash()
 
-	// TODO: after active chain's tip changed, previously rejected txs might be now valid, e.g. due to nLockTime'd tx
-	// becoming valid, or a double-spend. Reset the rejects filter and give those txs a second chance.
-
-	// Ignore transactions that we have already rejected.  Do not
-	// send a reject message here because if the transaction was already
-	// rejected, the transaction was unsolicited.
-	if _, exists = sm.rejectedTxns[txHash]; exists {
+	// Ignore transactions that we have already rejected since the last
+	// tip change.  Do not send a reject message here because if the
+	// transaction was already rejected, the transaction was unsolicited.
+	if lmempool.FindRejectTxInMempool(txHash) {
 		log.Debug("Ignoring unsolicited previously rejected "+
 			"transaction %v from %s", txHash, peer.Addr())
 		return
@@ -487,15 +499,16 @@ func (sm *SyncManager) handleTxMsg(tmsg *txMsg) {
 	}
 
 	if err != nil {
-		// Do not request this transaction again until a new block
-		// has been processed.
-		sm.rejectedTxns[txHash] = struct{}{}
-		sm.limitMap(sm.rejectedTxns, maxRejectedTxns)
-
 		// When the error is a rule error, it means the transaction was
 		// simply rejected as opposed to something actually going wrong,
 		// so log it as such.  Otherwise, something really did go wrong,
 		// so log it as an actual error.
+		if errcode.IsTxCheckTimeout(err) {
+			// the node is busy, the transaction is not rejected
+			log.Debug("Checking transaction %v from %s timed out",
+				txHash, peer.Addr())
+			return
+		}
 		if !errcode.IsErrorCode(err, errcode.TxErrNoPreviousOut) {
 			log.Debug("Rejected transaction %v from %s: %v",
 				txHash, peer.Addr(), err)
@@ -511,7 +524,7 @@ func (sm *SyncManager) handleTxMsg(tmsg *txMsg) {
 		//peer.PushRejectMsg(wire.CmdTx, code, code.String(), &txHash, false)
 		return
 	}
-	txentrys := make([]*mempool.TxEntry, len(acceptedTxs))
+	txentrys := make([]*mempool.TxEntry, 0, len(acceptedTxs))
 	for _, tx := range acceptedTxs {
 		if entry := lmempool.FindTxInMempool(tx.GetHash()); entry != nil {
 			txentrys = append(txentrys, entry)
@@ -543,6 +556,37 @@ func (sm *SyncManager) current() bool {
 	return true
 }
 
+// peerSyncStats collects the sync state of peer from the blocks it announced
+// and the blocks requested from it.
+func (sm *SyncManager) peerSyncStats(peer *peer.Peer) *PeerSyncStats {
+	stats := &PeerSyncStats{SyncedHeaders: -1, SyncedBlocks: -1}
+
+	activeChain := chain.GetInstance()
+	if hash := peer.LastAnnouncedBlock(); hash != nil {
+		if index := activeChain.FindBlockIndex(*hash); index != nil {
+			stats.SyncedHeaders = index.Height
+			if fork := activeChain.FindFork(index); fork != nil {
+				stats.SyncedBlocks = fork.Height
+			}
+		}
+	}
+
+	state, exists := sm.peerStates[peer]
+	if !exists {
+		return stats
+	}
+	stats.Inflight = make([]int32, 0, len(state.requestedBlocks))
+	for hash := range state.requestedBlocks {
+		if index := activeChain.FindBlockIndex(hash); index != nil {
+			stats.Inflight = append(stats.Inflight, index.Height)
+		}
+	}
+	sort.Slice(stats.Inflight, func(i, j int) bool {
+		return stats.Inflight[i] < stats.Inflight[j]
+	})
+	return stats
+}
+
 // handleBlockMsg handles block messages from all peers.
 func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 	peer := bmsg.peer
@@ -645,6 +689,9 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		}
 
 		log.Debug("ProcessBlockCallBack err:%v", err)
+		if score := errcode.GetChainErrBanScore(err); score > 0 {
+			go sm.peerNotifier.Misbehaving(peer, score, err.Error())
+		}
 		return
 	}
 
@@ -670,9 +717,6 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 	heightUpdate = best.Height
 	blkHashUpdate = best.GetBlockHash()
 
-	// Clear the rejected transactions.
-	sm.rejectedTxns = make(map[util.Hash]struct{})
-
 	// Update the block height for this peer. But only send a message to
 	// the server for updating peer heights if this is an orphan or our
 	// chain is "current". This avoids sending a spammy amount of messages
@@ -884,6 +928,9 @@ func (sm *SyncManager) handleHeadersMsg(hmsg *headersMsg) {
 		endHash := msg.Headers[len(msg.Headers)-1].GetHash()
 		log.Warn("processblockheader error, beginHeader hash : %s, endHeader hash : %s,"+
 			"error news : %s.", beginHash.String(), endHash.String(), err.Error())
+		if score := errcode.GetChainErrBanScore(err); score > 0 {
+			go sm.peerNotifier.Misbehaving(peer, score, err.Error())
+		}
 	}
 
 	// When this header is a checkpoint, switch to fetching the blocks for
@@ -1044,14 +1091,6 @@ func (sm *SyncManager) handleInvMsg(imsg *invMsg) {
 			continue
 		}
 		if !haveInv {
-			if iv.Type == wire.InvTypeTx {
-				// Skip the transaction if it has already been
-				// rejected.
-				if _, exists := sm.rejectedTxns[iv.Hash]; exists {
-					continue
-				}
-			}
-
 			// Add it to the request queue.
 			state.requestQueue = append(state.requestQueue, iv)
 			continue
@@ -1204,6 +1243,9 @@ out:
 				}
 				msg.reply <- peerID
 
+			case getPeerSyncStatsMsg:
+				msg.reply <- sm.peerSyncStats(msg.peer)
+
 			case isCurrentMsg:
 				msg.reply <- sm.current()
 
@@ -1279,7 +1321,7 @@ func (sm *SyncManager) handleBlockchainNotification(notification *chain.Notifica
 			// TODO: add it back when rcp command @SendRawTransaction is ready for broadcasting tx
 			// sm.peerNotifier.TransactionConfirmed(tx)
 			acceptedTxs := lmempool.ProcessOrphan(tx)
-			txentrys := make([]*mempool.TxEntry, len(acceptedTxs))
+			txentrys := make([]*mempool.TxEntry, 0, len(acceptedTxs))
 			for _, tx := range acceptedTxs {
 				if find := lmempool.FindTxInMempool(tx.GetHash()); find != nil {
 					txentrys = append(txentrys, find)
@@ -1290,15 +1332,18 @@ func (sm *SyncManager) handleBlockchainNotification(notification *chain.Notifica
 
 		// Register block with the fee estimator, if it exists.
 		if sm.feeEstimator != nil {
-			err := sm.feeEstimator.RegisterBlock(block)
+			var err error
+			index := chain.GetInstance().FindBlockIndex(block.GetHash())
+			if index != nil {
+				err = sm.feeEstimator.RegisterBlock(block, index.Height)
+			}
 
 			// If an error is somehow generated then the fee estimator
 			// has entered an invalid state. Since it doesn't know how
-			// to recover, create a new one.
-			if err != nil {
-				sm.feeEstimator = mempool.NewFeeEstimator(
-					mempool.DefaultEstimateFeeMaxRollback,
-					mempool.DefaultEstimateFeeMinRegisteredBlocks)
+			// to recover, start it over.
+			if index == nil || err != nil {
+				log.Debug("Fee estimator reset: %v", err)
+				sm.feeEstimator.Reset()
 			}
 		}
 
@@ -1310,6 +1355,16 @@ func (sm *SyncManager) handleBlockchainNotification(notification *chain.Notifica
 			break
 		}
 
+		// Unregister the block from the fee estimator, which cannot undo
+		// blocks older than its rollback depth and starts over then.
+		if sm.feeEstimator != nil {
+			blkHash := block.GetHash()
+			if err := sm.feeEstimator.Rollback(&blkHash); err != nil {
+				log.Debug("Fee estimator reset: %v", err)
+				sm.feeEstimator.Reset()
+			}
+		}
+
 		// Reinsert all of the transactions (except the coinbase) into
 		// the transaction pool.
 		for _, tx := range block.Txs[1:] {
@@ -1457,6 +1512,13 @@ func (sm *SyncManager) SyncPeerID() int32 {
 	return <-reply
 }
 
+// PeerSyncStats returns the sync state of the given peer.
+func (sm *SyncManager) PeerSyncStats(peer *peer.Peer) *PeerSyncStats {
+	reply := make(chan *PeerSyncStats)
+	sm.processBusinessChan <- getPeerSyncStatsMsg{peer: peer, reply: reply}
+	return <-reply
+}
+
 // ProcessBlock makes use of ProcessBlock on an internal instance of a block chain.
 func (sm *SyncManager) ProcessBlock(block *block.Block, flags chain.BehaviorFlags) (bool, error) {
 	reply := make(chan processBlockResponse, 1)
@@ -1489,7 +1551,7 @@ func New(config *Config) (*SyncManager, error) {
 	sm := SyncManager{
 		peerNotifier:        config.PeerNotifier,
 		chainParams:         config.ChainParams,
-		rejectedTxns:        make(map[util.Hash]struct{}),
+		feeEstimator:        config.FeeEstimator,
 		requestedTxns:       make(map[util.Hash]struct{}),
 		requestedBlocks:     make(map[util.Hash]struct{}),
 		peerStates:          make(map[*peer.Peer]*peerSyncState),
@@ -1532,6 +1594,8 @@ type PeerNotifier interface {
 	RelayUpdatedTipBlocks(event *chain.TipUpdatedEvent)
 
 	TransactionConfirmed(tx *tx.Tx)
+
+	Misbehaving(p *peer.Peer, score uint32, reason string)
 }
 
 // Config is a configuration struct used to initialize a new SyncManager.
@@ -1541,4 +1605,8 @@ type Config struct {
 
 	DisableCheckpoints bool
 	MaxPeers           int
+
+	// FeeEstimator, if set, learns from the blocks connected and
+	// disconnected.
+	FeeEstimator *mempool.FeeEstimator
 }
@@ -56,6 +56,7 @@ const (
 	CmdCmpctBlock  = "cmpctblock"
 	CmdGetBlockTxn = "getblocktxn"
 	CmdBlockTxn    = "blocktxn"
+	CmdMempoolSync = "mempoolsync"
 )
 
 // MessageEncoding represents the wire message encoding format to be used.
@@ -173,6 +174,9 @@ func makeEmptyMessage(command string) (Message, error) {
 	case CmdBlockTxn:
 		msg = &MsgBlockTxn{}
 
+	case CmdMempoolSync:
+		msg = &MsgMempoolSync{}
+
 	default:
 		return nil, fmt.Errorf("unhandled command [%s]", command)
 	}
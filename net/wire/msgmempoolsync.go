@@ -0,0 +1,127 @@
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/copernet/copernicus/util"
+)
+
+// MaxMempoolSyncIDs is the maximum number of short transaction ids in a
+// mempoolsync message.
+const MaxMempoolSyncIDs = 2000000
+
+// maxMempoolSyncIDsAlloc caps the short ids allocated ahead of reading them,
+// the count announced by a peer may well not be followed by as many ids.
+const maxMempoolSyncIDsAlloc = 50000
+
+// MsgMempoolSync implements the Message interface and represents a mempoolsync
+// message, a copernicus extension.  It carries the short ids of all the
+// transactions in the mempool of the sender, so that a trusted peer answers
+// with the inventory of the transactions the sender misses.  Unless the
+// message is a reply, the peer also sends the short ids of its own mempool
+// back in a reply, reconciling the mempools in both directions.
+//
+// The short ids are the SipHash-2-4 of the transaction hashes keyed with K0
+// and K1, truncated to 6 bytes as in compact blocks.
+type MsgMempoolSync struct {
+	K0       uint64
+	K1       uint64
+	Reply    bool
+	ShortIDs []uint64
+}
+
+// ShortID returns the short id of the transaction hash under the keys of the
+// message.
+func (msg *MsgMempoolSync) ShortID(hash *util.Hash) uint64 {
+	return getShortID(msg.K0, msg.K1, hash)
+}
+
+// AddShortID adds the short id of the transaction hash to the message.
+func (msg *MsgMempoolSync) AddShortID(hash *util.Hash) error {
+	if len(msg.ShortIDs)+1 > MaxMempoolSyncIDs {
+		str := fmt.Sprintf("too many short ids in message [max %v]",
+			MaxMempoolSyncIDs)
+		return messageError("MsgMempoolSync.AddShortID", str)
+	}
+	msg.ShortIDs = append(msg.ShortIDs, msg.ShortID(hash))
+	return nil
+}
+
+// Decode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgMempoolSync) Decode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	if err := util.ReadElements(r, &msg.K0, &msg.K1, &msg.Reply); err != nil {
+		return err
+	}
+	count, err := util.ReadVarInt(r)
+	if err != nil {
+		return err
+	}
+	if count > MaxMempoolSyncIDs {
+		str := fmt.Sprintf("too many short ids in message [%v]", count)
+		return messageError("MsgMempoolSync.Decode", str)
+	}
+
+	allocCount := count
+	if allocCount > maxMempoolSyncIDsAlloc {
+		allocCount = maxMempoolSyncIDsAlloc
+	}
+	msg.ShortIDs = make([]uint64, 0, allocCount)
+	for i := uint64(0); i < count; i++ {
+		var lsb uint32
+		var msb uint16
+		if err := util.ReadElements(r, &lsb, &msb); err != nil {
+			return err
+		}
+		msg.ShortIDs = append(msg.ShortIDs, uint64(msb)<<32|uint64(lsb))
+	}
+	return nil
+}
+
+// Encode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgMempoolSync) Encode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	count := len(msg.ShortIDs)
+	if count > MaxMempoolSyncIDs {
+		str := fmt.Sprintf("too many short ids in message [%v]", count)
+		return messageError("MsgMempoolSync.Encode", str)
+	}
+
+	if err := util.WriteElements(w, msg.K0, msg.K1, msg.Reply); err != nil {
+		return err
+	}
+	if err := util.WriteVarInt(w, uint64(count)); err != nil {
+		return err
+	}
+	for _, id := range msg.ShortIDs {
+		lsb := uint32(id & 0xffffffff)
+		msb := uint16((id >> 32) & 0xffff)
+		if err := util.WriteElements(w, lsb, msb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgMempoolSync) Command() string {
+	return CmdMempoolSync
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgMempoolSync) MaxPayloadLength(pver uint32) uint32 {
+	// Keys + reply flag + num short ids (varInt) + max short ids.
+	return 8 + 8 + 1 + MaxVarIntPayload + MaxMempoolSyncIDs*ShortTxIDsLength
+}
+
+// NewMsgMempoolSync returns a new mempoolsync message keyed with k0 and k1.
+func NewMsgMempoolSync(k0, k1 uint64, reply bool) *MsgMempoolSync {
+	return &MsgMempoolSync{
+		K0:    k0,
+		K1:    k1,
+		Reply: reply,
+	}
+}
@@ -0,0 +1,100 @@
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/copernet/copernicus/util"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestMempoolSync tests the MsgMempoolSync API.
+func TestMempoolSync(t *testing.T) {
+	pver := ProtocolVersion
+
+	msg := NewMsgMempoolSync(1, 2, false)
+	if cmd := msg.Command(); cmd != "mempoolsync" {
+		t.Errorf("NewMsgMempoolSync: wrong command - got %v want %v",
+			cmd, "mempoolsync")
+	}
+
+	wantPayload := uint32(12000026)
+	if maxPayload := msg.MaxPayloadLength(pver); maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	hash := util.HashFromString("000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f")
+	if err := msg.AddShortID(hash); err != nil {
+		t.Fatalf("AddShortID: %v", err)
+	}
+	if msg.ShortIDs[0] != msg.ShortID(hash) || msg.ShortIDs[0]>>48 != 0 {
+		t.Errorf("AddShortID: got short id %x, want the 6 byte id %x",
+			msg.ShortIDs[0], msg.ShortID(hash))
+	}
+	if other := NewMsgMempoolSync(2, 1, false); other.ShortID(hash) == msg.ShortID(hash) {
+		t.Errorf("ShortID: the short id should depend on the keys")
+	}
+
+	msg.ShortIDs = make([]uint64, MaxMempoolSyncIDs)
+	if err := msg.AddShortID(hash); err == nil {
+		t.Errorf("AddShortID: expected error on too many short ids")
+	}
+}
+
+// TestMempoolSyncWire tests the MsgMempoolSync wire encode and decode.
+func TestMempoolSyncWire(t *testing.T) {
+	msg := MsgMempoolSync{
+		K0:       0x0102030405060708,
+		K1:       0x1112131415161718,
+		Reply:    true,
+		ShortIDs: []uint64{0x010203040506, 0xa0b0c0d0e0f0},
+	}
+	msgEncoded := []byte{
+		0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01, // K0
+		0x18, 0x17, 0x16, 0x15, 0x14, 0x13, 0x12, 0x11, // K1
+		0x01,                               // Reply
+		0x02,                               // Varint for number of short ids
+		0x06, 0x05, 0x04, 0x03, 0x02, 0x01, // Short id
+		0xf0, 0xe0, 0xd0, 0xc0, 0xb0, 0xa0, // Short id
+	}
+
+	var buf bytes.Buffer
+	if err := msg.Encode(&buf, ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), msgEncoded) {
+		t.Errorf("Encode: got %s want %s", spew.Sdump(buf.Bytes()),
+			spew.Sdump(msgEncoded))
+	}
+
+	var readMsg MsgMempoolSync
+	if err := readMsg.Decode(bytes.NewReader(msgEncoded), ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !reflect.DeepEqual(&readMsg, &msg) {
+		t.Errorf("Decode: got %s want %s", spew.Sdump(&readMsg),
+			spew.Sdump(&msg))
+	}
+
+	// a count beyond the maximum is refused before allocating
+	tooMany := append([]byte{}, msgEncoded[:17]...)
+	tooMany = append(tooMany, 0xfe, 0xff, 0xff, 0xff, 0xff)
+	if err := readMsg.Decode(bytes.NewReader(tooMany), ProtocolVersion, BaseEncoding); err == nil {
+		t.Errorf("Decode: expected error on too many short ids")
+	}
+
+	// the maximum count without the short ids is refused without allocating
+	// for all of them
+	missing := append([]byte{}, msgEncoded[:17]...)
+	missing = append(missing, 0xfe, 0x80, 0x84, 0x1e, 0x00)
+	if err := readMsg.Decode(bytes.NewReader(missing), ProtocolVersion, BaseEncoding); err == nil {
+		t.Errorf("Decode: expected error on missing short ids")
+	}
+	if cap(readMsg.ShortIDs) > maxMempoolSyncIDsAlloc {
+		t.Errorf("Decode: allocated %d short ids ahead of reading them",
+			cap(readMsg.ShortIDs))
+	}
+}
@@ -99,6 +99,11 @@ const (
 	// collisions and other cases where nodes may be advertising a service they
 	// do not actually support. Other service bits should be allocated via the
 	// BIP process.
+
+	// SFNodeLargeDataCarrier means the node relays transactions with
+	// data-carrier outputs larger than the standard limit to the peers
+	// advertising it as well.  This is a copernicus relay policy experiment.
+	SFNodeLargeDataCarrier ServiceFlag = 1 << 24
 )
 
 // Map of service flags back to their constant names for pretty printing.
@@ -109,6 +114,8 @@ var sfStrings = map[ServiceFlag]string{
 	SFNodeWitness: "SFNodeWitness",
 	SFNodeXthin:   "SFNodeXthin",
 	SFNodeCash:    "SFNodeCash",
+
+	SFNodeLargeDataCarrier: "SFNodeLargeDataCarrier",
 }
 
 // orderedSFStrings is an ordered list of service flags from highest to
@@ -120,6 +127,7 @@ var orderedSFStrings = []ServiceFlag{
 	SFNodeWitness,
 	SFNodeXthin,
 	SFNodeCash,
+	SFNodeLargeDataCarrier,
 }
 
 // String returns the ServiceFlag in human-readable form.
@@ -19,7 +19,8 @@ func TestServiceFlagStringer(t *testing.T) {
 		{SFNodeWitness, "SFNodeWitness"},
 		{SFNodeXthin, "SFNodeXthin"},
 		{SFNodeCash, "SFNodeCash"},
-		{0xffffffff, "SFNodeNetwork|SFNodeGetUTXO|SFNodeBloom|SFNodeWitness|SFNodeXthin|SFNodeCash|0xffffffc0"},
+		{SFNodeLargeDataCarrier, "SFNodeLargeDataCarrier"},
+		{0xffffffff, "SFNodeNetwork|SFNodeGetUTXO|SFNodeBloom|SFNodeWitness|SFNodeXthin|SFNodeCash|SFNodeLargeDataCarrier|0xfeffffc0"},
 	}
 
 	t.Logf("Running %d tests", len(tests))
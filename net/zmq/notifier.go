@@ -0,0 +1,217 @@
+// Package zmq publishes the transactions accepted to the mempool and the
+// blocks connected to the active chain over ZeroMQ PUB sockets, the way
+// bitcoind's -zmqpub* options do, so that indexers and payment processors
+// are pushed the events instead of polling the RPC server.
+//
+// Every message has three frames: the topic, the body, and the sequence
+// number of the message in its topic as a 4 byte little-endian integer, which
+// lets the subscribers detect the messages the sockets dropped.  Mempool
+// transactions dropped while the notifier is behind are never numbered, so
+// they leave no gap.  The topics are:
+//
+//	hashtx     the 32 byte hash of a transaction, in the byte order of the RPCs
+//	rawtx      a serialized transaction
+//	hashblock  the 32 byte hash of a block, in the byte order of the RPCs
+//	rawblock   a serialized block
+//
+// The transactions are published when they enter the mempool and again in
+// the blocks connected.
+package zmq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/persist/disk"
+	"github.com/copernet/copernicus/util"
+)
+
+// The topics of the notifications.
+const (
+	TopicHashTx    = "hashtx"
+	TopicRawTx     = "rawtx"
+	TopicHashBlock = "hashblock"
+	TopicRawBlock  = "rawblock"
+)
+
+// notificationBufferSize is the number of chain notifications queued for the
+// notifier.  The blocks of a dropped tip update are still published with the
+// next one.
+const notificationBufferSize = 10000
+
+// Notifier publishes the notifications of the chain on the configured
+// endpoints.
+type Notifier struct {
+	publishers []*publisher
+	topics     map[string]*publisher
+	sequences  map[string]uint32
+	sub        *chain.Subscription
+	// tip is the tip of the active chain as of the latest blocks published.
+	tip *blockindex.BlockIndex
+	wg  sync.WaitGroup
+}
+
+// NewNotifier binds a publisher to the endpoint of every topic of endpoints,
+// the topics sharing an endpoint sharing the publisher, and publishes the
+// notifications of c on them until Stop.  highWaterMark is the number of
+// messages queued for a subscriber beyond which the new ones are dropped.
+func NewNotifier(c *chain.Chain, endpoints map[string]string, highWaterMark int) (*Notifier, error) {
+	n := &Notifier{
+		topics:    make(map[string]*publisher),
+		sequences: make(map[string]uint32),
+	}
+	byEndpoint := make(map[string]*publisher)
+	for topic, endpoint := range endpoints {
+		if endpoint == "" {
+			continue
+		}
+		p, ok := byEndpoint[endpoint]
+		if !ok {
+			var err error
+			p, err = newPublisher(endpoint, highWaterMark)
+			if err != nil {
+				n.closePublishers()
+				return nil, err
+			}
+			byEndpoint[endpoint] = p
+			n.publishers = append(n.publishers, p)
+		}
+		n.topics[topic] = p
+		log.Info("zmq: publishing %s on %s", topic, endpoint)
+	}
+
+	n.tip = c.Tip()
+	n.sub = c.SubscribeChan(notificationBufferSize, chain.DropNewest,
+		chain.NTChainTipUpdated, chain.NTMempoolTxAdded)
+	n.wg.Add(1)
+	go n.notificationHandler()
+	return n, nil
+}
+
+// Stop stops publishing and unbinds the publishers.
+func (n *Notifier) Stop() {
+	n.sub.Close()
+	n.wg.Wait()
+	n.closePublishers()
+}
+
+func (n *Notifier) closePublishers() {
+	for _, p := range n.publishers {
+		if err := p.Close(); err != nil {
+			log.Error("zmq: closing %s: %v", p.Addr(), err)
+		}
+	}
+}
+
+func (n *Notifier) notificationHandler() {
+	defer n.wg.Done()
+	for notification := range n.sub.Notifications() {
+		switch notification.Type {
+		case chain.NTChainTipUpdated:
+			event, ok := notification.Data.(*chain.TipUpdatedEvent)
+			if !ok || event.TipIndex == nil {
+				continue
+			}
+			n.updateTip(event)
+
+		case chain.NTMempoolTxAdded:
+			if transaction, ok := notification.Data.(*tx.Tx); ok {
+				n.notifyTransaction(transaction)
+			}
+		}
+	}
+}
+
+// updateTip publishes the blocks connected from the last common ancestor of
+// the previous tip and the new one up to the new tip.
+func (n *Notifier) updateTip(event *chain.TipUpdatedEvent) {
+	tip := event.TipIndex
+	fork := blockindex.LastCommonAncestor(n.tip, tip)
+	n.tip = tip
+	// the blocks of the initial download would only flood the subscribers
+	if event.IsInitialDownload {
+		return
+	}
+
+	height := int32(0)
+	if fork != nil {
+		height = fork.Height + 1
+	}
+	for ; height <= tip.Height; height++ {
+		n.notifyBlock(tip.GetAncestor(height))
+	}
+}
+
+func (n *Notifier) notifyBlock(index *blockindex.BlockIndex) {
+	hash := index.GetBlockHash()
+	n.publish(TopicHashBlock, reversed(hash))
+
+	if !n.enabled(TopicRawBlock) && !n.enabled(TopicHashTx) && !n.enabled(TopicRawTx) {
+		return
+	}
+	blk, ok := disk.ReadBlockFromDisk(index, model.ActiveNetParams)
+	if !ok {
+		log.Error("zmq: can't read block %s from disk", hash)
+		return
+	}
+	if n.enabled(TopicRawBlock) {
+		var buf bytes.Buffer
+		buf.Grow(blk.SerializeSize())
+		if err := blk.Serialize(&buf); err != nil {
+			log.Error("zmq: can't serialize block %s: %v", hash, err)
+		} else {
+			n.publish(TopicRawBlock, buf.Bytes())
+		}
+	}
+	for _, transaction := range blk.Txs {
+		n.notifyTransaction(transaction)
+	}
+}
+
+func (n *Notifier) notifyTransaction(transaction *tx.Tx) {
+	hash := transaction.GetHash()
+	if n.enabled(TopicHashTx) {
+		n.publish(TopicHashTx, reversed(&hash))
+	}
+	if n.enabled(TopicRawTx) {
+		var buf bytes.Buffer
+		buf.Grow(int(transaction.SerializeSize()))
+		if err := transaction.Serialize(&buf); err != nil {
+			log.Error("zmq: can't serialize transaction %s: %v", &hash, err)
+			return
+		}
+		n.publish(TopicRawTx, buf.Bytes())
+	}
+}
+
+func (n *Notifier) enabled(topic string) bool {
+	_, ok := n.topics[topic]
+	return ok
+}
+
+// publish publishes body on topic with the next sequence number of the topic.
+func (n *Notifier) publish(topic string, body []byte) {
+	p, ok := n.topics[topic]
+	if !ok {
+		return
+	}
+	var sequence [4]byte
+	binary.LittleEndian.PutUint32(sequence[:], n.sequences[topic])
+	n.sequences[topic]++
+	p.Publish([][]byte{[]byte(topic), body, sequence[:]})
+}
+
+// reversed returns the bytes of hash in the byte order of its hex string.
+func reversed(hash *util.Hash) []byte {
+	b := make([]byte, len(hash))
+	for i := range hash {
+		b[len(hash)-1-i] = hash[i]
+	}
+	return b
+}
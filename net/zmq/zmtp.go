@@ -0,0 +1,455 @@
+package zmq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/copernet/copernicus/log"
+)
+
+// The publisher speaks just enough of ZMTP 3.0, the wire protocol of ZeroMQ,
+// to serve SUB sockets over tcp with the NULL security mechanism, which is
+// all the notifications need, without linking against libzmq.
+const (
+	greetingSize = 64
+
+	flagMore    = 0x01
+	flagLong    = 0x02
+	flagCommand = 0x04
+
+	// maxSubscriptionSize bounds the frames accepted from the subscribers,
+	// which carry nothing but their subscriptions.
+	maxSubscriptionSize = 1 << 16
+
+	handshakeTimeout = 10 * time.Second
+)
+
+var errBadGreeting = errors.New("zmtp: malformed greeting")
+
+// greeting is the greeting of the publisher, version 3.0 with the NULL
+// mechanism, as a server.
+var greeting = func() []byte {
+	g := make([]byte, greetingSize)
+	g[0] = 0xff
+	g[9] = 0x7f
+	g[10] = 3
+	g[11] = 0
+	copy(g[12:32], "NULL")
+	return g
+}()
+
+// publisher is a PUB socket bound to one endpoint.  Like ZeroMQ's, it drops
+// the messages a subscriber doesn't keep up with once highWaterMark of them
+// are queued for it, so that a slow subscriber can't hold up the node.
+type publisher struct {
+	listener      net.Listener
+	highWaterMark int
+
+	mtx         sync.Mutex
+	subscribers map[*subscriber]struct{}
+	closed      bool
+	wg          sync.WaitGroup
+}
+
+// subscriber is a connection of a SUB socket.
+type subscriber struct {
+	conn   net.Conn
+	queue  chan [][]byte
+	quit   chan struct{}
+	once   sync.Once
+	mtx    sync.Mutex
+	topics map[string]int
+}
+
+// listenAddress turns a ZeroMQ endpoint such as tcp://127.0.0.1:28332 into
+// the address to listen on.
+func listenAddress(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "tcp" {
+		return "", fmt.Errorf("unsupported zmq transport %q in %s, only tcp is",
+			u.Scheme, endpoint)
+	}
+	host := u.Host
+	if strings.HasPrefix(host, "*:") {
+		host = host[1:]
+	}
+	return host, nil
+}
+
+// newPublisher binds a publisher to the endpoint and starts accepting
+// subscribers.
+func newPublisher(endpoint string, highWaterMark int) (*publisher, error) {
+	addr, err := listenAddress(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if highWaterMark < 1 {
+		highWaterMark = 1
+	}
+	p := &publisher{
+		listener:      listener,
+		highWaterMark: highWaterMark,
+		subscribers:   make(map[*subscriber]struct{}),
+	}
+	p.wg.Add(1)
+	go p.acceptHandler()
+	return p, nil
+}
+
+// Addr returns the address the publisher listens on.
+func (p *publisher) Addr() net.Addr {
+	return p.listener.Addr()
+}
+
+func (p *publisher) acceptHandler() {
+	defer p.wg.Done()
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			p.mtx.Lock()
+			closed := p.closed
+			p.mtx.Unlock()
+			if !closed {
+				log.Error("zmq: accept on %s failed: %v", p.listener.Addr(), err)
+			}
+			return
+		}
+		p.wg.Add(1)
+		go p.serve(conn)
+	}
+}
+
+// serve runs the handshake with a subscriber, then reads its subscriptions
+// until it goes away.  It is registered from the start, so that Close
+// disconnects it even during the handshake, messages being published to it
+// only once it subscribed.
+func (p *publisher) serve(conn net.Conn) {
+	defer p.wg.Done()
+
+	s := &subscriber{
+		conn:   conn,
+		queue:  make(chan [][]byte, p.highWaterMark),
+		quit:   make(chan struct{}),
+		topics: make(map[string]int),
+	}
+	p.mtx.Lock()
+	if p.closed {
+		p.mtx.Unlock()
+		conn.Close()
+		return
+	}
+	p.subscribers[s] = struct{}{}
+	p.mtx.Unlock()
+	defer p.remove(s)
+
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	r := bufio.NewReader(conn)
+	if err := handshake(conn, r); err != nil {
+		log.Debug("zmq: handshake with %s failed: %v", conn.RemoteAddr(), err)
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	p.wg.Add(1)
+	go p.writeHandler(s)
+
+	err := s.readSubscriptions(r)
+	if err != nil && err != io.EOF {
+		log.Debug("zmq: subscriber %s: %v", conn.RemoteAddr(), err)
+	}
+}
+
+func (p *publisher) remove(s *subscriber) {
+	p.mtx.Lock()
+	delete(p.subscribers, s)
+	p.mtx.Unlock()
+	s.close()
+}
+
+// writeHandler writes the messages queued for a subscriber.
+func (p *publisher) writeHandler(s *subscriber) {
+	defer p.wg.Done()
+	w := bufio.NewWriter(s.conn)
+	for {
+		select {
+		case msg := <-s.queue:
+			err := writeMessage(w, msg)
+			// coalesce the messages already queued into one write
+			for err == nil && len(s.queue) > 0 {
+				err = writeMessage(w, <-s.queue)
+			}
+			if err == nil {
+				err = w.Flush()
+			}
+			if err != nil {
+				p.remove(s)
+				return
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// Publish queues the message, made of its frames, for the subscribers whose
+// subscriptions match its first frame.
+func (p *publisher) Publish(msg [][]byte) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	for s := range p.subscribers {
+		if !s.subscribed(msg[0]) {
+			continue
+		}
+		select {
+		case s.queue <- msg:
+		default:
+		}
+	}
+}
+
+// Close stops accepting subscribers and disconnects the connected ones.
+func (p *publisher) Close() error {
+	p.mtx.Lock()
+	p.closed = true
+	subscribers := make([]*subscriber, 0, len(p.subscribers))
+	for s := range p.subscribers {
+		subscribers = append(subscribers, s)
+	}
+	p.mtx.Unlock()
+
+	err := p.listener.Close()
+	for _, s := range subscribers {
+		s.close()
+	}
+	p.wg.Wait()
+	return err
+}
+
+func (s *subscriber) close() {
+	s.once.Do(func() {
+		close(s.quit)
+		s.conn.Close()
+	})
+}
+
+// subscribed returns whether a subscription of s is a prefix of topic.
+func (s *subscriber) subscribed(topic []byte) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for prefix := range s.topics {
+		if bytes.HasPrefix(topic, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// readSubscriptions applies the subscriptions and cancellations of the
+// subscriber, sent as messages in ZMTP 3.0 and as commands in ZMTP 3.1.
+func (s *subscriber) readSubscriptions(r *bufio.Reader) error {
+	for {
+		flags, body, err := readFrame(r)
+		if err != nil {
+			return err
+		}
+		var subscribe bool
+		var topic []byte
+		if flags&flagCommand != 0 {
+			name, data, err := parseCommand(body)
+			if err != nil {
+				return err
+			}
+			switch name {
+			case "SUBSCRIBE":
+				subscribe = true
+			case "CANCEL":
+			default:
+				continue
+			}
+			topic = data
+		} else {
+			if flags&flagMore != 0 || len(body) == 0 || body[0] > 1 {
+				continue
+			}
+			subscribe = body[0] == 1
+			topic = body[1:]
+		}
+
+		s.mtx.Lock()
+		if subscribe {
+			s.topics[string(topic)]++
+		} else if n := s.topics[string(topic)]; n > 1 {
+			s.topics[string(topic)] = n - 1
+		} else {
+			delete(s.topics, string(topic))
+		}
+		s.mtx.Unlock()
+	}
+}
+
+// handshake exchanges the greetings and the READY commands with a
+// subscriber.
+func handshake(conn net.Conn, r *bufio.Reader) error {
+	if _, err := conn.Write(greeting); err != nil {
+		return err
+	}
+	var peer [greetingSize]byte
+	if _, err := io.ReadFull(r, peer[:]); err != nil {
+		return err
+	}
+	if peer[0] != 0xff || peer[9] != 0x7f {
+		return errBadGreeting
+	}
+	if peer[10] < 3 {
+		return fmt.Errorf("zmtp: unsupported version %d.%d", peer[10], peer[11])
+	}
+	if mechanism := string(bytes.TrimRight(peer[12:32], "\x00")); mechanism != "NULL" {
+		return fmt.Errorf("zmtp: unsupported security mechanism %q", mechanism)
+	}
+
+	var ready bytes.Buffer
+	ready.WriteByte(byte(len("READY")))
+	ready.WriteString("READY")
+	writeProperty(&ready, "Socket-Type", "PUB")
+	w := bufio.NewWriter(conn)
+	if err := writeFrame(w, flagCommand, ready.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	flags, body, err := readFrame(r)
+	if err != nil {
+		return err
+	}
+	if flags&flagCommand == 0 {
+		return errors.New("zmtp: expected the READY command")
+	}
+	name, data, err := parseCommand(body)
+	if err != nil {
+		return err
+	}
+	if name != "READY" {
+		return fmt.Errorf("zmtp: expected the READY command, got %s", name)
+	}
+	properties, err := parseProperties(data)
+	if err != nil {
+		return err
+	}
+	switch socketType := properties["Socket-Type"]; socketType {
+	case "SUB", "XSUB":
+	default:
+		return fmt.Errorf("zmtp: socket type %q can't connect to a PUB socket", socketType)
+	}
+	return nil
+}
+
+func writeProperty(buf *bytes.Buffer, name, value string) {
+	buf.WriteByte(byte(len(name)))
+	buf.WriteString(name)
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(value)))
+	buf.Write(size[:])
+	buf.WriteString(value)
+}
+
+func parseCommand(body []byte) (string, []byte, error) {
+	if len(body) == 0 || len(body) < 1+int(body[0]) {
+		return "", nil, errors.New("zmtp: malformed command")
+	}
+	n := int(body[0])
+	return string(body[1 : 1+n]), body[1+n:], nil
+}
+
+func parseProperties(data []byte) (map[string]string, error) {
+	properties := make(map[string]string)
+	for len(data) > 0 {
+		n := int(data[0])
+		if len(data) < 1+n+4 {
+			return nil, errors.New("zmtp: malformed property")
+		}
+		name := string(data[1 : 1+n])
+		data = data[1+n:]
+		size := binary.BigEndian.Uint32(data)
+		data = data[4:]
+		if uint64(len(data)) < uint64(size) {
+			return nil, errors.New("zmtp: malformed property")
+		}
+		properties[name] = string(data[:size])
+		data = data[size:]
+	}
+	return properties, nil
+}
+
+func readFrame(r *bufio.Reader) (byte, []byte, error) {
+	flags, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	var size uint64
+	if flags&flagLong != 0 {
+		var buf [8]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(buf[:])
+	} else {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		size = uint64(b)
+	}
+	if size > maxSubscriptionSize {
+		return 0, nil, fmt.Errorf("zmtp: frame of %d bytes too large", size)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return flags, body, nil
+}
+
+func writeFrame(w *bufio.Writer, flags byte, body []byte) error {
+	if len(body) > 255 {
+		w.WriteByte(flags | flagLong)
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(len(body)))
+		w.Write(size[:])
+	} else {
+		w.WriteByte(flags)
+		w.WriteByte(byte(len(body)))
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+func writeMessage(w *bufio.Writer, msg [][]byte) error {
+	for i, frame := range msg {
+		var flags byte
+		if i < len(msg)-1 {
+			flags = flagMore
+		}
+		if err := writeFrame(w, flags, frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package zmq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// testSubscriber is a ZMTP 3.0 SUB socket connected to a publisher.
+type testSubscriber struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+func dialSubscriber(t *testing.T, p *publisher, socketType string) *testSubscriber {
+	conn, err := net.Dial("tcp", p.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	s := &testSubscriber{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+
+	s.w.Write(greeting)
+	var ready bytes.Buffer
+	ready.WriteByte(byte(len("READY")))
+	ready.WriteString("READY")
+	writeProperty(&ready, "Socket-Type", socketType)
+	writeFrame(s.w, flagCommand, ready.Bytes())
+	if err := s.w.Flush(); err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+
+	var peer [greetingSize]byte
+	if _, err := io.ReadFull(s.r, peer[:]); err != nil {
+		t.Fatalf("greeting: %v", err)
+	}
+	if !bytes.Equal(peer[:], greeting) {
+		t.Fatalf("unexpected greeting %x", peer)
+	}
+	return s
+}
+
+func (s *testSubscriber) readReady(t *testing.T) {
+	flags, body, err := readFrame(s.r)
+	if err != nil {
+		t.Fatalf("READY: %v", err)
+	}
+	name, data, err := parseCommand(body)
+	if err != nil || flags&flagCommand == 0 || name != "READY" {
+		t.Fatalf("expected READY, got flags %x body %q", flags, body)
+	}
+	properties, err := parseProperties(data)
+	if err != nil || properties["Socket-Type"] != "PUB" {
+		t.Fatalf("unexpected READY properties %v, %v", properties, err)
+	}
+}
+
+func (s *testSubscriber) subscribe(t *testing.T, topic string) {
+	writeFrame(s.w, 0, append([]byte{1}, topic...))
+	if err := s.w.Flush(); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+}
+
+func (s *testSubscriber) readMessage(t *testing.T) [][]byte {
+	var msg [][]byte
+	for {
+		flags, body, err := readFrame(s.r)
+		if err != nil {
+			t.Fatalf("read message: %v", err)
+		}
+		msg = append(msg, body)
+		if flags&flagMore == 0 {
+			return msg
+		}
+	}
+}
+
+// waitSubscribed waits until n subscriptions of the publisher match topic.
+func waitSubscribed(t *testing.T, p *publisher, topic string, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		p.mtx.Lock()
+		matching := 0
+		for s := range p.subscribers {
+			if s.subscribed([]byte(topic)) {
+				matching++
+			}
+		}
+		p.mtx.Unlock()
+		if matching == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("%d subscriber(s) to %s expected", n, topic)
+}
+
+func TestListenAddress(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		addr     string
+		ok       bool
+	}{
+		{"tcp://127.0.0.1:28332", "127.0.0.1:28332", true},
+		{"tcp://*:28332", ":28332", true},
+		{"ipc:///tmp/copernicus", "", false},
+	}
+	for _, test := range tests {
+		addr, err := listenAddress(test.endpoint)
+		if (err == nil) != test.ok || addr != test.addr {
+			t.Errorf("listenAddress(%q) = %q, %v", test.endpoint, addr, err)
+		}
+	}
+}
+
+func TestPublisherFiltersByTopic(t *testing.T) {
+	p, err := newPublisher("tcp://127.0.0.1:0", 10)
+	if err != nil {
+		t.Fatalf("newPublisher: %v", err)
+	}
+	defer p.Close()
+
+	s := dialSubscriber(t, p, "SUB")
+	defer s.conn.Close()
+	s.readReady(t)
+	s.subscribe(t, "hash")
+	waitSubscribed(t, p, "hashblock", 1)
+
+	p.Publish([][]byte{[]byte("rawblock"), {1}, {0, 0, 0, 0}})
+	p.Publish([][]byte{[]byte("hashblock"), {2}, {0, 0, 0, 0}})
+	p.Publish([][]byte{[]byte("hashtx"), bytes.Repeat([]byte{3}, 300), {1, 0, 0, 0}})
+
+	msg := s.readMessage(t)
+	if len(msg) != 3 || string(msg[0]) != "hashblock" || !bytes.Equal(msg[1], []byte{2}) {
+		t.Fatalf("unexpected message %q", msg)
+	}
+	msg = s.readMessage(t)
+	if len(msg) != 3 || string(msg[0]) != "hashtx" || len(msg[1]) != 300 ||
+		binary.LittleEndian.Uint32(msg[2]) != 1 {
+		t.Fatalf("unexpected message %q", msg)
+	}
+}
+
+func TestPublisherSubscribeCommand(t *testing.T) {
+	p, err := newPublisher("tcp://127.0.0.1:0", 10)
+	if err != nil {
+		t.Fatalf("newPublisher: %v", err)
+	}
+	defer p.Close()
+
+	s := dialSubscriber(t, p, "SUB")
+	defer s.conn.Close()
+	s.readReady(t)
+
+	var cmd bytes.Buffer
+	cmd.WriteByte(byte(len("SUBSCRIBE")))
+	cmd.WriteString("SUBSCRIBE")
+	cmd.WriteString("rawtx")
+	writeFrame(s.w, flagCommand, cmd.Bytes())
+	s.w.Flush()
+	waitSubscribed(t, p, "rawtx", 1)
+
+	// a cancellation through a message undoes the subscription
+	writeFrame(s.w, 0, append([]byte{0}, "rawtx"...))
+	s.w.Flush()
+	waitSubscribed(t, p, "rawtx", 0)
+}
+
+func TestPublisherRejectsOtherSocketTypes(t *testing.T) {
+	p, err := newPublisher("tcp://127.0.0.1:0", 10)
+	if err != nil {
+		t.Fatalf("newPublisher: %v", err)
+	}
+	defer p.Close()
+
+	s := dialSubscriber(t, p, "REQ")
+	defer s.conn.Close()
+	s.readReady(t)
+	if _, _, err := readFrame(s.r); err == nil {
+		t.Fatal("a REQ socket was accepted")
+	}
+}
+
+func TestPublisherDropsBeyondHighWaterMark(t *testing.T) {
+	p, err := newPublisher("tcp://127.0.0.1:0", 2)
+	if err != nil {
+		t.Fatalf("newPublisher: %v", err)
+	}
+	defer p.Close()
+
+	// a subscriber registered but not writing holds the queued messages
+	s := &subscriber{
+		queue:  make(chan [][]byte, p.highWaterMark),
+		topics: map[string]int{"": 1},
+	}
+	p.mtx.Lock()
+	p.subscribers[s] = struct{}{}
+	p.mtx.Unlock()
+	for i := 0; i < 5; i++ {
+		p.Publish([][]byte{[]byte("hashtx"), {byte(i)}})
+	}
+	p.mtx.Lock()
+	delete(p.subscribers, s)
+	p.mtx.Unlock()
+
+	if len(s.queue) != 2 {
+		t.Fatalf("%d messages queued, want 2", len(s.queue))
+	}
+	if msg := <-s.queue; msg[1][0] != 0 {
+		t.Fatalf("the oldest messages should be kept, got %d", msg[1][0])
+	}
+}
+
+func TestNotifierSequences(t *testing.T) {
+	p, err := newPublisher("tcp://127.0.0.1:0", 10)
+	if err != nil {
+		t.Fatalf("newPublisher: %v", err)
+	}
+	defer p.Close()
+	n := &Notifier{
+		topics:    map[string]*publisher{TopicHashTx: p, TopicHashBlock: p},
+		sequences: make(map[string]uint32),
+	}
+
+	s := dialSubscriber(t, p, "SUB")
+	defer s.conn.Close()
+	s.readReady(t)
+	s.subscribe(t, "")
+	waitSubscribed(t, p, "", 1)
+
+	n.publish(TopicHashTx, []byte{1})
+	n.publish(TopicRawTx, []byte{2})
+	n.publish(TopicHashBlock, []byte{3})
+	n.publish(TopicHashTx, []byte{4})
+
+	want := []struct {
+		topic    string
+		sequence uint32
+	}{{TopicHashTx, 0}, {TopicHashBlock, 0}, {TopicHashTx, 1}}
+	for _, w := range want {
+		msg := s.readMessage(t)
+		if string(msg[0]) != w.topic || binary.LittleEndian.Uint32(msg[2]) != w.sequence {
+			t.Fatalf("got %s #%d, want %s #%d", msg[0],
+				binary.LittleEndian.Uint32(msg[2]), w.topic, w.sequence)
+		}
+	}
+}
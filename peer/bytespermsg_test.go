@@ -0,0 +1,62 @@
+package peer
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/copernet/copernicus/net/wire"
+)
+
+func TestBytesPerMsg(t *testing.T) {
+	sender := newPeerBase(&Config{}, false)
+	receiver := newPeerBase(&Config{}, true)
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	sender.conn, receiver.conn = c1, c2
+
+	errs := make(chan error, 1)
+	go func() { errs <- sender.writeMessage(wire.NewMsgPing(1), wire.BaseEncoding) }()
+	if _, _, err := receiver.readMessage(wire.BaseEncoding); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	// an unknown command is read up to its header and accounted as other
+	header := make([]byte, wire.MessageHeaderSize)
+	binary.LittleEndian.PutUint32(header, uint32(receiver.Cfg.ChainParams.BitcoinNet))
+	copy(header[4:], "nosuchcmd")
+	go func() {
+		_, err := c1.Write(header)
+		errs <- err
+	}()
+	if _, _, err := receiver.readMessage(wire.BaseEncoding); err == nil {
+		t.Fatal("read a message of an unknown command")
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	// a ping is a header and an 8 byte nonce
+	pingSize := uint64(wire.MessageHeaderSize + 8)
+	sent := sender.StatsSnapshot().MapSendBytesPerMsgCmd
+	if len(sent) != 1 || sent[wire.CmdPing] != pingSize {
+		t.Errorf("sent %v, expect %d bytes of ping", sent, pingSize)
+	}
+	recv := receiver.StatsSnapshot().MapRecvBytesPerMsgCmd
+	if len(recv) != 2 || recv[wire.CmdPing] != pingSize || recv[OtherMsgCmd] != wire.MessageHeaderSize {
+		t.Errorf("received %v, expect %d bytes of ping and %d of other", recv, pingSize, wire.MessageHeaderSize)
+	}
+	if recv := sender.StatsSnapshot().MapRecvBytesPerMsgCmd; len(recv) != 0 {
+		t.Errorf("the sender received %v", recv)
+	}
+
+	// the snapshot is a copy
+	sent[wire.CmdPing] = 0
+	if sender.StatsSnapshot().MapSendBytesPerMsgCmd[wire.CmdPing] != pingSize {
+		t.Error("the snapshot shares the accounting of the peer")
+	}
+}
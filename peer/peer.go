@@ -140,6 +140,9 @@ type MessageListeners struct {
 	// OnMemPool is invoked when a peer receives a mempool bitcoin message.
 	OnMemPool func(p *Peer, msg *wire.MsgMemPool)
 
+	// OnMempoolSync is invoked when a peer receives a mempoolsync message.
+	OnMempoolSync func(p *Peer, msg *wire.MsgMempoolSync)
+
 	// OnTx is invoked when a peer receives a tx bitcoin message.
 	OnTx func(p *Peer, msg *wire.MsgTx, done chan<- struct{})
 
@@ -374,13 +377,8 @@ type StatsSnap struct {
 	LastPingNonce         uint64
 	LastPingTime          time.Time
 	LastPingMicros        int64
+	MinPingMicros         int64
 	AddNode               bool
-	MingPing              float64
-	PingWait              float64
-	SyncedHeaders         int
-	SyncedBlocks          int
-	Inflight              []int
-	WhiteListed           bool
 	UsesCashMagic         bool
 	MapSendBytesPerMsgCmd map[string]uint64
 	MapRecvBytesPerMsgCmd map[string]uint64
@@ -474,6 +472,12 @@ type Peer struct {
 	lastPingNonce      uint64    // Set to nonce if we have a pending ping.
 	lastPingTime       time.Time // Time we sent last ping.
 	lastPingMicros     int64     // Time for last ping to return.
+	minPingMicros      int64     // Lowest ping time seen, 0 if none.
+
+	// bytesPerMsgMtx protects the bandwidth accounting per message command.
+	bytesPerMsgMtx  sync.Mutex
+	bytesSentPerMsg map[string]uint64
+	bytesRecvPerMsg map[string]uint64
 
 	stallControl      chan stallControlMsg
 	outputQueue       chan outMsg
@@ -558,13 +562,45 @@ func (p *Peer) StatsSnapshot() *StatsSnap {
 		LastBlock:      p.lastBlock,
 		LastPingNonce:  p.lastPingNonce,
 		LastPingMicros: p.lastPingMicros,
+		MinPingMicros:  p.minPingMicros,
 		LastPingTime:   p.lastPingTime,
 	}
 
 	p.statsMtx.RUnlock()
+
+	p.bytesPerMsgMtx.Lock()
+	statsSnap.MapSendBytesPerMsgCmd = copyBytesPerMsg(p.bytesSentPerMsg)
+	statsSnap.MapRecvBytesPerMsgCmd = copyBytesPerMsg(p.bytesRecvPerMsg)
+	p.bytesPerMsgMtx.Unlock()
 	return statsSnap
 }
 
+// OtherMsgCmd accounts the bytes of messages whose command is unknown, such
+// as messages which could not be decoded.
+const OtherMsgCmd = "*other*"
+
+// addBytesPerMsg accounts n bytes sent or received for the command of msg.
+func (p *Peer) addBytesPerMsg(perMsg map[string]uint64, msg wire.Message, n int) {
+	if n == 0 {
+		return
+	}
+	cmd := OtherMsgCmd
+	if msg != nil {
+		cmd = msg.Command()
+	}
+	p.bytesPerMsgMtx.Lock()
+	perMsg[cmd] += uint64(n)
+	p.bytesPerMsgMtx.Unlock()
+}
+
+func copyBytesPerMsg(perMsg map[string]uint64) map[string]uint64 {
+	perMsgCopy := make(map[string]uint64, len(perMsg))
+	for cmd, n := range perMsg {
+		perMsgCopy[cmd] = n
+	}
+	return perMsgCopy
+}
+
 // ID returns the peer id.
 //
 // This function is safe for concurrent access.
@@ -669,6 +705,18 @@ func (p *Peer) LastPingMicros() int64 {
 	return lastPingMicros
 }
 
+// MinPingMicros returns the lowest ping time of the remote peer in
+// microseconds, or 0 if no ping was answered yet.
+//
+// This function is safe for concurrent access.
+func (p *Peer) MinPingMicros() int64 {
+	p.statsMtx.RLock()
+	minPingMicros := p.minPingMicros
+	p.statsMtx.RUnlock()
+
+	return minPingMicros
+}
+
 // VersionKnown returns the whether or not the version of a peer is known
 // locally.
 //
@@ -1160,6 +1208,9 @@ func (p *Peer) HandlePongMsg(msg *wire.MsgPong) {
 			p.lastPingMicros = time.Since(p.lastPingTime).Nanoseconds()
 			p.lastPingMicros /= 1000 // convert to usec.
 			p.lastPingNonce = 0
+			if p.minPingMicros == 0 || p.lastPingMicros < p.minPingMicros {
+				p.minPingMicros = p.lastPingMicros
+			}
 		}
 		p.statsMtx.Unlock()
 	}
@@ -1177,6 +1228,7 @@ func (p *Peer) readMessage(encoding wire.MessageEncoding) (wire.Message, []byte,
 	n, msg, buf, err := wire.ReadMessageWithEncodingN(p.conn,
 		p.ProtocolVersion(), p.Cfg.ChainParams.BitcoinNet, encoding)
 	atomic.AddUint64(&p.bytesReceived, uint64(n))
+	p.addBytesPerMsg(p.bytesRecvPerMsg, msg, n)
 	if p.Cfg.Listeners.OnRead != nil {
 		p.Cfg.Listeners.OnRead(p, n, msg, err)
 	}
@@ -1238,6 +1290,7 @@ func (p *Peer) writeMessage(msg wire.Message, enc wire.MessageEncoding) error {
 	n, err := wire.WriteMessageWithEncodingN(p.conn, msg,
 		p.ProtocolVersion(), p.Cfg.ChainParams.BitcoinNet, enc)
 	atomic.AddUint64(&p.bytesSent, uint64(n))
+	p.addBytesPerMsg(p.bytesSentPerMsg, msg, n)
 	if p.Cfg.Listeners.OnWrite != nil {
 		p.Cfg.Listeners.OnWrite(p, n, msg, err)
 	}
@@ -2050,6 +2103,8 @@ func newPeerBase(origCfg *Config, inbound bool) *Peer {
 		inbound:         inbound,
 		wireEncoding:    wire.BaseEncoding,
 		knownInventory:  newMruInventoryMap(maxKnownInventory),
+		bytesSentPerMsg: make(map[string]uint64),
+		bytesRecvPerMsg: make(map[string]uint64),
 		stallControl:    make(chan stallControlMsg, 1), // nonblocking sync
 		outputQueue:     make(chan outMsg, outputBufferSize),
 		sendQueue:       make(chan outMsg, 1),   // nonblocking sync
@@ -0,0 +1,173 @@
+package blkdb
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+)
+
+// Address types of the address index keys, as used by the addressindex of
+// Bitcoin Unlimited and bitcore.
+const (
+	AddressTypePubKeyHash byte = 1
+	AddressTypeScriptHash byte = 2
+)
+
+// AddressKey identifies an indexed address by its type and hash160.
+type AddressKey struct {
+	Type    byte
+	Hash160 [script.Hash160BytesLength]byte
+}
+
+// AddressDelta is a change of an address balance: a transaction output paying
+// to it, or an input spending such an output.
+type AddressDelta struct {
+	Address  AddressKey
+	Height   int32
+	TxHash   util.Hash
+	Index    uint32 // output index, or input index when spending
+	Spending bool
+	Amount   amount.Amount // negative when spending
+}
+
+// AddressUnspent is an unspent output paying to an indexed address.
+type AddressUnspent struct {
+	Address      AddressKey
+	TxHash       util.Hash
+	Index        uint32
+	Amount       amount.Amount
+	ScriptPubKey []byte
+	Height       int32
+}
+
+// The address index key is DbAddressIndex, type, hash160, height (big
+// endian), txid, index and the spending flag, so that the deltas of an address
+// are iterated in height order. The unspent index key is
+// DbAddressUnspentIndex, type, hash160, txid and index.
+func (a *AddressKey) appendTo(key []byte) []byte {
+	key = append(key, a.Type)
+	return append(key, a.Hash160[:]...)
+}
+
+func addressDeltaKey(d *AddressDelta) []byte {
+	key := make([]byte, 0, 63)
+	key = d.Address.appendTo(append(key, db.DbAddressIndex))
+	key = appendUint32BE(key, uint32(d.Height))
+	key = append(key, d.TxHash[:]...)
+	key = appendUint32BE(key, d.Index)
+	if d.Spending {
+		return append(key, 1)
+	}
+	return append(key, 0)
+}
+
+func addressUnspentKey(u *AddressUnspent) []byte {
+	key := make([]byte, 0, 58)
+	key = u.Address.appendTo(append(key, db.DbAddressUnspentIndex))
+	key = append(key, u.TxHash[:]...)
+	return appendUint32BE(key, u.Index)
+}
+
+func appendUint32BE(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// UpdateAddressIndex applies the address index changes of a connected or
+// disconnected block in one batch.
+func (blockTreeDB *BlockTreeDB) UpdateAddressIndex(addDeltas, eraseDeltas []*AddressDelta,
+	addUnspents, eraseUnspents []*AddressUnspent) error {
+	if err := blockTreeDB.markIndexDirty(AddressIndexName); err != nil {
+		return err
+	}
+	batch := db.NewBatchWrapper(blockTreeDB.dbw)
+	valueBuf := bytes.NewBuffer(make([]byte, 0, 100))
+	for _, d := range eraseDeltas {
+		batch.Erase(addressDeltaKey(d))
+	}
+	for _, u := range eraseUnspents {
+		batch.Erase(addressUnspentKey(u))
+	}
+	for _, d := range addDeltas {
+		valueBuf.Reset()
+		if err := util.WriteElements(valueBuf, int64(d.Amount)); err != nil {
+			return err
+		}
+		batch.Write(addressDeltaKey(d), valueBuf.Bytes())
+	}
+	for _, u := range addUnspents {
+		valueBuf.Reset()
+		if err := util.WriteElements(valueBuf, int64(u.Amount), u.Height); err != nil {
+			return err
+		}
+		if err := util.WriteVarBytes(valueBuf, u.ScriptPubKey); err != nil {
+			return err
+		}
+		batch.Write(addressUnspentKey(u), valueBuf.Bytes())
+	}
+	return blockTreeDB.dbw.WriteBatch(batch, false)
+}
+
+// ReadAddressDeltas returns the deltas of an address with a height in
+// [start, end], in height order. An end of 0 means no upper bound.
+func (blockTreeDB *BlockTreeDB) ReadAddressDeltas(address *AddressKey, start, end int32) ([]*AddressDelta, error) {
+	prefix := address.appendTo([]byte{db.DbAddressIndex})
+	cursor := blockTreeDB.dbw.Prefix(prefix)
+	defer cursor.Close()
+	cursor.Seek(appendUint32BE(append([]byte{}, prefix...), uint32(start)))
+
+	deltas := make([]*AddressDelta, 0)
+	for ; cursor.Valid(); cursor.Next() {
+		k := cursor.GetKey()
+		d := &AddressDelta{Address: *address, Spending: k[len(k)-1] == 1}
+		k = k[len(prefix):]
+		d.Height = int32(binary.BigEndian.Uint32(k))
+		if end > 0 && d.Height > end {
+			break
+		}
+		copy(d.TxHash[:], k[4:4+util.Hash256Size])
+		d.Index = binary.BigEndian.Uint32(k[4+util.Hash256Size:])
+
+		var value int64
+		if err := util.ReadElements(bytes.NewReader(cursor.GetVal()), &value); err != nil {
+			return nil, err
+		}
+		d.Amount = amount.Amount(value)
+		deltas = append(deltas, d)
+	}
+	return deltas, nil
+}
+
+// ReadAddressUnspents returns the unspent outputs paying to an address.
+func (blockTreeDB *BlockTreeDB) ReadAddressUnspents(address *AddressKey) ([]*AddressUnspent, error) {
+	prefix := address.appendTo([]byte{db.DbAddressUnspentIndex})
+	cursor := blockTreeDB.dbw.Prefix(prefix)
+	defer cursor.Close()
+
+	unspents := make([]*AddressUnspent, 0)
+	for cursor.SeekToFirst(); cursor.Valid(); cursor.Next() {
+		k := cursor.GetKey()[len(prefix):]
+		u := &AddressUnspent{Address: *address}
+		copy(u.TxHash[:], k[:util.Hash256Size])
+		u.Index = binary.BigEndian.Uint32(k[util.Hash256Size:])
+
+		r := bytes.NewReader(cursor.GetVal())
+		var value int64
+		if err := util.ReadElements(r, &value, &u.Height); err != nil {
+			return nil, err
+		}
+		u.Amount = amount.Amount(value)
+		scriptPubKey, err := util.ReadVarBytes(r, script.MaxScriptSize, "scriptPubKey")
+		if err != nil {
+			return nil, err
+		}
+		u.ScriptPubKey = scriptPubKey
+		unspents = append(unspents, u)
+	}
+	return unspents, nil
+}
@@ -0,0 +1,55 @@
+package blkdb
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/util"
+)
+
+func TestAddressIndex(t *testing.T) {
+	initBlockDB()
+	addr := AddressKey{Type: AddressTypePubKeyHash, Hash160: [20]byte{1}}
+	other := AddressKey{Type: AddressTypeScriptHash, Hash160: [20]byte{1}}
+
+	deltas := []*AddressDelta{
+		{Address: addr, Height: 300, TxHash: util.HashOne, Index: 0, Amount: 100},
+		{Address: addr, Height: 2, TxHash: util.HashOne, Index: 1, Amount: 7},
+		{Address: addr, Height: 300, TxHash: util.HashOne, Index: 0, Spending: true, Amount: -7},
+		{Address: other, Height: 5, TxHash: util.HashOne, Index: 0, Amount: 9},
+	}
+	unspents := []*AddressUnspent{
+		{Address: addr, TxHash: util.HashOne, Index: 0, Amount: 100, ScriptPubKey: []byte{0x51}, Height: 300},
+		{Address: addr, TxHash: util.HashOne, Index: 1, Amount: 7, Height: 2},
+	}
+	if err := GetInstance().UpdateAddressIndex(deltas, nil, unspents, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := GetInstance().ReadAddressDeltas(&addr, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Height != 2 || got[1].Spending || !got[2].Spending || got[2].Amount != -7 {
+		t.Errorf("deltas are not read back in height order: %v", got)
+	}
+	got, err = GetInstance().ReadAddressDeltas(&addr, 3, 299)
+	if err != nil || len(got) != 0 {
+		t.Errorf("no delta expected in range, got %v, %v", got, err)
+	}
+
+	// spending the first output in a later block
+	if err := GetInstance().UpdateAddressIndex(nil, nil, nil, unspents[:1]); err != nil {
+		t.Fatal(err)
+	}
+	utxos, err := GetInstance().ReadAddressUnspents(&addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(utxos) != 1 || utxos[0].Index != 1 || utxos[0].Amount != 7 || utxos[0].Height != 2 {
+		t.Errorf("unexpected unspent outputs %v", utxos)
+	}
+	utxos, err = GetInstance().ReadAddressUnspents(&other)
+	if err != nil || len(utxos) != 0 {
+		t.Errorf("no unspent output expected, got %v, %v", utxos, err)
+	}
+}
@@ -2,6 +2,9 @@ package blkdb
 
 import (
 	"bytes"
+	"sync"
+
+	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/model/blockindex"
 	"github.com/copernet/copernicus/persist/db"
@@ -16,10 +19,33 @@ import (
 
 type BlockTreeDB struct {
 	dbw *db.DBWrapper
+
+	// dirtyIndexes holds the optional indexes written since the coins were
+	// last flushed, whose completeness mark is dropped until the next flush.
+	indexLock    sync.Mutex
+	dirtyIndexes map[string]bool
 }
 
 var blockTreeDb *BlockTreeDB
 
+// indexFlagPrefix keeps the index flags apart from the WriteFlag ones.
+const indexFlagPrefix = "index:"
+
+// Names of the optional indexes.
+const (
+	TxIndexName      = "txindex"
+	AddressIndexName = "addressindex"
+	SpentIndexName   = "spentindex"
+)
+
+// SchemaVersion is the version of the block tree layout written by this
+// release. Bump it together with a new entry of migrations when the layout
+// changes.
+const SchemaVersion uint32 = 1
+
+// migrations upgrade block tree databases of older schema versions.
+var migrations []db.Migration
+
 type BlockTreeDBConfig struct {
 	Do *db.DBOption
 }
@@ -43,6 +69,9 @@ func newBlockTreeDB(do *db.DBOption) *BlockTreeDB {
 	if err != nil {
 		panic("init DBWrapper failed..." + err.Error())
 	}
+	if err := dbw.Upgrade(SchemaVersion, migrations); err != nil {
+		panic("upgrade block tree DB failed..." + err.Error())
+	}
 	return &BlockTreeDB{
 		dbw: dbw,
 	}
@@ -166,6 +195,9 @@ func (blockTreeDB *BlockTreeDB) ReadTxIndex(txid *util.Hash) (*block.DiskTxPos,
 	tmp = append(tmp, db.DbTxIndex)
 	tmp = append(tmp, txid[:]...)
 	vdata, err := blockTreeDB.dbw.Read(tmp)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
 	if err != nil {
 		log.Error("Error: ReadTxIndex======%#v", err)
 		panic("Error: ReadTxIndex======")
@@ -179,6 +211,9 @@ func (blockTreeDB *BlockTreeDB) ReadTxIndex(txid *util.Hash) (*block.DiskTxPos,
 }
 
 func (blockTreeDB *BlockTreeDB) WriteTxIndex(txIndexes map[util.Hash]block.DiskTxPos) error {
+	if err := blockTreeDB.markIndexDirty(TxIndexName); err != nil {
+		return err
+	}
 	var batch = db.NewBatchWrapper(blockTreeDB.dbw)
 	keytmp := make([]byte, 0, 100)
 	valuetmp := make([]byte, 0, 100)
@@ -205,6 +240,110 @@ func (blockTreeDB *BlockTreeDB) WriteTxIndex(txIndexes map[util.Hash]block.DiskT
 	return blockTreeDB.dbw.WriteBatch(batch, false)
 }
 
+// WriteIndexComplete records whether the optional index called name covers
+// the whole active chain. It is cleared when the index is disabled, so that
+// enabling it again rebuilds the entries of the blocks connected in between.
+func (blockTreeDB *BlockTreeDB) WriteIndexComplete(name string, complete bool) error {
+	blockTreeDB.indexLock.Lock()
+	defer blockTreeDB.indexLock.Unlock()
+	delete(blockTreeDB.dirtyIndexes, name)
+	return blockTreeDB.writeIndexComplete(name, complete)
+}
+
+func (blockTreeDB *BlockTreeDB) writeIndexComplete(name string, complete bool) error {
+	key := append([]byte{db.DbFlag}, indexFlagPrefix+name...)
+	if complete {
+		return blockTreeDB.dbw.Write(key, []byte{'1'}, true)
+	}
+	return blockTreeDB.dbw.Erase(key, true)
+}
+
+func (blockTreeDB *BlockTreeDB) ReadIndexComplete(name string) bool {
+	return blockTreeDB.dbw.Exists(append([]byte{db.DbFlag}, indexFlagPrefix+name...))
+}
+
+// markIndexDirty drops the completeness mark of the index called name before
+// its first write since the coins were last flushed. The index entries of a
+// block are written when it is connected, the coins only when they are
+// flushed, so a crash in between rebuilds the index at startup rather than
+// leaving it out of step with the coins. An index being built has no mark.
+func (blockTreeDB *BlockTreeDB) markIndexDirty(name string) error {
+	blockTreeDB.indexLock.Lock()
+	defer blockTreeDB.indexLock.Unlock()
+	if blockTreeDB.dirtyIndexes[name] || !blockTreeDB.ReadIndexComplete(name) {
+		return nil
+	}
+	if err := blockTreeDB.writeIndexComplete(name, false); err != nil {
+		return err
+	}
+	if blockTreeDB.dirtyIndexes == nil {
+		blockTreeDB.dirtyIndexes = make(map[string]bool)
+	}
+	blockTreeDB.dirtyIndexes[name] = true
+	return nil
+}
+
+// IndexesFlushed marks the indexes written since the last flush complete
+// again. It is called once the coins they match are flushed; the synced mark
+// also syncs the index entries written before it.
+func (blockTreeDB *BlockTreeDB) IndexesFlushed() error {
+	blockTreeDB.indexLock.Lock()
+	defer blockTreeDB.indexLock.Unlock()
+	for name := range blockTreeDB.dirtyIndexes {
+		if err := blockTreeDB.writeIndexComplete(name, true); err != nil {
+			return err
+		}
+		delete(blockTreeDB.dirtyIndexes, name)
+	}
+	return nil
+}
+
+// indexPrefixes lists the key prefixes of the entries of each optional index.
+var indexPrefixes = map[string][]byte{
+	TxIndexName:      {db.DbTxIndex},
+	AddressIndexName: {db.DbAddressIndex, db.DbAddressUnspentIndex},
+	SpentIndexName:   {db.DbSpentIndex},
+}
+
+// wipeBatchSize bounds the size of the batches erasing an index.
+const wipeBatchSize = 16 << 20
+
+// WipeIndex erases every entry of the optional index called name together
+// with its completeness mark, leaving the rest of the block tree untouched.
+func (blockTreeDB *BlockTreeDB) WipeIndex(name string) error {
+	prefixes, ok := indexPrefixes[name]
+	if !ok {
+		return errcode.New(errcode.ErrorUnknownIndex)
+	}
+	// drop the mark first, so that an interrupted wipe still rebuilds
+	if err := blockTreeDB.WriteIndexComplete(name, false); err != nil {
+		return err
+	}
+	for _, prefix := range prefixes {
+		if err := blockTreeDB.erasePrefix(prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (blockTreeDB *BlockTreeDB) erasePrefix(prefix byte) error {
+	cursor := blockTreeDB.dbw.Prefix([]byte{prefix})
+	defer cursor.Close()
+
+	batch := db.NewBatchWrapper(blockTreeDB.dbw)
+	for cursor.SeekToFirst(); cursor.Valid(); cursor.Next() {
+		batch.Erase(cursor.GetKey())
+		if batch.SizeEstimate() > wipeBatchSize {
+			if err := blockTreeDB.dbw.WriteBatch(batch, false); err != nil {
+				return err
+			}
+			batch.Clear()
+		}
+	}
+	return blockTreeDB.dbw.WriteBatch(batch, false)
+}
+
 func (blockTreeDB *BlockTreeDB) WriteFlag(name string, value bool) error {
 	tmp := make([]byte, 0, 100)
 	tmp = append(tmp, db.DbFlag)
@@ -10,6 +10,7 @@ import (
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/block"
 	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/outpoint"
 	"github.com/copernet/copernicus/persist/db"
 	"github.com/copernet/copernicus/util"
 )
@@ -243,3 +244,127 @@ func TestWriteBatchSync(t *testing.T) {
 		t.Error("the blkidxMap should equal blkidx, please check")
 	}
 }
+
+func TestIndexComplete(t *testing.T) {
+	initBlockDB()
+	txpos, err := GetInstance().ReadTxIndex(&util.HashOne)
+	if err != nil || txpos != nil {
+		t.Errorf("unindexed tx should not be found: %v, %v", txpos, err)
+	}
+
+	if GetInstance().ReadIndexComplete(TxIndexName) {
+		t.Error("a new tx index should not be complete")
+	}
+	if err := GetInstance().WriteIndexComplete(TxIndexName, true); err != nil {
+		t.Fatal(err)
+	}
+	if !GetInstance().ReadIndexComplete(TxIndexName) {
+		t.Error("the tx index should be complete")
+	}
+	if err := GetInstance().WriteIndexComplete(TxIndexName, false); err != nil {
+		t.Fatal(err)
+	}
+	if GetInstance().ReadIndexComplete(TxIndexName) {
+		t.Error("the tx index should not be complete after clearing it")
+	}
+}
+
+func TestIndexDirtyUntilFlushed(t *testing.T) {
+	initBlockDB()
+	txindexs := map[util.Hash]block.DiskTxPos{util.HashOne: *block.NewDiskTxPos(block.NewDiskBlockPos(1, 8), 1)}
+
+	// an index being built keeps no mark
+	if err := GetInstance().WriteTxIndex(txindexs); err != nil {
+		t.Fatal(err)
+	}
+	if err := GetInstance().IndexesFlushed(); err != nil {
+		t.Fatal(err)
+	}
+	if GetInstance().ReadIndexComplete(TxIndexName) {
+		t.Error("a flush should not complete an index being built")
+	}
+
+	indexes := []string{TxIndexName, AddressIndexName, SpentIndexName}
+	for _, name := range indexes {
+		if err := GetInstance().WriteIndexComplete(name, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := GetInstance().WriteTxIndex(txindexs); err != nil {
+		t.Fatal(err)
+	}
+	if GetInstance().ReadIndexComplete(TxIndexName) {
+		t.Error("the tx index written ahead of the coins should not be complete")
+	}
+	if !GetInstance().ReadIndexComplete(AddressIndexName) {
+		t.Error("the unwritten address index should stay complete")
+	}
+	if err := GetInstance().UpdateAddressIndex(nil, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if GetInstance().ReadIndexComplete(AddressIndexName) {
+		t.Error("the address index written ahead of the coins should not be complete")
+	}
+	if err := GetInstance().UpdateSpentIndex(nil, []*outpoint.OutPoint{outpoint.NewOutPoint(util.HashOne, 0)}); err != nil {
+		t.Fatal(err)
+	}
+	if GetInstance().ReadIndexComplete(SpentIndexName) {
+		t.Error("the spent index written ahead of the coins should not be complete")
+	}
+
+	if err := GetInstance().IndexesFlushed(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range indexes {
+		if !GetInstance().ReadIndexComplete(name) {
+			t.Errorf("the %s should be complete once the coins are flushed", name)
+		}
+	}
+
+	// an index wiped before the flush stays incomplete
+	if err := GetInstance().WriteTxIndex(txindexs); err != nil {
+		t.Fatal(err)
+	}
+	if err := GetInstance().WipeIndex(TxIndexName); err != nil {
+		t.Fatal(err)
+	}
+	if err := GetInstance().IndexesFlushed(); err != nil {
+		t.Fatal(err)
+	}
+	if GetInstance().ReadIndexComplete(TxIndexName) {
+		t.Error("a flush should not complete a wiped index")
+	}
+}
+
+func TestWipeIndex(t *testing.T) {
+	initBlockDB()
+	txindexs := map[util.Hash]block.DiskTxPos{util.HashOne: *block.NewDiskTxPos(block.NewDiskBlockPos(1, 8), 1)}
+	if err := GetInstance().WriteTxIndex(txindexs); err != nil {
+		t.Fatal(err)
+	}
+	if err := GetInstance().WriteIndexComplete(TxIndexName, true); err != nil {
+		t.Fatal(err)
+	}
+	out := outpoint.NewOutPoint(util.HashOne, 0)
+	spender := &SpentInfo{TxHash: util.HashOne, Index: 1, Height: 2}
+	if err := GetInstance().UpdateSpentIndex(map[outpoint.OutPoint]*SpentInfo{*out: spender}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := GetInstance().WipeIndex(TxIndexName); err != nil {
+		t.Fatal(err)
+	}
+	if txpos, err := GetInstance().ReadTxIndex(&util.HashOne); err != nil || txpos != nil {
+		t.Errorf("wiped tx index entry should be gone: %v, %v", txpos, err)
+	}
+	if GetInstance().ReadIndexComplete(TxIndexName) {
+		t.Error("a wiped index should not be complete")
+	}
+	if info, err := GetInstance().ReadSpentIndex(out); err != nil || info == nil {
+		t.Errorf("wiping the tx index should keep the spent index: %v, %v", info, err)
+	}
+
+	if err := GetInstance().WipeIndex("filters"); err == nil {
+		t.Error("wiping an unknown index should fail")
+	}
+}
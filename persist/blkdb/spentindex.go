@@ -0,0 +1,63 @@
+package blkdb
+
+import (
+	"bytes"
+
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/copernet/copernicus/util"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// SpentInfo tells which input of which transaction spent an output.
+type SpentInfo struct {
+	TxHash util.Hash
+	Index  uint32 // input index in the spending transaction
+	Height int32
+}
+
+// The spent index key is DbSpentIndex, txid and output index (big endian).
+func spentIndexKey(out *outpoint.OutPoint) []byte {
+	key := make([]byte, 0, 37)
+	key = append(key, db.DbSpentIndex)
+	key = append(key, out.Hash[:]...)
+	return appendUint32BE(key, out.Index)
+}
+
+// UpdateSpentIndex records the outputs spent by a connected block and erases
+// those of a disconnected block in one batch.
+func (blockTreeDB *BlockTreeDB) UpdateSpentIndex(add map[outpoint.OutPoint]*SpentInfo, erase []*outpoint.OutPoint) error {
+	if err := blockTreeDB.markIndexDirty(SpentIndexName); err != nil {
+		return err
+	}
+	batch := db.NewBatchWrapper(blockTreeDB.dbw)
+	valueBuf := bytes.NewBuffer(make([]byte, 0, 40))
+	for i := range erase {
+		batch.Erase(spentIndexKey(erase[i]))
+	}
+	for out, info := range add {
+		valueBuf.Reset()
+		if err := util.WriteElements(valueBuf, &info.TxHash, info.Index, info.Height); err != nil {
+			return err
+		}
+		batch.Write(spentIndexKey(&out), valueBuf.Bytes())
+	}
+	return blockTreeDB.dbw.WriteBatch(batch, false)
+}
+
+// ReadSpentIndex returns how an output was spent, or nil if it was not spent
+// in the active chain.
+func (blockTreeDB *BlockTreeDB) ReadSpentIndex(out *outpoint.OutPoint) (*SpentInfo, error) {
+	value, err := blockTreeDB.dbw.Read(spentIndexKey(out))
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	info := &SpentInfo{}
+	if err := util.ReadElements(bytes.NewReader(value), &info.TxHash, &info.Index, &info.Height); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
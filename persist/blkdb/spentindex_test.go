@@ -0,0 +1,37 @@
+package blkdb
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/util"
+)
+
+func TestSpentIndex(t *testing.T) {
+	initBlockDB()
+	out := outpoint.NewOutPoint(util.HashOne, 2)
+	info, err := GetInstance().ReadSpentIndex(out)
+	if err != nil || info != nil {
+		t.Fatalf("unspent output should not be found: %v, %v", info, err)
+	}
+
+	spender := &SpentInfo{TxHash: *util.HashFromString("01"), Index: 1, Height: 42}
+	add := map[outpoint.OutPoint]*SpentInfo{*out: spender}
+	if err := GetInstance().UpdateSpentIndex(add, nil); err != nil {
+		t.Fatal(err)
+	}
+	info, err = GetInstance().ReadSpentIndex(out)
+	if err != nil || info == nil || *info != *spender {
+		t.Errorf("got spent info %v, %v, want %v", info, err, spender)
+	}
+	if info, _ = GetInstance().ReadSpentIndex(outpoint.NewOutPoint(util.HashOne, 1)); info != nil {
+		t.Errorf("other outputs of the transaction should not be spent")
+	}
+
+	if err := GetInstance().UpdateSpentIndex(nil, []*outpoint.OutPoint{out}); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ = GetInstance().ReadSpentIndex(out); info != nil {
+		t.Errorf("spent info should be erased, got %v", info)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"time"
 
 	lvldb "github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/comparer"
@@ -23,6 +24,10 @@ const (
 	DbTxIndex    byte = 't'
 	DbBlockIndex byte = 'b'
 
+	DbAddressIndex        byte = 'a'
+	DbAddressUnspentIndex byte = 'u'
+	DbSpentIndex          byte = 'p'
+
 	DbBestBlock   byte = 'B'
 	DbFlag        byte = 'F'
 	DbReindexFlag byte = 'R'
@@ -210,7 +215,9 @@ func (dbw *DBWrapper) Read(key []byte) ([]byte, error) {
 		value = append(value, origVal...)
 		err = origErr
 	} else {
+		start := time.Now()
 		value, err = dbw.db.Get(key, &dbw.readOption)
+		recordRead(dbw.name, time.Since(start))
 	}
 	if err != nil {
 		return nil, err
@@ -241,14 +248,19 @@ func (dbw *DBWrapper) WriteBatch(bw *BatchWrapper, sync bool) error {
 	} else {
 		opts = dbw.writeOption
 	}
-	return dbw.db.Write(&bw.bat, &opts)
+	start := time.Now()
+	err := dbw.db.Write(&bw.bat, &opts)
+	recordWrite(dbw.name, time.Since(start))
+	return err
 }
 
 func (dbw *DBWrapper) Exists(key []byte) bool {
 	if dbw.mdb != nil {
 		return dbw.mdb.Contains(key)
 	}
+	start := time.Now()
 	_, err := dbw.db.Get(key, &dbw.readOption)
+	recordRead(dbw.name, time.Since(start))
 	if err != nil {
 		if err == lvldb.ErrNotFound {
 			return false
@@ -3,6 +3,7 @@ package db
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
@@ -519,3 +520,117 @@ func TestIteratorStringOrderingWithMem(t *testing.T) {
 	defer dbw.Close()
 	testIteratorStringOrdering(t, dbw)
 }
+
+func TestUpgrade(t *testing.T) {
+	path, err := ioutil.TempDir("", "dbupgrade")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+
+	dbw, err := NewDBWrapper(&DBOption{FilePath: path, CacheSize: 1 << 20})
+	if err != nil {
+		t.Fatalf("NewDBWrapper failed: %s\n", err)
+	}
+	defer dbw.Close()
+	if version, err := dbw.ReadSchemaVersion(); err != nil || version != 0 {
+		t.Fatalf("an unversioned database should have version 0, got %d, %v", version, err)
+	}
+
+	ran := make([]uint32, 0)
+	migration := func(version uint32) Migration {
+		return Migration{
+			Version:     version,
+			Description: fmt.Sprintf("migration %d", version),
+			Migrate: func(dbw *DBWrapper, progress func(done, total uint64)) error {
+				progress(1, 1)
+				ran = append(ran, version)
+				return nil
+			},
+		}
+	}
+	migrations := []Migration{migration(1), migration(3), migration(5)}
+
+	if err := dbw.Upgrade(3, migrations); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbw.Upgrade(4, migrations); err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := dbw.ReadSchemaVersion(); version != 4 {
+		t.Errorf("got schema version %d, want 4", version)
+	}
+	if err := dbw.Upgrade(5, migrations); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(ran) != "[1 3 5]" {
+		t.Errorf("got migrations %v, want [1 3 5]", ran)
+	}
+
+	if err := dbw.Upgrade(4, migrations); err == nil {
+		t.Error("a database of a newer schema version should be refused")
+	}
+
+	failing := []Migration{{Version: 6, Migrate: func(*DBWrapper, func(uint64, uint64)) error {
+		return errors.New("failed")
+	}}}
+	if err := dbw.Upgrade(6, failing); err == nil {
+		t.Error("a failed migration should fail the upgrade")
+	}
+	if version, _ := dbw.ReadSchemaVersion(); version != 5 {
+		t.Errorf("a failed migration should keep schema version 5, got %d", version)
+	}
+}
+
+func TestDBWrapperStats(t *testing.T) {
+	path, err := ioutil.TempDir("", "dbwstats")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+
+	dbw, err := NewDBWrapper(&DBOption{FilePath: path, CacheSize: 1 << 20})
+	if err != nil {
+		t.Fatalf("NewDBWrapper failed: %s\n", err)
+	}
+	defer dbw.Close()
+
+	before := GetStats()[dbw.name]
+	if err := dbw.Write([]byte{'k'}, []byte{'v'}, false); err != nil {
+		t.Fatalf("dbw.Write(): %s", err)
+	}
+	if _, err := dbw.Read([]byte{'k'}); err != nil {
+		t.Fatalf("dbw.Read(): %s", err)
+	}
+	if _, err := dbw.Read([]byte{'x'}); err == nil {
+		t.Fatal("dbw.Read() of a missing key should fail")
+	}
+	after := GetStats()[dbw.name]
+
+	if n := after.Reads.Count - before.Reads.Count; n != 2 {
+		t.Errorf("%d reads recorded, want 2", n)
+	}
+	if n := after.Writes.Count - before.Writes.Count; n != 1 {
+		t.Errorf("%d writes recorded, want 1", n)
+	}
+	if len(after.Reads.Buckets) != len(LatencyBuckets)+1 {
+		t.Fatalf("%d read buckets, want %d", len(after.Reads.Buckets), len(LatencyBuckets)+1)
+	}
+	var total uint64
+	for _, n := range after.Reads.Buckets {
+		total += n
+	}
+	if total != after.Reads.Count {
+		t.Errorf("the read buckets count %d reads, want %d", total, after.Reads.Count)
+	}
+
+	// the in-memory databases aren't recorded
+	mem, err := NewDBWrapper(&DBOption{UseMemStore: true, DontObfuscate: true})
+	if err != nil {
+		t.Fatalf("NewDBWrapper failed: %s\n", err)
+	}
+	mem.Write([]byte{'k'}, []byte{'v'}, false)
+	if _, ok := GetStats()[""]; ok {
+		t.Error("the reads of an in-memory database were recorded")
+	}
+}
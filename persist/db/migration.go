@@ -0,0 +1,92 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/copernet/copernicus/log"
+	lvldb "github.com/syndtr/goleveldb/leveldb"
+)
+
+const schemaVersionKey = "\000schema_version"
+
+// Migration upgrades a database to the schema Version. Migrate is passed a
+// progress callback, which it should call with the amount of work done so far
+// and the total amount of work, in whatever unit suits it (usually keys).
+//
+// A migration may be interrupted and run again on the next start, so it must
+// cope with a database it has already partly converted.
+type Migration struct {
+	Version     uint32
+	Description string
+	Migrate     func(dbw *DBWrapper, progress func(done, total uint64)) error
+}
+
+// ReadSchemaVersion returns the schema version recorded in the database, or 0
+// for databases written before versions were recorded.
+func (dbw *DBWrapper) ReadSchemaVersion() (uint32, error) {
+	val, err := dbw.Read([]byte(schemaVersionKey))
+	if err == lvldb.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(val) != 4 {
+		return 0, fmt.Errorf("malformed schema version of database %s", dbw.name)
+	}
+	return binary.LittleEndian.Uint32(val), nil
+}
+
+// WriteSchemaVersion records the schema version of the database.
+func (dbw *DBWrapper) WriteSchemaVersion(version uint32) error {
+	var val [4]byte
+	binary.LittleEndian.PutUint32(val[:], version)
+	return dbw.Write([]byte(schemaVersionKey), val[:], true)
+}
+
+// Upgrade brings the database from its recorded schema version up to version
+// by running the migrations to the versions in between, which must be sorted
+// by Version. Versions without a migration keep the layout of the previous
+// one. The recorded version is bumped after every migration, so that an
+// interrupted upgrade resumes where it stopped. Databases written by a newer
+// release are refused.
+func (dbw *DBWrapper) Upgrade(version uint32, migrations []Migration) error {
+	current, err := dbw.ReadSchemaVersion()
+	if err != nil {
+		return err
+	}
+	if current > version {
+		return fmt.Errorf("database %s has schema version %d, newer than the supported %d",
+			dbw.name, current, version)
+	}
+	if current == version {
+		return nil
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current || m.Version > version {
+			continue
+		}
+		log.Info("Upgrading database %s to schema version %d: %s", dbw.name, m.Version, m.Description)
+		lastPercent := uint64(0)
+		progress := func(done, total uint64) {
+			if total == 0 {
+				return
+			}
+			if percent := done * 100 / total; percent >= lastPercent+10 {
+				lastPercent = percent - percent%10
+				log.Info("Upgrading database %s to schema version %d: %d%%", dbw.name, m.Version, lastPercent)
+			}
+		}
+		if err := m.Migrate(dbw, progress); err != nil {
+			log.Error("Upgrading database %s to schema version %d failed: %v", dbw.name, m.Version, err)
+			return err
+		}
+		if err := dbw.WriteSchemaVersion(m.Version); err != nil {
+			return err
+		}
+		current = m.Version
+	}
+	return dbw.WriteSchemaVersion(version)
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds of the buckets of the latency
+// histograms of the databases.
+var LatencyBuckets = []time.Duration{
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// LatencyHistogram counts the operations of a kind on a database by latency.
+// Buckets[i] counts the operations which took at most LatencyBuckets[i] and
+// more than the previous bound; the last entry counts the slower ones.
+type LatencyHistogram struct {
+	Count   uint64
+	Total   time.Duration
+	Buckets []uint64
+}
+
+func (h *LatencyHistogram) record(elapsed time.Duration) {
+	if h.Buckets == nil {
+		h.Buckets = make([]uint64, len(LatencyBuckets)+1)
+	}
+	h.Count++
+	h.Total += elapsed
+	h.Buckets[sort.Search(len(LatencyBuckets), func(i int) bool {
+		return elapsed <= LatencyBuckets[i]
+	})]++
+}
+
+func (h *LatencyHistogram) clone() LatencyHistogram {
+	c := *h
+	c.Buckets = append([]uint64(nil), h.Buckets...)
+	if c.Buckets == nil {
+		c.Buckets = make([]uint64, len(LatencyBuckets)+1)
+	}
+	return c
+}
+
+// Stats are the latencies of the reads and of the batch writes of a database.
+type Stats struct {
+	Reads  LatencyHistogram
+	Writes LatencyHistogram
+}
+
+// dbStats collects the latencies of the on-disk databases by name, the base
+// name of their directory.  The in-memory databases of the tests aren't
+// recorded.
+var dbStats = struct {
+	sync.Mutex
+	dbs map[string]*Stats
+}{dbs: make(map[string]*Stats)}
+
+func recordRead(name string, elapsed time.Duration) {
+	dbStats.Lock()
+	defer dbStats.Unlock()
+	statsOf(name).Reads.record(elapsed)
+}
+
+func recordWrite(name string, elapsed time.Duration) {
+	dbStats.Lock()
+	defer dbStats.Unlock()
+	statsOf(name).Writes.record(elapsed)
+}
+
+// statsOf returns the statistics of the database.  It must be called with
+// the lock held.
+func statsOf(name string) *Stats {
+	s, ok := dbStats.dbs[name]
+	if !ok {
+		s = &Stats{}
+		dbStats.dbs[name] = s
+	}
+	return s
+}
+
+// GetStats returns the latencies of the databases read or written so far, by
+// name.
+func GetStats() map[string]Stats {
+	dbStats.Lock()
+	defer dbStats.Unlock()
+
+	ret := make(map[string]Stats, len(dbStats.dbs))
+	for name, s := range dbStats.dbs {
+		ret[name] = Stats{Reads: s.Reads.clone(), Writes: s.Writes.clone()}
+	}
+	return ret
+}
@@ -0,0 +1,65 @@
+package disk
+
+import (
+	"sync"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/util"
+	"github.com/hashicorp/golang-lru"
+)
+
+// DefaultBlockCacheSize is the number of blocks kept deserialized in memory
+// when the configuration does not say otherwise.
+const DefaultBlockCacheSize = 10
+
+var (
+	blockCacheOnce sync.Once
+	blockCache     *lru.Cache
+)
+
+// getBlockCache returns the cache of the blocks recently read from or written
+// to disk, or nil when the cache is disabled. Getblock, merkle proofs and
+// reorgs tend to ask for the same few blocks near the tip, which saves reading
+// and parsing them again. The cached blocks are shared, callers must not
+// modify them.
+func getBlockCache() *lru.Cache {
+	blockCacheOnce.Do(func() {
+		size := DefaultBlockCacheSize
+		if conf.Cfg != nil {
+			size = conf.Cfg.Chain.BlockCacheSize
+		}
+		if size > 0 {
+			blockCache, _ = lru.New(size)
+		}
+	})
+	return blockCache
+}
+
+// cachedBlock returns the block with the hash from the block cache, or nil.
+func cachedBlock(hash util.Hash) *block.Block {
+	cache := getBlockCache()
+	if cache == nil {
+		return nil
+	}
+	if blk, ok := cache.Get(hash); ok {
+		return blk.(*block.Block)
+	}
+	return nil
+}
+
+// cacheBlock adds blk to the block cache, evicting the least recently used
+// block when it is full.
+func cacheBlock(blk *block.Block) {
+	if cache := getBlockCache(); cache != nil {
+		cache.Add(blk.GetHash(), blk)
+	}
+}
+
+// PurgeBlockCache empties the block cache, for instance after the block files
+// were pruned or reindexed.
+func PurgeBlockCache() {
+	if cache := getBlockCache(); cache != nil {
+		cache.Purge()
+	}
+}
@@ -0,0 +1,35 @@
+package disk
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/model/block"
+)
+
+func TestBlockCache(t *testing.T) {
+	defer PurgeBlockCache()
+
+	size := conf.Cfg.Chain.BlockCacheSize
+	if size <= 0 {
+		t.Skip("block cache disabled")
+	}
+	blocks := make([]*block.Block, size+1)
+	for i := range blocks {
+		blocks[i] = block.NewBlock()
+		blocks[i].Header.Nonce = uint32(i)
+		cacheBlock(blocks[i])
+	}
+
+	if blk := cachedBlock(blocks[0].GetHash()); blk != nil {
+		t.Error("the least recently used block should have been evicted")
+	}
+	if blk := cachedBlock(blocks[size].GetHash()); blk != blocks[size] {
+		t.Errorf("got %v from the cache, want the last block cached", blk)
+	}
+
+	PurgeBlockCache()
+	if blk := cachedBlock(blocks[size].GetHash()); blk != nil {
+		t.Error("the cache should be empty after a purge")
+	}
+}
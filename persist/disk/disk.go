@@ -22,6 +22,7 @@ import (
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/model/undo"
 	"github.com/copernet/copernicus/model/utxo"
 	"github.com/copernet/copernicus/net/wire"
@@ -251,11 +252,14 @@ func readBlockFromDiskByPos(pos block.DiskBlockPos, param *model.BitcoinParams)
 }
 
 func ReadBlockFromDisk(pindex *blockindex.BlockIndex, param *model.BitcoinParams) (*block.Block, bool) {
+	hash := pindex.GetBlockHash()
+	if blk := cachedBlock(*hash); blk != nil {
+		return blk, true
+	}
 	blk, ret := readBlockFromDiskByPos(pindex.GetBlockPos(), param)
 	if !ret {
 		return nil, false
 	}
-	hash := pindex.GetBlockHash()
 	pos := pindex.GetBlockPos()
 	blockHash := blk.GetHash()
 	if !bytes.Equal(blockHash[:], hash[:]) {
@@ -263,9 +267,41 @@ func ReadBlockFromDisk(pindex *blockindex.BlockIndex, param *model.BitcoinParams
 			"doesn't match index for %s at %s", pindex.String(), pos.String()))
 		return blk, false
 	}
+	cacheBlock(blk)
 	return blk, true
 }
 
+// ReadTxFromDisk reads a transaction located by the transaction index, along
+// with the header of the block containing it. The offset of the transaction
+// is counted from the end of the block header.
+func ReadTxFromDisk(pos *block.DiskTxPos) (*tx.Tx, *block.BlockHeader, error) {
+	file := OpenBlockFile(pos.BlockIn, true)
+	if file == nil {
+		log.Error("ReadTxFromDisk: OpenBlockFile failed for %s", pos.BlockIn.String())
+		return nil, nil, errcode.New(errcode.FailedToReadBlock)
+	}
+	defer file.Close()
+
+	// skip the block size
+	if _, err := util.BinarySerializer.Uint32(file, binary.LittleEndian); err != nil {
+		return nil, nil, err
+	}
+	header := block.NewBlockHeader()
+	if err := header.Unserialize(file); err != nil {
+		log.Error("ReadTxFromDisk: read block header failed for %s: %v", pos.BlockIn.String(), err)
+		return nil, nil, err
+	}
+	if _, err := file.Seek(int64(pos.TxOffsetIn), os.SEEK_CUR); err != nil {
+		return nil, nil, err
+	}
+	txn := tx.NewEmptyTx()
+	if err := txn.Unserialize(file); err != nil {
+		log.Error("ReadTxFromDisk: deserialize tx failed for %s: %v", pos.BlockIn.String(), err)
+		return nil, nil, err
+	}
+	return txn, header, nil
+}
+
 func WriteBlockToDisk(block *block.Block, pos *block.DiskBlockPos) bool {
 	// Open history file to append
 	file := OpenBlockFile(pos, false)
@@ -298,6 +334,7 @@ func WriteBlockToDisk(block *block.Block, pos *block.DiskBlockPos) bool {
 		log.Error("Write Block To Disk failed")
 		return false
 	}
+	cacheBlock(block)
 	return true
 }
 
@@ -426,6 +463,11 @@ func FlushStateToDisk(mode FlushStateMode, nManualPruneHeight int) error {
 			panic("write db failed, please check.")
 
 		}
+		// The optional indexes match the flushed coins again.
+		if err := blockTree.IndexesFlushed(); err != nil {
+			log.Error("FlushStateToDisk: mark the indexes complete failed: %v", err)
+			return err
+		}
 		gPersist.GlobalLastFlush = int(nNow)
 	}
 	if doFullFlush || ((mode == FlushStateAlways || mode == FlushStatePeriodic) &&
@@ -458,6 +500,8 @@ func CheckDiskSpace(nAdditionalBytes uint32) bool {
 	return true
 }
 
+// FlushBlockFile syncs the last block and undo files to disk. Finalizing
+// them also truncates them to their used size.
 func FlushBlockFile(fFinalize bool) {
 	// global.CsLastBlockFile.Lock()
 	// defer global.CsLastBlockFile.Unlock()
@@ -468,18 +512,18 @@ func FlushBlockFile(fFinalize bool) {
 	if fileOld != nil {
 		if fFinalize {
 			os.Truncate(fileOld.Name(), int64(gPersist.GlobalBlockFileInfo[gPersist.GlobalLastBlockFile].Size))
-			fileOld.Sync()
-			fileOld.Close()
 		}
+		fileOld.Sync()
+		fileOld.Close()
 	}
 
 	fileOld = OpenUndoFile(*posOld, false)
 	if fileOld != nil {
 		if fFinalize {
 			os.Truncate(fileOld.Name(), int64(gPersist.GlobalBlockFileInfo[gPersist.GlobalLastBlockFile].UndoSize))
-			fileOld.Sync()
-			fileOld.Close()
 		}
+		fileOld.Sync()
+		fileOld.Close()
 	}
 }
 
@@ -730,6 +774,9 @@ func UnlinkPrunedFiles(setFilesToPrune *set.Set) {
 		os.Remove(GetBlockPosFilename(*pos, "rev"))
 		log.Info("Prune: %s deleted blk/rev (%05u)\n", key)
 	}
+	if len(lists) != 0 {
+		PurgeBlockCache()
+	}
 }
 
 func GetPruneState() *persist.PruneState {
@@ -20,6 +20,7 @@ import (
 	"github.com/copernet/copernicus/model/opcodes"
 	"github.com/copernet/copernicus/model/outpoint"
 	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
 	"github.com/copernet/copernicus/model/txout"
 	"github.com/copernet/copernicus/model/undo"
 	"github.com/copernet/copernicus/model/utxo"
@@ -28,6 +29,7 @@ import (
 	"github.com/copernet/copernicus/persist/blkdb"
 	"github.com/copernet/copernicus/persist/db"
 	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
 )
 
 func TestMain(m *testing.M) {
@@ -368,3 +370,32 @@ func TestAllocateFileRange(t *testing.T) {
 		allocateFileRangeWithNewFile(t, size)
 	}
 }
+
+func TestReadTxFromDisk(t *testing.T) {
+	blk := block.NewBlock()
+	blk.Header.Bits = 0x207fffff
+	for i := 0; i < 3; i++ {
+		txn := tx.NewTx(uint32(i), tx.TxVersion)
+		txn.AddTxOut(txout.NewTxOut(amount.Amount(i+1), script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+		blk.Txs = append(blk.Txs, txn)
+	}
+	blockPos := block.NewDiskBlockPos(12, 0)
+	if !WriteBlockToDisk(blk, blockPos) {
+		t.Fatal("write block to disk failed")
+	}
+
+	offset := util.VarIntSerializeSize(uint64(len(blk.Txs)))
+	for _, want := range blk.Txs {
+		got, header, err := ReadTxFromDisk(block.NewDiskTxPos(blockPos, offset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.GetHash() != want.GetHash() {
+			t.Errorf("read tx %s at offset %d, want %s", got.GetHash(), offset, want.GetHash())
+		}
+		if header.GetHash() != blk.GetHash() {
+			t.Errorf("read header of block %s, want %s", header.GetHash(), blk.GetHash())
+		}
+		offset += want.SerializeSize()
+	}
+}
@@ -33,14 +33,6 @@ type PersistGlobal struct {
 	DefaultMaxMemPoolSize                                uint
 	GlobalDirtyFileInfo                                  map[int32]bool // temp for update file info
 	GlobalDirtyBlockIndex                                map[util.Hash]*blockindex.BlockIndex
-	GlobalTimeReadFromDisk                               int64
-	GlobalTimeConnectTotal                               int64
-	GlobalTimeChainState                                 int64
-	GlobalTimeFlush                                      int64
-	GlobalTimeCheck                                      int64
-	GlobalTimeForks                                      int64
-	GlobalTimePostConnect                                int64
-	GlobalTimeTotal                                      int64
 	GlobalBlockSequenceID                                int32
 	GlobalMapBlocksUnlinked                              map[*blockindex.BlockIndex][]*blockindex.BlockIndex
 }
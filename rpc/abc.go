@@ -40,6 +40,6 @@ func handleSetExcessiveBlock(s *Server, cmd interface{}, closeChan <-chan struct
 
 func registerABCRPCCommands() {
 	for name, handler := range abcHandlers {
-		appendCommand(name, handler)
+		appendCommand("network", name, handler)
 	}
 }
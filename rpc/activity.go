@@ -0,0 +1,187 @@
+package rpc
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+)
+
+const (
+	// maxActivityEvents is the number of events the activity log keeps at
+	// most.  Once full, the oldest quarter is forgotten at once.
+	maxActivityEvents = 50000
+
+	// maxActivityEventsPerRequest is the number of events getactivity
+	// returns at most, the poller fetching the next ones from the sequence
+	// of the last one.
+	maxActivityEventsPerRequest = 1000
+)
+
+// The types of the events of the activity log.
+const (
+	activityBlockConnected    = "blockconnected"
+	activityBlockDisconnected = "blockdisconnected"
+	activityTxAdded           = "txadded"
+	activityTxRemoved         = "txremoved"
+)
+
+var activityRemovalReasons = map[mempool.PoolRemovalReason]string{
+	mempool.UNKNOWN:   "unknown",
+	mempool.EXPIRY:    "expiry",
+	mempool.SIZELIMIT: "sizelimit",
+	mempool.REORG:     "reorg",
+	mempool.BLOCK:     "block",
+	mempool.CONFLICT:  "conflict",
+	mempool.REPLACED:  "replaced",
+}
+
+// activityLog numbers the blocks connected to and disconnected from the
+// active chain and the transactions added to and removed from the mempool
+// with a monotonically increasing sequence, and keeps the recent events so
+// that stateless pollers catch up on the ones they missed with getactivity.
+// The sequence starts over on restart.
+type activityLog struct {
+	mtx      sync.Mutex
+	sequence uint64
+	events   []btcjson.ActivityEvent
+	// tip is the tip of the active chain as of the latest block events.
+	tip *blockindex.BlockIndex
+}
+
+// newActivityLog returns an activity log recording the events of the chain.
+func newActivityLog(c *chain.Chain) *activityLog {
+	l := &activityLog{tip: c.Tip()}
+	c.Subscribe(l.handleNotification)
+	return l
+}
+
+// handleNotification records the events of a chain notification.
+func (l *activityLog) handleNotification(n *chain.Notification) {
+	switch n.Type {
+	case chain.NTChainTipUpdated:
+		event, ok := n.Data.(*chain.TipUpdatedEvent)
+		if !ok || event.TipIndex == nil {
+			return
+		}
+		l.updateTip(event.TipIndex)
+
+	case chain.NTMempoolTxAdded:
+		if transaction, ok := n.Data.(*tx.Tx); ok {
+			l.mtx.Lock()
+			l.addTxEvent(activityTxAdded, transaction, "")
+			l.mtx.Unlock()
+		}
+
+	case chain.NTMempoolTxRemoved:
+		if event, ok := n.Data.(*mempool.TxRemovedEvent); ok {
+			l.mtx.Lock()
+			l.addTxEvent(activityTxRemoved, event.Tx, activityRemovalReasons[event.Reason])
+			l.mtx.Unlock()
+		}
+	}
+}
+
+// updateTip records the blocks disconnected from the previous tip down to the
+// fork, then the blocks connected from the fork up to the new tip.
+func (l *activityLog) updateTip(tip *blockindex.BlockIndex) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	// without a previous tip, only the new one is recorded
+	height := tip.Height
+	if l.tip != nil {
+		fork := blockindex.LastCommonAncestor(l.tip, tip)
+		for index := l.tip; index != nil && index != fork; index = index.Prev {
+			l.addBlockEvent(activityBlockDisconnected, index)
+		}
+		height = 0
+		if fork != nil {
+			height = fork.Height + 1
+		}
+	}
+	for ; height <= tip.Height; height++ {
+		l.addBlockEvent(activityBlockConnected, tip.GetAncestor(height))
+	}
+	l.tip = tip
+}
+
+func (l *activityLog) addBlockEvent(typ string, index *blockindex.BlockIndex) {
+	l.add(btcjson.ActivityEvent{
+		Type:   typ,
+		Hash:   index.GetBlockHash().String(),
+		Height: index.Height,
+	})
+}
+
+func (l *activityLog) addTxEvent(typ string, transaction *tx.Tx, reason string) {
+	l.add(btcjson.ActivityEvent{
+		Type:   typ,
+		Hash:   util.TxID(transaction.GetHash()).String(),
+		Reason: reason,
+	})
+}
+
+// add numbers and appends an event.  It must be called with the lock held.
+func (l *activityLog) add(event btcjson.ActivityEvent) {
+	l.sequence++
+	event.Sequence = l.sequence
+	l.events = append(l.events, event)
+	if len(l.events) > maxActivityEvents {
+		n := copy(l.events, l.events[len(l.events)-maxActivityEvents*3/4:])
+		l.events = l.events[:n]
+	}
+}
+
+// since returns the events following the sequence, at most
+// maxActivityEventsPerRequest of them.
+func (l *activityLog) since(sequence uint64) (*btcjson.GetActivityResult, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	// a poller ahead of the log has seen the events of a node which
+	// restarted since
+	if sequence > l.sequence {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			fmt.Sprintf("Sequence %d is ahead of the latest event %d, "+
+				"the node restarted", sequence, l.sequence))
+	}
+
+	result := &btcjson.GetActivityResult{
+		Sequence: l.sequence,
+		Events:   make([]btcjson.ActivityEvent, 0),
+	}
+	if len(l.events) == 0 || sequence == l.sequence {
+		return result, nil
+	}
+
+	first := l.events[0].Sequence
+	start := 0
+	if sequence+1 < first {
+		result.Truncated = true
+	} else {
+		start = int(sequence + 1 - first)
+	}
+	end := len(l.events)
+	if end-start > maxActivityEventsPerRequest {
+		end = start + maxActivityEventsPerRequest
+	}
+	result.Events = append(result.Events, l.events[start:end]...)
+	return result, nil
+}
+
+// handleGetActivity implements the getactivity command.
+func handleGetActivity(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetActivityCmd)
+
+	var sequence uint64
+	if c.Sequence != nil {
+		sequence = *c.Sequence
+	}
+	return s.activity.since(sequence)
+}
@@ -0,0 +1,119 @@
+package rpc
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+)
+
+// newActivityBranch returns n block indexes on top of prev, their time
+// telling the branches apart.
+func newActivityBranch(prev *blockindex.BlockIndex, n int, time uint32) []*blockindex.BlockIndex {
+	branch := make([]*blockindex.BlockIndex, 0, n)
+	for i := 0; i < n; i++ {
+		header := block.BlockHeader{Time: time + uint32(i)}
+		index := blockindex.NewBlockIndex(&header)
+		if prev != nil {
+			header.HashPrevBlock = *prev.GetBlockHash()
+			index = blockindex.NewBlockIndex(&header)
+			index.Height = prev.Height + 1
+			index.Prev = prev
+		}
+		branch = append(branch, index)
+		prev = index
+	}
+	return branch
+}
+
+func TestActivityLogEvents(t *testing.T) {
+	active := newActivityBranch(nil, 3, 1)
+	side := newActivityBranch(active[0], 3, 100)
+	transaction := tx.NewTx(0, tx.TxVersion)
+	txid := util.TxID(transaction.GetHash()).String()
+
+	// without a previous tip, only the new one is recorded
+	l := &activityLog{}
+	l.handleNotification(&chain.Notification{Type: chain.NTChainTipUpdated,
+		Data: &chain.TipUpdatedEvent{TipIndex: active[2]}})
+	l.handleNotification(&chain.Notification{Type: chain.NTMempoolTxAdded, Data: transaction})
+	// the reorganization disconnects the blocks down to the fork first
+	l.handleNotification(&chain.Notification{Type: chain.NTChainTipUpdated,
+		Data: &chain.TipUpdatedEvent{TipIndex: side[1]}})
+	l.handleNotification(&chain.Notification{Type: chain.NTMempoolTxRemoved,
+		Data: &mempool.TxRemovedEvent{Tx: transaction, Reason: mempool.BLOCK}})
+	l.handleNotification(&chain.Notification{Type: chain.NTChainTipUpdated, Data: nil})
+
+	blockEvent := func(typ string, index *blockindex.BlockIndex) btcjson.ActivityEvent {
+		return btcjson.ActivityEvent{Type: typ, Hash: index.GetBlockHash().String(), Height: index.Height}
+	}
+	expected := []btcjson.ActivityEvent{
+		blockEvent(activityBlockConnected, active[2]),
+		{Type: activityTxAdded, Hash: txid},
+		blockEvent(activityBlockDisconnected, active[2]),
+		blockEvent(activityBlockDisconnected, active[1]),
+		blockEvent(activityBlockConnected, side[0]),
+		blockEvent(activityBlockConnected, side[1]),
+		{Type: activityTxRemoved, Hash: txid, Reason: "block"},
+	}
+	result, err := l.since(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Sequence != uint64(len(expected)) || result.Truncated || len(result.Events) != len(expected) {
+		t.Fatalf("got sequence %d, truncated %t and %d events, expect %d events",
+			result.Sequence, result.Truncated, len(result.Events), len(expected))
+	}
+	for i, event := range result.Events {
+		expected[i].Sequence = uint64(i + 1)
+		if event != expected[i] {
+			t.Errorf("event %d is %+v, expect %+v", i, event, expected[i])
+		}
+	}
+
+	result, err = l.since(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Events) != 2 || result.Events[0].Sequence != 6 {
+		t.Errorf("got events %+v after the sequence 5", result.Events)
+	}
+	if result, err = l.since(result.Sequence); err != nil || len(result.Events) != 0 {
+		t.Errorf("got events %+v, error %v after the latest sequence", result, err)
+	}
+	if _, err := l.since(8); err == nil || toRPCError(err).Code != btcjson.ErrRPCInvalidParameter {
+		t.Errorf("got error %v for a sequence ahead of the log", err)
+	}
+}
+
+func TestActivityLogLimits(t *testing.T) {
+	transaction := tx.NewTx(0, tx.TxVersion)
+	l := &activityLog{}
+	for i := 0; i <= maxActivityEvents; i++ {
+		l.addTxEvent(activityTxAdded, transaction, "")
+	}
+	// the oldest quarter is forgotten once the log is full
+	if len(l.events) != maxActivityEvents*3/4 || l.events[len(l.events)-1].Sequence != maxActivityEvents+1 {
+		t.Fatalf("the log holds %d events up to %d", len(l.events), l.events[len(l.events)-1].Sequence)
+	}
+
+	result, err := l.since(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Truncated || len(result.Events) != maxActivityEventsPerRequest ||
+		result.Events[0].Sequence != l.events[0].Sequence {
+		t.Errorf("got truncated %t and %d events from %d", result.Truncated, len(result.Events),
+			result.Events[0].Sequence)
+	}
+
+	sequence := l.events[0].Sequence
+	if result, err = l.since(sequence); err != nil || result.Truncated || result.Events[0].Sequence != sequence+1 {
+		t.Errorf("got %+v, error %v after the oldest event", result, err)
+	}
+}
@@ -0,0 +1,187 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"sort"
+
+	"github.com/copernet/copernicus/logic/lchain"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/persist/blkdb"
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+)
+
+var addressIndexHandlers = map[string]commandHandler{
+	"getaddresstxids":   handleGetAddressTxIDs,
+	"getaddressbalance": handleGetAddressBalance,
+	"getaddressutxos":   handleGetAddressUtxos,
+	"getaddressdeltas":  handleGetAddressDeltas,
+}
+
+// addressIndexKeys checks the index is enabled and converts the requested
+// addresses to index keys.
+func addressIndexKeys(request *btcjson.AddressIndexRequest) ([]*blkdb.AddressKey, error) {
+	if !lchain.AddressIndexEnabled() {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCMisc, "Address index not enabled")
+	}
+	keys := make([]*blkdb.AddressKey, 0, len(request.Addresses))
+	for _, addrStr := range request.Addresses {
+		addr, err := script.AddressFromString(addrStr)
+		if err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Invalid address")
+		}
+		key, err := lchain.AddressKeyFromAddress(addr)
+		if err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Invalid address")
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func addressIndexRange(request *btcjson.AddressIndexRequest) (int32, int32, error) {
+	var start, end int32
+	if request.Start != nil {
+		start = *request.Start
+	}
+	if request.End != nil {
+		end = *request.End
+	}
+	if start < 0 || end < 0 || (end > 0 && end < start) {
+		return 0, 0, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"End value is expected to be greater than start")
+	}
+	return start, end, nil
+}
+
+func addressKeyToString(key *blkdb.AddressKey) string {
+	version := script.AddressVerPubKey()
+	if key.Type == blkdb.AddressTypeScriptHash {
+		version = script.AddressVerScript()
+	}
+	addrStr, _ := script.Hash160ToAddressStr(key.Hash160[:], version)
+	return addrStr
+}
+
+// readAddressDeltas returns the deltas of all requested addresses ordered by
+// height.
+func readAddressDeltas(request *btcjson.AddressIndexRequest) ([]*blkdb.AddressDelta, error) {
+	keys, err := addressIndexKeys(request)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := addressIndexRange(request)
+	if err != nil {
+		return nil, err
+	}
+	deltas := make([]*blkdb.AddressDelta, 0)
+	for _, key := range keys {
+		found, err := blkdb.GetInstance().ReadAddressDeltas(key, start, end)
+		if err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCDatabase, "No information available for address")
+		}
+		deltas = append(deltas, found...)
+	}
+	sort.SliceStable(deltas, func(i, j int) bool {
+		return deltas[i].Height < deltas[j].Height
+	})
+	return deltas, nil
+}
+
+func handleGetAddressTxIDs(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressTxIDsCmd)
+	deltas, err := readAddressDeltas(&c.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	txids := make([]string, 0, len(deltas))
+	seen := make(map[string]struct{}, len(deltas))
+	for _, delta := range deltas {
+		txid := util.TxID(delta.TxHash).String()
+		if _, ok := seen[txid]; ok {
+			continue
+		}
+		seen[txid] = struct{}{}
+		txids = append(txids, txid)
+	}
+	return txids, nil
+}
+
+func handleGetAddressBalance(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressBalanceCmd)
+	// the balance always covers the whole chain
+	request := btcjson.AddressIndexRequest{Addresses: c.Request.Addresses}
+	deltas, err := readAddressDeltas(&request)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &btcjson.GetAddressBalanceResult{}
+	for _, delta := range deltas {
+		if delta.Amount > 0 {
+			result.Received += int64(delta.Amount)
+		}
+		result.Balance += int64(delta.Amount)
+	}
+	return result, nil
+}
+
+func handleGetAddressUtxos(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressUtxosCmd)
+	keys, err := addressIndexKeys(&c.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	unspents := make([]*blkdb.AddressUnspent, 0)
+	for _, key := range keys {
+		found, err := blkdb.GetInstance().ReadAddressUnspents(key)
+		if err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCDatabase, "No information available for address")
+		}
+		unspents = append(unspents, found...)
+	}
+	sort.SliceStable(unspents, func(i, j int) bool {
+		return unspents[i].Height < unspents[j].Height
+	})
+
+	result := make([]*btcjson.AddressUtxoResult, 0, len(unspents))
+	for _, unspent := range unspents {
+		result = append(result, &btcjson.AddressUtxoResult{
+			Address:     addressKeyToString(&unspent.Address),
+			TxID:        util.TxID(unspent.TxHash).String(),
+			OutputIndex: unspent.Index,
+			Script:      hex.EncodeToString(unspent.ScriptPubKey),
+			Satoshis:    int64(unspent.Amount),
+			Height:      unspent.Height,
+		})
+	}
+	return result, nil
+}
+
+func handleGetAddressDeltas(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetAddressDeltasCmd)
+	deltas, err := readAddressDeltas(&c.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*btcjson.AddressDeltaResult, 0, len(deltas))
+	for _, delta := range deltas {
+		result = append(result, &btcjson.AddressDeltaResult{
+			Satoshis: int64(delta.Amount),
+			TxID:     util.TxID(delta.TxHash).String(),
+			Index:    delta.Index,
+			Height:   delta.Height,
+			Address:  addressKeyToString(&delta.Address),
+		})
+	}
+	return result, nil
+}
+
+func registerAddressIndexRPCCommands() {
+	for name, handler := range addressIndexHandlers {
+		appendCommand("addressindex", name, handler)
+	}
+}
@@ -0,0 +1,240 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/copernet/copernicus/logic/lblock"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/consensus"
+	"github.com/copernet/copernicus/model/undo"
+	"github.com/copernet/copernicus/persist/disk"
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+)
+
+// perUTXOOverhead is the size an unspent output takes in the UTXO set on top
+// of its serialized size: the outpoint, the height and the coinbase flag.
+const perUTXOOverhead = 36 + 4 + 1
+
+type feeRateWeight struct {
+	feeRate int64
+	size    int64
+}
+
+// truncatedMedian returns the median of values, sorting them in place. The
+// mean of the two middle values of an even count is truncated.
+func truncatedMedian(values []int64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	n := len(values)
+	if n%2 == 0 {
+		return (values[n/2-1] + values[n/2]) / 2
+	}
+	return values[n/2]
+}
+
+// feeRatePercentiles returns the 10th, 25th, 50th, 75th and 90th percentiles
+// of the fee rates weighted by the size of their transactions.
+func feeRatePercentiles(feeRates []feeRateWeight, totalSize int64) [5]int64 {
+	var percentiles [5]int64
+	if len(feeRates) == 0 {
+		return percentiles
+	}
+	sort.Slice(feeRates, func(i, j int) bool { return feeRates[i].feeRate < feeRates[j].feeRate })
+
+	weights := [5]int64{totalSize / 10, totalSize / 4, totalSize / 2, totalSize * 3 / 4, totalSize * 9 / 10}
+	next := 0
+	cumulative := int64(0)
+	for _, fr := range feeRates {
+		cumulative += fr.size
+		for next < len(weights) && cumulative >= weights[next] {
+			percentiles[next] = fr.feeRate
+			next++
+		}
+	}
+	for ; next < len(weights); next++ {
+		percentiles[next] = feeRates[len(feeRates)-1].feeRate
+	}
+	return percentiles
+}
+
+// computeBlockStats scans blk, spending the coins of blockUndo, which is nil
+// for the genesis block.
+func computeBlockStats(blk *block.Block, index *blockindex.BlockIndex, blockUndo *undo.BlockUndo) (*btcjson.GetBlockStatsResult, error) {
+	var txUndos []*undo.TxUndo
+	if blockUndo != nil {
+		txUndos = blockUndo.GetTxundo()
+	}
+	if len(txUndos)+1 != len(blk.Txs) && index.Height > 0 {
+		return nil, fmt.Errorf("undo data of block %s does not match its transactions", index.GetBlockHash())
+	}
+
+	stats := &btcjson.GetBlockStatsResult{
+		BlockHash:  index.GetBlockHash().String(),
+		Height:     index.Height,
+		MedianTime: index.GetMedianTimePast(),
+		Subsidy:    int64(lblock.GetBlockSubsidy(index.Height, chain.GetInstance().GetParams())),
+		Time:       int64(blk.Header.Time),
+		Txs:        int64(len(blk.Txs)),
+		MinFee:     util.MaxMoney,
+		MinFeeRate: util.MaxMoney,
+		MinTxSize:  int64(consensus.MaxTxSize),
+	}
+
+	fees := make([]int64, 0, len(blk.Txs))
+	sizes := make([]int64, 0, len(blk.Txs))
+	feeRates := make([]feeRateWeight, 0, len(blk.Txs))
+	for i, transaction := range blk.Txs {
+		stats.Outs += int64(transaction.GetOutsCount())
+		txTotalOut := int64(0)
+		for _, out := range transaction.GetOuts() {
+			txTotalOut += int64(out.GetValue())
+			if out.IsSpendable() {
+				stats.UtxoSizeInc += int64(out.SerializeSize()) + perUTXOOverhead
+			}
+		}
+		if i == 0 {
+			continue
+		}
+
+		stats.Ins += int64(len(transaction.GetIns()))
+		stats.TotalOut += txTotalOut
+
+		txSize := int64(transaction.EncodeSize())
+		sizes = append(sizes, txSize)
+		stats.TotalSize += txSize
+		if txSize > stats.MaxTxSize {
+			stats.MaxTxSize = txSize
+		}
+		if txSize < stats.MinTxSize {
+			stats.MinTxSize = txSize
+		}
+
+		txTotalIn := int64(0)
+		for _, coin := range txUndos[i-1].GetUndoCoins() {
+			txTotalIn += int64(coin.GetAmount())
+			spent := coin.GetTxOut()
+			stats.UtxoSizeInc -= int64(spent.SerializeSize()) + perUTXOOverhead
+		}
+
+		fee := txTotalIn - txTotalOut
+		fees = append(fees, fee)
+		stats.TotalFee += fee
+		if fee > stats.MaxFee {
+			stats.MaxFee = fee
+		}
+		if fee < stats.MinFee {
+			stats.MinFee = fee
+		}
+
+		feeRate := fee / txSize
+		feeRates = append(feeRates, feeRateWeight{feeRate: feeRate, size: txSize})
+		if feeRate > stats.MaxFeeRate {
+			stats.MaxFeeRate = feeRate
+		}
+		if feeRate < stats.MinFeeRate {
+			stats.MinFeeRate = feeRate
+		}
+	}
+
+	stats.UtxoIncrease = stats.Outs - stats.Ins
+	if len(blk.Txs) > 1 {
+		stats.AvgFee = stats.TotalFee / int64(len(blk.Txs)-1)
+		stats.AvgTxSize = stats.TotalSize / int64(len(blk.Txs)-1)
+		stats.AvgFeeRate = stats.TotalFee / stats.TotalSize
+	} else {
+		stats.MinFee, stats.MinFeeRate, stats.MinTxSize = 0, 0, 0
+	}
+	stats.MedianFee = truncatedMedian(fees)
+	stats.MedianTxSize = truncatedMedian(sizes)
+	stats.FeeRatePercentiles = feeRatePercentiles(feeRates, stats.TotalSize)
+	return stats, nil
+}
+
+// selectBlockStats returns the named statistics of stats only.
+func selectBlockStats(stats *btcjson.GetBlockStatsResult, names []string) (interface{}, error) {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return nil, err
+	}
+	all := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]json.RawMessage, len(names))
+	for _, name := range names {
+		value, ok := all[name]
+		if !ok {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+				fmt.Sprintf("Invalid selected statistic %s", name))
+		}
+		selected[name] = value
+	}
+	return selected, nil
+}
+
+// handleGetBlockStats computes per block statistics of a block of the active
+// chain, reading the spent coins from the undo data of the block.
+func handleGetBlockStats(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetBlockStatsCmd)
+
+	snapshot := newChainSnapshot()
+	var index *blockindex.BlockIndex
+	if c.HashOrHeight.Height != nil {
+		height := *c.HashOrHeight.Height
+		if height < 0 {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+				fmt.Sprintf("Target block height %d is negative", height))
+		}
+		if height > snapshot.Height() {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+				fmt.Sprintf("Target block height %d after current tip %d", height, snapshot.Height()))
+		}
+		index = snapshot.GetIndex(height)
+	} else {
+		hash, err := util.BlockHashFromStr(c.HashOrHeight.Hash)
+		if err != nil {
+			return nil, rpcDecodeHexError(c.HashOrHeight.Hash)
+		}
+		index = chain.GetInstance().FindBlockIndex(hash.Hash())
+		if index == nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Block not found")
+		}
+		if !snapshot.Contains(index) {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+				fmt.Sprintf("Block is not in chain %s", chain.GetInstance().GetParams().Name))
+		}
+	}
+
+	if disk.GetPruneState().HavePruned && !index.HasData() {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCMisc, "Block not available (pruned data)")
+	}
+	blk, ok := disk.ReadBlockFromDisk(index, chain.GetInstance().GetParams())
+	if !ok {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCMisc, "Block not found on disk")
+	}
+	var blockUndo *undo.BlockUndo
+	if index.Prev != nil {
+		pos := index.GetUndoPos()
+		blockUndo, ok = disk.UndoReadFromDisk(&pos, *index.Prev.GetBlockHash())
+		if !ok {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCMisc, "Can't read undo data from disk")
+		}
+	}
+
+	stats, err := computeBlockStats(blk, index, blockUndo)
+	if err != nil {
+		return nil, internalRPCError(err.Error(), "Failed to compute block stats")
+	}
+	if c.Stats == nil || len(*c.Stats) == 0 {
+		return stats, nil
+	}
+	return selectBlockStats(stats, *c.Stats)
+}
@@ -0,0 +1,156 @@
+package rpc
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/undo"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+)
+
+func TestTruncatedMedian(t *testing.T) {
+	tests := []struct {
+		values []int64
+		median int64
+	}{
+		{nil, 0},
+		{[]int64{7}, 7},
+		{[]int64{9, 1, 5}, 5},
+		{[]int64{4, 1}, 2},
+		{[]int64{8, 1, 4, 2}, 3},
+		{[]int64{-3, -4}, -3},
+	}
+	for _, test := range tests {
+		if median := truncatedMedian(test.values); median != test.median {
+			t.Errorf("truncatedMedian(%v) = %d, expect %d", test.values, median, test.median)
+		}
+	}
+}
+
+func TestFeeRatePercentiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		feeRates    []feeRateWeight
+		totalSize   int64
+		percentiles [5]int64
+	}{
+		{"no transaction", nil, 0, [5]int64{}},
+		{"one transaction", []feeRateWeight{{5, 100}}, 100, [5]int64{5, 5, 5, 5, 5}},
+		{"equal sizes", []feeRateWeight{{4, 100}, {1, 100}, {3, 100}, {2, 100}}, 400,
+			[5]int64{1, 1, 2, 3, 4}},
+		{"weighted by size", []feeRateWeight{{10, 300}, {1, 100}}, 400,
+			[5]int64{1, 1, 10, 10, 10}},
+		{"large transaction at a low rate", []feeRateWeight{{1, 900}, {50, 50}, {100, 50}}, 1000,
+			[5]int64{1, 1, 1, 1, 1}},
+		{"small transaction at a low rate", []feeRateWeight{{100, 900}, {1, 50}, {50, 50}}, 1000,
+			[5]int64{50, 100, 100, 100, 100}},
+	}
+	for _, test := range tests {
+		if percentiles := feeRatePercentiles(test.feeRates, test.totalSize); percentiles != test.percentiles {
+			t.Errorf("%s: got percentiles %v, expect %v", test.name, percentiles, test.percentiles)
+		}
+	}
+}
+
+func TestComputeBlockStats(t *testing.T) {
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	chain.InitGlobalChain()
+
+	opTrue := script.NewScriptRaw([]byte{opcodes.OP_TRUE})
+	spend := func(prevIndex uint32, values ...amount.Amount) *tx.Tx {
+		transaction := tx.NewTx(0, tx.TxVersion)
+		transaction.AddTxIn(txin.NewTxIn(&outpoint.OutPoint{Hash: util.HashOne, Index: prevIndex},
+			script.NewScriptRaw([]byte{opcodes.OP_TRUE}), script.SequenceFinal))
+		for _, value := range values {
+			transaction.AddTxOut(txout.NewTxOut(value, opTrue))
+		}
+		return transaction
+	}
+	spentCoins := func(values ...amount.Amount) *undo.TxUndo {
+		coins := make([]*utxo.Coin, 0, len(values))
+		for _, value := range values {
+			coins = append(coins, utxo.NewCoin(txout.NewTxOut(value, opTrue), 1, false))
+		}
+		txUndo := undo.NewTxUndo()
+		txUndo.SetUndoCoins(coins)
+		return txUndo
+	}
+
+	coinbase := spend(0, 5000000000)
+	tx1 := spend(1, 9000)
+	tx2 := spend(2, 4000, 3000)
+	tx2.AddTxIn(txin.NewTxIn(&outpoint.OutPoint{Hash: util.HashOne, Index: 3},
+		script.NewScriptRaw([]byte{opcodes.OP_TRUE}), script.SequenceFinal))
+	blk := block.NewBlock()
+	blk.Txs = []*tx.Tx{coinbase, tx1, tx2}
+	blockUndo := undo.NewBlockUndo(2)
+	blockUndo.SetTxUndo([]*undo.TxUndo{spentCoins(10000), spentCoins(5000, 5000)})
+	index := blockindex.NewBlockIndex(&blk.Header)
+	index.Height = 1
+
+	stats, err := computeBlockStats(blk, index, blockUndo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size1, size2 := int64(tx1.EncodeSize()), int64(tx2.EncodeSize())
+	outSize := int64(txout.NewTxOut(0, opTrue).SerializeSize()) + perUTXOOverhead
+	checks := []struct {
+		name      string
+		got, want int64
+	}{
+		{"txs", stats.Txs, 3},
+		{"ins", stats.Ins, 3},
+		{"outs", stats.Outs, 4},
+		{"total_out", stats.TotalOut, 16000},
+		{"totalfee", stats.TotalFee, 4000},
+		{"minfee", stats.MinFee, 1000},
+		{"maxfee", stats.MaxFee, 3000},
+		{"avgfee", stats.AvgFee, 2000},
+		{"medianfee", stats.MedianFee, 2000},
+		{"total_size", stats.TotalSize, size1 + size2},
+		{"mintxsize", stats.MinTxSize, size1},
+		{"maxtxsize", stats.MaxTxSize, size2},
+		{"avgtxsize", stats.AvgTxSize, (size1 + size2) / 2},
+		{"minfeerate", stats.MinFeeRate, 1000 / size1},
+		{"maxfeerate", stats.MaxFeeRate, 3000 / size2},
+		{"avgfeerate", stats.AvgFeeRate, 4000 / (size1 + size2)},
+		{"utxo_increase", stats.UtxoIncrease, 1},
+		{"utxo_size_inc", stats.UtxoSizeInc, outSize},
+		{"subsidy", stats.Subsidy, 5000000000},
+	}
+	for _, check := range checks {
+		if check.got != check.want {
+			t.Errorf("%s = %d, expect %d", check.name, check.got, check.want)
+		}
+	}
+
+	// a block of its coinbase only has no fee statistics
+	blk.Txs = blk.Txs[:1]
+	stats, err = computeBlockStats(blk, index, undo.NewBlockUndo(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.MinFee != 0 || stats.MinFeeRate != 0 || stats.MinTxSize != 0 || stats.TotalSize != 0 ||
+		stats.FeeRatePercentiles != [5]int64{} {
+		t.Errorf("coinbase only block got fee statistics %+v", stats)
+	}
+
+	// undo data not matching the transactions is an error
+	blk.Txs = []*tx.Tx{coinbase, tx1}
+	if _, err := computeBlockStats(blk, index, blockUndo); err == nil {
+		t.Errorf("undo data of another block accepted")
+	}
+}
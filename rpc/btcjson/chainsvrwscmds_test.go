@@ -0,0 +1,141 @@
+// Copyright (c) 2014-2017 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestChainSvrWsCmds tests the websocket commands and notifications of the
+// chain server marshal and unmarshal as expected, including the defaults of
+// the optional parameters.
+func TestChainSvrWsCmds(t *testing.T) {
+	t.Parallel()
+
+	testID := int(1)
+	tests := []struct {
+		name         string
+		id           interface{}
+		cmd          interface{}
+		marshalled   string
+		unmarshalled interface{}
+	}{
+		{
+			name:         "notifyblocks",
+			id:           testID,
+			cmd:          NewNotifyBlocksCmd(),
+			marshalled:   `{"jsonrpc":"1.0","method":"notifyblocks","params":[],"id":1}`,
+			unmarshalled: &NotifyBlocksCmd{},
+		},
+		{
+			name:         "notifynewtransactions",
+			id:           testID,
+			cmd:          NewNotifyNewTransactionsCmd(nil),
+			marshalled:   `{"jsonrpc":"1.0","method":"notifynewtransactions","params":[],"id":1}`,
+			unmarshalled: &NotifyNewTransactionsCmd{Verbose: Bool(false)},
+		},
+		{
+			name:         "notifynewtransactions verbose",
+			id:           testID,
+			cmd:          NewNotifyNewTransactionsCmd(Bool(true)),
+			marshalled:   `{"jsonrpc":"1.0","method":"notifynewtransactions","params":[true],"id":1}`,
+			unmarshalled: &NotifyNewTransactionsCmd{Verbose: Bool(true)},
+		},
+		{
+			name:         "notifyspent",
+			id:           testID,
+			cmd:          NewNotifySpentCmd([]OutPoint{{Hash: "123", Index: 0}}),
+			marshalled:   `{"jsonrpc":"1.0","method":"notifyspent","params":[[{"hash":"123","index":0}]],"id":1}`,
+			unmarshalled: &NotifySpentCmd{OutPoints: []OutPoint{{Hash: "123", Index: 0}}},
+		},
+		{
+			name:         "stopnotifyreceived",
+			id:           testID,
+			cmd:          NewStopNotifyReceivedCmd([]string{"1Address"}),
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifyreceived","params":[["1Address"]],"id":1}`,
+			unmarshalled: &StopNotifyReceivedCmd{Addresses: []string{"1Address"}},
+		},
+		{
+			name:         "blockconnected",
+			cmd:          NewBlockConnectedNtfn("123", 100000, 123456789),
+			marshalled:   `{"jsonrpc":"1.0","method":"blockconnected","params":["123",100000,123456789],"id":null}`,
+			unmarshalled: &BlockConnectedNtfn{Hash: "123", Height: 100000, Time: 123456789},
+		},
+		{
+			name: "redeemingtx",
+			cmd: NewRedeemingTxNtfn("001122", &BlockDetails{
+				Height: 100000,
+				Hash:   "123",
+				Index:  0,
+				Time:   12345678,
+			}),
+			marshalled: `{"jsonrpc":"1.0","method":"redeemingtx","params":["001122",{"height":100000,"hash":"123","index":0,"time":12345678}],"id":null}`,
+			unmarshalled: &RedeemingTxNtfn{
+				HexTx: "001122",
+				Block: &BlockDetails{Height: 100000, Hash: "123", Index: 0, Time: 12345678},
+			},
+		},
+		{
+			name:         "txaccepted",
+			cmd:          NewTxAcceptedNtfn("123", 1.5),
+			marshalled:   `{"jsonrpc":"1.0","method":"txaccepted","params":["123",1.5],"id":null}`,
+			unmarshalled: &TxAcceptedNtfn{TxID: "123", Amount: 1.5},
+		},
+	}
+
+	for i, test := range tests {
+		marshalled, err := MarshalCmd(test.id, test.cmd)
+		if err != nil {
+			t.Errorf("MarshalCmd #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+		if !bytes.Equal(marshalled, []byte(test.marshalled)) {
+			t.Errorf("Test #%d (%s) unexpected marshalled data - "+
+				"got %s, want %s", i, test.name, marshalled,
+				test.marshalled)
+			continue
+		}
+
+		var request Request
+		if err := json.Unmarshal(marshalled, &request); err != nil {
+			t.Errorf("Test #%d (%s) unexpected error while "+
+				"unmarshalling JSON-RPC request: %v", i,
+				test.name, err)
+			continue
+		}
+		cmd, err := UnmarshalCmd(&request)
+		if err != nil {
+			t.Errorf("UnmarshalCmd #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(cmd, test.unmarshalled) {
+			t.Errorf("Test #%d (%s) unexpected unmarshalled command "+
+				"- got %#v, want %#v", i, test.name, cmd,
+				test.unmarshalled)
+		}
+	}
+}
+
+// TestChainSvrWsFlags tests the websocket commands are flagged as such and
+// the notifications as notifications.
+func TestChainSvrWsFlags(t *testing.T) {
+	t.Parallel()
+
+	for _, method := range []string{"notifyblocks", "stopnotifyspent"} {
+		flags, err := MethodUsageFlags(method)
+		if err != nil || flags != UFWebsocketOnly {
+			t.Errorf("%s: unexpected flags %v, %v", method, flags, err)
+		}
+	}
+	flags, err := MethodUsageFlags(TxAcceptedVerboseNtfnMethod)
+	if err != nil || flags != UFWebsocketOnly|UFNotification {
+		t.Errorf("%s: unexpected flags %v, %v", TxAcceptedVerboseNtfnMethod, flags, err)
+	}
+}
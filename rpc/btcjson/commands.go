@@ -1,6 +1,7 @@
 package btcjson
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 )
@@ -36,6 +37,21 @@ func NewAddNodeCmd(addr string, subCmd AddNodeSubCmd) *AddNodeCmd {
 	}
 }
 
+// AddConnectionCmd defines the addconnection JSON-RPC command.
+type AddConnectionCmd struct {
+	Address        string
+	ConnectionType string `jsonrpcusage:"\"outbound-full-relay|block-relay-only|feeler\""`
+}
+
+// NewAddConnectionCmd returns a new instance which can be used to issue an
+// addconnection JSON-RPC command.
+func NewAddConnectionCmd(address, connectionType string) *AddConnectionCmd {
+	return &AddConnectionCmd{
+		Address:        address,
+		ConnectionType: connectionType,
+	}
+}
+
 type DisconnectNodeCmd struct {
 	Address *string
 	ID      *int
@@ -49,10 +65,71 @@ type TransactionInput struct {
 	Sequence *int64 `json:"sequence"`
 }
 
+// TransactionOutput is a single key of the outputs object of the
+// createrawtransaction command: an address with its amount, or the key "data"
+// with hex encoded data for an OP_RETURN output.
+type TransactionOutput struct {
+	Key   string
+	Value interface{}
+}
+
+// TransactionOutputs is the outputs object of the createrawtransaction
+// command. Unlike a map it keeps the keys in the order they were given,
+// including repeated ones, since that order is the order of the outputs.
+type TransactionOutputs []TransactionOutput
+
+// MarshalJSON provides a custom Marshal method for TransactionOutputs, which
+// are encoded as a JSON object.
+func (outs TransactionOutputs) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBufferString("{")
+	for i, out := range outs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(out.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(out.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON provides a custom Unmarshal method for TransactionOutputs.
+// This is necessary because a map would lose the order of the keys.
+func (outs *TransactionOutputs) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return makeError(ErrInvalidType, "outputs must be a JSON object")
+	}
+	*outs = make(TransactionOutputs, 0)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var out TransactionOutput
+		out.Key = tok.(string)
+		if err := dec.Decode(&out.Value); err != nil {
+			return err
+		}
+		*outs = append(*outs, out)
+	}
+	_, err := dec.Token()
+	return err
+}
+
 // CreateRawTransactionCmd defines the createrawtransaction JSON-RPC command.
 type CreateRawTransactionCmd struct {
 	Inputs   []TransactionInput
-	Outputs  map[string]interface{}
+	Outputs  TransactionOutputs
 	LockTime *int64
 }
 
@@ -60,7 +137,7 @@ type CreateRawTransactionCmd struct {
 // a createrawtransaction JSON-RPC command.
 //
 // Amounts are in BTC.
-func NewCreateRawTransactionCmd(inputs []TransactionInput, outputs map[string]interface{},
+func NewCreateRawTransactionCmd(inputs []TransactionInput, outputs TransactionOutputs,
 	lockTime *int64) *CreateRawTransactionCmd {
 
 	return &CreateRawTransactionCmd{
@@ -96,6 +173,19 @@ func NewDecodeScriptCmd(hexScript string) *DecodeScriptCmd {
 	}
 }
 
+// EstimateSmartFeeCmd defines the estimatesmartfee JSON-RPC command.
+type EstimateSmartFeeCmd struct {
+	ConfTarget int64
+}
+
+// NewEstimateSmartFeeCmd returns a new instance which can be used to issue an
+// estimatesmartfee JSON-RPC command.
+func NewEstimateSmartFeeCmd(confTarget int64) *EstimateSmartFeeCmd {
+	return &EstimateSmartFeeCmd{
+		ConfTarget: confTarget,
+	}
+}
+
 // GetAddedNodeInfoCmd defines the getaddednodeinfo JSON-RPC command.
 type GetAddedNodeInfoCmd struct {
 	Node *string
@@ -121,11 +211,34 @@ func NewGetBestBlockHashCmd() *GetBestBlockHashCmd {
 	return &GetBestBlockHashCmd{}
 }
 
+// BlockVerbosity is the verbosity of the getblock command: 0 for the hex
+// encoded block, 1 for the block with the txids of its transactions and 2
+// for the block with its decoded transactions.  The booleans false and true
+// of the former verbose parameter are accepted as 0 and 1.
+type BlockVerbosity int
+
+// UnmarshalJSON unmarshals a verbosity level or a verbose boolean.
+func (v *BlockVerbosity) UnmarshalJSON(data []byte) error {
+	var verbose bool
+	if err := json.Unmarshal(data, &verbose); err == nil {
+		*v = 0
+		if verbose {
+			*v = 1
+		}
+		return nil
+	}
+	var level int
+	if err := json.Unmarshal(data, &level); err != nil {
+		return err
+	}
+	*v = BlockVerbosity(level)
+	return nil
+}
+
 // GetBlockCmd defines the getblock JSON-RPC command.
 type GetBlockCmd struct {
-	Hash    string
-	Verbose *bool `jsonrpcdefault:"true"`
-	//VerboseTx *bool `jsonrpcdefault:"false"`  // todo check no such item
+	Hash      string
+	Verbosity *BlockVerbosity `jsonrpcdefault:"1"`
 }
 
 // NewGetBlockCmd returns a new instance which can be used to issue a getblock
@@ -133,11 +246,10 @@ type GetBlockCmd struct {
 //
 // The parameters which are pointers indicate they are optional.  Passing nil
 // for optional parameters will use the default value.
-func NewGetBlockCmd(hash string, verbose *bool) *GetBlockCmd {
+func NewGetBlockCmd(hash string, verbosity *BlockVerbosity) *GetBlockCmd {
 	return &GetBlockCmd{
-		Hash:    hash,
-		Verbose: verbose,
-		//VerboseTx: verboseTx,
+		Hash:      hash,
+		Verbosity: verbosity,
 	}
 }
 
@@ -150,6 +262,15 @@ func NewGetBlockChainInfoCmd() *GetBlockChainInfoCmd {
 	return &GetBlockChainInfoCmd{}
 }
 
+// GetChainStatesCmd defines the getchainstates JSON-RPC command.
+type GetChainStatesCmd struct{}
+
+// NewGetChainStatesCmd returns a new instance which can be used to issue a
+// getchainstates JSON-RPC command.
+func NewGetChainStatesCmd() *GetChainStatesCmd {
+	return &GetChainStatesCmd{}
+}
+
 // GetBlockCountCmd defines the getblockcount JSON-RPC command.
 type GetBlockCountCmd struct{}
 
@@ -187,6 +308,54 @@ func NewGetBlockHeaderCmd(hash string, verbose *bool) *GetBlockHeaderCmd {
 	}
 }
 
+// HashOrHeight identifies a block by its hash or by its height in the active
+// chain.  It unmarshals from a JSON string or number.
+type HashOrHeight struct {
+	Hash   string
+	Height *int32
+}
+
+// MarshalJSON marshals the height when it is set and the hash otherwise.
+func (h HashOrHeight) MarshalJSON() ([]byte, error) {
+	if h.Height != nil {
+		return json.Marshal(*h.Height)
+	}
+	return json.Marshal(h.Hash)
+}
+
+// UnmarshalJSON unmarshals a block hash or a block height.
+func (h *HashOrHeight) UnmarshalJSON(data []byte) error {
+	var height int32
+	if err := json.Unmarshal(data, &height); err == nil {
+		h.Hash, h.Height = "", &height
+		return nil
+	}
+	var hash string
+	if err := json.Unmarshal(data, &hash); err != nil {
+		return err
+	}
+	h.Hash, h.Height = hash, nil
+	return nil
+}
+
+// GetBlockStatsCmd defines the getblockstats JSON-RPC command.
+type GetBlockStatsCmd struct {
+	HashOrHeight HashOrHeight `jsonrpcusage:"hash_or_height"`
+	Stats        *[]string
+}
+
+// NewGetBlockStatsCmd returns a new instance which can be used to issue a
+// getblockstats JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetBlockStatsCmd(hashOrHeight HashOrHeight, stats *[]string) *GetBlockStatsCmd {
+	return &GetBlockStatsCmd{
+		HashOrHeight: hashOrHeight,
+		Stats:        stats,
+	}
+}
+
 // GetChainTxStatsCmd defines the getchaintxstats JSON-RPC command.
 type GetChainTxStatsCmd struct {
 	Blocks    *int32
@@ -372,6 +541,22 @@ func NewGetMempoolInfoCmd() *GetMempoolInfoCmd {
 	return &GetMempoolInfoCmd{}
 }
 
+// GetMemoryInfoCmd defines the getmemoryinfo JSON-RPC command.
+type GetMemoryInfoCmd struct {
+	Mode *string `jsonrpcdefault:"\"stats\""`
+}
+
+// NewGetMemoryInfoCmd returns a new instance which can be used to issue a
+// getmemoryinfo JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetMemoryInfoCmd(mode *string) *GetMemoryInfoCmd {
+	return &GetMemoryInfoCmd{
+		Mode: mode,
+	}
+}
+
 // GetMiningInfoCmd defines the getmininginfo JSON-RPC command.
 type GetMiningInfoCmd struct{}
 
@@ -447,8 +632,9 @@ func NewGetRawMempoolCmd(verbose *bool) *GetRawMempoolCmd {
 // NOTE: This field is an int versus a bool to remain compatible with Bitcoin
 // Core even though it really should be a bool.
 type GetRawTransactionCmd struct {
-	Txid    string
-	Verbose *bool `jsonrpcdefault:"false"`
+	Txid      string
+	Verbose   *bool `jsonrpcdefault:"false"`
+	BlockHash *string
 }
 
 // NewGetRawTransactionCmd returns a new instance which can be used to issue a
@@ -456,10 +642,11 @@ type GetRawTransactionCmd struct {
 //
 // The parameters which are pointers indicate they are optional.  Passing nil
 // for optional parameters will use the default value.
-func NewGetRawTransactionCmd(txHash string, verbose *bool) *GetRawTransactionCmd {
+func NewGetRawTransactionCmd(txHash string, verbose *bool, blockHash *string) *GetRawTransactionCmd {
 	return &GetRawTransactionCmd{
-		Txid:    txHash,
-		Verbose: verbose,
+		Txid:      txHash,
+		Verbose:   verbose,
+		BlockHash: blockHash,
 	}
 }
 
@@ -597,6 +784,33 @@ func NewReconsiderBlockCmd(blockHash string) *ReconsiderBlockCmd {
 	}
 }
 
+// SaveMempoolCmd defines the savemempool JSON-RPC command.
+type SaveMempoolCmd struct{}
+
+// NewSaveMempoolCmd returns a new instance which can be used to issue a
+// savemempool JSON-RPC command.
+func NewSaveMempoolCmd() *SaveMempoolCmd {
+	return &SaveMempoolCmd{}
+}
+
+// ScanTxOutSetCmd defines the scantxoutset JSON-RPC command.
+type ScanTxOutSetCmd struct {
+	Action      string
+	ScanObjects *[]string
+}
+
+// NewScanTxOutSetCmd returns a new instance which can be used to issue a
+// scantxoutset JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewScanTxOutSetCmd(action string, scanObjects *[]string) *ScanTxOutSetCmd {
+	return &ScanTxOutSetCmd{
+		Action:      action,
+		ScanObjects: scanObjects,
+	}
+}
+
 // SearchRawTransactionsCmd defines the searchrawtransactions JSON-RPC command.
 type SearchRawTransactionsCmd struct {
 	Address     string
@@ -643,6 +857,24 @@ func NewSendRawTransactionCmd(hexTx string, allowHighFees *bool) *SendRawTransac
 	}
 }
 
+// SetGenerateCmd defines the setgenerate JSON-RPC command.
+type SetGenerateCmd struct {
+	Generate     bool
+	GenProcLimit *int `jsonrpcdefault:"-1"`
+}
+
+// NewSetGenerateCmd returns a new instance which can be used to issue a
+// setgenerate JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSetGenerateCmd(generate bool, genProcLimit *int) *SetGenerateCmd {
+	return &SetGenerateCmd{
+		Generate:     generate,
+		GenProcLimit: genProcLimit,
+	}
+}
+
 // StopCmd defines the stop JSON-RPC command.
 type StopCmd struct{}
 
@@ -677,6 +909,28 @@ func NewSubmitBlockCmd(hexBlock string, options *SubmitBlockOptions) *SubmitBloc
 	}
 }
 
+// SubmitHeaderCmd defines the submitheader JSON-RPC command.
+type SubmitHeaderCmd struct {
+	HexData string
+}
+
+// NewSubmitHeaderCmd returns a new instance which can be used to issue a
+// submitheader JSON-RPC command.
+func NewSubmitHeaderCmd(hexData string) *SubmitHeaderCmd {
+	return &SubmitHeaderCmd{
+		HexData: hexData,
+	}
+}
+
+// GetRPCInfoCmd defines the getrpcinfo JSON-RPC command.
+type GetRPCInfoCmd struct{}
+
+// NewGetRPCInfoCmd returns a new instance which can be used to issue a
+// getrpcinfo JSON-RPC command.
+func NewGetRPCInfoCmd() *GetRPCInfoCmd {
+	return &GetRPCInfoCmd{}
+}
+
 // UptimeCmd defines the uptime JSON-RPC command.
 type UptimeCmd struct{}
 
@@ -776,24 +1030,24 @@ func NewPruneBlockChainCmd(height *int) *PruneBlockChainCmd {
 
 type GetMempoolAncestorsCmd struct {
 	TxID    string `json:"txid"`
-	Verbose *bool  `json: "verbose";jsonrpcdefault: "false"`
+	Verbose *bool  `json:"verbose" jsonrpcdefault:"false"`
 	// todo
 }
 
 type GetMempoolDescendantsCmd struct {
 	TxID    string `json:"txid"`
-	Verbose *bool  `json: "verbose";jsonrpcdefault: "false"`
+	Verbose *bool  `json:"verbose" jsonrpcdefault:"false"`
 	// todo
 }
 
 // RawTxInput models the data needed for raw transaction input that is used in
 // the SignRawTransactionCmd struct.
 type RawTxInput struct {
-	Txid         string `json:"txid"`
-	Vout         uint32 `json:"vout"`
-	ScriptPubKey string `json:"scriptPubKey"`
-	RedeemScript string `json:"redeemScript"`
-	Amount       *int   `json:"amount"`
+	Txid         string      `json:"txid"`
+	Vout         uint32      `json:"vout"`
+	ScriptPubKey string      `json:"scriptPubKey"`
+	RedeemScript string      `json:"redeemScript"`
+	Amount       interface{} `json:"amount"`
 }
 
 // SignRawTransactionCmd defines the signrawtransaction JSON-RPC command.
@@ -801,7 +1055,7 @@ type SignRawTransactionCmd struct {
 	RawTx    string
 	Inputs   *[]RawTxInput
 	PrivKeys *[]string
-	Flags    *string `jsonrpcdefault:"\"ALL\""`
+	Flags    *string `jsonrpcdefault:"\"ALL|FORKID\""`
 }
 
 type VerifyTxoutProofCmd struct {
@@ -825,7 +1079,19 @@ type ListBannedCmd struct {
 type ClearBannedCmd struct {
 }
 
+// CreateMultiSigCmd defines the createmultisig JSON-RPC command.
 type CreateMultiSigCmd struct {
+	NRequired int
+	Keys      []string
+}
+
+// NewCreateMultiSigCmd returns a new instance which can be used to issue a
+// createmultisig JSON-RPC command.
+func NewCreateMultiSigCmd(nRequired int, keys []string) *CreateMultiSigCmd {
+	return &CreateMultiSigCmd{
+		NRequired: nRequired,
+		Keys:      keys,
+	}
 }
 
 type SetNetWorkActiveCmd struct {
@@ -836,6 +1102,7 @@ func init() {
 	// No special flags for commands in this file.
 	flags := UsageFlag(0)
 
+	MustRegisterCmd("addconnection", (*AddConnectionCmd)(nil), flags)
 	MustRegisterCmd("addnode", (*AddNodeCmd)(nil), flags)
 	MustRegisterCmd("createrawtransaction", (*CreateRawTransactionCmd)(nil), flags)
 	MustRegisterCmd("decoderawtransaction", (*DecodeRawTransactionCmd)(nil), flags)
@@ -847,7 +1114,9 @@ func init() {
 	MustRegisterCmd("getblockcount", (*GetBlockCountCmd)(nil), flags)
 	MustRegisterCmd("getblockhash", (*GetBlockHashCmd)(nil), flags)
 	MustRegisterCmd("getblockheader", (*GetBlockHeaderCmd)(nil), flags)
+	MustRegisterCmd("getblockstats", (*GetBlockStatsCmd)(nil), flags)
 	MustRegisterCmd("getblocktemplate", (*GetBlockTemplateCmd)(nil), flags)
+	MustRegisterCmd("getchainstates", (*GetChainStatesCmd)(nil), flags)
 	MustRegisterCmd("getchaintips", (*GetChainTipsCmd)(nil), flags)
 	MustRegisterCmd("getchaintxstats", (*GetChainTxStatsCmd)(nil), flags)
 	MustRegisterCmd("getconnectioncount", (*GetConnectionCountCmd)(nil), flags)
@@ -857,6 +1126,7 @@ func init() {
 	MustRegisterCmd("getinfo", (*GetInfoCmd)(nil), flags)
 	MustRegisterCmd("getmempoolentry", (*GetMempoolEntryCmd)(nil), flags)
 	MustRegisterCmd("getmempoolinfo", (*GetMempoolInfoCmd)(nil), flags)
+	MustRegisterCmd("getmemoryinfo", (*GetMemoryInfoCmd)(nil), flags)
 	MustRegisterCmd("getmininginfo", (*GetMiningInfoCmd)(nil), flags)
 	MustRegisterCmd("getnetworkinfo", (*GetNetworkInfoCmd)(nil), flags)
 	MustRegisterCmd("getnettotals", (*GetNetTotalsCmd)(nil), flags)
@@ -874,11 +1144,16 @@ func init() {
 	MustRegisterCmd("ping", (*PingCmd)(nil), flags)
 	MustRegisterCmd("preciousblock", (*PreciousBlockCmd)(nil), flags)
 	MustRegisterCmd("reconsiderblock", (*ReconsiderBlockCmd)(nil), flags)
+	MustRegisterCmd("savemempool", (*SaveMempoolCmd)(nil), flags)
+	MustRegisterCmd("scantxoutset", (*ScanTxOutSetCmd)(nil), flags)
 	MustRegisterCmd("searchrawtransactions", (*SearchRawTransactionsCmd)(nil), flags)
 	MustRegisterCmd("sendrawtransaction", (*SendRawTransactionCmd)(nil), flags)
+	MustRegisterCmd("setgenerate", (*SetGenerateCmd)(nil), flags)
 	MustRegisterCmd("signmessagewithprivkey", (*SignMessageWithPrivkeyCmd)(nil), flags)
 	MustRegisterCmd("stop", (*StopCmd)(nil), flags)
 	MustRegisterCmd("submitblock", (*SubmitBlockCmd)(nil), flags)
+	MustRegisterCmd("submitheader", (*SubmitHeaderCmd)(nil), flags)
+	MustRegisterCmd("getrpcinfo", (*GetRPCInfoCmd)(nil), flags)
 	MustRegisterCmd("uptime", (*UptimeCmd)(nil), flags)
 	MustRegisterCmd("validateaddress", (*ValidateAddressCmd)(nil), flags)
 	MustRegisterCmd("verifychain", (*VerifyChainCmd)(nil), flags)
@@ -900,4 +1175,5 @@ func init() {
 	MustRegisterCmd("pruneblockchain", (*PruneBlockChainCmd)(nil), flags)
 	MustRegisterCmd("createmultisig", (*CreateMultiSigCmd)(nil), flags)
 	MustRegisterCmd("estimatefee", (*EstimateFeeCmd)(nil), flags)
+	MustRegisterCmd("estimatesmartfee", (*EstimateSmartFeeCmd)(nil), flags)
 }
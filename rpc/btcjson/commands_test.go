@@ -4,6 +4,48 @@
 
 package btcjson
 
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestGetBlockVerbosity ensures getblock accepts the verbose booleans of the
+// former interface as well as the verbosity levels.
+func TestGetBlockVerbosity(t *testing.T) {
+	tests := []struct {
+		params    string
+		verbosity BlockVerbosity
+		valid     bool
+	}{
+		{`["123"]`, 1, true},
+		{`["123",false]`, 0, true},
+		{`["123",true]`, 1, true},
+		{`["123",0]`, 0, true},
+		{`["123",1]`, 1, true},
+		{`["123",2]`, 2, true},
+		{`["123","true"]`, 0, false},
+		{`["123",1.5]`, 0, false},
+	}
+	for _, test := range tests {
+		request := Request{Jsonrpc: "1.0", Method: "getblock", ID: 1}
+		if err := json.Unmarshal([]byte(test.params), &request.Params); err != nil {
+			t.Fatal(err)
+		}
+		cmd, err := UnmarshalCmd(&request)
+		if (err == nil) != test.valid {
+			t.Errorf("params %s: error %v, expect valid %t", test.params, err, test.valid)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		gbCmd := cmd.(*GetBlockCmd)
+		if gbCmd.Hash != "123" || gbCmd.Verbosity == nil || *gbCmd.Verbosity != test.verbosity {
+			t.Errorf("params %s: got %+v, expect verbosity %d", test.params, gbCmd, test.verbosity)
+		}
+	}
+}
+
 /*
 import (
 	"bytes"
@@ -13,6 +55,11 @@ import (
 	"testing"
 )
 
+func blockVerbosity(v int) *BlockVerbosity {
+	verbosity := BlockVerbosity(v)
+	return &verbosity
+}
+
 // TestChainSvrCmds tests all of the chain server commands marshal and unmarshal
 // into valid results include handling of optional fields being omitted in the
 // marshalled command, while optional fields with defaults have the default
@@ -49,13 +96,13 @@ func TestChainSvrCmds(t *testing.T) {
 				txInputs := []TransactionInput{
 					{Txid: "123", Vout: 1},
 				}
-				amounts := map[string]float64{"456": .0123}
+				amounts := TransactionOutputs{{Key: "456", Value: .0123}}
 				return NewCreateRawTransactionCmd(txInputs, amounts, nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"createrawtransaction","params":[[{"txid":"123","vout":1}],{"456":0.0123}],"id":1}`,
 			unmarshalled: &CreateRawTransactionCmd{
 				Inputs:  []TransactionInput{{Txid: "123", Vout: 1}},
-				Amounts: map[string]float64{"456": .0123},
+				Outputs: TransactionOutputs{{Key: "456", Value: .0123}},
 			},
 		},
 		{
@@ -68,13 +115,13 @@ func TestChainSvrCmds(t *testing.T) {
 				txInputs := []TransactionInput{
 					{Txid: "123", Vout: 1},
 				}
-				amounts := map[string]float64{"456": .0123}
+				amounts := TransactionOutputs{{Key: "456", Value: .0123}}
 				return NewCreateRawTransactionCmd(txInputs, amounts, Int64(12312333333))
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"createrawtransaction","params":[[{"txid":"123","vout":1}],{"456":0.0123},12312333333],"id":1}`,
 			unmarshalled: &CreateRawTransactionCmd{
 				Inputs:   []TransactionInput{{Txid: "123", Vout: 1}},
-				Amounts:  map[string]float64{"456": .0123},
+				Outputs:  TransactionOutputs{{Key: "456", Value: .0123}},
 				LockTime: Int64(12312333333),
 			},
 		},
@@ -101,6 +148,17 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"decodescript","params":["00"],"id":1}`,
 			unmarshalled: &DecodeScriptCmd{HexScript: "00"},
 		},
+		{
+			name: "estimatesmartfee",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("estimatesmartfee", 6)
+			},
+			staticCmd: func() interface{} {
+				return NewEstimateSmartFeeCmd(6)
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"estimatesmartfee","params":[6],"id":1}`,
+			unmarshalled: &EstimateSmartFeeCmd{ConfTarget: 6},
+		},
 		{
 			name: "getaddednodeinfo",
 			newCmd: func() (interface{}, error) {
@@ -146,9 +204,8 @@ func TestChainSvrCmds(t *testing.T) {
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"getblock","params":["123"],"id":1}`,
 			unmarshalled: &GetBlockCmd{
-				Hash:    "123",
-				Verbose: Bool(true),
-				//VerboseTx: Bool(false),
+				Hash:      "123",
+				Verbosity: blockVerbosity(1),
 			},
 		},
 		{
@@ -157,32 +214,30 @@ func TestChainSvrCmds(t *testing.T) {
 				// Intentionally use a source param that is
 				// more pointers than the destination to
 				// exercise that path.
-				verbosePtr := Bool(true)
-				return NewCmd("getblock", "123", &verbosePtr)
+				verbosityPtr := blockVerbosity(1)
+				return NewCmd("getblock", "123", &verbosityPtr)
 			},
 			staticCmd: func() interface{} {
-				return NewGetBlockCmd("123", Bool(true))
+				return NewGetBlockCmd("123", blockVerbosity(1))
 			},
-			marshalled: `{"jsonrpc":"1.0","method":"getblock","params":["123",true],"id":1}`,
+			marshalled: `{"jsonrpc":"1.0","method":"getblock","params":["123",1],"id":1}`,
 			unmarshalled: &GetBlockCmd{
-				Hash:    "123",
-				Verbose: Bool(true),
-				//VerboseTx: Bool(false),
+				Hash:      "123",
+				Verbosity: blockVerbosity(1),
 			},
 		},
 		{
 			name: "getblock required optional2",
 			newCmd: func() (interface{}, error) {
-				return NewCmd("getblock", "123", true)
+				return NewCmd("getblock", "123", 2)
 			},
 			staticCmd: func() interface{} {
-				return NewGetBlockCmd("123", Bool(true))
+				return NewGetBlockCmd("123", blockVerbosity(2))
 			},
-			marshalled: `{"jsonrpc":"1.0","method":"getblock","params":["123",true],"id":1}`,
+			marshalled: `{"jsonrpc":"1.0","method":"getblock","params":["123",2],"id":1}`,
 			unmarshalled: &GetBlockCmd{
-				Hash:    "123",
-				Verbose: Bool(true),
-				//VerboseTx: Bool(true),
+				Hash:      "123",
+				Verbosity: blockVerbosity(2),
 			},
 		},
 		{
@@ -619,6 +674,42 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"gettxoutsetinfo","params":[],"id":1}`,
 			unmarshalled: &GetTxOutSetInfoCmd{},
 		},
+		{
+			name: "savemempool",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("savemempool")
+			},
+			staticCmd: func() interface{} {
+				return NewSaveMempoolCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"savemempool","params":[],"id":1}`,
+			unmarshalled: &SaveMempoolCmd{},
+		},
+		{
+			name: "scantxoutset",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("scantxoutset", "start", []string{"raw(51)"})
+			},
+			staticCmd: func() interface{} {
+				return NewScanTxOutSetCmd("start", &[]string{"raw(51)"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"scantxoutset","params":["start",["raw(51)"]],"id":1}`,
+			unmarshalled: &ScanTxOutSetCmd{
+				Action:      "start",
+				ScanObjects: &[]string{"raw(51)"},
+			},
+		},
+		{
+			name: "scantxoutset status",
+			newCmd: func() (interface{}, error) {
+				return NewCmd("scantxoutset", "status")
+			},
+			staticCmd: func() interface{} {
+				return NewScanTxOutSetCmd("status", nil)
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"scantxoutset","params":["status"],"id":1}`,
+			unmarshalled: &ScanTxOutSetCmd{Action: "status"},
+		},
 		{
 			name: "getwork",
 			newCmd: func() (interface{}, error) {
@@ -47,14 +47,18 @@ func NewNodeCmd(subCmd NodeSubCmd, target string, connectSubCmd *string) *NodeCm
 // GenerateCmd defines the generate JSON-RPC command.
 type GenerateCmd struct {
 	NumBlocks uint32
-	MaxTries  uint64
+	MaxTries  *uint64 `jsonrpcdefault:"1000000"`
 }
 
 // NewGenerateCmd returns a new instance which can be used to issue a generate
 // JSON-RPC command.
-func NewGenerateCmd(numBlocks uint32) *GenerateCmd {
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGenerateCmd(numBlocks uint32, maxTries *uint64) *GenerateCmd {
 	return &GenerateCmd{
 		NumBlocks: numBlocks,
+		MaxTries:  maxTries,
 	}
 }
 
@@ -75,7 +79,20 @@ func NewEstimateFeeCmd(numBlocks int64) *EstimateFeeCmd {
 type GenerateToAddressCmd struct {
 	NumBlocks uint32
 	Address   string
-	MaxTries  uint64
+	MaxTries  *uint64 `jsonrpcdefault:"1000000"`
+}
+
+// NewGenerateToAddressCmd returns a new instance which can be used to issue a
+// generatetoaddress JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGenerateToAddressCmd(numBlocks uint32, address string, maxTries *uint64) *GenerateToAddressCmd {
+	return &GenerateToAddressCmd{
+		NumBlocks: numBlocks,
+		Address:   address,
+		MaxTries:  maxTries,
+	}
 }
 
 // GetBestBlockCmd defines the getbestblock JSON-RPC command.
@@ -117,6 +134,272 @@ func NewGetHeadersCmd(blockLocators []string, hashStop string) *GetHeadersCmd {
 	}
 }
 
+// GetRawTransactionsCmd defines the getrawtransactions JSON-RPC command.
+//
+// NOTE: This is a copernicus extension, a batch variant of getrawtransaction.
+type GetRawTransactionsCmd struct {
+	Txids   []string
+	Verbose *bool `jsonrpcdefault:"false"`
+}
+
+// NewGetRawTransactionsCmd returns a new instance which can be used to issue
+// a getrawtransactions JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetRawTransactionsCmd(txids []string, verbose *bool) *GetRawTransactionsCmd {
+	return &GetRawTransactionsCmd{
+		Txids:   txids,
+		Verbose: verbose,
+	}
+}
+
+// GetRPCStatsCmd defines the getrpcstats JSON-RPC command.
+//
+// NOTE: This is a copernicus extension.
+type GetRPCStatsCmd struct{}
+
+// NewGetRPCStatsCmd returns a new instance which can be used to issue a
+// getrpcstats JSON-RPC command.
+func NewGetRPCStatsCmd() *GetRPCStatsCmd {
+	return &GetRPCStatsCmd{}
+}
+
+// GetVersionInfoCmd defines the getversioninfo JSON-RPC command.
+//
+// NOTE: This is a copernicus extension.
+type GetVersionInfoCmd struct{}
+
+// NewGetVersionInfoCmd returns a new instance which can be used to issue a
+// getversioninfo JSON-RPC command.
+func NewGetVersionInfoCmd() *GetVersionInfoCmd {
+	return &GetVersionInfoCmd{}
+}
+
+// GetUTXODistributionCmd defines the getutxodistribution JSON-RPC command.
+// SampleRate accounts one output in SampleRate only, all of them by default.
+//
+// NOTE: This is a copernicus extension.
+type GetUTXODistributionCmd struct {
+	SampleRate *uint32 `jsonrpcdefault:"1"`
+}
+
+// NewGetUTXODistributionCmd returns a new instance which can be used to issue
+// a getutxodistribution JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetUTXODistributionCmd(sampleRate *uint32) *GetUTXODistributionCmd {
+	return &GetUTXODistributionCmd{
+		SampleRate: sampleRate,
+	}
+}
+
+// GetBlockLocationsCmd defines the getblocklocations JSON-RPC command.
+// NBlocks is the number of blocks reported, following the block in the active
+// chain.
+//
+// NOTE: This is a copernicus extension.
+type GetBlockLocationsCmd struct {
+	BlockHash string
+	NBlocks   *int32 `jsonrpcdefault:"1"`
+}
+
+// NewGetBlockLocationsCmd returns a new instance which can be used to issue a
+// getblocklocations JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetBlockLocationsCmd(blockHash string, nBlocks *int32) *GetBlockLocationsCmd {
+	return &GetBlockLocationsCmd{
+		BlockHash: blockHash,
+		NBlocks:   nBlocks,
+	}
+}
+
+// GetHexBlockHeaderChainCmd defines the gethexblockheaderchain JSON-RPC
+// command.
+//
+// NOTE: This is a copernicus extension.
+type GetHexBlockHeaderChainCmd struct {
+	TxID      string
+	NHeaders  *int32 `jsonrpcdefault:"6"`
+	BlockHash *string
+}
+
+// NewGetHexBlockHeaderChainCmd returns a new instance which can be used to
+// issue a gethexblockheaderchain JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetHexBlockHeaderChainCmd(txID string, nHeaders *int32, blockHash *string) *GetHexBlockHeaderChainCmd {
+	return &GetHexBlockHeaderChainCmd{
+		TxID:      txID,
+		NHeaders:  nHeaders,
+		BlockHash: blockHash,
+	}
+}
+
+// AddressIndexRequest selects the addresses, and for the commands reporting
+// history the block height range, of the address index commands.
+type AddressIndexRequest struct {
+	Addresses []string `json:"addresses"`
+	Start     *int32   `json:"start,omitempty"`
+	End       *int32   `json:"end,omitempty"`
+}
+
+// GetAddressTxIDsCmd defines the getaddresstxids JSON-RPC command.
+//
+// NOTE: This is a copernicus extension compatible with bitcore.
+type GetAddressTxIDsCmd struct {
+	Request AddressIndexRequest
+}
+
+// NewGetAddressTxIDsCmd returns a new instance which can be used to issue a
+// getaddresstxids JSON-RPC command.
+func NewGetAddressTxIDsCmd(request AddressIndexRequest) *GetAddressTxIDsCmd {
+	return &GetAddressTxIDsCmd{Request: request}
+}
+
+// GetAddressBalanceCmd defines the getaddressbalance JSON-RPC command.
+//
+// NOTE: This is a copernicus extension compatible with bitcore.
+type GetAddressBalanceCmd struct {
+	Request AddressIndexRequest
+}
+
+// NewGetAddressBalanceCmd returns a new instance which can be used to issue a
+// getaddressbalance JSON-RPC command.
+func NewGetAddressBalanceCmd(request AddressIndexRequest) *GetAddressBalanceCmd {
+	return &GetAddressBalanceCmd{Request: request}
+}
+
+// GetAddressUtxosCmd defines the getaddressutxos JSON-RPC command.
+//
+// NOTE: This is a copernicus extension compatible with bitcore.
+type GetAddressUtxosCmd struct {
+	Request AddressIndexRequest
+}
+
+// NewGetAddressUtxosCmd returns a new instance which can be used to issue a
+// getaddressutxos JSON-RPC command.
+func NewGetAddressUtxosCmd(request AddressIndexRequest) *GetAddressUtxosCmd {
+	return &GetAddressUtxosCmd{Request: request}
+}
+
+// GetAddressDeltasCmd defines the getaddressdeltas JSON-RPC command.
+//
+// NOTE: This is a copernicus extension compatible with bitcore.
+type GetAddressDeltasCmd struct {
+	Request AddressIndexRequest
+}
+
+// NewGetAddressDeltasCmd returns a new instance which can be used to issue a
+// getaddressdeltas JSON-RPC command.
+func NewGetAddressDeltasCmd(request AddressIndexRequest) *GetAddressDeltasCmd {
+	return &GetAddressDeltasCmd{Request: request}
+}
+
+// SpentInfoRequest selects the output of the getspentinfo command.
+type SpentInfoRequest struct {
+	TxID  string `json:"txid"`
+	Index uint32 `json:"index"`
+}
+
+// GetSpentInfoCmd defines the getspentinfo JSON-RPC command.
+//
+// NOTE: This is a copernicus extension compatible with bitcore.
+type GetSpentInfoCmd struct {
+	Request SpentInfoRequest
+}
+
+// NewGetSpentInfoCmd returns a new instance which can be used to issue a
+// getspentinfo JSON-RPC command.
+func NewGetSpentInfoCmd(request SpentInfoRequest) *GetSpentInfoCmd {
+	return &GetSpentInfoCmd{Request: request}
+}
+
+// RollbackChainCmd defines the rollbackchain JSON-RPC command.
+//
+// NOTE: This is a copernicus extension.
+type RollbackChainCmd struct {
+	Height   int32
+	Override *bool `jsonrpcdefault:"false"`
+}
+
+// NewRollbackChainCmd returns a new instance which can be used to issue a
+// rollbackchain JSON-RPC command.
+func NewRollbackChainCmd(height int32, override *bool) *RollbackChainCmd {
+	return &RollbackChainCmd{
+		Height:   height,
+		Override: override,
+	}
+}
+
+// SyncMempoolCmd defines the syncmempool JSON-RPC command.
+//
+// NOTE: This is a copernicus extension.
+type SyncMempoolCmd struct {
+	ID int32
+}
+
+// NewSyncMempoolCmd returns a new instance which can be used to issue a
+// syncmempool JSON-RPC command.
+func NewSyncMempoolCmd(id int32) *SyncMempoolCmd {
+	return &SyncMempoolCmd{
+		ID: id,
+	}
+}
+
+// GetActivityCmd defines the getactivity JSON-RPC command.
+//
+// NOTE: This is a copernicus extension.
+type GetActivityCmd struct {
+	Sequence *uint64 `jsonrpcdefault:"0"`
+}
+
+// NewGetActivityCmd returns a new instance which can be used to issue a
+// getactivity JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetActivityCmd(sequence *uint64) *GetActivityCmd {
+	return &GetActivityCmd{
+		Sequence: sequence,
+	}
+}
+
+// GetUtxoCacheStatsCmd defines the getutxocachestats JSON-RPC command.
+//
+// NOTE: This is a copernicus extension.
+type GetUtxoCacheStatsCmd struct{}
+
+// NewGetUtxoCacheStatsCmd returns a new instance which can be used to issue a
+// getutxocachestats JSON-RPC command.
+func NewGetUtxoCacheStatsCmd() *GetUtxoCacheStatsCmd {
+	return &GetUtxoCacheStatsCmd{}
+}
+
+// GetDifficultyHistoryCmd defines the getdifficultyhistory JSON-RPC command.
+//
+// NOTE: This is a copernicus extension.
+type GetDifficultyHistoryCmd struct {
+	StartHeight int32
+	EndHeight   *int32 `jsonrpcdefault:"-1"`
+}
+
+// NewGetDifficultyHistoryCmd returns a new instance which can be used to
+// issue a getdifficultyhistory JSON-RPC command.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetDifficultyHistoryCmd(startHeight int32, endHeight *int32) *GetDifficultyHistoryCmd {
+	return &GetDifficultyHistoryCmd{
+		StartHeight: startHeight,
+		EndHeight:   endHeight,
+	}
+}
+
 func init() {
 	// No special flags for commands in this file.
 	flags := UsageFlag(0)
@@ -126,4 +409,20 @@ func init() {
 	MustRegisterCmd("getbestblock", (*GetBestBlockCmd)(nil), flags)
 	MustRegisterCmd("getcurrentnet", (*GetCurrentNetCmd)(nil), flags)
 	MustRegisterCmd("getheaders", (*GetHeadersCmd)(nil), flags)
+	MustRegisterCmd("getrawtransactions", (*GetRawTransactionsCmd)(nil), flags)
+	MustRegisterCmd("getrpcstats", (*GetRPCStatsCmd)(nil), flags)
+	MustRegisterCmd("getversioninfo", (*GetVersionInfoCmd)(nil), flags)
+	MustRegisterCmd("getutxodistribution", (*GetUTXODistributionCmd)(nil), flags)
+	MustRegisterCmd("getblocklocations", (*GetBlockLocationsCmd)(nil), flags)
+	MustRegisterCmd("gethexblockheaderchain", (*GetHexBlockHeaderChainCmd)(nil), flags)
+	MustRegisterCmd("getaddresstxids", (*GetAddressTxIDsCmd)(nil), flags)
+	MustRegisterCmd("getaddressbalance", (*GetAddressBalanceCmd)(nil), flags)
+	MustRegisterCmd("getaddressutxos", (*GetAddressUtxosCmd)(nil), flags)
+	MustRegisterCmd("getaddressdeltas", (*GetAddressDeltasCmd)(nil), flags)
+	MustRegisterCmd("getspentinfo", (*GetSpentInfoCmd)(nil), flags)
+	MustRegisterCmd("rollbackchain", (*RollbackChainCmd)(nil), flags)
+	MustRegisterCmd("syncmempool", (*SyncMempoolCmd)(nil), flags)
+	MustRegisterCmd("getactivity", (*GetActivityCmd)(nil), flags)
+	MustRegisterCmd("getutxocachestats", (*GetUtxoCacheStatsCmd)(nil), flags)
+	MustRegisterCmd("getdifficultyhistory", (*GetDifficultyHistoryCmd)(nil), flags)
 }
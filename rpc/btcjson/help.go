@@ -501,6 +501,72 @@ func isValidResultType(kind reflect.Kind) bool {
 //   "help--result0":    "List of commands"
 //   "help--result1":    "Help for specified command"
 func GenerateHelp(method string, descs map[string]string, resultTypes ...interface{}) (string, error) {
+	rtp, info, err := helpMethodInfo(method, resultTypes)
+	if err != nil {
+		return "", err
+	}
+
+	// Create a closure for the description lookup function which falls back
+	// to the base help descriptions map for unrecognized keys and tracks
+	// and missing keys.
+	var missingKey string
+	xT := func(key string) string {
+		if desc, ok := descs[key]; ok {
+			return desc
+		}
+		if desc, ok := baseHelpDescs[key]; ok {
+			return desc
+		}
+
+		missingKey = key
+		return key
+	}
+
+	// Generate and return the help for the method.
+	help := methodHelp(xT, rtp, info.defaults, method, resultTypes)
+	if missingKey != "" {
+		return help, makeError(ErrMissingDescription, missingKey)
+	}
+	return help, nil
+}
+
+// GenerateUsageHelp generates and returns help output for the provided method
+// and result types like GenerateHelp, but derived from the registration of the
+// method alone: the usage, the argument names and types along with their
+// defaults, and the schema of the results.  Only the synopsis is provided by
+// the caller, the arguments and result fields are left without a description.
+func GenerateUsageHelp(method string, synopsis string, resultTypes ...interface{}) (string, error) {
+	rtp, info, err := helpMethodInfo(method, resultTypes)
+	if err != nil {
+		return "", err
+	}
+
+	xT := func(key string) string {
+		switch {
+		case key == method+"--synopsis":
+			return synopsis
+		case strings.HasSuffix(key, "--key"):
+			return "key"
+		case strings.HasSuffix(key, "--value"):
+			return "value"
+		}
+		return baseHelpDescs[key]
+	}
+
+	// Drop the padding left at the end of the lines by the missing
+	// descriptions.
+	help := methodHelp(xT, rtp, info.defaults, method, resultTypes)
+	lines := strings.Split(help, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// helpMethodInfo returns the concrete type and the details of the registered
+// method after validating each result type is a pointer to a supported type
+// (or nil).
+func helpMethodInfo(method string, resultTypes []interface{}) (reflect.Type, methodInfo, error) {
 	// Look up details about the provided method and error out if not
 	// registered.
 	registerLock.RLock()
@@ -509,10 +575,9 @@ func GenerateHelp(method string, descs map[string]string, resultTypes ...interfa
 	registerLock.RUnlock()
 	if !ok {
 		str := fmt.Sprintf("%q is not registered", method)
-		return "", makeError(ErrUnregisteredMethod, str)
+		return nil, info, makeError(ErrUnregisteredMethod, str)
 	}
 
-	// Validate each result type is a pointer to a supported type (or nil).
 	for i, resultType := range resultTypes {
 		if resultType == nil {
 			continue
@@ -522,37 +587,15 @@ func GenerateHelp(method string, descs map[string]string, resultTypes ...interfa
 		if rtp.Kind() != reflect.Ptr {
 			str := fmt.Sprintf("result #%d (%v) is not a pointer",
 				i, rtp.Kind())
-			return "", makeError(ErrInvalidType, str)
+			return nil, info, makeError(ErrInvalidType, str)
 		}
 
 		elemKind := rtp.Elem().Kind()
 		if !isValidResultType(elemKind) {
 			str := fmt.Sprintf("result #%d (%v) is not an allowed "+
 				"type", i, elemKind)
-			return "", makeError(ErrInvalidType, str)
+			return nil, info, makeError(ErrInvalidType, str)
 		}
 	}
-
-	// Create a closure for the description lookup function which falls back
-	// to the base help descriptions map for unrecognized keys and tracks
-	// and missing keys.
-	var missingKey string
-	xT := func(key string) string {
-		if desc, ok := descs[key]; ok {
-			return desc
-		}
-		if desc, ok := baseHelpDescs[key]; ok {
-			return desc
-		}
-
-		missingKey = key
-		return key
-	}
-
-	// Generate and return the help for the method.
-	help := methodHelp(xT, rtp, info.defaults, method, resultTypes)
-	if missingKey != "" {
-		return help, makeError(ErrMissingDescription, missingKey)
-	}
-	return help, nil
+	return rtp, info, nil
 }
@@ -6,6 +6,7 @@ package btcjson
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -733,3 +734,35 @@ func TestGenerateHelp(t *testing.T) {
 			help, wantHelp)
 	}
 }
+
+// TestGenerateUsageHelp ensures GenerateUsageHelp renders the arguments with
+// their defaults and the result schema without requiring descriptions.
+func TestGenerateUsageHelp(t *testing.T) {
+	t.Parallel()
+
+	help, err := GenerateUsageHelp("getmemoryinfo", "test", (*string)(nil))
+	if err != nil {
+		t.Fatalf("GenerateUsageHelp: unexpected error: %v", err)
+	}
+	wantHelp := "getmemoryinfo (mode=\"stats\")\n\n" +
+		"test\n\nArguments:\n" +
+		"1. mode (string, optional, default=\"stats\")\n\n" +
+		"Result:\n\"value\" (string)\n"
+	if help != wantHelp {
+		t.Fatalf("GenerateUsageHelp: unexpected help - got\n%v\nwant\n%v",
+			help, wantHelp)
+	}
+
+	help, err = GenerateUsageHelp("getnettotals", "test",
+		(*GetNetTotalsResult)(nil))
+	if err != nil {
+		t.Fatalf("GenerateUsageHelp: unexpected error: %v", err)
+	}
+	if !strings.Contains(help, "\"totalbytesrecv\": n,") {
+		t.Fatalf("GenerateUsageHelp: missing result field in\n%v", help)
+	}
+
+	if _, err := GenerateUsageHelp("nonexistent", "test"); err == nil {
+		t.Fatal("GenerateUsageHelp: expected error on unregistered method")
+	}
+}
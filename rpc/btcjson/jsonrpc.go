@@ -40,6 +40,19 @@ func NewRPCError(code RPCErrorCode, message string) *RPCError {
 	}
 }
 
+// Protocol versions accepted in the jsonrpc field of a request.  Requests
+// without the field are JSON-RPC 1.0 requests.
+const (
+	RPCVersion1 = "1.0"
+	RPCVersion2 = "2.0"
+)
+
+// IsValidRPCVersion returns whether the passed jsonrpc field names a supported
+// protocol version.
+func IsValidRPCVersion(version string) bool {
+	return version == RPCVersion1 || version == RPCVersion2
+}
+
 // IsValidIDType checks that the ID field (which can go in any of the JSON-RPC
 // requests, responses, or notifications) is valid.  JSON-RPC 1.0 allows any
 // valid JSON type.  JSON-RPC 2.0 (which bitcoind follows for some parts) only
@@ -148,3 +161,35 @@ func MarshalResponse(id interface{}, result interface{}, rpcErr *RPCError) ([]by
 	}
 	return json.Marshal(&response)
 }
+
+// Response2 is the JSON-RPC 2.0 form of a response.  Unlike 1.0 it names the
+// protocol version and carries exactly one of the result and error members.
+type Response2 struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      *interface{}    `json:"id"`
+}
+
+// MarshalVersionedResponse marshals the passed id, result, and RPCError to a
+// response framed for the given protocol version.  Any version other than
+// RPCVersion2 produces the same output as MarshalResponse.
+func MarshalVersionedResponse(rpcVersion string, id interface{}, result interface{}, rpcErr *RPCError) ([]byte, error) {
+	if rpcVersion != RPCVersion2 {
+		return MarshalResponse(id, result, rpcErr)
+	}
+	if !IsValidIDType(id) {
+		str := fmt.Sprintf("the id of type '%T' is invalid", id)
+		return nil, makeError(ErrInvalidType, str)
+	}
+
+	response := Response2{Jsonrpc: RPCVersion2, Error: rpcErr, ID: &id}
+	if rpcErr == nil {
+		marshalledResult, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+		response.Result = marshalledResult
+	}
+	return json.Marshal(&response)
+}
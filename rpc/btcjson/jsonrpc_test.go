@@ -96,6 +96,66 @@ func TestMarshalResponse(t *testing.T) {
 	}
 }
 
+// TestMarshalVersionedResponse ensures responses are framed according to the
+// protocol version of the request.
+func TestMarshalVersionedResponse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		version  string
+		id       interface{}
+		result   interface{}
+		jsonErr  *RPCError
+		expected []byte
+	}{
+		{
+			name:     "1.0 result",
+			version:  RPCVersion1,
+			id:       1,
+			result:   true,
+			expected: []byte(`{"result":true,"error":null,"id":1}`),
+		},
+		{
+			name:     "2.0 result",
+			version:  RPCVersion2,
+			id:       "a",
+			result:   true,
+			expected: []byte(`{"jsonrpc":"2.0","result":true,"id":"a"}`),
+		},
+		{
+			name:     "2.0 null result",
+			version:  RPCVersion2,
+			id:       1,
+			result:   nil,
+			expected: []byte(`{"jsonrpc":"2.0","result":null,"id":1}`),
+		},
+		{
+			name:     "2.0 error",
+			version:  RPCVersion2,
+			id:       nil,
+			jsonErr:  ErrRPCParse,
+			expected: []byte(`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`),
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		marshalled, err := MarshalVersionedResponse(test.version, test.id, test.result, test.jsonErr)
+		if err != nil {
+			t.Errorf("Test #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(marshalled, test.expected) {
+			t.Errorf("Test #%d (%s) mismatched result - got %s, "+
+				"want %s", i, test.name, marshalled,
+				test.expected)
+		}
+	}
+}
+
 // TestMiscErrors tests a few error conditions not covered elsewhere.
 func TestMiscErrors(t *testing.T) {
 	t.Parallel()
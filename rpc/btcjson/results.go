@@ -13,7 +13,7 @@ import (
 // returns a hex-encoded string.
 type GetBlockHeaderVerboseResult struct {
 	Hash          string  `json:"hash"`
-	Confirmations uint64  `json:"confirmations"`
+	Confirmations int32   `json:"confirmations"`
 	Height        int32   `json:"height"`
 	Version       int32   `json:"version"`
 	VersionHex    string  `json:"versionHex"`
@@ -23,7 +23,8 @@ type GetBlockHeaderVerboseResult struct {
 	Nonce         uint64  `json:"nonce"`
 	Bits          string  `json:"bits"`
 	Difficulty    float64 `json:"difficulty"`
-	Chainwork     string  `josn:"chainwork"`
+	Chainwork     string  `json:"chainwork"`
+	NTx           int32   `json:"nTx"`
 	PreviousHash  string  `json:"previousblockhash,omitempty"`
 	NextHash      string  `json:"nextblockhash,omitempty"`
 }
@@ -50,6 +51,43 @@ type GetBlockVerboseResult struct {
 	NextHash      string   `json:"nextblockhash,omitempty"`
 }
 
+// GetBlockVerboseTxResult models the data from the getblock command when
+// the verbosity is 2, with the decoded transactions in place of their txids.
+type GetBlockVerboseTxResult struct {
+	GetBlockVerboseResult
+	Tx []TxRawResult `json:"tx"`
+}
+
+// GetBlockStatsResult models the data from the getblockstats command.  Amounts
+// are in satoshis and fee rates in satoshis per byte.
+type GetBlockStatsResult struct {
+	AvgFee             int64    `json:"avgfee"`
+	AvgFeeRate         int64    `json:"avgfeerate"`
+	AvgTxSize          int64    `json:"avgtxsize"`
+	BlockHash          string   `json:"blockhash"`
+	FeeRatePercentiles [5]int64 `json:"feerate_percentiles"`
+	Height             int32    `json:"height"`
+	Ins                int64    `json:"ins"`
+	MaxFee             int64    `json:"maxfee"`
+	MaxFeeRate         int64    `json:"maxfeerate"`
+	MaxTxSize          int64    `json:"maxtxsize"`
+	MedianFee          int64    `json:"medianfee"`
+	MedianTime         int64    `json:"mediantime"`
+	MedianTxSize       int64    `json:"mediantxsize"`
+	MinFee             int64    `json:"minfee"`
+	MinFeeRate         int64    `json:"minfeerate"`
+	MinTxSize          int64    `json:"mintxsize"`
+	Outs               int64    `json:"outs"`
+	Subsidy            int64    `json:"subsidy"`
+	Time               int64    `json:"time"`
+	TotalOut           int64    `json:"total_out"`
+	TotalSize          int64    `json:"total_size"`
+	TotalFee           int64    `json:"totalfee"`
+	Txs                int64    `json:"txs"`
+	UtxoIncrease       int64    `json:"utxo_increase"`
+	UtxoSizeInc        int64    `json:"utxo_size_inc"`
+}
+
 // GetChainTxStatsResult models the data from the getchaintxstats command.
 type GetChainTxStatsResult struct {
 	FinalTime      uint32  `json:"time"`
@@ -100,6 +138,16 @@ type SoftForkDescription struct {
 	} `json:"reject"`
 }
 
+// UpgradeDescription describes the activation of a Bitcoin Cash network
+// upgrade, scheduled at a block height or at a median time past.
+type UpgradeDescription struct {
+	Name           string `json:"name"`
+	ActivationType string `json:"activationtype"`
+	Height         int32  `json:"height,omitempty"`
+	Time           int64  `json:"time,omitempty"`
+	Active         bool   `json:"active"`
+}
+
 // Bip9SoftForkDescription describes the current state of a defined BIP0009
 // version bits soft-fork.
 type Bip9SoftForkDescription struct {
@@ -123,8 +171,10 @@ type GetBlockChainInfoResult struct {
 	Pruned               bool                                `json:"pruned"`
 	PruneHeight          int32                               `json:"pruneheight,omitempty"`
 	ChainWork            string                              `json:"chainwork,omitempty"`
+	SizeOnDisk           uint64                              `json:"size_on_disk"`
 	SoftForks            []*SoftForkDescription              `json:"softforks"`
 	Bip9SoftForks        map[string]*Bip9SoftForkDescription `json:"bip9_softforks"`
+	Upgrades             []*UpgradeDescription               `json:"upgrades"`
 }
 
 // GetBlockTemplateResultTx models the transactions field of the
@@ -208,9 +258,18 @@ type GetMempoolEntryResult struct {
 	Depends          []string `json:"depends"`
 }
 
+// EstimateSmartFeeResult models the data returned from the estimatesmartfee
+// command.
+type EstimateSmartFeeResult struct {
+	FeeRate *float64 `json:"feerate,omitempty"`
+	Errors  []string `json:"errors,omitempty"`
+	Blocks  int64    `json:"blocks"`
+}
+
 // GetMempoolInfoResult models the data returned from the getmempoolinfo
 // command.
 type GetMempoolInfoResult struct {
+	Loaded        bool    `json:"loaded"`
 	Size          int     `json:"size"`
 	Bytes         uint64  `json:"bytes"`
 	Usage         int64   `json:"usage"`
@@ -253,6 +312,12 @@ type GetNetworkInfoResult struct {
 	Warnings        string                 `json:"warnings"`
 }
 
+// AddConnectionResult models the data returned from the addconnection command.
+type AddConnectionResult struct {
+	Address        string `json:"address"`
+	ConnectionType string `json:"connection_type"`
+}
+
 // GetPeerInfoResult models the data returned from the getpeerinfo command.
 type GetPeerInfoResult struct {
 	ID              int32             `json:"id"`
@@ -273,6 +338,7 @@ type GetPeerInfoResult struct {
 	SubVer          string            `json:"subver"`
 	Inbound         bool              `json:"inbound"`
 	AddNode         bool              `json:"addnode"`
+	ConnectionType  string            `json:"connection_type"`
 	StartingHeight  int32             `json:"startingheight"`
 	BanScore        int32             `json:"banscore,omitempty"`
 	SyncedHeaders   int               `json:"synced_headers,omitempty"`
@@ -313,18 +379,94 @@ type ScriptPubKeyResult struct {
 type GetTxOutResult struct {
 	BestBlock     string             `json:"bestblock"`
 	Confirmations int32              `json:"confirmations"`
-	Value         string             `json:"value"`
+	Value         float64            `json:"value"`
 	ScriptPubKey  ScriptPubKeyResult `json:"scriptPubKey"`
-	Version       int32              `json:"version"`
 	Coinbase      bool               `json:"coinbase"`
 }
 
+// GetTxOutSetInfoResult models the data from the gettxoutsetinfo command.
+type GetTxOutSetInfoResult struct {
+	Height         int32   `json:"height"`
+	BestBlock      string  `json:"bestblock"`
+	Transactions   uint64  `json:"transactions"`
+	TxOuts         uint64  `json:"txouts"`
+	BogoSize       uint64  `json:"bogosize"`
+	HashSerialized string  `json:"hash_serialized"`
+	DiskSize       uint64  `json:"disk_size"`
+	TotalAmount    float64 `json:"total_amount"`
+}
+
+// UTXODistributionBucket models a bucket of the getutxodistribution command.
+// Max is the inclusive upper bound of the bucket, unset for the last one.
+type UTXODistributionBucket struct {
+	Max    *float64 `json:"max,omitempty"`
+	Count  uint64   `json:"count"`
+	Amount float64  `json:"amount"`
+}
+
+// GetUTXODistributionResult models the data from the getutxodistribution
+// command.  TxOuts is the number of outputs walked, Sampled the number of them
+// accounted in the buckets.
+type GetUTXODistributionResult struct {
+	Height       int32                             `json:"height"`
+	BestBlock    string                            `json:"bestblock"`
+	SampleRate   uint32                            `json:"samplerate"`
+	TxOuts       uint64                            `json:"txouts"`
+	Sampled      uint64                            `json:"sampled"`
+	ByValue      []UTXODistributionBucket          `json:"byvalue"`
+	ByAge        []UTXODistributionBucket          `json:"byage"`
+	ByScriptType map[string]UTXODistributionBucket `json:"byscripttype"`
+}
+
+// BlockLocationResult models a block of the getblocklocations command.  The
+// data and undo offsets are those of the 4 byte length preceding the block and
+// its undo data in their files, unset if the file has not got them.
+type BlockLocationResult struct {
+	Hash       string  `json:"hash"`
+	Height     int32   `json:"height"`
+	File       int32   `json:"file"`
+	DataFile   string  `json:"datafile,omitempty"`
+	DataOffset *uint32 `json:"dataoffset,omitempty"`
+	UndoFile   string  `json:"undofile,omitempty"`
+	UndoOffset *uint32 `json:"undooffset,omitempty"`
+}
+
+// ScanTxOutSetUnspent models an unspent output found by the scantxoutset
+// command.
+type ScanTxOutSetUnspent struct {
+	TxID         string  `json:"txid"`
+	Vout         uint32  `json:"vout"`
+	ScriptPubKey string  `json:"scriptPubKey"`
+	Desc         string  `json:"desc"`
+	Amount       float64 `json:"amount"`
+	Height       int32   `json:"height"`
+}
+
+// ScanTxOutSetResult models the data returned from the scantxoutset command
+// with the start action.
+type ScanTxOutSetResult struct {
+	Success       bool                   `json:"success"`
+	SearchedItems uint64                 `json:"searched_items"`
+	Height        int32                  `json:"height"`
+	BestBlock     string                 `json:"bestblock"`
+	Unspents      []*ScanTxOutSetUnspent `json:"unspents"`
+	TotalAmount   float64                `json:"total_amount"`
+}
+
+// ScanTxOutSetStatusResult models the data returned from the scantxoutset
+// command with the status action.
+type ScanTxOutSetStatusResult struct {
+	Progress int `json:"progress"`
+}
+
 // GetNetTotalsResult models the data returned from the getnettotals command.
 type GetNetTotalsResult struct {
-	TotalBytesRecv uint64       `json:"totalbytesrecv"`
-	TotalBytesSent uint64       `json:"totalbytessent"`
-	TimeMillis     int64        `json:"timemillis"`
-	Uploadtarget   Uploadtarget `json:"pploadtarget"`
+	TotalBytesRecv  uint64            `json:"totalbytesrecv"`
+	TotalBytesSent  uint64            `json:"totalbytessent"`
+	TimeMillis      int64             `json:"timemillis"`
+	Uploadtarget    Uploadtarget      `json:"uploadtarget"`
+	BytesSentPerMsg map[string]uint64 `json:"bytessent_per_msg"`
+	BytesRecvPerMsg map[string]uint64 `json:"bytesrecv_per_msg"`
 }
 
 type Uploadtarget struct {
@@ -453,9 +595,13 @@ type GetMiningInfoResult struct {
 	Difficulty              float64 `json:"difficulty"`
 	BlockPriorityPercentage int64   `json:"blockprioritypercentage"`
 	Errors                  string  `json:"errors"`
-	NetworkHashPS           string  `json:"networkhashps"`
+	NetworkHashPS           float64 `json:"networkhashps"`
 	PooledTx                uint64  `json:"pooledtx"`
 	Chain                   string  `json:"chain"`
+	CurrentBlockFees        float64 `json:"currentblockfees"`
+	Generate                bool    `json:"generate"`
+	GenProcLimit            int     `json:"genproclimit"`
+	Warnings                string  `json:"warnings"`
 }
 
 // GetWorkResult models the data from the getwork command.
@@ -480,8 +626,16 @@ type InfoChainResult struct {
 	Errors          string  `json:"errors"`
 }
 
+// GetRawTransactionsEntry models the per txid data returned by the
+// getrawtransactions command.  Exactly one of Result and Error is set.
+type GetRawTransactionsEntry struct {
+	Result interface{} `json:"result"`
+	Error  *RPCError   `json:"error"`
+}
+
 // TxRawResult models the data from the getrawtransaction command.
 type TxRawResult struct {
+	InActiveChain *bool  `json:"in_active_chain,omitempty"`
 	Hex           string `json:"hex"`
 	TxID          string `json:"txid"`
 	Hash          string `json:"hash"`
@@ -490,10 +644,10 @@ type TxRawResult struct {
 	LockTime      uint32 `json:"locktime"`
 	Vin           []Vin  `json:"vin"`
 	Vout          []Vout `json:"vout"`
-	BlockHash     string `json:"blockhash"`
-	Confirmations int32  `json:"confirmations"`
-	Time          uint32 `json:"time"`
-	Blocktime     uint32 `json:"blocktime"`
+	BlockHash     string `json:"blockhash,omitempty"`
+	Confirmations int32  `json:"confirmations,omitempty"`
+	Time          uint32 `json:"time,omitempty"`
+	Blocktime     uint32 `json:"blocktime,omitempty"`
 }
 
 // SearchRawTransactionsResult models the data from the searchrawtransaction
@@ -516,13 +670,14 @@ type SearchRawTransactionsResult struct {
 
 // TxRawDecodeResult models the data from the decoderawtransaction command.
 type TxRawDecodeResult struct {
-	Txid     string `json:"txid"`
-	Hash     string `json:"hash"`
-	Size     uint32 `json:"size"`
-	Version  int32  `json:"version"`
-	Locktime uint32 `json:"locktime"`
-	Vin      []Vin  `json:"vin"`
-	Vout     []Vout `json:"vout"`
+	Txid     string   `json:"txid"`
+	Hash     string   `json:"hash"`
+	Size     uint32   `json:"size"`
+	Fee      *float64 `json:"fee,omitempty"`
+	Version  int32    `json:"version"`
+	Locktime uint32   `json:"locktime"`
+	Vin      []Vin    `json:"vin"`
+	Vout     []Vout   `json:"vout"`
 }
 
 // ValidateAddressChainResult models the data returned by the chain server
@@ -534,20 +689,44 @@ type ValidateAddressChainResult struct {
 }
 
 type GetMempoolEntryRelativeInfoVerbose struct {
-	Size             int      `json:"size"`
-	Fee              float64  `json:"fee"`
-	ModifiedFee      float64  `json:"modifiedfee"`
-	Time             int64    `json:"time"`
-	Height           int32    `json:"height"`
-	StartingPriority float64  `json:"startingpriority"`
-	CurrentPriority  float64  `json:"currentpriority"`
-	DescendantCount  int64    `json:"descendantcount"`
-	DescendantSize   int64    `json:"descendantsize"`
-	DescendantFees   int64    `json:"descendantfees"`
-	AncestorCount    int64    `json:"ancestorcount"`
-	AncestorSize     int64    `json:"ancestorsize"`
-	AncestorFees     int64    `json:"ancestorfees"`
-	Depends          []string `json:"depends"`
+	Size             int              `json:"size"`
+	Fee              float64          `json:"fee"`
+	ModifiedFee      float64          `json:"modifiedfee"`
+	Time             int64            `json:"time"`
+	Height           int32            `json:"height"`
+	StartingPriority float64          `json:"startingpriority"`
+	CurrentPriority  float64          `json:"currentpriority"`
+	DescendantCount  int64            `json:"descendantcount"`
+	DescendantSize   int64            `json:"descendantsize"`
+	DescendantFees   int64            `json:"descendantfees"`
+	AncestorCount    int64            `json:"ancestorcount"`
+	AncestorSize     int64            `json:"ancestorsize"`
+	AncestorFees     int64            `json:"ancestorfees"`
+	Fees             MempoolEntryFees `json:"fees"`
+	Depends          []string         `json:"depends"`
+	Unbroadcast      bool             `json:"unbroadcast"`
+}
+
+// MempoolEntryFees models the fees in BCH of a verbose mempool entry.  The
+// modified fees include the deltas set by prioritisetransaction.
+type MempoolEntryFees struct {
+	Base       float64 `json:"base"`
+	Modified   float64 `json:"modified"`
+	Ancestor   float64 `json:"ancestor"`
+	Descendant float64 `json:"descendant"`
+}
+
+// GetHexBlockHeaderChainResult models the data from the
+// gethexblockheaderchain command: the raw transaction, the gettxoutproof proof
+// of its inclusion in a block and the headers of the blocks built on it.
+type GetHexBlockHeaderChainResult struct {
+	TxID          string   `json:"txid"`
+	Hex           string   `json:"hex"`
+	BlockHash     string   `json:"blockhash"`
+	Height        int32    `json:"height"`
+	Confirmations int32    `json:"confirmations"`
+	TxOutProof    string   `json:"txoutproof"`
+	Headers       []string `json:"headers"`
 }
 
 // SignRawTransactionError models the data that contains script verification
@@ -568,6 +747,24 @@ type SignRawTransactionResult struct {
 	Errors   []*SignRawTransactionError `json:"errors,omitempty"`
 }
 
+// ChainStateResult models one chainstate of the getchainstates command.
+// SnapshotBlockHash is only set for a chainstate loaded from a UTXO snapshot.
+type ChainStateResult struct {
+	Blocks               int32   `json:"blocks"`
+	BestBlockHash        string  `json:"bestblockhash"`
+	Difficulty           float64 `json:"difficulty"`
+	VerificationProgress float64 `json:"verificationprogress"`
+	SnapshotBlockHash    string  `json:"snapshot_blockhash,omitempty"`
+	Validated            bool    `json:"validated"`
+}
+
+// GetChainStatesResult models the data returned from the getchainstates
+// command.
+type GetChainStatesResult struct {
+	Headers     int32               `json:"headers"`
+	ChainStates []*ChainStateResult `json:"chainstates"`
+}
+
 type GetChainTipsResult struct {
 	Tips []ChainTipsInfo
 }
@@ -579,10 +776,6 @@ type ChainTipsInfo struct {
 	Status    string `json:"status"`
 }
 
-type ListBannedResult struct {
-	BannedINfo []BannedINfo
-}
-
 type BannedINfo struct {
 	Address     string `json:"address"`
 	BannedUntil int64  `json:"banned_until"`
@@ -599,3 +792,183 @@ type VersionResult struct {
 	Prerelease    string `json:"prerelease"`
 	BuildMetadata string `json:"buildmetadata"`
 }
+
+// RPCMethodStats models the statistics of one method returned by the
+// getrpcstats command.  LatencyBuckets maps the upper bound of each latency
+// bucket to the number of calls which completed within it.
+type RPCMethodStats struct {
+	Calls          uint64            `json:"calls"`
+	Errors         uint64            `json:"errors"`
+	Panics         uint64            `json:"panics"`
+	TotalTimeMs    float64           `json:"total_time_ms"`
+	AverageTimeMs  float64           `json:"avg_time_ms"`
+	LatencyBuckets map[string]uint64 `json:"latency_buckets"`
+}
+
+// GetVersionInfoResult models the data returned from the getversioninfo
+// command.  Features maps the optional features and indexes to whether they
+// are available in the running node.
+type GetVersionInfoResult struct {
+	Version         string          `json:"version"`
+	ProtocolVersion uint32          `json:"protocolversion"`
+	UserAgent       string          `json:"useragent"`
+	GitCommit       string          `json:"gitcommit"`
+	BuildDate       string          `json:"builddate"`
+	GoVersion       string          `json:"goversion"`
+	Features        map[string]bool `json:"features"`
+}
+
+// GetMemoryInfoResult models the data returned from the getmemoryinfo
+// command.  Locked keeps the layout of the upstream locked memory manager
+// report, filled from the Go runtime statistics: used and free are the heap
+// bytes in use and idle, and chunks_used is the number of live objects.  The
+// Go heap is never locked, so locked and chunks_free are always zero.
+type GetMemoryInfoResult struct {
+	Locked  LockedMemoryInfo  `json:"locked"`
+	Runtime RuntimeMemoryInfo `json:"runtime"`
+}
+
+// LockedMemoryInfo models the locked object of the getmemoryinfo command.
+type LockedMemoryInfo struct {
+	Used       uint64 `json:"used"`
+	Free       uint64 `json:"free"`
+	Total      uint64 `json:"total"`
+	Locked     uint64 `json:"locked"`
+	ChunksUsed uint64 `json:"chunks_used"`
+	ChunksFree uint64 `json:"chunks_free"`
+}
+
+// RuntimeMemoryInfo models the Go runtime memory and garbage collector
+// statistics of the getmemoryinfo command.  Sizes are in bytes.
+type RuntimeMemoryInfo struct {
+	HeapAlloc    uint64  `json:"heap_alloc"`
+	HeapSys      uint64  `json:"heap_sys"`
+	HeapInuse    uint64  `json:"heap_inuse"`
+	HeapIdle     uint64  `json:"heap_idle"`
+	HeapReleased uint64  `json:"heap_released"`
+	HeapObjects  uint64  `json:"heap_objects"`
+	StackInuse   uint64  `json:"stack_inuse"`
+	Sys          uint64  `json:"sys"`
+	TotalAlloc   uint64  `json:"total_alloc"`
+	Mallocs      uint64  `json:"mallocs"`
+	Frees        uint64  `json:"frees"`
+	NumGC        uint32  `json:"num_gc"`
+	NumForcedGC  uint32  `json:"num_forced_gc"`
+	PauseTotalMs float64 `json:"pause_total_ms"`
+	LastGC       int64   `json:"last_gc"`
+	Goroutines   int     `json:"goroutines"`
+}
+
+// ActiveCommand models a command being executed, returned by the getrpcinfo
+// command.  Duration is in microseconds.
+type ActiveCommand struct {
+	Method   string `json:"method"`
+	Duration int64  `json:"duration"`
+}
+
+// GetRPCInfoResult models the data returned from the getrpcinfo command.
+type GetRPCInfoResult struct {
+	ActiveCommands []ActiveCommand `json:"active_commands"`
+	LogPath        string          `json:"logpath"`
+}
+
+// ActivityEvent models an event of the block chain or of the mempool returned
+// by the getactivity command.  Height is set for the blocks, Reason for the
+// transactions removed from the mempool.
+type ActivityEvent struct {
+	Sequence uint64 `json:"sequence"`
+	Type     string `json:"type"`
+	Hash     string `json:"hash"`
+	Height   int32  `json:"height,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// GetActivityResult models the data returned from the getactivity command.
+// Sequence is the one of the latest event, Truncated tells that events
+// following the requested sequence were forgotten.
+type GetActivityResult struct {
+	Sequence  uint64          `json:"sequence"`
+	Truncated bool            `json:"truncated"`
+	Events    []ActivityEvent `json:"events"`
+}
+
+// DBLatencyStats models the latencies of the reads or of the writes of a
+// database returned by the getutxocachestats command.  LatencyBuckets maps the
+// upper bound of each latency bucket to the number of operations which
+// completed within it.
+type DBLatencyStats struct {
+	Count          uint64            `json:"count"`
+	TotalTimeMs    float64           `json:"total_time_ms"`
+	AverageTimeMs  float64           `json:"avg_time_ms"`
+	LatencyBuckets map[string]uint64 `json:"latency_buckets"`
+}
+
+// DBStats models the latencies of a database returned by the
+// getutxocachestats command.
+type DBStats struct {
+	Reads  DBLatencyStats `json:"reads"`
+	Writes DBLatencyStats `json:"writes"`
+}
+
+// GetUtxoCacheStatsResult models the data returned from the getutxocachestats
+// command.  Misses counts the lookups read through to the database, NotFound
+// those of them which found no coin.
+type GetUtxoCacheStatsResult struct {
+	Hits       uint64             `json:"hits"`
+	Misses     uint64             `json:"misses"`
+	NotFound   uint64             `json:"notfound"`
+	HitRatio   float64            `json:"hitratio"`
+	Coins      int                `json:"coins"`
+	DirtyCoins int                `json:"dirtycoins"`
+	Capacity   int                `json:"capacity"`
+	Databases  map[string]DBStats `json:"databases"`
+}
+
+// DifficultyHistoryEntry models the proof of work of a block returned by the
+// getdifficultyhistory command.  Target is the hex of the target the compact
+// Bits encode.
+type DifficultyHistoryEntry struct {
+	Height     int32   `json:"height"`
+	Hash       string  `json:"hash"`
+	Time       uint32  `json:"time"`
+	MedianTime int64   `json:"mediantime"`
+	Bits       string  `json:"bits"`
+	Difficulty float64 `json:"difficulty"`
+	Target     string  `json:"target"`
+}
+
+// GetAddressBalanceResult models the data returned from the getaddressbalance
+// command.  Received is the sum of all outputs ever paid to the addresses.
+type GetAddressBalanceResult struct {
+	Balance  int64 `json:"balance"`
+	Received int64 `json:"received"`
+}
+
+// AddressUtxoResult models an unspent output returned from the
+// getaddressutxos command.
+type AddressUtxoResult struct {
+	Address     string `json:"address"`
+	TxID        string `json:"txid"`
+	OutputIndex uint32 `json:"outputIndex"`
+	Script      string `json:"script"`
+	Satoshis    int64  `json:"satoshis"`
+	Height      int32  `json:"height"`
+}
+
+// AddressDeltaResult models a balance change returned from the
+// getaddressdeltas command.  Index is the input index for spends and the
+// output index otherwise.
+type AddressDeltaResult struct {
+	Satoshis int64  `json:"satoshis"`
+	TxID     string `json:"txid"`
+	Index    uint32 `json:"index"`
+	Height   int32  `json:"height"`
+	Address  string `json:"address"`
+}
+
+// SpentInfoResult models the data returned from the getspentinfo command.
+type SpentInfoResult struct {
+	TxID   string `json:"txid"`
+	Index  uint32 `json:"index"`
+	Height int32  `json:"height"`
+}
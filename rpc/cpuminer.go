@@ -0,0 +1,83 @@
+package rpc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/script"
+)
+
+// cpuMinerRetryInterval is how long a mining worker waits after failing to
+// build or submit a block before trying again.
+const cpuMinerRetryInterval = time.Second
+
+// cpuMiner mines blocks on top of the active chain in background workers, as
+// enabled by setgenerate. It is meant for test networks, where blocks can be
+// found with a CPU.
+type cpuMiner struct {
+	sync.Mutex
+	workers int
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+var miner cpuMiner
+
+// generating reports whether the miner runs, and with how many workers.
+func (m *cpuMiner) generating() (bool, int) {
+	m.Lock()
+	defer m.Unlock()
+	return m.workers > 0, m.workers
+}
+
+// setWorkers stops the running workers and starts n new ones mining blocks
+// paying to coinbaseScript. Passing 0 stops the miner.
+func (m *cpuMiner) setWorkers(n int, coinbaseScript *script.Script) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.quit != nil {
+		close(m.quit)
+		m.wg.Wait()
+		m.quit = nil
+		log.Info("CPU miner stopped")
+	}
+	m.workers = n
+	if n <= 0 {
+		m.workers = 0
+		return
+	}
+
+	m.quit = make(chan struct{})
+	m.wg.Add(n)
+	for i := 0; i < n; i++ {
+		go m.mine(coinbaseScript, m.quit)
+	}
+	log.Info("CPU miner started with %d workers", n)
+}
+
+func (m *cpuMiner) mine(coinbaseScript *script.Script, quit chan struct{}) {
+	defer m.wg.Done()
+
+	for {
+		hashes, err := generateBlocks(coinbaseScript, 1, nInnerLoopCount, quit)
+		select {
+		case <-quit:
+			return
+		default:
+		}
+		if err != nil {
+			log.Warn("CPU miner failed to mine a block: %v", err)
+			select {
+			case <-quit:
+				return
+			case <-time.After(cpuMinerRetryInterval):
+			}
+			continue
+		}
+		if len(hashes) > 0 {
+			log.Info("CPU miner found block %s", hashes[0])
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"fmt"
 	"sort"
 	"strings"
 	"sync"
@@ -16,6 +17,8 @@ type helpCacher struct {
 	methodHelp map[string]string
 }
 
+// methodHelp maps the RPC commands to their handwritten descriptions, whose
+// synopsis and examples complete the generated help.
 var methodHelp = map[string]string{
 	"getexcessiveblock": getexcessiveblockDesc,
 	"setexcessiveblock": setexcessiveblockDesc,
@@ -26,6 +29,8 @@ var methodHelp = map[string]string{
 	"getblock":              getblockDesc,
 	"getblockhash":          getblockhashDesc,
 	"getblockheader":        getblockheader,
+	"getblockstats":         getblockstatsDesc,
+	"getchainstates":        getchainstatesDesc,
 	"getchaintips":          getchaintipsDesc,
 	"getchaintxstats":       getchaintxstatsDesc,
 	"getdifficulty":         getdifficultyDesc,
@@ -33,19 +38,40 @@ var methodHelp = map[string]string{
 	"getmempooldescendants": getmempooldescendantsDesc,
 	"getmempoolentry":       getmempoolentryDesc,
 	"getmempoolinfo":        getmempoolinfoDesc,
+	"savemempool":           savemempoolDesc,
 	"getrawmempool":         getrawmempoolDesc,
 	"gettxout":              gettxoutDesc,
 	"gettxoutsetinfo":       gettxoutsetinfoDesc,
+	"scantxoutset":          scantxoutsetDesc,
+	"getutxodistribution":   getutxodistributionDesc,
+	"getblocklocations":     getblocklocationsDesc,
 	"pruneblockchain":       pruneblockchainDesc,
 	"verifychain":           verifychainDesc,
 	"preciousblock":         preciousblockDesc,
+	"getactivity":           getactivityDesc,
+	"getutxocachestats":     getutxocachestatsDesc,
+	"getdifficultyhistory":  getdifficultyhistoryDesc,
+
+	"notifyblocks":              notifyblocksDesc,
+	"stopnotifyblocks":          stopnotifyblocksDesc,
+	"notifynewtransactions":     notifynewtransactionsDesc,
+	"stopnotifynewtransactions": stopnotifynewtransactionsDesc,
+	"notifyspent":               notifyspentDesc,
+	"stopnotifyspent":           stopnotifyspentDesc,
+	"notifyreceived":            notifyreceivedDesc,
+	"stopnotifyreceived":        stopnotifyreceivedDesc,
 
 	"getnetworkhashps":  getnetworkhashpsDesc,
 	"getmininginfo":     getmininginfoDesc,
+	"getgenerate":       getgenerateDesc,
+	"setgenerate":       setgenerateDesc,
 	"getblocktemplate":  getblocktemplateDesc,
 	"submitblock":       submitblockDesc,
+	"submitheader":      submitheaderDesc,
 	"generate":          generateDesc,
 	"generatetoaddress": generatetoaddressDesc,
+	"estimatefee":       estimatefeeDesc,
+	"estimatesmartfee":  estimatesmartfeeDesc,
 
 	"getconnectioncount": getconnectioncountDesc,
 	"ping":               pingDesc,
@@ -59,8 +85,11 @@ var methodHelp = map[string]string{
 	"listbanned":         listbannedDesc,
 	"clearbanned":        clearbannedDesc,
 	"setnetworkactive":   setnetworkactiveDesc,
+	"syncmempool":        syncmempoolDesc,
 
 	"getrawtransaction":    getrawtransactionDesc,
+	"getrawtransactions":   getrawtransactionsDesc,
+	"getspentinfo":         getspentinfoDesc,
 	"createrawtransaction": createrawtransactionDesc,
 	"decoderawtransaction": decoderawtransactionDesc,
 	"decodescript":         decodescriptDesc,
@@ -69,27 +98,173 @@ var methodHelp = map[string]string{
 	"gettxoutproof":        gettxoutproofDesc,
 	"verifytxoutproof":     verifytxoutproofDesc,
 
+	"gethexblockheaderchain": gethexblockheaderchainDesc,
+
 	"getinfo":         getinfoDesc,
 	"validateaddress": validateaddressDesc,
 	"createmultisig":  createmultisigDesc,
+	"getrpcstats":     getrpcstatsDesc,
+	"getversioninfo":  getversioninfoDesc,
+	"uptime":          uptimeDesc,
+	"getmemoryinfo":   getmemoryinfoDesc,
+	"getrpcinfo":      getrpcinfoDesc,
+
+	"getaddresstxids":   getaddresstxidsDesc,
+	"getaddressbalance": getaddressbalanceDesc,
+	"getaddressutxos":   getaddressutxosDesc,
+	"getaddressdeltas":  getaddressdeltasDesc,
+}
+
+// helpResultTypes maps the RPC commands to the types of the results they
+// return, rendered as a schema by help.  A command missing returns nothing.
+// When a command returns one of several types depending on its arguments, the
+// type of its most detailed result is listed.
+var helpResultTypes = map[string][]interface{}{
+	"getexcessiveblock": {(*btcjson.ExcessiveBlockSizeResult)(nil)},
+	"setexcessiveblock": {(*string)(nil)},
+
+	"getblockchaininfo":     {(*btcjson.GetBlockChainInfoResult)(nil)},
+	"getbestblockhash":      {(*string)(nil)},
+	"getblockcount":         {(*int32)(nil)},
+	"getblock":              {(*btcjson.GetBlockVerboseResult)(nil)},
+	"getblockhash":          {(*string)(nil)},
+	"getblockheader":        {(*btcjson.GetBlockHeaderVerboseResult)(nil)},
+	"getblockstats":         {(*btcjson.GetBlockStatsResult)(nil)},
+	"getchainstates":        {(*btcjson.GetChainStatesResult)(nil)},
+	"getchaintips":          {(*btcjson.GetChainTipsResult)(nil)},
+	"getchaintxstats":       {(*btcjson.GetChainTxStatsResult)(nil)},
+	"getdifficulty":         {(*float64)(nil)},
+	"getmempoolancestors":   {(*map[string]btcjson.GetMempoolEntryRelativeInfoVerbose)(nil)},
+	"getmempooldescendants": {(*map[string]btcjson.GetMempoolEntryRelativeInfoVerbose)(nil)},
+	"getmempoolentry":       {(*btcjson.GetMempoolEntryRelativeInfoVerbose)(nil)},
+	"getmempoolinfo":        {(*btcjson.GetMempoolInfoResult)(nil)},
+	"getrawmempool":         {(*map[string]btcjson.GetMempoolEntryRelativeInfoVerbose)(nil)},
+	"gettxout":              {(*btcjson.GetTxOutResult)(nil)},
+	"gettxoutsetinfo":       {(*btcjson.GetTxOutSetInfoResult)(nil)},
+	"scantxoutset":          {(*btcjson.ScanTxOutSetResult)(nil)},
+	"getutxodistribution":   {(*btcjson.GetUTXODistributionResult)(nil)},
+	"getblocklocations":     {(*[]btcjson.BlockLocationResult)(nil)},
+	"pruneblockchain":       {(*uint64)(nil)},
+	"verifychain":           {(*bool)(nil)},
+	"getactivity":           {(*btcjson.GetActivityResult)(nil)},
+	"getutxocachestats":     {(*btcjson.GetUtxoCacheStatsResult)(nil)},
+	"getdifficultyhistory":  {(*[]btcjson.DifficultyHistoryEntry)(nil)},
+
+	"getnetworkhashps":  {(*float64)(nil)},
+	"getmininginfo":     {(*btcjson.GetMiningInfoResult)(nil)},
+	"getgenerate":       {(*bool)(nil)},
+	"getblocktemplate":  {(*btcjson.GetBlockTemplateResult)(nil)},
+	"submitblock":       {(*string)(nil)},
+	"generate":          {(*[]string)(nil)},
+	"generatetoaddress": {(*[]string)(nil)},
+	"estimatefee":       {(*float64)(nil)},
+	"estimatesmartfee":  {(*btcjson.EstimateSmartFeeResult)(nil)},
+
+	"getconnectioncount": {(*int32)(nil)},
+	"getpeerinfo":        {(*[]btcjson.GetPeerInfoResult)(nil)},
+	"addconnection":      {(*btcjson.AddConnectionResult)(nil)},
+	"getaddednodeinfo":   {(*[]btcjson.GetAddedNodeInfoResult)(nil)},
+	"getnettotals":       {(*btcjson.GetNetTotalsResult)(nil)},
+	"getnetworkinfo":     {(*btcjson.GetNetworkInfoResult)(nil)},
+	"listbanned":         {(*[]btcjson.BannedINfo)(nil)},
+	"setnetworkactive":   {(*bool)(nil)},
+
+	"getrawtransaction":    {(*btcjson.TxRawResult)(nil)},
+	"getrawtransactions":   {(*map[string]btcjson.GetRawTransactionsEntry)(nil)},
+	"getspentinfo":         {(*btcjson.SpentInfoResult)(nil)},
+	"createrawtransaction": {(*string)(nil)},
+	"decoderawtransaction": {(*btcjson.TxRawDecodeResult)(nil)},
+	"decodescript":         {(*btcjson.ScriptPubKeyResult)(nil)},
+	"sendrawtransaction":   {(*string)(nil)},
+	"signrawtransaction":   {(*btcjson.SignRawTransactionResult)(nil)},
+	"gettxoutproof":        {(*string)(nil)},
+	"verifytxoutproof":     {(*[]string)(nil)},
+
+	"gethexblockheaderchain": {(*btcjson.GetHexBlockHeaderChainResult)(nil)},
+
+	"getinfo":                {(*btcjson.InfoChainResult)(nil)},
+	"validateaddress":        {(*btcjson.ValidateAddressChainResult)(nil)},
+	"createmultisig":         {(*btcjson.CreateMultiSigResult)(nil)},
+	"verifymessage":          {(*bool)(nil)},
+	"signmessagewithprivkey": {(*string)(nil)},
+	"help":                   {(*string)(nil)},
+	"stop":                   {(*string)(nil)},
+	"version":                {(*map[string]btcjson.VersionResult)(nil)},
+	"getrpcstats":            {(*map[string]btcjson.RPCMethodStats)(nil)},
+	"getversioninfo":         {(*btcjson.GetVersionInfoResult)(nil)},
+	"uptime":                 {(*int64)(nil)},
+	"getmemoryinfo":          {(*btcjson.GetMemoryInfoResult)(nil)},
+	"getrpcinfo":             {(*btcjson.GetRPCInfoResult)(nil)},
+
+	"getaddresstxids":   {(*[]string)(nil)},
+	"getaddressbalance": {(*btcjson.GetAddressBalanceResult)(nil)},
+	"getaddressutxos":   {(*[]btcjson.AddressUtxoResult)(nil)},
+	"getaddressdeltas":  {(*[]btcjson.AddressDeltaResult)(nil)},
+}
+
+// helpDescSections splits a handwritten description in its synopsis, the text
+// between the usage line and the arguments or the result, and its examples.
+func helpDescSections(desc string) (synopsis string, examples string) {
+	lines := strings.Split(desc, "\n")
+	if len(lines) > 0 {
+		lines = lines[1:]
+	}
+
+	var synopsisLines []string
+	inSynopsis := true
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "Examples:") {
+			examples = strings.Join(lines[i:], "\n")
+			break
+		}
+		if strings.HasPrefix(trimmed, "Arguments") ||
+			strings.HasPrefix(trimmed, "Result") {
+			inSynopsis = false
+		}
+		if inSynopsis {
+			synopsisLines = append(synopsisLines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(synopsisLines, "\n")), examples
 }
 
-// rpcMethodHelp returns an RPC help string for the provided method.
+// rpcMethodHelp returns an RPC help string for the provided method, generated
+// from its btcjson registration and completed with the synopsis and the
+// examples of its handwritten description.
 //
 // This function is safe for concurrent access.
 func (c *helpCacher) rpcMethodHelp(method string) (string, error) {
 	c.Lock()
 	defer c.Unlock()
-	help, exists := c.methodHelp[method]
 
-	if !exists {
-		return "", nil
+	// Return the cached method help if it exists.
+	if help, exists := c.methodHelp[method]; exists {
+		return help, nil
+	}
+
+	// The commands served without a btcjson registration, whose parameters
+	// are not parsed, only have their handwritten description.
+	synopsis, examples := helpDescSections(methodHelp[method])
+	help, err := btcjson.GenerateUsageHelp(method, synopsis,
+		helpResultTypes[method]...)
+	if jerr, ok := err.(btcjson.Error); ok &&
+		jerr.ErrorCode == btcjson.ErrUnregisteredMethod {
+		return methodHelp[method], nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if examples != "" {
+		help += "\n" + examples + "\n"
 	}
 
+	c.methodHelp[method] = help
 	return help, nil
 }
 
-// rpcUsage returns one-line usage for all support RPC commands.
+// rpcUsage returns one-line usage for all supported RPC commands, listed by
+// category.
 //
 // This function is safe for concurrent access.
 func (c *helpCacher) rpcUsage(includeWebsockets bool) (string, error) {
@@ -101,19 +276,31 @@ func (c *helpCacher) rpcUsage(includeWebsockets bool) (string, error) {
 		return c.usage, nil
 	}
 
-	// Generate a list of one-line usage for every command.
-	usageTexts := make([]string, 0, len(methodHelp))
-	for k := range methodHelp {
-
-		usage, err := btcjson.MethodUsageText(k)
+	// Generate a list of one-line usage for every command, grouped by
+	// category.
+	usageTexts := make(map[string][]string)
+	for method := range rpcHandlers {
+		usage, err := btcjson.MethodUsageText(method)
 		if err != nil {
-			return "", err
+			usage = method
 		}
-		usageTexts = append(usageTexts, usage)
+		category := rpcCategories[method]
+		usageTexts[category] = append(usageTexts[category], usage)
 	}
 
-	sort.Sort(sort.StringSlice(usageTexts))
-	c.usage = strings.Join(usageTexts, "\n")
+	categories := make([]string, 0, len(usageTexts))
+	for category := range usageTexts {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	sections := make([]string, 0, len(categories))
+	for _, category := range categories {
+		sort.Strings(usageTexts[category])
+		sections = append(sections, fmt.Sprintf("== %s ==\n%s",
+			strings.Title(category), strings.Join(usageTexts[category], "\n")))
+	}
+	c.usage = strings.Join(sections, "\n\n")
 	return c.usage, nil
 }
 
@@ -121,6 +308,6 @@ func (c *helpCacher) rpcUsage(includeWebsockets bool) (string, error) {
 // usage for the RPC server commands and caches the results for future calls.
 func newHelpCacher() *helpCacher {
 	return &helpCacher{
-		methodHelp: methodHelp,
+		methodHelp: make(map[string]string),
 	}
 }
@@ -45,6 +45,8 @@ const (
 		"active chain, in hexadecimal\n" +
 		"  \"pruned\": xx,             (boolean) if the blocks are subject " +
 		"to pruning\n" +
+		"  \"size_on_disk\": xxxxxx,   (numeric) the estimated size of " +
+		"the block and undo files on disk\n" +
 		"  \"pruneheight\": xxxxxx,    (numeric) lowest-height complete " +
 		"block stored\n" +
 		"  \"softforks\": [            (array) status of softforks in " +
@@ -76,6 +78,20 @@ const (
 		"block to which the status applies\n" +
 		"     }\n" +
 		"  }\n" +
+		"  \"upgrades\": [             (array) activation status of the " +
+		"network upgrades\n" +
+		"     {\n" +
+		"        \"name\": \"xxxx\",          (string) name of the upgrade\n" +
+		"        \"activationtype\": \"xxxx\", (string) \"height\" or " +
+		"\"mediantime\"\n" +
+		"        \"height\": xx,              (numeric) activation height " +
+		"of a height activation\n" +
+		"        \"time\": xx,                (numeric) activation median " +
+		"time past of a time activation\n" +
+		"        \"active\": xx,              (boolean) true if the " +
+		"upgrade is active at the tip\n" +
+		"     }, ...\n" +
+		"  ]\n" +
 		"}\n" +
 		"\nExamples:\n" +
 		"> coperctl getblockchaininfo\n" +
@@ -98,16 +114,19 @@ const (
 		"> coperctl getblockcount\n" +
 		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getblockcount", "params": [] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
 
-	getblockDesc = "getblock \"blockhash\" ( verbose )\n" +
-		"\nIf verbose is false, returns a string that is serialized, " +
+	getblockDesc = "getblock \"blockhash\" ( verbosity )\n" +
+		"\nIf verbosity is 0, returns a string that is serialized, " +
 		"hex-encoded data for block 'hash'.\n" +
-		"If verbose is true, returns an Object with information about " +
+		"If verbosity is 1, returns an Object with information about " +
 		"block <hash>.\n" +
+		"If verbosity is 2, returns an Object with information about " +
+		"block <hash> and information about each transaction.\n" +
 		"\nArguments:\n" +
 		"1. \"blockhash\"          (string, required) The block hash\n" +
-		"2. verbose                (boolean, optional, default=true) true " +
-		"for a json object, false for the hex encoded data\n" +
-		"\nResult (for verbose = true):\n" +
+		"2. verbosity              (numeric, optional, default=1) 0 for hex " +
+		"encoded data, 1 for a json object, and 2 for json object with " +
+		"transaction data. The booleans false and true are accepted as 0 and 1\n" +
+		"\nResult (for verbosity = 1):\n" +
 		"{\n" +
 		"  \"hash\" : \"hash\",     (string) the block hash (same as " +
 		"provided)\n" +
@@ -137,7 +156,17 @@ const (
 		"  \"nextblockhash\" : \"hash\"       (string) The hash of the " +
 		"next block\n" +
 		"}\n" +
-		"\nResult (for verbose=false):\n" +
+		"\nResult (for verbosity = 2):\n" +
+		"{\n" +
+		"  ...,                     Same output as verbosity = 1\n" +
+		"  \"tx\" : [               (array of Objects) The transactions in " +
+		"the format of the getrawtransaction RPC. Different from verbosity " +
+		"= 1 \"tx\" result\n" +
+		"         ,...\n" +
+		"  ],\n" +
+		"  ,...                     Same output as verbosity = 1\n" +
+		"}\n" +
+		"\nResult (for verbosity = 0):\n" +
 		"\"data\"             (string) A string that is serialized, " +
 		"hex-encoded data for block 'hash'.\n" +
 		"\nExamples:\n" +
@@ -183,6 +212,8 @@ const (
 		"  \"difficulty\" : x.xxx,  (numeric) The difficulty\n" +
 		"  \"chainwork\" : \"0000...1f3\"     (string) Expected number of " +
 		"hashes required to produce the current chain (in hex)\n" +
+		"  \"nTx\" : n,             (numeric) The number of transactions " +
+		"in the block\n" +
 		"  \"previousblockhash\" : \"hash\",  (string) The hash of the " +
 		"previous block\n" +
 		"  \"nextblockhash\" : \"hash\",      (string) The hash of the " +
@@ -195,6 +226,109 @@ const (
 		`> coperctl getblockheader "00000000c937983704a73af28acdec37b049d214adbda81d7e2a3dd146f6ed09"` + "\n" +
 		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getblockheader", "params": ["00000000c937983704a73af28acdec37b049d214adbda81d7e2a3dd146f6ed09"] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
 
+	getblockstatsDesc = "getblockstats hash_or_height ( stats )\n" +
+		"\nCompute per block statistics for a given window. All amounts are " +
+		"in satoshis.\n" +
+		"It won't work for some heights with pruning.\n" +
+		"\nArguments:\n" +
+		"1. \"hash_or_height\"     (string or numeric, required) The block " +
+		"hash or height of the target block\n" +
+		"2. \"stats\"              (array, optional) Values to plot, by " +
+		"default all values (see result below)\n" +
+		"    [\n" +
+		"      \"height\",         (string, optional) Selected statistic\n" +
+		"      \"time\",           (string, optional) Selected statistic\n" +
+		"      ,...\n" +
+		"    ]\n" +
+		"\nResult:\n" +
+		"{                           (json object)\n" +
+		"  \"avgfee\": xxxxx,          (numeric) Average fee in the block\n" +
+		"  \"avgfeerate\": xxxxx,      (numeric) Average feerate (in " +
+		"satoshis per byte)\n" +
+		"  \"avgtxsize\": xxxxx,       (numeric) Average transaction size\n" +
+		"  \"blockhash\": xxxxx,       (string) The block hash (to check " +
+		"for potential reorgs)\n" +
+		"  \"feerate_percentiles\": [  (array of numeric) Feerates at the " +
+		"10th, 25th, 50th, 75th, and 90th percentile weight unit (in " +
+		"satoshis per byte)\n" +
+		"      \"10th_percentile_feerate\",      (numeric) The 10th " +
+		"percentile feerate\n" +
+		"      \"25th_percentile_feerate\",      (numeric) The 25th " +
+		"percentile feerate\n" +
+		"      \"50th_percentile_feerate\",      (numeric) The 50th " +
+		"percentile feerate\n" +
+		"      \"75th_percentile_feerate\",      (numeric) The 75th " +
+		"percentile feerate\n" +
+		"      \"90th_percentile_feerate\",      (numeric) The 90th " +
+		"percentile feerate\n" +
+		"  ],\n" +
+		"  \"height\": xxxxx,          (numeric) The height of the block\n" +
+		"  \"ins\": xxxxx,             (numeric) The number of inputs " +
+		"(excluding coinbase)\n" +
+		"  \"maxfee\": xxxxx,          (numeric) Maximum fee in the block\n" +
+		"  \"maxfeerate\": xxxxx,      (numeric) Maximum feerate (in " +
+		"satoshis per byte)\n" +
+		"  \"maxtxsize\": xxxxx,       (numeric) Maximum transaction size\n" +
+		"  \"medianfee\": xxxxx,       (numeric) Truncated median fee in " +
+		"the block\n" +
+		"  \"mediantime\": xxxxx,      (numeric) The block median time past\n" +
+		"  \"mediantxsize\": xxxxx,    (numeric) Truncated median " +
+		"transaction size\n" +
+		"  \"minfee\": xxxxx,          (numeric) Minimum fee in the block\n" +
+		"  \"minfeerate\": xxxxx,      (numeric) Minimum feerate (in " +
+		"satoshis per byte)\n" +
+		"  \"mintxsize\": xxxxx,       (numeric) Minimum transaction size\n" +
+		"  \"outs\": xxxxx,            (numeric) The number of outputs\n" +
+		"  \"subsidy\": xxxxx,         (numeric) The block subsidy\n" +
+		"  \"time\": xxxxx,            (numeric) The block time\n" +
+		"  \"total_out\": xxxxx,       (numeric) Total amount in all " +
+		"outputs (excluding coinbase and thus reward [ie subsidy + " +
+		"totalfee])\n" +
+		"  \"total_size\": xxxxx,      (numeric) Total size of all " +
+		"non-coinbase transactions\n" +
+		"  \"totalfee\": xxxxx,        (numeric) The fee total\n" +
+		"  \"txs\": xxxxx,             (numeric) The number of " +
+		"transactions (including coinbase)\n" +
+		"  \"utxo_increase\": xxxxx,   (numeric) The increase/decrease in " +
+		"the number of unspent outputs\n" +
+		"  \"utxo_size_inc\": xxxxx,   (numeric) The increase/decrease in " +
+		"size for the utxo index (unspendable outputs are not counted)\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		`> coperctl getblockstats 1000 '["minfeerate","avgfeerate"]'` + "\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getblockstats", "params": [1000, ["minfeerate","avgfeerate"]] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
+	getchainstatesDesc = "getchainstates\n" +
+		"\nReturn information about chainstates.\n" +
+		"Besides the fully validated chainstate, a chainstate loaded from a " +
+		"UTXO snapshot would be listed,\n" +
+		"until the background validation of the snapshot completes. " +
+		"Loading snapshots is not supported yet,\n" +
+		"so the active chainstate is the only one.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"headers\" : n,                  (numeric) the number of headers " +
+		"seen so far\n" +
+		"  \"chainstates\" : [               (json array) list of the " +
+		"chainstates ordered by work, with the most-work (active) chainstate last\n" +
+		"    {\n" +
+		"      \"blocks\" : n,               (numeric) number of blocks in " +
+		"this chainstate\n" +
+		"      \"bestblockhash\" : \"hash\",  (string) blockhash of the tip\n" +
+		"      \"difficulty\" : x.xxx,       (numeric) difficulty of the tip\n" +
+		"      \"verificationprogress\" : x.xxx, (numeric) progress towards the " +
+		"network tip\n" +
+		"      \"snapshot_blockhash\" : \"hash\", (string, optional) the base " +
+		"block of the snapshot this chainstate is based on, if any\n" +
+		"      \"validated\" : true|false    (boolean) whether the chainstate " +
+		"is fully validated\n" +
+		"    }\n" +
+		"  ]\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		"> coperctl getchainstates\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getchainstates", "params": [] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
 	getchaintipsDesc = "getchaintips\n" +
 		"Return information about all known tips in the block tree," +
 		" including the main chain as well as orphaned branches.\n" +
@@ -311,10 +445,22 @@ const (
 		"of in-mempool ancestors (including this one)\n" +
 		"    \"ancestorfees\" : n,     (numeric) modified fees (see above) " +
 		"of in-mempool ancestors (including this one)\n" +
+		"    \"fees\" : {             (json object) the fees in BCH\n" +
+		"        \"base\" : n,         (numeric) transaction fee\n" +
+		"        \"modified\" : n,     (numeric) transaction fee with fee " +
+		"deltas used for mining priority\n" +
+		"        \"ancestor\" : n,     (numeric) modified fees of " +
+		"in-mempool ancestors (including this one)\n" +
+		"        \"descendant\" : n    (numeric) modified fees of " +
+		"in-mempool descendants (including this one)\n" +
+		"    }\n" +
 		"    \"depends\" : [           (array) unconfirmed transactions " +
 		"used as inputs for this transaction\n" +
 		"        \"transactionid\",    (string) parent transaction id\n" +
-		"       ... ]\n" +
+		"       ... ],\n" +
+		"    \"unbroadcast\" : true|false (boolean) whether this transaction " +
+		"is currently unbroadcast (initial broadcast not yet acknowledged " +
+		"by any peers)\n" +
 		"  }, ...\n" +
 		"}\n" +
 		"\nExamples:\n" +
@@ -362,10 +508,22 @@ const (
 		"of in-mempool ancestors (including this one)\n" +
 		"    \"ancestorfees\" : n,     (numeric) modified fees (see above) " +
 		"of in-mempool ancestors (including this one)\n" +
+		"    \"fees\" : {             (json object) the fees in BCH\n" +
+		"        \"base\" : n,         (numeric) transaction fee\n" +
+		"        \"modified\" : n,     (numeric) transaction fee with fee " +
+		"deltas used for mining priority\n" +
+		"        \"ancestor\" : n,     (numeric) modified fees of " +
+		"in-mempool ancestors (including this one)\n" +
+		"        \"descendant\" : n    (numeric) modified fees of " +
+		"in-mempool descendants (including this one)\n" +
+		"    }\n" +
 		"    \"depends\" : [           (array) unconfirmed transactions " +
 		"used as inputs for this transaction\n" +
 		"        \"transactionid\",    (string) parent transaction id\n" +
-		"       ... ]\n" +
+		"       ... ],\n" +
+		"    \"unbroadcast\" : true|false (boolean) whether this transaction " +
+		"is currently unbroadcast (initial broadcast not yet acknowledged " +
+		"by any peers)\n" +
 		"  }, ...\n" +
 		"}\n" +
 		"\nExamples:\n" +
@@ -404,10 +562,22 @@ const (
 		"of in-mempool ancestors (including this one)\n" +
 		"    \"ancestorfees\" : n,     (numeric) modified fees (see above) " +
 		"of in-mempool ancestors (including this one)\n" +
+		"    \"fees\" : {             (json object) the fees in BCH\n" +
+		"        \"base\" : n,         (numeric) transaction fee\n" +
+		"        \"modified\" : n,     (numeric) transaction fee with fee " +
+		"deltas used for mining priority\n" +
+		"        \"ancestor\" : n,     (numeric) modified fees of " +
+		"in-mempool ancestors (including this one)\n" +
+		"        \"descendant\" : n    (numeric) modified fees of " +
+		"in-mempool descendants (including this one)\n" +
+		"    }\n" +
 		"    \"depends\" : [           (array) unconfirmed transactions " +
 		"used as inputs for this transaction\n" +
 		"        \"transactionid\",    (string) parent transaction id\n" +
-		"       ... ]\n" +
+		"       ... ],\n" +
+		"    \"unbroadcast\" : true|false (boolean) whether this transaction " +
+		"is currently unbroadcast (initial broadcast not yet acknowledged " +
+		"by any peers)\n" +
 		"}\n" +
 		"\nExamples:\n" +
 		`> coperctl getmempoolentry "mytxid"` + "\n" +
@@ -417,6 +587,8 @@ const (
 		"\nReturns details on the active state of the TX memory pool.\n" +
 		"\nResult:\n" +
 		"{\n" +
+		"  \"loaded\": true|false,       (boolean) True if the mempool is " +
+		"fully loaded\n" +
 		"  \"size\": xxxxx,               (numeric) Current tx count\n" +
 		"  \"bytes\": xxxxx,              (numeric) Transaction size.\n" +
 		"  \"usage\": xxxxx,              (numeric) Total memory usage for " +
@@ -430,6 +602,13 @@ const (
 		"> coperctl getmempoolinfo\n" +
 		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getmempoolinfo", "params": [] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
 
+	savemempoolDesc = "savemempool\n" +
+		"\nDumps the mempool to disk. It will fail until the previous dump " +
+		"is fully loaded.\n" +
+		"\nExamples:\n" +
+		"> coperctl savemempool\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "savemempool", "params": [] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
 	getrawmempoolDesc = "getrawmempool ( verbose )\n" +
 		"\nReturns all transaction ids in memory pool as a json array of " +
 		"string transaction ids.\n" +
@@ -469,10 +648,22 @@ const (
 		"of in-mempool ancestors (including this one)\n" +
 		"    \"ancestorfees\" : n,     (numeric) modified fees (see above) " +
 		"of in-mempool ancestors (including this one)\n" +
+		"    \"fees\" : {             (json object) the fees in BCH\n" +
+		"        \"base\" : n,         (numeric) transaction fee\n" +
+		"        \"modified\" : n,     (numeric) transaction fee with fee " +
+		"deltas used for mining priority\n" +
+		"        \"ancestor\" : n,     (numeric) modified fees of " +
+		"in-mempool ancestors (including this one)\n" +
+		"        \"descendant\" : n    (numeric) modified fees of " +
+		"in-mempool descendants (including this one)\n" +
+		"    }\n" +
 		"    \"depends\" : [           (array) unconfirmed transactions " +
 		"used as inputs for this transaction\n" +
 		"        \"transactionid\",    (string) parent transaction id\n" +
-		"       ... ]\n" +
+		"       ... ],\n" +
+		"    \"unbroadcast\" : true|false (boolean) whether this transaction " +
+		"is currently unbroadcast (initial broadcast not yet acknowledged " +
+		"by any peers)\n" +
 		"  }, ...\n" +
 		"}\n" +
 		"\nExamples:\n" +
@@ -538,6 +729,127 @@ const (
 		"> coperctl gettxoutsetinfo\n" +
 		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "gettxoutsetinfo", "params": [] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
 
+	scantxoutsetDesc = "scantxoutset \"action\" ( [scanobjects,...] )\n" +
+		"\nScans the unspent transaction output set for outputs matching the " +
+		"given scan objects.\n" +
+		"Note this call may take some time, only one scan may run at a time.\n" +
+		"\nArguments:\n" +
+		"1. \"action\"       (string, required) The action to execute\n" +
+		"                   \"start\" for starting a scan\n" +
+		"                   \"abort\" for aborting the current scan (returns " +
+		"true when abort was successful)\n" +
+		"                   \"status\" for progress report (in %) of the " +
+		"current scan\n" +
+		"2. \"scanobjects\"  (array, required for \"start\") Array of scan " +
+		"objects\n" +
+		"    [\n" +
+		"      \"descriptor\", (string) An address, or one of the descriptors " +
+		"addr(<address>), raw(<hex script>), pk(<hex pubkey>), " +
+		"pkh(<hex pubkey>) and combo(<hex pubkey>)\n" +
+		"      ,...\n" +
+		"    ]\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"success\": true|false,         (boolean) Whether the scan " +
+		"completed\n" +
+		"  \"searched_items\": n,           (numeric) The number of unspent " +
+		"outputs scanned\n" +
+		"  \"height\": n,                   (numeric) The height of the " +
+		"block the UTXO set was scanned at\n" +
+		"  \"bestblock\": \"hex\",            (string) The hash of that block\n" +
+		"  \"unspents\": [\n" +
+		"    {\n" +
+		"      \"txid\" : \"transactionid\",   (string) The transaction id\n" +
+		"      \"vout\": n,                    (numeric) the vout value\n" +
+		"      \"scriptPubKey\" : \"script\",  (string) the script key\n" +
+		"      \"desc\" : \"descriptor\",      (string) The matched scan " +
+		"object\n" +
+		"      \"amount\" : x.xxx,             (numeric) The total amount " +
+		"in BCH of the unspent output\n" +
+		"      \"height\" : n,                 (numeric) Height of the " +
+		"unspent transaction output\n" +
+		"    }\n" +
+		"    ,...\n" +
+		"  ],\n" +
+		"  \"total_amount\" : x.xxx,        (numeric) The total amount of " +
+		"all found unspent outputs in BCH\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		`> coperctl scantxoutset start '["addr(1PMycacnJaSqwwJqjawXBErnLsZ7RkXUAs)"]'` + "\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "scantxoutset", "params": ["start", ["addr(1PMycacnJaSqwwJqjawXBErnLsZ7RkXUAs)"]] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
+	getutxodistributionDesc = "getutxodistribution ( samplerate )\n" +
+		"\nReturns how the unspent transaction outputs are distributed by " +
+		"value, by age and by script type.\n" +
+		"Note this call may take some time.\n" +
+		"\nArguments:\n" +
+		"1. samplerate    (numeric, optional, default=1) Account one output " +
+		"in samplerate only. The outputs are picked by their outpoint, so " +
+		"the same rate picks the same outputs on every call.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"height\" : n,           (numeric) The height of the block " +
+		"the UTXO set was walked at\n" +
+		"  \"bestblock\" : \"hex\",    (string) The hash of that block\n" +
+		"  \"samplerate\" : n,       (numeric) The sample rate used\n" +
+		"  \"txouts\" : n,           (numeric) The number of outputs walked\n" +
+		"  \"sampled\" : n,          (numeric) The number of outputs " +
+		"accounted in the buckets\n" +
+		"  \"byvalue\" : [           (json array) The outputs by value\n" +
+		"    {\n" +
+		"      \"max\" : x.xxx,      (numeric) The largest value in BCH " +
+		"of the bucket, absent for the last one\n" +
+		"      \"count\" : n,        (numeric) The number of outputs\n" +
+		"      \"amount\" : x.xxx    (numeric) Their total amount in BCH\n" +
+		"    }\n" +
+		"    ,...\n" +
+		"  ],\n" +
+		"  \"byage\" : [             (json array) The outputs by age, " +
+		"\"max\" being the largest age in blocks of the bucket\n" +
+		"    ...\n" +
+		"  ],\n" +
+		"  \"byscripttype\" : {      (json object) The outputs by script " +
+		"type (pubkey, pubkeyhash, scripthash, multisig, nonstandard)\n" +
+		"    \"type\" : { \"count\" : n, \"amount\" : x.xxx }\n" +
+		"    ,...\n" +
+		"  }\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		"> coperctl getutxodistribution 100\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getutxodistribution", "params": [100] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
+	getblocklocationsDesc = "getblocklocations \"blockhash\" ( nblocks )\n" +
+		"\nReturns where blocks and their undo data are stored in the " +
+		"blk?????.dat and rev?????.dat files, so that external tools can " +
+		"read them from the raw files.\n" +
+		"\nArguments:\n" +
+		"1. \"blockhash\"    (string, required) The hash of the first block\n" +
+		"2. nblocks        (numeric, optional, default=1) The number of " +
+		"blocks to report, the block and the blocks following it in the " +
+		"active chain, at most 2000\n" +
+		"\nResult:\n" +
+		"[\n" +
+		"  {\n" +
+		"    \"hash\" : \"hash\",     (string) The block hash\n" +
+		"    \"height\" : n,         (numeric) The block height\n" +
+		"    \"file\" : n,           (numeric) The number of the files " +
+		"holding the block and its undo data, -1 if the block is not stored\n" +
+		"    \"datafile\" : \"path\", (string) The path of the block file\n" +
+		"    \"dataoffset\" : n,     (numeric) The offset in the block " +
+		"file of the 4 byte little endian length preceding the serialized " +
+		"block\n" +
+		"    \"undofile\" : \"path\", (string) The path of the undo file, " +
+		"absent if the block has no undo data\n" +
+		"    \"undooffset\" : n      (numeric) The offset in the undo file " +
+		"of the 4 byte little endian length preceding the undo data and its " +
+		"32 byte checksum\n" +
+		"  }\n" +
+		"  ,...\n" +
+		"]\n" +
+		"\nExamples:\n" +
+		"> coperctl getblocklocations \"00000000c937983704a73af28acdec37b049d214adbda81d7e2a3dd146f6ed09\" 10\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getblocklocations", "params": ["00000000c937983704a73af28acdec37b049d214adbda81d7e2a3dd146f6ed09", 10] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
 	pruneblockchainDesc = "pruneblockchain\n" +
 		"\nArguments:\n" +
 		"1. \"height\"       (numeric, required) The block height to prune " +
@@ -555,7 +867,7 @@ const (
 		"\nArguments:\n" +
 		"1. checklevel   (numeric, optional, 0-4, default=3)" +
 		" How thorough the block verification is.\n" +
-		"2. nblocks      (numeric, optional, default=6 , 0=all) " +
+		"2. nblocks      (numeric, optional, default=288, 0=all) " +
 		"The number of blocks to check.\n" +
 		"\nResult:\n" +
 		"true|false       (boolean) Verified or not\n" +
@@ -576,6 +888,110 @@ const (
 		"\nExamples:\n" +
 		`> coperctl preciousblock "blockhash"` + "\n" +
 		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "preciousblock", "params": ["blockhash"] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
+	getactivityDesc = "getactivity ( sequence )\n" +
+		"\nReturns the events following the sequence number: the blocks " +
+		"connected to and disconnected from the active chain and the " +
+		"transactions added to and removed from the mempool, numbered with " +
+		"a monotonically increasing sequence.\n" +
+		"Pollers pass the sequence of the last event they saw to catch up on " +
+		"the ones they missed. At most 1000 events are returned at once and " +
+		"only the recent events are kept, truncated tells that some events " +
+		"following the sequence were forgotten and a full resync is needed. " +
+		"The sequence starts over when the node restarts, a sequence ahead " +
+		"of the latest event is refused.\n" +
+		"\nArguments:\n" +
+		"1. sequence   (numeric, optional, default=0) the sequence of the " +
+		"last event seen\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"sequence\": n,       (numeric) the sequence of the latest event\n" +
+		"  \"truncated\": true|false, (boolean) whether events following " +
+		"the sequence were forgotten\n" +
+		"  \"events\": [          (array) the events in order\n" +
+		"    {\n" +
+		"      \"sequence\": n,   (numeric) the sequence of the event\n" +
+		"      \"type\": \"xxxx\",  (string) blockconnected, " +
+		"blockdisconnected, txadded or txremoved\n" +
+		"      \"hash\": \"xxxx\",  (string) the block hash or the txid\n" +
+		"      \"height\": n,     (numeric) the height of the block\n" +
+		"      \"reason\": \"xxxx\" (string) why the transaction was " +
+		"removed from the mempool\n" +
+		"    }, ...\n" +
+		"  ]\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		"> coperctl getactivity 1000\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getactivity", "params": [1000] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
+	getutxocachestatsDesc = "getutxocachestats\n" +
+		"\nReturns the lookups of the coins cache since the node started, " +
+		"and the latencies of the reads and writes of the databases, to size " +
+		"the caches.\n" +
+		"The same statistics are served to Prometheus on the /metrics path " +
+		"of the RPC server, with the RPC credentials.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"hits\": n,              (numeric) Coins found in the cache\n" +
+		"  \"misses\": n,            (numeric) Coins read through to the " +
+		"database\n" +
+		"  \"notfound\": n,          (numeric) Misses which found no coin " +
+		"in the database either\n" +
+		"  \"hitratio\": x.xxx,      (numeric) Share of the lookups found in " +
+		"the cache\n" +
+		"  \"coins\": n,             (numeric) Coins in the cache\n" +
+		"  \"dirtycoins\": n,        (numeric) Coins not flushed to the " +
+		"database yet\n" +
+		"  \"capacity\": n,          (numeric) Coins the cache holds at most\n" +
+		"  \"databases\": {          (json object) One entry per database " +
+		"read or written\n" +
+		"    \"name\": {\n" +
+		"      \"reads\": {          (json object) The reads of the database\n" +
+		"        \"count\": n,           (numeric) Number of reads\n" +
+		"        \"total_time_ms\": x.xxx, (numeric) Time spent reading\n" +
+		"        \"avg_time_ms\": x.xxx,   (numeric) Average time per read\n" +
+		"        \"latency_buckets\": {  (json object) Number of reads " +
+		"completed within each bound, cumulative\n" +
+		"          \"10µs\": n,\n" +
+		"          ...\n" +
+		"          \"+Inf\": n\n" +
+		"        }\n" +
+		"      },\n" +
+		"      \"writes\": {...}     (json object) The batch writes of the " +
+		"database, as the reads\n" +
+		"    }, ...\n" +
+		"  }\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		"> coperctl getutxocachestats\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getutxocachestats", "params": [] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
+	getdifficultyhistoryDesc = "getdifficultyhistory startheight ( endheight )\n" +
+		"\nReturns the proof of work of the blocks of the active chain from " +
+		"startheight to endheight, to follow the difficulty adjustments " +
+		"without a getblockheader call per block.\n" +
+		"A reply covers at most 10000 heights, longer ranges are fetched " +
+		"in pages.\n" +
+		"\nArguments:\n" +
+		"1. startheight   (numeric, required) The height of the first block\n" +
+		"2. endheight     (numeric, optional, default=the tip) The height of " +
+		"the last block\n" +
+		"\nResult:\n" +
+		"[                  (array) The blocks by increasing height\n" +
+		"  {\n" +
+		"    \"height\": n,     (numeric) The height of the block\n" +
+		"    \"hash\": \"hash\",  (string) The block hash\n" +
+		"    \"time\": n,       (numeric) The block time in seconds since " +
+		"epoch\n" +
+		"    \"mediantime\": n, (numeric) The median time past of the block\n" +
+		"    \"bits\": \"xxxx\",  (string) The compact target\n" +
+		"    \"difficulty\": x.xxx, (numeric) The difficulty\n" +
+		"    \"target\": \"xxxx\" (string) The target the bits encode\n" +
+		"  }, ...\n" +
+		"]\n" +
+		"\nExamples:\n" +
+		"> coperctl getdifficultyhistory 556000 556100\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getdifficultyhistory", "params": [556000, 556100] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
 )
 
 //mining
@@ -606,13 +1022,19 @@ const (
 		"  \"currentblocksize\": nnn,   (numeric) The last block size\n" +
 		"  \"currentblocktx\": nnn,     (numeric) The last block " +
 		"transaction\n" +
+		"  \"currentblockfees\": x.xxx, (numeric) The fees of the last " +
+		"block template in BCH\n" +
 		"  \"difficulty\": xxx.xxxxx    (numeric) The current difficulty\n" +
 		"  \"errors\": \"...\"            (string) Current errors\n" +
+		"  \"warnings\": \"...\"          (string) Any network warnings\n" +
 		"  \"networkhashps\": nnn,      (numeric) The network hashes per " +
 		"second\n" +
 		"  \"pooledtx\": n              (numeric) The size of the mempool\n" +
 		"  \"chain\": \"xxxx\",           (string) current network name as " +
 		"defined in BIP70 (main, test, regtest)\n" +
+		"  \"generate\": true|false     (boolean) If the CPU miner is on\n" +
+		"  \"genproclimit\": n          (numeric) The number of CPU miner " +
+		"workers\n" +
 		"}\n" +
 		"\nExamples:\n" +
 		"> coperctl getmininginfo\n" +
@@ -620,6 +1042,30 @@ const (
 
 	prioritisetransactionDesc = ""
 
+	getgenerateDesc = "getgenerate\n" +
+		"\nReturn if the server is set to generate coins or not, see " +
+		"setgenerate.\n" +
+		"\nResult:\n" +
+		"true|false      (boolean) If the server is set to generate coins " +
+		"or not\n" +
+		"\nExamples:\n" +
+		"> coperctl getgenerate\n"
+
+	setgenerateDesc = "setgenerate generate ( genproclimit )\n" +
+		"\nSet 'generate' true or false to turn generation on or off.\n" +
+		"Generation is limited to 'genproclimit' workers, -1 is one per " +
+		"CPU. The blocks pay to the address configured as MiningAddr.\n" +
+		"\nArguments:\n" +
+		"1. generate         (boolean, required) Set to true to turn on " +
+		"generation, false to turn off.\n" +
+		"2. genproclimit     (numeric, optional) Set the worker limit for " +
+		"when generation is on. Can be -1 for unlimited.\n" +
+		"\nExamples:\n" +
+		"\nSet the generation on with a limit of one worker\n" +
+		"> coperctl setgenerate true 1\n" +
+		"\nTurn off generation\n" +
+		"> coperctl setgenerate false\n"
+
 	getblocktemplateDesc = "getblocktemplate ( TemplateRequest )\n" +
 		"\nIf the request parameters include a 'mode' key, that is used to " +
 		"explicitly select between the default 'template' request or a " +
@@ -653,7 +1099,11 @@ const (
 		"           \"support\"          (string) client side supported " +
 		"softfork deployment\n" +
 		"           ,...\n" +
-		"       ]\n" +
+		"       ],\n" +
+		"       \"longpollid\":\"id\"   (string, optional) Delay the " +
+		"reply until the template identified by id is stale\n" +
+		"       \"maxversion\":n       (numeric, optional) The highest " +
+		"block version the client understands\n" +
 		"     }\n" +
 		"\n" +
 
@@ -715,7 +1165,11 @@ const (
 		"input to coinbase transaction, including the generation award and " +
 		"transaction fees (in Satoshis)\n" +
 		"  \"coinbasetxn\" : { ... },          (json object) information " +
-		"for coinbase transaction\n" +
+		"for coinbase transaction paying to the configured mining address, " +
+		"returned instead of coinbasevalue when 'coinbasetxn' is a " +
+		"capability\n" +
+		"  \"longpollid\" : \"xxxx\",            (string) id to wait on " +
+		"with a long poll request\n" +
 		"  \"target\" : \"xxxx\",                (string) The hash target\n" +
 		"  \"mintime\" : xxx,                  (numeric) The minimum " +
 		"timestamp appropriate for next block time in seconds since epoch " +
@@ -758,13 +1212,29 @@ const (
 		"provided a workid, it MUST be included with submissions\n" +
 		"    }\n" +
 		"\nResult:\n" +
+		"null, or a string describing why the block was not accepted: " +
+		"\"duplicate\", \"duplicate-invalid\", \"inconclusive\" or " +
+		"\"rejected: <reason>\"\n" +
 		"\nExamples:\n" +
 		`> coperctl submitblock "mydata"` + "\n" +
 		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "submitblock", "params": ["mydata"] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
 
+	submitheaderDesc = "submitheader \"hexdata\"\n" +
+		"\nDecode the given hexdata as a header and submit it as a " +
+		"candidate chain tip if valid.\n" +
+		"Throws when the header is invalid.\n" +
+		"\nArguments\n" +
+		"1. \"hexdata\"        (string, required) the hex-encoded block " +
+		"header data\n" +
+		"\nResult:\n" +
+		"None\n" +
+		"\nExamples:\n" +
+		`> coperctl submitheader "aabbcc"` + "\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "submitheader", "params": ["aabbcc"] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
 	generateDesc = "generate nblocks ( maxtries )\n" +
 		"\nMine up to nblocks blocks immediately (before the RPC call " +
-		"returns)\n" +
+		"returns), paying to the address configured as MiningAddr\n" +
 		"\nArguments:\n" +
 		"1. nblocks      (numeric, required) How many blocks are generated " +
 		"immediately.\n" +
@@ -791,6 +1261,38 @@ const (
 		"\nExamples:\n" +
 		"\nGenerate 11 blocks to myaddress\n" +
 		`> coperctl generatetoaddress 11 "myaddress"`
+
+	estimatefeeDesc = "estimatefee nblocks\n" +
+		"\nEstimates the approximate fee per kilobyte needed for a " +
+		"transaction to begin confirmation within nblocks blocks.\n" +
+		"\nArguments:\n" +
+		"1. nblocks     (numeric, required) The number of blocks, up to 25\n" +
+		"\nResult:\n" +
+		"n              (numeric) estimated fee-per-kilobyte in BCH\n" +
+		"\nAn error is returned until enough blocks were observed to " +
+		"make an estimate.\n" +
+		"\nExamples:\n" +
+		"> coperctl estimatefee 6\n"
+
+	estimatesmartfeeDesc = "estimatesmartfee conf_target\n" +
+		"\nEstimates the approximate fee per kilobyte needed for a " +
+		"transaction to begin confirmation within conf_target blocks if " +
+		"possible and return the number of blocks for which the estimate " +
+		"is valid. The estimate is never below the minimum fee the " +
+		"mempool accepts.\n" +
+		"\nArguments:\n" +
+		"1. conf_target     (numeric, required) Confirmation target in blocks\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"feerate\" : x.x,     (numeric, optional) estimate fee-per-kilobyte (in BCH)\n" +
+		"  \"errors\": [ str... ] (json array of strings, optional) Errors encountered during processing\n" +
+		"  \"blocks\" : n         (numeric) block number where estimate was found\n" +
+		"}\n" +
+		"\nA target beyond 25 blocks is estimated for 25 blocks. When no " +
+		"estimate can be made for conf_target, the nearest longer target " +
+		"with an estimate is used.\n" +
+		"\nExamples:\n" +
+		"> coperctl estimatesmartfee 6\n"
 )
 
 // net
@@ -853,6 +1355,8 @@ const (
 		"Outbound (false)\n" +
 		"    \"addnode\": true|false,     (boolean) Whether connection was " +
 		"due to addnode and is using an addnode slot\n" +
+		"    \"connection_type\": \"str\",  (string) Type of connection: " +
+		"inbound, manual, outbound-full-relay, block-relay-only or feeler\n" +
 		"    \"startingheight\": n,       (numeric) The starting height " +
 		"(block) of the peer\n" +
 		"    \"banscore\": n,             (numeric) The ban score\n" +
@@ -940,9 +1444,9 @@ const (
 		"  ,...\n" +
 		"]\n" +
 		"\nExamples:\n" +
-		"> coperctl getaddednodeinfo true\n" +
-		`> coperctl getaddednodeinfo true "192.168.0.201"` + "\n" +
-		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getaddednodeinfo", "params": [true, "192.168.0.201"] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+		"> coperctl getaddednodeinfo\n" +
+		`> coperctl getaddednodeinfo "192.168.0.201"` + "\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getaddednodeinfo", "params": ["192.168.0.201"] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
 
 	getnettotalsDesc = "getnettotals\n" +
 		"\nReturns information about network traffic, including bytes in, " +
@@ -968,6 +1472,16 @@ const (
 		"left in current time cycle\n" +
 		"    \"time_left_in_cycle\": t                 (numeric) Seconds " +
 		"left in current time cycle\n" +
+		"  },\n" +
+		"  \"bytessent_per_msg\": {\n" +
+		"     \"addr\": n,            (numeric) The total bytes sent to all " +
+		"peers aggregated by message type\n" +
+		"     ...\n" +
+		"  },\n" +
+		"  \"bytesrecv_per_msg\": {\n" +
+		"     \"addr\": n,            (numeric) The total bytes received " +
+		"from all peers aggregated by message type\n" +
+		"     ...\n" +
 		"  }\n" +
 		"}\n" +
 		"\nExamples:\n" +
@@ -1057,6 +1571,16 @@ const (
 
 	listbannedDesc = "listbanned\n" +
 		"\nList all banned IPs/Subnets.\n" +
+		"\nResult:\n" +
+		"[\n" +
+		"  {\n" +
+		"    \"address\": \"xxxx\",      (string) The banned IP/Subnet\n" +
+		"    \"banned_until\": n,      (numeric) The time the ban ends, in seconds since epoch (Jan 1 1970 GMT)\n" +
+		"    \"ban_created\": n,       (numeric) The time the ban was created, in seconds since epoch (Jan 1 1970 GMT)\n" +
+		"    \"ban_reason\": \"xxxx\"    (string) Why the IP/Subnet was banned, 'node misbehaving' or 'manually added'\n" +
+		"  }\n" +
+		"  ,...\n" +
+		"]\n" +
 		"\nExamples:\n" +
 		`> coperctl listbanned` + "\n" +
 		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "listbanned", "params": [] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
@@ -1064,7 +1588,7 @@ const (
 	clearbannedDesc = "clearbanned\n" +
 		"\nClear all banned IPs.\n" +
 		"\nExamples:\n" +
-		`> coperctl listbanned` + "\n" +
+		`> coperctl clearbanned` + "\n" +
 		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "clearbanned", "params": [] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
 
 	setnetworkactiveDesc = "setnetworkactive true|false\n" +
@@ -1072,14 +1596,36 @@ const (
 		"\nArguments:\n" +
 		"1. \"state\"        (boolean, required) true to " +
 		"enable networking, false to disable\n"
+
+	syncmempoolDesc = "syncmempool nodeid\n" +
+		"\nReconciles the mempool with a trusted peer, typically after " +
+		"restarting one node of a redundant pair.\n" +
+		"Both nodes exchange the short ids of their mempool transactions " +
+		"and each fetches the transactions it misses from the other.\n" +
+		"Mempool sync must be enabled on both nodes (P2PNet.MempoolSync) " +
+		"and each node must whitelist the other.\n" +
+		"The reconciliation runs in the background, this call returns once " +
+		"it is started.\n" +
+		"\nArguments:\n" +
+		"1. \"nodeid\"     (numeric, required) The node ID (see getpeerinfo for node IDs)\n" +
+		"\nExamples:\n" +
+		"> coperctl syncmempool 1\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "syncmempool", "params": [1] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
 )
 
 // rawtransaction
 const (
-	getrawtransactionDesc = "getrawtransaction \"txid\" ( verbose )\n" +
+	getrawtransactionDesc = "getrawtransaction \"txid\" ( verbose \"blockhash\" )\n" +
 		"\nNOTE: By default this function only works for mempool " +
 		"transactions. If the -txindex option is\n" +
-		"enabled, it also works for blockchain transactions.\n" +
+		"enabled, it also works for blockchain transactions. If the block " +
+		"which contains the transaction\n" +
+		"is known, its hash can be provided even for nodes without -txindex. " +
+		"Note that if a blockhash is\n" +
+		"provided, only that block will be searched and if the transaction " +
+		"is in the mempool or other\n" +
+		"blocks, or if this node does not have the given block available, " +
+		"the transaction will not be found.\n" +
 		"DEPRECATED: for now, it also works for transactions with unspent " +
 		"outputs.\n" +
 		"\nReturn the raw transaction data.\n" +
@@ -1091,11 +1637,15 @@ const (
 		"1. \"txid\"      (string, required) The transaction id\n" +
 		"2. verbose       (bool, optional, default=false) If false, return " +
 		"a string, otherwise return a json object\n" +
+		"3. \"blockhash\" (string, optional) The block in which to look for " +
+		"the transaction\n" +
 		"\nResult (if verbose is not set or set to false):\n" +
 		"\"data\"      (string) The serialized, hex-encoded data for " +
 		"'txid'\n" +
 		"\nResult (if verbose is set to true):\n" +
 		"{\n" +
+		"  \"in_active_chain\": b, (bool) Whether specified block is in " +
+		"the active chain or not (only present with explicit \"blockhash\" argument)\n" +
 		"  \"hex\" : \"data\",       (string) The serialized, hex-encoded " +
 		"data for 'txid'\n" +
 		"  \"txid\" : \"id\",        (string) The transaction id (same as " +
@@ -1146,7 +1696,57 @@ const (
 		"\nExamples:\n" +
 		`> coperctl getrawtransaction "mytxid"` + "\n" +
 		`> coperctl getrawtransaction "mytxid" true` + "\n" +
-		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getrawtransaction", "params": ["mytxid", true] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getrawtransaction", "params": ["mytxid", true] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/` + "\n" +
+		`> coperctl getrawtransaction "mytxid" false "myblockhash"` + "\n" +
+		`> coperctl getrawtransaction "mytxid" true "myblockhash"`
+
+	getrawtransactionsDesc = "getrawtransactions [\"txid\",...] ( verbose )\n" +
+		"\nReturn the raw transaction data of several transactions at once.\n" +
+		"Every txid is looked up like getrawtransaction does. A txid which " +
+		"can't be found doesn't fail the request,\n" +
+		"its entry carries the error instead.\n" +
+		"\nArguments:\n" +
+		"1. \"txids\"     (array, required) The transaction ids, at most 1000\n" +
+		"2. verbose       (bool, optional, default=false) If false, return " +
+		"hex strings, otherwise return json objects\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"txid\" : {              (json object) One entry per requested txid\n" +
+		"    \"result\" : ...,       (string or json object) The same result " +
+		"getrawtransaction returns, null on error\n" +
+		"    \"error\" : {           (json object) null on success\n" +
+		"      \"code\" : n,         (numeric) The error code\n" +
+		"      \"message\" : \"...\" (string) The error message\n" +
+		"    }\n" +
+		"  }\n" +
+		"  ,...\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		`> coperctl getrawtransactions '["mytxid","myothertxid"]'` + "\n" +
+		`> coperctl getrawtransactions '["mytxid","myothertxid"]' true` + "\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getrawtransactions", "params": [["mytxid","myothertxid"], true] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
+	getspentinfoDesc = "getspentinfo {\"txid\": \"id\", \"index\": n}\n" +
+		"\nReturns the txid and input index of the transaction spending an " +
+		"output in the active chain.\n" +
+		"\nRequires the spent index (Chain.SpentIndex) to be enabled.\n" +
+		"\nArguments:\n" +
+		"{\n" +
+		"  \"txid\" : \"id\",    (string, required) The id of the transaction " +
+		"creating the output\n" +
+		"  \"index\" : n       (numeric, required) The output index\n" +
+		"}\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"txid\" : \"id\",    (string) The id of the spending transaction\n" +
+		"  \"index\" : n,      (numeric) The input index in the spending " +
+		"transaction\n" +
+		"  \"height\" : n      (numeric) The height of the block of the " +
+		"spending transaction\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		`> coperctl getspentinfo '{"txid": "mytxid", "index": 0}'` + "\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getspentinfo", "params": [{"txid": "mytxid", "index": 0}] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
 
 	createrawtransactionDesc = "createrawtransaction [{\"txid\":\"id\",\"vout\":n},...] " +
 		"{\"address\":amount,\"data\":\"hex\",...} ( locktime )\n" +
@@ -1166,7 +1766,8 @@ const (
 		"         \"vout\":n,         (numeric, required) The output " +
 		"number\n" +
 		"         \"sequence\":n      (numeric, optional) The sequence " +
-		"number\n" +
+		"number, default 4294967295, or 4294967294 with a non-0 locktime. " +
+		"Values below 2147483648 set a relative locktime (BIP68)\n" +
 		"       } \n" +
 		"       ,...\n" +
 		"     ]\n" +
@@ -1207,6 +1808,8 @@ const (
 		"  \"hash\" : \"id\",        (string) The transaction hash " +
 		"(differs from txid for witness transactions)\n" +
 		"  \"size\" : n,             (numeric) The transaction size\n" +
+		"  \"fee\" : x.xxx,          (numeric, optional) The fee in " +
+		"BCH, if all the spent outputs are in the UTXO set or the mempool\n" +
 		"  \"version\" : n,          (numeric) The version\n" +
 		"  \"locktime\" : ttt,       (numeric) The lock time\n" +
 		"  \"vin\" : [               (array of json objects)\n" +
@@ -1318,8 +1921,8 @@ const (
 		"output number\n" +
 		"         \"scriptPubKey\": \"hex\",   (string, required) script " +
 		"key\n" +
-		"         \"redeemScript\": \"hex\",   (string, required for P2SH " +
-		"or P2WSH) redeem script\n" +
+		"         \"redeemScript\": \"hex\",   (string, required for P2SH) " +
+		"redeem script\n" +
 		"         \"amount\": value            (numeric, required) The " +
 		"amount spent\n" +
 		"       }\n" +
@@ -1332,14 +1935,8 @@ const (
 		"      \"privatekey\"   (string) private key in base58-encoding\n" +
 		"      ,...\n" +
 		"    ]\n" +
-		"4. \"sighashtype\"     (string, optional, default=ALL) The " +
+		"4. \"sighashtype\"     (string, optional, default=ALL|FORKID) The " +
 		"signature hash type. Must be one of\n" +
-		"       \"ALL\"\n" +
-		"       \"NONE\"\n" +
-		"       \"SINGLE\"\n" +
-		"       \"ALL|ANYONECANPAY\"\n" +
-		"       \"NONE|ANYONECANPAY\"\n" +
-		"       \"SINGLE|ANYONECANPAY\"\n" +
 		"       \"ALL|FORKID\"\n" +
 		"       \"NONE|FORKID\"\n" +
 		"       \"SINGLE|FORKID\"\n" +
@@ -1406,6 +2003,43 @@ const (
 		"\nResult:\n" +
 		"[\"txid\"]      (array, strings) The txid(s) which the proof " +
 		"commits to, or empty array if the proof is invalid\n"
+
+	gethexblockheaderchainDesc = "gethexblockheaderchain \"txid\" ( nheaders \"blockhash\" )\n" +
+		"\nReturns in one response what a light client needs to check that " +
+		"a transaction is buried in the best chain: the raw transaction, " +
+		"the proof of its inclusion in a block and the headers of the " +
+		"blocks following that block.\n" +
+		"\nLike gettxoutproof the block is only found without blockhash when " +
+		"the transaction has an unspent output or the transaction index is " +
+		"enabled.\n" +
+		"\nArguments:\n" +
+		"1. \"txid\"        (string, required) The transaction id\n" +
+		"2. nheaders      (numeric, optional, default=6) The number of " +
+		"headers to return after the block, at most 2000. Fewer are " +
+		"returned near the tip\n" +
+		"3. \"blockhash\"   (string, optional) If specified, looks for " +
+		"txid in the block with this hash\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"txid\" : \"id\",             (string) The transaction id\n" +
+		"  \"hex\" : \"data\",            (string) The serialized, " +
+		"hex-encoded transaction\n" +
+		"  \"blockhash\" : \"hash\",      (string) The block holding the " +
+		"transaction\n" +
+		"  \"height\" : n,              (numeric) The height of the block\n" +
+		"  \"confirmations\" : n,       (numeric) The confirmations of the " +
+		"transaction\n" +
+		"  \"txoutproof\" : \"data\",     (string) The hex-encoded proof, as " +
+		"returned by gettxoutproof. It starts with the block header\n" +
+		"  \"headers\" : [              (json array) The hex-encoded headers " +
+		"of the following blocks, in chain order\n" +
+		"    \"data\"\n" +
+		"    ,...\n" +
+		"  ]\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		`> coperctl gethexblockheaderchain "mytxid" 6` + "\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "gethexblockheaderchain", "params": ["mytxid", 6] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
 )
 
 // misc
@@ -1489,12 +2123,11 @@ const (
 
 		"\nArguments:\n" +
 		"1. nrequired      (numeric, required) The number of required " +
-		"signatures out of the n keys or addresses.\n" +
-		"2. \"keys\"       (string, required) A json array of keys which " +
-		"are bitcoin addresses or hex-encoded public keys\n" +
+		"signatures out of the n keys.\n" +
+		"2. \"keys\"       (string, required) A json array of hex-encoded " +
+		"public keys\n" +
 		"     [\n" +
-		"       \"key\"    (string) bitcoin address or hex-encoded public " +
-		"key\n" +
+		"       \"key\"    (string) The hex-encoded public key\n" +
 		"       ,...\n" +
 		"     ]\n" +
 
@@ -1507,8 +2140,316 @@ const (
 		"}\n" +
 
 		"\nExamples:\n" +
-		"\nCreate a multisig address from 2 addresses\n" +
-		`> coperctl createmultisig 2 "[\"16sSauSf5pF2UkUwvKGq4qjNRzBZYqgEL5\",\"171sgjn4YtPu27adkKGrdDwzRTxnRkBfKV\"]"` +
+		"\nCreate a multisig address from 2 public keys\n" +
+		`> coperctl createmultisig 2 "[\"03789ed0bb717d88f7d321a368d905e7430207ebbd82bd342cf11ae157a7ace5fd\",\"03dbc6764b8884a92e871274b87583e6d5c2a58819473e17e107ef3f6aa5a61626\"]"` +
 		"\nAs a json rpc call\n" +
-		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "createmultisig", "params": [2, "[\"16sSauSf5pF2UkUwvKGq4qjNRzBZYqgEL5\",\"171sgjn4YtPu27adkKGrdDwzRTxnRkBfKV\"]"] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "createmultisig", "params": [2, "[\"03789ed0bb717d88f7d321a368d905e7430207ebbd82bd342cf11ae157a7ace5fd\",\"03dbc6764b8884a92e871274b87583e6d5c2a58819473e17e107ef3f6aa5a61626\"]"] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
+	getrpcstatsDesc = "getrpcstats\n" +
+		"\nReturns call counts, error counts and latencies of every RPC " +
+		"method called since the server started.\n" +
+		"The same statistics are served to Prometheus on the /metrics path " +
+		"of the RPC server, with the RPC credentials.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"method\" : {                (json object) One entry per called method\n" +
+		"    \"calls\" : n,              (numeric) Number of calls\n" +
+		"    \"errors\" : n,             (numeric) Number of calls which " +
+		"returned an error\n" +
+		"    \"panics\" : n,             (numeric) Number of calls whose " +
+		"handler crashed, reported as internal errors\n" +
+		"    \"total_time_ms\" : x.xxx,  (numeric) Time spent in the method\n" +
+		"    \"avg_time_ms\" : x.xxx,    (numeric) Average time per call\n" +
+		"    \"latency_buckets\" : {     (json object) Number of calls " +
+		"completed within each bound, cumulative\n" +
+		"      \"1ms\" : n,\n" +
+		"      ...\n" +
+		"      \"+Inf\" : n\n" +
+		"    }\n" +
+		"  }\n" +
+		"  ,...\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		"> coperctl getrpcstats\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getrpcstats", "params": [] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
+	getversioninfoDesc = "getversioninfo\n" +
+		"\nReturns the version of the running node, the commit and toolchain " +
+		"it was built from and the optional features it supports.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"version\" : \"x.x.x\",       (string) The node version\n" +
+		"  \"protocolversion\" : n,     (numeric) The protocol version\n" +
+		"  \"useragent\" : \"xxx\",       (string) The user agent sent to peers\n" +
+		"  \"gitcommit\" : \"xxx\",       (string) The commit the binary was " +
+		"built from, empty if unknown\n" +
+		"  \"builddate\" : \"xxx\",       (string) The date of that commit\n" +
+		"  \"goversion\" : \"xxx\",       (string) The Go toolchain version\n" +
+		"  \"features\" : {             (json object) Whether each optional " +
+		"feature is available\n" +
+		"    \"wallet\" : true|false,\n" +
+		"    \"zmq\" : true|false,\n" +
+		"    \"txindex\" : true|false,\n" +
+		"    \"addressindex\" : true|false,\n" +
+		"    \"spentindex\" : true|false\n" +
+		"  }\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		"> coperctl getversioninfo\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getversioninfo", "params": [] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
+	uptimeDesc = "uptime\n" +
+		"\nReturns the total uptime of the server.\n" +
+		"\nResult:\n" +
+		"ttt        (numeric) The number of seconds that the server has been running\n" +
+		"\nExamples:\n" +
+		"> coperctl uptime\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "uptime", "params": [] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
+	getmemoryinfoDesc = "getmemoryinfo (\"mode\")\n" +
+		"\nReturns an object containing information about memory usage.\n" +
+		"\nArguments:\n" +
+		"1. \"mode\"  (string, optional, default: \"stats\") determines what kind of " +
+		"information is returned. Only \"stats\" is supported, \"mallocinfo\" is " +
+		"rejected as the node does not use the C allocator.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"locked\" : {               (json object) Information about the heap, " +
+		"in the layout of the locked memory report\n" +
+		"    \"used\" : xxxxx,          (numeric) Heap bytes in use\n" +
+		"    \"free\" : xxxxx,          (numeric) Heap bytes idle\n" +
+		"    \"total\" : xxxxxxx,       (numeric) Heap bytes obtained from the OS\n" +
+		"    \"locked\" : xxxxxx,       (numeric) Always zero, the heap is not locked\n" +
+		"    \"chunks_used\" : xxxxx,   (numeric) Number of live objects\n" +
+		"    \"chunks_free\" : xxxxx    (numeric) Always zero\n" +
+		"  },\n" +
+		"  \"runtime\" : {              (json object) Go runtime memory statistics, " +
+		"sizes in bytes\n" +
+		"    \"heap_alloc\" : n,        (numeric) Bytes of allocated heap objects\n" +
+		"    \"heap_sys\" : n,          (numeric) Heap bytes obtained from the OS\n" +
+		"    \"heap_inuse\" : n,        (numeric) Bytes in in-use spans\n" +
+		"    \"heap_idle\" : n,         (numeric) Bytes in idle spans\n" +
+		"    \"heap_released\" : n,     (numeric) Bytes returned to the OS\n" +
+		"    \"heap_objects\" : n,      (numeric) Number of allocated heap objects\n" +
+		"    \"stack_inuse\" : n,       (numeric) Bytes in stack spans\n" +
+		"    \"sys\" : n,               (numeric) Total bytes obtained from the OS\n" +
+		"    \"total_alloc\" : n,       (numeric) Cumulative bytes allocated\n" +
+		"    \"mallocs\" : n,           (numeric) Cumulative count of allocations\n" +
+		"    \"frees\" : n,             (numeric) Cumulative count of frees\n" +
+		"    \"num_gc\" : n,            (numeric) Number of completed GC cycles\n" +
+		"    \"num_forced_gc\" : n,     (numeric) Number of GC cycles forced by " +
+		"the application\n" +
+		"    \"pause_total_ms\" : x.xxx, (numeric) Total time spent in GC pauses\n" +
+		"    \"last_gc\" : ttt,         (numeric) The time of the last GC in " +
+		"seconds since epoch, 0 if none\n" +
+		"    \"goroutines\" : n         (numeric) Number of running goroutines\n" +
+		"  }\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		"> coperctl getmemoryinfo\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getmemoryinfo", "params": [] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
+	getrpcinfoDesc = "getrpcinfo\n" +
+		"\nReturns details of the RPC server.\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"active_commands\" : [     (json array) All active commands, the " +
+		"longest running first\n" +
+		"    {\n" +
+		"      \"method\" : \"xxx\",     (string) The name of the RPC command\n" +
+		"      \"duration\" : n         (numeric) The running time in microseconds\n" +
+		"    }\n" +
+		"    ,...\n" +
+		"  ],\n" +
+		"  \"logpath\" : \"xxx\"        (string) The complete file path to the debug log\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		"> coperctl getrpcinfo\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getrpcinfo", "params": [] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+)
+
+// addressindex
+const (
+	getaddresstxidsDesc = "getaddresstxids {\"addresses\": [\"address\",...], \"start\": n, \"end\": n}\n" +
+		"\nReturns the txids of the transactions paying to or spending from the " +
+		"addresses, in block order.\n" +
+		"\nRequires the address index (Chain.AddressIndex) to be enabled.\n" +
+		"\nArguments:\n" +
+		"{\n" +
+		"  \"addresses\" : [            (array, required) The base58check encoded addresses\n" +
+		"    \"address\"\n" +
+		"    ,...\n" +
+		"  ],\n" +
+		"  \"start\" : n,                (numeric, optional) The start block height\n" +
+		"  \"end\" : n                   (numeric, optional) The end block height\n" +
+		"}\n" +
+		"\nResult:\n" +
+		"[\n" +
+		"  \"transactionid\"             (string) The transaction id\n" +
+		"  ,...\n" +
+		"]\n" +
+		"\nExamples:\n" +
+		"> coperctl getaddresstxids '{\"addresses\": [\"12c6DSiU4Rq3P4ZxziKxzrL5LmMBrzjrJX\"]}'\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getaddresstxids", "params": [{"addresses": ["12c6DSiU4Rq3P4ZxziKxzrL5LmMBrzjrJX"]}] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
+	getaddressbalanceDesc = "getaddressbalance {\"addresses\": [\"address\",...]}\n" +
+		"\nReturns the balance of the addresses in the active chain.\n" +
+		"\nRequires the address index (Chain.AddressIndex) to be enabled.\n" +
+		"\nArguments:\n" +
+		"{\n" +
+		"  \"addresses\" : [            (array, required) The base58check encoded addresses\n" +
+		"    \"address\"\n" +
+		"    ,...\n" +
+		"  ]\n" +
+		"}\n" +
+		"\nResult:\n" +
+		"{\n" +
+		"  \"balance\" : n,              (numeric) The current balance in satoshis\n" +
+		"  \"received\" : n              (numeric) The total number of satoshis received, " +
+		"including change\n" +
+		"}\n" +
+		"\nExamples:\n" +
+		"> coperctl getaddressbalance '{\"addresses\": [\"12c6DSiU4Rq3P4ZxziKxzrL5LmMBrzjrJX\"]}'\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getaddressbalance", "params": [{"addresses": ["12c6DSiU4Rq3P4ZxziKxzrL5LmMBrzjrJX"]}] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
+	getaddressutxosDesc = "getaddressutxos {\"addresses\": [\"address\",...]}\n" +
+		"\nReturns the unspent outputs paying to the addresses.\n" +
+		"\nRequires the address index (Chain.AddressIndex) to be enabled.\n" +
+		"\nArguments:\n" +
+		"{\n" +
+		"  \"addresses\" : [            (array, required) The base58check encoded addresses\n" +
+		"    \"address\"\n" +
+		"    ,...\n" +
+		"  ]\n" +
+		"}\n" +
+		"\nResult:\n" +
+		"[\n" +
+		"  {\n" +
+		"    \"address\" : \"address\",    (string) The address base58check encoded\n" +
+		"    \"txid\" : \"hash\",          (string) The output txid\n" +
+		"    \"outputIndex\" : n,         (numeric) The output index\n" +
+		"    \"script\" : \"hex\",         (string) The scriptPubKey hex encoded\n" +
+		"    \"satoshis\" : n,            (numeric) The number of satoshis of the output\n" +
+		"    \"height\" : n               (numeric) The block height\n" +
+		"  }\n" +
+		"  ,...\n" +
+		"]\n" +
+		"\nExamples:\n" +
+		"> coperctl getaddressutxos '{\"addresses\": [\"12c6DSiU4Rq3P4ZxziKxzrL5LmMBrzjrJX\"]}'\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getaddressutxos", "params": [{"addresses": ["12c6DSiU4Rq3P4ZxziKxzrL5LmMBrzjrJX"]}] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
+	getaddressdeltasDesc = "getaddressdeltas {\"addresses\": [\"address\",...], \"start\": n, \"end\": n}\n" +
+		"\nReturns all changes of the balance of the addresses, in block order.\n" +
+		"\nRequires the address index (Chain.AddressIndex) to be enabled.\n" +
+		"\nArguments:\n" +
+		"{\n" +
+		"  \"addresses\" : [            (array, required) The base58check encoded addresses\n" +
+		"    \"address\"\n" +
+		"    ,...\n" +
+		"  ],\n" +
+		"  \"start\" : n,                (numeric, optional) The start block height\n" +
+		"  \"end\" : n                   (numeric, optional) The end block height\n" +
+		"}\n" +
+		"\nResult:\n" +
+		"[\n" +
+		"  {\n" +
+		"    \"satoshis\" : n,            (numeric) The difference of satoshis, " +
+		"negative when spending\n" +
+		"    \"txid\" : \"hash\",          (string) The related txid\n" +
+		"    \"index\" : n,               (numeric) The related input or output index\n" +
+		"    \"height\" : n,              (numeric) The block height\n" +
+		"    \"address\" : \"address\"     (string) The base58check encoded address\n" +
+		"  }\n" +
+		"  ,...\n" +
+		"]\n" +
+		"\nExamples:\n" +
+		"> coperctl getaddressdeltas '{\"addresses\": [\"12c6DSiU4Rq3P4ZxziKxzrL5LmMBrzjrJX\"]}'\n" +
+		`> curl --user myusername --data-binary '{"jsonrpc": "1.0", "id":"curltest", "method": "getaddressdeltas", "params": [{"addresses": ["12c6DSiU4Rq3P4ZxziKxzrL5LmMBrzjrJX"]}] }' -H 'content-type: text/plain;' http://127.0.0.1:8332/`
+
+	notifyblocksDesc = "notifyblocks\n" +
+		"\nSends a blockconnected or blockdisconnected notification to the " +
+		"websocket client whenever a block is connected to or disconnected " +
+		"from the active chain, with the hash, the height and the time of " +
+		"the block. Only available over the websocket endpoint /ws.\n" +
+		"\nResult:\n" +
+		"null\n" +
+		"\nExamples:\n" +
+		`{"jsonrpc": "1.0", "id": 1, "method": "notifyblocks", "params": []}`
+
+	stopnotifyblocksDesc = "stopnotifyblocks\n" +
+		"\nStops the notifications of notifyblocks. Only available over the " +
+		"websocket endpoint /ws.\n" +
+		"\nResult:\n" +
+		"null\n" +
+		"\nExamples:\n" +
+		`{"jsonrpc": "1.0", "id": 1, "method": "stopnotifyblocks", "params": []}`
+
+	notifynewtransactionsDesc = "notifynewtransactions ( verbose )\n" +
+		"\nSends a txaccepted notification to the websocket client, with " +
+		"the txid and the amount of the outputs, whenever a transaction is " +
+		"accepted to the mempool, or a txacceptedverbose notification with " +
+		"the decoded transaction when verbose is set. Only available over " +
+		"the websocket endpoint /ws.\n" +
+		"\nArguments:\n" +
+		"1. verbose   (boolean, optional, default=false) notify the decoded " +
+		"transactions\n" +
+		"\nResult:\n" +
+		"null\n" +
+		"\nExamples:\n" +
+		`{"jsonrpc": "1.0", "id": 1, "method": "notifynewtransactions", "params": [true]}`
+
+	stopnotifynewtransactionsDesc = "stopnotifynewtransactions\n" +
+		"\nStops the notifications of notifynewtransactions. Only available " +
+		"over the websocket endpoint /ws.\n" +
+		"\nResult:\n" +
+		"null\n" +
+		"\nExamples:\n" +
+		`{"jsonrpc": "1.0", "id": 1, "method": "stopnotifynewtransactions", "params": []}`
+
+	notifyspentDesc = "notifyspent [{\"hash\": \"txid\", \"index\": n},...]\n" +
+		"\nSends a redeemingtx notification to the websocket client, with " +
+		"the serialized transaction and the block it is mined in if any, " +
+		"whenever a transaction spending one of the outpoints enters the " +
+		"mempool or is mined. The outpoints are no longer watched once " +
+		"spent in a block. Only available over the websocket endpoint /ws.\n" +
+		"\nArguments:\n" +
+		"1. outpoints   (array, required) the outpoints to watch\n" +
+		"\nResult:\n" +
+		"null\n" +
+		"\nExamples:\n" +
+		`{"jsonrpc": "1.0", "id": 1, "method": "notifyspent", "params": [[{"hash": "txid", "index": 0}]]}`
+
+	stopnotifyspentDesc = "stopnotifyspent [{\"hash\": \"txid\", \"index\": n},...]\n" +
+		"\nStops watching the outpoints registered with notifyspent. Only " +
+		"available over the websocket endpoint /ws.\n" +
+		"\nArguments:\n" +
+		"1. outpoints   (array, required) the outpoints to stop watching\n" +
+		"\nResult:\n" +
+		"null\n" +
+		"\nExamples:\n" +
+		`{"jsonrpc": "1.0", "id": 1, "method": "stopnotifyspent", "params": [[{"hash": "txid", "index": 0}]]}`
+
+	notifyreceivedDesc = "notifyreceived [\"address\",...]\n" +
+		"\nSends a recvtx notification to the websocket client, with the " +
+		"serialized transaction and the block it is mined in if any, " +
+		"whenever a transaction paying to one of the addresses enters the " +
+		"mempool or is mined. The outputs paying to the addresses are " +
+		"watched as with notifyspent from then on. Only available over the " +
+		"websocket endpoint /ws.\n" +
+		"\nArguments:\n" +
+		"1. addresses   (array, required) the addresses to watch\n" +
+		"\nResult:\n" +
+		"null\n" +
+		"\nExamples:\n" +
+		`{"jsonrpc": "1.0", "id": 1, "method": "notifyreceived", "params": [["12c6DSiU4Rq3P4ZxziKxzrL5LmMBrzjrJX"]]}`
+
+	stopnotifyreceivedDesc = "stopnotifyreceived [\"address\",...]\n" +
+		"\nStops watching the addresses registered with notifyreceived. " +
+		"Only available over the websocket endpoint /ws.\n" +
+		"\nArguments:\n" +
+		"1. addresses   (array, required) the addresses to stop watching\n" +
+		"\nResult:\n" +
+		"null\n" +
+		"\nExamples:\n" +
+		`{"jsonrpc": "1.0", "id": 1, "method": "stopnotifyreceived", "params": [["12c6DSiU4Rq3P4ZxziKxzrL5LmMBrzjrJX"]]}`
 )
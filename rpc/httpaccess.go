@@ -0,0 +1,249 @@
+package rpc
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/net/banman"
+)
+
+const (
+	// corsAllowedHeaders are the request headers browsers may send to the
+	// server from another origin.
+	corsAllowedHeaders = "Authorization, Content-Type"
+
+	// corsMaxAge is the number of seconds browsers may cache the answer to
+	// a preflight request.
+	corsMaxAge = "600"
+
+	// maxRateLimitedClients is the number of clients whose request rate is
+	// tracked before the idle ones are forgotten.
+	maxRateLimitedClients = 10000
+)
+
+// httpAccess decides which HTTP clients may use the server: it finds the IP
+// of a client behind the trusted reverse proxies, checks it against the
+// allowed subnets and the request rate limit, and answers the CORS requests
+// of browsers on the allowed origins.
+type httpAccess struct {
+	allowedIPs     []*net.IPNet
+	trustedProxies []*net.IPNet
+	// corsOrigins are the allowed origins, all of them when it holds "*".
+	corsOrigins map[string]struct{}
+	limiter     *rateLimiter
+}
+
+// newHTTPAccess parses the access settings of the RPC configuration.
+func newHTTPAccess() (*httpAccess, error) {
+	allowedIPs, err := parseSubnets(conf.Cfg.RPC.RPCAllowIPs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RPCAllowIPs: %v", err)
+	}
+	trustedProxies, err := parseSubnets(conf.Cfg.RPC.RPCTrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RPCTrustedProxies: %v", err)
+	}
+	access := &httpAccess{
+		allowedIPs:     allowedIPs,
+		trustedProxies: trustedProxies,
+		corsOrigins:    make(map[string]struct{}, len(conf.Cfg.RPC.RPCCorsDomains)),
+	}
+	for _, origin := range conf.Cfg.RPC.RPCCorsDomains {
+		access.corsOrigins[strings.TrimRight(origin, "/")] = struct{}{}
+	}
+	if conf.Cfg.RPC.RPCRateLimit > 0 {
+		access.limiter = newRateLimiter(conf.Cfg.RPC.RPCRateLimit)
+	}
+	return access, nil
+}
+
+func parseSubnets(entries []string) ([]*net.IPNet, error) {
+	subnets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		subnet, err := banman.ParseSubnet(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %v", entry, err)
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets, nil
+}
+
+func containsIP(subnets []*net.IPNet, ip net.IP) bool {
+	for _, subnet := range subnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the IP of the client of the request.  Behind trusted
+// reverse proxies it is the last address of X-Forwarded-For which is not a
+// trusted proxy itself; the header is ignored on direct connections, which
+// could forge it.
+func (a *httpAccess) clientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !containsIP(a.trustedProxies, ip) {
+		return ip
+	}
+
+	var forwarded []string
+	for _, header := range r.Header["X-Forwarded-For"] {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !containsIP(a.trustedProxies, hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// clientAddr describes the client of the request for the logs.
+func (a *httpAccess) clientAddr(r *http.Request) string {
+	ip := a.clientIP(r)
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if ip == nil || err != nil || ip.Equal(net.ParseIP(host)) {
+		return r.RemoteAddr
+	}
+	return fmt.Sprintf("%s (via %s)", ip, r.RemoteAddr)
+}
+
+// allowed reports whether a client with the IP may use the server, all of
+// them being allowed when no subnet is configured.
+func (a *httpAccess) allowed(ip net.IP) bool {
+	return len(a.allowedIPs) == 0 || (ip != nil && containsIP(a.allowedIPs, ip))
+}
+
+// allowedOrigin reports whether browsers may call the server from a page of
+// origin.
+func (a *httpAccess) allowedOrigin(origin string) bool {
+	if _, ok := a.corsOrigins["*"]; ok {
+		return true
+	}
+	return a.listedOrigin(origin)
+}
+
+// listedOrigin reports whether the origin is allowed explicitly rather than
+// through "*", which is required for the requests carrying the credentials
+// of the browser.
+func (a *httpAccess) listedOrigin(origin string) bool {
+	_, ok := a.corsOrigins[origin]
+	return ok
+}
+
+// serveCORS sets the CORS headers of a request from a browser on an allowed
+// origin, returning whether the request was a preflight request, which is
+// then answered already.  Only the listed origins may send credentials, any
+// page a user visits could call the server with them otherwise.
+func (a *httpAccess) serveCORS(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || !a.allowedOrigin(origin) {
+		return false
+	}
+
+	header := w.Header()
+	if a.listedOrigin(origin) {
+		header.Add("Vary", "Origin")
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Set("Access-Control-Allow-Credentials", "true")
+	} else {
+		header.Set("Access-Control-Allow-Origin", "*")
+	}
+	if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+
+	header.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	header.Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+	header.Set("Access-Control-Max-Age", corsMaxAge)
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// rateLimited takes a token from the bucket of the client of the request,
+// returning true when it exceeds the rate limit.  The clients without an IP
+// are told apart by their remote address rather than sharing a bucket.
+func (a *httpAccess) rateLimited(r *http.Request, ip net.IP) bool {
+	if a.limiter == nil {
+		return false
+	}
+	client := r.RemoteAddr
+	if ip != nil {
+		client = ip.String()
+	}
+	return !a.limiter.allow(client)
+}
+
+// rateLimiter bounds the request rate of every client with a token bucket
+// holding a second worth of requests.
+type rateLimiter struct {
+	mtx     sync.Mutex
+	rate    float64
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate int) *rateLimiter {
+	return &rateLimiter{
+		rate:    float64(rate),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes a token from the bucket of the client, returning false when
+// it is empty.
+func (rl *rateLimiter) allow(client string) bool {
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+
+	now := time.Now()
+	bucket, ok := rl.buckets[client]
+	if !ok {
+		if len(rl.buckets) >= maxRateLimitedClients {
+			rl.forgetIdle(now)
+		}
+		bucket = &tokenBucket{tokens: rl.rate, last: now}
+		rl.buckets[client] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.last).Seconds() * rl.rate
+	if bucket.tokens > rl.rate {
+		bucket.tokens = rl.rate
+	}
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// forgetIdle drops the buckets which have refilled, their clients having
+// been idle for a second.  It must be called with the lock held.
+func (rl *rateLimiter) forgetIdle(now time.Time) {
+	for client, bucket := range rl.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*rl.rate >= rl.rate {
+			delete(rl.buckets, client)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package rpc
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/copernet/copernicus/conf"
+)
+
+func newTestHTTPAccess(t *testing.T, allowIPs, trustedProxies, corsDomains []string, rateLimit int) *httpAccess {
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	rpcCfg := conf.Cfg.RPC
+	defer func() { conf.Cfg.RPC = rpcCfg }()
+
+	conf.Cfg.RPC.RPCAllowIPs = allowIPs
+	conf.Cfg.RPC.RPCTrustedProxies = trustedProxies
+	conf.Cfg.RPC.RPCCorsDomains = corsDomains
+	conf.Cfg.RPC.RPCRateLimit = rateLimit
+	access, err := newHTTPAccess()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return access
+}
+
+func TestNewHTTPAccessInvalidSubnet(t *testing.T) {
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	rpcCfg := conf.Cfg.RPC
+	defer func() { conf.Cfg.RPC = rpcCfg }()
+
+	conf.Cfg.RPC.RPCAllowIPs = []string{"10.0.0.0/8", "nosuchsubnet"}
+	if _, err := newHTTPAccess(); err == nil {
+		t.Error("an invalid allowed subnet is accepted")
+	}
+	conf.Cfg.RPC.RPCAllowIPs = nil
+	conf.Cfg.RPC.RPCTrustedProxies = []string{"nosuchproxy"}
+	if _, err := newHTTPAccess(); err == nil {
+		t.Error("an invalid trusted proxy is accepted")
+	}
+}
+
+func TestHTTPAccessClientIP(t *testing.T) {
+	access := newTestHTTPAccess(t, []string{"192.168.0.0/16"}, []string{"10.0.0.1", "10.0.1.0/24"}, nil, 0)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		ip         string
+		addr       string
+		allowed    bool
+	}{
+		{"direct client", "192.168.1.2:1234", nil, "192.168.1.2", "192.168.1.2:1234", true},
+		{"forged header of a direct client", "172.16.0.1:1234", []string{"192.168.1.2"},
+			"172.16.0.1", "172.16.0.1:1234", false},
+		{"behind a proxy", "10.0.0.1:1234", []string{"192.168.1.2"},
+			"192.168.1.2", "192.168.1.2 (via 10.0.0.1:1234)", true},
+		{"behind a chain of proxies", "10.0.0.1:1234", []string{"172.16.0.1, 192.168.1.2", "10.0.1.5"},
+			"192.168.1.2", "192.168.1.2 (via 10.0.0.1:1234)", true},
+		{"invalid hop", "10.0.0.1:1234", []string{"192.168.1.2, nosuchip, 10.0.1.5"},
+			"10.0.1.5", "10.0.1.5 (via 10.0.0.1:1234)", false},
+		{"proxy without header", "10.0.0.1:1234", nil, "10.0.0.1", "10.0.0.1:1234", false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.RemoteAddr = test.remoteAddr
+		for _, header := range test.forwarded {
+			r.Header.Add("X-Forwarded-For", header)
+		}
+		ip := access.clientIP(r)
+		if ip.String() != test.ip {
+			t.Errorf("%s: got client %v, expect %s", test.name, ip, test.ip)
+		}
+		if addr := access.clientAddr(r); addr != test.addr {
+			t.Errorf("%s: got address %q, expect %q", test.name, addr, test.addr)
+		}
+		if allowed := access.allowed(ip); allowed != test.allowed {
+			t.Errorf("%s: allowed %t, expect %t", test.name, allowed, test.allowed)
+		}
+	}
+
+	if access := newTestHTTPAccess(t, nil, nil, nil, 0); !access.allowed(nil) {
+		t.Error("a client is refused without an allowed subnet")
+	}
+}
+
+func TestHTTPAccessServeCORS(t *testing.T) {
+	access := newTestHTTPAccess(t, nil, nil, []string{"https://wallet.example/"}, 0)
+
+	tests := []struct {
+		name      string
+		method    string
+		origin    string
+		preflight bool
+		allowed   bool
+	}{
+		{"same origin", "POST", "", false, false},
+		{"allowed origin", "POST", "https://wallet.example", false, true},
+		{"other origin", "POST", "https://evil.example", false, false},
+		{"preflight", "OPTIONS", "https://wallet.example", true, true},
+		{"preflight of another origin", "OPTIONS", "https://evil.example", false, false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, "/", nil)
+		if test.origin != "" {
+			r.Header.Set("Origin", test.origin)
+		}
+		if test.method == "OPTIONS" {
+			r.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		w := httptest.NewRecorder()
+		if preflight := access.serveCORS(w, r); preflight != test.preflight {
+			t.Errorf("%s: preflight %t, expect %t", test.name, preflight, test.preflight)
+		}
+		allowOrigin := w.Header().Get("Access-Control-Allow-Origin")
+		if (allowOrigin != "") != test.allowed || (test.allowed && allowOrigin != test.origin) {
+			t.Errorf("%s: got allowed origin %q", test.name, allowOrigin)
+		}
+		if credentials := w.Header().Get("Access-Control-Allow-Credentials"); (credentials == "true") != test.allowed {
+			t.Errorf("%s: got allowed credentials %q", test.name, credentials)
+		}
+		if test.preflight && (w.Code != http.StatusNoContent ||
+			w.Header().Get("Access-Control-Allow-Headers") != corsAllowedHeaders) {
+			t.Errorf("%s: preflight answered with %d and headers %v", test.name, w.Code, w.Header())
+		}
+	}
+
+	// any origin may call the server, but without the credentials of the
+	// browser unless it is listed
+	access = newTestHTTPAccess(t, nil, nil, []string{"*", "https://wallet.example"}, 0)
+	for origin, credentials := range map[string]string{"https://any.example": "", "https://wallet.example": "true"} {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		access.serveCORS(w, r)
+		allowOrigin := "*"
+		if credentials != "" {
+			allowOrigin = origin
+		}
+		if w.Header().Get("Access-Control-Allow-Origin") != allowOrigin ||
+			w.Header().Get("Access-Control-Allow-Credentials") != credentials {
+			t.Errorf("%s: got CORS headers %v", origin, w.Header())
+		}
+	}
+	if access.listedOrigin("https://any.example") {
+		t.Error("an origin allowed by \"*\" is reported as listed")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newTestHTTPAccess(t, nil, nil, nil, 2).limiter
+	if limiter == nil {
+		t.Fatal("no rate limiter with a rate limit")
+	}
+	if !limiter.allow("a") || !limiter.allow("a") {
+		t.Fatal("a request within the rate is refused")
+	}
+	if limiter.allow("a") {
+		t.Error("a request beyond the rate is allowed")
+	}
+	if !limiter.allow("b") {
+		t.Error("the rate of a client limits another one")
+	}
+
+	// the bucket refills with time, up to the rate
+	limiter.buckets["a"].last = time.Now().Add(-time.Hour)
+	if !limiter.allow("a") || !limiter.allow("a") || limiter.allow("a") {
+		t.Error("the bucket of an idle client does not refill to the rate")
+	}
+
+	limiter.buckets["b"].last = time.Now().Add(-time.Second)
+	limiter.forgetIdle(time.Now())
+	if _, ok := limiter.buckets["b"]; ok {
+		t.Error("the idle client is not forgotten")
+	}
+	if _, ok := limiter.buckets["a"]; !ok {
+		t.Error("the active client is forgotten")
+	}
+
+	// the clients without an IP don't share a bucket
+	access := newTestHTTPAccess(t, nil, nil, nil, 1)
+	for _, remoteAddr := range []string{"pipe-1", "pipe-2"} {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.RemoteAddr = remoteAddr
+		if access.rateLimited(r, nil) {
+			t.Errorf("the first request of %s is limited", remoteAddr)
+		}
+	}
+
+	if newTestHTTPAccess(t, nil, nil, nil, 0).limiter != nil {
+		t.Error("a rate limiter without a rate limit")
+	}
+}
+
+func TestServeClientAccess(t *testing.T) {
+	s := &Server{
+		access:    newTestHTTPAccess(t, []string{"192.168.0.0/16"}, nil, []string{"https://wallet.example"}, 1),
+		workQueue: newRPCWorkQueue(1, 1),
+	}
+	rpcCfg := conf.Cfg.RPC
+	defer func() { conf.Cfg.RPC = rpcCfg }()
+	conf.Cfg.RPC.RPCMaxClients = 10
+
+	tests := []struct {
+		name       string
+		method     string
+		remoteAddr string
+		status     int
+		served     bool
+	}{
+		{"address not allowed", "POST", "172.16.0.1:1234", http.StatusForbidden, false},
+		{"preflight", "OPTIONS", "192.168.1.2:1234", http.StatusNoContent, false},
+		{"allowed", "POST", "192.168.1.2:1234", http.StatusOK, true},
+		{"rate exceeded", "POST", "192.168.1.2:1234", http.StatusTooManyRequests, false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, "/", nil)
+		r.RemoteAddr = test.remoteAddr
+		r.Header.Set("Origin", "https://wallet.example")
+		r.Header.Set("Access-Control-Request-Method", "POST")
+		w := httptest.NewRecorder()
+		served := false
+		s.serveClient(w, r, false, func(bool) { served = true })
+		if w.Code != test.status || served != test.served {
+			t.Errorf("%s: status %d and served %t, expect %d and %t",
+				test.name, w.Code, served, test.status, test.served)
+		}
+	}
+}
+
+func TestSetupRPCPlainListeners(t *testing.T) {
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	rpcCfg, disableTLS := conf.Cfg.RPC, conf.Cfg.P2PNet.DisableTLS
+	defer func() {
+		conf.Cfg.RPC = rpcCfg
+		conf.Cfg.P2PNet.DisableTLS = disableTLS
+	}()
+
+	dir, err := ioutil.TempDir("", "rpclisteners")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	conf.Cfg.P2PNet.DisableTLS = false
+	conf.Cfg.RPC.RPCCert = filepath.Join(dir, "rpc.cert")
+	conf.Cfg.RPC.RPCKey = filepath.Join(dir, "rpc.key")
+	conf.Cfg.RPC.RPCListeners = []string{"127.0.0.1:0"}
+	conf.Cfg.RPC.RPCPlainListeners = []string{"127.0.0.1:0"}
+
+	listeners, err := SetupRPCListeners()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, listener := range listeners {
+		defer listener.Close()
+	}
+	if len(listeners) != 2 {
+		t.Fatalf("got %d listeners, expect 2", len(listeners))
+	}
+	if _, plain := listeners[0].(*net.TCPListener); plain {
+		t.Error("the RPC listener does not use TLS")
+	}
+	if _, plain := listeners[1].(*net.TCPListener); !plain {
+		t.Errorf("the plaintext listener is a %T", listeners[1])
+	}
+}
@@ -0,0 +1,35 @@
+package rpc
+
+import (
+	"bufio"
+	"net/http"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/mempool"
+)
+
+// rawMempoolTxsPath is the HTTP path streaming the raw transactions of the
+// mempool, in the format of MempoolDump.WriteRawTxs, for a restarting node to
+// warm its mempool with lmempool.ImportRawTxs.
+const rawMempoolTxsPath = "/mempool/rawtxs"
+
+// streamRawMempoolTxs writes the transactions of a snapshot of the mempool
+// as they are encoded, without holding the whole stream in memory.
+func (s *Server) streamRawMempoolTxs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "405 Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	d := mempool.GetInstance().Dump()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	bw := bufio.NewWriter(w)
+	err := d.WriteRawTxs(bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		// the status is already sent, the client sees a truncated stream
+		log.Warn("Failed to stream %d mempool transactions to %s: %v", len(d.Txs), r.RemoteAddr, err)
+	}
+}
@@ -4,12 +4,18 @@ import (
 	"bytes"
 	"encoding/hex"
 	"fmt"
+	"math"
 	"math/big"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
 
 	"errors"
+	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/log"
-	"github.com/copernet/copernicus/logic/lblock"
+	"github.com/copernet/copernicus/logic/lchain"
 	"github.com/copernet/copernicus/logic/lundo"
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/block"
@@ -21,22 +27,30 @@ import (
 	"github.com/copernet/copernicus/model/pow"
 	"github.com/copernet/copernicus/model/script"
 	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
 	"github.com/copernet/copernicus/model/versionbits"
+	"github.com/copernet/copernicus/persist"
 	"github.com/copernet/copernicus/rpc/btcjson"
 	"github.com/copernet/copernicus/service"
 	"github.com/copernet/copernicus/service/mining"
 	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
 	"gopkg.in/fatih/set.v0"
 )
 
 var miningHandlers = map[string]commandHandler{
 	"getnetworkhashps":  handleGetNetWorkhashPS,
 	"getmininginfo":     handleGetMiningInfo,
+	"getgenerate":       handleGetGenerate,
+	"setgenerate":       handleSetGenerate,
 	"getblocktemplate":  handleGetblocktemplate,
 	"submitblock":       handleSubmitBlock,
+	"submitheader":      handleSubmitHeader,
 	"generatetoaddress": handleGenerateToAddress,
 	"generate":          handleGenerate,
 	"estimatefee":       handleEstimateFee,
+	"estimatesmartfee":  handleEstimateSmartFee,
 }
 
 func handleGetNetWorkhashPS(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
@@ -52,13 +66,20 @@ func handleGetNetWorkhashPS(s *Server, cmd interface{}, closeChan <-chan struct{
 		height = *c.Height
 	}
 
+	return networkHashPS(lookup, height), nil
+}
+
+// networkHashPS estimates the hashes per second of the network from the work
+// and timestamps of the lookup blocks up to height, the tip when negative. A
+// lookup not above zero means since the last difficulty change.
+func networkHashPS(lookup int, height int32) float64 {
 	index := chain.GetInstance().Tip()
-	if height > 0 && height < chain.GetInstance().Height() {
+	if height >= 0 && height < chain.GetInstance().Height() {
 		index = chain.GetInstance().GetIndex(height)
 	}
 
 	if index == nil || index.Height == 0 {
-		return 0, nil
+		return 0
 	}
 
 	if lookup <= 0 {
@@ -80,46 +101,87 @@ func handleGetNetWorkhashPS(s *Server, cmd interface{}, closeChan <-chan struct{
 	}
 
 	if minTime == maxTime {
-		return 0, nil
+		return 0
 	}
 
-	workDiff := new(big.Int).Sub(&index.ChainWork, &b.ChainWork)
-	timeDiff := int64(maxTime - minTime)
-
-	hashesPerSec := new(big.Int).Div(workDiff, big.NewInt(timeDiff))
-	return hashesPerSec, nil
+	workDiff, _ := new(big.Float).SetInt(new(big.Int).Sub(&index.ChainWork, &b.ChainWork)).Float64()
+	return workDiff / float64(maxTime-minTime)
 }
 
 func handleGetMiningInfo(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	gnhpsCmd := btcjson.NewGetNetworkHashPSCmd(nil, nil)
-	networkHashesPerSecIface, err := handleGetNetWorkhashPS(s, gnhpsCmd,
-		closeChan)
-	if err != nil {
-		return nil, err
-	}
-	networkHashesPerSec := fmt.Sprintf("%v", networkHashesPerSecIface)
-
 	index := chain.GetInstance().Tip()
+	generate, genProcLimit := miner.generating()
+	warnings := lchain.GetWarnings()
 	result := &btcjson.GetMiningInfoResult{
 		Blocks:                  int64(index.Height),
 		CurrentBlockSize:        mining.GetLastBlockSize(),
 		CurrentBlockTx:          mining.GetLastBlockTx(),
+		CurrentBlockFees:        mining.GetLastBlockFees().ToBTC(),
 		Difficulty:              getDifficulty(index),
 		BlockPriorityPercentage: tx.DefaultBlockPriorityPercentage, // NOT support this parameter yet
-		Errors:                  "",                                // NOT sure if errors are logged
-		NetworkHashPS:           networkHashesPerSec,
+		Errors:                  warnings,
+		Warnings:                warnings,
+		NetworkHashPS:           networkHashPS(120, -1),
 		PooledTx:                uint64(mempool.GetInstance().Size()),
 		Chain:                   chain.GetInstance().GetParams().Name,
+		Generate:                generate,
+		GenProcLimit:            genProcLimit,
 	}
 	return result, nil
 }
 
-// global variable in package rpc
-var (
+// handleGetGenerate implements the getgenerate command.
+func handleGetGenerate(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	generate, _ := miner.generating()
+	return generate, nil
+}
+
+// handleSetGenerate implements the setgenerate command, which starts or stops
+// the CPU miner. A negative genproclimit uses one worker per CPU.
+func handleSetGenerate(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.SetGenerateCmd)
+
+	workers := 0
+	if c.Generate {
+		workers = *c.GenProcLimit
+		if workers < 0 {
+			workers = runtime.NumCPU()
+		}
+	}
+
+	var coinbaseScript *script.Script
+	if workers > 0 {
+		var err error
+		if coinbaseScript, err = miningAddrScript(); err != nil {
+			return nil, err
+		}
+	}
+	miner.setWorkers(workers, coinbaseScript)
+	return nil, nil
+}
+
+// gbtState caches the block template getblocktemplate hands out, so that
+// miners polling it get the same template until the tip changes or, at most
+// every gbtRegenerateSeconds, the mempool does.
+var gbtState struct {
+	sync.Mutex
 	transactionsUpdatedLast uint64
 	indexPrev               *blockindex.BlockIndex
 	start                   int64
-	blocktemplate           *mining.BlockTemplate
+	template                *mining.BlockTemplate
+}
+
+const (
+	// gbtRegenerateSeconds is how long a template is served while only the
+	// mempool changed.
+	gbtRegenerateSeconds = 5
+
+	// gbtLongPollCheckInterval is how often a long poll checks for a new tip.
+	gbtLongPollCheckInterval = 250 * time.Millisecond
+
+	// gbtLongPollMempoolWait is how long a long poll waits before a mempool
+	// change is enough to answer it.
+	gbtLongPollMempoolWait = time.Minute
 )
 
 // See https://en.bitcoin.it/wiki/BIP_0022 and
@@ -150,14 +212,24 @@ func handleGetblocktemplate(s *Server, cmd interface{}, closeChan <-chan struct{
 func handleGetBlockTemplateRequest(request *btcjson.TemplateRequest, closeChan <-chan struct{}) (interface{}, error) {
 	maxVersionVb := int64(-1)
 	setClientRules := set.New()
-	//if len(request.Rules) > 0 { // todo check
-	//	for _, str := range request.Rules {
-	//		setClientRules.Add(str)
-	//	}
-	//} else {
-	//	// NOTE: It is important that this NOT be read if versionbits is supported
-	//	maxVersionVb = int64(request.MaxVersion)
-	//}
+	var coinbaseScript *script.Script
+	if request != nil {
+		if len(request.Rules) > 0 {
+			for _, str := range request.Rules {
+				setClientRules.Add(str)
+			}
+		} else {
+			// NOTE: It is important that this NOT be read if versionbits is supported
+			maxVersionVb = int64(request.MaxVersion)
+		}
+
+		if useCoinbaseTxn(request.Capabilities) {
+			var err error
+			if coinbaseScript, err = miningAddrScript(); err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	if lundo.IsInitialBlockDownload() {
 		return nil, &btcjson.RPCError{
@@ -169,28 +241,31 @@ func handleGetBlockTemplateRequest(request *btcjson.TemplateRequest, closeChan <
 	if request != nil && request.LongPollID != "" {
 		// Wait to respond until either the best block changes, OR a minute has
 		// passed and there are more transactions
-		//var hashWatchedChain utils.Hash
-		//checktxtime := time.Now()
-		//transactionsUpdatedLastLP := 0
-		// todo complete
+		if err := waitForLongPoll(request.LongPollID, closeChan); err != nil {
+			return nil, err
+		}
 	}
 
-	if indexPrev != chain.GetInstance().Tip() ||
-		mempool.GetInstance().TransactionsUpdated != transactionsUpdatedLast &&
-			util.GetTime()-start > 5 {
+	gbtState.Lock()
+	defer gbtState.Unlock()
+
+	pool := mempool.GetInstance()
+	if gbtState.indexPrev != chain.GetInstance().Tip() ||
+		pool.TransactionsUpdated != gbtState.transactionsUpdatedLast &&
+			util.GetTime()-gbtState.start > gbtRegenerateSeconds {
 
 		// Clear pindexPrev so future calls make a new block, despite any
 		// failures from here on
-		indexPrev = nil
+		gbtState.indexPrev = nil
 		// Store the pindexBest used before CreateNewBlock, to avoid races
-		transactionsUpdatedLast = mempool.GetInstance().TransactionsUpdated
+		gbtState.transactionsUpdatedLast = pool.TransactionsUpdated
 		indexPrevNew := chain.GetInstance().Tip()
-		start = util.GetTime()
+		gbtState.start = util.GetTime()
 
 		// Create new block
 		ba := mining.NewBlockAssembler(model.ActiveNetParams)
-		blocktemplate = ba.CreateNewBlock(script.NewScriptRaw([]byte{opcodes.OP_TRUE}))
-		if blocktemplate == nil {
+		gbtState.template = ba.CreateNewBlock(script.NewScriptRaw([]byte{opcodes.OP_TRUE}))
+		if gbtState.template == nil {
 			return nil, &btcjson.RPCError{
 				Code:    btcjson.ErrUnDefined,
 				Message: "Out of memory",
@@ -198,21 +273,108 @@ func handleGetBlockTemplateRequest(request *btcjson.TemplateRequest, closeChan <
 		}
 
 		// Need to update only after we know CreateNewBlock succeeded
-		indexPrev = indexPrevNew
+		gbtState.indexPrev = indexPrevNew
 	}
-	bk := blocktemplate.Block
-	mining.UpdateTime(bk, indexPrev)
+	bk := gbtState.template.Block
+	mining.UpdateTime(bk, gbtState.indexPrev)
 	bk.Header.Nonce = 0
 
-	return blockTemplateResult(blocktemplate, setClientRules, uint32(maxVersionVb), transactionsUpdatedLast)
+	return blockTemplateResult(gbtState.template, gbtState.indexPrev, setClientRules, maxVersionVb,
+		gbtState.transactionsUpdatedLast, coinbaseScript)
+}
+
+// useCoinbaseTxn reports whether the client capabilities ask for a coinbase
+// transaction rather than a coinbase value. The value is preferred when the
+// client supports both, as it lets the miner build its own coinbase.
+func useCoinbaseTxn(capabilities []string) bool {
+	coinbaseTxn := false
+	for _, capability := range capabilities {
+		switch capability {
+		case "coinbasevalue":
+			return false
+		case "coinbasetxn":
+			coinbaseTxn = true
+		}
+	}
+	return coinbaseTxn
+}
+
+// miningAddrScript returns the script paying to the configured mining
+// address, which the coinbase transactions of the templates pay to.
+func miningAddrScript() (*script.Script, error) {
+	if conf.Cfg.Mining.MiningAddr == "" {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInternal.Code,
+			Message: "A coinbase transaction has been requested, but the " +
+				"server has not been configured with a payment address " +
+				"via MiningAddr",
+		}
+	}
+	addr, err := script.AddressFromString(conf.Cfg.Mining.MiningAddr)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInternal.Code,
+			Message: "Invalid MiningAddr: " + err.Error(),
+		}
+	}
+	return script.PayToAddrScript(addr)
+}
+
+// waitForLongPoll blocks until the tip differs from the one longPollID was
+// issued for or, once gbtLongPollMempoolWait passed, the mempool changed since
+// then. A long poll id is the hash of the tip followed by the mempool update
+// counter. An id not issued by this server waits for the current tip to
+// change.
+func waitForLongPoll(longPollID string, closeChan <-chan struct{}) error {
+	var watchedTip util.Hash
+	transactionsUpdatedLastLP := mempool.GetInstance().TransactionsUpdated
+	if tip := chain.GetInstance().Tip(); tip != nil {
+		watchedTip = *tip.GetBlockHash()
+	}
+	if len(longPollID) > 64 {
+		hash, errHash := util.GetHashFromStr(longPollID[:64])
+		updated, errUpdated := strconv.ParseUint(longPollID[64:], 10, 64)
+		if errHash == nil && errUpdated == nil {
+			watchedTip = *hash
+			transactionsUpdatedLastLP = updated
+		}
+	}
+
+	ticker := time.NewTicker(gbtLongPollCheckInterval)
+	defer ticker.Stop()
+	checkTxTime := time.Now().Add(gbtLongPollMempoolWait)
+	for {
+		if tip := chain.GetInstance().Tip(); tip != nil && *tip.GetBlockHash() != watchedTip {
+			return nil
+		}
+		if !time.Now().Before(checkTxTime) {
+			if mempool.GetInstance().TransactionsUpdated != transactionsUpdatedLastLP {
+				return nil
+			}
+			checkTxTime = time.Now().Add(gbtLongPollMempoolWait)
+		}
+
+		select {
+		case <-closeChan:
+			return &btcjson.RPCError{
+				Code:    btcjson.ErrRPCClientNotConnected,
+				Message: "Client disconnected while waiting for a new template",
+			}
+		case <-ticker.C:
+		}
+	}
 }
 
 // blockTemplateResult returns the current block template associated with the
 // state as a btcjson.GetBlockTemplateResult that is ready to be encoded to JSON
-// and returned to the caller.
+// and returned to the caller. When coinbaseScript is not nil, the result holds
+// a coinbase transaction paying to it instead of the coinbase value.
 //
 // This function MUST be called with the state locked.
-func blockTemplateResult(bt *mining.BlockTemplate, s *set.Set, maxVersionVb uint32, transactionsUpdatedLast uint64) (*btcjson.GetBlockTemplateResult, error) {
+func blockTemplateResult(bt *mining.BlockTemplate, indexPrev *blockindex.BlockIndex, s *set.Set,
+	maxVersionVb int64, transactionsUpdatedLast uint64,
+	coinbaseScript *script.Script) (*btcjson.GetBlockTemplateResult, error) {
+
 	setTxIndex := make(map[util.Hash]int)
 	var i int
 	transactions := make([]btcjson.GetBlockTemplateResultTx, 0, len(bt.Block.Txs))
@@ -225,18 +387,10 @@ func blockTemplateResult(bt *mining.BlockTemplate, s *set.Set, maxVersionVb uint
 			continue
 		}
 
-		entry := btcjson.GetBlockTemplateResultTx{}
-
-		dataBuf := bytes.NewBuffer(nil)
-		err := tx.Serialize(dataBuf)
+		entry, err := templateTxResult(tx, bt.TxFees[i-1], bt.TxSigOpsCount[i-1])
 		if err != nil {
-			log.Error("mining:serialize tx failed.")
 			return nil, err
 		}
-		entry.Data = hex.EncodeToString(dataBuf.Bytes())
-
-		entry.TxID = txID.String()
-		entry.Hash = txID.String()
 
 		deps := make([]int, 0)
 		for _, in := range tx.GetIns() {
@@ -246,13 +400,8 @@ func blockTemplateResult(bt *mining.BlockTemplate, s *set.Set, maxVersionVb uint
 		}
 		entry.Depends = deps
 
-		indexInTemplate := i - 1
-		entry.Fee = int64(blocktemplate.TxFees[indexInTemplate])
-		entry.SigOps = int64(blocktemplate.TxSigOpsCount[indexInTemplate])
-
-		transactions = append(transactions, entry)
+		transactions = append(transactions, *entry)
 	}
-
 	vbAvailable := make(map[string]int)
 	rules := make([]string, 0)
 	for i := 0; i < int(consensus.MaxVersionBitsDeployments); i++ {
@@ -309,30 +458,68 @@ func blockTemplateResult(bt *mining.BlockTemplate, s *set.Set, maxVersionVb uint
 		// that this can probably also be removed entirely after the first BIP9
 		// non-force deployment (ie, probably segwit) gets activated.
 		mutable = append(mutable, "version/force")
+
+	}
+
+	maxBlockSize := mining.GetBlockSize()
+	result := &btcjson.GetBlockTemplateResult{
+		Capabilities: []string{"proposal"},
+		Version:      bt.Block.Header.Version,
+		Rules:        rules,
+		VbAvailable:  vbAvailable,
+		VbRequired:   0,
+		PreviousHash: util.BlockHash(bt.Block.Header.HashPrevBlock).String(),
+		Transactions: transactions,
+		CoinbaseAux:  &btcjson.GetBlockTemplateResultAux{Flags: mining.CoinbaseFlag},
+		LongPollID:   indexPrev.GetBlockHash().String() + fmt.Sprintf("%d", transactionsUpdatedLast),
+		Target:       pow.CompactToBig(bt.Block.Header.Bits).String(),
+		MinTime:      indexPrev.GetMedianTimePast() + 1,
+		Mutable:      mutable,
+		NonceRange:   "00000000ffffffff",
+		SigOpLimit:   int64(consensus.GetMaxBlockSigOpsCount(maxBlockSize)),
+		SizeLimit:    int64(maxBlockSize),
+		CurTime:      int64(bt.Block.Header.Time),
+		Bits:         fmt.Sprintf("%08x", bt.Block.Header.Bits),
+		Height:       int64(indexPrev.Height) + 1,
+	}
+
+	coinbase := bt.Block.Txs[0]
+	if coinbaseScript == nil {
+		v := int64(coinbase.GetTxOut(0).GetValue())
+		result.CoinbaseValue = &v
+		return result, nil
 	}
 
-	v := bt.Block.Txs[0].GetTxOut(0).GetValue()
-	return &btcjson.GetBlockTemplateResult{
-		Capabilities:  []string{"proposal"},
-		Version:       bt.Block.Header.Version,
-		Rules:         rules,
-		VbAvailable:   vbAvailable,
-		VbRequired:    0,
-		PreviousHash:  bt.Block.Header.HashPrevBlock.String(),
-		Transactions:  transactions,
-		CoinbaseAux:   &btcjson.GetBlockTemplateResultAux{Flags: mining.CoinbaseFlag},
-		CoinbaseValue: (*int64)(&v),
-		LongPollID:    chain.GetInstance().Tip().GetBlockHash().String() + fmt.Sprintf("%d", transactionsUpdatedLast),
-		Target:        pow.CompactToBig(bt.Block.Header.Bits).String(),
-		MinTime:       indexPrev.GetMedianTimePast() + 1,
-		Mutable:       mutable,
-		NonceRange:    "00000000ffffffff",
-		// FIXME: Allow for mining block greater than 1M.
-		SigOpLimit: int64(consensus.GetMaxBlockSigOpsCount(consensus.DefaultMaxBlockSize)),
-		SizeLimit:  consensus.DefaultMaxBlockSize,
-		CurTime:    int64(bt.Block.Header.Time),
-		Bits:       fmt.Sprintf("%08x", bt.Block.Header.Bits),
-		Height:     int64(indexPrev.Height) + 1,
+	// Pay the template coinbase to the mining address.
+	in := coinbase.GetIns()[0]
+	coinbaseTx := tx.NewTx(coinbase.GetLockTime(), coinbase.GetVersion())
+	coinbaseTx.AddTxIn(txin.NewTxIn(in.PreviousOutPoint, in.GetScriptSig(), in.Sequence))
+	coinbaseTx.AddTxOut(txout.NewTxOut(coinbase.GetTxOut(0).GetValue(), coinbaseScript))
+	entry, err := templateTxResult(coinbaseTx, bt.TxFees[0], bt.TxSigOpsCount[0])
+	if err != nil {
+		return nil, err
+	}
+	entry.Depends = []int{}
+	result.CoinbaseTxn = entry
+	result.Mutable = append(result.Mutable, "coinbase/append")
+	return result, nil
+}
+
+// templateTxResult returns the fields of a transaction of a block template
+// except its dependencies.
+func templateTxResult(transaction *tx.Tx, fee amount.Amount, sigOps int) (*btcjson.GetBlockTemplateResultTx, error) {
+	dataBuf := bytes.NewBuffer(nil)
+	if err := transaction.Serialize(dataBuf); err != nil {
+		log.Error("mining:serialize tx failed.")
+		return nil, err
+	}
+	txID := util.TxID(transaction.GetHash()).String()
+	return &btcjson.GetBlockTemplateResultTx{
+		Data:   hex.EncodeToString(dataBuf.Bytes()),
+		TxID:   txID,
+		Hash:   txID,
+		Fee:    int64(fee),
+		SigOps: int64(sigOps),
 	}, nil
 }
 
@@ -401,6 +588,8 @@ func handleGetBlockTemplateProposal(request *btcjson.TemplateRequest) (interface
 		}
 	}
 
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
 	indexPrev := chain.GetInstance().Tip()
 	// TestBlockValidity only supports blocks built on the current Tip
 	if bk.Header.HashPrevBlock != *indexPrev.GetBlockHash() {
@@ -410,7 +599,7 @@ func handleGetBlockTemplateProposal(request *btcjson.TemplateRequest) (interface
 		}
 	}
 
-	err = lblock.CheckBlock(&bk)
+	err = lchain.TestBlockValidity(&bk, indexPrev)
 	return BIP22ValidationResult(err)
 }
 
@@ -473,52 +662,113 @@ func handleSubmitBlock(s *Server, cmd interface{}, closeChan <-chan struct{}) (i
 			Message: "Block decode failed: " + err.Error(),
 		}
 	}
+	if len(bk.Txs) == 0 || !bk.Txs[0].IsCoinBase() {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDeserialization,
+			Message: "Block does not start with a coinbase",
+		}
+	}
+
+	// Report blocks we already know about with the BIP22 status strings
+	// instead of an error, pool software relies on them.
 	hash := bk.GetHash()
 	ch := chain.GetInstance()
-	blkIdx := ch.FindBlockIndex(hash)
-	if blkIdx != nil {
+	if blkIdx := ch.FindBlockIndex(hash); blkIdx != nil {
 		if blkIdx.IsValid(blockindex.BlockValidScripts) {
-			return nil, &btcjson.RPCError{
-				Code:    btcjson.RPCTransactionAlreadyInChain,
-				Message: "duplicate",
-			}
+			return "duplicate", nil
 		}
-
-		if (blkIdx.Status & blockindex.BlockInvalidMask) < 0 {
-			return nil, &btcjson.RPCError{
-				Code:    btcjson.RPCTransactionError,
-				Message: "duplicate-invalid",
-			}
+		if blkIdx.IsInvalid() {
+			return "duplicate-invalid", nil
 		}
 	}
 
 	// Process this block using the same rules as blocks coming from other
 	// nodes.  This will in turn relay it to the network like normal.
-	_, err = service.ProcessBlock(bk)
+	isNewBlock, err := service.ProcessBlock(bk)
 	if err != nil {
+		// report the reject reason of a validation error, e.g. time-too-new
+		if pe, ok := err.(errcode.ProjectError); ok {
+			return fmt.Sprintf("rejected: %s", pe.Desc), nil
+		}
 		return fmt.Sprintf("rejected: %s", err.Error()), nil
 	}
+	if !isNewBlock {
+		return "duplicate", nil
+	}
 
-	log.Info("Accepted block %s via submitblock", bk.Header.GetHash())
+	// The block was stored but not connected, e.g. it is on a side chain
+	// with less work, so its scripts have not been checked yet.
+	if blkIdx := ch.FindBlockIndex(hash); blkIdx == nil ||
+		!blkIdx.IsValid(blockindex.BlockValidScripts) {
+		return "inconclusive", nil
+	}
+
+	log.Info("Accepted block %s via submitblock", util.BlockHash(bk.Header.GetHash()))
 	return nil, nil
 }
 
+// handleSubmitHeader validates a standalone header and adds it to the block
+// index, as if it was received from a peer. The parent must be known.
+func handleSubmitHeader(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.SubmitHeaderCmd)
+
+	serializedHeader, err := hex.DecodeString(c.HexData)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.HexData)
+	}
+	header := block.NewBlockHeader()
+	if err := header.UnserializeHeader(bytes.NewBuffer(serializedHeader)); err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDeserialization,
+			Message: "Block header decode failed",
+		}
+	}
+
+	if chain.GetInstance().FindBlockIndex(header.HashPrevBlock) == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCVerify,
+			Message: fmt.Sprintf("Must submit previous header (%s) first", header.HashPrevBlock.String()),
+		}
+	}
+
+	var lastIndex blockindex.BlockIndex
+	if err := service.ProcessBlockHeader([]*block.BlockHeader{header}, &lastIndex); err != nil {
+		message := err.Error()
+		if pe, ok := err.(errcode.ProjectError); ok {
+			message = pe.Desc
+		}
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCVerify,
+			Message: message,
+		}
+	}
+	return nil, nil
+}
+
+// handleGenerateToAddress handles generatetoaddress commands.
 func handleGenerateToAddress(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GenerateToAddressCmd)
 
 	addr, err := script.AddressFromString(c.Address)
 	if err != nil {
-		return nil, btcjson.RPCError{
+		return nil, &btcjson.RPCError{
 			Code:    btcjson.RPCInvalidAddressOrKey,
 			Message: "Error: Invalid address",
 		}
 	}
 
-	coinbaseScript := script.NewScriptRaw(addr.EncodeToPubKeyHash())
-	return generateBlocks(coinbaseScript, int(c.NumBlocks), c.MaxTries)
+	coinbaseScript, err := script.PayToAddrScript(addr)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.RPCInvalidAddressOrKey,
+			Message: "Error: Invalid address",
+		}
+	}
+	return generateBlocks(coinbaseScript, int(c.NumBlocks), *c.MaxTries, closeChan)
 }
 
-// handleGenerate handles generate commands.
+// handleGenerate handles generate commands. There is no wallet, the blocks
+// pay to the configured mining address.
 func handleGenerate(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GenerateCmd)
 
@@ -530,65 +780,72 @@ func handleGenerate(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 		}
 	}
 
-	coinbaseScript := script.NewScriptRaw(nil)
-	if coinbaseScript == nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.RPCInternalError,
-			Message: "No coinbase script available (mining requires a wallet)",
-		}
+	coinbaseScript, err := miningAddrScript()
+	if err != nil {
+		return nil, err
 	}
-
-	return generateBlocks(coinbaseScript, int(c.NumBlocks), c.MaxTries)
+	return generateBlocks(coinbaseScript, int(c.NumBlocks), *c.MaxTries, closeChan)
 }
 
+// nInnerLoopCount is the number of nonces tried on a template before a new
+// one is built with a fresh time and extra nonce.
 const nInnerLoopCount = 0x100000
 
-func generateBlocks(coinbaseScript *script.Script, generate int, maxTries uint64) (interface{}, error) {
-	heightStart := chain.GetInstance().Height()
-	heightEnd := heightStart + int32(generate)
-	height := heightStart
+// generateBlocks mines blocks paying to coinbaseScript on top of the active
+// chain and returns their hashes. It stops early once maxTries nonces were
+// tried, so it returns less than generate hashes on networks with a real
+// difficulty.
+func generateBlocks(coinbaseScript *script.Script, generate int, maxTries uint64,
+	closeChan <-chan struct{}) ([]string, error) {
+
 	params := model.ActiveNetParams
+	powCheck := pow.Pow{}
+
+	ret := make([]string, 0, generate)
+	for len(ret) < generate && maxTries > 0 {
+		select {
+		case <-closeChan:
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCClientNotConnected,
+				Message: "Client disconnected",
+			}
+		default:
+		}
 
-	ret := make([]string, 0)
-	var extraNonce uint
-	for height < heightEnd {
 		ba := mining.NewBlockAssembler(params)
 		bt := ba.CreateNewBlock(coinbaseScript)
 		if bt == nil {
-			return nil, btcjson.RPCError{
+			return nil, &btcjson.RPCError{
 				Code:    btcjson.RPCInternalError,
 				Message: "Could not create new block",
 			}
 		}
+		indexPrev := chain.GetInstance().FindBlockIndex(bt.Block.Header.HashPrevBlock)
+		mining.IncrementExtraNonce(bt.Block, indexPrev)
 
-		extraNonce = mining.IncrementExtraNonce(bt.Block, chain.GetInstance().Tip())
-
-		powCheck := pow.Pow{}
-		hash := bt.Block.GetHash()
-		bits := bt.Block.Header.Bits
-		for maxTries > 0 && bt.Block.Header.Nonce < nInnerLoopCount && !powCheck.CheckProofOfWork(&hash, bits, params) {
-			bt.Block.Header.Nonce++
+		header := &bt.Block.Header
+		found := false
+		for ; maxTries > 0 && header.Nonce < nInnerLoopCount; header.Nonce++ {
 			maxTries--
+			header.Hash = util.Hash{}
+			hash := header.GetHash()
+			if powCheck.CheckProofOfWork(&hash, header.Bits, params) {
+				found = true
+				break
+			}
 		}
-
-		if maxTries == 0 {
-			break
-		}
-		if bt.Block.Header.Nonce == nInnerLoopCount {
+		if !found {
 			continue
 		}
 
-		if service.ProcessNewBlock(bt.Block, true, nil) != nil {
-			return nil, btcjson.RPCError{
+		if err := service.ProcessNewBlock(bt.Block, true, nil); err != nil {
+			return nil, &btcjson.RPCError{
 				Code:    btcjson.RPCInternalError,
-				Message: "ProcessNewBlock, block not accepted",
+				Message: "ProcessNewBlock, block not accepted: " + err.Error(),
 			}
 		}
-		height++
-		blkHash := bt.Block.GetHash()
-		ret = append(ret, blkHash.String())
+		ret = append(ret, util.BlockHash(header.GetHash()).String())
 	}
-	_ = extraNonce
 
 	return ret, nil
 }
@@ -615,8 +872,34 @@ func handleEstimateFee(s *Server, cmd interface{}, closeChan <-chan struct{}) (i
 	return float64(feeRate), nil
 }
 
+// handleEstimateSmartFee handles estimatesmartfee commands.
+func handleEstimateSmartFee(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.EstimateSmartFeeCmd)
+
+	if s.cfg.FeeEstimator == nil {
+		return nil, errors.New("Fee estimation disabled")
+	}
+
+	if c.ConfTarget <= 0 || c.ConfTarget > math.MaxUint32 {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Invalid conf_target")
+	}
+
+	feeRate, blocks, err := s.cfg.FeeEstimator.EstimateSmartFee(uint32(c.ConfTarget))
+	if err != nil {
+		return &btcjson.EstimateSmartFeeResult{Errors: []string{err.Error()}}, nil
+	}
+
+	// Never advise a fee rate below the one the mempool requires.
+	minFeeRate := mempool.GetInstance().GetMinFeeRate()
+	rate := math.Max(float64(feeRate), amount.Amount(minFeeRate.GetFeePerK()).ToBTC())
+	return &btcjson.EstimateSmartFeeResult{
+		FeeRate: &rate,
+		Blocks:  int64(blocks),
+	}, nil
+}
+
 func registerMiningRPCCommands() {
 	for name, handler := range miningHandlers {
-		appendCommand(name, handler)
+		appendCommand("mining", name, handler)
 	}
 }
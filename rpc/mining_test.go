@@ -0,0 +1,179 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/versionbits"
+	"github.com/copernet/copernicus/persist"
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+)
+
+func TestSubmitBlockKnown(t *testing.T) {
+	path, err := ioutil.TempDir("", "submitblock")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	_, tip := initWalkedCoins(t, path)
+	persist.InitPersistGlobal()
+
+	newBlock := func(time uint32, coinbase bool) (*block.Block, string) {
+		transaction := tx.NewTx(0, tx.TxVersion)
+		prevOut := outpoint.NewOutPoint(util.HashOne, 0)
+		if coinbase {
+			prevOut = outpoint.NewOutPoint(util.Hash{}, math.MaxUint32)
+		}
+		transaction.AddTxIn(txin.NewTxIn(prevOut, script.NewScriptRaw([]byte{opcodes.OP_1, opcodes.OP_1}),
+			script.SequenceFinal))
+		transaction.AddTxOut(txout.NewTxOut(10000, script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+		blk := block.NewBlock()
+		blk.Header = block.BlockHeader{HashPrevBlock: *tip.GetBlockHash(), Time: time, Bits: 0x207fffff}
+		blk.Txs = []*tx.Tx{transaction}
+		buf := new(bytes.Buffer)
+		if err := blk.Serialize(buf); err != nil {
+			t.Fatal(err)
+		}
+		return blk, hex.EncodeToString(buf.Bytes())
+	}
+	// blocks already known, with their scripts checked or found invalid
+	addIndex := func(blk *block.Block, status uint32) {
+		index := blockindex.NewBlockIndex(&blk.Header)
+		index.Height = tip.Height + 1
+		index.Prev = tip
+		index.AddStatus(status)
+		if err := chain.GetInstance().AddToIndexMap(index); err != nil {
+			t.Fatal(err)
+		}
+	}
+	valid, validHex := newBlock(tip.Header.Time+600, true)
+	addIndex(valid, blockindex.BlockValidScripts)
+	invalid, invalidHex := newBlock(tip.Header.Time+601, true)
+	addIndex(invalid, blockindex.BlockFailed)
+	_, noCoinbaseHex := newBlock(tip.Header.Time+602, false)
+
+	tests := []struct {
+		name    string
+		hex     string
+		status  interface{}
+		errCode btcjson.RPCErrorCode
+	}{
+		{"not hex", "zz", nil, btcjson.ErrRPCDecodeHexString},
+		{"truncated", validHex[:len(validHex)-2], nil, btcjson.ErrRPCDeserialization},
+		{"no coinbase", noCoinbaseHex, nil, btcjson.ErrRPCDeserialization},
+		{"known valid block", validHex, "duplicate", 0},
+		{"known invalid block", invalidHex, "duplicate-invalid", 0},
+	}
+	for _, test := range tests {
+		status, err := handleSubmitBlock(nil, &btcjson.SubmitBlockCmd{HexBlock: test.hex}, nil)
+		if test.errCode != 0 {
+			if rpcErr := toRPCError(err); rpcErr == nil || rpcErr.Code != test.errCode {
+				t.Errorf("%s: got error %v, expect code %d", test.name, err, test.errCode)
+			}
+			continue
+		}
+		if err != nil || status != test.status {
+			t.Errorf("%s: got status %v, error %v, expect %v", test.name, status, err, test.status)
+		}
+	}
+}
+
+func TestGetMiningInfo(t *testing.T) {
+	path, err := ioutil.TempDir("", "getmininginfo")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	_, tip := initWalkedCoins(t, path)
+	mempool.InitMempool()
+
+	// 60 blocks ten minutes and 1000 of work apart, signalling a version
+	// bit no deployment uses
+	const unknownVersion = versionbits.VersionBitsTopBits | 1<<27
+	indexes := []*blockindex.BlockIndex{tip}
+	for prev := tip; prev.Height < tip.Height+60; prev = indexes[len(indexes)-1] {
+		header := block.BlockHeader{Version: unknownVersion, HashPrevBlock: *prev.GetBlockHash(),
+			Time: prev.Header.Time + 600, Bits: 0x207fffff}
+		index := blockindex.NewBlockIndex(&header)
+		index.Height = prev.Height + 1
+		index.Prev = prev
+		index.ChainWork.Add(&prev.ChainWork, big.NewInt(1000))
+		indexes = append(indexes, index)
+	}
+	gChain := chain.GetInstance()
+	defer gChain.SetTip(tip)
+
+	gChain.SetTip(indexes[60])
+	if hashPS := networkHashPS(10, -1); hashPS != 1000.0/600 {
+		t.Errorf("%v hashes per second over the last 10 blocks, expect %v", hashPS, 1000.0/600)
+	}
+	if hashPS := networkHashPS(5, indexes[30].Height); hashPS != 1000.0/600 {
+		t.Errorf("%v hashes per second at height %d, expect %v", hashPS, indexes[30].Height, 1000.0/600)
+	}
+	if hashPS := networkHashPS(10, 0); hashPS != 0 {
+		t.Errorf("%v hashes per second at the genesis block, expect none", hashPS)
+	}
+
+	tests := []struct {
+		name    string
+		tip     *blockindex.BlockIndex
+		warning bool
+	}{
+		{"most blocks of unknown versions", indexes[60], true},
+		{"few blocks of unknown versions", indexes[40], false},
+	}
+	for _, test := range tests {
+		gChain.SetTip(test.tip)
+		ret, err := handleGetMiningInfo(nil, &btcjson.GetMiningInfoCmd{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := ret.(*btcjson.GetMiningInfoResult)
+		if info.Blocks != int64(test.tip.Height) || info.Generate || info.GenProcLimit != 0 {
+			t.Errorf("%s: got %+v", test.name, info)
+		}
+		if (info.Warnings != "") != test.warning || info.Errors != info.Warnings {
+			t.Errorf("%s: got warnings %q and errors %q, expect a warning %t", test.name, info.Warnings,
+				info.Errors, test.warning)
+		}
+	}
+}
+
+func TestSetGenerateWithoutAddress(t *testing.T) {
+	defer func(addr string) { conf.Cfg.Mining.MiningAddr = addr }(conf.Cfg.Mining.MiningAddr)
+	conf.Cfg.Mining.MiningAddr = ""
+
+	workers := 1
+	_, err := handleSetGenerate(nil, &btcjson.SetGenerateCmd{Generate: true, GenProcLimit: &workers}, nil)
+	if toRPCError(err) == nil {
+		t.Errorf("generating without a mining address got error %v", err)
+	}
+	if generate, _ := handleGetGenerate(nil, nil, nil); generate.(bool) {
+		t.Error("the miner runs without a mining address")
+	}
+
+	// stopping needs no address, the worker limit is ignored
+	if _, err := handleSetGenerate(nil, &btcjson.SetGenerateCmd{Generate: false, GenProcLimit: &workers}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if generate, limit := miner.generating(); generate || limit != 0 {
+		t.Errorf("stopped miner generating %t with %d workers", generate, limit)
+	}
+}
@@ -5,12 +5,17 @@ import (
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/hex"
+	"fmt"
+	"runtime"
+	"time"
 
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/net/server"
 	"github.com/copernet/copernicus/net/wire"
 	"github.com/copernet/copernicus/rpc/btcjson"
 	"github.com/copernet/copernicus/util"
@@ -36,6 +41,11 @@ var miscHandlers = map[string]commandHandler{
 	"help":                   handleHelp,
 	"stop":                   handleStop,
 	"version":                handleVersion,
+	"getrpcstats":            handleGetRPCStats,
+	"getversioninfo":         handleGetVersionInfo,
+	"uptime":                 handleUptime,
+	"getmemoryinfo":          handleGetMemoryInfo,
+	"getrpcinfo":             handleGetRPCInfo,
 }
 
 func handleGetInfo(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
@@ -71,15 +81,70 @@ func handleValidateAddress(s *Server, cmd interface{}, closeChan <-chan struct{}
 		return result, nil
 	}
 
+	scriptPubKey, err := script.PayToAddrScript(dest)
+	if err != nil {
+		result.IsValid = false
+		return result, nil
+	}
+
 	result.IsValid = true
 	result.Address = c.Address
-	result.ScriptPubKey = hex.EncodeToString(dest.EncodeToPubKeyHash())
+	result.ScriptPubKey = hex.EncodeToString(scriptPubKey.GetData())
 
 	return result, nil
 }
 
 func handleCreatemultisig(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	return nil, nil
+	c := cmd.(*btcjson.CreateMultiSigCmd)
+
+	if c.NRequired < 1 {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"a multisignature address must require at least one key to redeem")
+	}
+	if len(c.Keys) < c.NRequired {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			fmt.Sprintf("not enough keys supplied (got %d keys, but need at least %d to redeem)",
+				len(c.Keys), c.NRequired))
+	}
+	if len(c.Keys) > 16 {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"Number of addresses involved in the multisignature address creation > 16\n"+
+				"Reduce the number")
+	}
+
+	pubKeys := make([][]byte, 0, len(c.Keys))
+	for _, key := range c.Keys {
+		pubKey, err := hex.DecodeString(key)
+		if err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey,
+				"Invalid public key: "+key)
+		}
+		if _, err := crypto.ParsePubKey(pubKey); err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey,
+				"Invalid public key: "+key)
+		}
+		pubKeys = append(pubKeys, pubKey)
+	}
+
+	redeemScript, err := script.NewScriptMultiSig(c.NRequired, pubKeys)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, err.Error())
+	}
+	if redeemScript.Size() > script.MaxScriptElementSize {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			fmt.Sprintf("redeemScript exceeds size limit: %d > %d",
+				redeemScript.Size(), script.MaxScriptElementSize))
+	}
+
+	address, err := script.AddressFromScriptHash(redeemScript.GetData())
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, err.Error())
+	}
+
+	return &btcjson.CreateMultiSigResult{
+		Address:      address.String(),
+		RedeemScript: hex.EncodeToString(redeemScript.GetData()),
+	}, nil
 }
 
 func handleVerifyMessage(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
@@ -203,7 +268,8 @@ func handleHelp(s *Server, cmd interface{}, closeChan <-chan struct{}) (interfac
 		return usage, nil
 	}
 
-	if _, ok := rpcHandlers[command]; !ok {
+	_, ok := rpcHandlers[command]
+	if _, wsOnly := wsHandlers[command]; !ok && !wsOnly {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCInvalidParameter,
 			Message: "Unknown command: " + command,
@@ -239,8 +305,104 @@ func handleVersion(s *Server, cmd interface{}, closeChan <-chan struct{}) (inter
 	return result, nil
 }
 
+// handleGetVersionInfo describes the running binary: its version, the commit
+// and toolchain it was built from and the optional features it supports.
+func handleGetVersionInfo(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	features := make(map[string]bool)
+	for _, name := range conf.OptionalFeatures {
+		features[name] = conf.HasFeature(name)
+	}
+	features["txindex"] = conf.Cfg.Chain.TxIndex
+	features["addressindex"] = conf.Cfg.Chain.AddressIndex
+	features["spentindex"] = conf.Cfg.Chain.SpentIndex
+
+	return &btcjson.GetVersionInfoResult{
+		Version:         conf.Version(),
+		ProtocolVersion: wire.ProtocolVersion,
+		UserAgent:       server.UserAgent(),
+		GitCommit:       conf.GitCommit,
+		BuildDate:       conf.BuildDate,
+		GoVersion:       conf.GoVersion(),
+		Features:        features,
+	}, nil
+}
+
+func handleUptime(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	return time.Now().Unix() - s.cfg.StartupTime, nil
+}
+
+func handleGetMemoryInfo(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetMemoryInfoCmd)
+	mode := "stats"
+	if c.Mode != nil {
+		mode = *c.Mode
+	}
+	switch mode {
+	case "stats":
+	case "mallocinfo":
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"mallocinfo is only available when compiled with glibc 2.10+")
+	default:
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"unknown mode "+mode)
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	var lastGC int64
+	if ms.LastGC != 0 {
+		lastGC = time.Unix(0, int64(ms.LastGC)).Unix()
+	}
+	return &btcjson.GetMemoryInfoResult{
+		Locked: btcjson.LockedMemoryInfo{
+			Used:       ms.HeapInuse,
+			Free:       ms.HeapIdle,
+			Total:      ms.HeapSys,
+			ChunksUsed: ms.HeapObjects,
+		},
+		Runtime: btcjson.RuntimeMemoryInfo{
+			HeapAlloc:    ms.HeapAlloc,
+			HeapSys:      ms.HeapSys,
+			HeapInuse:    ms.HeapInuse,
+			HeapIdle:     ms.HeapIdle,
+			HeapReleased: ms.HeapReleased,
+			HeapObjects:  ms.HeapObjects,
+			StackInuse:   ms.StackInuse,
+			Sys:          ms.Sys,
+			TotalAlloc:   ms.TotalAlloc,
+			Mallocs:      ms.Mallocs,
+			Frees:        ms.Frees,
+			NumGC:        ms.NumGC,
+			NumForcedGC:  ms.NumForcedGC,
+			PauseTotalMs: durationToMs(time.Duration(ms.PauseTotalNs)),
+			LastGC:       lastGC,
+			Goroutines:   runtime.NumGoroutine(),
+		},
+	}, nil
+}
+
+func handleGetRPCInfo(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	return &btcjson.GetRPCInfoResult{
+		ActiveCommands: activeCmds.snapshot(),
+		LogPath:        log.FilePath(),
+	}, nil
+}
+
+// miscUtilCommands are the miscellaneous commands listed as utilities rather
+// than as controls of the server.
+var miscUtilCommands = map[string]struct{}{
+	"validateaddress":        {},
+	"createmultisig":         {},
+	"verifymessage":          {},
+	"signmessagewithprivkey": {},
+}
+
 func registerMiscRPCCommands() {
 	for name, handler := range miscHandlers {
-		appendCommand(name, handler)
+		category := "control"
+		if _, ok := miscUtilCommands[name]; ok {
+			category = "util"
+		}
+		appendCommand(category, name, handler)
 	}
 }
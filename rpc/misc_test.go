@@ -0,0 +1,70 @@
+package rpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/rpc/btcjson"
+)
+
+func TestUptime(t *testing.T) {
+	s := &Server{cfg: ServerConfig{StartupTime: time.Now().Unix() - 100}}
+	ret, err := handleUptime(s, nil, nil)
+	if uptime, ok := ret.(int64); err != nil || !ok || uptime < 100 || uptime > 101 {
+		t.Errorf("uptime %v, error %v, expect 100 seconds", ret, err)
+	}
+}
+
+func TestGetMemoryInfo(t *testing.T) {
+	mode := func(m string) *string { return &m }
+	tests := []struct {
+		name string
+		mode *string
+		ok   bool
+	}{
+		{"default", nil, true},
+		{"stats", mode("stats"), true},
+		{"mallocinfo", mode("mallocinfo"), false},
+		{"unknown mode", mode("nosuchmode"), false},
+	}
+	for _, test := range tests {
+		ret, err := handleGetMemoryInfo(nil, &btcjson.GetMemoryInfoCmd{Mode: test.mode}, nil)
+		if !test.ok {
+			if rpcErr := toRPCError(err); rpcErr == nil || rpcErr.Code != btcjson.ErrRPCInvalidParameter {
+				t.Errorf("%s: got error %v, expect an invalid parameter", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		info := ret.(*btcjson.GetMemoryInfoResult)
+		if info.Runtime.HeapSys == 0 || info.Runtime.Goroutines == 0 || info.Locked.Total != info.Runtime.HeapSys {
+			t.Errorf("%s: got %+v", test.name, info)
+		}
+	}
+}
+
+func TestGetRPCInfo(t *testing.T) {
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	id := activeCmds.begin("getrpcinfotest", time.Now().Add(-time.Second))
+	defer activeCmds.end(id)
+
+	ret, err := handleGetRPCInfo(nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, cmd := range ret.(*btcjson.GetRPCInfoResult).ActiveCommands {
+		if cmd.Method == "getrpcinfotest" {
+			found = cmd.Duration >= int64(time.Second/time.Microsecond)
+		}
+	}
+	if !found {
+		t.Errorf("the active command is not reported: %+v", ret)
+	}
+}
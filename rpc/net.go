@@ -5,6 +5,11 @@ import (
 	"math"
 	"time"
 
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/logic/lchain"
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/net/banman"
 	"github.com/copernet/copernicus/net/server"
 	"github.com/copernet/copernicus/net/wire"
 	"github.com/copernet/copernicus/rpc/btcjson"
@@ -17,6 +22,7 @@ var netHandlers = map[string]commandHandler{
 	"ping":               handlePing,
 	"getpeerinfo":        handleGetPeerInfo,
 	"addnode":            handleAddNode,
+	"addconnection":      handleAddConnection,
 	"disconnectnode":     handleDisconnectNode,
 	"getaddednodeinfo":   handleGetAddedNodeInfo,
 	"getnettotals":       handleGetNetTotals,
@@ -25,6 +31,7 @@ var netHandlers = map[string]commandHandler{
 	"listbanned":         handleListBanned,
 	"clearbanned":        handleClearBanned,
 	"setnetworkactive":   handleSetNetWorkActive,
+	"syncmempool":        handleSyncMempool,
 }
 
 func handleGetConnectionCount(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
@@ -62,19 +69,24 @@ func handlePing(s *Server, cmd interface{}, closeChan <-chan struct{}) (interfac
 }
 
 func handleGetPeerInfo(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	getPeerInfoCmd := &service.GetPeersInfoRequest{}
-
-	ret, _ := server.ProcessForRPC(getPeerInfoCmd) // todo Alert: match with return type
+	ret, err := server.ProcessForRPC(&service.GetPeersInfoRequest{})
+	if err != nil {
+		return nil, btcjson.RPCError{
+			Code:    btcjson.RPCInternalError,
+			Message: "Can not acquire peer info",
+		}
+	}
 	peers := ret.([]server.RPCServerPeer)
-	//syncPeerID := s.cfg.SyncMgr.SyncPeerID()
+
 	infos := make([]*btcjson.GetPeerInfoResult, 0, len(peers))
 	for _, item := range peers {
-		statsSnap := item.ToPeer().StatsSnapshot()
+		p := item.ToPeer()
+		statsSnap := p.StatsSnapshot()
+		syncStats := item.SyncStats()
 		info := &btcjson.GetPeerInfoResult{
 			ID:              statsSnap.ID,
 			Addr:            statsSnap.Addr,
-			AddrLocal:       item.ToPeer().LocalAddr().String(),
-			Services:        fmt.Sprintf("%08d", uint64(statsSnap.Services)),
+			Services:        fmt.Sprintf("%016x", uint64(statsSnap.Services)),
 			RelayTxes:       !item.IsTxRelayDisabled(),
 			LastSend:        statsSnap.LastSend.Unix(),
 			LastRecv:        statsSnap.LastRecv.Unix(),
@@ -82,54 +94,132 @@ func handleGetPeerInfo(s *Server, cmd interface{}, closeChan <-chan struct{}) (i
 			BytesRecv:       statsSnap.BytesRecv,
 			ConnTime:        statsSnap.ConnTime.Unix(),
 			TimeOffset:      statsSnap.TimeOffset,
-			PingTime:        float64(statsSnap.LastPingMicros),
-			MinPing:         statsSnap.MingPing,
+			PingTime:        microsToSeconds(statsSnap.LastPingMicros),
+			MinPing:         microsToSeconds(statsSnap.MinPingMicros),
 			Version:         statsSnap.Version,
 			SubVer:          statsSnap.UserAgent,
 			Inbound:         statsSnap.Inbound,
-			AddNode:         statsSnap.AddNode,
+			AddNode:         item.ConnectionType() == server.ConnManual,
+			ConnectionType:  string(item.ConnectionType()),
 			StartingHeight:  statsSnap.StartingHeight,
-			BanScore:        int32(item.BanScore()), // TODO
-			SyncedHeaders:   statsSnap.SyncedHeaders,
-			SyncedBlocks:    statsSnap.SyncedBlocks,
-			Inflight:        statsSnap.Inflight,
-			WhiteListed:     statsSnap.WhiteListed,
+			BanScore:        int32(item.BanScore()),
+			SyncedHeaders:   int(syncStats.SyncedHeaders),
+			SyncedBlocks:    int(syncStats.SyncedBlocks),
+			Inflight:        make([]int, 0, len(syncStats.Inflight)),
+			WhiteListed:     item.IsWhitelisted(),
 			CashMagic:       statsSnap.UsesCashMagic,
 			BytesSendPerMsg: statsSnap.MapSendBytesPerMsgCmd,
 			BytesRecvPerMsg: statsSnap.MapRecvBytesPerMsgCmd,
 		}
-		if item.ToPeer().LastPingNonce() != 0 {
-			wait := float64(time.Since(statsSnap.LastPingTime).Nanoseconds())
-			// We actually want microseconds.
-			info.PingWait = wait / 1000
+		if addr := p.LocalAddr(); addr != nil {
+			info.AddrLocal = addr.String()
+		}
+		for _, height := range syncStats.Inflight {
+			info.Inflight = append(info.Inflight, int(height))
+		}
+		if statsSnap.LastPingNonce != 0 {
+			info.PingWait = time.Since(statsSnap.LastPingTime).Seconds()
 		}
 		infos = append(infos, info)
 	}
 	return infos, nil
 }
 
+// microsToSeconds converts a ping time in microseconds to the decimal seconds
+// reported by getpeerinfo.
+func microsToSeconds(micros int64) float64 {
+	return float64(micros) / float64(time.Second/time.Microsecond)
+}
+
 func handleAddNode(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.AddNodeCmd)
+
+	switch c.SubCmd {
+	case btcjson.ANAdd, btcjson.ANRemove, btcjson.ANOneTry:
+	default:
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"invalid subcommand for addnode")
+	}
+
 	_, err := server.ProcessForRPC(c)
 	if err != nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCInvalidParameter,
-			Message: err.Error(),
-		}
+		return nil, connManagerError(err)
 	}
 
 	return nil, nil
 }
 
+// connManagerError converts an error of the connection manager to the RPC
+// error bitcoind returns in the same case.
+func connManagerError(err error) error {
+	switch err {
+	case server.ErrNodeAlreadyAdded:
+		return btcjson.NewRPCError(btcjson.ErrRPCClientNodeAlreadyAdded,
+			"Error: Node already added")
+	case server.ErrNodeNotAdded:
+		return btcjson.NewRPCError(btcjson.ErrRPCClientNodeNotAdded,
+			"Error: Node has not been added.")
+	case server.ErrNodeNotConnected:
+		return btcjson.NewRPCError(btcjson.ErrRPCClientNodeNotConnected,
+			"Node not found in connected nodes")
+	case banman.ErrInvalidSubnet:
+		return btcjson.NewRPCError(btcjson.ErrRPCClientInvalidIPOrSubnet,
+			"Error: Invalid IP/Subnet")
+	case server.ErrSubnetAlreadyBanned:
+		return btcjson.NewRPCError(btcjson.ErrRPCClientNodeAlreadyAdded,
+			"Error: IP/Subnet already banned")
+	case server.ErrSubnetNotBanned:
+		return btcjson.NewRPCError(btcjson.ErrRPCClientInvalidIPOrSubnet,
+			"Error: Unban failed. Requested address/subnet was not previously banned.")
+	case server.ErrMempoolSyncDisabled:
+		return btcjson.NewRPCError(btcjson.ErrRPCMisc,
+			"Error: Mempool sync is disabled, enable P2PNet.MempoolSync")
+	case server.ErrPeerNotWhitelisted:
+		return btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"Error: Mempool sync requires a whitelisted peer")
+	}
+	return btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, err.Error())
+}
+
+// handleAddConnection opens an outbound connection of a chosen type, for
+// functional tests of the behavior of each type.
+func handleAddConnection(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.AddConnectionCmd)
+
+	if !model.ActiveNetParams.MineBlocksOnDemands {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCMisc,
+			"addconnection is for regression testing (-regtest mode) only")
+	}
+
+	switch server.ConnectionType(c.ConnectionType) {
+	case server.ConnOutboundFullRelay, server.ConnBlockRelayOnly, server.ConnFeeler:
+	default:
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"Invalid connection type: "+c.ConnectionType)
+	}
+
+	ret, err := server.ProcessForRPC(c)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCClientNodeNotAdded, err.Error())
+	}
+	return ret, nil
+}
+
 func handleDisconnectNode(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.DisconnectNodeCmd)
 
+	// an empty address is how positional callers pass only the node id
+	if c.Address != nil && *c.Address == "" {
+		c.Address = nil
+	}
+	if (c.Address == nil) == (c.ID == nil) {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"Only one of address and nodeid should be provided.")
+	}
+
 	_, err := server.ProcessForRPC(c)
 	if err != nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCInvalidParameter,
-			Message: err.Error(),
-		}
+		return nil, connManagerError(err)
 	}
 
 	return nil, nil
@@ -140,10 +230,7 @@ func handleGetAddedNodeInfo(s *Server, cmd interface{}, closeChan <-chan struct{
 
 	ret, err := server.ProcessForRPC(c)
 	if err != nil {
-		return nil, btcjson.RPCError{
-			Code:    btcjson.RPCInternalError,
-			Message: "Can not acquire connection count",
-		}
+		return nil, connManagerError(err)
 	}
 
 	return ret, nil
@@ -167,45 +254,54 @@ func handleGetnetWorkInfo(s *Server, cmd interface{}, closeChan <-chan struct{})
 	if err != nil {
 		return nil, btcjson.RPCError{
 			Code:    btcjson.RPCInternalError,
-			Message: "Can not acquire connection count",
+			Message: "Can not acquire network info",
 		}
 	}
 
-	return ret, nil
+	info := ret.(*btcjson.GetNetworkInfoResult)
+	info.Version = int32(1000000*conf.AppMajor + 10000*conf.AppMinor + 100*conf.AppPatch)
+	pool := mempool.GetInstance()
+	info.RelayFee = valueFromAmount(pool.GetMinFeeRate().SataoshisPerK)
+	info.IncrementalFee = valueFromAmount(pool.GetIncrementalRelayFee().SataoshisPerK)
+	info.Warnings = lchain.GetWarnings()
+	return info, nil
 }
 
 func handleSetBan(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.SetBanCmd)
 
+	if c.Command != "add" && c.Command != "remove" {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"invalid subcommand for setban")
+	}
+	if c.BanTime != nil && *c.BanTime < 0 {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			"bantime must not be negative")
+	}
+
 	_, err := server.ProcessForRPC(c)
 	if err != nil {
-		return nil, btcjson.RPCError{
-			Code:    btcjson.RPCInternalError,
-			Message: "Can not acquire connection count",
-		}
+		return nil, connManagerError(err)
 	}
 
 	return nil, nil
 }
 
 func handleListBanned(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	ret, err := server.ProcessForRPC(&service.ListBannedRequest{})
-	if err != nil {
-		return nil, btcjson.RPCError{
-			Code:    btcjson.RPCInternalError,
-			Message: "Can not acquire connection count",
-		}
-	}
-	return ret, nil
+	return server.ProcessForRPC(&service.ListBannedRequest{})
 }
 
 func handleClearBanned(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	_, err := server.ProcessForRPC(&service.ClearBannedRequest{})
+	return nil, err
+}
+
+func handleSyncMempool(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.SyncMempoolCmd)
+
+	_, err := server.ProcessForRPC(c)
 	if err != nil {
-		return nil, btcjson.RPCError{
-			Code:    btcjson.RPCInternalError,
-			Message: "Can not acquire connection count",
-		}
+		return nil, connManagerError(err)
 	}
 
 	return nil, nil
@@ -227,6 +323,6 @@ func handleSetNetWorkActive(s *Server, cmd interface{}, closeChan <-chan struct{
 
 func registerNetRPCCommands() {
 	for name, handler := range netHandlers {
-		appendCommand(name, handler)
+		appendCommand("network", name, handler)
 	}
 }
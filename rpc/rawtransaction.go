@@ -6,12 +6,14 @@ import (
 	"fmt"
 	"gopkg.in/fatih/set.v0"
 	"math"
-	"strconv"
 
 	"github.com/copernet/copernicus/crypto"
+	"github.com/copernet/copernicus/errcode"
 	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/lchain"
 	"github.com/copernet/copernicus/logic/lmempool"
 	"github.com/copernet/copernicus/logic/lmerkleblock"
+	"github.com/copernet/copernicus/logic/ltx"
 	"github.com/copernet/copernicus/logic/lutxo"
 	"github.com/copernet/copernicus/model/blockindex"
 	"github.com/copernet/copernicus/model/chain"
@@ -25,30 +27,69 @@ import (
 	"github.com/copernet/copernicus/model/utxo"
 	"github.com/copernet/copernicus/net/server"
 	"github.com/copernet/copernicus/net/wire"
+	"github.com/copernet/copernicus/persist/blkdb"
 	"github.com/copernet/copernicus/persist/disk"
 	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/service/mining"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
 )
 
 var rawTransactionHandlers = map[string]commandHandler{
-	"getrawtransaction":    handleGetRawTransaction,    // complete
+	"getrawtransaction":    handleGetRawTransaction,  // complete
+	"getrawtransactions":   handleGetRawTransactions, // complete
+	"getspentinfo":         handleGetSpentInfo,
 	"createrawtransaction": handleCreateRawTransaction, // complete
 	"decoderawtransaction": handleDecodeRawTransaction, // complete
 	"decodescript":         handleDecodeScript,         // complete
 	"sendrawtransaction":   handleSendRawTransaction,   // complete
-	"signrawtransaction":   handleSignRawTransaction,   // partial complete
+	"signrawtransaction":   handleSignRawTransaction,   // complete
 	"gettxoutproof":        handleGetTxoutProof,        // complete
 	"verifytxoutproof":     handleVerifyTxoutProof,     // complete
+
+	"gethexblockheaderchain": handleGetHexBlockHeaderChain,
 }
 
+// maxRawTransactionsPerRequest limits the number of txids a single
+// getrawtransactions request may ask for.
+const maxRawTransactionsPerRequest = 1000
+
+// defaultProofHeaders is the number of headers following the block of the
+// transaction gethexblockheaderchain returns by default.
+const defaultProofHeaders = 6
+
 func handleGetRawTransaction(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetRawTransactionCmd)
 
-	// Convert the provided transaction hash hex to a Hash.
-	txHash, err := util.GetHashFromStr(c.Txid)
-	if err != nil {
-		return nil, rpcDecodeHexError(c.Txid)
+	verbose := false
+	if c.Verbose != nil {
+		verbose = *c.Verbose
+	}
+
+	var blockIndex *blockindex.BlockIndex
+	if c.BlockHash != nil {
+		blockHash, err := util.BlockHashFromStr(*c.BlockHash)
+		if err != nil {
+			return nil, rpcDecodeHexError(*c.BlockHash)
+		}
+		blockIndex = chain.GetInstance().FindBlockIndex(blockHash.Hash())
+		if blockIndex == nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Block hash not found")
+		}
+	}
+
+	return getRawTransaction(c.Txid, verbose, blockIndex, newChainSnapshot())
+}
+
+// handleGetRawTransactions implements the getrawtransactions command, a batch
+// variant of getrawtransaction. A txid that can't be looked up doesn't fail the
+// request, its entry carries the error instead.
+func handleGetRawTransactions(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetRawTransactionsCmd)
+
+	if len(c.Txids) > maxRawTransactionsPerRequest {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			fmt.Sprintf("Too many txids, at most %d are allowed", maxRawTransactionsPerRequest))
 	}
 
 	verbose := false
@@ -56,40 +97,97 @@ func handleGetRawTransaction(s *Server, cmd interface{}, closeChan <-chan struct
 		verbose = *c.Verbose
 	}
 
-	tx, hashBlock, ok := GetTransaction(txHash, true)
-	if !ok {
-		return nil, btcjson.RPCError{
-			Code:    btcjson.ErrRPCInvalidAddressOrKey,
-			Message: "No such mempool or blockchain transaction. Use gettransaction for wallet transactions.",
+	snapshot := newChainSnapshot()
+	ret := make(map[string]*btcjson.GetRawTransactionsEntry, len(c.Txids))
+	for _, txid := range c.Txids {
+		if _, ok := ret[txid]; ok {
+			continue
 		}
+		result, err := getRawTransaction(txid, verbose, nil, snapshot)
+		if err != nil {
+			ret[txid] = &btcjson.GetRawTransactionsEntry{Error: toRPCError(err)}
+			continue
+		}
+		ret[txid] = &btcjson.GetRawTransactionsEntry{Result: result}
+	}
+
+	return ret, nil
+}
+
+// getRawTransaction looks up a transaction in the mempool or the block chain,
+// or only in the given block when blockIndex is not nil, and returns it as
+// hex, or as a TxRawResult when verbose is set. Confirmations are counted in
+// the chain pinned by snapshot.
+func getRawTransaction(txid string, verbose bool, blockIndex *blockindex.BlockIndex,
+	snapshot *chainSnapshot) (interface{}, error) {
+	transaction, hashBlock, err := lookupTransaction(txid, blockIndex)
+	if err != nil {
+		return nil, err
 	}
 
 	buf := bytes.NewBuffer(nil)
-	err = tx.Serialize(buf)
+	err = transaction.Serialize(buf)
 	if err != nil {
-		return nil, rpcDecodeHexError(c.Txid)
+		return nil, rpcDecodeHexError(txid)
 	}
 	strHex := hex.EncodeToString(buf.Bytes())
 	if !verbose {
 		return strHex, nil
 	}
 
-	rawTxn, err := getTxRawResult(tx, hashBlock, strHex)
+	rawTxn, err := getTxRawResult(transaction, hashBlock, strHex, snapshot)
 	if err != nil {
 		return nil, err
 	}
+	if blockIndex != nil {
+		inActiveChain := snapshot.Contains(blockIndex)
+		rawTxn.InActiveChain = &inActiveChain
+	}
 	return rawTxn, nil
 }
 
+// lookupTransaction looks up a transaction in the mempool or the block chain,
+// or only in the given block when blockIndex is not nil, and returns it along
+// with the hash of its block, which is nil for a mempool transaction.
+func lookupTransaction(txid string, blockIndex *blockindex.BlockIndex) (*tx.Tx, *util.Hash, error) {
+	// Convert the provided transaction hash hex to a Hash.
+	id, err := util.TxIDFromStr(txid)
+	if err != nil {
+		return nil, nil, rpcDecodeHexError(txid)
+	}
+	txHash := id.Hash()
+
+	if blockIndex != nil {
+		if !blockIndex.HasData() {
+			return nil, nil, btcjson.NewRPCError(btcjson.ErrRPCMisc, "Block not available")
+		}
+		transaction, ok := getTransactionFromBlock(&txHash, blockIndex)
+		if !ok {
+			return nil, nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey,
+				"No such transaction found in the provided block")
+		}
+		return transaction, blockIndex.GetBlockHash(), nil
+	}
+
+	transaction, hashBlock, ok := GetTransaction(&txHash, true)
+	if !ok {
+		return nil, nil, btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "No such mempool or blockchain transaction. Use gettransaction for wallet transactions.",
+		}
+	}
+	return transaction, hashBlock, nil
+}
+
 // getTxRawResult converts the passed transaction and associated parameters
 // to a raw transaction JSON object.
-func getTxRawResult(tx *tx.Tx, hashBlock *util.Hash, strHex string) (*btcjson.TxRawResult, error) {
+func getTxRawResult(tx *tx.Tx, hashBlock *util.Hash, strHex string, snapshot *chainSnapshot) (*btcjson.TxRawResult, error) {
 
-	hash := tx.GetHash()
+	txid := util.TxID(tx.GetHash())
 	txReply := &btcjson.TxRawResult{
 		Hex:      strHex,
-		TxID:     hash.String(),
-		Hash:     hash.String(),
+		TxID:     txid.String(),
+		Hash:     txid.String(),
 		Size:     int(tx.SerializeSize()),
 		Version:  tx.GetVersion(),
 		LockTime: tx.GetLockTime(),
@@ -97,12 +195,12 @@ func getTxRawResult(tx *tx.Tx, hashBlock *util.Hash, strHex string) (*btcjson.Tx
 		Vout:     getVoutList(tx),
 	}
 
-	if !hashBlock.IsNull() {
-		txReply.BlockHash = hashBlock.String()
+	if hashBlock != nil && !hashBlock.IsNull() {
+		txReply.BlockHash = util.BlockHash(*hashBlock).String()
 		bindex := chain.GetInstance().FindBlockIndex(*hashBlock)
 		if bindex != nil {
-			if chain.GetInstance().Contains(bindex) {
-				txReply.Confirmations = chain.GetInstance().TipHeight() - bindex.Height + 1
+			if snapshot.Contains(bindex) {
+				txReply.Confirmations = snapshot.Confirmations(bindex)
 				txReply.Time = bindex.Header.Time
 				txReply.Blocktime = bindex.Header.Time
 			} else {
@@ -124,7 +222,7 @@ func getVinList(tx *tx.Tx) []btcjson.Vin {
 			}
 		} else {
 			vinList[i] = btcjson.Vin{
-				Txid: in.PreviousOutPoint.Hash.String(),
+				Txid: util.TxID(in.PreviousOutPoint.Hash).String(),
 				Vout: in.PreviousOutPoint.Index,
 				ScriptSig: &btcjson.ScriptSig{
 					Asm: ScriptToAsmStr(in.GetScriptSig(), true),
@@ -205,23 +303,30 @@ func ScriptToAsmStr(s *script.Script, attemptSighashDecode bool) string {
 			str += opcodes.GetOpName(int(opcode))
 		}
 	}
+	if s.GetBadOpCode() {
+		if len(str) > 0 {
+			str += " "
+		}
+		str += "[error]"
+	}
 	return str
 }
 
-func ScriptPubKeyToJSON(script *script.Script, includeHex bool) btcjson.ScriptPubKeyResult {
+func ScriptPubKeyToJSON(scriptPubKey *script.Script, includeHex bool) btcjson.ScriptPubKeyResult {
 	result := btcjson.ScriptPubKeyResult{}
 
-	if script == nil {
+	if scriptPubKey == nil {
 		return result
 	}
 
-	result.Asm = ScriptToAsmStr(script, includeHex)
+	// scriptPubKeys carry no signatures, so never decode sighash types here
+	result.Asm = ScriptToAsmStr(scriptPubKey, false)
 	if includeHex {
-		result.Hex = hex.EncodeToString(script.GetData())
+		result.Hex = hex.EncodeToString(scriptPubKey.GetData())
 	}
 
-	t, addresses, required, err := script.ExtractDestinations()
-	result.Type = GetTxnOutputType(t)
+	t, addresses, required, err := scriptPubKey.ExtractDestinations()
+	result.Type = script.ScriptTypeName(t)
 
 	if err != nil {
 		return result
@@ -236,35 +341,17 @@ func ScriptPubKeyToJSON(script *script.Script, includeHex bool) btcjson.ScriptPu
 	return result
 }
 
-func GetTxnOutputType(sType int) string {
-	switch sType {
-	case script.ScriptNonStandard:
-		return "nonstandard"
-	case script.ScriptPubkey:
-		return "pubkey"
-	case script.ScriptPubkeyHash:
-		return "pubkeyhash"
-	case script.ScriptHash:
-		return "scripthash"
-	case script.ScriptMultiSig:
-		return "multisig"
-	case script.ScriptNullData:
-		return "nulldata"
-	default:
-		return "unknown"
-	}
-}
-
 func GetTransaction(hash *util.Hash, allowSlow bool) (*tx.Tx, *util.Hash, bool) {
 	entry := mempool.GetInstance().FindTx(*hash)
 	if entry != nil {
 		return entry.Tx, nil, true
 	}
 
-	/* TODO: NOT support txindex yet
-	if chain.GTxIndex {
-		chain.GBlockTree.ReadTxIndex(hash)
-	}*/
+	if lchain.TxIndexEnabled() {
+		if item, blockHash, ok := getTransactionFromTxIndex(hash); ok {
+			return item, blockHash, true
+		}
+	}
 
 	if !allowSlow {
 		return nil, nil, false
@@ -273,36 +360,70 @@ func GetTransaction(hash *util.Hash, allowSlow bool) (*tx.Tx, *util.Hash, bool)
 	// use coin database to locate block that contains transaction, and scan it
 	var indexSlow *blockindex.BlockIndex
 	coin := lutxo.AccessByTxid(utxo.GetUtxoCacheInstance(), hash)
-	if coin.IsSpent() {
+	if coin == nil {
 		return nil, nil, false
 	}
 
 	indexSlow = chain.GetInstance().GetIndex(coin.GetHeight())
 	if indexSlow != nil {
-		bk, ok := disk.ReadBlockFromDisk(indexSlow, chain.GetInstance().GetParams())
-		if ok {
-			for _, item := range bk.Txs {
-				if *hash == item.GetHash() {
-					return item, indexSlow.GetBlockHash(), true
-				}
-			}
+		if item, ok := getTransactionFromBlock(hash, indexSlow); ok {
+			return item, indexSlow.GetBlockHash(), true
 		}
 	}
 	return nil, nil, false
 }
 
+// getTransactionFromTxIndex locates the transaction with the transaction
+// index and reads it from its block file.
+func getTransactionFromTxIndex(hash *util.Hash) (*tx.Tx, *util.Hash, bool) {
+	pos, err := blkdb.GetInstance().ReadTxIndex(hash)
+	if err != nil || pos == nil {
+		return nil, nil, false
+	}
+	item, header, err := disk.ReadTxFromDisk(pos)
+	if err != nil {
+		log.Error("GetTransaction: read tx %s from disk failed: %v", hash.String(), err)
+		return nil, nil, false
+	}
+	if item.GetHash() != *hash {
+		log.Error("GetTransaction: txid mismatch for %s in the transaction index", hash.String())
+		return nil, nil, false
+	}
+	blockHash := header.GetHash()
+	return item, &blockHash, true
+}
+
+// getTransactionFromBlock reads the block from disk and looks the transaction
+// up in it.
+func getTransactionFromBlock(hash *util.Hash, index *blockindex.BlockIndex) (*tx.Tx, bool) {
+	bk, ok := disk.ReadBlockFromDisk(index, chain.GetInstance().GetParams())
+	if !ok {
+		return nil, false
+	}
+	for _, item := range bk.Txs {
+		if *hash == item.GetHash() {
+			return item, true
+		}
+	}
+	return nil, false
+}
+
 func handleCreateRawTransaction(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.CreateRawTransactionCmd)
 
 	lockTime := uint32(0)
-	if c.LockTime != nil && (*c.LockTime < 0 || *c.LockTime > int64(script.SequenceFinal)) {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCInvalidParameter,
-			Message: "Locktime out of range",
+	if c.LockTime != nil {
+		if *c.LockTime < 0 || *c.LockTime > int64(script.SequenceFinal) {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Invalid parameter, locktime out of range",
+			}
 		}
 		lockTime = uint32(*c.LockTime)
 	}
-	transaction := tx.NewTx(lockTime, 0)
+	// relative lock-times given by the input sequences (BIP68) only apply to
+	// version 2 transactions
+	transaction := tx.NewTx(lockTime, tx.MaxStandardVersion)
 
 	for _, input := range c.Inputs {
 		txIn, err := createRawTxInput(&input, lockTime)
@@ -312,8 +433,20 @@ func handleCreateRawTransaction(s *Server, cmd interface{}, closeChan <-chan str
 		transaction.AddTxIn(txIn)
 	}
 
-	for address, cost := range c.Outputs {
-		txOut, err := createRawTxOutput(address, cost)
+	addresses := make(map[string]struct{})
+	for _, output := range c.Outputs {
+		// any number of data outputs is fine, but paying to the same
+		// address twice is most likely a mistake
+		if output.Key != "data" {
+			if _, ok := addresses[output.Key]; ok {
+				return nil, btcjson.RPCError{
+					Code:    btcjson.ErrInvalidParameter,
+					Message: "Invalid parameter, duplicated address: " + output.Key,
+				}
+			}
+			addresses[output.Key] = struct{}{}
+		}
+		txOut, err := createRawTxOutput(output.Key, output.Value)
 		if err != nil {
 			return nil, err
 		}
@@ -331,7 +464,7 @@ func handleCreateRawTransaction(s *Server, cmd interface{}, closeChan <-chan str
 }
 
 func createRawTxInput(input *btcjson.TransactionInput, lockTime uint32) (*txin.TxIn, error) {
-	hash, err := util.GetHashFromStr(input.Txid)
+	txid, err := util.TxIDFromStr(input.Txid)
 	if err != nil {
 		return nil, rpcDecodeHexError(input.Txid)
 	}
@@ -356,13 +489,13 @@ func createRawTxInput(input *btcjson.TransactionInput, lockTime uint32) (*txin.T
 		sequence = math.MaxUint32 - 1
 	}
 
-	txIn := txin.NewTxIn(outpoint.NewOutPoint(*hash, input.Vout), &script.Script{}, sequence)
+	txIn := txin.NewTxIn(outpoint.NewOutPoint(txid.Hash(), input.Vout), &script.Script{}, sequence)
 	return txIn, nil
 }
 
 func createRawTxOutput(address string, cost interface{}) (*txout.TxOut, error) {
 	var txAmount amount.Amount
-	scriptPubKey := script.NewEmptyScript()
+	var scriptPubKey *script.Script
 
 	if address == "data" {
 		data, ok := cost.(string)
@@ -377,27 +510,19 @@ func createRawTxOutput(address string, cost interface{}) (*txout.TxOut, error) {
 			return nil, rpcDecodeHexError(data)
 		}
 		txAmount = amount.Amount(0)
-		scriptPubKey.PushOpCode(opcodes.OP_RETURN)
-		scriptPubKey.PushSingleData(dataBuf)
-	} else {
-		costVal, ok := cost.(float64)
-		if !ok {
-			costData, ok := cost.(string)
-			if !ok {
-				return nil, btcjson.RPCError{
-					Code:    btcjson.ErrInvalidParameter,
-					Message: "Invalid parameter, value of amount must be numeric or string",
-				}
-			}
-			var err error
-			costVal, err = strconv.ParseFloat(costData, 64)
-			if err != nil {
-				return nil, btcjson.RPCError{
-					Code:    btcjson.ErrInvalidParameter,
-					Message: "Invalid parameter, value of amount must be numeric",
-				}
+		scriptPubKey, err = script.NewScriptNullData(dataBuf)
+		if err != nil {
+			return nil, btcjson.RPCError{
+				Code:    btcjson.ErrInvalidParameter,
+				Message: "Invalid parameter, data is too large",
 			}
 		}
+	} else {
+		var err error
+		txAmount, err = amountFromValue(cost)
+		if err != nil {
+			return nil, err
+		}
 		addr, err := script.AddressFromString(address)
 		if err != nil {
 			return nil, btcjson.RPCError{
@@ -405,16 +530,13 @@ func createRawTxOutput(address string, cost interface{}) (*txout.TxOut, error) {
 				Message: "Invalid Bitcoin address: " + address,
 			}
 		}
-		txAmount = amount.Amount(int64(costVal * 1e8))
-		if !amount.MoneyRange(txAmount) {
+		scriptPubKey, err = script.PayToAddrScript(addr)
+		if err != nil {
 			return nil, btcjson.RPCError{
-				Code:    btcjson.ErrInvalidParameter,
-				Message: "Invalid amount",
+				Code:    btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Invalid Bitcoin address: " + address,
 			}
 		}
-		scriptPubKey.PushOpCode(opcodes.OP_HASH160)
-		scriptPubKey.PushSingleData(addr.EncodeToPubKeyHash())
-		scriptPubKey.PushOpCode(opcodes.OP_EQUAL)
 	}
 
 	txOut := txout.NewTxOut(txAmount, scriptPubKey)
@@ -424,36 +546,78 @@ func createRawTxOutput(address string, cost interface{}) (*txout.TxOut, error) {
 func handleDecodeRawTransaction(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.DecodeRawTransactionCmd)
 
-	// Unserialize the transaction.
-	serializedTx, err := hex.DecodeString(c.HexTx)
-	if err != nil {
-		return nil, rpcDecodeHexError(c.HexTx)
-	}
-
-	transaction := tx.NewEmptyTx()
-	err = transaction.Unserialize(bytes.NewReader(serializedTx))
+	transaction, err := decodeHexTx(c.HexTx)
 	if err != nil {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCDeserialization,
-			Message: "TX decode failed: " + err.Error(),
-		}
+		return nil, err
 	}
-	txHash := transaction.GetHash()
+	txid := util.TxID(transaction.GetHash())
 
 	// Create and return the result.
 	txReply := &btcjson.TxRawDecodeResult{
-		Txid:     txHash.String(),
-		Hash:     txHash.String(),
+		Txid:     txid.String(),
+		Hash:     txid.String(),
 		Size:     transaction.SerializeSize(),
 		Version:  transaction.GetVersion(),
 		Locktime: transaction.GetLockTime(),
 		Vin:      getVinList(transaction),
 		Vout:     getVoutList(transaction),
 	}
+	if fee, ok := txFee(transaction); ok {
+		feeBCH := valueFromAmount(int64(fee))
+		txReply.Fee = &feeBCH
+	}
 
 	return txReply, nil
 }
 
+// txFee returns the fee of transaction if all the outputs it spends are
+// unspent in the UTXO set or created by mempool transactions.
+func txFee(transaction *tx.Tx) (amount.Amount, bool) {
+	if transaction.IsCoinBase() {
+		return 0, false
+	}
+
+	var inputValue amount.Amount
+	for _, in := range transaction.GetIns() {
+		coin := utxo.GetUtxoCacheInstance().GetCoin(in.PreviousOutPoint)
+		if coin == nil || coin.IsSpent() {
+			coin = mempool.GetInstance().GetCoin(in.PreviousOutPoint)
+		}
+		if coin == nil || coin.IsSpent() {
+			return 0, false
+		}
+		inputValue += coin.GetAmount()
+	}
+	return inputValue - transaction.GetValueOut(), true
+}
+
+// decodeHexTx decodes a hex-encoded serialized transaction. Like Bitcoin ABC
+// it fails if any data follows the transaction.
+func decodeHexTx(hexTx string) (*tx.Tx, error) {
+	serializedTx, err := hex.DecodeString(hexTx)
+	if err != nil {
+		return nil, rpcDecodeHexError(hexTx)
+	}
+	return decodeTx(serializedTx)
+}
+
+// decodeTx deserializes a transaction, which must span all of serializedTx.
+func decodeTx(serializedTx []byte) (*tx.Tx, error) {
+	r := bytes.NewReader(serializedTx)
+	transaction := tx.NewEmptyTx()
+	err := transaction.Unserialize(r)
+	if err == nil && r.Len() != 0 {
+		err = fmt.Errorf("%d unexpected trailing bytes", r.Len())
+	}
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDeserialization,
+			Message: "TX decode failed: " + err.Error(),
+		}
+	}
+	return transaction, nil
+}
+
 func handleDecodeScript(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.DecodeScriptCmd)
 
@@ -481,46 +645,69 @@ func handleDecodeScript(s *Server, cmd interface{}, closeChan <-chan struct{}) (
 func handleSendRawTransaction(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.SendRawTransactionCmd)
 
-	buf := bytes.NewBufferString(c.HexTx)
-	transaction := tx.Tx{}
-	err := transaction.Unserialize(buf)
+	transaction, err := decodeHexTx(c.HexTx)
 	if err != nil {
-		return nil, rpcDecodeHexError(c.HexTx)
+		return nil, err
+	}
+	if err := s.sendRawTransaction(transaction, c.AllowHighFees != nil && *c.AllowHighFees); err != nil {
+		return nil, err
 	}
-
 	hash := transaction.GetHash()
+	return hash.String(), nil
+}
 
-	// NOT support high fee limit yet
-	//maxRawTxFee := mining.MaxTxFee
-	//if c.AllowHighFees != nil && *c.AllowHighFees {
-	//	maxRawTxFee = 0
-	//}
+// sendRawTransaction adds a transaction to the mempool, unless it is there
+// already, and relays it.
+func (s *Server) sendRawTransaction(transaction *tx.Tx, allowHighFees bool) error {
+	hash := transaction.GetHash()
 
-	view := utxo.GetUtxoCacheInstance()
-	var haveChain bool
-	for i := 0; !haveChain && i < transaction.GetOutsCount(); i++ {
-		existingCoin := view.GetCoin(outpoint.NewOutPoint(hash, uint32(i)))
-		haveChain = !existingCoin.IsSpent()
+	maxRawTxFee := mining.MaxTxFee
+	if allowHighFees {
+		maxRawTxFee = 0
 	}
 
-	entry := mempool.GetInstance().FindTx(hash)
-	if entry == nil && !haveChain {
-		err = lmempool.AcceptTxToMemPool(&transaction)
-		if err != nil {
-			return nil, btcjson.RPCError{
-				Code:    btcjson.ErrUnDefined,
-				Message: "mempool reject the specified transaction for undefined reason",
+	haveChain := coinsView(false).HaveOutputs(hash, transaction.GetOutsCount())
+
+	pool := mempool.GetInstance()
+	haveMempool := pool.FindTx(hash) != nil
+	if haveChain {
+		return btcjson.NewRPCError(btcjson.RPCTransactionAlreadyInChain,
+			"transaction already in block chain")
+	}
+	if !haveMempool {
+		if err := ltx.CheckRegularTransaction(transaction); err != nil {
+			return txRejectedError(err)
+		}
+		if err := lmempool.AcceptTxToMemPoolWithAbsurdFee(transaction, maxRawTxFee); err != nil {
+			if errcode.IsErrorCode(err, errcode.TxErrNoPreviousOut) {
+				return btcjson.NewRPCError(btcjson.RPCTransactionError, "Missing inputs")
 			}
+			return txRejectedError(err)
+		}
+		pool.AddUnbroadcastTx(hash)
+		if entry := pool.FindTx(hash); entry != nil && s.cfg.FeeEstimator != nil {
+			s.cfg.FeeEstimator.ObserveTransaction(entry)
 		}
 	}
 
 	txInvMsg := wire.NewInvVect(wire.InvTypeTx, &hash)
-	_, err = server.ProcessForRPC(txInvMsg)
+	_, err := server.ProcessForRPC(txInvMsg)
 	if err != nil {
-		return nil, btcjson.ErrRPCInternal
+		return btcjson.ErrRPCInternal
 	}
 
-	return hash.String(), nil
+	return nil
+}
+
+// txRejectedError reports a transaction failing validation the way Bitcoin
+// ABC does: the reject code sent to peers followed by the reason.  A check
+// which timed out has no reject code, the transaction may be sent again.
+func txRejectedError(err error) *btcjson.RPCError {
+	if errcode.IsTxCheckTimeout(err) {
+		return btcjson.NewRPCError(btcjson.RPCTransactionError, errcode.TxErrScriptCheckTimeout.String())
+	}
+	code, reason := errcode.GetTxRejectCode(err)
+	return btcjson.NewRPCError(btcjson.RPCTransactionRejected, fmt.Sprintf("%d: %s", code, reason))
 }
 
 var mapSigHashValues = map[string]int{
@@ -568,13 +755,24 @@ func handleSignRawTransaction(s *Server, cmd interface{}, closeChan <-chan struc
 	}
 
 	// mergedTx will end up with all the signatures; it starts as a clone of the rawtx
-	//mergedTx := transactions[0]
-	// todo Fetch previous transactions (inputs) to cache <FetchCoin() function>
-	// todo do not support this feature at current utxo version
+	mergedTx := transactions[0]
+
+	// Fetch previous transactions (inputs) from the chain and the mempool
+	// into a private view, so the hints below never touch the global cache
+	view := utxo.NewEmptyCoinsMap()
+	for _, in := range mergedTx.GetIns() {
+		coin := utxo.GetUtxoCacheInstance().GetCoin(in.PreviousOutPoint)
+		if coin == nil || coin.IsSpent() {
+			coin = mempool.GetInstance().GetCoin(in.PreviousOutPoint)
+		}
+		if coin != nil && !coin.IsSpent() {
+			view.AddCoin(in.PreviousOutPoint, coin.DeepCopy(), true)
+		}
+	}
 
 	givenKeys := false
-	keyStore := make([]*crypto.PrivateKey, 0)
-	scriptStore := make([]*script.Script, 0)
+	keyStore := make(map[string]*crypto.PrivateKey)
+	scriptStore := make(map[string]string)
 	if c.PrivKeys != nil {
 		givenKeys = true
 		for _, key := range *c.PrivKeys {
@@ -586,88 +784,72 @@ func handleSignRawTransaction(s *Server, cmd interface{}, closeChan <-chan struc
 				}
 			}
 
-			keyStore = append(keyStore, privKey)
+			keyStore[string(util.Hash160(privKey.PubKey().ToBytes()))] = privKey
 		}
 	}
 
-	for _, input := range *c.Inputs {
-		hash, err := util.GetHashFromStr(input.Txid)
-		if err != nil {
-			return nil, rpcDecodeHexError(input.Txid)
-		}
-
-		if input.Vout < 0 {
-			return nil, btcjson.RPCError{
-				Code:    btcjson.RPCDeserializationError,
-				Message: "vout must be positive",
+	// Add previous txouts given in the RPC call
+	if c.Inputs != nil {
+		for _, input := range *c.Inputs {
+			txid, err := util.TxIDFromStr(input.Txid)
+			if err != nil {
+				return nil, rpcDecodeHexError(input.Txid)
 			}
-		}
-		out := outpoint.NewOutPoint(*hash, input.Vout)
-
-		scriptPubKey, err := hex.DecodeString(input.ScriptPubKey)
-		if err != nil {
-			return nil, rpcDecodeHexError(input.ScriptPubKey)
-		}
+			out := outpoint.NewOutPoint(txid.Hash(), input.Vout)
 
-		view := utxo.GetUtxoCacheInstance()
-		coin := view.GetCoin(out)
+			scriptData, err := hex.DecodeString(input.ScriptPubKey)
+			if err != nil {
+				return nil, rpcDecodeHexError(input.ScriptPubKey)
+			}
+			scriptPubKey := script.NewScriptRaw(scriptData)
 
-		coinOut := coin.GetTxOut()
-		if !coin.IsSpent() && !coinOut.GetScriptPubKey().IsEqual(script.NewScriptRaw(scriptPubKey)) {
-			return nil, btcjson.RPCError{
-				Code: btcjson.RPCDeserializationError,
-				Message: "Previous output scriptPubKey mismatch:\n" +
-					ScriptToAsmStr(coinOut.GetScriptPubKey(), false) +
-					"\nvs:\n" + ScriptToAsmStr(script.NewScriptRaw(scriptPubKey), false),
+			coin := view.GetCoin(out)
+			if coin != nil && !coin.IsSpent() && !coin.GetScriptPubKey().IsEqual(scriptPubKey) {
+				return nil, btcjson.RPCError{
+					Code: btcjson.RPCDeserializationError,
+					Message: "Previous output scriptPubKey mismatch:\n" +
+						ScriptToAsmStr(coin.GetScriptPubKey(), false) +
+						"\nvs:\n" + ScriptToAsmStr(scriptPubKey, false),
+				}
 			}
-		}
 
-		txOut := txout.NewTxOut(0, script.NewScriptRaw(scriptPubKey))
-		if input.Amount != nil {
-			cost := int64(*input.Amount) * util.COIN
-			if !amount.MoneyRange(amount.Amount(cost)) {
+			// amount param is required in replay-protected txs.
+			if input.Amount == nil {
 				return nil, btcjson.RPCError{
 					Code:    btcjson.ErrRPCInvalidParameter,
-					Message: "Amount out of range",
+					Message: "Missing amount",
 				}
 			}
-			txOut.SetValue(amount.Amount(cost))
-		} else {
-			// amount param is required in replay-protected txs.
-			// Note that we must check for its presence here rather
-			// than use RPCTypeCheckObj() above, since UniValue::VNUM
-			// parser incorrectly parses numerics with quotes, eg
-			// "3.12" as a string when JSON allows it to also parse
-			// as numeric. And we have to accept numerics with quotes
-			// because our own dogfood (our rpc results) always
-			// produces decimal numbers that are quoted
-			// eg getbalance returns "3.14152" rather than 3.14152
-			return nil, btcjson.RPCError{
-				Code:    btcjson.ErrRPCInvalidParameter,
-				Message: "Missing amount",
+			cost, err := amountFromValue(input.Amount)
+			if err != nil {
+				return nil, err
 			}
-		}
-
-		// todo confirm
-		coinsMap := utxo.NewEmptyCoinsMap()
-		coinsMap.AddCoin(out, utxo.NewCoin(txOut, 1, false), true)
-		view.UpdateCoins(coinsMap, hash)
+			if !amount.MoneyRange(cost) {
+				return nil, btcjson.RPCError{
+					Code:    btcjson.ErrRPCInvalidParameter,
+					Message: "Amount out of range",
+				}
+			}
+			view.AddCoin(out, utxo.NewCoin(txout.NewTxOut(cost, scriptPubKey), 1, false), true)
 
-		// If redeemScript given and not using the local wallet (private
-		// keys given), add redeemScript to the tempKeystore so it can be
-		// signed:
-		if givenKeys && script.NewScriptRaw(scriptPubKey).IsPayToScriptHash() {
-			if input.RedeemScript != "" {
+			// If redeemScript given and not using the local wallet (private
+			// keys given), add redeemScript to the tempKeystore so it can be
+			// signed:
+			if givenKeys && scriptPubKey.IsPayToScriptHash() && input.RedeemScript != "" {
 				rsData, err := hex.DecodeString(input.RedeemScript)
 				if err != nil {
 					return nil, rpcDecodeHexError(input.RedeemScript)
 				}
-				scriptStore = append(scriptStore, script.NewScriptRaw(rsData))
+				scriptStore[string(util.Hash160(rsData))] = string(rsData)
 			}
 		}
 	}
 
-	hashType, ok := mapSigHashValues[*c.Flags]
+	flags := "ALL|FORKID"
+	if c.Flags != nil {
+		flags = *c.Flags
+	}
+	hashType, ok := mapSigHashValues[flags]
 	if !ok {
 		return nil, btcjson.RPCError{
 			Code:    btcjson.ErrRPCInvalidParameter,
@@ -682,29 +864,17 @@ func handleSignRawTransaction(s *Server, cmd interface{}, closeChan <-chan struc
 		}
 	}
 
-	hashSingle := hashType & ^(crypto.SigHashAnyoneCanpay|crypto.SigHashForkID) == crypto.SigHashSingle
-
 	errors := make([]*btcjson.SignRawTransactionError, 0)
-	for index, in := range transactions[0].GetIns() {
-		view := utxo.GetUtxoCacheInstance()
-		coin := view.GetCoin(in.PreviousOutPoint)
-		if coin.IsSpent() {
-			errors = append(errors, TxInErrorToJSON(in, "Input not found or already spent"))
-			continue
+	signErrs := ltx.SignRawTransaction(transactions, view, scriptStore, keyStore, uint32(hashType))
+	for index, in := range mergedTx.GetIns() {
+		if signErrs[index] != nil {
+			errors = append(errors, TxInErrorToJSON(in, signErrorMessage(signErrs[index])))
 		}
-
-		//prevPubKey := coin.GetTxOut().GetScriptPubKey()
-		//cost := coin.GetTxOut().GetValue()
-
-		if !hashSingle || index < transactions[0].GetOutsCount() {
-			// todo make and check signature
-		}
-
 	}
 
 	complete := len(errors) == 0
 	buf := bytes.NewBuffer(nil)
-	err = transactions[0].Serialize(buf)
+	err = mergedTx.Serialize(buf)
 	if err != nil {
 		log.Error("rawTransaction:serialize tx[0] failed.")
 		return nil, err
@@ -716,9 +886,19 @@ func handleSignRawTransaction(s *Server, cmd interface{}, closeChan <-chan struc
 	}, err
 }
 
+func signErrorMessage(err error) string {
+	if errcode.IsErrorCode(err, errcode.TxErrNoPreviousOut) {
+		return "Input not found or already spent"
+	}
+	if projectError, ok := err.(errcode.ProjectError); ok {
+		return projectError.Desc
+	}
+	return err.Error()
+}
+
 func TxInErrorToJSON(in *txin.TxIn, errorMessage string) *btcjson.SignRawTransactionError {
 	return &btcjson.SignRawTransactionError{
-		TxID:      in.PreviousOutPoint.Hash.String(),
+		TxID:      util.TxID(in.PreviousOutPoint.Hash).String(),
 		Vout:      in.PreviousOutPoint.Index,
 		ScriptSig: hex.EncodeToString(in.GetScriptSig().GetData()),
 		Sequence:  in.Sequence,
@@ -726,6 +906,53 @@ func TxInErrorToJSON(in *txin.TxIn, errorMessage string) *btcjson.SignRawTransac
 	}
 }
 
+// txBlockIndex returns the index of the block holding the transaction txid,
+// the block with hash blockHash when given. Without a block hash the block is
+// found through an unspent output of the transaction or the transaction
+// index.
+func txBlockIndex(txid util.Hash, blockHash *string) (*blockindex.BlockIndex, error) {
+	if blockHash != nil {
+		hash, err := util.BlockHashFromStr(*blockHash)
+		if err != nil {
+			return nil, rpcDecodeHexError(*blockHash)
+		}
+
+		bindex := chain.GetInstance().FindBlockIndex(hash.Hash())
+		if bindex == nil {
+			return nil, btcjson.RPCError{
+				Code:    btcjson.RPCInvalidAddressOrKey,
+				Message: "Block not found",
+			}
+		}
+		return bindex, nil
+	}
+
+	var bindex *blockindex.BlockIndex
+	view := utxo.GetUtxoCacheInstance()
+	coin := lutxo.AccessByTxid(view, &txid)
+	if coin != nil && coin.GetHeight() > 0 && coin.GetHeight() <= chain.GetInstance().Height() {
+		bindex = chain.GetInstance().GetIndex(coin.GetHeight())
+	}
+
+	if bindex == nil {
+		_, hashBlock, ok := GetTransaction(&txid, false)
+		if !ok || hashBlock == nil {
+			return nil, btcjson.RPCError{
+				Code:    btcjson.RPCInvalidAddressOrKey,
+				Message: "Transaction not yet in block",
+			}
+		}
+		bindex = chain.GetInstance().FindBlockIndex(*hashBlock)
+		if bindex == nil {
+			return nil, btcjson.RPCError{
+				Code:    btcjson.RPCInternalError,
+				Message: "Transaction index corrupt",
+			}
+		}
+	}
+	return bindex, nil
+}
+
 func handleGetTxoutProof(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetTxOutProofCmd)
 
@@ -734,62 +961,27 @@ func handleGetTxoutProof(s *Server, cmd interface{}, closeChan <-chan struct{})
 	txIds := c.TxIDs
 
 	for _, txID := range txIds {
-		hash, err := util.GetHashFromStr(txID)
-		if len(txID) != 64 || err != nil {
+		id, err := util.TxIDFromStr(txID)
+		if err != nil {
 			return nil, &btcjson.RPCError{
 				Code:    btcjson.ErrRPCInvalidParameter,
 				Message: "Invalid txid " + txID,
 			}
 		}
-		if setTxIds.Has(*hash) {
+		hash := id.Hash()
+		if setTxIds.Has(hash) {
 			return nil, &btcjson.RPCError{
 				Code:    btcjson.ErrRPCInvalidParameter,
 				Message: "Invalid parameter, duplicated txid: " + txID,
 			}
 		}
-		setTxIds.Add(*hash)
-		oneTxID = *hash
+		setTxIds.Add(hash)
+		oneTxID = hash
 	}
 
-	var bindex *blockindex.BlockIndex
-	var hashBlock *util.Hash
-	if c.BlockHash != nil {
-		var err error
-		hashBlock, err = util.GetHashFromStr(*c.BlockHash)
-		if err != nil {
-			return nil, rpcDecodeHexError(*c.BlockHash)
-		}
-
-		bindex = chain.GetInstance().FindBlockIndex(*hashBlock)
-		if bindex == nil {
-			return nil, btcjson.RPCError{
-				Code:    btcjson.RPCInvalidAddressOrKey,
-				Message: "Block not found",
-			}
-		}
-	} else {
-		view := utxo.GetUtxoCacheInstance()
-		coin := lutxo.AccessByTxid(view, &oneTxID)
-		if !coin.IsSpent() && coin.GetHeight() > 0 && coin.GetHeight() <= chain.GetInstance().Height() {
-			bindex = chain.GetInstance().GetIndex(coin.GetHeight())
-		}
-
-		if bindex == nil {
-			_, hashBlock, ok := GetTransaction(&oneTxID, false)
-			if !ok || hashBlock == nil {
-				return nil, btcjson.RPCError{
-					Code:    btcjson.RPCInvalidAddressOrKey,
-					Message: "Transaction not yet in block",
-				}
-			}
-			bindex = chain.GetInstance().FindBlockIndex(*hashBlock)
-			if bindex == nil {
-				return nil, btcjson.RPCError{
-					Code:    btcjson.RPCInternalError,
-					Message: "Transaction index corrupt",
-				}
-			}
-		}
+	bindex, err := txBlockIndex(oneTxID, c.BlockHash)
+	if err != nil {
+		return nil, err
 	}
 
 	bk, ok := disk.ReadBlockFromDisk(bindex, chain.GetInstance().GetParams())
@@ -840,7 +1032,7 @@ func handleVerifyTxoutProof(s *Server, cmd interface{}, closeChan <-chan struct{
 	matches := make([]util.Hash, 0)
 	items := make([]int, 0)
 	if !mb.Txn.ExtractMatches(&matches, &items).IsEqual(&mb.Header.MerkleRoot) {
-		return nil, nil
+		return []string{}, nil
 	}
 
 	bindex := chain.GetInstance().FindBlockIndex(mb.Header.GetHash())
@@ -858,8 +1050,108 @@ func handleVerifyTxoutProof(s *Server, cmd interface{}, closeChan <-chan struct{
 	return ret, nil
 }
 
+func handleGetHexBlockHeaderChain(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetHexBlockHeaderChainCmd)
+
+	txid, err := util.TxIDFromStr(c.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.TxID)
+	}
+	nHeaders := int32(defaultProofHeaders)
+	if c.NHeaders != nil {
+		nHeaders = *c.NHeaders
+	}
+	if nHeaders < 0 || nHeaders > wire.MaxBlockHeadersPerMsg {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			fmt.Sprintf("nheaders must be between 0 and %d", wire.MaxBlockHeadersPerMsg))
+	}
+
+	bindex, err := txBlockIndex(txid.Hash(), c.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	gChain := chain.GetInstance()
+	// the headers following the block only prove anything on the best chain
+	if !gChain.Contains(bindex) {
+		return nil, btcjson.RPCError{
+			Code:    btcjson.RPCInvalidAddressOrKey,
+			Message: "Block not found in chain",
+		}
+	}
+
+	bk, ok := disk.ReadBlockFromDisk(bindex, gChain.GetParams())
+	if !ok {
+		return nil, btcjson.RPCError{
+			Code:    btcjson.RPCInternalError,
+			Message: "Can not read block from disk",
+		}
+	}
+	var transaction *tx.Tx
+	for _, t := range bk.Txs {
+		if t.GetHash() == txid.Hash() {
+			transaction = t
+			break
+		}
+	}
+	if transaction == nil {
+		return nil, btcjson.RPCError{
+			Code:    btcjson.RPCInvalidAddressOrKey,
+			Message: "Transaction not found in specified block",
+		}
+	}
+
+	txIds := set.New()
+	txIds.Add(txid.Hash())
+	proof := bytes.NewBuffer(nil)
+	lmerkleblock.NewMerkleBlock(bk, txIds).Serialize(proof)
+	rawTx := bytes.NewBuffer(nil)
+	if err := transaction.Serialize(rawTx); err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInternal.Code, err.Error())
+	}
+
+	headers := make([]string, 0, nHeaders)
+	for index := gChain.Next(bindex); index != nil && int32(len(headers)) < nHeaders; index = gChain.Next(index) {
+		buf := bytes.NewBuffer(nil)
+		if err := index.Header.Serialize(buf); err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInternal.Code, err.Error())
+		}
+		headers = append(headers, hex.EncodeToString(buf.Bytes()))
+	}
+
+	return &btcjson.GetHexBlockHeaderChainResult{
+		TxID:          txid.String(),
+		Hex:           hex.EncodeToString(rawTx.Bytes()),
+		BlockHash:     util.BlockHash(*bindex.GetBlockHash()).String(),
+		Height:        bindex.Height,
+		Confirmations: gChain.Height() - bindex.Height + 1,
+		TxOutProof:    hex.EncodeToString(proof.Bytes()),
+		Headers:       headers,
+	}, nil
+}
+
+func handleGetSpentInfo(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetSpentInfoCmd)
+	if !lchain.SpentIndexEnabled() {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCMisc, "Spent index not enabled")
+	}
+	txid, err := util.TxIDFromStr(c.Request.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.Request.TxID)
+	}
+
+	info, err := blkdb.GetInstance().ReadSpentIndex(outpoint.NewOutPoint(txid.Hash(), c.Request.Index))
+	if err != nil || info == nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "Unable to get spent info")
+	}
+	return &btcjson.SpentInfoResult{
+		TxID:   util.TxID(info.TxHash).String(),
+		Index:  info.Index,
+		Height: info.Height,
+	}, nil
+}
+
 func registeRawTransactionRPCCommands() {
 	for name, handler := range rawTransactionHandlers {
-		appendCommand(name, handler)
+		appendCommand("rawtransactions", name, handler)
 	}
 }
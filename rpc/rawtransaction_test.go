@@ -0,0 +1,551 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/errcode"
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/pow"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/net/wire"
+	"github.com/copernet/copernicus/persist"
+	"github.com/copernet/copernicus/persist/disk"
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+	"github.com/copernet/copernicus/util/amount"
+)
+
+func TestDecodeHexTx(t *testing.T) {
+	transaction := tx.NewTx(0, tx.TxVersion)
+	transaction.AddTxIn(txin.NewTxIn(&outpoint.OutPoint{Hash: util.HashOne, Index: 0},
+		script.NewScriptRaw([]byte{opcodes.OP_TRUE}), script.SequenceFinal))
+	transaction.AddTxOut(txout.NewTxOut(10000, script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+	buf := new(bytes.Buffer)
+	if err := transaction.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	rawTx := hex.EncodeToString(buf.Bytes())
+
+	tests := []struct {
+		name    string
+		hexTx   string
+		errCode btcjson.RPCErrorCode
+		errText string
+	}{
+		{"transaction", rawTx, 0, ""},
+		{"trailing byte", rawTx + "00", btcjson.ErrRPCDeserialization, "1 unexpected trailing bytes"},
+		{"trailing transaction", rawTx + rawTx, btcjson.ErrRPCDeserialization, "trailing bytes"},
+		{"truncated", rawTx[:len(rawTx)-2], btcjson.ErrRPCDeserialization, "TX decode failed"},
+		{"odd length", rawTx + "0", btcjson.ErrRPCDecodeHexString, "hexadecimal"},
+		{"not hex", "zz", btcjson.ErrRPCDecodeHexString, "hexadecimal"},
+	}
+	for _, test := range tests {
+		decoded, err := decodeHexTx(test.hexTx)
+		if test.errCode == 0 {
+			if err != nil || decoded.GetHash() != transaction.GetHash() {
+				t.Errorf("%s: decoded %v, error %v", test.name, decoded, err)
+			}
+			continue
+		}
+		rpcErr, ok := err.(*btcjson.RPCError)
+		if !ok || rpcErr.Code != test.errCode || !strings.Contains(rpcErr.Message, test.errText) {
+			t.Errorf("%s: got error %v, expect code %d with %q", test.name, err, test.errCode, test.errText)
+		}
+		if decoded != nil {
+			t.Errorf("%s: a transaction was returned along with the error", test.name)
+		}
+	}
+}
+
+func TestGetRawTransactions(t *testing.T) {
+	path, err := ioutil.TempDir("", "getrawtransactions")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	initWalkedCoins(t, path)
+	mempool.InitMempool()
+
+	transaction := tx.NewTx(0, tx.TxVersion)
+	transaction.AddTxIn(txin.NewTxIn(&outpoint.OutPoint{Hash: util.HashOne, Index: 0},
+		script.NewScriptRaw([]byte{opcodes.OP_TRUE}), script.SequenceFinal))
+	transaction.AddTxOut(txout.NewTxOut(10000, script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+	entry := mempool.NewTxentry(transaction, 1000, 100, 1, mempool.LockPoints{}, 0, false)
+	if err := mempool.GetInstance().AddTx(entry, map[*mempool.TxEntry]struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if err := transaction.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	known := util.TxID(transaction.GetHash()).String()
+	unknown := strings.Repeat("03", 32)
+
+	for _, verbose := range []bool{false, true} {
+		verbose := verbose
+		cmd := &btcjson.GetRawTransactionsCmd{Txids: []string{known, unknown, "zz", known}, Verbose: &verbose}
+		result, err := handleGetRawTransactions(nil, cmd, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries := result.(map[string]*btcjson.GetRawTransactionsEntry)
+		if len(entries) != 3 {
+			t.Errorf("verbose %t: got %d entries, expect one per distinct txid", verbose, len(entries))
+		}
+
+		found := entries[known]
+		if found == nil || found.Error != nil {
+			t.Fatalf("verbose %t: mempool transaction not returned: %+v", verbose, found)
+		}
+		if verbose {
+			raw, ok := found.Result.(*btcjson.TxRawResult)
+			if !ok || raw.TxID != known || raw.Hex != hex.EncodeToString(buf.Bytes()) || raw.Confirmations != 0 {
+				t.Errorf("verbose result %+v", found.Result)
+			}
+		} else if found.Result != hex.EncodeToString(buf.Bytes()) {
+			t.Errorf("hex result %v", found.Result)
+		}
+
+		errCodes := map[string]btcjson.RPCErrorCode{
+			unknown: btcjson.ErrRPCInvalidAddressOrKey,
+			"zz":    btcjson.ErrRPCDecodeHexString,
+		}
+		for txid, code := range errCodes {
+			e := entries[txid]
+			if e == nil || e.Result != nil || e.Error == nil || e.Error.Code != code {
+				t.Errorf("verbose %t: entry of %s is %+v, expect error code %d", verbose, txid, e, code)
+			}
+		}
+	}
+
+	tooMany := &btcjson.GetRawTransactionsCmd{Txids: make([]string, maxRawTransactionsPerRequest+1)}
+	_, err = handleGetRawTransactions(nil, tooMany, nil)
+	if rpcErr, ok := err.(*btcjson.RPCError); !ok || rpcErr.Code != btcjson.ErrRPCInvalidParameter {
+		t.Errorf("%d txids got error %v, expect an invalid parameter", len(tooMany.Txids), err)
+	}
+}
+
+func TestScriptPubKeyToJSONAsm(t *testing.T) {
+	// a DER signature with an ALL|FORKID sighash byte
+	sig := append([]byte{0x30, 0x44, 0x02, 0x20}, bytes.Repeat([]byte{0x01}, 32)...)
+	sig = append(sig, 0x02, 0x20)
+	sig = append(sig, bytes.Repeat([]byte{0x01}, 32)...)
+	sig = append(sig, 0x41)
+	sigPush := append([]byte{byte(len(sig))}, sig...)
+
+	tests := []struct {
+		name   string
+		script []byte
+		asm    string
+	}{
+		{"small number", []byte{0x01, 0x05}, "5"},
+		{"opcodes", []byte{opcodes.OP_DUP, opcodes.OP_HASH160}, "OP_DUP OP_HASH160"},
+		{"signature push", sigPush, hex.EncodeToString(sig)},
+		{"truncated push", []byte{opcodes.OP_TRUE, 0x02, 0x01}, "1 [error]"},
+		{"truncated pushdata", []byte{opcodes.OP_PUSHDATA1}, "[error]"},
+	}
+	for _, test := range tests {
+		result := ScriptPubKeyToJSON(script.NewScriptRaw(test.script), true)
+		if result.Asm != test.asm {
+			t.Errorf("%s: asm %q, expect %q", test.name, result.Asm, test.asm)
+		}
+		if result.Hex != hex.EncodeToString(test.script) {
+			t.Errorf("%s: hex %q", test.name, result.Hex)
+		}
+	}
+
+	// the sighash type is decoded in scriptSigs only
+	if asm := ScriptToAsmStr(script.NewScriptRaw(sigPush), true); asm != hex.EncodeToString(sig[:len(sig)-1])+"[ALL|FORKID]" {
+		t.Errorf("scriptSig asm %q", asm)
+	}
+}
+
+// storeTestBlock mines a block of txs on top of prev at the regtest limit,
+// writes it to disk at pos of the first block file and indexes it.
+func storeTestBlock(t *testing.T, prev *blockindex.BlockIndex, time uint32, txs []*tx.Tx,
+	pos uint32) *blockindex.BlockIndex {
+
+	params := chain.GetInstance().GetParams()
+	blk := block.NewBlock()
+	blk.Header = block.BlockHeader{HashPrevBlock: *prev.GetBlockHash(), Time: time, Bits: 0x207fffff}
+	blk.Txs = txs
+	for hash := blk.GetHash(); !new(pow.Pow).CheckProofOfWork(&hash, blk.Header.Bits, params); hash = blk.GetHash() {
+		blk.Header.Nonce++
+	}
+	if !disk.WriteBlockToDisk(blk, block.NewDiskBlockPos(0, pos)) {
+		t.Fatal("write block to disk failed")
+	}
+	index := blockindex.NewBlockIndex(&blk.Header)
+	index.Height = prev.Height + 1
+	index.Prev = prev
+	index.File = 0
+	index.DataPos = pos
+	index.AddStatus(blockindex.BlockHaveData)
+	if err := chain.GetInstance().AddToIndexMap(index); err != nil {
+		t.Fatal(err)
+	}
+	return index
+}
+
+func TestGetRawTransactionInBlock(t *testing.T) {
+	path, err := ioutil.TempDir("", "getrawtransaction")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	_, tip := initWalkedCoins(t, path)
+	defer func(dataDir string) { conf.Cfg.DataDir = dataDir }(conf.Cfg.DataDir)
+	conf.Cfg.DataDir = path
+
+	newTx := func(lockTime uint32) *tx.Tx {
+		transaction := tx.NewTx(lockTime, tx.TxVersion)
+		transaction.AddTxIn(txin.NewTxIn(&outpoint.OutPoint{Hash: util.HashOne, Index: lockTime},
+			script.NewScriptRaw([]byte{opcodes.OP_TRUE}), script.SequenceFinal))
+		transaction.AddTxOut(txout.NewTxOut(10000, script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+		return transaction
+	}
+	// the blocks are read back from disk with their proof of work checked,
+	// let them be mined at the regtest limit
+	params := chain.GetInstance().GetParams()
+	defer func(powLimit *big.Int) { params.PowLimit = powLimit }(params.PowLimit)
+	params.PowLimit = model.RegressionNetParams.PowLimit
+	persist.InitPersistGlobal()
+
+	// store a block on top of tip and one on a side branch, holding a
+	// transaction each
+	inActive, inSide := newTx(1), newTx(2)
+	active := storeTestBlock(t, tip, tip.Header.Time+600, []*tx.Tx{inActive}, 0)
+	side := storeTestBlock(t, tip, tip.Header.Time+601, []*tx.Tx{inSide}, 4096)
+	chain.GetInstance().SetTip(active)
+
+	txid := func(transaction *tx.Tx) string { return util.TxID(transaction.GetHash()).String() }
+	blockHash := func(index *blockindex.BlockIndex) string { return util.BlockHash(*index.GetBlockHash()).String() }
+	tests := []struct {
+		name          string
+		txid          string
+		blockHash     string
+		errCode       btcjson.RPCErrorCode
+		inActiveChain bool
+	}{
+		{"malformed block hash", txid(inActive), "zz", btcjson.ErrRPCDecodeHexString, false},
+		{"unknown block", txid(inActive), strings.Repeat("03", 32), btcjson.ErrRPCInvalidAddressOrKey, false},
+		{"block without data", txid(inActive), blockHash(tip), btcjson.ErrRPCMisc, false},
+		{"transaction of another block", txid(inSide), blockHash(active), btcjson.ErrRPCInvalidAddressOrKey, false},
+		{"active block", txid(inActive), blockHash(active), 0, true},
+		{"side block", txid(inSide), blockHash(side), 0, false},
+	}
+	verbose := true
+	for _, test := range tests {
+		hash := test.blockHash
+		cmd := &btcjson.GetRawTransactionCmd{Txid: test.txid, Verbose: &verbose, BlockHash: &hash}
+		result, err := handleGetRawTransaction(nil, cmd, nil)
+		if test.errCode != 0 {
+			if err == nil || toRPCError(err).Code != test.errCode {
+				t.Errorf("%s: got error %v, expect code %d", test.name, err, test.errCode)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		raw := result.(*btcjson.TxRawResult)
+		if raw.TxID != test.txid || raw.BlockHash != test.blockHash {
+			t.Errorf("%s: got transaction %s of block %s", test.name, raw.TxID, raw.BlockHash)
+		}
+		if raw.InActiveChain == nil || *raw.InActiveChain != test.inActiveChain {
+			t.Errorf("%s: in_active_chain %v, expect %t", test.name, raw.InActiveChain, test.inActiveChain)
+		}
+	}
+}
+
+func TestCreateRawTransactionVersion(t *testing.T) {
+	txid := util.TxID(util.HashOne).String()
+	relative := int64(10)
+	cmd := &btcjson.CreateRawTransactionCmd{
+		Inputs: []btcjson.TransactionInput{
+			{Txid: txid, Vout: 0, Sequence: &relative},
+			{Txid: txid, Vout: 1},
+		},
+		Outputs: btcjson.TransactionOutputs{{Key: "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", Value: 0.1}},
+	}
+	result, err := handleCreateRawTransaction(nil, cmd, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := decodeHexTx(result.(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sequences only lock relatively in version 2 transactions
+	if created.GetVersion() != tx.MaxStandardVersion || created.GetVersion() < 2 {
+		t.Errorf("created a version %d transaction, expect %d", created.GetVersion(), tx.MaxStandardVersion)
+	}
+	ins := created.GetIns()
+	if len(ins) != 2 || ins[0].Sequence != uint32(relative) || ins[1].Sequence != script.SequenceFinal {
+		t.Errorf("got inputs %v, expect the sequences %d and %d", ins, relative, script.SequenceFinal)
+	}
+}
+
+func TestCreateRawTransactionLockTime(t *testing.T) {
+	txid := util.TxID(util.HashOne).String()
+	sequence := int64(5)
+	outputs := btcjson.TransactionOutputs{{Key: "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", Value: 0.1}}
+	lockTime := func(n int64) *int64 { return &n }
+
+	tests := []struct {
+		name      string
+		lockTime  *int64
+		lock      uint32
+		sequences []uint32
+		errText   string
+	}{
+		{"no locktime", nil, 0, []uint32{5, script.SequenceFinal}, ""},
+		{"zero locktime", lockTime(0), 0, []uint32{5, script.SequenceFinal}, ""},
+		{"locktime", lockTime(500000), 500000, []uint32{5, script.SequenceFinal - 1}, ""},
+		{"max locktime", lockTime(int64(script.SequenceFinal)), script.SequenceFinal,
+			[]uint32{5, script.SequenceFinal - 1}, ""},
+		{"negative locktime", lockTime(-1), 0, nil, "locktime out of range"},
+		{"locktime too large", lockTime(int64(script.SequenceFinal) + 1), 0, nil, "locktime out of range"},
+	}
+	for _, test := range tests {
+		cmd := &btcjson.CreateRawTransactionCmd{
+			Inputs: []btcjson.TransactionInput{
+				{Txid: txid, Vout: 0, Sequence: &sequence},
+				{Txid: txid, Vout: 1},
+			},
+			Outputs:  outputs,
+			LockTime: test.lockTime,
+		}
+		result, err := handleCreateRawTransaction(nil, cmd, nil)
+		if test.errText != "" {
+			rpcErr := toRPCError(err)
+			if rpcErr == nil || rpcErr.Code != btcjson.ErrRPCInvalidParameter ||
+				!strings.Contains(rpcErr.Message, test.errText) {
+				t.Errorf("%s: got error %v, expect %q", test.name, err, test.errText)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		created, err := decodeHexTx(result.(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ins := created.GetIns()
+		if created.GetLockTime() != test.lock || ins[0].Sequence != test.sequences[0] ||
+			ins[1].Sequence != test.sequences[1] {
+			t.Errorf("%s: locktime %d with sequences %d and %d, expect %d and %v", test.name,
+				created.GetLockTime(), ins[0].Sequence, ins[1].Sequence, test.lock, test.sequences)
+		}
+	}
+}
+
+func TestGenerateToAddressScriptHash(t *testing.T) {
+	maxTries := uint64(1)
+	for _, address := range []string{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", "31nwvkZwyPdgzjBJZXfDmSWsC4ZLKpYyUw"} {
+		cmd := &btcjson.GenerateToAddressCmd{NumBlocks: 0, Address: address, MaxTries: &maxTries}
+		result, err := handleGenerateToAddress(nil, cmd, nil)
+		if err != nil || len(result.([]string)) != 0 {
+			t.Errorf("generate to %s got %v, error %v", address, result, err)
+		}
+	}
+
+	cmd := &btcjson.GenerateToAddressCmd{NumBlocks: 1, Address: "not an address", MaxTries: &maxTries}
+	_, err := handleGenerateToAddress(nil, cmd, nil)
+	if rpcErr := toRPCError(err); rpcErr == nil || rpcErr.Code != btcjson.RPCInvalidAddressOrKey {
+		t.Errorf("generate to an invalid address got error %v", err)
+	}
+}
+
+func TestGetHexBlockHeaderChain(t *testing.T) {
+	path, err := ioutil.TempDir("", "gethexblockheaderchain")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	_, tip := initWalkedCoins(t, path)
+	defer func(dataDir string) { conf.Cfg.DataDir = dataDir }(conf.Cfg.DataDir)
+	conf.Cfg.DataDir = path
+	params := chain.GetInstance().GetParams()
+	defer func(powLimit *big.Int) { params.PowLimit = powLimit }(params.PowLimit)
+	params.PowLimit = model.RegressionNetParams.PowLimit
+	persist.InitPersistGlobal()
+
+	newTx := func(index uint32) *tx.Tx {
+		transaction := tx.NewTx(0, tx.TxVersion)
+		transaction.AddTxIn(txin.NewTxIn(&outpoint.OutPoint{Hash: util.HashOne, Index: index},
+			script.NewScriptRaw([]byte{opcodes.OP_TRUE}), script.SequenceFinal))
+		transaction.AddTxOut(txout.NewTxOut(10000, script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+		return transaction
+	}
+	// the transaction is in a block followed by two blocks on the active
+	// chain, another one is in a side block
+	first, second, inSide := newTx(1), newTx(2), newTx(3)
+	proven := storeTestBlock(t, tip, tip.Header.Time+600, []*tx.Tx{first, second}, 0)
+	side := storeTestBlock(t, tip, tip.Header.Time+601, []*tx.Tx{inSide}, 4096)
+	next := storeTestBlock(t, proven, proven.Header.Time+600, []*tx.Tx{newTx(4)}, 8192)
+	last := storeTestBlock(t, next, next.Header.Time+600, []*tx.Tx{newTx(5)}, 12288)
+	chain.GetInstance().SetTip(last)
+
+	headerHex := func(index *blockindex.BlockIndex) string {
+		buf := new(bytes.Buffer)
+		if err := index.Header.Serialize(buf); err != nil {
+			t.Fatal(err)
+		}
+		return hex.EncodeToString(buf.Bytes())
+	}
+	txid := func(transaction *tx.Tx) string { return util.TxID(transaction.GetHash()).String() }
+	blockHash := func(index *blockindex.BlockIndex) string { return util.BlockHash(*index.GetBlockHash()).String() }
+	nHeaders := func(n int32) *int32 { return &n }
+	tests := []struct {
+		name      string
+		txid      string
+		blockHash string
+		nHeaders  *int32
+		headers   []string
+		errCode   btcjson.RPCErrorCode
+	}{
+		{"default headers", txid(second), blockHash(proven), nil, []string{headerHex(next), headerHex(last)}, 0},
+		{"one header", txid(second), blockHash(proven), nHeaders(1), []string{headerHex(next)}, 0},
+		{"no header", txid(first), blockHash(proven), nHeaders(0), []string{}, 0},
+		{"negative headers", txid(second), blockHash(proven), nHeaders(-1), nil, btcjson.ErrRPCInvalidParameter},
+		{"too many headers", txid(second), blockHash(proven), nHeaders(wire.MaxBlockHeadersPerMsg + 1), nil,
+			btcjson.ErrRPCInvalidParameter},
+		{"malformed txid", "zz", blockHash(proven), nil, nil, btcjson.ErrRPCDecodeHexString},
+		{"unknown block", txid(second), strings.Repeat("03", 32), nil, nil, btcjson.ErrRPCInvalidAddressOrKey},
+		{"side block", txid(inSide), blockHash(side), nil, nil, btcjson.ErrRPCInvalidAddressOrKey},
+		{"transaction of another block", txid(second), blockHash(next), nil, nil, btcjson.ErrRPCInvalidAddressOrKey},
+	}
+	for _, test := range tests {
+		hash := test.blockHash
+		cmd := &btcjson.GetHexBlockHeaderChainCmd{TxID: test.txid, NHeaders: test.nHeaders, BlockHash: &hash}
+		ret, err := handleGetHexBlockHeaderChain(nil, cmd, nil)
+		if test.errCode != 0 {
+			if rpcErr := toRPCError(err); rpcErr == nil || rpcErr.Code != test.errCode {
+				t.Errorf("%s: got error %v, expect code %d", test.name, err, test.errCode)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		result := ret.(*btcjson.GetHexBlockHeaderChainResult)
+		if result.TxID != test.txid || result.BlockHash != test.blockHash || result.Height != proven.Height ||
+			result.Confirmations != 3 {
+			t.Errorf("%s: got %s in %s at %d with %d confirmations", test.name, result.TxID, result.BlockHash,
+				result.Height, result.Confirmations)
+		}
+		// the proof is a merkle block, which starts with the header of the
+		// block
+		if !strings.HasPrefix(result.TxOutProof, headerHex(proven)) {
+			t.Errorf("%s: proof %s is not of the block", test.name, result.TxOutProof)
+		}
+		if !reflect.DeepEqual(result.Headers, test.headers) {
+			t.Errorf("%s: got headers %v, expect %v", test.name, result.Headers, test.headers)
+		}
+	}
+}
+
+func TestDecodeRawTransactionFee(t *testing.T) {
+	path, err := ioutil.TempDir("", "decoderawtransaction")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	initWalkedCoins(t, path)
+	mempool.InitMempool()
+
+	newTx := func(value int64, prevs ...*outpoint.OutPoint) *tx.Tx {
+		transaction := tx.NewTx(0, tx.TxVersion)
+		for _, prev := range prevs {
+			transaction.AddTxIn(txin.NewTxIn(prev, script.NewScriptRaw([]byte{opcodes.OP_TRUE}), script.SequenceFinal))
+		}
+		transaction.AddTxOut(txout.NewTxOut(amount.Amount(value), script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+		return transaction
+	}
+	hash1, hash2 := *util.HashFromString("01"), *util.HashFromString("02")
+	// a mempool transaction spending the coinbase coin of 1000
+	inPool := newTx(900, outpoint.NewOutPoint(hash1, 0))
+	entry := mempool.NewTxentry(inPool, 100, 100, 1, mempool.LockPoints{}, 0, false)
+	if err := mempool.GetInstance().AddTx(entry, map[*mempool.TxEntry]struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	coinbase := newTx(5000, outpoint.NewOutPoint(util.Hash{}, math.MaxUint32))
+
+	tests := []struct {
+		name        string
+		transaction *tx.Tx
+		fee         int64
+		known       bool
+	}{
+		{"coins", newTx(450, outpoint.NewOutPoint(hash2, 0), outpoint.NewOutPoint(hash2, 1)), 50, true},
+		{"mempool output", newTx(800, outpoint.NewOutPoint(inPool.GetHash(), 0)), 100, true},
+		{"coin and mempool output", newTx(1000, outpoint.NewOutPoint(hash2, 0),
+			outpoint.NewOutPoint(inPool.GetHash(), 0)), 200, true},
+		{"unknown output", newTx(100, outpoint.NewOutPoint(hash2, 0), outpoint.NewOutPoint(hash2, 2)), 0, false},
+		{"coinbase", coinbase, 0, false},
+	}
+	for _, test := range tests {
+		buf := new(bytes.Buffer)
+		if err := test.transaction.Serialize(buf); err != nil {
+			t.Fatal(err)
+		}
+		cmd := &btcjson.DecodeRawTransactionCmd{HexTx: hex.EncodeToString(buf.Bytes())}
+		ret, err := handleDecodeRawTransaction(nil, cmd, nil)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		fee := ret.(*btcjson.TxRawDecodeResult).Fee
+		if !test.known {
+			if fee != nil {
+				t.Errorf("%s: got fee %v of unknown inputs", test.name, *fee)
+			}
+			continue
+		}
+		if fee == nil || *fee != valueFromAmount(test.fee) {
+			t.Errorf("%s: got fee %v, expect %v", test.name, fee, valueFromAmount(test.fee))
+		}
+	}
+}
+
+func TestTxRejectedError(t *testing.T) {
+	tests := []struct {
+		err     error
+		code    btcjson.RPCErrorCode
+		message string
+	}{
+		{errcode.New(errcode.TxErrRejectDust), btcjson.RPCTransactionRejected,
+			"65: " + errcode.TxErrRejectDust.String()},
+		{errcode.New(errcode.TxErrScriptCheckTimeout), btcjson.RPCTransactionError,
+			errcode.TxErrScriptCheckTimeout.String()},
+	}
+	for _, test := range tests {
+		rpcErr := txRejectedError(test.err)
+		if rpcErr.Code != test.code || rpcErr.Message != test.message {
+			t.Errorf("%v: got %v, expect %d %q", test.err, rpcErr, test.code, test.message)
+		}
+	}
+}
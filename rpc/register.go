@@ -12,16 +12,22 @@ type commandHandler func(*Server, interface{}, <-chan struct{}) (interface{}, er
 // a dependency loop.
 var rpcHandlers = map[string]commandHandler{}
 
-func appendCommand(name string, cmd commandHandler) bool {
+// rpcCategories maps RPC command strings to the category they are listed
+// under by help.
+var rpcCategories = map[string]string{}
+
+func appendCommand(category string, name string, cmd commandHandler) bool {
 	if _, ok := rpcHandlers[name]; ok {
 		return false
 	}
 	rpcHandlers[name] = cmd
+	rpcCategories[name] = category
 	return true
 }
 
 func registerAllRPCCommands() {
 	registerABCRPCCommands()
+	registerAddressIndexRPCCommands()
 	registerBlockchainRPCCommands()
 	registerMiningRPCCommands()
 	registerMiscRPCCommands()
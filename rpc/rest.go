@@ -0,0 +1,395 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+)
+
+// restPath is the HTTP path of the public REST interface, compatible with the
+// one of bitcoind.  When RPCRest is set it is served without authentication,
+// the lookups sharing the backend of the RPC commands:
+//
+//	GET /rest/tx/<txid>.<bin|hex|json>
+//	GET /rest/block/<hash>.<bin|hex|json>
+//	GET /rest/block/notxdetails/<hash>.<bin|hex|json>
+//	GET /rest/headers/<count>/<hash>.<bin|hex|json>
+//	GET /rest/chaininfo.json
+//	GET /rest/getutxos[/checkmempool]/<txid>-<n>/<txid>-<n>/....<bin|hex|json>
+const restPath = "/rest/"
+
+const (
+	// maxRESTHeaders is the number of headers /rest/headers returns at
+	// most.
+	maxRESTHeaders = 2000
+
+	// maxRESTOutPoints is the number of outpoints /rest/getutxos looks up
+	// at most.
+	maxRESTOutPoints = 15
+
+	// restMempoolHeight is the height /rest/getutxos reports for the
+	// outputs of mempool transactions.
+	restMempoolHeight = 0x7fffffff
+)
+
+// restFormat is the output format of a REST request, given by the extension
+// of its path.
+type restFormat int
+
+const (
+	restBinary restFormat = iota
+	restHex
+	restJSON
+)
+
+var restFormats = map[string]restFormat{
+	"bin":  restBinary,
+	"hex":  restHex,
+	"json": restJSON,
+}
+
+// restError is an error of a REST request answered with its HTTP status.
+type restError struct {
+	status  int
+	message string
+}
+
+func (e *restError) Error() string {
+	return e.message
+}
+
+var errRESTFormat = &restError{http.StatusNotFound,
+	"output format not found (available: bin, hex, json)"}
+
+// restUTXOsResult is the answer to a /rest/getutxos request in JSON.  The
+// bitmap tells with a 0 or a 1 per requested outpoint whether it is unspent,
+// the utxos describing the unspent ones in order.
+type restUTXOsResult struct {
+	ChainHeight  int32      `json:"chainHeight"`
+	ChainTipHash string     `json:"chaintipHash"`
+	Bitmap       string     `json:"bitmap"`
+	UTXOs        []restUTXO `json:"utxos"`
+}
+
+type restUTXO struct {
+	Height       int32                      `json:"height"`
+	Value        float64                    `json:"value"`
+	ScriptPubKey btcjson.ScriptPubKeyResult `json:"scriptPubKey"`
+}
+
+// splitRESTFormat splits the last parameter of a REST path in its value and
+// its format, returning false when the extension is not a known format.
+func splitRESTFormat(param string) (string, restFormat, bool) {
+	i := strings.LastIndexByte(param, '.')
+	if i < 0 {
+		return param, restBinary, false
+	}
+	format, ok := restFormats[param[i+1:]]
+	return param[:i], format, ok
+}
+
+// isRESTTxLookup returns whether the request looks up a transaction through
+// the REST interface rather than querying the result of a submission, which
+// share the restTxPath.
+func isRESTTxLookup(r *http.Request) bool {
+	if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, restTxPath+"/") {
+		return false
+	}
+	_, _, ok := splitRESTFormat(strings.TrimPrefix(r.URL.Path, restTxPath+"/"))
+	return ok
+}
+
+// handleREST serves the requests of the REST interface.
+func (s *Server) handleREST(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "405 Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, restPath)
+	var err error
+	switch {
+	case strings.HasPrefix(path, "tx/"):
+		err = restTx(w, strings.TrimPrefix(path, "tx/"))
+	case strings.HasPrefix(path, "block/notxdetails/"):
+		err = restBlock(w, strings.TrimPrefix(path, "block/notxdetails/"), false)
+	case strings.HasPrefix(path, "block/"):
+		err = restBlock(w, strings.TrimPrefix(path, "block/"), true)
+	case strings.HasPrefix(path, "headers/"):
+		err = restHeaders(w, strings.TrimPrefix(path, "headers/"))
+	case strings.HasPrefix(path, "chaininfo."):
+		err = s.restChainInfo(w, path)
+	case strings.HasPrefix(path, "getutxos"):
+		err = restGetUTXOs(w, strings.TrimPrefix(path, "getutxos"))
+	default:
+		http.Error(w, "404 Not found.", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeRESTError(w, err)
+	}
+}
+
+func restTx(w http.ResponseWriter, param string) error {
+	txid, format, ok := splitRESTFormat(param)
+	if !ok {
+		return errRESTFormat
+	}
+	transaction, hashBlock, err := lookupTransaction(txid, nil)
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := transaction.Serialize(buf); err != nil {
+		return err
+	}
+	var result interface{}
+	if format == restJSON {
+		result, err = getTxRawResult(transaction, hashBlock,
+			hex.EncodeToString(buf.Bytes()), newChainSnapshot())
+		if err != nil {
+			return err
+		}
+	}
+	writeRESTResult(w, format, buf.Bytes(), result)
+	return nil
+}
+
+func restBlock(w http.ResponseWriter, param string, txDetails bool) error {
+	hash, format, ok := splitRESTFormat(param)
+	if !ok {
+		return errRESTFormat
+	}
+	blk, blockIndex, err := readBlock(hash)
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := blk.Serialize(buf); err != nil {
+		return err
+	}
+	var result interface{}
+	if format == restJSON {
+		blockReply := blockToJSON(blk, blockIndex, newChainSnapshot())
+		result = blockReply
+		if txDetails {
+			result, err = blockToJSONWithTxs(blk, blockReply)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	writeRESTResult(w, format, buf.Bytes(), result)
+	return nil
+}
+
+// restHeaders serves the headers of the active chain starting at the block
+// with the given hash, none when it is not in the active chain.
+func restHeaders(w http.ResponseWriter, param string) error {
+	params := strings.SplitN(param, "/", 2)
+	if len(params) != 2 {
+		return &restError{http.StatusBadRequest,
+			"Invalid URI format. Expected /rest/headers/<count>/<hash>.<ext>"}
+	}
+	count, err := strconv.Atoi(params[0])
+	if err != nil || count < 1 || count > maxRESTHeaders {
+		return &restError{http.StatusBadRequest,
+			fmt.Sprintf("Header count out of range: %s", params[0])}
+	}
+	hashStr, format, ok := splitRESTFormat(params[1])
+	if !ok {
+		return errRESTFormat
+	}
+	hash, err := util.BlockHashFromStr(hashStr)
+	if err != nil {
+		return rpcDecodeHexError(hashStr)
+	}
+
+	snapshot := newChainSnapshot()
+	var headers []*blockindex.BlockIndex
+	index := chain.GetInstance().FindBlockIndex(hash.Hash())
+	for index != nil && len(headers) < count && snapshot.Contains(index) {
+		headers = append(headers, index)
+		index = snapshot.Next(index)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	results := make([]*btcjson.GetBlockHeaderVerboseResult, 0, len(headers))
+	for _, header := range headers {
+		if err := header.Header.Serialize(buf); err != nil {
+			return err
+		}
+		results = append(results, blockHeaderToJSON(header, snapshot))
+	}
+	writeRESTResult(w, format, buf.Bytes(), results)
+	return nil
+}
+
+func (s *Server) restChainInfo(w http.ResponseWriter, param string) error {
+	if _, format, ok := splitRESTFormat(param); !ok || format != restJSON {
+		return &restError{http.StatusNotFound, "output format not found (available: json)"}
+	}
+	result, err := handleGetBlockChainInfo(s, nil, nil)
+	if err != nil {
+		return err
+	}
+	writeRESTResult(w, restJSON, nil, result)
+	return nil
+}
+
+// restGetUTXOs serves which of the given outpoints are unspent in the UTXO set,
+// or in the mempool with checkmempool, along with their outputs.
+func restGetUTXOs(w http.ResponseWriter, param string) error {
+	param, format, ok := splitRESTFormat(param)
+	if !ok {
+		return errRESTFormat
+	}
+	params := strings.Split(strings.TrimPrefix(param, "/"), "/")
+	checkMempool := params[0] == "checkmempool"
+	if checkMempool {
+		params = params[1:]
+	}
+	if len(params) == 0 || params[0] == "" {
+		return &restError{http.StatusBadRequest, "Error: empty request"}
+	}
+	if len(params) > maxRESTOutPoints {
+		return &restError{http.StatusBadRequest, fmt.Sprintf(
+			"Error: max outpoints exceeded (max: %d, tried: %d)",
+			maxRESTOutPoints, len(params))}
+	}
+
+	outPoints := make([]*outpoint.OutPoint, 0, len(params))
+	for _, param := range params {
+		i := strings.LastIndexByte(param, '-')
+		if i < 0 {
+			return &restError{http.StatusBadRequest, "Parse error"}
+		}
+		txid, err := util.TxIDFromStr(param[:i])
+		if err != nil {
+			return &restError{http.StatusBadRequest, "Parse error"}
+		}
+		n, err := strconv.ParseUint(param[i+1:], 10, 32)
+		if err != nil {
+			return &restError{http.StatusBadRequest, "Parse error"}
+		}
+		outPoints = append(outPoints, outpoint.NewOutPoint(txid.Hash(), uint32(n)))
+	}
+
+	tip := chain.GetInstance().Tip()
+	bitmap := make([]byte, (len(outPoints)+7)/8)
+	bitmapText := make([]byte, len(outPoints))
+	coins := make([]*utxo.Coin, 0, len(outPoints))
+	for i, outPoint := range outPoints {
+		coin := getUnspentCoin(outPoint, checkMempool)
+		if coin == nil {
+			bitmapText[i] = '0'
+			continue
+		}
+		bitmap[i/8] |= 1 << uint(i%8)
+		bitmapText[i] = '1'
+		coins = append(coins, coin)
+	}
+
+	if format == restJSON {
+		result := &restUTXOsResult{
+			ChainHeight:  tip.Height,
+			ChainTipHash: tip.GetBlockHash().String(),
+			Bitmap:       string(bitmapText),
+			UTXOs:        make([]restUTXO, 0, len(coins)),
+		}
+		for _, coin := range coins {
+			result.UTXOs = append(result.UTXOs, restUTXO{
+				Height:       restCoinHeight(coin),
+				Value:        valueFromAmount(int64(coin.GetAmount())),
+				ScriptPubKey: ScriptPubKeyToJSON(coin.GetScriptPubKey(), true),
+			})
+		}
+		writeRESTResult(w, format, nil, result)
+		return nil
+	}
+
+	// The serialization of bitcoind, whose coins carry a dummy transaction
+	// version.
+	buf := bytes.NewBuffer(nil)
+	if err := util.WriteElements(buf, tip.Height); err != nil {
+		return err
+	}
+	buf.Write(tip.GetBlockHash()[:])
+	if err := util.WriteVarBytes(buf, bitmap); err != nil {
+		return err
+	}
+	if err := util.WriteVarInt(buf, uint64(len(coins))); err != nil {
+		return err
+	}
+	for _, coin := range coins {
+		if err := util.WriteElements(buf, uint32(0), uint32(restCoinHeight(coin))); err != nil {
+			return err
+		}
+		txOut := coin.GetTxOut()
+		if err := txOut.Serialize(buf); err != nil {
+			return err
+		}
+	}
+	writeRESTResult(w, format, buf.Bytes(), nil)
+	return nil
+}
+
+func restCoinHeight(coin *utxo.Coin) int32 {
+	if coin.IsMempoolCoin() {
+		return restMempoolHeight
+	}
+	return coin.GetHeight()
+}
+
+// writeRESTResult writes the serialized data in the binary and hex formats,
+// or the result in JSON.
+func writeRESTResult(w http.ResponseWriter, format restFormat, data []byte, result interface{}) {
+	var err error
+	switch format {
+	case restBinary:
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, err = w.Write(data)
+	case restHex:
+		w.Header().Set("Content-Type", "text/plain")
+		_, err = fmt.Fprintln(w, hex.EncodeToString(data))
+	case restJSON:
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(result)
+	}
+	if err != nil {
+		log.Warn("Failed to write a REST result: %v", err)
+	}
+}
+
+// writeRESTError answers a failed REST request, the errors of the RPC lookups
+// being mapped to the HTTP status of their code.
+func writeRESTError(w http.ResponseWriter, err error) {
+	if restErr, ok := err.(*restError); ok {
+		http.Error(w, fmt.Sprintf("%d %s.", restErr.status, restErr.message), restErr.status)
+		return
+	}
+
+	rpcErr := toRPCError(err)
+	status := http.StatusInternalServerError
+	switch rpcErr.Code {
+	case btcjson.ErrRPCDecodeHexString, btcjson.ErrRPCInvalidParameter:
+		status = http.StatusBadRequest
+	case btcjson.ErrRPCInvalidAddressOrKey, btcjson.ErrRPCMisc:
+		status = http.StatusNotFound
+	}
+	http.Error(w, fmt.Sprintf("%d %s.", status, rpcErr.Message), status)
+}
@@ -0,0 +1,180 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+)
+
+func TestSplitRESTFormat(t *testing.T) {
+	tests := []struct {
+		param  string
+		value  string
+		format restFormat
+		ok     bool
+	}{
+		{"00ff.bin", "00ff", restBinary, true},
+		{"00ff.hex", "00ff", restHex, true},
+		{"a.b.json", "a.b", restJSON, true},
+		{"00ff.xml", "00ff", restBinary, false},
+		{"00ff", "00ff", restBinary, false},
+	}
+	for _, test := range tests {
+		value, format, ok := splitRESTFormat(test.param)
+		if ok != test.ok || (ok && (value != test.value || format != test.format)) {
+			t.Errorf("%s: got %q, %d and %t", test.param, value, format, ok)
+		}
+	}
+
+	lookups := []struct {
+		method string
+		path   string
+		lookup bool
+	}{
+		{"GET", restTxPath + "/00ff.json", true},
+		{"GET", restTxPath + "/00ff", false},
+		{"POST", restTxPath + "/00ff.hex", false},
+		{"GET", restTxPath, false},
+	}
+	for _, test := range lookups {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if lookup := isRESTTxLookup(r); lookup != test.lookup {
+			t.Errorf("%s %s: lookup %t, expect %t", test.method, test.path, lookup, test.lookup)
+		}
+	}
+}
+
+func TestHandleRESTErrors(t *testing.T) {
+	tooMany := strings.TrimSuffix(strings.Repeat(strings.Repeat("01", 32)+"-0/", maxRESTOutPoints+1), "/")
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{"not a lookup", "POST", "tx/00.json", http.StatusMethodNotAllowed},
+		{"unknown resource", "GET", "nosuchresource", http.StatusNotFound},
+		{"unknown format", "GET", "tx/00.xml", http.StatusNotFound},
+		{"malformed txid", "GET", "tx/zz.json", http.StatusBadRequest},
+		{"malformed block hash", "GET", "block/zz.hex", http.StatusBadRequest},
+		{"chain info in binary", "GET", "chaininfo.bin", http.StatusNotFound},
+		{"headers without a count", "GET", "headers/00.json", http.StatusBadRequest},
+		{"too many headers", "GET", "headers/2001/00.json", http.StatusBadRequest},
+		{"no header", "GET", "headers/0/00.json", http.StatusBadRequest},
+		{"malformed header hash", "GET", "headers/1/zz.json", http.StatusBadRequest},
+		{"no outpoint", "GET", "getutxos/checkmempool.json", http.StatusBadRequest},
+		{"too many outpoints", "GET", "getutxos/" + tooMany + ".json", http.StatusBadRequest},
+		{"outpoint without index", "GET", "getutxos/" + strings.Repeat("01", 32) + ".json", http.StatusBadRequest},
+		{"malformed outpoint index", "GET", "getutxos/" + strings.Repeat("01", 32) + "-x.json", http.StatusBadRequest},
+	}
+	s := &Server{}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		s.handleREST(w, httptest.NewRequest(test.method, restPath+test.path, nil))
+		if w.Code != test.status {
+			t.Errorf("%s: status %d, expect %d: %s", test.name, w.Code, test.status, w.Body)
+		}
+	}
+}
+
+func TestWriteRESTError(t *testing.T) {
+	tests := []struct {
+		err   error
+		reply string
+	}{
+		{errRESTFormat, "404 " + errRESTFormat.message + ".\n"},
+		{btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "invalid"), "400 invalid.\n"},
+		{btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey, "unknown"), "404 unknown.\n"},
+		{btcjson.NewRPCError(btcjson.ErrRPCDatabase, "broken"), "500 broken.\n"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		writeRESTError(w, test.err)
+		if w.Body.String() != test.reply || !strings.HasPrefix(test.reply, strconv.Itoa(w.Code)) {
+			t.Errorf("%v: got %d %q, expect %q", test.err, w.Code, w.Body, test.reply)
+		}
+	}
+}
+
+func TestRESTHeadersAndUTXOs(t *testing.T) {
+	path, err := ioutil.TempDir("", "rest")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	genesis, tip := initWalkedCoins(t, path)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		(&Server{}).handleREST(w, httptest.NewRequest("GET", restPath+path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", path, w.Code, w.Body)
+		}
+		return w
+	}
+
+	// the headers stop at the tip
+	genesisHash := util.BlockHash(*genesis.GetBlockHash()).String()
+	var headers []btcjson.GetBlockHeaderVerboseResult
+	if err := json.Unmarshal(get("headers/5/"+genesisHash+".json").Body.Bytes(), &headers); err != nil {
+		t.Fatal(err)
+	}
+	if len(headers) != 2 || headers[0].Hash != genesisHash ||
+		headers[1].Hash != util.BlockHash(*tip.GetBlockHash()).String() {
+		t.Errorf("got headers %+v, expect the genesis and the tip", headers)
+	}
+	w := get("headers/1/" + genesisHash + ".bin")
+	buf := bytes.NewBuffer(nil)
+	if err := genesis.Header.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(w.Body.Bytes(), buf.Bytes()) {
+		t.Errorf("got header %x, expect %x", w.Body.Bytes(), buf.Bytes())
+	}
+	if w := get("headers/1/" + strings.Repeat("03", 32) + ".hex"); w.Body.String() != "\n" {
+		t.Errorf("got headers %q of an unknown block", w.Body)
+	}
+
+	txid := func(s string) string { return util.TxID(*util.HashFromString(s)).String() }
+	outPoints := txid("01") + "-0/" + txid("03") + "-0/" + txid("02") + "-1"
+	var result restUTXOsResult
+	if err := json.Unmarshal(get("getutxos/"+outPoints+".json").Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.ChainHeight != tip.Height || result.Bitmap != "101" || len(result.UTXOs) != 2 ||
+		result.UTXOs[0].Value != 0.00001 || result.UTXOs[1].Value != 0.000002 {
+		t.Errorf("got utxos %+v", result)
+	}
+
+	data, err := hex.DecodeString(strings.TrimSpace(get("getutxos/" + outPoints + ".hex").Body.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bytes.NewReader(data)
+	var height int32
+	var tipHash util.Hash
+	if err := util.ReadElements(r, &height, &tipHash); err != nil {
+		t.Fatal(err)
+	}
+	bitmap, err := util.ReadVarBytes(r, 1, "bitmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := util.ReadVarInt(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != tip.Height || tipHash != *tip.GetBlockHash() || !bytes.Equal(bitmap, []byte{0x05}) || count != 2 {
+		t.Errorf("got height %d, tip %s, bitmap %x and %d utxos", height, tipHash, bitmap, count)
+	}
+}
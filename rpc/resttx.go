@@ -0,0 +1,235 @@
+package rpc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/consensus"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/rpc/btcjson"
+)
+
+// restTxPath is the HTTP path submitting serialized transactions. A POST of a
+// transaction relays it as sendrawtransaction does. With the async query
+// parameter the transaction is queued for validation instead, and the answer
+// carries a token to query the result with a GET of restTxPath/<token>.
+const restTxPath = "/rest/tx"
+
+const (
+	// maxQueuedTxSubmissions is the number of asynchronous submissions
+	// waiting for validation at most.
+	maxQueuedTxSubmissions = 1000
+
+	// maxTxSubmissionResults is the number of asynchronous submissions
+	// whose result is kept for querying.
+	maxTxSubmissionResults = 10000
+)
+
+// The statuses of a submitted transaction.
+const (
+	txSubmissionPending  = "pending"
+	txSubmissionAccepted = "accepted"
+	txSubmissionRejected = "rejected"
+)
+
+// restTxResult is the answer to a transaction submission or to the query of
+// its result.
+type restTxResult struct {
+	TxID   string            `json:"txid"`
+	Token  string            `json:"token,omitempty"`
+	Status string            `json:"status"`
+	Error  *btcjson.RPCError `json:"error,omitempty"`
+}
+
+type txSubmission struct {
+	token         string
+	tx            *tx.Tx
+	allowHighFees bool
+}
+
+// txSubmitter queues the asynchronous transaction submissions and keeps
+// their results.
+type txSubmitter struct {
+	queue chan *txSubmission
+
+	mtx     sync.Mutex
+	results map[string]*restTxResult
+	// tokens are the tokens of the results in submission order, to forget
+	// the oldest results first.
+	tokens []string
+}
+
+func newTxSubmitter() *txSubmitter {
+	return &txSubmitter{
+		queue:   make(chan *txSubmission, maxQueuedTxSubmissions),
+		results: make(map[string]*restTxResult),
+	}
+}
+
+// submit queues a transaction for validation, returning its pending result,
+// or nil if the queue is full.
+func (ts *txSubmitter) submit(transaction *tx.Tx, allowHighFees bool) (*restTxResult, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	hash := transaction.GetHash()
+	sub := &txSubmission{
+		token:         hex.EncodeToString(token),
+		tx:            transaction,
+		allowHighFees: allowHighFees,
+	}
+	result := &restTxResult{
+		TxID:   hash.String(),
+		Token:  sub.token,
+		Status: txSubmissionPending,
+	}
+
+	ts.mtx.Lock()
+	defer ts.mtx.Unlock()
+	select {
+	case ts.queue <- sub:
+	default:
+		return nil, nil
+	}
+	ts.results[sub.token] = result
+	ts.tokens = append(ts.tokens, sub.token)
+	if len(ts.tokens) > maxTxSubmissionResults {
+		delete(ts.results, ts.tokens[0])
+		ts.tokens = ts.tokens[1:]
+	}
+	return result, nil
+}
+
+// result returns the result of the submission with token, nil if it is
+// unknown or forgotten.
+func (ts *txSubmitter) result(token string) *restTxResult {
+	ts.mtx.Lock()
+	defer ts.mtx.Unlock()
+	if result, ok := ts.results[token]; ok {
+		resultCopy := *result
+		return &resultCopy
+	}
+	return nil
+}
+
+// finish records the result of a validated submission.
+func (ts *txSubmitter) finish(token string, err error) {
+	ts.mtx.Lock()
+	defer ts.mtx.Unlock()
+	result, ok := ts.results[token]
+	if !ok {
+		return
+	}
+	if err != nil {
+		result.Status = txSubmissionRejected
+		result.Error = toRPCError(err)
+		return
+	}
+	result.Status = txSubmissionAccepted
+}
+
+// txSubmissionHandler validates the queued transaction submissions in order.
+// It must be run as a goroutine.
+func (s *Server) txSubmissionHandler() {
+	defer s.wg.Done()
+	for {
+		select {
+		case sub := <-s.txSubmitter.queue:
+			s.txSubmitter.finish(sub.token, s.sendRawTransaction(sub.tx, sub.allowHighFees))
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// handleRESTTx serves the submissions of transactions and the queries of the
+// results of the asynchronous ones.
+func (s *Server) handleRESTTx(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == restTxPath:
+		s.submitRESTTx(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, restTxPath+"/"):
+		result := s.txSubmitter.result(strings.TrimPrefix(r.URL.Path, restTxPath+"/"))
+		if result == nil {
+			http.Error(w, "404 Unknown submission token.", http.StatusNotFound)
+			return
+		}
+		writeRESTTxResult(w, http.StatusOK, result)
+	default:
+		http.Error(w, "405 Method not allowed.", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) submitRESTTx(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	async, err := restBoolParam(query.Get("async"))
+	if err != nil {
+		http.Error(w, "400 Invalid async parameter.", http.StatusBadRequest)
+		return
+	}
+	allowHighFees, err := restBoolParam(query.Get("allowhighfees"))
+	if err != nil {
+		http.Error(w, "400 Invalid allowhighfees parameter.", http.StatusBadRequest)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, consensus.MaxTxSize))
+	if err != nil {
+		http.Error(w, "400 Unable to read the transaction.", http.StatusBadRequest)
+		return
+	}
+	transaction, err := decodeTx(body)
+	if err != nil {
+		http.Error(w, "400 "+toRPCError(err).Message+".", http.StatusBadRequest)
+		return
+	}
+
+	if async {
+		result, err := s.txSubmitter.submit(transaction, allowHighFees)
+		if err != nil {
+			log.Error("Unable to queue the transaction submission of %s: %v", r.RemoteAddr, err)
+			http.Error(w, "500 Unable to queue the transaction.", http.StatusInternalServerError)
+			return
+		}
+		if result == nil {
+			http.Error(w, "503 Transaction submission queue full.", http.StatusServiceUnavailable)
+			return
+		}
+		writeRESTTxResult(w, http.StatusAccepted, result)
+		return
+	}
+
+	hash := transaction.GetHash()
+	result := &restTxResult{TxID: hash.String(), Status: txSubmissionAccepted}
+	status := http.StatusOK
+	if err := s.sendRawTransaction(transaction, allowHighFees); err != nil {
+		result.Status = txSubmissionRejected
+		result.Error = toRPCError(err)
+		status = http.StatusUnprocessableEntity
+	}
+	writeRESTTxResult(w, status, result)
+}
+
+// restBoolParam parses an optional boolean query parameter.
+func restBoolParam(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func writeRESTTxResult(w http.ResponseWriter, status int, result *restTxResult) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Warn("Failed to write a transaction submission result: %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+)
+
+func newRESTTestTx(index uint32) *tx.Tx {
+	transaction := tx.NewTx(0, tx.TxVersion)
+	transaction.AddTxIn(txin.NewTxIn(outpoint.NewOutPoint(util.HashOne, index),
+		script.NewScriptRaw([]byte{opcodes.OP_TRUE}), script.SequenceFinal))
+	transaction.AddTxOut(txout.NewTxOut(10000, script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+	return transaction
+}
+
+func TestTxSubmitter(t *testing.T) {
+	ts := newTxSubmitter()
+	accepted, err := ts.submit(newRESTTestTx(0), false)
+	if err != nil || accepted == nil || accepted.Status != txSubmissionPending || accepted.Token == "" {
+		t.Fatalf("submitted %+v, error %v", accepted, err)
+	}
+	rejected, err := ts.submit(newRESTTestTx(1), true)
+	if err != nil || rejected == nil || rejected.Token == accepted.Token {
+		t.Fatalf("submitted %+v, error %v", rejected, err)
+	}
+	if sub := <-ts.queue; sub.token != accepted.Token || sub.allowHighFees {
+		t.Errorf("queued %+v first", sub)
+	}
+	if sub := <-ts.queue; sub.token != rejected.Token || !sub.allowHighFees {
+		t.Errorf("queued %+v second", sub)
+	}
+
+	ts.finish(accepted.Token, nil)
+	ts.finish(rejected.Token, btcjson.NewRPCError(btcjson.RPCTransactionRejected, "rejected for the test"))
+	ts.finish("unknown", nil)
+	if result := ts.result(accepted.Token); result == nil || result.Status != txSubmissionAccepted ||
+		result.Error != nil || result.TxID != accepted.TxID {
+		t.Errorf("accepted submission result %+v", result)
+	}
+	result := ts.result(rejected.Token)
+	if result == nil || result.Status != txSubmissionRejected || result.Error == nil ||
+		result.Error.Code != btcjson.RPCTransactionRejected {
+		t.Fatalf("rejected submission result %+v", result)
+	}
+	// the results returned are copies
+	result.Status = txSubmissionPending
+	if ts.result(rejected.Token).Status != txSubmissionRejected {
+		t.Error("the result of a submission was changed through a copy")
+	}
+	if ts.result("unknown") != nil {
+		t.Error("got the result of an unknown token")
+	}
+
+	// a full queue refuses submissions
+	for i := 0; i < maxQueuedTxSubmissions; i++ {
+		if result, err := ts.submit(newRESTTestTx(2), false); err != nil || result == nil {
+			t.Fatalf("submission %d refused: %v", i, err)
+		}
+	}
+	if result, err := ts.submit(newRESTTestTx(2), false); err != nil || result != nil {
+		t.Errorf("submitted %+v to a full queue, error %v", result, err)
+	}
+
+	// the oldest results are forgotten
+	for len(ts.tokens) < maxTxSubmissionResults {
+		<-ts.queue
+		if _, err := ts.submit(newRESTTestTx(2), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-ts.queue
+	if _, err := ts.submit(newRESTTestTx(2), false); err != nil {
+		t.Fatal(err)
+	}
+	if len(ts.results) != maxTxSubmissionResults || ts.result(accepted.Token) != nil ||
+		ts.result(rejected.Token) == nil {
+		t.Errorf("kept %d results, the oldest one forgotten: %t", len(ts.results), ts.result(accepted.Token) == nil)
+	}
+}
+
+func TestHandleRESTTxAsync(t *testing.T) {
+	s := &Server{txSubmitter: newTxSubmitter()}
+	transaction := newRESTTestTx(0)
+	buf := new(bytes.Buffer)
+	if err := transaction.Serialize(buf); err != nil {
+		t.Fatal(err)
+	}
+	serve := func(method, target string, body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.handleRESTTx(w, httptest.NewRequest(method, target, bytes.NewReader(body)))
+		return w
+	}
+
+	w := serve(http.MethodPost, restTxPath+"?async=1", buf.Bytes())
+	var submitted restTxResult
+	if err := json.Unmarshal(w.Body.Bytes(), &submitted); err != nil || w.Code != http.StatusAccepted {
+		t.Fatalf("async submission answered %d %s", w.Code, w.Body)
+	}
+	hash := transaction.GetHash()
+	if submitted.TxID != hash.String() || submitted.Status != txSubmissionPending || submitted.Token == "" {
+		t.Errorf("async submission result %+v", submitted)
+	}
+
+	s.txSubmitter.finish(submitted.Token, nil)
+	w = serve(http.MethodGet, restTxPath+"/"+submitted.Token, nil)
+	var queried restTxResult
+	if err := json.Unmarshal(w.Body.Bytes(), &queried); err != nil || w.Code != http.StatusOK ||
+		queried.Status != txSubmissionAccepted || queried.TxID != submitted.TxID {
+		t.Errorf("query of the submission answered %d %s", w.Code, w.Body)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   []byte
+		status int
+	}{
+		{"unknown token", http.MethodGet, restTxPath + "/unknown", nil, http.StatusNotFound},
+		{"invalid async", http.MethodPost, restTxPath + "?async=maybe", buf.Bytes(), http.StatusBadRequest},
+		{"invalid allowhighfees", http.MethodPost, restTxPath + "?allowhighfees=maybe", buf.Bytes(),
+			http.StatusBadRequest},
+		{"truncated transaction", http.MethodPost, restTxPath + "?async=1", buf.Bytes()[:buf.Len()-1],
+			http.StatusBadRequest},
+		{"trailing bytes", http.MethodPost, restTxPath + "?async=1", append(buf.Bytes(), 0),
+			http.StatusBadRequest},
+		{"post to a token", http.MethodPost, restTxPath + "/" + submitted.Token, buf.Bytes(),
+			http.StatusMethodNotAllowed},
+		{"put", http.MethodPut, restTxPath, buf.Bytes(), http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		if w := serve(test.method, test.target, test.body); w.Code != test.status {
+			t.Errorf("%s: answered %d, expect %d", test.name, w.Code, test.status)
+		}
+	}
+	if len(s.txSubmitter.queue) != 1 {
+		t.Errorf("%d submissions queued, expect one", len(s.txSubmitter.queue))
+	}
+}
@@ -0,0 +1,126 @@
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/copernet/copernicus/conf"
+)
+
+const (
+	// cookieAuthUser is the user name of the cookie authentication.
+	cookieAuthUser = "__cookie__"
+
+	// defaultCookieFile is the name of the cookie file in the data dir.
+	defaultCookieFile = ".cookie"
+)
+
+// rpcAuthEntry is a user of an rpcauth config entry, which stores the
+// password of the user as HMAC-SHA256(salt, password) instead of in plain
+// text, in the format of the rpcauth option of bitcoind:
+//
+//	<user>:<salt>$<hex hmac>
+type rpcAuthEntry struct {
+	user string
+	salt string
+	hash []byte
+}
+
+// parseRPCAuth parses the rpcauth config entries.
+func parseRPCAuth(entries []string) ([]rpcAuthEntry, error) {
+	auths := make([]rpcAuthEntry, 0, len(entries))
+	for _, entry := range entries {
+		colon := strings.IndexByte(entry, ':')
+		dollar := strings.LastIndexByte(entry, '$')
+		if colon <= 0 || dollar < colon {
+			return nil, fmt.Errorf("invalid rpcauth entry %q, expected <user>:<salt>$<hash>", entry)
+		}
+		hash, err := hex.DecodeString(entry[dollar+1:])
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("invalid hash in rpcauth entry of user %s", entry[:colon])
+		}
+		auths = append(auths, rpcAuthEntry{
+			user: entry[:colon],
+			salt: entry[colon+1 : dollar],
+			hash: hash,
+		})
+	}
+	return auths, nil
+}
+
+// matches reports whether user and pass are the credentials of the entry.
+func (e *rpcAuthEntry) matches(user, pass string) bool {
+	mac := hmac.New(sha256.New, []byte(e.salt))
+	mac.Write([]byte(pass))
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(e.user)) == 1
+	return hmac.Equal(mac.Sum(nil), e.hash) && userOK
+}
+
+// basicAuthCredentials decodes the user and password of a basic
+// authorization header.
+func basicAuthCredentials(authhdr string) (string, string, bool) {
+	const prefix = "Basic "
+	if !strings.HasPrefix(authhdr, prefix) {
+		return "", "", false
+	}
+	login, err := base64.StdEncoding.DecodeString(authhdr[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+	colon := strings.IndexByte(string(login), ':')
+	if colon < 0 {
+		return "", "", false
+	}
+	return string(login[:colon]), string(login[colon+1:]), true
+}
+
+// basicAuthSha returns the hash of the basic authorization header sent with
+// the credentials user and pass, which is what the server compares.
+func basicAuthSha(user, pass string) [sha256.Size]byte {
+	login := user + ":" + pass
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
+	return sha256.Sum256([]byte(auth))
+}
+
+// cookieFilePath returns the path of the authentication cookie file.
+func cookieFilePath() string {
+	path := conf.Cfg.RPC.RPCCookieFile
+	if path == "" {
+		path = defaultCookieFile
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(conf.Cfg.DataDir, path)
+	}
+	return path
+}
+
+// generateAuthCookie writes credentials with a random password to the cookie
+// file, readable by the user running the node only, so that local tools can
+// authenticate without a configured password. It returns the password.
+func generateAuthCookie(path string) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	pass := hex.EncodeToString(secret)
+
+	// write to a temporary file first so readers never see a partial cookie
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(cookieAuthUser+":"+pass), 0600); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return pass, nil
+}
@@ -0,0 +1,181 @@
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func rpcAuthLine(user, salt, pass string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(pass))
+	return user + ":" + salt + "$" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseRPCAuth(t *testing.T) {
+	valid := rpcAuthLine("alice", "cb77f0957de88ff388cf817ddbc7273", "secret")
+	hash := valid[strings.LastIndexByte(valid, '$')+1:]
+
+	tests := []struct {
+		entry string
+		valid bool
+	}{
+		{valid, true},
+		// the salt may contain a dollar sign, the hash starts after the last one
+		{"alice:salt$with$dollar$" + hash, true},
+		{"alice:$" + hash, true},
+		{"", false},
+		{"alice", false},
+		{":salt$" + hash, false},
+		{"alice:salt" + hash, false},
+		{"alice$salt:" + hash, false},
+		{"alice:salt$" + hash[:62], false},
+		{"alice:salt$" + hash + "00", false},
+		{"alice:salt$" + strings.Repeat("zz", 32), false},
+	}
+	for _, test := range tests {
+		auths, err := parseRPCAuth([]string{test.entry})
+		if (err == nil) != test.valid {
+			t.Errorf("parseRPCAuth(%q) error %v, expect valid %t", test.entry, err, test.valid)
+		}
+		if err == nil && (len(auths) != 1 || auths[0].user != "alice") {
+			t.Errorf("parseRPCAuth(%q) = %+v", test.entry, auths)
+		}
+	}
+
+	// one malformed entry rejects the whole option
+	if _, err := parseRPCAuth([]string{valid, "bob"}); err == nil {
+		t.Errorf("a malformed entry after a valid one should be rejected")
+	}
+}
+
+func TestRPCAuthMatches(t *testing.T) {
+	auths, err := parseRPCAuth([]string{
+		rpcAuthLine("alice", "salt1", "secret"),
+		rpcAuthLine("bob", "salt2", "secret"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		user, pass string
+		match      []bool
+	}{
+		{"alice", "secret", []bool{true, false}},
+		{"bob", "secret", []bool{false, true}},
+		{"alice", "Secret", []bool{false, false}},
+		{"alice", "secret ", []bool{false, false}},
+		{"alice", "", []bool{false, false}},
+		{"alic", "secret", []bool{false, false}},
+		{"alicee", "secret", []bool{false, false}},
+		{"alice\x00", "secret", []bool{false, false}},
+		{"", "secret", []bool{false, false}},
+	}
+	// the user and the hash are both compared in constant time, a mismatch
+	// in either fails whatever its position
+	for _, test := range tests {
+		for i := range auths {
+			if got := auths[i].matches(test.user, test.pass); got != test.match[i] {
+				t.Errorf("entry of %s matches(%q, %q) = %t", auths[i].user, test.user, test.pass, got)
+			}
+		}
+	}
+
+	// the same password under another salt is another hash
+	if hmac.Equal(auths[0].hash, auths[1].hash) {
+		t.Errorf("the salt is not part of the hash")
+	}
+}
+
+func TestBasicAuthCredentials(t *testing.T) {
+	encode := func(login string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
+	}
+	tests := []struct {
+		header     string
+		user, pass string
+		ok         bool
+	}{
+		{encode("alice:secret"), "alice", "secret", true},
+		{encode("alice:sec:ret"), "alice", "sec:ret", true},
+		{encode("alice:"), "alice", "", true},
+		{encode(":secret"), "", "secret", true},
+		{encode("alice"), "", "", false},
+		{"Bearer " + base64.StdEncoding.EncodeToString([]byte("alice:secret")), "", "", false},
+		{"basic " + base64.StdEncoding.EncodeToString([]byte("alice:secret")), "", "", false},
+		{"Basic !!!", "", "", false},
+		{"", "", "", false},
+	}
+	for _, test := range tests {
+		user, pass, ok := basicAuthCredentials(test.header)
+		if user != test.user || pass != test.pass || ok != test.ok {
+			t.Errorf("basicAuthCredentials(%q) = %q, %q, %t", test.header, user, pass, ok)
+		}
+	}
+}
+
+func TestAuthCookie(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpccookie")
+	if err != nil {
+		t.Fatalf("generate temp dir failed: %s\n", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, defaultCookieFile)
+
+	pass, err := generateAuthCookie(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("cookie file mode %v, expect 0600", info.Mode().Perm())
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary cookie file left behind")
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the file holds what a client sends as basic credentials
+	header := "Basic " + base64.StdEncoding.EncodeToString(content)
+	user, filePass, ok := basicAuthCredentials(header)
+	if !ok || user != cookieAuthUser || filePass != pass || len(pass) != 64 {
+		t.Fatalf("cookie %q does not hold the generated credentials", content)
+	}
+
+	s := &Server{cookiePath: path, cookiesha: basicAuthSha(cookieAuthUser, pass), access: &httpAccess{}}
+	request := func(header string) *http.Request {
+		r, _ := http.NewRequest("POST", "/", nil)
+		r.Header.Set("Authorization", header)
+		return r
+	}
+	authenticated, isAdmin, err := s.checkAuth(request(header), true)
+	if err != nil || !authenticated || !isAdmin {
+		t.Errorf("cookie credentials rejected: %v", err)
+	}
+	wrong := "Basic " + base64.StdEncoding.EncodeToString([]byte(cookieAuthUser+":"+pass[1:]))
+	if authenticated, _, err := s.checkAuth(request(wrong), true); err == nil || authenticated {
+		t.Errorf("wrong cookie password accepted")
+	}
+
+	again, err := generateAuthCookie(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == pass {
+		t.Errorf("a new cookie should get a new password")
+	}
+}
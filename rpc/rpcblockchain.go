@@ -3,23 +3,36 @@ package rpc
 import (
 	"bytes"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
 	"github.com/copernet/copernicus/logic/lchain"
+	"github.com/copernet/copernicus/logic/lmempool"
+	"github.com/copernet/copernicus/logic/lutxo"
+	"github.com/copernet/copernicus/model"
 	"github.com/copernet/copernicus/model/block"
 	"github.com/copernet/copernicus/model/blockindex"
 	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/model/consensus"
 	"github.com/copernet/copernicus/model/mempool"
 	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/pow"
 	"github.com/copernet/copernicus/model/utxo"
 	"github.com/copernet/copernicus/model/versionbits"
+	"github.com/copernet/copernicus/net/wire"
+	"github.com/copernet/copernicus/persist"
 	"github.com/copernet/copernicus/persist/disk"
 	"github.com/copernet/copernicus/rpc/btcjson"
 	"github.com/copernet/copernicus/util"
-	"gopkg.in/fatih/set.v0"
+	"github.com/copernet/copernicus/util/amount"
+	"math"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 var blockchainHandlers = map[string]commandHandler{
@@ -29,6 +42,8 @@ var blockchainHandlers = map[string]commandHandler{
 	"getblock":              handleGetBlock,              // complete
 	"getblockhash":          handleGetBlockHash,          // complete
 	"getblockheader":        handleGetBlockHeader,        // complete
+	"getblockstats":         handleGetBlockStats,         // complete
+	"getchainstates":        handleGetChainStates,        // partial complete
 	"getchaintips":          handleGetChainTips,          // partial complete
 	"getdifficulty":         handleGetDifficulty,         //complete
 	"getchaintxstats":       handleGetChainTxStats,       // complete
@@ -36,16 +51,24 @@ var blockchainHandlers = map[string]commandHandler{
 	"getmempooldescendants": handleGetMempoolDescendants, //complete
 	"getmempoolentry":       handleGetMempoolEntry,       // complete
 	"getmempoolinfo":        handleGetMempoolInfo,        // complete
+	"savemempool":           handleSaveMempool,           // complete
 	"getrawmempool":         handleGetRawMempool,         // complete
 	"gettxout":              handleGetTxOut,              // complete
-	"gettxoutsetinfo":       handleGetTxoutSetInfo,
-	"pruneblockchain":       handlePruneBlockChain, //complete
-	"verifychain":           handleVerifyChain,     //complete
-	"preciousblock":         handlePreciousblock,   //complete
+	"gettxoutsetinfo":       handleGetTxoutSetInfo,       // complete
+	"scantxoutset":          handleScanTxOutSet,          // complete
+	"getutxodistribution":   handleGetUTXODistribution,   // complete
+	"getblocklocations":     handleGetBlockLocations,     // complete
+	"pruneblockchain":       handlePruneBlockChain,       //complete
+	"verifychain":           handleVerifyChain,           //complete
+	"preciousblock":         handlePreciousblock,         //complete
+	"getactivity":           handleGetActivity,
+	"getutxocachestats":     handleGetUtxoCacheStats,
+	"getdifficultyhistory":  handleGetDifficultyHistory,
 
 	/*not shown in help*/
 	"invalidateblock":    handlInvalidateBlock,  //complete
 	"reconsiderblock":    handleReconsiderBlock, //complete
+	"rollbackchain":      handleRollbackChain,
 	"waitfornewblock":    handleWaitForNewBlock,
 	"waitforblock":       handleWaitForBlock,
 	"waitforblockheight": handleWaitForBlockHeight,
@@ -56,17 +79,28 @@ func handleGetBlockChainInfo(s *Server, cmd interface{}, closeChan <-chan struct
 	params := gChain.GetParams()
 	tip := gChain.Tip()
 
+	pruneState := disk.GetPruneState()
 	chainInfo := &btcjson.GetBlockChainInfoResult{
 		Chain:                params.Name,
 		Blocks:               gChain.Height(),
+		Headers:              gChain.BestHeader().Height,
 		BestBlockHash:        tip.GetBlockHash().String(),
 		Difficulty:           getDifficulty(tip),
 		MedianTime:           tip.GetMedianTimePast(),
 		VerificationProgress: lchain.GuessVerificationProgress(params.TxData(), tip),
-		ChainWork:            tip.ChainWork.Text(16),
-		Pruned:               false,
+		ChainWork:            fmt.Sprintf("%064x", &tip.ChainWork),
+		SizeOnDisk:           disk.CalculateCurrentUsage(),
+		Pruned:               pruneState.PruneMode,
 		Bip9SoftForks:        make(map[string]*btcjson.Bip9SoftForkDescription),
-		//Headers:            lblockindex.indexBestHeader.Height,   // TODO: NOT support yet
+		Upgrades:             upgradeDescriptions(params, tip),
+	}
+	if pruneState.PruneMode {
+		// the lowest block which still has its data on disk
+		lowest := tip
+		for lowest.Prev != nil && lowest.Prev.HasData() {
+			lowest = lowest.Prev
+		}
+		chainInfo.PruneHeight = lowest.Height
 	}
 
 	// Next, populate the response with information describing the current
@@ -108,7 +142,7 @@ func handleGetBlockChainInfo(s *Server, cmd interface{}, closeChan <-chan struct
 	// defined BIP0009 soft-fork deployments.
 	for i := 0; i < int(consensus.MaxVersionBitsDeployments); i++ {
 		pos := consensus.DeploymentPos(i)
-		state := versionbits.VersionBitsState(indexPrev, params, pos, versionbits.VBCache)
+		state := versionbits.VersionBitsState(tip, params, pos, versionbits.VBCache)
 		forkName := getVbName(pos)
 
 		// Attempt to convert the current deployment status into a
@@ -137,6 +171,35 @@ func handleGetBlockChainInfo(s *Server, cmd interface{}, closeChan <-chan struct
 	return chainInfo, nil
 }
 
+// upgradeDescriptions returns the activation status at tip of the network
+// upgrades of Bitcoin Cash. The upgrades scheduled by time activate once the
+// median time past of the tip reaches their activation time.
+func upgradeDescriptions(params *model.BitcoinParams, tip *blockindex.BlockIndex) []*btcjson.UpgradeDescription {
+	byHeight := func(name string, height int32) *btcjson.UpgradeDescription {
+		return &btcjson.UpgradeDescription{
+			Name:           name,
+			ActivationType: "height",
+			Height:         height,
+			Active:         tip.Height >= height,
+		}
+	}
+	mtp := tip.GetMedianTimePast()
+	byTime := func(name string, time int64) *btcjson.UpgradeDescription {
+		return &btcjson.UpgradeDescription{
+			Name:           name,
+			ActivationType: "mediantime",
+			Time:           time,
+			Active:         mtp >= time,
+		}
+	}
+	return []*btcjson.UpgradeDescription{
+		byHeight("uahf", params.UAHFHeight),
+		byHeight("daa", params.DAAHeight),
+		byTime("monolith", params.MonolithActivationTime),
+		byTime("magneticanomaly", params.MagneticAnomalyActivationTime),
+	}
+}
+
 // softForkStatus converts a ThresholdState state into a human readable string
 // corresponding to the particular state.
 func softForkStatus(state versionbits.ThresholdState) (string, error) {
@@ -167,15 +230,41 @@ func handleGetBlockCount(s *Server, cmd interface{}, closeChan <-chan struct{})
 func handleGetBlock(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetBlockCmd)
 
-	// Load the raw block bytes from the database.
-	hash, err := util.GetHashFromStr(c.Hash)
+	blk, blockIndex, err := readBlock(c.Hash)
 	if err != nil {
-		return nil, rpcDecodeHexError(c.Hash)
+		return false, err
+	}
+
+	verbosity := btcjson.BlockVerbosity(1)
+	if c.Verbosity != nil {
+		verbosity = *c.Verbosity
+	}
+
+	if verbosity <= 0 {
+		blkBuf := bytes.NewBuffer(nil)
+		blk.Serialize(blkBuf)
+		strHex := hex.EncodeToString(blkBuf.Bytes())
+		return strHex, nil
+	}
+
+	blockReply := blockToJSON(blk, blockIndex, newChainSnapshot())
+	if verbosity == 1 {
+		return blockReply, nil
+	}
+
+	return blockToJSONWithTxs(blk, blockReply)
+}
+
+// readBlock loads the block with the given hash from the disk.
+func readBlock(blockHash string) (*block.Block, *blockindex.BlockIndex, error) {
+	hash, err := util.BlockHashFromStr(blockHash)
+	if err != nil {
+		return nil, nil, rpcDecodeHexError(blockHash)
 	}
 
-	blockIndex := chain.GetInstance().FindBlockIndex(*hash)
+	blockIndex := chain.GetInstance().FindBlockIndex(hash.Hash())
 	if blockIndex == nil {
-		return false, &btcjson.RPCError{
+		return nil, nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCInvalidAddressOrKey,
 			Message: "Block not found",
 		}
@@ -183,7 +272,7 @@ func handleGetBlock(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 
 	pruneState := disk.GetPruneState()
 	if pruneState.HavePruned && !blockIndex.HasData() && blockIndex.TxCount > 0 {
-		return false, &btcjson.RPCError{
+		return nil, nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCMisc,
 			Message: "Block not available (pruned data)",
 		}
@@ -191,30 +280,42 @@ func handleGetBlock(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 
 	blk, ret := disk.ReadBlockFromDisk(blockIndex, chain.GetInstance().GetParams())
 	if !ret {
-		return false, &btcjson.RPCError{
+		return nil, nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCMisc,
 			Message: "Block not found on disk",
 		}
 	}
+	return blk, blockIndex, nil
+}
 
-	if c.Verbose != nil && !*c.Verbose {
-		blkBuf := bytes.NewBuffer(nil)
-		blk.Serialize(blkBuf)
-		strHex := hex.EncodeToString(blkBuf.Bytes())
-		return strHex, nil
+// blockToJSONWithTxs returns the reply of getblock with verbosity 2, which
+// decodes the transactions of the block instead of listing their txids.
+// The transactions carry no block fields, which are those of the block.
+func blockToJSONWithTxs(blk *block.Block, blockReply *btcjson.GetBlockVerboseResult) (*btcjson.GetBlockVerboseTxResult, error) {
+	reply := &btcjson.GetBlockVerboseTxResult{
+		GetBlockVerboseResult: *blockReply,
+		Tx:                    make([]btcjson.TxRawResult, len(blk.Txs)),
 	}
+	reply.GetBlockVerboseResult.Tx = nil
 
-	blockReply := blockToJSON(blk, blockIndex)
-
-	return blockReply, nil
+	buf := bytes.NewBuffer(nil)
+	for i, transaction := range blk.Txs {
+		buf.Reset()
+		if err := transaction.Serialize(buf); err != nil {
+			return nil, internalRPCError(err.Error(), "Failed to serialize transaction")
+		}
+		rawTx, err := getTxRawResult(transaction, &util.Hash{}, hex.EncodeToString(buf.Bytes()), nil)
+		if err != nil {
+			return nil, err
+		}
+		reply.Tx[i] = *rawTx
+	}
+	return reply, nil
 }
 
-func blockToJSON(blk *block.Block, blockIndex *blockindex.BlockIndex) *btcjson.GetBlockVerboseResult {
-	confirmations := int32(-1)
+func blockToJSON(blk *block.Block, blockIndex *blockindex.BlockIndex, snapshot *chainSnapshot) *btcjson.GetBlockVerboseResult {
 	// Only report confirmations if the block is on the main chain
-	if chain.GetInstance().Contains(blockIndex) {
-		confirmations = chain.GetInstance().TipHeight() - blockIndex.Height + 1
-	}
+	confirmations := snapshot.Confirmations(blockIndex)
 
 	var previousHash string
 	if blockIndex.Prev != nil {
@@ -222,7 +323,7 @@ func blockToJSON(blk *block.Block, blockIndex *blockindex.BlockIndex) *btcjson.G
 	}
 
 	var nextHash string
-	next := chain.GetInstance().Next(blockIndex)
+	next := snapshot.Next(blockIndex)
 	if next != nil {
 		nextHash = next.GetBlockHash().String()
 	}
@@ -248,8 +349,7 @@ func blockToJSON(blk *block.Block, blockIndex *blockindex.BlockIndex) *btcjson.G
 	}
 
 	for i, tx := range blk.Txs {
-		txHash := tx.GetHash()
-		blockReply.Tx[i] = txHash.String()
+		blockReply.Tx[i] = util.TxID(tx.GetHash()).String()
 	}
 	return blockReply
 }
@@ -271,11 +371,11 @@ func handleGetBlockHeader(s *Server, cmd interface{}, closeChan <-chan struct{})
 	c := cmd.(*btcjson.GetBlockHeaderCmd)
 
 	// Fetch the header from chain.
-	hash, err := util.GetHashFromStr(c.Hash)
+	hash, err := util.BlockHashFromStr(c.Hash)
 	if err != nil {
 		return nil, rpcDecodeHexError(c.Hash)
 	}
-	blockIndex := chain.GetInstance().FindBlockIndex(*hash)
+	blockIndex := chain.GetInstance().FindBlockIndex(hash.Hash())
 
 	if blockIndex == nil {
 		return nil, &btcjson.RPCError{
@@ -295,11 +395,14 @@ func handleGetBlockHeader(s *Server, cmd interface{}, closeChan <-chan struct{})
 		return hex.EncodeToString(headerBuf.Bytes()), nil
 	}
 
-	confirmations := int32(-1)
+	return blockHeaderToJSON(blockIndex, newChainSnapshot()), nil
+}
+
+// blockHeaderToJSON describes the header of the block, its confirmations and
+// next block being those of the chain pinned by snapshot.
+func blockHeaderToJSON(blockIndex *blockindex.BlockIndex, snapshot *chainSnapshot) *btcjson.GetBlockHeaderVerboseResult {
 	// Only report confirmations if the block is on the main chain
-	if chain.GetInstance().Contains(blockIndex) {
-		confirmations = chain.GetInstance().TipHeight() - blockIndex.Height + 1
-	}
+	confirmations := snapshot.Confirmations(blockIndex)
 
 	var previousblockhash string
 	if blockIndex.Prev != nil {
@@ -307,14 +410,14 @@ func handleGetBlockHeader(s *Server, cmd interface{}, closeChan <-chan struct{})
 	}
 
 	var nextblockhash string
-	next := chain.GetInstance().Next(blockIndex)
+	next := snapshot.Next(blockIndex)
 	if next != nil {
 		nextblockhash = next.GetBlockHash().String()
 	}
 
-	blockHeaderReply := &btcjson.GetBlockHeaderVerboseResult{
-		Hash:          c.Hash,
-		Confirmations: uint64(confirmations),
+	return &btcjson.GetBlockHeaderVerboseResult{
+		Hash:          blockIndex.GetBlockHash().String(),
+		Confirmations: confirmations,
 		Height:        blockIndex.Height,
 		Version:       blockIndex.Header.Version,
 		VersionHex:    fmt.Sprintf("%08x", blockIndex.Header.Version),
@@ -322,42 +425,67 @@ func handleGetBlockHeader(s *Server, cmd interface{}, closeChan <-chan struct{})
 		Time:          blockIndex.Header.Time,
 		Mediantime:    blockIndex.GetMedianTimePast(),
 		Nonce:         uint64(blockIndex.Header.Nonce),
-		Bits:          fmt.Sprintf("%8x", blockIndex.Header.Bits),
+		Bits:          fmt.Sprintf("%08x", blockIndex.Header.Bits),
 		Difficulty:    getDifficulty(blockIndex),
-		Chainwork:     blockIndex.ChainWork.Text(16),
+		Chainwork:     fmt.Sprintf("%064x", &blockIndex.ChainWork),
+		NTx:           blockIndex.TxCount,
 		PreviousHash:  previousblockhash,
 		NextHash:      nextblockhash,
 	}
-	return blockHeaderReply, nil
 }
 
+// handleGetChainStates reports the chainstates the node runs. Without UTXO
+// snapshot support there is only the fully validated active chainstate: the
+// snapshot chainstate, its background validation and its promotion once
+// validated are left to the snapshot support.
+func handleGetChainStates(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	gChain := chain.GetInstance()
+	params := gChain.GetParams()
+	tip := gChain.Tip()
+
+	headers := gChain.Height()
+	if best := gChain.BestHeader(); best != nil && best.Height > headers {
+		headers = best.Height
+	}
+
+	active := &btcjson.ChainStateResult{
+		Blocks:    gChain.Height(),
+		Validated: true,
+	}
+	if tip != nil {
+		active.BestBlockHash = tip.GetBlockHash().String()
+		active.Difficulty = getDifficulty(tip)
+		active.VerificationProgress = lchain.GuessVerificationProgress(params.TxData(), tip)
+	}
+
+	return &btcjson.GetChainStatesResult{
+		Headers:     headers,
+		ChainStates: []*btcjson.ChainStateResult{active},
+	}, nil
+}
+
+// handleGetChainTips reports the tips of the active chain and of every fork
+// in the block index, with the length of their branch off the active chain.
 func handleGetChainTips(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	// Idea:  the set of chain tips is chainActive.tip, plus orphan blocks which
-	// do not have another orphan building off of them.
-	// Algorithm:
-	//	- Make one pass through mapBlockIndex, picking out the orphan blocks,
-	//	  and also storing a set of the orphan block's pprev pointers.
-	//  - Iterate through the orphan blocks. If the block isn't pointed to by
-	//	  another orphan, it is a chain tip.
-	//	- add chainActive.Tip()
-	setTips := set.New() // element type:
-
-	// todo add orphan blockindex, lack of chain's support<orphan>
-	setTips.Add(chain.GetInstance().Tip())
+	gChain := chain.GetInstance()
+	tips := gChain.ChainTips()
 
 	ret := btcjson.GetChainTipsResult{
-		Tips: make([]btcjson.ChainTipsInfo, 0, setTips.Size()),
+		Tips: make([]btcjson.ChainTipsInfo, 0, len(tips)),
 	}
-	setTips.Each(func(item interface{}) bool {
-		bindex := item.(*blockindex.BlockIndex)
+	for _, bindex := range tips {
 		tipInfo := btcjson.ChainTipsInfo{
-			Height:    bindex.Height,
-			Hash:      bindex.GetBlockHash().String(),
-			BranchLen: bindex.Height - chain.GetInstance().FindFork(bindex).Height,
+			Height: bindex.Height,
+			Hash:   bindex.GetBlockHash().String(),
+		}
+		if fork := gChain.FindFork(bindex); fork != nil {
+			tipInfo.BranchLen = bindex.Height - fork.Height
+		} else {
+			tipInfo.BranchLen = bindex.Height + 1
 		}
 
 		var status string
-		if chain.GetInstance().Contains(bindex) {
+		if gChain.Contains(bindex) {
 			// This block is part of the currently active chain.
 			status = "active"
 		} else if bindex.IsInvalid() {
@@ -383,9 +511,7 @@ func handleGetChainTips(s *Server, cmd interface{}, closeChan <-chan struct{}) (
 		tipInfo.Status = status
 
 		ret.Tips = append(ret.Tips, tipInfo)
-
-		return true
-	})
+	}
 
 	return ret, nil
 }
@@ -394,32 +520,56 @@ func getDifficulty(bi *blockindex.BlockIndex) float64 {
 	if bi == nil {
 		return 1.0
 	}
-	return getDifficultyFromBits(bi.GetBlockHeader().Bits)
+	return pow.GetDifficulty(bi.GetBlockHeader().Bits)
 }
 
-// getDifficultyRatio returns the proof-of-work difficulty as a multiple of the
-// minimum difficulty using the passed bits field from the header of a block.
-func getDifficultyFromBits(bits uint32) float64 {
-	shift := (bits >> 24) & 0xff
-	diff := float64(0x0000ffff) / float64(bits&0x00ffffff)
-	const factor = float64(256.0)
+func handleGetDifficulty(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	best := chain.GetInstance().Tip()
+	return getDifficulty(best), nil
+}
 
-	for shift < 29 {
-		diff *= factor
-		shift++
-	}
+// maxDifficultyHistoryHeights bounds the heights a getdifficultyhistory reply
+// covers, longer ranges are fetched in pages.
+const maxDifficultyHistoryHeights = 10000
 
-	for shift > 29 {
-		diff /= factor
-		shift--
-	}
+// handleGetDifficultyHistory implements the getdifficultyhistory command.  It
+// only reads the block index, so the range needs no block from disk.
+func handleGetDifficultyHistory(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetDifficultyHistoryCmd)
 
-	return diff
-}
+	snapshot := newChainSnapshot()
+	endHeight := snapshot.Height()
+	if c.EndHeight != nil && *c.EndHeight >= 0 {
+		endHeight = *c.EndHeight
+	}
+	if c.StartHeight < 0 || endHeight > snapshot.Height() || c.StartHeight > endHeight {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCOutOfRange,
+			Message: "Block height out of range",
+		}
+	}
+	if endHeight-c.StartHeight >= maxDifficultyHistoryHeights {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			fmt.Sprintf("Range exceeds %d heights", maxDifficultyHistoryHeights))
+	}
 
-func handleGetDifficulty(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	best := chain.GetInstance().Tip()
-	return getDifficulty(best), nil
+	// walk the parents down from the end of the range, which keeps to the
+	// snapshot chain even if the tip moves meanwhile
+	entries := make([]btcjson.DifficultyHistoryEntry, endHeight-c.StartHeight+1)
+	index := snapshot.GetIndex(endHeight)
+	for i := len(entries) - 1; i >= 0; i-- {
+		entries[i] = btcjson.DifficultyHistoryEntry{
+			Height:     index.Height,
+			Hash:       index.GetBlockHash().String(),
+			Time:       index.Header.Time,
+			MedianTime: index.GetMedianTimePast(),
+			Bits:       fmt.Sprintf("%08x", index.Header.Bits),
+			Difficulty: getDifficulty(index),
+			Target:     fmt.Sprintf("%064x", pow.CompactToBig(index.Header.Bits)),
+		}
+		index = index.Prev
+	}
+	return entries, nil
 }
 
 func handleGetChainTxStats(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
@@ -428,11 +578,11 @@ func handleGetChainTxStats(s *Server, cmd interface{}, closeChan <-chan struct{}
 	gChan := chain.GetInstance()
 	blockIndex := gChan.Tip()
 	if c.BlockHash != nil {
-		blockHash, err := util.GetHashFromStr(*c.BlockHash)
+		blockHash, err := util.BlockHashFromStr(*c.BlockHash)
 		if err != nil {
 			return nil, rpcDecodeHexError(*c.BlockHash)
 		}
-		blockIndex = gChan.FindBlockIndex(*blockHash)
+		blockIndex = gChan.FindBlockIndex(blockHash.Hash())
 		if blockIndex == nil {
 			return false, &btcjson.RPCError{
 				Code:    btcjson.ErrRPCBlockNotFound,
@@ -484,48 +634,54 @@ func handleGetChainTxStats(s *Server, cmd interface{}, closeChan <-chan struct{}
 
 func handleGetMempoolAncestors(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetMempoolAncestorsCmd)
-	hash, err := util.GetHashFromStr(c.TxID)
+	txid, err := util.TxIDFromStr(c.TxID)
 	if err != nil {
-		return nil, btcjson.RPCError{
-			Code:    btcjson.ErrRPCInvalidParameter,
-			Message: "the string " + c.TxID + " is not a standard hash",
-		}
+		return nil, rpcDecodeHexError(c.TxID)
 	}
-	entry := mempool.GetInstance().FindTx(*hash)
+
+	pool := mempool.GetInstance()
+	pool.RLock()
+	defer pool.RUnlock()
+	entry := pool.FindTxWithoutLock(txid.Hash())
 	if entry == nil {
 		return nil, btcjson.RPCError{
-			Code:    btcjson.RPCInvalidAddressOrKey,
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
 			Message: "Transaction not in mempool",
 		}
 	}
 
-	h := entry.Tx.GetHash()
-	txSet := mempool.GetInstance().CalculateMemPoolAncestorsWithLock(&h)
+	noLimit := uint64(math.MaxUint64)
+	ancestors, _ := pool.CalculateMemPoolAncestors(entry.Tx, noLimit, noLimit, noLimit, noLimit, false)
+	return mempoolEntriesToJSON(ancestors, c.Verbose != nil && *c.Verbose), nil
+}
 
-	if c.Verbose == nil || !*c.Verbose {
-		s := make([]string, len(txSet))
-		i := 0
-		for index := range txSet {
-			hash := index.Tx.GetHash()
-			s[i] = hash.String()
-			i++
+// mempoolEntriesToJSON returns the sorted ids of the entries, or a map of
+// their verbose descriptions when verbose is set. The caller holds the
+// mempool lock.
+func mempoolEntriesToJSON(entries map[*mempool.TxEntry]struct{}, verbose bool) interface{} {
+	if !verbose {
+		txids := make([]string, 0, len(entries))
+		for entry := range entries {
+			txids = append(txids, util.TxID(entry.Tx.GetHash()).String())
 		}
-		return s, nil
+		sort.Strings(txids)
+		return txids
 	}
 
-	infos := make(map[string]*btcjson.GetMempoolEntryRelativeInfoVerbose)
-	for index := range txSet {
-		hash := index.Tx.GetHash()
-		infos[hash.String()] = entryToJSON(index)
+	infos := make(map[string]*btcjson.GetMempoolEntryRelativeInfoVerbose, len(entries))
+	for entry := range entries {
+		infos[util.TxID(entry.Tx.GetHash()).String()] = entryToJSON(entry)
 	}
-	return infos, nil
+	return infos
 }
 
+// entryToJSON describes a mempool entry, the caller holds the mempool lock so
+// the statistics and parents of the entry are consistent.
 func entryToJSON(entry *mempool.TxEntry) *btcjson.GetMempoolEntryRelativeInfoVerbose {
 	result := btcjson.GetMempoolEntryRelativeInfoVerbose{}
 	result.Size = entry.TxSize
 	result.Fee = valueFromAmount(entry.TxFee)
-	result.ModifiedFee = valueFromAmount(entry.SumTxFeeWithAncestors) // todo check: GetModifiedFee() is equal to SumFeeWithAncestors
+	result.ModifiedFee = valueFromAmount(entry.GetModifiedFee())
 	result.Time = entry.GetTime()
 	result.Height = entry.TxHeight
 	// remove priority at current version
@@ -537,14 +693,22 @@ func entryToJSON(entry *mempool.TxEntry) *btcjson.GetMempoolEntryRelativeInfoVer
 	result.AncestorCount = entry.SumTxCountWithAncestors
 	result.AncestorSize = entry.SumTxSizeWitAncestors
 	result.AncestorFees = entry.SumTxFeeWithAncestors
+	result.Fees = btcjson.MempoolEntryFees{
+		Base:       valueFromAmount(entry.TxFee),
+		Modified:   valueFromAmount(entry.GetModifiedFee()),
+		Ancestor:   valueFromAmount(entry.SumTxFeeWithAncestors),
+		Descendant: valueFromAmount(entry.SumTxFeeWithDescendants),
+	}
 
-	setDepends := make([]string, 0)
-	for _, in := range entry.Tx.GetIns() {
-		if txItem := mempool.GetInstance().FindTx(in.PreviousOutPoint.Hash); txItem != nil {
-			setDepends = append(setDepends, in.PreviousOutPoint.Hash.String())
-		}
+	// the in-mempool parents, each listed once however many of its outputs
+	// the entry spends
+	setDepends := make([]string, 0, len(entry.ParentTx))
+	for parent := range entry.ParentTx {
+		setDepends = append(setDepends, util.TxID(parent.Tx.GetHash()).String())
 	}
+	sort.Strings(setDepends)
 	result.Depends = setDepends
+	result.Unbroadcast = mempool.GetInstance().IsUnbroadcastWithoutLock(entry.Tx.GetHash())
 
 	return &result
 }
@@ -552,47 +716,41 @@ func entryToJSON(entry *mempool.TxEntry) *btcjson.GetMempoolEntryRelativeInfoVer
 func handleGetMempoolDescendants(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetMempoolDescendantsCmd)
 
-	hash, err := util.GetHashFromStr(c.TxID)
+	txid, err := util.TxIDFromStr(c.TxID)
 	if err != nil {
 		return nil, rpcDecodeHexError(c.TxID)
 	}
 
-	entry := mempool.GetInstance().FindTx(*hash)
+	pool := mempool.GetInstance()
+	pool.RLock()
+	defer pool.RUnlock()
+	entry := pool.FindTxWithoutLock(txid.Hash())
 	if entry == nil {
 		return nil, btcjson.RPCError{
-			Code:    btcjson.RPCInvalidAddressOrKey,
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
 			Message: "Transaction not in mempool",
 		}
 	}
 
-	descendants := mempool.GetInstance().CalculateDescendantsWithLock(hash)
+	descendants := make(map[*mempool.TxEntry]struct{})
+	pool.CalculateDescendants(entry, descendants)
 	// CTxMemPool::CalculateDescendants will include the given tx
 	delete(descendants, entry)
-
-	if c.Verbose == nil || !*c.Verbose {
-		des := make([]string, 0)
-		for item := range descendants {
-			hash := item.Tx.GetHash()
-			des = append(des, hash.String())
-		}
-		return des, nil
-	}
-
-	infos := make(map[string]*btcjson.GetMempoolEntryRelativeInfoVerbose)
-	h := entry.Tx.GetHash()
-	infos[h.String()] = entryToJSON(entry)
-	return infos, nil
+	return mempoolEntriesToJSON(descendants, c.Verbose != nil && *c.Verbose), nil
 }
 
 func handleGetMempoolEntry(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetMempoolEntryCmd)
 
-	hash, err := util.GetHashFromStr(c.TxID)
+	txid, err := util.TxIDFromStr(c.TxID)
 	if err != nil {
 		return nil, rpcDecodeHexError(c.TxID)
 	}
 
-	entry := mempool.GetInstance().FindTx(*hash)
+	pool := mempool.GetInstance()
+	pool.RLock()
+	defer pool.RUnlock()
+	entry := pool.FindTxWithoutLock(txid.Hash())
 	if entry == nil {
 		return nil, btcjson.RPCError{
 			Code:    btcjson.ErrRPCInvalidAddressOrKey,
@@ -606,6 +764,7 @@ func handleGetMempoolEntry(s *Server, cmd interface{}, closeChan <-chan struct{}
 func handleGetMempoolInfo(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	pool := mempool.GetInstance()
 	ret := &btcjson.GetMempoolInfoResult{
+		Loaded:        lmempool.IsMempoolLoaded(),
 		Size:          pool.Size(),
 		Bytes:         pool.GetPoolAllTxSize(),
 		Usage:         pool.GetPoolUsage(),
@@ -615,47 +774,77 @@ func handleGetMempoolInfo(s *Server, cmd interface{}, closeChan <-chan struct{})
 	return ret, nil
 }
 
-func valueFromAmount(sizeLimit int64) float64 {
-	var nAbs int64
-	var strFormat string
-	if sizeLimit < 0 {
-		nAbs = -sizeLimit
-		strFormat = "-%d.%08d"
-	} else {
-		nAbs = sizeLimit
-		strFormat = "%d.%08d"
+func handleSaveMempool(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	err := lmempool.SaveMempool(filepath.Join(conf.Cfg.DataDir, lmempool.MempoolFileName))
+	if err == lmempool.ErrMempoolNotLoaded {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCMisc, "The mempool was not loaded yet")
 	}
+	if err != nil {
+		log.Error("savemempool: %v", err)
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCMisc, "Unable to dump mempool to disk")
+	}
+	return nil, nil
+}
 
-	quotient := nAbs / util.COIN
-	remainder := nAbs % util.COIN
-	strValue := fmt.Sprintf(strFormat, quotient, remainder)
-
-	result, err := strconv.ParseFloat(strValue, 64)
+// valueFromAmount converts satoshis to the BTC value sent to clients. It goes
+// through the exact decimal representation so the float64 marshals back to the
+// same digits.
+func valueFromAmount(sizeLimit int64) float64 {
+	result, err := strconv.ParseFloat(amount.FormatMoney(amount.Amount(sizeLimit)), 64)
 	if err != nil {
 		return 0
 	}
 	return result
 }
 
+// amountFromValue converts a BTC amount received from a client to satoshis.
+// The value may be a JSON number, which the command parser hands over as a
+// float64 or json.Number, or a string; either way it is parsed as a decimal so
+// no satoshi is lost to float rounding.
+func amountFromValue(value interface{}) (amount.Amount, error) {
+	var str string
+	switch v := value.(type) {
+	case float64:
+		str = strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		str = v.String()
+	case string:
+		str = v
+	default:
+		return 0, btcjson.NewRPCError(btcjson.ErrRPCType, "Amount is not a number or string")
+	}
+
+	ret, err := amount.ParseMoney(str)
+	if err != nil {
+		return 0, btcjson.NewRPCError(btcjson.ErrRPCType, "Invalid amount")
+	}
+	return ret, nil
+}
+
 func handleGetRawMempool(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetRawMempoolCmd)
 
 	pool := mempool.GetInstance()
 
 	if c.Verbose != nil && *c.Verbose {
-		infos := make(map[string]*btcjson.GetMempoolEntryRelativeInfoVerbose)
-		for hash, entry := range pool.GetAllTxEntry() {
-			infos[hash.String()] = entryToJSON(entry)
+		all := pool.GetAllTxEntry()
+		entries := make(map[*mempool.TxEntry]struct{}, len(all))
+		pool.RLock()
+		defer pool.RUnlock()
+		for hash := range all {
+			// skip the transactions removed since the snapshot
+			if entry := pool.FindTxWithoutLock(hash); entry != nil {
+				entries[entry] = struct{}{}
+			}
 		}
-		return infos, nil
+		return mempoolEntriesToJSON(entries, true), nil
 	}
 
 	// The response is simply an array of the transaction hashes if the verbose flag is not set.
 	txAll := pool.TxInfoAll()
 	txIds := make([]string, 0, len(txAll))
 	for _, txInfo := range txAll {
-		txHash := txInfo.Tx.GetHash()
-		txIds = append(txIds, txHash.String())
+		txIds = append(txIds, util.TxID(txInfo.Tx.GetHash()).String())
 	}
 
 	return txIds, nil
@@ -665,27 +854,18 @@ func handleGetTxOut(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 	c := cmd.(*btcjson.GetTxOutCmd)
 
 	// Convert the provided transaction hash hex to a Hash.
-	hash, err := util.GetHashFromStr(c.Txid)
+	txid, err := util.TxIDFromStr(c.Txid)
 	if err != nil {
 		return nil, rpcDecodeHexError(c.Txid)
 	}
 
-	outPoint := outpoint.NewOutPoint(*hash, c.Vout)
-	coinView := utxo.GetUtxoCacheInstance()
-
-	coin := coinView.GetCoin(outPoint)
+	outPoint := outpoint.NewOutPoint(txid.Hash(), c.Vout)
+	coin := getUnspentCoin(outPoint, c.IncludeMempool == nil || *c.IncludeMempool)
 	if coin == nil {
-		if c.IncludeMempool == nil || *c.IncludeMempool {
-			coin = mempool.GetInstance().GetCoin(outPoint)
-			if coin == nil || mempool.GetInstance().HasSpentOut(outPoint) {
-				return nil, nil
-			}
-		} else {
-			return nil, nil
-		}
+		return nil, nil
 	}
 
-	bestHash, err := coinView.GetBestBlock()
+	bestHash, err := utxo.GetUtxoCacheInstance().GetBestBlock()
 	if err != nil {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCNoNewestBlockInfo,
@@ -693,6 +873,12 @@ func handleGetTxOut(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 		}
 	}
 	index := chain.GetInstance().FindBlockIndex(bestHash)
+	if index == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCNoNewestBlockInfo,
+			Message: "Cannot get best block",
+		}
+	}
 
 	confirmations := int32(0)
 	if !coin.IsMempoolCoin() {
@@ -700,20 +886,218 @@ func handleGetTxOut(s *Server, cmd interface{}, closeChan <-chan struct{}) (inte
 	}
 
 	txOut := coin.GetTxOut()
-	amountValue := valueFromAmount(int64(coin.GetAmount()))
 	txOutReply := &btcjson.GetTxOutResult{
 		BestBlock:     index.GetBlockHash().String(),
 		Confirmations: confirmations,
-		Value:         strconv.FormatFloat(amountValue, 'f', -1, 64),
+		Value:         valueFromAmount(int64(coin.GetAmount())),
 		ScriptPubKey:  ScriptPubKeyToJSON(txOut.GetScriptPubKey(), true),
 		Coinbase:      coin.IsCoinBase(),
 	}
 
-	return &txOutReply, nil
+	return txOutReply, nil
+}
+
+// coinsView returns the UTXO set, overlaid with the mempool when
+// includeMempool is set: the outputs of the mempool transactions are
+// available and the outputs they spend are spent.
+func coinsView(includeMempool bool) *mempool.CoinsViewMempool {
+	var pool *mempool.TxMempool
+	if includeMempool {
+		pool = mempool.GetInstance()
+	}
+	return mempool.NewCoinsViewMempool(utxo.GetUtxoCacheInstance(), pool)
+}
+
+// getUnspentCoin returns the unspent coin of the outpoint in the UTXO set,
+// overlaid with the mempool when includeMempool is set, or nil.
+func getUnspentCoin(outPoint *outpoint.OutPoint, includeMempool bool) *utxo.Coin {
+	return coinsView(includeMempool).GetCoin(outPoint)
+}
+
+// minCoinsWalkFlushInterval is how long the coins flushed for a walk of the
+// UTXO set are reused by the next walks, rather than flushing on every call.
+const minCoinsWalkFlushInterval = time.Minute
+
+// coinsWalkFlush records when the coins cache was last flushed for a walk.
+var coinsWalkFlush struct {
+	sync.Mutex
+	last time.Time
+}
+
+// flushCoinsForWalk prepares the coins database for a walk of the UTXO set:
+// the walks read the database only, so the coins cache is flushed to it
+// first. Flushing is expensive and at most done once per
+// minCoinsWalkFlushInterval, a walk in between sees the UTXO set as of the
+// previous flush, so the callers report the best block of the database
+// instead of the tip. The caller holds persist.CsMain, so that no block is
+// connected or disconnected during the flush.
+func flushCoinsForWalk() error {
+	coinsWalkFlush.Lock()
+	defer coinsWalkFlush.Unlock()
+	if time.Since(coinsWalkFlush.last) < minCoinsWalkFlushInterval {
+		return nil
+	}
+	if err := disk.FlushStateToDisk(disk.FlushStateAlways, 0); err != nil {
+		return btcjson.NewRPCError(btcjson.ErrRPCInternal.Code, "Unable to flush the UTXO set")
+	}
+	coinsWalkFlush.last = time.Now()
+	return nil
+}
+
+// openCoinsCursor flushes the coins for a walk and opens a cursor on the coins
+// database, returning the index of the block the walked coins are at.
+func openCoinsCursor() (*utxo.CoinsCursor, *blockindex.BlockIndex, error) {
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+	if err := flushCoinsForWalk(); err != nil {
+		return nil, nil, err
+	}
+	coinsDB := utxo.GetUtxoCacheInstance().GetCoinsDB()
+	cursor, err := coinsDB.Cursor()
+	if err != nil {
+		return nil, nil, btcjson.NewRPCError(btcjson.ErrRPCInternal.Code, "Unable to read UTXO set")
+	}
+	bestBlock := cursor.GetBestBlock()
+	index := chain.GetInstance().FindBlockIndex(bestBlock)
+	if index == nil {
+		cursor.Close()
+		return nil, nil, btcjson.NewRPCError(btcjson.ErrRPCInternal.Code, "Unable to read UTXO set")
+	}
+	return cursor, index, nil
 }
 
 func handleGetTxoutSetInfo(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	return nil, nil
+	persist.CsMain.Lock()
+	err := flushCoinsForWalk()
+	persist.CsMain.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	// the walk reads a snapshot of the database, the lock is not held for it
+	coinsDB := utxo.GetUtxoCacheInstance().GetCoinsDB()
+	stats, err := lutxo.GetCoinsStats(&coinsDB, closeChan)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInternal.Code, "Unable to read UTXO set")
+	}
+	persist.CsMain.Lock()
+	index := chain.GetInstance().FindBlockIndex(stats.BestBlock)
+	persist.CsMain.Unlock()
+	if index == nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInternal.Code, "Unable to read UTXO set")
+	}
+
+	return &btcjson.GetTxOutSetInfoResult{
+		Height:         index.Height,
+		BestBlock:      stats.BestBlock.String(),
+		Transactions:   stats.Transactions,
+		TxOuts:         stats.TxOuts,
+		BogoSize:       stats.BogoSize,
+		HashSerialized: stats.HashSerialized.String(),
+		DiskSize:       stats.DiskSize,
+		TotalAmount:    valueFromAmount(int64(stats.TotalAmount)),
+	}, nil
+}
+
+func handleGetUTXODistribution(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetUTXODistributionCmd)
+	sampleRate := uint32(1)
+	if c.SampleRate != nil && *c.SampleRate > 1 {
+		sampleRate = *c.SampleRate
+	}
+
+	cursor, index, err := openCoinsCursor()
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+	dist, err := lutxo.GetCoinsDistribution(cursor, index.Height, sampleRate, closeChan)
+	if err != nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInternal.Code, "Unable to read UTXO set")
+	}
+
+	result := &btcjson.GetUTXODistributionResult{
+		Height:       index.Height,
+		BestBlock:    util.BlockHash(dist.BestBlock).String(),
+		SampleRate:   sampleRate,
+		TxOuts:       dist.TxOuts,
+		Sampled:      dist.Sampled,
+		ByValue:      make([]btcjson.UTXODistributionBucket, len(dist.ByValue)),
+		ByAge:        make([]btcjson.UTXODistributionBucket, len(dist.ByAge)),
+		ByScriptType: make(map[string]btcjson.UTXODistributionBucket, len(dist.ByScriptType)),
+	}
+	for i, bucket := range dist.ByValue {
+		result.ByValue[i] = distributionBucketToJSON(bucket)
+		if i < len(lutxo.ValueBucketBounds) {
+			max := valueFromAmount(int64(lutxo.ValueBucketBounds[i]))
+			result.ByValue[i].Max = &max
+		}
+	}
+	for i, bucket := range dist.ByAge {
+		result.ByAge[i] = distributionBucketToJSON(bucket)
+		if i < len(lutxo.AgeBucketBounds) {
+			max := float64(lutxo.AgeBucketBounds[i])
+			result.ByAge[i].Max = &max
+		}
+	}
+	for name, bucket := range dist.ByScriptType {
+		result.ByScriptType[name] = distributionBucketToJSON(*bucket)
+	}
+	return result, nil
+}
+
+func distributionBucketToJSON(bucket lutxo.CoinsBucket) btcjson.UTXODistributionBucket {
+	return btcjson.UTXODistributionBucket{
+		Count:  bucket.Count,
+		Amount: valueFromAmount(int64(bucket.Amount)),
+	}
+}
+
+// maxBlockLocations is the number of blocks getblocklocations reports at most.
+const maxBlockLocations = 2000
+
+func handleGetBlockLocations(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetBlockLocationsCmd)
+
+	hash, err := util.BlockHashFromStr(c.BlockHash)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.BlockHash)
+	}
+	nBlocks := int32(1)
+	if c.NBlocks != nil {
+		nBlocks = *c.NBlocks
+	}
+	if nBlocks < 1 || nBlocks > maxBlockLocations {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			fmt.Sprintf("nblocks must be between 1 and %d", maxBlockLocations))
+	}
+
+	gChain := chain.GetInstance()
+	index := gChain.FindBlockIndex(hash.Hash())
+	if index == nil {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCBlockNotFound, "Block not found")
+	}
+
+	results := make([]btcjson.BlockLocationResult, 0, nBlocks)
+	for ; index != nil && int32(len(results)) < nBlocks; index = gChain.Next(index) {
+		result := btcjson.BlockLocationResult{
+			Hash:   index.GetBlockHash().String(),
+			Height: index.Height,
+			File:   -1,
+		}
+		if index.HasData() {
+			pos := index.GetBlockPos()
+			result.File = pos.File
+			result.DataFile = disk.GetBlockPosFilename(pos, "blk")
+			result.DataOffset = &pos.Pos
+		}
+		if index.HasUndo() {
+			pos := index.GetUndoPos()
+			result.UndoFile = disk.GetBlockPosFilename(pos, "rev")
+			result.UndoOffset = &pos.Pos
+		}
+		results = append(results, result)
+	}
+	return results, nil
 }
 
 func getPrunMode() (bool, error) {
@@ -778,91 +1162,141 @@ func handlePruneBlockChain(s *Server, cmd interface{}, closeChan <-chan struct{}
 
 // handleVerifyChain implements the verifychain command.
 func handleVerifyChain(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	/*	c := cmd.(*btcjson.VerifyChainCmd)
+	c := cmd.(*btcjson.VerifyChainCmd)
 
-		var checkLevel, checkDepth int32
-		if c.CheckLevel != nil {
-			checkLevel = *c.CheckLevel
-		}
-		if c.CheckDepth != nil {
-			checkDepth = *c.CheckDepth
-		}
+	checkLevel, checkDepth := int32(lchain.DefaultCheckLevel), int32(lchain.DefaultCheckBlocks)
+	if c.CheckLevel != nil {
+		checkLevel = *c.CheckLevel
+	}
+	if c.CheckDepth != nil {
+		checkDepth = *c.CheckDepth
+	}
 
-		return VerifyDB(consensus.ActiveNetParams, utxo.GetUtxoCacheInstance(), checkLevel, checkDepth), nil*/ // todo open
-	return nil, nil
+	// the checks above level 2 reconnect blocks on top of the coins of the
+	// tip, which must not move meanwhile
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+	if err := lchain.VerifyDB(checkLevel, checkDepth, closeChan); err != nil {
+		log.Error("verifychain: %v", err)
+		return false, nil
+	}
+	return true, nil
 }
 
 func handlePreciousblock(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	/*	c := cmd.(*btcjson.PreciousBlockCmd)
-		hash, err := util.GetHashFromStr(c.BlockHash)
-		if err != nil {
-			return nil, err
-		}
-		blockIndex := chain.GetInstance().FindBlockIndex(*hash)
-		if blockIndex == nil {
-			return nil, &btcjson.RPCError{
-				Code:    btcjson.ErrRPCBlockNotFound,
-				Message: "Block not found",
-			}
+	c := cmd.(*btcjson.PreciousBlockCmd)
+	hash, err := util.BlockHashFromStr(c.BlockHash)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.BlockHash)
+	}
+
+	gChain := chain.GetInstance()
+	index := gChain.FindBlockIndex(hash.Hash())
+	if index == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Block not found",
 		}
-		state := valistate.ValidationState{}
-		chain.PreciousBlock(consensus.ActiveNetParams, &state, blockIndex)
-		if !state.IsValid() {
+	}
 
-		}*/ // todo open
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+	gChain.PreciousBlock(index)
+	if err := lchain.ActivateBestChain(nil); err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDatabase,
+			Message: err.Error(),
+		}
+	}
 	return nil, nil
 }
 
 func handlInvalidateBlock(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	//c := cmd.(*btcjson.InvalidateBlockCmd)
-	//hash, _ := util.GetHashFromStr(c.BlockHash)
-	/*	state := valistate.ValidationState{}
-
-		if len(chain.MapBlockIndex.Data) == 0 {
-			return nil, &btcjson.RPCError{
-				Code:    btcjson.ErrRPCInvalidAddressOrKey,
-				Message: "Block not found",
-			}
+	c := cmd.(*btcjson.InvalidateBlockCmd)
+	hash, err := util.BlockHashFromStr(c.BlockHash)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.BlockHash)
+	}
 
-			//blkIndex := chain.MapBlockIndex.Data[*hash]
-			//chain.InvalidateBlock()                  // todo
-		}
-		if state.IsValid() {
-			//chain.ActivateBestChain()        // todo
+	index := chain.GetInstance().FindBlockIndex(hash.Hash())
+	if index == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Block not found",
 		}
+	}
 
-		if state.IsInvalid() {
-			return nil, &btcjson.RPCError{
-				Code:    btcjson.ErrRPCDatabase,
-				Message: state.GetRejectReason(),
-			}
-		}*/ // todo open
-
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+	err = lchain.InvalidateBlock(index)
+	if err == nil {
+		err = lchain.ActivateBestChain(nil)
+	}
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDatabase,
+			Message: err.Error(),
+		}
+	}
 	return nil, nil
 }
 
 func handleReconsiderBlock(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	/*	c := cmd.(*btcjson.ReconsiderBlockCmd)
-		hash, _ := util.GetHashFromStr(c.BlockHash)
+	c := cmd.(*btcjson.ReconsiderBlockCmd)
+	hash, err := util.BlockHashFromStr(c.BlockHash)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.BlockHash)
+	}
 
-		index := chain.GetInstance().FindBlockIndex(*hash)
-		if index == nil {
-			return nil, &btcjson.RPCError{
-				Code:    btcjson.ErrRPCInvalidAddressOrKey,
-				Message: "Block not found",
-			}
+	index := chain.GetInstance().FindBlockIndex(hash.Hash())
+	if index == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Block not found",
 		}
-		chain.ResetBlockFailureFlags(index)
+	}
 
-		state := valistate.ValidationState{}
-		chain.ActivateBestChain(consensus.ActiveNetParams, &state, nil)
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+	lchain.ResetBlockFailureFlags(index)
+	if err := lchain.ActivateBestChain(nil); err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDatabase,
+			Message: err.Error(),
+		}
+	}
+	return nil, nil
+}
 
-		if state.IsInvalid() {
-			return nil, &btcjson.RPCError{
-				Code:    btcjson.ErrRPCDatabase,
-				Message: state.FormatStateMessage(),
-			}
-		}*/ // todo open
+// maxRollbackDepth is the number of blocks rollbackchain disconnects without
+// its override argument.
+const maxRollbackDepth = 100
+
+func handleRollbackChain(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.RollbackChainCmd)
+	if !conf.Cfg.RPC.RPCAllowRollback || model.ActiveNetParams.BitcoinNet == wire.MainNet {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCMisc,
+			"rollbackchain is only available on test networks with RPCAllowRollback set")
+	}
+
+	persist.CsMain.Lock()
+	defer persist.CsMain.Unlock()
+	gChain := chain.GetInstance()
+	height := gChain.Height()
+	if c.Height < 0 || c.Height > height {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Block height out of range")
+	}
+	if height-c.Height > maxRollbackDepth && !*c.Override {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+			fmt.Sprintf("Rolling back more than %d blocks requires override", maxRollbackDepth))
+	}
+
+	if err := lchain.RollbackChain(c.Height); err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDatabase,
+			Message: err.Error(),
+		}
+	}
 	return nil, nil
 }
 
@@ -880,6 +1314,6 @@ func handleWaitForBlockHeight(s *Server, cmd interface{}, closeChan <-chan struc
 
 func registerBlockchainRPCCommands() {
 	for name, handler := range blockchainHandlers {
-		appendCommand(name, handler)
+		appendCommand("blockchain", name, handler)
 	}
 }
@@ -0,0 +1,511 @@
+package rpc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/logic/lchain"
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/mempool"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/model/txin"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/persist"
+	"github.com/copernet/copernicus/persist/disk"
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+)
+
+func TestGetUTXODistributionBestBlock(t *testing.T) {
+	path, err := ioutil.TempDir("", "utxodistribution")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	genesis, _ := initWalkedCoins(t, path)
+
+	// the tip is one block ahead of the coins database
+	ret, err := handleGetUTXODistribution(nil, &btcjson.GetUTXODistributionCmd{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := ret.(*btcjson.GetUTXODistributionResult)
+	if result.Height != genesis.Height || result.BestBlock != genesis.GetBlockHash().String() {
+		t.Errorf("distribution reported at %d %s, expect the block of the coins database",
+			result.Height, result.BestBlock)
+	}
+	if result.TxOuts != 3 || result.Sampled != 3 {
+		t.Errorf("got %d outputs and %d sampled, want 3 and 3", result.TxOuts, result.Sampled)
+	}
+}
+
+func TestGetChainStates(t *testing.T) {
+	path, err := ioutil.TempDir("", "getchainstates")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	_, tip := initWalkedCoins(t, path)
+	persist.InitPersistGlobal()
+
+	check := func(headers int32) {
+		ret, err := handleGetChainStates(nil, &btcjson.GetChainStatesCmd{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := ret.(*btcjson.GetChainStatesResult)
+		if result.Headers != headers || len(result.ChainStates) != 1 {
+			t.Fatalf("got %d headers and %d chainstates, expect %d and one", result.Headers,
+				len(result.ChainStates), headers)
+		}
+		active := result.ChainStates[0]
+		if active.Blocks != tip.Height || active.BestBlockHash != tip.GetBlockHash().String() ||
+			!active.Validated || active.SnapshotBlockHash != "" {
+			t.Errorf("active chainstate %+v, expect the validated chain at %d", active, tip.Height)
+		}
+	}
+	check(tip.Height)
+
+	// headers received ahead of the blocks count in headers only
+	prev := tip
+	for i := 0; i < 2; i++ {
+		header := block.BlockHeader{HashPrevBlock: *prev.GetBlockHash(), Time: prev.Header.Time + 600, Bits: 0x207fffff}
+		index := blockindex.NewBlockIndex(&header)
+		if err := chain.GetInstance().AddToIndexMap(index); err != nil {
+			t.Fatal(err)
+		}
+		prev = index
+	}
+	check(tip.Height + 2)
+}
+
+func TestGetTxOut(t *testing.T) {
+	path, err := ioutil.TempDir("", "gettxout")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	genesis, _ := initWalkedCoins(t, path)
+	mempool.InitMempool()
+
+	// a mempool transaction spending 02:1
+	hash1, hash2 := util.HashFromString("01"), util.HashFromString("02")
+	spend := tx.NewTx(0, tx.TxVersion)
+	spend.AddTxIn(txin.NewTxIn(&outpoint.OutPoint{Hash: *hash2, Index: 1},
+		script.NewScriptRaw(scanScriptTrue), script.SequenceFinal))
+	spend.AddTxOut(txout.NewTxOut(150, script.NewScriptRaw(scanScriptTrue)))
+	entry := mempool.NewTxentry(spend, 50, 100, 1, mempool.LockPoints{}, 0, false)
+	if err := mempool.GetInstance().AddTx(entry, map[*mempool.TxEntry]struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	spendHash := spend.GetHash()
+
+	tests := []struct {
+		name           string
+		hash           *util.Hash
+		vout           uint32
+		includeMempool bool
+		value          float64 // 0 when no output is returned
+		confirmations  int32
+		coinbase       bool
+	}{
+		{"confirmed coinbase", hash1, 0, true, 0.00001, 1, true},
+		{"confirmed output", hash2, 0, true, 0.000003, 1, false},
+		{"spent in the mempool", hash2, 1, true, 0, 0, false},
+		{"spent in the mempool, mempool excluded", hash2, 1, false, 0.000002, 1, false},
+		{"mempool output", &spendHash, 0, true, 0.0000015, 0, false},
+		{"mempool output, mempool excluded", &spendHash, 0, false, 0, 0, false},
+		{"unknown vout", hash1, 1, true, 0, 0, false},
+	}
+	for _, test := range tests {
+		includeMempool := test.includeMempool
+		cmd := &btcjson.GetTxOutCmd{Txid: util.TxID(*test.hash).String(), Vout: test.vout,
+			IncludeMempool: &includeMempool}
+		ret, err := handleGetTxOut(nil, cmd, nil)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if test.value == 0 {
+			if ret != nil {
+				t.Errorf("%s: got %+v, expect no output", test.name, ret)
+			}
+			continue
+		}
+		result, ok := ret.(*btcjson.GetTxOutResult)
+		if !ok {
+			t.Errorf("%s: got %v, expect an output", test.name, ret)
+			continue
+		}
+		if result.Value != test.value || result.Confirmations != test.confirmations ||
+			result.Coinbase != test.coinbase || result.BestBlock != genesis.GetBlockHash().String() {
+			t.Errorf("%s: got %+v", test.name, result)
+		}
+	}
+
+	if _, err := handleGetTxOut(nil, &btcjson.GetTxOutCmd{Txid: "zz"}, nil); err == nil ||
+		toRPCError(err).Code != btcjson.ErrRPCDecodeHexString {
+		t.Errorf("malformed txid got error %v", err)
+	}
+}
+
+func TestMempoolEntriesToJSON(t *testing.T) {
+	mempool.InitMempool()
+	pool := mempool.GetInstance()
+	noLimit := uint64(math.MaxUint64)
+
+	// a parent with two outputs spent by a child, and a grandchild
+	addTx := func(fee int64, outs int, prevs ...*outpoint.OutPoint) *tx.Tx {
+		transaction := tx.NewTx(0, tx.TxVersion)
+		for _, prev := range prevs {
+			transaction.AddTxIn(txin.NewTxIn(prev, script.NewScriptRaw([]byte{opcodes.OP_TRUE}), script.SequenceFinal))
+		}
+		for i := 0; i < outs; i++ {
+			transaction.AddTxOut(txout.NewTxOut(10000, script.NewScriptRaw([]byte{opcodes.OP_TRUE})))
+		}
+		ancestors, err := pool.CalculateMemPoolAncestors(transaction, noLimit, noLimit, noLimit, noLimit, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := mempool.NewTxentry(transaction, fee, 100, 1, mempool.LockPoints{}, 0, false)
+		if err := pool.AddTx(entry, ancestors); err != nil {
+			t.Fatal(err)
+		}
+		return transaction
+	}
+	parent := addTx(1000, 2, outpoint.NewOutPoint(util.HashOne, 0))
+	child := addTx(2000, 1, outpoint.NewOutPoint(parent.GetHash(), 0), outpoint.NewOutPoint(parent.GetHash(), 1))
+	grandchild := addTx(3000, 1, outpoint.NewOutPoint(child.GetHash(), 0))
+	pool.PrioritiseTransaction(child.GetHash(), 500)
+
+	txid := func(transaction *tx.Tx) string { return util.TxID(transaction.GetHash()).String() }
+	sorted := func(txs ...*tx.Tx) []string {
+		txids := make([]string, 0, len(txs))
+		for _, transaction := range txs {
+			txids = append(txids, txid(transaction))
+		}
+		sort.Strings(txids)
+		return txids
+	}
+	verbose, brief := true, false
+
+	ret, err := handleGetMempoolEntry(nil, &btcjson.GetMempoolEntryCmd{TxID: txid(child)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := ret.(*btcjson.GetMempoolEntryRelativeInfoVerbose)
+	fees := btcjson.MempoolEntryFees{
+		Base:       valueFromAmount(2000),
+		Modified:   valueFromAmount(2500),
+		Ancestor:   valueFromAmount(3500),
+		Descendant: valueFromAmount(5500),
+	}
+	if entry.Fee != fees.Base || entry.ModifiedFee != fees.Modified || entry.Fees != fees {
+		t.Errorf("child fee %v modified %v and fees %+v, expect %+v", entry.Fee, entry.ModifiedFee, entry.Fees, fees)
+	}
+	if !reflect.DeepEqual(entry.Depends, []string{txid(parent)}) {
+		t.Errorf("child depends on %v, expect the parent once", entry.Depends)
+	}
+
+	ret, err = handleGetMempoolAncestors(nil, &btcjson.GetMempoolAncestorsCmd{TxID: txid(grandchild), Verbose: &brief}, nil)
+	if err != nil || !reflect.DeepEqual(ret, sorted(parent, child)) {
+		t.Errorf("ancestors %v, error %v, expect %v", ret, err, sorted(parent, child))
+	}
+	ret, err = handleGetMempoolAncestors(nil, &btcjson.GetMempoolAncestorsCmd{TxID: txid(grandchild), Verbose: &verbose}, nil)
+	if infos, ok := ret.(map[string]*btcjson.GetMempoolEntryRelativeInfoVerbose); err != nil || !ok || len(infos) != 2 ||
+		infos[txid(parent)] == nil || infos[txid(child)] == nil || infos[txid(child)].Fees != fees {
+		t.Errorf("verbose ancestors %v, error %v", ret, err)
+	}
+
+	ret, err = handleGetMempoolDescendants(nil, &btcjson.GetMempoolDescendantsCmd{TxID: txid(parent), Verbose: &brief}, nil)
+	if err != nil || !reflect.DeepEqual(ret, sorted(child, grandchild)) {
+		t.Errorf("descendants %v, error %v, expect %v", ret, err, sorted(child, grandchild))
+	}
+	ret, err = handleGetMempoolDescendants(nil, &btcjson.GetMempoolDescendantsCmd{TxID: txid(parent), Verbose: &verbose}, nil)
+	if infos, ok := ret.(map[string]*btcjson.GetMempoolEntryRelativeInfoVerbose); err != nil || !ok || len(infos) != 2 ||
+		infos[txid(child)] == nil || infos[txid(grandchild)] == nil {
+		t.Errorf("verbose descendants %v, error %v", ret, err)
+	}
+	ret, err = handleGetMempoolDescendants(nil, &btcjson.GetMempoolDescendantsCmd{TxID: txid(grandchild)}, nil)
+	if err != nil || len(ret.([]string)) != 0 {
+		t.Errorf("descendants of the grandchild %v, error %v", ret, err)
+	}
+
+	ret, err = handleGetRawMempool(nil, &btcjson.GetRawMempoolCmd{Verbose: &verbose}, nil)
+	if infos, ok := ret.(map[string]*btcjson.GetMempoolEntryRelativeInfoVerbose); err != nil || !ok || len(infos) != 3 ||
+		infos[txid(child)] == nil || infos[txid(child)].Fees != fees {
+		t.Errorf("verbose mempool %v, error %v", ret, err)
+	}
+
+	unknown := strings.Repeat("03", 32)
+	tests := []struct {
+		name    string
+		call    func() (interface{}, error)
+		errCode btcjson.RPCErrorCode
+	}{
+		{"entry of a malformed txid", func() (interface{}, error) {
+			return handleGetMempoolEntry(nil, &btcjson.GetMempoolEntryCmd{TxID: "zz"}, nil)
+		}, btcjson.ErrRPCDecodeHexString},
+		{"entry not in the mempool", func() (interface{}, error) {
+			return handleGetMempoolEntry(nil, &btcjson.GetMempoolEntryCmd{TxID: unknown}, nil)
+		}, btcjson.ErrRPCInvalidAddressOrKey},
+		{"ancestors of a malformed txid", func() (interface{}, error) {
+			return handleGetMempoolAncestors(nil, &btcjson.GetMempoolAncestorsCmd{TxID: "zz"}, nil)
+		}, btcjson.ErrRPCDecodeHexString},
+		{"ancestors not in the mempool", func() (interface{}, error) {
+			return handleGetMempoolAncestors(nil, &btcjson.GetMempoolAncestorsCmd{TxID: unknown}, nil)
+		}, btcjson.ErrRPCInvalidAddressOrKey},
+		{"descendants not in the mempool", func() (interface{}, error) {
+			return handleGetMempoolDescendants(nil, &btcjson.GetMempoolDescendantsCmd{TxID: unknown}, nil)
+		}, btcjson.ErrRPCInvalidAddressOrKey},
+	}
+	for _, test := range tests {
+		_, err := test.call()
+		if rpcErr := toRPCError(err); rpcErr == nil || rpcErr.Code != test.errCode {
+			t.Errorf("%s: got error %v, expect code %d", test.name, err, test.errCode)
+		}
+	}
+}
+
+func TestRollbackChainChecks(t *testing.T) {
+	path, err := ioutil.TempDir("", "rollbackchain")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	_, tip := initWalkedCoins(t, path)
+
+	// headers on top of the tip, rolling back the chain is only checked
+	prev := tip
+	for prev.Height < maxRollbackDepth+10 {
+		header := block.BlockHeader{HashPrevBlock: *prev.GetBlockHash(), Time: prev.Header.Time + 600, Bits: 0x207fffff}
+		index := blockindex.NewBlockIndex(&header)
+		index.Height = prev.Height + 1
+		index.Prev = prev
+		prev = index
+	}
+	gChain := chain.GetInstance()
+	gChain.SetTip(prev)
+	height := prev.Height
+
+	defer func(allow bool) { conf.Cfg.RPC.RPCAllowRollback = allow }(conf.Cfg.RPC.RPCAllowRollback)
+	defer func(params *model.BitcoinParams) { model.ActiveNetParams = params }(model.ActiveNetParams)
+	override := true
+	tests := []struct {
+		name     string
+		allow    bool
+		params   *model.BitcoinParams
+		height   int32
+		override *bool
+		errCode  btcjson.RPCErrorCode
+	}{
+		{"not allowed", false, &model.RegressionNetParams, height, nil, btcjson.ErrRPCMisc},
+		{"mainnet", true, &model.MainNetParams, height, nil, btcjson.ErrRPCMisc},
+		{"negative height", true, &model.RegressionNetParams, -1, nil, btcjson.ErrRPCInvalidParameter},
+		{"above the tip", true, &model.RegressionNetParams, height + 1, nil, btcjson.ErrRPCInvalidParameter},
+		{"too deep", true, &model.RegressionNetParams, height - maxRollbackDepth - 1, nil,
+			btcjson.ErrRPCInvalidParameter},
+		{"at the tip", true, &model.RegressionNetParams, height, nil, 0},
+		{"at the tip with override", true, &model.RegressionNetParams, height, &override, 0},
+	}
+	for _, test := range tests {
+		conf.Cfg.RPC.RPCAllowRollback = test.allow
+		model.ActiveNetParams = test.params
+		notOverridden := false
+		cmd := &btcjson.RollbackChainCmd{Height: test.height, Override: test.override}
+		if cmd.Override == nil {
+			cmd.Override = &notOverridden
+		}
+		_, err := handleRollbackChain(nil, cmd, nil)
+		if test.errCode == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+		} else if rpcErr := toRPCError(err); rpcErr == nil || rpcErr.Code != test.errCode {
+			t.Errorf("%s: got error %v, expect code %d", test.name, err, test.errCode)
+		}
+		if gChain.Tip() != prev {
+			t.Fatalf("%s: the tip moved to %d", test.name, gChain.Height())
+		}
+	}
+}
+
+func TestGetBlockLocations(t *testing.T) {
+	path, err := ioutil.TempDir("", "getblocklocations")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	genesis, tip := initWalkedCoins(t, path)
+	defer func(dataDir string) { conf.Cfg.DataDir = dataDir }(conf.Cfg.DataDir)
+	conf.Cfg.DataDir = path
+
+	// the tip is stored in the third block file with its undo data, the
+	// genesis block has no data
+	tip.File = 2
+	tip.DataPos = 1234
+	tip.UndoPos = 567
+	tip.AddStatus(blockindex.BlockHaveData | blockindex.BlockHaveUndo)
+	defer func() { tip.Status &^= blockindex.BlockHaveData | blockindex.BlockHaveUndo }()
+	dataPos, undoPos := tip.GetBlockPos(), tip.GetUndoPos()
+	genesisLocation := btcjson.BlockLocationResult{Hash: genesis.GetBlockHash().String(), Height: 0, File: -1}
+	tipLocation := btcjson.BlockLocationResult{
+		Hash:       tip.GetBlockHash().String(),
+		Height:     1,
+		File:       2,
+		DataFile:   disk.GetBlockPosFilename(dataPos, "blk"),
+		DataOffset: &dataPos.Pos,
+		UndoFile:   disk.GetBlockPosFilename(undoPos, "rev"),
+		UndoOffset: &undoPos.Pos,
+	}
+
+	nBlocks := func(n int32) *int32 { return &n }
+	tests := []struct {
+		name      string
+		blockHash string
+		nBlocks   *int32
+		locations []btcjson.BlockLocationResult
+		errCode   btcjson.RPCErrorCode
+	}{
+		{"default", genesis.GetBlockHash().String(), nil, []btcjson.BlockLocationResult{genesisLocation}, 0},
+		{"following blocks", genesis.GetBlockHash().String(), nBlocks(10),
+			[]btcjson.BlockLocationResult{genesisLocation, tipLocation}, 0},
+		{"tip", tip.GetBlockHash().String(), nBlocks(2), []btcjson.BlockLocationResult{tipLocation}, 0},
+		{"no blocks", tip.GetBlockHash().String(), nBlocks(0), nil, btcjson.ErrRPCInvalidParameter},
+		{"too many blocks", tip.GetBlockHash().String(), nBlocks(maxBlockLocations + 1), nil,
+			btcjson.ErrRPCInvalidParameter},
+		{"malformed hash", "zz", nil, nil, btcjson.ErrRPCDecodeHexString},
+		{"unknown block", strings.Repeat("03", 32), nil, nil, btcjson.ErrRPCBlockNotFound},
+	}
+	for _, test := range tests {
+		ret, err := handleGetBlockLocations(nil, &btcjson.GetBlockLocationsCmd{BlockHash: test.blockHash,
+			NBlocks: test.nBlocks}, nil)
+		if test.errCode != 0 {
+			if rpcErr := toRPCError(err); rpcErr == nil || rpcErr.Code != test.errCode {
+				t.Errorf("%s: got error %v, expect code %d", test.name, err, test.errCode)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(ret, test.locations) {
+			t.Errorf("%s: got %+v, error %v, expect %+v", test.name, ret, err, test.locations)
+		}
+	}
+}
+
+func TestGetDifficultyHistory(t *testing.T) {
+	path, err := ioutil.TempDir("", "difficultyhistory")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	_, tip := initWalkedCoins(t, path)
+
+	// headers on top of the tip, long enough for a range beyond the limit
+	prev := tip
+	for prev.Height <= maxDifficultyHistoryHeights {
+		header := block.BlockHeader{HashPrevBlock: *prev.GetBlockHash(), Time: prev.Header.Time + 600, Bits: 0x207fffff}
+		if prev.Height == 1 {
+			header.Bits = 0x1d00ffff
+		}
+		index := blockindex.NewBlockIndex(&header)
+		index.Height = prev.Height + 1
+		index.Prev = prev
+		prev = index
+	}
+	chain.GetInstance().SetTip(prev)
+	height := prev.Height
+
+	endHeight := func(height int32) *int32 { return &height }
+	tests := []struct {
+		name        string
+		startHeight int32
+		endHeight   *int32
+		heights     []int32
+		errCode     btcjson.RPCErrorCode
+	}{
+		{"up to the tip", height - 1, nil, []int32{height - 1, height}, 0},
+		{"default end height", height, endHeight(-1), []int32{height}, 0},
+		{"range", 2, endHeight(4), []int32{2, 3, 4}, 0},
+		{"negative start height", -1, endHeight(4), nil, btcjson.ErrRPCOutOfRange},
+		{"above the tip", 2, endHeight(height + 1), nil, btcjson.ErrRPCOutOfRange},
+		{"reversed range", 4, endHeight(3), nil, btcjson.ErrRPCOutOfRange},
+		{"range too large", 0, endHeight(maxDifficultyHistoryHeights), nil, btcjson.ErrRPCInvalidParameter},
+		{"largest range", 1, endHeight(maxDifficultyHistoryHeights), nil, 0},
+	}
+	for _, test := range tests {
+		cmd := &btcjson.GetDifficultyHistoryCmd{StartHeight: test.startHeight, EndHeight: test.endHeight}
+		result, err := handleGetDifficultyHistory(nil, cmd, nil)
+		if test.errCode != 0 {
+			if err == nil || toRPCError(err).Code != test.errCode {
+				t.Errorf("%s: got error %v, expect code %d", test.name, err, test.errCode)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		entries := result.([]btcjson.DifficultyHistoryEntry)
+		if test.heights == nil {
+			if len(entries) != maxDifficultyHistoryHeights {
+				t.Errorf("%s: got %d entries", test.name, len(entries))
+			}
+			continue
+		}
+		if len(entries) != len(test.heights) {
+			t.Errorf("%s: got %d entries, expect %d", test.name, len(entries), len(test.heights))
+			continue
+		}
+		for i, entry := range entries {
+			index := prev.GetAncestor(test.heights[i])
+			if entry.Height != test.heights[i] || entry.Hash != index.GetBlockHash().String() ||
+				entry.Time != index.Header.Time || entry.MedianTime != index.GetMedianTimePast() {
+				t.Errorf("%s: entry %d is %+v", test.name, i, entry)
+			}
+		}
+	}
+
+	cmd := &btcjson.GetDifficultyHistoryCmd{StartHeight: 2, EndHeight: endHeight(2)}
+	result, err := handleGetDifficultyHistory(nil, cmd, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := result.([]btcjson.DifficultyHistoryEntry)[0]
+	if entry.Bits != "1d00ffff" || entry.Difficulty != 1 ||
+		entry.Target != "00000000ffff0000000000000000000000000000000000000000000000000000" {
+		t.Errorf("got proof of work %s, %v and %s", entry.Bits, entry.Difficulty, entry.Target)
+	}
+}
+
+func TestVerifyChainDefaults(t *testing.T) {
+	request, err := btcjson.NewRequest(1, "verifychain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := btcjson.UnmarshalCmd(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := cmd.(*btcjson.VerifyChainCmd)
+	if *c.CheckLevel != lchain.DefaultCheckLevel || *c.CheckDepth != lchain.DefaultCheckBlocks {
+		t.Errorf("verifychain defaults to level %d and %d blocks, expect %d and %d",
+			*c.CheckLevel, *c.CheckDepth, lchain.DefaultCheckLevel, lchain.DefaultCheckBlocks)
+	}
+	help := fmt.Sprintf("default=%d, 0=all", lchain.DefaultCheckBlocks)
+	if !strings.Contains(verifychainDesc, help) {
+		t.Errorf("the help of verifychain does not tell %q", help)
+	}
+}
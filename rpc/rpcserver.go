@@ -1,20 +1,20 @@
 package rpc
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"crypto/subtle"
 	"crypto/tls"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
 	"runtime"
-	"strconv"
+	"runtime/debug"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -22,6 +22,7 @@ import (
 
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/model/mempool"
 	"github.com/copernet/copernicus/net/server"
 	"github.com/copernet/copernicus/rpc/btcjson"
@@ -32,6 +33,13 @@ const (
 	// RPC server is allowed to stay open without authenticating before it
 	// is closed.
 	rpcAuthTimeoutSeconds = 10
+
+	// rpcShutdownTimeout is how long stopping the RPC server waits for the
+	// calls in flight to complete.
+	rpcShutdownTimeout = time.Minute
+
+	// maxBatchRequests is the number of requests a batch may hold at most.
+	maxBatchRequests = 1000
 )
 
 func internalRPCError(errStr, context string) *btcjson.RPCError {
@@ -60,64 +68,22 @@ type Server struct {
 	cfg                    ServerConfig
 	authsha                [sha256.Size]byte
 	limitauthsha           [sha256.Size]byte
+	cookiesha              [sha256.Size]byte
+	cookiePath             string
+	rpcAuths               []rpcAuthEntry
 	numClients             int32
-	statusLines            map[int]string
-	statusLock             sync.RWMutex
+	workQueue              *rpcWorkQueue
 	wg                     sync.WaitGroup
 	helpCacher             *helpCacher
+	activity               *activityLog
+	ws                     *wsNotificationManager
+	txSubmitter            *txSubmitter
+	access                 *httpAccess
+	httpServer             *http.Server
 	requestProcessShutdown chan struct{}
 	quit                   chan int
 }
 
-func (s *Server) httpStatusLine(req *http.Request, code int) string {
-	// Fast path:
-	key := code
-	proto11 := req.ProtoAtLeast(1, 1)
-	if !proto11 {
-		key = -key
-	}
-	s.statusLock.RLock()
-	line, ok := s.statusLines[key]
-	s.statusLock.RUnlock()
-	if ok {
-		return line
-	}
-
-	// Slow path:
-	proto := "HTTP/1.0"
-	if proto11 {
-		proto = "HTTP/1.1"
-	}
-	codeStr := strconv.Itoa(code)
-	text := http.StatusText(code)
-	if text != "" {
-		line = proto + " " + codeStr + " " + text + "\r\n"
-		s.statusLock.Lock()
-		s.statusLines[key] = line
-		s.statusLock.Unlock()
-	} else {
-		text = "status code " + codeStr
-		line = proto + " " + codeStr + " " + text + "\r\n"
-	}
-
-	return line
-}
-
-func (s *Server) writeHTTPResponseHeaders(req *http.Request, headers http.Header, code int, w io.Writer) error {
-	_, err := io.WriteString(w, s.httpStatusLine(req, code))
-	if err != nil {
-		return err
-	}
-
-	err = headers.Write(w)
-	if err != nil {
-		return err
-	}
-
-	_, err = io.WriteString(w, "\r\n")
-	return err
-}
-
 // Stop is used by server.go to stop the rpc listener.
 func (s *Server) Stop() error {
 	if atomic.AddInt32(&s.shutdown, 1) != 1 {
@@ -125,15 +91,27 @@ func (s *Server) Stop() error {
 		return nil
 	}
 	log.Warn("RPC server shutting down")
-	for _, listener := range s.cfg.Listeners {
-		err := listener.Close()
-		if err != nil {
-			log.Error("Problem shutting down rpc: %v", err)
-			return err
+	// Stop accepting requests and let the calls in flight complete; quit
+	// interrupts the long polling ones.
+	close(s.quit)
+	s.ws.stop()
+	// the http server is only set once started
+	if s.httpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), rpcShutdownTimeout)
+		defer cancel()
+		if err := s.httpServer.Shutdown(ctx); err != nil {
+			log.Warn("RPC calls still in flight after %v, closing their "+
+				"connections: %v", rpcShutdownTimeout, err)
+			s.httpServer.Close()
 		}
 	}
-	close(s.quit)
 	s.wg.Wait()
+	miner.setWorkers(0, nil)
+	if s.cookiePath != "" {
+		if err := os.Remove(s.cookiePath); err != nil {
+			log.Warn("Unable to remove the RPC authentication cookie: %v", err)
+		}
+	}
 	log.Info("RPC server shutdown complete")
 	return nil
 }
@@ -159,12 +137,63 @@ func (s *Server) limitConnections(w http.ResponseWriter, remoteAddr string) bool
 	return false
 }
 
-//  adds one to the number of connected RPC clients.
+// rpcWorkQueue bounds the RPC requests executed at the same time to the
+// number of RPC threads, with a limited number of requests waiting for a
+// thread.  Requests which find the queue full are refused instead of piling
+// up on the server.
+type rpcWorkQueue struct {
+	threads chan struct{}
+	pending chan struct{}
+}
+
+func newRPCWorkQueue(threads, depth int) *rpcWorkQueue {
+	if threads < 1 {
+		threads = 1
+	}
+	if depth < 1 {
+		depth = 1
+	}
+	return &rpcWorkQueue{
+		threads: make(chan struct{}, threads),
+		pending: make(chan struct{}, depth),
+	}
+}
+
+// enter waits for a thread to execute a request on.  It returns false at once
+// when the queue is full, and when cancel is closed while waiting.
+func (q *rpcWorkQueue) enter(cancel <-chan struct{}) bool {
+	select {
+	case q.threads <- struct{}{}:
+		return true
+	default:
+	}
+
+	select {
+	case q.pending <- struct{}{}:
+	default:
+		return false
+	}
+	defer func() { <-q.pending }()
+
+	select {
+	case q.threads <- struct{}{}:
+		return true
+	case <-cancel:
+		return false
+	}
+}
+
+// leave releases the thread of a request entered with enter.
+func (q *rpcWorkQueue) leave() {
+	<-q.threads
+}
+
+// adds one to the number of connected RPC clients.
 func (s *Server) incrementClients() {
 	atomic.AddInt32(&s.numClients, 1)
 }
 
-//  subtracts one from the number of connected RPC clients.
+// subtracts one from the number of connected RPC clients.
 func (s *Server) decrementClients() {
 	atomic.AddInt32(&s.numClients, -1)
 }
@@ -174,7 +203,7 @@ func (s *Server) checkAuth(r *http.Request, require bool) (bool, bool, error) {
 	if len(authhdr) <= 0 {
 		if require {
 			log.Warn("RPC authentication failure from %s",
-				r.RemoteAddr)
+				s.access.clientAddr(r))
 			return false, false, errors.New("auth failure")
 		}
 
@@ -196,8 +225,20 @@ func (s *Server) checkAuth(r *http.Request, require bool) (bool, bool, error) {
 		return true, true, nil
 	}
 
+	// The cookie and the rpcauth users are admins as well
+	if s.cookiePath != "" && subtle.ConstantTimeCompare(authsha[:], s.cookiesha[:]) == 1 {
+		return true, true, nil
+	}
+	if user, pass, ok := basicAuthCredentials(authhdr[0]); ok {
+		for i := range s.rpcAuths {
+			if s.rpcAuths[i].matches(user, pass) {
+				return true, true, nil
+			}
+		}
+	}
+
 	// Request's auth doesn't match either user
-	log.Warn("RPC authentication failure from %s", r.RemoteAddr)
+	log.Warn("RPC authentication failure from %s", s.access.clientAddr(r))
 	return false, false, errors.New("auth failure")
 }
 
@@ -210,12 +251,28 @@ type parsedRPCCmd struct {
 	err    *btcjson.RPCError
 }
 
-func (s *Server) standardCmdResult(cmd *parsedRPCCmd, closeChan <-chan struct{}) (interface{}, error) {
+func (s *Server) standardCmdResult(cmd *parsedRPCCmd, closeChan <-chan struct{}) (result interface{}, err error) {
 	handler, ok := rpcHandlers[cmd.method]
-	if ok {
-		goto handled
-	}
-handled:
+	if !ok {
+		return nil, btcjson.ErrRPCMethodNotFound
+	}
+
+	start := time.Now()
+	id := activeCmds.begin(cmd.method, start)
+	defer func() {
+		activeCmds.end(id)
+		// A panicking handler must not take the whole server down; report
+		// it to the client as an internal error instead.
+		panicked := false
+		if r := recover(); r != nil {
+			panicked = true
+			log.Error("RPC handler for %s panicked: %v\n%s", cmd.method, r, debug.Stack())
+			result = nil
+			err = btcjson.NewRPCError(btcjson.ErrRPCInternal.Code,
+				fmt.Sprintf("internal error while handling %s", cmd.method))
+		}
+		stats.record(cmd.method, time.Since(start), err != nil, panicked)
+	}()
 	return handler(s, cmd.cmd, closeChan)
 }
 
@@ -246,17 +303,47 @@ func parseCmd(request *btcjson.Request) *parsedRPCCmd {
 // createMarshalledReply returns a new marshalled JSON-RPC response given the
 // passed parameters.  It will automatically convert errors that are not of
 // the type *btcjson.RPCError to the appropriate type as needed.
-func createMarshalledReply(id, result interface{}, replyErr error) ([]byte, error) {
+func createMarshalledReply(rpcVersion string, id, result interface{}, replyErr error) ([]byte, error) {
 	var jsonErr *btcjson.RPCError
 	if replyErr != nil {
-		if jErr, ok := replyErr.(*btcjson.RPCError); ok {
-			jsonErr = jErr
-		} else {
-			jsonErr = internalRPCError(replyErr.Error(), "")
-		}
+		jsonErr = toRPCError(replyErr)
 	}
 
-	return btcjson.MarshalResponse(id, result, jsonErr)
+	return btcjson.MarshalVersionedResponse(rpcVersion, id, result, jsonErr)
+}
+
+// requestRPCVersion returns the protocol version the request is framed with,
+// or an invalid request error if it does not conform to that version.
+// Requests without a jsonrpc field are 1.0 requests unless strict version
+// checking is configured.
+func requestRPCVersion(request *btcjson.Request) (string, error) {
+	switch {
+	case request.Jsonrpc == btcjson.RPCVersion2:
+		if request.Method == "" || !btcjson.IsValidIDType(request.ID) {
+			return btcjson.RPCVersion2, btcjson.ErrRPCInvalidRequest
+		}
+		return btcjson.RPCVersion2, nil
+	case request.Jsonrpc == btcjson.RPCVersion1:
+		return btcjson.RPCVersion1, nil
+	case request.Jsonrpc == "" && !conf.Cfg.RPC.RPCStrictVersion:
+		return btcjson.RPCVersion1, nil
+	}
+	return btcjson.RPCVersion1, btcjson.NewRPCError(btcjson.ErrRPCInvalidRequest.Code,
+		"Unsupported JSON-RPC version: \""+request.Jsonrpc+"\"")
+}
+
+// toRPCError converts an error returned by a command handler to the error
+// object sent to the client. Handlers return btcjson.RPCError both by value
+// and by pointer, any other error is reported as an internal error.
+func toRPCError(err error) *btcjson.RPCError {
+	switch e := err.(type) {
+	case *btcjson.RPCError:
+		return e
+	case btcjson.RPCError:
+		return &e
+	default:
+		return internalRPCError(err.Error(), "")
+	}
 }
 
 // jsonRPCRead handles reading and responding to RPC messages.
@@ -275,61 +362,135 @@ func (s *Server) jsonRPCRead(w http.ResponseWriter, r *http.Request, isAdmin boo
 		return
 	}
 
-	// Unfortunately, the http server doesn't provide the ability to
-	// change the read deadline for the new connection and having one breaks
-	// long polling.  However, not having a read deadline on the initial
-	// connection would mean clients can connect and idle forever.  Thus,
-	// hijack the connecton from the HTTP server, clear the read deadline,
-	// and handle writing the response manually.
-	hj, ok := w.(http.Hijacker)
-	if !ok {
-		errMsg := "webserver doesn't support hijacking"
-		log.Warn(errMsg)
-		errCode := http.StatusInternalServerError
-		http.Error(w, strconv.Itoa(errCode)+" "+errMsg, errCode)
+	// The connection is kept alive between requests, so a client which goes
+	// away is noticed through the request context instead of by reading from
+	// the connection.  The server sets no write timeout, which leaves long
+	// polling requests alone.  Stopping the server interrupts the calls
+	// which watch closeChan as well, so that they do not hold up shutdown.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	closeChan := ctx.Done()
+
+	var msg []byte
+	isNotification := false
+	if isBatchRequest(body) {
+		msg, isNotification = s.processBatch(body, closeChan)
+	} else {
+		msg, isNotification = s.processRequest(body, closeChan)
+	}
+
+	if isNotification {
+		w.WriteHeader(http.StatusNoContent)
 		return
 	}
-	conn, buf, err := hj.Hijack()
-	if err != nil {
-		log.Warn("Failed to hijack HTTP connection: %v", err)
-		errCode := http.StatusInternalServerError
-		http.Error(w, strconv.Itoa(errCode)+" "+err.Error(), errCode)
+	if msg == nil {
+		return
+	}
+
+	// Write the response, terminated with newline to maintain
+	// compatibility.
+	if _, err := w.Write(append(msg, '\n')); err != nil {
+		log.Error("Failed to write marshalled reply: %v", err)
 		return
 	}
-	defer conn.Close()
-	defer buf.Flush()
-	//conn.SetReadDeadline(timeZeroVal)
+}
+
+// isBatchRequest returns whether the body of a request is a JSON array.
+func isBatchRequest(body []byte) bool {
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// processBatch executes a batch of requests, a JSON array, and returns the
+// array of their replies in the same order.  Each request succeeds or fails
+// on its own.  Like for processRequest, a batch of notifications only is not
+// answered.  An empty batch or one of more than maxBatchRequests requests is
+// an invalid request, answered by a single error.
+func (s *Server) processBatch(body []byte, closeChan <-chan struct{}) ([]byte, bool) {
+	var requests []json.RawMessage
+	var jsonErr *btcjson.RPCError
+	if err := json.Unmarshal(body, &requests); err != nil {
+		jsonErr = &btcjson.RPCError{
+			Code:    btcjson.ErrRPCParse.Code,
+			Message: "Failed to parse request: " + err.Error(),
+		}
+	} else if len(requests) == 0 {
+		jsonErr = btcjson.NewRPCError(btcjson.ErrRPCInvalidRequest.Code, "Empty batch")
+	} else if len(requests) > maxBatchRequests {
+		jsonErr = btcjson.NewRPCError(btcjson.ErrRPCInvalidRequest.Code,
+			fmt.Sprintf("Batch of %d requests exceeds the limit of %d", len(requests), maxBatchRequests))
+	}
+	if jsonErr != nil {
+		msg, err := createMarshalledReply(btcjson.RPCVersion1, nil, nil, jsonErr)
+		if err != nil {
+			log.Error("Failed to marshal reply: %v", err)
+			return nil, false
+		}
+		return msg, false
+	}
 
+	replies := make([][]byte, 0, len(requests))
+	for _, request := range requests {
+		reply, _ := s.processRequest(request, closeChan)
+		if reply != nil {
+			replies = append(replies, reply)
+		}
+	}
+	if len(replies) == 0 {
+		return nil, true
+	}
+	msg := append([]byte{'['}, bytes.Join(replies, []byte{','})...)
+	return append(msg, ']'), false
+}
+
+// processRequest executes a single JSON-RPC request and returns its
+// marshalled reply.  The reply is nil when the request is not answered, in
+// particular when it is a JSON-RPC 2.0 notification, which is reported as
+// well.
+func (s *Server) processRequest(body []byte, closeChan <-chan struct{}) ([]byte, bool) {
 	// Attempt to parse the raw body into a JSON-RPC request.
 	var responseID interface{}
 	var jsonErr error
 	var result interface{}
 	var request btcjson.Request
+	rpcVersion := btcjson.RPCVersion1
 	if err := json.Unmarshal(body, &request); err != nil {
 		jsonErr = &btcjson.RPCError{
 			Code:    btcjson.ErrRPCParse.Code,
 			Message: "Failed to parse request: " + err.Error(),
 		}
+
+		// Still answer a 2.0 client in its own framing if the version
+		// can be told apart from the rest of the body.
+		var framing struct {
+			Jsonrpc string `json:"jsonrpc"`
+		}
+		if json.Unmarshal(body, &framing) == nil && framing.Jsonrpc == btcjson.RPCVersion2 {
+			rpcVersion = btcjson.RPCVersion2
+		}
+	} else {
+		rpcVersion, jsonErr = requestRPCVersion(&request)
 	}
+
+	// A 2.0 request without an id is a notification: it is executed but
+	// never answered.
+	isNotification := jsonErr == nil && rpcVersion == btcjson.RPCVersion2 && request.ID == nil
 	if jsonErr == nil {
-		if request.ID == nil && !(conf.Cfg.RPC.RPCQuirks && request.Jsonrpc == "") {
-			return
+		if request.ID == nil && !isNotification && !(conf.Cfg.RPC.RPCQuirks && request.Jsonrpc == "") {
+			return nil, false
 		}
 
 		// The parse was at least successful enough to have an ID so
 		// set it for the response.
 		responseID = request.ID
 
-		// Setup a close notifier.  Since the connection is hijacked,
-		// the CloseNotifer on the ResponseWriter is not available.
-		closeChan := make(chan struct{}, 1)
-		go func() {
-			_, err := conn.Read(make([]byte, 1))
-			if err != nil {
-				close(closeChan)
-			}
-		}()
-
 		// Check if the user is limited and set error if method unauthorized
 		//if !isAdmin {
 		//	if _, ok := rpcLimited[request.Method]; !ok {
@@ -350,35 +511,77 @@ func (s *Server) jsonRPCRead(w http.ResponseWriter, r *http.Request, isAdmin boo
 		}
 	}
 
+	if isNotification {
+		return nil, true
+	}
+
 	// Marshal the response.
-	msg, err := createMarshalledReply(responseID, result, jsonErr)
+	msg, err := createMarshalledReply(rpcVersion, responseID, result, jsonErr)
 	if err != nil {
 		log.Error("Failed to marshal reply: %v", err)
-		return
+		return nil, false
 	}
+	return msg, false
+}
 
-	// Write the response.
-	err = s.writeHTTPResponseHeaders(r, w.Header(), http.StatusOK, buf)
-	if err != nil {
-		log.Error(err)
+// jsonAuthFail sends a message back to the client if the http auth is rejected.
+func jsonAuthFail(w http.ResponseWriter) {
+	w.Header().Add("WWW-Authenticate", `Basic realm="btcd RPC"`)
+	http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+}
+
+// serveAuthorized runs serve for an authenticated client within the limits
+// of connected clients and of the work queue.
+func (s *Server) serveAuthorized(w http.ResponseWriter, r *http.Request, serve func(isAdmin bool)) {
+	s.serveClient(w, r, true, serve)
+}
+
+// serveClient runs serve for a client within the limits of connected clients
+// and of the work queue, once authenticated if authenticate is set.  The
+// requests of unauthenticated clients are served as those of limited users.
+func (s *Server) serveClient(w http.ResponseWriter, r *http.Request, authenticate bool, serve func(isAdmin bool)) {
+	ip := s.access.clientIP(r)
+	if !s.access.allowed(ip) {
+		log.Warn("RPC request of %s refused, the address is not allowed",
+			s.access.clientAddr(r))
+		http.Error(w, "403 Forbidden.", http.StatusForbidden)
 		return
 	}
-	if _, err := buf.Write(msg); err != nil {
-		log.Error("Failed to write marshalled reply: %v", err)
+	if s.access.serveCORS(w, r) {
+		return
+	}
+	if s.access.rateLimited(r, ip) {
+		log.Debug("RPC request rate of %s exceeded", s.access.clientAddr(r))
+		http.Error(w, "429 Too many requests.", http.StatusTooManyRequests)
 		return
 	}
 
-	// Terminate with newline to maintain compatibility.
-	if err := buf.WriteByte('\n'); err != nil {
-		log.Error("Failed to append terminating newline to reply: %v", err)
+	// Limit the number of connections to max allowed.
+	if s.limitConnections(w, s.access.clientAddr(r)) {
 		return
 	}
-}
 
-// jsonAuthFail sends a message back to the client if the http auth is rejected.
-func jsonAuthFail(w http.ResponseWriter) {
-	w.Header().Add("WWW-Authenticate", `Basic realm="btcd RPC"`)
-	http.Error(w, "401 Unauthorized.", http.StatusUnauthorized)
+	// Keep track of the number of connected clients.
+	s.incrementClients()
+	defer s.decrementClients()
+	isAdmin := false
+	if authenticate {
+		_, admin, err := s.checkAuth(r, true)
+		if err != nil {
+			jsonAuthFail(w)
+			return
+		}
+		isAdmin = admin
+	}
+
+	if !s.workQueue.enter(r.Context().Done()) {
+		log.Warn("RPC work queue depth exceeded - refusing request of %s", s.access.clientAddr(r))
+		http.Error(w, "503 Work queue depth exceeded.", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.workQueue.leave()
+
+	serve(isAdmin)
 }
 
 // Start func starts the rpc listener.
@@ -389,39 +592,64 @@ func (s *Server) Start() {
 
 	log.Info("Starting RPC server")
 	rpcServeMux := http.NewServeMux()
-	httpServer := &http.Server{
+	s.httpServer = &http.Server{
 		Handler: rpcServeMux,
-		// Timeout connections which don't complete the initial
-		// handshake within the allowed timeframe.
-		ReadTimeout: time.Second * rpcAuthTimeoutSeconds,
+		// Timeout connections which don't send the headers of a request
+		// within the allowed timeframe.  Only the headers are bounded, a
+		// read deadline on the whole request would cancel long polling
+		// requests.
+		ReadHeaderTimeout: time.Second * rpcAuthTimeoutSeconds,
+		// Close keep-alive connections left idle between requests.
+		IdleTimeout: time.Second * time.Duration(conf.Cfg.RPC.RPCServerTimeout),
 	}
 	rpcServeMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Connection", "close")
 		w.Header().Set("Content-Type", "application/json")
-		r.Close = true
-
-		// Limit the number of connections to max allowed.
-		if s.limitConnections(w, r.RemoteAddr) {
-			return
-		}
-
-		// Keep track of the number of connected clients.
-		s.incrementClients()
-		defer s.decrementClients()
-		_, isAdmin, err := s.checkAuth(r, true)
-		if err != nil {
-			jsonAuthFail(w)
+		s.serveAuthorized(w, r, func(isAdmin bool) {
+			s.jsonRPCRead(w, r, isAdmin)
+		})
+	})
+	rpcServeMux.HandleFunc(rawMempoolTxsPath, func(w http.ResponseWriter, r *http.Request) {
+		s.serveAuthorized(w, r, func(bool) {
+			s.streamRawMempoolTxs(w, r)
+		})
+	})
+	rpcServeMux.HandleFunc(metricsPath, func(w http.ResponseWriter, r *http.Request) {
+		s.serveAuthorized(w, r, func(bool) {
+			serveMetrics(w)
+		})
+	})
+	rpcServeMux.HandleFunc(wsPath, s.serveWebsocket)
+	restTxHandler := func(w http.ResponseWriter, r *http.Request) {
+		// the lookups of transactions are part of the public REST
+		// interface, unlike the submissions
+		if conf.Cfg.RPC.RPCRest && isRESTTxLookup(r) {
+			s.serveClient(w, r, false, func(bool) {
+				s.handleREST(w, r)
+			})
 			return
 		}
+		s.serveAuthorized(w, r, func(bool) {
+			s.handleRESTTx(w, r)
+		})
+	}
+	rpcServeMux.HandleFunc(restTxPath, restTxHandler)
+	rpcServeMux.HandleFunc(restTxPath+"/", restTxHandler)
+	if conf.Cfg.RPC.RPCRest {
+		rpcServeMux.HandleFunc(restPath, func(w http.ResponseWriter, r *http.Request) {
+			s.serveClient(w, r, false, func(bool) {
+				s.handleREST(w, r)
+			})
+		})
+	}
 
-		s.jsonRPCRead(w, r, isAdmin)
-	})
+	s.wg.Add(1)
+	go s.txSubmissionHandler()
 
 	for _, listener := range s.cfg.Listeners {
 		s.wg.Add(1)
 		go func(listener net.Listener) {
 			log.Info("RPC server listening on %s", listener.Addr())
-			httpServer.Serve(listener)
+			s.httpServer.Serve(listener)
 			log.Trace("RPC listener done for %s", listener.Addr())
 			s.wg.Done()
 		}(listener)
@@ -497,7 +725,12 @@ func SetupRPCListeners() ([]net.Listener, error) {
 		return nil, err
 	}
 
-	listeners := make([]net.Listener, 0, len(netAddrs))
+	plainAddrs, err := parseListeners(conf.Cfg.RPC.RPCPlainListeners)
+	if err != nil {
+		return nil, err
+	}
+
+	listeners := make([]net.Listener, 0, len(netAddrs)+len(plainAddrs))
 	for _, addr := range netAddrs {
 		listener, err := listenFunc(addr.Network(), addr.String())
 		if err != nil {
@@ -506,6 +739,15 @@ func SetupRPCListeners() ([]net.Listener, error) {
 		}
 		listeners = append(listeners, listener)
 	}
+	// A reverse proxy in front of the server terminates TLS itself.
+	for _, addr := range plainAddrs {
+		listener, err := net.Listen(addr.Network(), addr.String())
+		if err != nil {
+			log.Warn("Can't listen on %s: %v", addr, err)
+			continue
+		}
+		listeners = append(listeners, listener)
+	}
 
 	return listeners, nil
 }
@@ -579,29 +821,49 @@ var _ net.Addr = simpleAddr{}
 
 func NewServer(config *ServerConfig) (*Server, error) {
 	rpc := Server{
-		cfg:         *config,
-		statusLines: make(map[int]string),
+		cfg:       *config,
+		workQueue: newRPCWorkQueue(conf.Cfg.RPC.RPCThreads, conf.Cfg.RPC.RPCWorkQueue),
 		//gbtWorkState:           newGbtWorkState(config.TimeSource), // todo open
 		helpCacher:             newHelpCacher(),
-		requestProcessShutdown: make(chan struct{}),
-		quit: make(chan int),
+		txSubmitter:            newTxSubmitter(),
+		requestProcessShutdown: make(chan struct{}, 1),
+		quit:                   make(chan int),
 	}
 	if conf.Cfg.RPC.RPCUser != "" && conf.Cfg.RPC.RPCPass != "" {
-		login := conf.Cfg.RPC.RPCUser + ":" + conf.Cfg.RPC.RPCPass
-		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
-		rpc.authsha = sha256.Sum256([]byte(auth))
+		rpc.authsha = basicAuthSha(conf.Cfg.RPC.RPCUser, conf.Cfg.RPC.RPCPass)
 	}
 	if conf.Cfg.RPC.RPCLimitUser != "" && conf.Cfg.RPC.RPCLimitPass != "" {
-		login := conf.Cfg.RPC.RPCLimitUser + ":" + conf.Cfg.RPC.RPCLimitPass
-		auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(login))
-		rpc.limitauthsha = sha256.Sum256([]byte(auth))
+		rpc.limitauthsha = basicAuthSha(conf.Cfg.RPC.RPCLimitUser, conf.Cfg.RPC.RPCLimitPass)
+	}
+	rpcAuths, err := parseRPCAuth(conf.Cfg.RPC.RPCAuth)
+	if err != nil {
+		return nil, err
+	}
+	rpc.rpcAuths = rpcAuths
+	rpc.access, err = newHTTPAccess()
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a configured password, local clients authenticate with the
+	// credentials of the cookie file.
+	if conf.Cfg.RPC.RPCPass == "" {
+		path := cookieFilePath()
+		pass, err := generateAuthCookie(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate the RPC authentication cookie: %v", err)
+		}
+		rpc.cookiePath = path
+		rpc.cookiesha = basicAuthSha(cookieAuthUser, pass)
+		log.Info("Generated RPC authentication cookie %s", path)
 	}
-	//rpc.cfg.Chain.Subscribe(rpc.handleBlockchainNotification)  // todo open
+	rpc.activity = newActivityLog(chain.GetInstance())
+	rpc.ws = newWSNotificationManager(chain.GetInstance())
 
 	return &rpc, nil
 }
 
-func InitRPCServer() (*Server, error) {
+func InitRPCServer(feeEstimator *mempool.FeeEstimator) (*Server, error) {
 	if !conf.Cfg.P2PNet.DisableRPC {
 		// Setup listeners for the configured RPC listen addresses and
 		// TLS settings.
@@ -614,8 +876,9 @@ func InitRPCServer() (*Server, error) {
 		}
 
 		rpcServer, err := NewServer(&ServerConfig{
-			Listeners: rpcListeners,
-			//StartupTime: s.startupTime,
+			Listeners:    rpcListeners,
+			StartupTime:  time.Now().Unix(),
+			FeeEstimator: feeEstimator,
 		})
 		if err != nil {
 			return nil, err
@@ -0,0 +1,325 @@
+package rpc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/rpc/btcjson"
+)
+
+func TestProcessBatch(t *testing.T) {
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	s := &Server{}
+	call := func(id int) string {
+		return `{"jsonrpc":"2.0","method":"nosuchmethod","id":` + strconv.Itoa(id) + `}`
+	}
+	notification := `{"jsonrpc":"2.0","method":"nosuchmethod"}`
+	tooMany := "[" + strings.TrimSuffix(strings.Repeat(notification+",", maxBatchRequests+1), ",") + "]"
+	maxBatch := "[" + strings.TrimSuffix(strings.Repeat(notification+",", maxBatchRequests), ",") + "]"
+
+	tests := []struct {
+		name           string
+		body           string
+		isNotification bool
+		ids            []float64 // ids of the replies of a batch answered by an array
+		errCode        btcjson.RPCErrorCode
+	}{
+		{"empty batch", "[]", false, nil, btcjson.ErrRPCInvalidRequest.Code},
+		{"empty batch with spaces", " [ ] ", false, nil, btcjson.ErrRPCInvalidRequest.Code},
+		{"malformed batch", "[" + call(1) + ",", false, nil, btcjson.ErrRPCParse.Code},
+		{"too many requests", tooMany, false, nil, btcjson.ErrRPCInvalidRequest.Code},
+		{"replies in order", "[" + call(2) + "," + notification + "," + call(1) + "]", false, []float64{2, 1}, 0},
+		{"notifications only", "[" + notification + "," + notification + "]", true, nil, 0},
+		{"largest batch", maxBatch, true, nil, 0},
+	}
+	for _, test := range tests {
+		msg, isNotification := s.processBatch([]byte(test.body), nil)
+		if isNotification != test.isNotification {
+			t.Errorf("%s: notification %t, expect %t", test.name, isNotification, test.isNotification)
+			continue
+		}
+		if isNotification {
+			if msg != nil {
+				t.Errorf("%s: a batch of notifications answered with %s", test.name, msg)
+			}
+			continue
+		}
+
+		if test.ids == nil {
+			var reply btcjson.Response
+			if err := json.Unmarshal(msg, &reply); err != nil {
+				t.Errorf("%s: reply %s is not a single response: %v", test.name, msg, err)
+				continue
+			}
+			if reply.Error == nil || reply.Error.Code != test.errCode {
+				t.Errorf("%s: got error %v, expect code %d", test.name, reply.Error, test.errCode)
+			}
+			continue
+		}
+
+		var replies []btcjson.Response
+		if err := json.Unmarshal(msg, &replies); err != nil {
+			t.Errorf("%s: reply %s is not an array of responses: %v", test.name, msg, err)
+			continue
+		}
+		if len(replies) != len(test.ids) {
+			t.Errorf("%s: got %d replies, expect %d", test.name, len(replies), len(test.ids))
+			continue
+		}
+		for i, reply := range replies {
+			if reply.ID == nil || *reply.ID != test.ids[i] {
+				t.Errorf("%s: reply %d has id %v, expect %v", test.name, i, reply.ID, test.ids[i])
+			}
+			if reply.Error == nil || reply.Error.Code != btcjson.ErrRPCMethodNotFound.Code {
+				t.Errorf("%s: reply %d has error %v", test.name, i, reply.Error)
+			}
+		}
+	}
+}
+
+func TestStandardCmdResultRecover(t *testing.T) {
+	const method = "testpanic"
+	rpcHandlers[method] = func(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+		var index map[string]int
+		index["boom"]++
+		return "unreachable", nil
+	}
+	defer delete(rpcHandlers, method)
+
+	panics := uint64(0)
+	if entry, ok := stats.snapshot()[method]; ok {
+		panics = entry.Panics
+	}
+	s := &Server{}
+	result, err := s.standardCmdResult(&parsedRPCCmd{method: method}, nil)
+	if result != nil {
+		t.Errorf("a panicking handler returned %v", result)
+	}
+	rpcErr, ok := err.(*btcjson.RPCError)
+	if !ok || rpcErr.Code != btcjson.ErrRPCInternal.Code || !strings.Contains(rpcErr.Message, method) {
+		t.Errorf("got error %v, expect an internal error naming the method", err)
+	}
+	if entry := stats.snapshot()[method]; entry == nil || entry.Panics != panics+1 || entry.Errors == 0 {
+		t.Errorf("the panic is not recorded in the statistics: %+v", entry)
+	}
+	if len(activeCmds.snapshot()) != 0 {
+		t.Errorf("the panicking command is still reported as active")
+	}
+}
+
+func TestRPCWorkQueue(t *testing.T) {
+	q := newRPCWorkQueue(1, 1)
+	if !q.enter(nil) {
+		t.Fatal("no thread for the first request")
+	}
+
+	// the second request waits for the thread, and the third finds the
+	// queue full
+	entered := make(chan bool)
+	go func() { entered <- q.enter(nil) }()
+	for len(q.pending) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if q.enter(nil) {
+		t.Fatal("entered a full queue")
+	}
+	q.leave()
+	if !<-entered {
+		t.Fatal("the waiting request did not get the thread")
+	}
+	if len(q.pending) != 0 {
+		t.Fatal("the request still waits after getting the thread")
+	}
+
+	// a canceled request leaves the queue
+	cancel := make(chan struct{})
+	go func() { entered <- q.enter(cancel) }()
+	for len(q.pending) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(cancel)
+	if <-entered {
+		t.Fatal("a canceled request entered")
+	}
+	if len(q.pending) != 0 {
+		t.Fatal("the canceled request still waits")
+	}
+	q.leave()
+
+	q = newRPCWorkQueue(0, 0)
+	if cap(q.threads) != 1 || cap(q.pending) != 1 {
+		t.Errorf("got %d threads and a depth of %d, expect 1 and 1", cap(q.threads), cap(q.pending))
+	}
+}
+
+func TestJSONRPCReadKeepAlive(t *testing.T) {
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	s := &Server{quit: make(chan int)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.jsonRPCRead(w, r, true)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"call", `{"jsonrpc":"1.0","method":"nosuchmethod","id":1}`, http.StatusOK},
+		{"notification", `{"jsonrpc":"2.0","method":"nosuchmethod"}`, http.StatusNoContent},
+		{"call after a notification", `{"jsonrpc":"1.0","method":"nosuchmethod","id":2}`, http.StatusOK},
+	}
+	client := &http.Client{}
+	for i, test := range tests {
+		reused := false
+		trace := &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused },
+		}
+		req, err := http.NewRequest("POST", server.URL, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != test.status || resp.Close {
+			t.Errorf("%s: status %d with close %t, expect %d on a kept alive connection",
+				test.name, resp.StatusCode, resp.Close, test.status)
+		}
+		if test.status == http.StatusOK && !strings.HasSuffix(string(body), "}\n") {
+			t.Errorf("%s: reply %q does not end with a newline", test.name, body)
+		}
+		if reused != (i > 0) {
+			t.Errorf("%s: connection reused %t", test.name, reused)
+		}
+	}
+}
+
+func TestStopWaitsForCalls(t *testing.T) {
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	chain.InitGlobalChain()
+
+	// the handlers stand in for registered commands, which are the only ones
+	// to reach their handler
+	const slowMethod, pollMethod = "ping", "uptime"
+	slowHandler, pollHandler := rpcHandlers[slowMethod], rpcHandlers[pollMethod]
+	defer func() {
+		rpcHandlers[slowMethod] = slowHandler
+		rpcHandlers[pollMethod] = pollHandler
+	}()
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	rpcHandlers[slowMethod] = func(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+		started <- struct{}{}
+		<-release
+		return "completed", nil
+	}
+	rpcHandlers[pollMethod] = func(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+		started <- struct{}{}
+		<-closeChan
+		return "interrupted", nil
+	}
+
+	s := &Server{
+		quit: make(chan int),
+		ws:   newWSNotificationManager(chain.GetInstance()),
+	}
+	s.httpServer = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.jsonRPCRead(w, r, true)
+	})}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.wg.Add(1)
+	go func() {
+		s.httpServer.Serve(listener)
+		s.wg.Done()
+	}()
+
+	results := make(chan string, 2)
+	call := func(method string) {
+		body := `{"jsonrpc":"1.0","method":"` + method + `","params":[],"id":1}`
+		resp, err := http.Post("http://"+listener.Addr().String(), "application/json", strings.NewReader(body))
+		if err != nil {
+			results <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		var reply btcjson.Response
+		if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+			results <- err.Error()
+			return
+		}
+		var result string
+		json.Unmarshal(reply.Result, &result)
+		results <- method + ":" + result
+	}
+	go call(slowMethod)
+	go call(pollMethod)
+	<-started
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+	// quit interrupts the long polling call, the other one holds up the
+	// shutdown until it completes
+	if result := <-results; result != pollMethod+":interrupted" {
+		t.Errorf("got %q, expect the long polling call to be interrupted", result)
+	}
+	select {
+	case <-stopped:
+		t.Fatal("stopped with a call in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if result := <-results; result != slowMethod+":completed" {
+		t.Errorf("got %q, expect the call in flight to complete", result)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server did not stop after the calls completed")
+	}
+}
+
+func TestStopBeforeStart(t *testing.T) {
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	chain.InitGlobalChain()
+
+	s := &Server{
+		quit: make(chan int),
+		ws:   newWSNotificationManager(chain.GetInstance()),
+	}
+	if err := s.Stop(); err != nil {
+		t.Errorf("stopping a server which was not started: %v", err)
+	}
+}
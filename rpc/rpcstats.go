@@ -0,0 +1,337 @@
+package rpc
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/copernet/copernicus/logic/lchain"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/copernet/copernicus/rpc/btcjson"
+)
+
+// rpcLatencyBuckets are the upper bounds of the per-method latency histogram.
+var rpcLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+type methodStats struct {
+	calls     uint64
+	errors    uint64
+	panics    uint64
+	totalTime time.Duration
+	// buckets[i] counts calls which took at most rpcLatencyBuckets[i] and
+	// more than the previous bound; the last entry counts the slower ones.
+	buckets []uint64
+}
+
+// rpcStats collects call counts, error counts and latencies per RPC method.
+// Only methods with a handler are recorded, which keeps the number of series
+// bounded whatever clients send.
+type rpcStats struct {
+	sync.Mutex
+	methods map[string]*methodStats
+}
+
+var stats = newRPCStats()
+
+func newRPCStats() *rpcStats {
+	return &rpcStats{methods: make(map[string]*methodStats)}
+}
+
+func (rs *rpcStats) record(method string, elapsed time.Duration, failed bool, panicked bool) {
+	rs.Lock()
+	defer rs.Unlock()
+
+	ms, ok := rs.methods[method]
+	if !ok {
+		ms = &methodStats{buckets: make([]uint64, len(rpcLatencyBuckets)+1)}
+		rs.methods[method] = ms
+	}
+	ms.calls++
+	if failed {
+		ms.errors++
+	}
+	if panicked {
+		ms.panics++
+	}
+	ms.totalTime += elapsed
+	ms.buckets[sort.Search(len(rpcLatencyBuckets), func(i int) bool {
+		return elapsed <= rpcLatencyBuckets[i]
+	})]++
+}
+
+// snapshot returns the statistics of every method called so far, with
+// cumulative histogram buckets keyed by their upper bound.
+func (rs *rpcStats) snapshot() map[string]*btcjson.RPCMethodStats {
+	rs.Lock()
+	defer rs.Unlock()
+
+	ret := make(map[string]*btcjson.RPCMethodStats, len(rs.methods))
+	for method, ms := range rs.methods {
+		entry := &btcjson.RPCMethodStats{
+			Calls:          ms.calls,
+			Errors:         ms.errors,
+			Panics:         ms.panics,
+			TotalTimeMs:    durationToMs(ms.totalTime),
+			AverageTimeMs:  durationToMs(ms.totalTime / time.Duration(ms.calls)),
+			LatencyBuckets: make(map[string]uint64, len(ms.buckets)),
+		}
+		var cumulative uint64
+		for i, count := range ms.buckets {
+			cumulative += count
+			entry.LatencyBuckets[bucketLabel(i)] = cumulative
+		}
+		ret[method] = entry
+	}
+	return ret
+}
+
+// writeMetrics writes the statistics in the Prometheus text exposition format.
+func (rs *rpcStats) writeMetrics(w io.Writer) {
+	snapshot := rs.snapshot()
+	methods := make([]string, 0, len(snapshot))
+	for method := range snapshot {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	fmt.Fprintln(w, "# HELP copernicus_rpc_requests_total Number of RPC requests handled, by method.")
+	fmt.Fprintln(w, "# TYPE copernicus_rpc_requests_total counter")
+	for _, method := range methods {
+		fmt.Fprintf(w, "copernicus_rpc_requests_total{method=%q} %d\n", method, snapshot[method].Calls)
+	}
+	fmt.Fprintln(w, "# HELP copernicus_rpc_errors_total Number of RPC requests which returned an error, by method.")
+	fmt.Fprintln(w, "# TYPE copernicus_rpc_errors_total counter")
+	for _, method := range methods {
+		fmt.Fprintf(w, "copernicus_rpc_errors_total{method=%q} %d\n", method, snapshot[method].Errors)
+	}
+	fmt.Fprintln(w, "# HELP copernicus_rpc_panics_total Number of RPC requests whose handler panicked, by method.")
+	fmt.Fprintln(w, "# TYPE copernicus_rpc_panics_total counter")
+	for _, method := range methods {
+		fmt.Fprintf(w, "copernicus_rpc_panics_total{method=%q} %d\n", method, snapshot[method].Panics)
+	}
+	fmt.Fprintln(w, "# HELP copernicus_rpc_duration_seconds Time spent handling RPC requests, by method.")
+	fmt.Fprintln(w, "# TYPE copernicus_rpc_duration_seconds histogram")
+	for _, method := range methods {
+		entry := snapshot[method]
+		for i := range rpcLatencyBuckets {
+			fmt.Fprintf(w, "copernicus_rpc_duration_seconds_bucket{method=%q,le=\"%g\"} %d\n",
+				method, rpcLatencyBuckets[i].Seconds(), entry.LatencyBuckets[bucketLabel(i)])
+		}
+		fmt.Fprintf(w, "copernicus_rpc_duration_seconds_bucket{method=%q,le=\"+Inf\"} %d\n",
+			method, entry.Calls)
+		fmt.Fprintf(w, "copernicus_rpc_duration_seconds_sum{method=%q} %g\n", method, entry.TotalTimeMs/1000)
+		fmt.Fprintf(w, "copernicus_rpc_duration_seconds_count{method=%q} %d\n", method, entry.Calls)
+	}
+}
+
+func bucketLabel(i int) string {
+	if i >= len(rpcLatencyBuckets) {
+		return "+Inf"
+	}
+	return rpcLatencyBuckets[i].String()
+}
+
+func durationToMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// activeCommands tracks the RPC commands being executed, for getrpcinfo.
+type activeCommands struct {
+	sync.Mutex
+	nextID   uint64
+	commands map[uint64]activeCommand
+}
+
+type activeCommand struct {
+	method string
+	start  time.Time
+}
+
+var activeCmds = &activeCommands{commands: make(map[uint64]activeCommand)}
+
+// begin records the start of a command, returning the id to end it with.
+func (ac *activeCommands) begin(method string, start time.Time) uint64 {
+	ac.Lock()
+	defer ac.Unlock()
+
+	ac.nextID++
+	ac.commands[ac.nextID] = activeCommand{method: method, start: start}
+	return ac.nextID
+}
+
+func (ac *activeCommands) end(id uint64) {
+	ac.Lock()
+	defer ac.Unlock()
+
+	delete(ac.commands, id)
+}
+
+// snapshot returns the commands being executed, the longest running first.
+func (ac *activeCommands) snapshot() []btcjson.ActiveCommand {
+	ac.Lock()
+	cmds := make([]activeCommand, 0, len(ac.commands))
+	for _, cmd := range ac.commands {
+		cmds = append(cmds, cmd)
+	}
+	ac.Unlock()
+
+	sort.Slice(cmds, func(i, j int) bool {
+		return cmds[i].start.Before(cmds[j].start)
+	})
+	now := time.Now()
+	ret := make([]btcjson.ActiveCommand, 0, len(cmds))
+	for _, cmd := range cmds {
+		ret = append(ret, btcjson.ActiveCommand{
+			Method:   cmd.method,
+			Duration: int64(now.Sub(cmd.start) / time.Microsecond),
+		})
+	}
+	return ret
+}
+
+// writeBlockBenchMetrics writes the time spent connecting blocks, by stage,
+// in the Prometheus text exposition format.
+func writeBlockBenchMetrics(w io.Writer) {
+	bench := lchain.GetBlockBench()
+
+	fmt.Fprintln(w, "# HELP copernicus_blocks_connected_total Number of blocks connected to the active chain.")
+	fmt.Fprintln(w, "# TYPE copernicus_blocks_connected_total counter")
+	fmt.Fprintf(w, "copernicus_blocks_connected_total %d\n", bench.Blocks)
+	fmt.Fprintln(w, "# HELP copernicus_block_connect_seconds_total Time spent connecting blocks, by stage.")
+	fmt.Fprintln(w, "# TYPE copernicus_block_connect_seconds_total counter")
+	for _, stage := range lchain.BenchStages {
+		fmt.Fprintf(w, "copernicus_block_connect_seconds_total{stage=%q} %g\n",
+			stage, bench.Stages[stage].Seconds())
+	}
+	fmt.Fprintf(w, "copernicus_block_connect_seconds_total{stage=\"total\"} %g\n", bench.Total.Seconds())
+}
+
+// utxoCacheStats returns the lookups of the coins cache and the latencies of
+// the databases, with cumulative histogram buckets keyed by their upper bound.
+func utxoCacheStats() *btcjson.GetUtxoCacheStatsResult {
+	cache := utxo.GetUtxoCacheInstance().GetStats()
+	ret := &btcjson.GetUtxoCacheStatsResult{
+		Hits:       cache.Hits,
+		Misses:     cache.Misses,
+		NotFound:   cache.NotFound,
+		Coins:      cache.Coins,
+		DirtyCoins: cache.DirtyCoins,
+		Capacity:   cache.Capacity,
+		Databases:  make(map[string]btcjson.DBStats),
+	}
+	if lookups := cache.Hits + cache.Misses; lookups > 0 {
+		ret.HitRatio = float64(cache.Hits) / float64(lookups)
+	}
+	for name, s := range db.GetStats() {
+		ret.Databases[name] = btcjson.DBStats{
+			Reads:  dbLatencyStats(&s.Reads),
+			Writes: dbLatencyStats(&s.Writes),
+		}
+	}
+	return ret
+}
+
+func dbLatencyStats(h *db.LatencyHistogram) btcjson.DBLatencyStats {
+	ret := btcjson.DBLatencyStats{
+		Count:          h.Count,
+		TotalTimeMs:    durationToMs(h.Total),
+		LatencyBuckets: make(map[string]uint64, len(h.Buckets)),
+	}
+	if h.Count > 0 {
+		ret.AverageTimeMs = durationToMs(h.Total / time.Duration(h.Count))
+	}
+	var cumulative uint64
+	for i, count := range h.Buckets {
+		cumulative += count
+		label := "+Inf"
+		if i < len(db.LatencyBuckets) {
+			label = db.LatencyBuckets[i].String()
+		}
+		ret.LatencyBuckets[label] = cumulative
+	}
+	return ret
+}
+
+// writeUtxoCacheMetrics writes the lookups of the coins cache and the
+// latencies of the databases in the Prometheus text exposition format.
+func writeUtxoCacheMetrics(w io.Writer) {
+	cache := utxo.GetUtxoCacheInstance().GetStats()
+
+	fmt.Fprintln(w, "# HELP copernicus_utxo_cache_lookups_total Number of coin lookups, by whether the cache had the coin.")
+	fmt.Fprintln(w, "# TYPE copernicus_utxo_cache_lookups_total counter")
+	fmt.Fprintf(w, "copernicus_utxo_cache_lookups_total{result=\"hit\"} %d\n", cache.Hits)
+	fmt.Fprintf(w, "copernicus_utxo_cache_lookups_total{result=\"miss\"} %d\n", cache.Misses)
+	fmt.Fprintln(w, "# HELP copernicus_utxo_cache_not_found_total Number of cache misses which found no coin in the database.")
+	fmt.Fprintln(w, "# TYPE copernicus_utxo_cache_not_found_total counter")
+	fmt.Fprintf(w, "copernicus_utxo_cache_not_found_total %d\n", cache.NotFound)
+	fmt.Fprintln(w, "# HELP copernicus_utxo_cache_coins Number of coins in the cache.")
+	fmt.Fprintln(w, "# TYPE copernicus_utxo_cache_coins gauge")
+	fmt.Fprintf(w, "copernicus_utxo_cache_coins %d\n", cache.Coins)
+	fmt.Fprintln(w, "# HELP copernicus_utxo_cache_dirty_coins Number of coins of the cache not flushed to the database.")
+	fmt.Fprintln(w, "# TYPE copernicus_utxo_cache_dirty_coins gauge")
+	fmt.Fprintf(w, "copernicus_utxo_cache_dirty_coins %d\n", cache.DirtyCoins)
+
+	dbs := db.GetStats()
+	names := make([]string, 0, len(dbs))
+	for name := range dbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "# HELP copernicus_db_read_seconds Time spent reading the databases, by database.")
+	fmt.Fprintln(w, "# TYPE copernicus_db_read_seconds histogram")
+	for _, name := range names {
+		s := dbs[name]
+		writeDBLatencyHistogram(w, "copernicus_db_read_seconds", name, &s.Reads)
+	}
+	fmt.Fprintln(w, "# HELP copernicus_db_write_seconds Time spent writing batches to the databases, by database.")
+	fmt.Fprintln(w, "# TYPE copernicus_db_write_seconds histogram")
+	for _, name := range names {
+		s := dbs[name]
+		writeDBLatencyHistogram(w, "copernicus_db_write_seconds", name, &s.Writes)
+	}
+}
+
+func writeDBLatencyHistogram(w io.Writer, metric string, name string, h *db.LatencyHistogram) {
+	var cumulative uint64
+	for i, bound := range db.LatencyBuckets {
+		cumulative += h.Buckets[i]
+		fmt.Fprintf(w, "%s_bucket{db=%q,le=\"%g\"} %d\n", metric, name, bound.Seconds(), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{db=%q,le=\"+Inf\"} %d\n", metric, name, h.Count)
+	fmt.Fprintf(w, "%s_sum{db=%q} %g\n", metric, name, h.Total.Seconds())
+	fmt.Fprintf(w, "%s_count{db=%q} %d\n", metric, name, h.Count)
+}
+
+// metricsPath is the HTTP path of the metrics of the node on the RPC server.
+const metricsPath = "/metrics"
+
+// serveMetrics serves the RPC statistics, block connection timings, coins
+// cache lookups and database latencies to Prometheus.
+func serveMetrics(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	stats.writeMetrics(w)
+	writeBlockBenchMetrics(w)
+	writeUtxoCacheMetrics(w)
+}
+
+func handleGetRPCStats(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	return stats.snapshot(), nil
+}
+
+// handleGetUtxoCacheStats implements the getutxocachestats command.
+func handleGetUtxoCacheStats(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	return utxoCacheStats(), nil
+}
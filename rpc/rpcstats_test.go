@@ -0,0 +1,96 @@
+package rpc
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// metricLine matches a sample of the Prometheus text exposition format.
+var metricLine = regexp.MustCompile(`^[a-z_]+(\{[a-z]+="[^"]*"(,[a-z]+="[^"]*")*\})? [0-9.e+-]+$`)
+
+func TestWriteMetrics(t *testing.T) {
+	rs := newRPCStats()
+	rs.record("getblock", 2*time.Millisecond, false, false)
+	rs.record("getblock", 20*time.Millisecond, true, false)
+	rs.record("getblock", 10*time.Second, true, true)
+	rs.record("getblockcount", 0, false, false)
+
+	buf := new(bytes.Buffer)
+	rs.writeMetrics(buf)
+
+	samples := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if strings.HasPrefix(line, "# HELP ") || strings.HasPrefix(line, "# TYPE ") {
+			continue
+		}
+		if !metricLine.MatchString(line) {
+			t.Errorf("malformed metric line %q", line)
+			continue
+		}
+		space := strings.LastIndexByte(line, ' ')
+		samples[line[:space]] = line[space+1:]
+	}
+
+	expected := map[string]string{
+		`copernicus_rpc_requests_total{method="getblock"}`:                          "3",
+		`copernicus_rpc_requests_total{method="getblockcount"}`:                     "1",
+		`copernicus_rpc_errors_total{method="getblock"}`:                            "2",
+		`copernicus_rpc_panics_total{method="getblock"}`:                            "1",
+		`copernicus_rpc_panics_total{method="getblockcount"}`:                       "0",
+		`copernicus_rpc_duration_seconds_bucket{method="getblock",le="0.001"}`:      "0",
+		`copernicus_rpc_duration_seconds_bucket{method="getblock",le="0.005"}`:      "1",
+		`copernicus_rpc_duration_seconds_bucket{method="getblock",le="0.05"}`:       "2",
+		`copernicus_rpc_duration_seconds_bucket{method="getblock",le="5"}`:          "2",
+		`copernicus_rpc_duration_seconds_bucket{method="getblock",le="+Inf"}`:       "3",
+		`copernicus_rpc_duration_seconds_sum{method="getblock"}`:                    "10.022",
+		`copernicus_rpc_duration_seconds_count{method="getblock"}`:                  "3",
+		`copernicus_rpc_duration_seconds_bucket{method="getblockcount",le="0.001"}`: "1",
+	}
+	for sample, value := range expected {
+		if samples[sample] != value {
+			t.Errorf("%s = %q, expect %q", sample, samples[sample], value)
+		}
+	}
+
+	// every histogram has a bucket per bound and the +Inf one
+	buckets := strings.Count(buf.String(), `copernicus_rpc_duration_seconds_bucket{method="getblock",`)
+	if buckets != len(rpcLatencyBuckets)+1 {
+		t.Errorf("got %d buckets, expect %d", buckets, len(rpcLatencyBuckets)+1)
+	}
+	// every metric is declared once, before its samples
+	for _, metric := range []string{"copernicus_rpc_requests_total", "copernicus_rpc_duration_seconds"} {
+		if strings.Count(buf.String(), "# TYPE "+metric+" ") != 1 {
+			t.Errorf("metric %s is not declared once", metric)
+		}
+	}
+}
+
+func TestActiveCommands(t *testing.T) {
+	ac := &activeCommands{commands: make(map[uint64]activeCommand)}
+	now := time.Now()
+	recent := ac.begin("getblockcount", now.Add(-time.Millisecond))
+	oldest := ac.begin("getblock", now.Add(-time.Minute))
+	ended := ac.begin("getbestblockhash", now.Add(-time.Hour))
+	ac.end(ended)
+	if recent == oldest || oldest == ended {
+		t.Fatalf("ids %d, %d and %d are not unique", recent, oldest, ended)
+	}
+
+	cmds := ac.snapshot()
+	if len(cmds) != 2 || cmds[0].Method != "getblock" || cmds[1].Method != "getblockcount" {
+		t.Fatalf("got commands %+v, expect getblock then getblockcount", cmds)
+	}
+	if cmds[0].Duration < int64(time.Minute/time.Microsecond) || cmds[1].Duration < 1000 ||
+		cmds[1].Duration >= cmds[0].Duration {
+		t.Errorf("got durations %d and %d in microseconds", cmds[0].Duration, cmds[1].Duration)
+	}
+
+	ac.end(oldest)
+	ac.end(recent)
+	if cmds := ac.snapshot(); len(cmds) != 0 {
+		t.Errorf("got commands %+v after they all ended", cmds)
+	}
+}
@@ -0,0 +1,706 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/tx"
+	"github.com/copernet/copernicus/persist/disk"
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// wsPath is the path of the websocket endpoint.  Its clients send the
+	// same JSON-RPC requests as over HTTP, and can subscribe to the
+	// notifications of the chain with the websocket only commands.
+	wsPath = "/ws"
+
+	// websocketSendBufferSize is the number of replies and notifications
+	// queued for a websocket client.  A client which doesn't keep up is
+	// disconnected rather than let its queue grow without bounds.
+	websocketSendBufferSize = 1000
+
+	// websocketMaxMessageSize bounds the requests read from the clients.
+	websocketMaxMessageSize = 4 << 20
+
+	// wsNotificationBufferSize is the number of chain notifications queued
+	// for the websocket clients.
+	wsNotificationBufferSize = 10000
+)
+
+// wsCommandHandler describes a callback function used to handle a specific
+// command over websockets.
+type wsCommandHandler func(*wsClient, interface{}) (interface{}, error)
+
+// wsHandlers maps the websocket only commands to their handlers.  The other
+// commands are served by the handlers of the HTTP requests.
+var wsHandlers = map[string]wsCommandHandler{
+	"notifyblocks":              handleNotifyBlocks,
+	"stopnotifyblocks":          handleStopNotifyBlocks,
+	"notifynewtransactions":     handleNotifyNewTransactions,
+	"stopnotifynewtransactions": handleStopNotifyNewTransactions,
+	"notifyspent":               handleNotifySpent,
+	"stopnotifyspent":           handleStopNotifySpent,
+	"notifyreceived":            handleNotifyReceived,
+	"stopnotifyreceived":        handleStopNotifyReceived,
+}
+
+// serveWebsocket upgrades the connection of an authenticated client to a
+// websocket, within the limit of websocket clients.  The connection then
+// lives on its own: it holds no slot of the work queue, only the requests it
+// carries do while executing.
+func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	ip := s.access.clientIP(r)
+	if !s.access.allowed(ip) {
+		log.Warn("RPC websocket of %s refused, the address is not allowed",
+			s.access.clientAddr(r))
+		http.Error(w, "403 Forbidden.", http.StatusForbidden)
+		return
+	}
+	// browsers don't apply the same-origin policy to websockets, whose
+	// handshake carries the credentials of the browser: "*" does not allow
+	// them
+	if origin := r.Header.Get("Origin"); origin != "" && !s.access.listedOrigin(origin) {
+		log.Warn("RPC websocket of %s refused, origin %s is not allowed",
+			s.access.clientAddr(r), origin)
+		http.Error(w, "403 Forbidden.", http.StatusForbidden)
+		return
+	}
+	if s.access.rateLimited(r, ip) {
+		log.Debug("RPC request rate of %s exceeded", s.access.clientAddr(r))
+		http.Error(w, "429 Too many requests.", http.StatusTooManyRequests)
+		return
+	}
+	_, isAdmin, err := s.checkAuth(r, true)
+	if err != nil {
+		jsonAuthFail(w)
+		return
+	}
+
+	addr := s.access.clientAddr(r)
+	if !s.ws.reserve() {
+		log.Info("Max RPC websocket clients exceeded [%d] - disconnecting "+
+			"client %s", conf.Cfg.RPC.RPCMaxWebsockets, addr)
+		http.Error(w, "503 Too busy.  Try again later.",
+			http.StatusServiceUnavailable)
+		return
+	}
+	defer s.ws.release()
+
+	server := websocket.Server{Handler: func(conn *websocket.Conn) {
+		conn.MaxPayloadBytes = websocketMaxMessageSize
+		s.ws.serve(newWSClient(s, conn, addr, isAdmin))
+	}}
+	server.ServeHTTP(w, r)
+}
+
+// wsNotificationManager notifies the websocket clients of the blocks and
+// transactions they subscribed to.
+type wsNotificationManager struct {
+	sub *chain.Subscription
+	// tip is the tip of the active chain as of the latest blocks notified.
+	tip *blockindex.BlockIndex
+	// numClients counts the websocket connections, including those being
+	// upgraded.
+	numClients int32
+
+	mtx     sync.Mutex
+	clients map[*wsClient]struct{}
+	closed  bool
+	// wg tracks the notification handler and the clients.
+	wg sync.WaitGroup
+}
+
+func newWSNotificationManager(c *chain.Chain) *wsNotificationManager {
+	m := &wsNotificationManager{
+		tip:     c.Tip(),
+		clients: make(map[*wsClient]struct{}),
+	}
+	m.sub = c.SubscribeChan(wsNotificationBufferSize, chain.DropNewest,
+		chain.NTChainTipUpdated, chain.NTMempoolTxAdded)
+	m.wg.Add(1)
+	go m.notificationHandler()
+	return m
+}
+
+// reserve counts a new websocket client, unless the maximum is reached.
+func (m *wsNotificationManager) reserve() bool {
+	if int(atomic.AddInt32(&m.numClients, 1)) > conf.Cfg.RPC.RPCMaxWebsockets {
+		atomic.AddInt32(&m.numClients, -1)
+		return false
+	}
+	return true
+}
+
+// release uncounts a websocket client counted by reserve.
+func (m *wsNotificationManager) release() {
+	atomic.AddInt32(&m.numClients, -1)
+}
+
+// serve registers the client and serves its requests until it disconnects
+// or the manager stops.
+func (m *wsNotificationManager) serve(c *wsClient) {
+	m.mtx.Lock()
+	if m.closed {
+		m.mtx.Unlock()
+		c.conn.Close()
+		return
+	}
+	m.clients[c] = struct{}{}
+	m.wg.Add(1)
+	m.mtx.Unlock()
+	log.Info("New websocket client %s", c.addr)
+
+	go c.outHandler()
+	c.inHandler()
+
+	m.mtx.Lock()
+	delete(m.clients, c)
+	m.mtx.Unlock()
+	m.wg.Done()
+	log.Info("Disconnected websocket client %s", c.addr)
+}
+
+// stop stops the notifications and disconnects the clients.  The server
+// shutdown doesn't close the websocket connections, which are hijacked.
+func (m *wsNotificationManager) stop() {
+	m.sub.Close()
+	m.mtx.Lock()
+	m.closed = true
+	for c := range m.clients {
+		c.disconnect()
+	}
+	m.mtx.Unlock()
+	m.wg.Wait()
+}
+
+// subscribers returns the clients which are subscribed to anything.
+func (m *wsNotificationManager) subscribers() []*wsClient {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	clients := make([]*wsClient, 0, len(m.clients))
+	for c := range m.clients {
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+func (m *wsNotificationManager) notificationHandler() {
+	defer m.wg.Done()
+	for notification := range m.sub.Notifications() {
+		switch notification.Type {
+		case chain.NTChainTipUpdated:
+			event, ok := notification.Data.(*chain.TipUpdatedEvent)
+			if !ok || event.TipIndex == nil {
+				continue
+			}
+			m.updateTip(event.TipIndex)
+
+		case chain.NTMempoolTxAdded:
+			if transaction, ok := notification.Data.(*tx.Tx); ok {
+				m.notifyTransaction(m.subscribers(), transaction, nil)
+			}
+		}
+	}
+}
+
+// updateTip notifies the blocks disconnected from the previous tip down to
+// the last common ancestor with the new one, then the blocks connected up to
+// the new tip.
+func (m *wsNotificationManager) updateTip(tip *blockindex.BlockIndex) {
+	fork := blockindex.LastCommonAncestor(m.tip, tip)
+	for index := m.tip; index != nil && index != fork; index = index.Prev {
+		m.notifyBlock(btcjson.BlockDisconnectedNtfnMethod, index)
+	}
+
+	height := tip.Height
+	if fork != nil {
+		height = fork.Height + 1
+	}
+	for ; height <= tip.Height; height++ {
+		m.notifyBlockConnected(tip.GetAncestor(height))
+	}
+	m.tip = tip
+}
+
+// notifyBlock sends a blockconnected or blockdisconnected notification to the
+// clients which registered with notifyblocks.
+func (m *wsNotificationManager) notifyBlock(method string, index *blockindex.BlockIndex) {
+	hash := index.GetBlockHash().String()
+	t := int64(index.Header.Time)
+	var ntfn interface{}
+	if method == btcjson.BlockConnectedNtfnMethod {
+		ntfn = btcjson.NewBlockConnectedNtfn(hash, index.Height, t)
+	} else {
+		ntfn = btcjson.NewBlockDisconnectedNtfn(hash, index.Height, t)
+	}
+
+	var msg []byte
+	for _, c := range m.subscribers() {
+		if !c.watchesBlocks() {
+			continue
+		}
+		if msg == nil {
+			var err error
+			msg, err = btcjson.MarshalCmd(nil, ntfn)
+			if err != nil {
+				log.Error("Failed to marshal %s notification: %v", method, err)
+				return
+			}
+		}
+		c.queue(msg)
+	}
+}
+
+// notifyBlockConnected notifies the block, and its transactions to the
+// clients watching the addresses they pay to or the outpoints they spend.
+func (m *wsNotificationManager) notifyBlockConnected(index *blockindex.BlockIndex) {
+	m.notifyBlock(btcjson.BlockConnectedNtfnMethod, index)
+
+	var watchers []*wsClient
+	for _, c := range m.subscribers() {
+		if c.watchesTransactions() {
+			watchers = append(watchers, c)
+		}
+	}
+	if len(watchers) == 0 {
+		return
+	}
+	blk, ok := disk.ReadBlockFromDisk(index, chain.GetInstance().GetParams())
+	if !ok {
+		log.Error("Websocket notifications: can't read block %s from disk",
+			index.GetBlockHash())
+		return
+	}
+	for i, transaction := range blk.Txs {
+		m.notifyTransaction(watchers, transaction, &btcjson.BlockDetails{
+			Height: index.Height,
+			Hash:   index.GetBlockHash().String(),
+			Index:  i,
+			Time:   int64(index.Header.Time),
+		})
+	}
+}
+
+// notifyTransaction sends the notifications of a transaction to clients: a
+// txaccepted or txacceptedverbose notification to those which registered
+// with notifynewtransactions when it enters the mempool, that is when block
+// is nil, and recvtx and redeemingtx notifications to those watching the
+// addresses it pays to or the outpoints it spends.
+func (m *wsNotificationManager) notifyTransaction(clients []*wsClient, transaction *tx.Tx, block *btcjson.BlockDetails) {
+	n := wsTxNotifications{transaction: transaction, block: block}
+	for _, c := range clients {
+		if block == nil {
+			if txs, verbose := c.watchesNewTransactions(); txs {
+				c.queue(n.accepted(verbose))
+			}
+		}
+		redeeming, received := c.matchTransaction(transaction, block != nil)
+		if redeeming {
+			c.queue(n.tx(btcjson.RedeemingTxNtfnMethod))
+		}
+		if received {
+			c.queue(n.tx(btcjson.RecvTxNtfnMethod))
+		}
+	}
+}
+
+// wsTxNotifications marshals the notifications of a transaction once for all
+// the clients.
+type wsTxNotifications struct {
+	transaction *tx.Tx
+	block       *btcjson.BlockDetails
+	hexTx       string
+	msgs        map[string][]byte
+}
+
+func (n *wsTxNotifications) hex() string {
+	if n.hexTx == "" {
+		var buf bytes.Buffer
+		buf.Grow(int(n.transaction.SerializeSize()))
+		if err := n.transaction.Serialize(&buf); err != nil {
+			log.Error("Failed to serialize transaction %s: %v",
+				util.TxID(n.transaction.GetHash()), err)
+		}
+		n.hexTx = hex.EncodeToString(buf.Bytes())
+	}
+	return n.hexTx
+}
+
+func (n *wsTxNotifications) marshal(method string, ntfn func() interface{}) []byte {
+	if msg, ok := n.msgs[method]; ok {
+		return msg
+	}
+	msg, err := btcjson.MarshalCmd(nil, ntfn())
+	if err != nil {
+		log.Error("Failed to marshal %s notification: %v", method, err)
+	}
+	if n.msgs == nil {
+		n.msgs = make(map[string][]byte)
+	}
+	n.msgs[method] = msg
+	return msg
+}
+
+func (n *wsTxNotifications) accepted(verbose bool) []byte {
+	if !verbose {
+		return n.marshal(btcjson.TxAcceptedNtfnMethod, func() interface{} {
+			var total int64
+			for _, out := range n.transaction.GetOuts() {
+				total += int64(out.GetValue())
+			}
+			return btcjson.NewTxAcceptedNtfn(util.TxID(n.transaction.GetHash()).String(),
+				valueFromAmount(total))
+		})
+	}
+	return n.marshal(btcjson.TxAcceptedVerboseNtfnMethod, func() interface{} {
+		rawTx, _ := getTxRawResult(n.transaction, &util.Hash{}, n.hex(), nil)
+		return btcjson.NewTxAcceptedVerboseNtfn(*rawTx)
+	})
+}
+
+func (n *wsTxNotifications) tx(method string) []byte {
+	return n.marshal(method, func() interface{} {
+		if method == btcjson.RedeemingTxNtfnMethod {
+			return btcjson.NewRedeemingTxNtfn(n.hex(), n.block)
+		}
+		return btcjson.NewRecvTxNtfn(n.hex(), n.block)
+	})
+}
+
+// wsClient is a websocket connection and the notifications it subscribed to.
+type wsClient struct {
+	server  *Server
+	conn    *websocket.Conn
+	addr    string
+	isAdmin bool
+
+	send      chan []byte
+	quit      chan struct{}
+	closeOnce sync.Once
+
+	mtx        sync.Mutex
+	blocks     bool
+	txs        bool
+	txsVerbose bool
+	addrs      map[string]struct{}
+	spent      map[outpoint.OutPoint]struct{}
+}
+
+func newWSClient(s *Server, conn *websocket.Conn, addr string, isAdmin bool) *wsClient {
+	return &wsClient{
+		server:  s,
+		conn:    conn,
+		addr:    addr,
+		isAdmin: isAdmin,
+		send:    make(chan []byte, websocketSendBufferSize),
+		quit:    make(chan struct{}),
+		addrs:   make(map[string]struct{}),
+		spent:   make(map[outpoint.OutPoint]struct{}),
+	}
+}
+
+// disconnect closes the connection, which ends the handlers of the client.
+func (c *wsClient) disconnect() {
+	c.closeOnce.Do(func() {
+		close(c.quit)
+		c.conn.Close()
+	})
+}
+
+// queue queues a reply or a notification for the client, disconnecting it
+// when its queue is full.
+func (c *wsClient) queue(msg []byte) {
+	if msg == nil {
+		return
+	}
+	select {
+	case c.send <- msg:
+	case <-c.quit:
+	default:
+		log.Warn("Websocket client %s doesn't keep up with its "+
+			"notifications, disconnecting", c.addr)
+		c.disconnect()
+	}
+}
+
+// outHandler writes the queued messages to the connection.
+func (c *wsClient) outHandler() {
+	for {
+		select {
+		case msg := <-c.send:
+			if err := websocket.Message.Send(c.conn, string(msg)); err != nil {
+				c.disconnect()
+				return
+			}
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// inHandler reads and executes the requests of the client in order until it
+// disconnects.
+func (c *wsClient) inHandler() {
+	defer c.disconnect()
+	for {
+		var msg []byte
+		if err := websocket.Message.Receive(c.conn, &msg); err != nil {
+			return
+		}
+		c.handleMessage(msg)
+	}
+}
+
+func (c *wsClient) handleMessage(msg []byte) {
+	var request btcjson.Request
+	if err := json.Unmarshal(msg, &request); err == nil {
+		if handler, ok := wsHandlers[request.Method]; ok {
+			c.queue(c.handleWSRequest(&request, handler))
+			return
+		}
+	}
+
+	if !c.server.workQueue.enter(c.quit) {
+		log.Warn("RPC work queue depth exceeded - refusing request of %s", c.addr)
+		reply, err := createMarshalledReply(btcjson.RPCVersion1, request.ID, nil,
+			btcjson.NewRPCError(btcjson.ErrRPCMisc, "Work queue depth exceeded"))
+		if err != nil {
+			log.Error("Failed to marshal reply: %v", err)
+			return
+		}
+		c.queue(reply)
+		return
+	}
+	reply, _ := c.server.processRequest(msg, c.quit)
+	c.server.workQueue.leave()
+	c.queue(reply)
+}
+
+// handleWSRequest executes a websocket only command and returns its reply.
+func (c *wsClient) handleWSRequest(request *btcjson.Request, handler wsCommandHandler) []byte {
+	rpcVersion, jsonErr := requestRPCVersion(request)
+	isNotification := jsonErr == nil && rpcVersion == btcjson.RPCVersion2 && request.ID == nil
+	if jsonErr == nil && request.ID == nil && !isNotification &&
+		!(conf.Cfg.RPC.RPCQuirks && request.Jsonrpc == "") {
+		return nil
+	}
+
+	var result interface{}
+	if jsonErr == nil {
+		parsedCmd := parseCmd(request)
+		if parsedCmd.err != nil {
+			jsonErr = parsedCmd.err
+		} else {
+			result, jsonErr = handler(c, parsedCmd.cmd)
+		}
+	}
+	if isNotification {
+		return nil
+	}
+
+	reply, err := createMarshalledReply(rpcVersion, request.ID, result, jsonErr)
+	if err != nil {
+		log.Error("Failed to marshal reply: %v", err)
+		return nil
+	}
+	return reply
+}
+
+func (c *wsClient) watchesBlocks() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.blocks
+}
+
+func (c *wsClient) watchesNewTransactions() (txs, verbose bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.txs, c.txsVerbose
+}
+
+// watchesTransactions returns whether the client watches addresses or
+// outpoints.
+func (c *wsClient) watchesTransactions() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return len(c.addrs) > 0 || len(c.spent) > 0
+}
+
+// matchTransaction returns whether the transaction spends an outpoint the
+// client watches, and whether it pays to an address the client watches.  The
+// outputs paying to the addresses are watched from then on, and the outpoints
+// spent are no longer watched once the transaction is mined.
+func (c *wsClient) matchTransaction(transaction *tx.Tx, mined bool) (redeeming, received bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if len(c.spent) > 0 && !transaction.IsCoinBase() {
+		for _, in := range transaction.GetIns() {
+			if _, ok := c.spent[*in.PreviousOutPoint]; ok {
+				redeeming = true
+				if mined {
+					delete(c.spent, *in.PreviousOutPoint)
+				}
+			}
+		}
+	}
+
+	if len(c.addrs) > 0 {
+		hash := transaction.GetHash()
+		for i, out := range transaction.GetOuts() {
+			_, addresses, _, err := out.GetScriptPubKey().ExtractDestinations()
+			if err != nil {
+				continue
+			}
+			for _, addr := range addresses {
+				if _, ok := c.addrs[addr.String()]; ok {
+					received = true
+					c.spent[*outpoint.NewOutPoint(hash, uint32(i))] = struct{}{}
+					break
+				}
+			}
+		}
+	}
+	return redeeming, received
+}
+
+// handleNotifyBlocks implements the notifyblocks command extension for
+// websocket connections.
+func handleNotifyBlocks(c *wsClient, cmd interface{}) (interface{}, error) {
+	c.mtx.Lock()
+	c.blocks = true
+	c.mtx.Unlock()
+	return nil, nil
+}
+
+// handleStopNotifyBlocks implements the stopnotifyblocks command extension for
+// websocket connections.
+func handleStopNotifyBlocks(c *wsClient, cmd interface{}) (interface{}, error) {
+	c.mtx.Lock()
+	c.blocks = false
+	c.mtx.Unlock()
+	return nil, nil
+}
+
+// handleNotifyNewTransactions implements the notifynewtransactions command
+// extension for websocket connections.
+func handleNotifyNewTransactions(c *wsClient, cmd interface{}) (interface{}, error) {
+	command := cmd.(*btcjson.NotifyNewTransactionsCmd)
+	c.mtx.Lock()
+	c.txs = true
+	c.txsVerbose = command.Verbose != nil && *command.Verbose
+	c.mtx.Unlock()
+	return nil, nil
+}
+
+// handleStopNotifyNewTransactions implements the stopnotifynewtransactions
+// command extension for websocket connections.
+func handleStopNotifyNewTransactions(c *wsClient, cmd interface{}) (interface{}, error) {
+	c.mtx.Lock()
+	c.txs = false
+	c.txsVerbose = false
+	c.mtx.Unlock()
+	return nil, nil
+}
+
+// handleNotifySpent implements the notifyspent command extension for
+// websocket connections.
+func handleNotifySpent(c *wsClient, cmd interface{}) (interface{}, error) {
+	outPoints, err := deserializeOutpoints(cmd.(*btcjson.NotifySpentCmd).OutPoints)
+	if err != nil {
+		return nil, err
+	}
+	c.mtx.Lock()
+	for _, op := range outPoints {
+		c.spent[op] = struct{}{}
+	}
+	c.mtx.Unlock()
+	return nil, nil
+}
+
+// handleStopNotifySpent implements the stopnotifyspent command extension for
+// websocket connections.
+func handleStopNotifySpent(c *wsClient, cmd interface{}) (interface{}, error) {
+	outPoints, err := deserializeOutpoints(cmd.(*btcjson.StopNotifySpentCmd).OutPoints)
+	if err != nil {
+		return nil, err
+	}
+	c.mtx.Lock()
+	for _, op := range outPoints {
+		delete(c.spent, op)
+	}
+	c.mtx.Unlock()
+	return nil, nil
+}
+
+// handleNotifyReceived implements the notifyreceived command extension for
+// websocket connections.
+func handleNotifyReceived(c *wsClient, cmd interface{}) (interface{}, error) {
+	addrs, err := decodeAddresses(cmd.(*btcjson.NotifyReceivedCmd).Addresses)
+	if err != nil {
+		return nil, err
+	}
+	c.mtx.Lock()
+	for _, addr := range addrs {
+		c.addrs[addr] = struct{}{}
+	}
+	c.mtx.Unlock()
+	return nil, nil
+}
+
+// handleStopNotifyReceived implements the stopnotifyreceived command extension
+// for websocket connections.
+func handleStopNotifyReceived(c *wsClient, cmd interface{}) (interface{}, error) {
+	addrs, err := decodeAddresses(cmd.(*btcjson.StopNotifyReceivedCmd).Addresses)
+	if err != nil {
+		return nil, err
+	}
+	c.mtx.Lock()
+	for _, addr := range addrs {
+		delete(c.addrs, addr)
+	}
+	c.mtx.Unlock()
+	return nil, nil
+}
+
+// deserializeOutpoints converts the outpoints of a command to those of the
+// transactions.
+func deserializeOutpoints(serializedOuts []btcjson.OutPoint) ([]outpoint.OutPoint, error) {
+	outpoints := make([]outpoint.OutPoint, 0, len(serializedOuts))
+	for _, out := range serializedOuts {
+		txid, err := util.TxIDFromStr(out.Hash)
+		if err != nil {
+			return nil, rpcDecodeHexError(out.Hash)
+		}
+		outpoints = append(outpoints, *outpoint.NewOutPoint(txid.Hash(), out.Index))
+	}
+	return outpoints, nil
+}
+
+// decodeAddresses checks the addresses of a command and returns them in their
+// canonical encoding.
+func decodeAddresses(addrs []string) ([]string, error) {
+	decoded := make([]string, 0, len(addrs))
+	for _, addrStr := range addrs {
+		addr, err := script.AddressFromString(addrStr)
+		if err != nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey,
+				"Invalid address: "+addrStr)
+		}
+		decoded = append(decoded, addr.String())
+	}
+	return decoded, nil
+}
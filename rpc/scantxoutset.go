@@ -0,0 +1,219 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/copernet/copernicus/logic/lutxo"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+)
+
+// txOutSetScan tracks the scantxoutset scan in progress, only one may run at
+// a time.
+var txOutSetScan struct {
+	sync.Mutex
+	running  bool
+	progress int
+	abort    chan struct{}
+}
+
+func handleScanTxOutSet(s *Server, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ScanTxOutSetCmd)
+
+	switch c.Action {
+	case "status":
+		txOutSetScan.Lock()
+		defer txOutSetScan.Unlock()
+		if !txOutSetScan.running {
+			return nil, nil
+		}
+		return &btcjson.ScanTxOutSetStatusResult{Progress: txOutSetScan.progress}, nil
+
+	case "abort":
+		txOutSetScan.Lock()
+		defer txOutSetScan.Unlock()
+		if !txOutSetScan.running {
+			return false, nil
+		}
+		select {
+		case <-txOutSetScan.abort:
+		default:
+			close(txOutSetScan.abort)
+		}
+		return true, nil
+
+	case "start":
+		if c.ScanObjects == nil {
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "scanobjects argument is required for the start action")
+		}
+		descs := make(map[string]string)
+		for _, scanObject := range *c.ScanObjects {
+			scripts, err := scanObjectScripts(scanObject)
+			if err != nil {
+				return nil, err
+			}
+			for _, scriptPubKey := range scripts {
+				descs[string(scriptPubKey)] = scanObject
+			}
+		}
+
+		txOutSetScan.Lock()
+		if txOutSetScan.running {
+			txOutSetScan.Unlock()
+			return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter,
+				"Scan already in progress, use action \"abort\" or \"status\"")
+		}
+		txOutSetScan.running = true
+		txOutSetScan.progress = 0
+		txOutSetScan.abort = make(chan struct{})
+		abort := txOutSetScan.abort
+		txOutSetScan.Unlock()
+		defer func() {
+			txOutSetScan.Lock()
+			txOutSetScan.running = false
+			txOutSetScan.Unlock()
+		}()
+
+		return scanTxOutSet(descs, closeChan, abort)
+
+	default:
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInvalidParameter, "Invalid command")
+	}
+}
+
+// scanTxOutSet walks the UTXO set for the outputs paying to the scripts of
+// descs, which maps scriptPubKeys to the scan objects they were derived from.
+func scanTxOutSet(descs map[string]string, closeChan, abort <-chan struct{}) (interface{}, error) {
+	cursor, index, err := openCoinsCursor()
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	interrupt := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-closeChan:
+		case <-abort:
+		case <-done:
+			return
+		}
+		close(interrupt)
+	}()
+
+	result := &btcjson.ScanTxOutSetResult{
+		Height:    index.Height,
+		BestBlock: index.GetBlockHash().String(),
+		Unspents:  make([]*btcjson.ScanTxOutSetUnspent, 0),
+	}
+	totalAmount := int64(0)
+	err = lutxo.WalkCoins(cursor, interrupt, func(out *outpoint.OutPoint, coin *utxo.Coin) error {
+		result.SearchedItems++
+		if result.SearchedItems%10000 == 0 {
+			// coins are walked in txid order, whose leading bytes tell how far
+			// the scan got
+			high := 0x100*int(out.Hash[0]) + int(out.Hash[1])
+			txOutSetScan.Lock()
+			txOutSetScan.progress = high * 100 / 0x10000
+			txOutSetScan.Unlock()
+		}
+		scriptPubKey := coin.GetScriptPubKey().GetData()
+		desc, ok := descs[string(scriptPubKey)]
+		if !ok {
+			return nil
+		}
+		totalAmount += int64(coin.GetAmount())
+		result.Unspents = append(result.Unspents, &btcjson.ScanTxOutSetUnspent{
+			TxID:         util.TxID(out.Hash).String(),
+			Vout:         out.Index,
+			ScriptPubKey: hex.EncodeToString(scriptPubKey),
+			Desc:         desc,
+			Amount:       valueFromAmount(int64(coin.GetAmount())),
+			Height:       coin.GetHeight(),
+		})
+		return nil
+	})
+	if err != nil && err != lutxo.ErrInterrupted {
+		return nil, btcjson.NewRPCError(btcjson.ErrRPCInternal.Code, "Unable to read UTXO set")
+	}
+	result.Success = err == nil
+	result.TotalAmount = valueFromAmount(totalAmount)
+	return result, nil
+}
+
+// scanObjectScripts returns the scriptPubKeys described by a scan object: an
+// address, or one of the descriptors addr(<address>), raw(<hex script>),
+// pk(<hex pubkey>), pkh(<hex pubkey>) and combo(<hex pubkey>).
+func scanObjectScripts(scanObject string) ([][]byte, error) {
+	name, arg := "addr", scanObject
+	if open := strings.IndexByte(scanObject, '('); open > 0 && strings.HasSuffix(scanObject, ")") {
+		name, arg = scanObject[:open], scanObject[open+1:len(scanObject)-1]
+	}
+	invalid := btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey,
+		fmt.Sprintf("Invalid scan object: %s", scanObject))
+
+	switch name {
+	case "addr":
+		addr, err := script.AddressFromString(arg)
+		if err != nil {
+			return nil, invalid
+		}
+		scriptPubKey, err := script.PayToAddrScript(addr)
+		if err != nil {
+			return nil, invalid
+		}
+		return [][]byte{scriptPubKey.GetData()}, nil
+
+	case "raw":
+		scriptPubKey, err := hex.DecodeString(arg)
+		if err != nil {
+			return nil, invalid
+		}
+		return [][]byte{scriptPubKey}, nil
+
+	case "pk", "pkh", "combo":
+		pubKey, err := hex.DecodeString(arg)
+		if err != nil || !isPubKeyEncoding(pubKey) {
+			return nil, invalid
+		}
+		scripts := make([][]byte, 0, 2)
+		if name != "pkh" {
+			p2pk, err := script.NewScriptPubKey(pubKey)
+			if err != nil {
+				return nil, invalid
+			}
+			scripts = append(scripts, p2pk.GetData())
+		}
+		if name != "pk" {
+			p2pkh, err := script.NewScriptPubKeyHash(util.Hash160(pubKey))
+			if err != nil {
+				return nil, invalid
+			}
+			scripts = append(scripts, p2pkh.GetData())
+		}
+		return scripts, nil
+
+	default:
+		return nil, invalid
+	}
+}
+
+// isPubKeyEncoding reports whether b looks like a compressed or uncompressed
+// public key.
+func isPubKeyEncoding(b []byte) bool {
+	switch len(b) {
+	case 33:
+		return b[0] == 0x02 || b[0] == 0x03
+	case 65:
+		return b[0] == 0x04
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+	"github.com/copernet/copernicus/model/opcodes"
+	"github.com/copernet/copernicus/model/outpoint"
+	"github.com/copernet/copernicus/model/script"
+	"github.com/copernet/copernicus/model/txout"
+	"github.com/copernet/copernicus/model/utxo"
+	"github.com/copernet/copernicus/persist/db"
+	"github.com/copernet/copernicus/rpc/btcjson"
+	"github.com/copernet/copernicus/util"
+)
+
+var (
+	scanScriptTrue  = []byte{opcodes.OP_TRUE}
+	scanScriptEqual = []byte{opcodes.OP_11, opcodes.OP_EQUAL}
+)
+
+// initWalkedCoins sets up in path a chain of two blocks whose coins database
+// is at the first one, holding two outputs paying to scanScriptTrue and one
+// to scanScriptEqual. It returns the two block indexes.
+func initWalkedCoins(t *testing.T, path string) (*blockindex.BlockIndex, *blockindex.BlockIndex) {
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	utxo.InitUtxoLruTip(&utxo.UtxoConfig{Do: &db.DBOption{FilePath: path, CacheSize: 1 << 20}})
+	chain.InitGlobalChain()
+
+	genesis := blockindex.NewBlockIndex(&model.ActiveNetParams.GenesisBlock.Header)
+	header := block.BlockHeader{HashPrevBlock: *genesis.GetBlockHash(), Time: genesis.Header.Time + 600}
+	tip := blockindex.NewBlockIndex(&header)
+	tip.Height = 1
+	tip.Prev = genesis
+	chain.GetInstance().InitLoad(map[util.Hash]*blockindex.BlockIndex{
+		*genesis.GetBlockHash(): genesis,
+		*tip.GetBlockHash():     tip,
+	}, nil)
+	chain.GetInstance().SetTip(tip)
+
+	hash1 := util.HashFromString("01")
+	hash2 := util.HashFromString("02")
+	necm := utxo.NewEmptyCoinsMap()
+	necm.AddCoin(&outpoint.OutPoint{Hash: *hash1, Index: 0},
+		utxo.NewCoin(txout.NewTxOut(1000, script.NewScriptRaw(scanScriptTrue)), 0, true), true)
+	necm.AddCoin(&outpoint.OutPoint{Hash: *hash2, Index: 0},
+		utxo.NewCoin(txout.NewTxOut(300, script.NewScriptRaw(scanScriptEqual)), 0, false), true)
+	necm.AddCoin(&outpoint.OutPoint{Hash: *hash2, Index: 1},
+		utxo.NewCoin(txout.NewTxOut(200, script.NewScriptRaw(scanScriptTrue)), 0, false), true)
+	view := utxo.GetUtxoCacheInstance()
+	if err := view.UpdateCoins(necm, genesis.GetBlockHash()); err != nil {
+		t.Fatal(err)
+	}
+	if !view.Flush() {
+		t.Fatal("flush the coins failed")
+	}
+
+	// the coins are flushed already, skip the flush of the whole state
+	coinsWalkFlush.Lock()
+	coinsWalkFlush.last = time.Now()
+	coinsWalkFlush.Unlock()
+	return genesis, tip
+}
+
+func TestScanTxOutSet(t *testing.T) {
+	path, err := ioutil.TempDir("", "scantxoutset")
+	if err != nil {
+		t.Fatalf("generate temp db path failed: %s\n", err)
+	}
+	defer os.RemoveAll(path)
+	genesis, _ := initWalkedCoins(t, path)
+
+	tests := []struct {
+		scanObjects []string
+		vouts       map[uint32]int
+		total       float64
+	}{
+		{[]string{"raw(" + hex.EncodeToString(scanScriptTrue) + ")"}, map[uint32]int{0: 1, 1: 1}, 0.000012},
+		{[]string{"raw(" + hex.EncodeToString(scanScriptEqual) + ")"}, map[uint32]int{0: 1}, 0.000003},
+		{[]string{"raw(" + hex.EncodeToString(scanScriptTrue) + ")",
+			"raw(" + hex.EncodeToString(scanScriptEqual) + ")"}, map[uint32]int{0: 2, 1: 1}, 0.000015},
+		{[]string{"raw(00)"}, map[uint32]int{}, 0},
+	}
+	for _, test := range tests {
+		descs := make(map[string]string)
+		for _, scanObject := range test.scanObjects {
+			scripts, err := scanObjectScripts(scanObject)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, scriptPubKey := range scripts {
+				descs[string(scriptPubKey)] = scanObject
+			}
+		}
+		ret, err := scanTxOutSet(descs, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := ret.(*btcjson.ScanTxOutSetResult)
+		if !result.Success || result.SearchedItems != 3 {
+			t.Errorf("%v: success %t after %d outputs", test.scanObjects, result.Success, result.SearchedItems)
+		}
+		if result.Height != 0 || result.BestBlock != genesis.GetBlockHash().String() {
+			t.Errorf("%v: scanned at %d %s, expect the block of the coins database",
+				test.scanObjects, result.Height, result.BestBlock)
+		}
+		vouts := make(map[uint32]int)
+		for _, unspent := range result.Unspents {
+			if descs[string(mustDecodeHex(t, unspent.ScriptPubKey))] != unspent.Desc {
+				t.Errorf("%v: output %s:%d reported for %s", test.scanObjects, unspent.TxID, unspent.Vout, unspent.Desc)
+			}
+			vouts[unspent.Vout]++
+		}
+		if len(vouts) != len(test.vouts) || result.TotalAmount != test.total {
+			t.Errorf("%v: found outputs %v of %v BCH, expect %v of %v BCH",
+				test.scanObjects, vouts, result.TotalAmount, test.vouts, test.total)
+		}
+		for vout, count := range test.vouts {
+			if vouts[vout] != count {
+				t.Errorf("%v: found %d outputs %d, expect %d", test.scanObjects, vouts[vout], vout, count)
+			}
+		}
+	}
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
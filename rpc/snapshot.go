@@ -0,0 +1,73 @@
+package rpc
+
+import (
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+)
+
+// chainSnapshot pins the tip of the active chain for the duration of a
+// request, so that handlers answering from several chain lookups (heights,
+// confirmations, next blocks) stay consistent when blocks are connected or
+// disconnected meanwhile. Handlers take one when they start and answer every
+// chain question through it.
+type chainSnapshot struct {
+	tip *blockindex.BlockIndex
+}
+
+func newChainSnapshot() *chainSnapshot {
+	return &chainSnapshot{tip: chain.GetInstance().Tip()}
+}
+
+// Tip returns the pinned tip, nil before the genesis block is connected.
+func (cs *chainSnapshot) Tip() *blockindex.BlockIndex {
+	return cs.tip
+}
+
+// Height returns the height of the pinned tip, or -1 when there is none.
+func (cs *chainSnapshot) Height() int32 {
+	if cs.tip == nil {
+		return -1
+	}
+	return cs.tip.Height
+}
+
+// GetIndex returns the block of the pinned chain at height.
+func (cs *chainSnapshot) GetIndex(height int32) *blockindex.BlockIndex {
+	if cs.tip == nil || height < 0 || height > cs.tip.Height {
+		return nil
+	}
+	gChain := chain.GetInstance()
+	index := gChain.GetIndex(height)
+	// while the pinned tip is still active, the active chain below it is the
+	// pinned one and the lookup needs no walk
+	if index != nil && gChain.Contains(cs.tip) {
+		return index
+	}
+	return cs.tip.GetAncestor(height)
+}
+
+// Contains reports whether index is part of the pinned chain.
+func (cs *chainSnapshot) Contains(index *blockindex.BlockIndex) bool {
+	if index == nil {
+		return false
+	}
+	return cs.GetIndex(index.Height) == index
+}
+
+// Next returns the successor of index in the pinned chain, or nil if index is
+// not part of it or is the pinned tip.
+func (cs *chainSnapshot) Next(index *blockindex.BlockIndex) *blockindex.BlockIndex {
+	if !cs.Contains(index) {
+		return nil
+	}
+	return cs.GetIndex(index.Height + 1)
+}
+
+// Confirmations returns the confirmations of index in the pinned chain, or -1
+// if it is not part of it.
+func (cs *chainSnapshot) Confirmations(index *blockindex.BlockIndex) int32 {
+	if !cs.Contains(index) {
+		return -1
+	}
+	return cs.tip.Height - index.Height + 1
+}
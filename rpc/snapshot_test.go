@@ -0,0 +1,74 @@
+package rpc
+
+import (
+	"testing"
+
+	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
+	"github.com/copernet/copernicus/model/chain"
+)
+
+func TestChainSnapshot(t *testing.T) {
+	if conf.Cfg == nil {
+		conf.Cfg = &conf.Configuration{}
+	}
+	chain.InitGlobalChain()
+	gChain := chain.GetInstance()
+
+	newIndex := func(prev *blockindex.BlockIndex, time uint32) *blockindex.BlockIndex {
+		header := block.BlockHeader{Time: time}
+		index := blockindex.NewBlockIndex(&header)
+		if prev != nil {
+			index.Prev = prev
+			index.Height = prev.Height + 1
+		}
+		return index
+	}
+	genesis := newIndex(nil, 1)
+	a1 := newIndex(genesis, 2)
+	a2 := newIndex(a1, 3)
+	b1 := newIndex(genesis, 4)
+	b2 := newIndex(b1, 5)
+	b3 := newIndex(b2, 6)
+
+	defer gChain.SetTip(nil)
+	gChain.SetTip(nil)
+	empty := newChainSnapshot()
+	if empty.Tip() != nil || empty.Height() != -1 || empty.GetIndex(0) != nil || empty.Contains(genesis) {
+		t.Errorf("a snapshot of an empty chain reports blocks")
+	}
+
+	gChain.SetTip(a2)
+	snapshot := newChainSnapshot()
+	check := func(when string) {
+		if snapshot.Tip() != a2 || snapshot.Height() != 2 {
+			t.Errorf("%s: pinned tip at %d", when, snapshot.Height())
+		}
+		for height, index := range []*blockindex.BlockIndex{genesis, a1, a2} {
+			if got := snapshot.GetIndex(int32(height)); got != index {
+				t.Errorf("%s: block at %d is %v", when, height, got)
+			}
+		}
+		if snapshot.GetIndex(3) != nil || snapshot.GetIndex(-1) != nil {
+			t.Errorf("%s: blocks out of the pinned chain returned", when)
+		}
+		if !snapshot.Contains(a1) || snapshot.Contains(b1) || snapshot.Contains(nil) {
+			t.Errorf("%s: wrong blocks in the pinned chain", when)
+		}
+		if snapshot.Next(a1) != a2 || snapshot.Next(a2) != nil || snapshot.Next(b1) != nil {
+			t.Errorf("%s: wrong successors in the pinned chain", when)
+		}
+		if snapshot.Confirmations(a1) != 2 || snapshot.Confirmations(a2) != 1 || snapshot.Confirmations(b1) != -1 {
+			t.Errorf("%s: wrong confirmations in the pinned chain", when)
+		}
+	}
+	check("active tip")
+
+	// a reorganization to a longer branch leaves the pinned chain alone
+	gChain.SetTip(b3)
+	check("after a reorganization")
+	if newChainSnapshot().Confirmations(b1) != 3 {
+		t.Errorf("a new snapshot does not follow the active chain")
+	}
+}
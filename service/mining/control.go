@@ -1,16 +1,14 @@
 package mining
 
-import "sync/atomic"
-
-var (
-	maxBlockSize uint64
-	//blockPriorityPercentage uint64		// not be used at current version
-)
+import "github.com/copernet/copernicus/model/block"
 
+// GetBlockSize returns the excessive block size.
 func GetBlockSize() uint64 {
-	return atomic.LoadUint64(&maxBlockSize)
+	return block.GetMaxBlockSize()
 }
 
+// SetBlockSize sets the excessive block size, which also bounds the size of
+// the blocks unserialized from peers and disk.
 func SetBlockSize(size uint64) {
-	atomic.StoreUint64(&maxBlockSize, size)
+	block.SetMaxBlockSize(size)
 }
@@ -1,10 +1,9 @@
 package mining
 
 import (
-	"bytes"
-	"encoding/binary"
 	"math"
 	"strconv"
+	"sync"
 
 	"github.com/copernet/copernicus/conf"
 	"github.com/copernet/copernicus/log"
@@ -36,18 +35,35 @@ const (
 	maxConsecutiveFailures = 1000
 )
 
-// global value for getmininginfo rpc use
-var (
-	lastBlockTx   uint64
-	lastBlockSize uint64
-)
+// lastBlock describes the last block template created, for getmininginfo.
+var lastBlock struct {
+	sync.RWMutex
+	tx   uint64
+	size uint64
+	fees amount.Amount
+}
 
+// GetLastBlockTx returns the number of transactions, not counting the
+// coinbase, of the last block template created.
 func GetLastBlockTx() uint64 {
-	return lastBlockTx
+	lastBlock.RLock()
+	defer lastBlock.RUnlock()
+	return lastBlock.tx
 }
 
+// GetLastBlockSize returns the size of the last block template created.
 func GetLastBlockSize() uint64 {
-	return lastBlockSize
+	lastBlock.RLock()
+	defer lastBlock.RUnlock()
+	return lastBlock.size
+}
+
+// GetLastBlockFees returns the fees collected by the last block template
+// created.
+func GetLastBlockFees() amount.Amount {
+	lastBlock.RLock()
+	defer lastBlock.RUnlock()
+	return lastBlock.fees
 }
 
 type BlockTemplate struct {
@@ -129,10 +145,10 @@ func computeMaxGeneratedBlockSize() uint64 {
 	// If both are given, restrict both.
 	maxGeneratedBlockSize := conf.Cfg.Mining.BlockMaxSize
 
-	// Limit size to between 1K and MaxBlockSize-1K for sanity:
-	csize := consensus.DefaultMaxBlockSize - 1000
-	if uint64(csize) < maxGeneratedBlockSize {
-		maxGeneratedBlockSize = uint64(csize)
+	// Limit size to between 1K and the excessive block size-1K for sanity:
+	csize := GetBlockSize() - 1000
+	if csize < maxGeneratedBlockSize {
+		maxGeneratedBlockSize = csize
 	}
 	if 1000 > maxGeneratedBlockSize {
 		maxGeneratedBlockSize = 1000
@@ -282,16 +298,19 @@ func (ba *BlockAssembler) CreateNewBlock(coinbaseScript *script.Script) *BlockTe
 	time1 := util.GetMockTimeInMicros()
 
 	// record last mining info for getmininginfo rpc using
-	lastBlockTx = ba.blockTx
-	lastBlockSize = ba.blockSize
+	lastBlock.Lock()
+	lastBlock.tx = ba.blockTx
+	lastBlock.size = ba.blockSize
+	lastBlock.fees = ba.fees
+	lastBlock.Unlock()
 
 	// Create coinbase transaction
 	coinbaseTx := tx.NewTx(0, 0x01)
-	buf := bytes.NewBuffer(nil)
-	bs := make([]byte, 8)
-	binary.LittleEndian.PutUint64(bs, uint64(ba.height))
-	buf.Write([]byte{opcodes.OP_0})
-	coinbaseTx.AddTxIn(txin.NewTxIn(&outpoint.OutPoint{Hash: util.HashZero, Index: 0xffffffff}, script.NewScriptRaw(buf.Bytes()), 0xffffffff))
+	// The height comes first in the coinbase script as BIP34 requires.
+	scriptSig := script.NewEmptyScript()
+	scriptSig.PushInt64(int64(ba.height))
+	scriptSig.PushOpCode(opcodes.OP_0)
+	coinbaseTx.AddTxIn(txin.NewTxIn(&outpoint.OutPoint{Hash: util.HashZero, Index: 0xffffffff}, scriptSig, 0xffffffff))
 
 	// value represents total reward(fee and block generate reward)
 	value := ba.fees + GetBlockSubsidy(ba.height, ba.chainParams)
@@ -396,33 +415,48 @@ func (ba *BlockAssembler) updatePackagesForAdded(txSet *btree.BTree, alreadyAdde
 	return descendantUpdate
 }
 
-func IncrementExtraNonce(bk *block.Block, bindex *blockindex.BlockIndex) (extraNonce uint) {
-	// Update nExtraNonce
-	if bk.Header.HashPrevBlock != util.HashZero {
-		extraNonce = 0
-	}
-	extraNonce++
-	// Height first in coinbase required for block.version=2
-	height := bindex.Height + 1
-
-	// TODO lack of lscript builder to construct script conveniently<lscript>
-	buf := bytes.NewBuffer(nil)
-	bytesEight := make([]byte, 8)
-	binary.LittleEndian.PutUint64(bytesEight, uint64(height))
-	buf.Write(bytesEight)
-
-	binary.LittleEndian.PutUint64(bytesEight, uint64(extraNonce))
-	buf.Write(bytesEight)
+// extraNonce is the last extra nonce IncrementExtraNonce put in a coinbase,
+// it restarts from one when the parent block changes.
+var extraNonce struct {
+	sync.Mutex
+	hashPrevBlock util.Hash
+	value         uint
+}
 
-	buf.Write(getExcessiveBlockSizeSig())
-	buf.Write([]byte(CoinbaseFlag))
+// IncrementExtraNonce rewrites the coinbase of bk, a block template on top of
+// bindex, with the next extra nonce, so that the nonce space of its header
+// can be searched again. It returns the extra nonce used.
+func IncrementExtraNonce(bk *block.Block, bindex *blockindex.BlockIndex) uint {
+	extraNonce.Lock()
+	if extraNonce.hashPrevBlock != bk.Header.HashPrevBlock {
+		extraNonce.hashPrevBlock = bk.Header.HashPrevBlock
+		extraNonce.value = 0
+	}
+	extraNonce.value++
+	nonce := extraNonce.value
+	extraNonce.Unlock()
 
-	coinbaseScript := script.NewScriptRaw(buf.Bytes())
-	bk.Txs[0].GetIns()[0].SetScriptSig(coinbaseScript)
+	// Height first in coinbase required for block.version=2
+	scriptSig := script.NewEmptyScript()
+	scriptSig.PushInt64(int64(bindex.Height + 1))
+	scriptSig.PushInt64(int64(nonce))
+	scriptSig.PushSingleData(append(getExcessiveBlockSizeSig(), CoinbaseFlag...))
+
+	// The transaction caches its hash, build a new one rather than changing
+	// the script of its input.
+	coinbase := bk.Txs[0]
+	in := coinbase.GetIns()[0]
+	newCoinbase := tx.NewTx(coinbase.GetLockTime(), coinbase.GetVersion())
+	newCoinbase.AddTxIn(txin.NewTxIn(in.PreviousOutPoint, scriptSig, in.Sequence))
+	for _, out := range coinbase.GetOuts() {
+		newCoinbase.AddTxOut(out)
+	}
+	bk.Txs[0] = newCoinbase
 
 	bk.Header.MerkleRoot = lmerkleroot.BlockMerkleRoot(bk.Txs, nil)
+	bk.Header.Hash = util.Hash{}
 
-	return extraNonce
+	return nonce
 }
 
 // This function convert MaxBlockSize from byte to
@@ -451,7 +485,7 @@ func getSubVersionEB(maxBlockSize uint64) string {
 }
 
 func getExcessiveBlockSizeSig() []byte {
-	cbmsg := "/EB" + getSubVersionEB(consensus.DefaultMaxBlockSize) + "/"
+	cbmsg := "/EB" + getSubVersionEB(GetBlockSize()) + "/"
 	return []byte(cbmsg)
 }
 
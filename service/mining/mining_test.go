@@ -1,8 +1,16 @@
 package mining
 
 import (
+	"bytes"
+	"math"
+	"os"
+	"testing"
+
 	"github.com/copernet/copernicus/conf"
+	"github.com/copernet/copernicus/logic/lmerkleroot"
 	"github.com/copernet/copernicus/model"
+	"github.com/copernet/copernicus/model/block"
+	"github.com/copernet/copernicus/model/blockindex"
 	"github.com/copernet/copernicus/model/chain"
 	"github.com/copernet/copernicus/model/mempool"
 	"github.com/copernet/copernicus/model/opcodes"
@@ -13,8 +21,6 @@ import (
 	"github.com/copernet/copernicus/model/txout"
 	"github.com/copernet/copernicus/util"
 	"github.com/copernet/copernicus/util/amount"
-	"math"
-	"testing"
 )
 
 type TestMemPoolEntry struct {
@@ -75,6 +81,7 @@ func (t *TestMemPoolEntry) FromTxToEntry(transaction *tx.Tx) *mempool.TxEntry {
 
 func TestMain(t *testing.M) {
 	conf.Cfg = conf.InitConfig([]string{})
+	os.Exit(t.Run())
 }
 
 func createTx() []*mempool.TxEntry {
@@ -194,3 +201,38 @@ func TestCreateNewBlockByFeeRate(t *testing.T) {
 	//	t.Error("error sort by tx feerate")
 	//}
 }
+
+func TestIncrementExtraNonce(t *testing.T) {
+	mempool.InitMempool()
+	chain.InitGlobalChain()
+
+	ba := NewBlockAssembler(model.ActiveNetParams)
+	bt := ba.CreateNewBlock(script.NewEmptyScript())
+	indexPrev := blockindex.NewBlockIndex(block.NewBlockHeader())
+	indexPrev.Height = 99
+
+	first := IncrementExtraNonce(bt.Block, indexPrev)
+	firstHash := bt.Block.Txs[0].GetHash()
+	second := IncrementExtraNonce(bt.Block, indexPrev)
+	if second != first+1 {
+		t.Errorf("got extra nonce %d after %d", second, first)
+	}
+	if bt.Block.Txs[0].GetHash() == firstHash {
+		t.Error("the coinbase did not change with the extra nonce")
+	}
+	if bt.Block.Header.MerkleRoot != lmerkleroot.BlockMerkleRoot(bt.Block.Txs, nil) {
+		t.Error("the merkle root was not updated")
+	}
+
+	height := script.NewEmptyScript()
+	height.PushInt64(int64(indexPrev.Height + 1))
+	scriptSig := bt.Block.Txs[0].GetIns()[0].GetScriptSig().GetData()
+	if !bytes.HasPrefix(scriptSig, height.GetData()) {
+		t.Errorf("coinbase script %x does not start with the height", scriptSig)
+	}
+
+	bt.Block.Header.HashPrevBlock = util.HashOne
+	if nonce := IncrementExtraNonce(bt.Block, indexPrev); nonce != 1 {
+		t.Errorf("got extra nonce %d on a new parent, want 1", nonce)
+	}
+}
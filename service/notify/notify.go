@@ -0,0 +1,106 @@
+// Package notify runs the commands configured by the operator on the events
+// of the node, the way bitcoind's -blocknotify and -alertnotify options do:
+// %s in a command is replaced by the hash of the new best block, or by the
+// message of the warning quoted for the shell.
+package notify
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/copernet/copernicus/log"
+	"github.com/copernet/copernicus/logic/lchain"
+	"github.com/copernet/copernicus/model/chain"
+)
+
+// notificationBufferSize is the number of tip updates queued for the
+// notifier.
+const notificationBufferSize = 100
+
+// safeChars are the characters kept in the messages substituted in the alert
+// command, the others being removed so that the message can't escape its
+// quotes.
+const safeChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 .,;-_/:?@()"
+
+// Notifier runs the block and alert commands on the tip updates of a chain.
+type Notifier struct {
+	blockNotify string
+	alertNotify string
+	sub         *chain.Subscription
+	// warned is set once the alert command ran: it runs once per process.
+	warned bool
+	wg     sync.WaitGroup
+}
+
+// NewNotifier runs blockNotify whenever the tip of c changes outside of the
+// initial block download, and alertNotify the first time a warning is raised,
+// until Stop.  An empty command is not run.
+func NewNotifier(c *chain.Chain, blockNotify, alertNotify string) *Notifier {
+	n := &Notifier{
+		blockNotify: blockNotify,
+		alertNotify: alertNotify,
+	}
+	n.sub = c.SubscribeChan(notificationBufferSize, chain.DropNewest, chain.NTChainTipUpdated)
+	n.wg.Add(1)
+	go n.notificationHandler()
+	return n
+}
+
+// Stop stops running the commands.  The commands already started are left to
+// complete on their own.
+func (n *Notifier) Stop() {
+	n.sub.Close()
+	n.wg.Wait()
+}
+
+func (n *Notifier) notificationHandler() {
+	defer n.wg.Done()
+	for notification := range n.sub.Notifications() {
+		event, ok := notification.Data.(*chain.TipUpdatedEvent)
+		if !ok || event.TipIndex == nil || event.IsInitialDownload {
+			continue
+		}
+		if n.blockNotify != "" {
+			go runCommand(blockCommand(n.blockNotify, event.TipIndex.GetBlockHash().String()))
+		}
+		if n.alertNotify != "" && !n.warned {
+			if warning := lchain.GetWarnings(); warning != "" {
+				n.warned = true
+				go runCommand(alertCommand(n.alertNotify, warning))
+			}
+		}
+	}
+}
+
+// blockCommand returns the block command for the block hash.
+func blockCommand(command, hash string) string {
+	return strings.Replace(command, "%s", hash, -1)
+}
+
+// alertCommand returns the alert command for the message, which is stripped
+// of the characters which aren't safe in the shell and single quoted.
+func alertCommand(command, message string) string {
+	safe := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(safeChars, r) {
+			return r
+		}
+		return -1
+	}, message)
+	return strings.Replace(command, "%s", "'"+safe+"'", -1)
+}
+
+// runCommand runs command in the shell of the system and logs its failure.
+func runCommand(command string) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("/bin/sh", "-c", command)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		log.Error("runCommand error: %s returned %v: %s", command, err,
+			strings.TrimSpace(string(output)))
+	}
+}
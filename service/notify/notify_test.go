@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestBlockCommand(t *testing.T) {
+	got := blockCommand("echo %s >> blocks; echo %s", "00ab")
+	if want := "echo 00ab >> blocks; echo 00ab"; got != want {
+		t.Errorf("blockCommand = %q, want %q", got, want)
+	}
+}
+
+func TestAlertCommand(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"Warning: Unknown block versions being mined!", "echo 'Warning: Unknown block versions being mined'"},
+		{"it's `rm -rf /` $(time)", "echo 'its rm -rf / (time)'"},
+	}
+	for _, test := range tests {
+		if got := alertCommand("echo %s", test.message); got != test.want {
+			t.Errorf("alertCommand(%q) = %q, want %q", test.message, got, test.want)
+		}
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the command uses the unix shell")
+	}
+	dir, err := ioutil.TempDir("", "notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "alert")
+	runCommand(alertCommand("echo %s > "+path, "a `b`"))
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("the command didn't run: %v", err)
+	}
+	if string(content) != "a b\n" {
+		t.Errorf("unexpected output %q", content)
+	}
+
+	// a failing command is only logged
+	runCommand("exit 3")
+}
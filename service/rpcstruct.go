@@ -38,10 +38,10 @@ type GetNetTotalsRequest struct{}
 // *btcjson.SetBanCmd
 // return error if encountering any error
 
-// ListBannedRequest returns btcjson.ListBannedResult and any error
+// ListBannedRequest returns []btcjson.BannedINfo and any error
 type ListBannedRequest struct{}
 
-// ClearBannedRequest returns boolean
+// ClearBannedRequest returns nothing
 
 type ClearBannedRequest struct{}
 
@@ -13,12 +13,17 @@ import (
 func handleRejectedTx(transaction *tx.Tx, err error,
 	nodeID int64) (lostTx []util.Hash) {
 
+	// the transaction was not checked in time, it isn't known to be invalid
+	if errcode.IsTxCheckTimeout(err) {
+		return
+	}
+
 	pool := mempool.GetInstance()
 	if errcode.IsErrorCode(err, errcode.TxErrNoPreviousOut) {
 		fRejectedParents := false
 		prevouts := transaction.GetAllPreviousOut()
 		for _, preOut := range prevouts {
-			if _, ok := pool.RecentRejects[preOut.Hash]; ok {
+			if lmempool.FindRejectTxInMempool(preOut.Hash) {
 				fRejectedParents = true
 				break
 			}
@@ -28,15 +33,18 @@ func handleRejectedTx(transaction *tx.Tx, err error,
 				lostTx = append(lostTx, preOut.Hash)
 			}
 			pool.AddOrphanTx(transaction, nodeID)
-		}
-		evicted := pool.LimitOrphanTx()
-		if evicted > 0 {
-			log.Print("service", "debug", "Orphan transaction "+
-				"overflow, removed %d tx", evicted)
+			evicted := pool.LimitOrphanTx()
+			if evicted > 0 {
+				log.Print("service", "debug", "Orphan transaction "+
+					"overflow, removed %d tx", evicted)
+			}
+			return
 		}
 	}
 
-	pool.RecentRejects[transaction.GetHash()] = struct{}{}
+	// the transaction is invalid, below the policy of this node or spends a
+	// rejected one: don't validate it again when it is re-announced
+	lmempool.AddRecentReject(transaction.GetHash())
 
 	return
 }
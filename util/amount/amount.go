@@ -6,8 +6,11 @@ package amount
 
 import (
 	"errors"
+	"fmt"
 	"math"
+	"math/big"
 	"strconv"
+	"strings"
 
 	"github.com/copernet/copernicus/util"
 )
@@ -128,3 +131,45 @@ func (a Amount) MulF64(f float64) Amount {
 func MoneyRange(value Amount) bool {
 	return value >= 0 && value <= Amount(util.MaxMoney)
 }
+
+// bigSatoshiPerBitcoin is util.COIN as a big.Rat, used to scale
+// decimal amounts without going through floating point.
+var bigSatoshiPerBitcoin = new(big.Rat).SetInt64(util.COIN)
+
+// ParseMoney parses a decimal amount of bitcoin such as "0.29" or "1e-8" to an
+// Amount. The conversion is exact: unlike multiplying a float64 by 1e8 it never
+// loses a satoshi to rounding, and amounts with more than 8 decimals or
+// outside the money range are rejected.
+func ParseMoney(str string) (Amount, error) {
+	str = strings.TrimSpace(str)
+	if str == "" || strings.ContainsAny(str, "/xXpP_") {
+		return 0, errors.New("invalid bitcoin amount")
+	}
+
+	r, ok := new(big.Rat).SetString(str)
+	if !ok {
+		return 0, errors.New("invalid bitcoin amount")
+	}
+	r.Mul(r, bigSatoshiPerBitcoin)
+	if !r.IsInt() {
+		return 0, errors.New("invalid bitcoin amount, too many decimals")
+	}
+	satoshi := r.Num()
+	if !satoshi.IsInt64() || !MoneyRange(Amount(satoshi.Int64())) {
+		return 0, errors.New("bitcoin amount out of range")
+	}
+
+	return Amount(satoshi.Int64()), nil
+}
+
+// FormatMoney formats an Amount as a decimal amount of bitcoin with exactly 8
+// decimals, the inverse of ParseMoney.
+func FormatMoney(a Amount) string {
+	sign := ""
+	abs := int64(a)
+	if abs < 0 {
+		sign = "-"
+		abs = -abs
+	}
+	return fmt.Sprintf("%s%d.%08d", sign, abs/util.COIN, abs%util.COIN)
+}
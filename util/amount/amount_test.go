@@ -307,3 +307,64 @@ func TestAmountMulF64(t *testing.T) {
 		}
 	}
 }
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in       string
+		valid    bool
+		expected Amount
+	}{
+		{"0", true, 0},
+		{"1", true, 100000000},
+		{"0.29", true, 29000000},
+		{"0.00000001", true, 1},
+		{"1e-8", true, 1},
+		{"1.5E2", true, 15000000000},
+		{"20999999.99999999", true, 2099999999999999},
+		{"21000000", true, 2100000000000000},
+		{" 0.1 ", true, 10000000},
+		{"0.000000001", false, 0},
+		{"21000000.00000001", false, 0},
+		{"-0.1", false, 0},
+		{"", false, 0},
+		{"abc", false, 0},
+		{"1/3", false, 0},
+		{"0x10", false, 0},
+		{"1e100", false, 0},
+	}
+
+	for _, test := range tests {
+		a, err := ParseMoney(test.in)
+		if (err == nil) != test.valid {
+			t.Errorf("ParseMoney(%q): got error %v, want valid %v", test.in, err, test.valid)
+			continue
+		}
+		if a != test.expected {
+			t.Errorf("ParseMoney(%q): got %d, want %d", test.in, a, test.expected)
+		}
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		in       Amount
+		expected string
+	}{
+		{0, "0.00000000"},
+		{1, "0.00000001"},
+		{29000000, "0.29000000"},
+		{2100000000000000, "21000000.00000000"},
+		{-150000000, "-1.50000000"},
+	}
+
+	for _, test := range tests {
+		if s := FormatMoney(test.in); s != test.expected {
+			t.Errorf("FormatMoney(%d): got %s, want %s", test.in, s, test.expected)
+		}
+		if test.in >= 0 {
+			if a, err := ParseMoney(FormatMoney(test.in)); err != nil || a != test.in {
+				t.Errorf("ParseMoney(FormatMoney(%d)) got %d (%v)", test.in, a, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package bloom
+
+import (
+	"math"
+
+	"github.com/copernet/copernicus/util"
+)
+
+// maxRollingHashFuncs caps the number of hash functions of a rolling filter.
+const maxRollingHashFuncs = 50
+
+// RollingFilter is a probabilistic set of the most recently inserted keys,
+// after Bitcoin Core's CRollingBloomFilter. It always contains the last
+// nElements keys and at most 1.5 times as many, with a false positive rate
+// of fpRate.
+//
+// The filter tracks the keys in three generations of nElements/2 keys each.
+// Every position of the filter holds a two bit generation number, 0 meaning
+// empty, in a pair of 64 bit words. Starting a new generation clears the bits
+// of the oldest one.
+//
+// RollingFilter is not safe for concurrent access.
+type RollingFilter struct {
+	entriesPerGeneration  int
+	entriesThisGeneration int
+	generation            uint64
+	hashFuncs             uint32
+	tweak                 uint32
+	data                  []uint64
+}
+
+// NewRollingFilter returns a rolling filter remembering at least the last
+// nElements inserted keys with a false positive rate of fpRate.
+func NewRollingFilter(nElements int, fpRate float64) *RollingFilter {
+	logFpRate := math.Log(fpRate)
+	hashFuncs := int(math.Round(logFpRate / math.Log(0.5)))
+	if hashFuncs < 1 {
+		hashFuncs = 1
+	} else if hashFuncs > maxRollingHashFuncs {
+		hashFuncs = maxRollingHashFuncs
+	}
+
+	entriesPerGeneration := (nElements + 1) / 2
+	maxElements := float64(entriesPerGeneration * 3)
+	// the number of bits for maxElements keys with hashFuncs hash
+	// functions to reach fpRate
+	filterBits := uint64(math.Ceil(-1.0 * float64(hashFuncs) * maxElements /
+		math.Log(1.0-math.Exp(logFpRate/float64(hashFuncs)))))
+
+	f := &RollingFilter{
+		entriesPerGeneration: entriesPerGeneration,
+		hashFuncs:            uint32(hashFuncs),
+		// a pair of words for every 64 positions
+		data: make([]uint64, ((filterBits+63)/64)<<1),
+	}
+	f.Reset()
+	return f
+}
+
+// hash returns the n-th hash of key.
+func (f *RollingFilter) hash(n uint32, key []byte) uint32 {
+	return MurmurHash3(n*0xFBA4C795+f.tweak, key)
+}
+
+// position returns the index of the first word of the pair holding the
+// position selected by hash h, and the bit of the position in the words.
+func (f *RollingFilter) position(h uint32) (int, uint) {
+	// map h onto the words without a modulo
+	pos := int((uint64(h) * uint64(len(f.data))) >> 32)
+	return pos &^ 1, uint(h & 63)
+}
+
+// Add inserts key into the filter, starting a new generation if the current
+// one is full.
+func (f *RollingFilter) Add(key []byte) {
+	if f.entriesThisGeneration == f.entriesPerGeneration {
+		f.entriesThisGeneration = 0
+		f.generation++
+		if f.generation == 4 {
+			f.generation = 1
+		}
+		// clear the positions of the generation being reused
+		mask1 := -(f.generation & 1)
+		mask2 := -(f.generation >> 1)
+		for p := 0; p < len(f.data); p += 2 {
+			p1, p2 := f.data[p], f.data[p+1]
+			mask := (p1 ^ mask1) | (p2 ^ mask2)
+			f.data[p] = p1 & mask
+			f.data[p+1] = p2 & mask
+		}
+	}
+	f.entriesThisGeneration++
+
+	for n := uint32(0); n < f.hashFuncs; n++ {
+		pos, bit := f.position(f.hash(n, key))
+		f.data[pos] &^= 1 << bit
+		f.data[pos] |= (f.generation & 1) << bit
+		f.data[pos+1] &^= 1 << bit
+		f.data[pos+1] |= (f.generation >> 1) << bit
+	}
+}
+
+// AddHash inserts hash into the filter.
+func (f *RollingFilter) AddHash(hash *util.Hash) {
+	f.Add(hash[:])
+}
+
+// Contains returns whether key is probably in the filter. Keys inserted in
+// the last nElements insertions are always found.
+func (f *RollingFilter) Contains(key []byte) bool {
+	for n := uint32(0); n < f.hashFuncs; n++ {
+		pos, bit := f.position(f.hash(n, key))
+		if (f.data[pos]|f.data[pos+1])>>bit&1 == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainsHash returns whether hash is probably in the filter.
+func (f *RollingFilter) ContainsHash(hash *util.Hash) bool {
+	return f.Contains(hash[:])
+}
+
+// Reset empties the filter and picks a new tweak for its hash functions.
+func (f *RollingFilter) Reset() {
+	f.tweak = uint32(util.GetRand(math.MaxUint32))
+	f.entriesThisGeneration = 0
+	f.generation = 1
+	for i := range f.data {
+		f.data[i] = 0
+	}
+}
@@ -0,0 +1,76 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func rollingKey(i int) []byte {
+	key := make([]byte, 32)
+	binary.LittleEndian.PutUint64(key, uint64(i))
+	return key
+}
+
+func TestRollingFilter(t *testing.T) {
+	f := NewRollingFilter(100, 0.01)
+
+	// the last 100 keys are always found
+	for i := 0; i < 399; i++ {
+		f.Add(rollingKey(i))
+		for j := i - 99; j <= i; j++ {
+			if j >= 0 && !f.Contains(rollingKey(j)) {
+				t.Fatalf("key %d not found after inserting %d", j, i)
+			}
+		}
+	}
+
+	// keys older than 1.5 generations of the capacity are forgotten,
+	// up to the false positive rate
+	found := 0
+	for i := 0; i < 150; i++ {
+		if f.Contains(rollingKey(i)) {
+			found++
+		}
+	}
+	if found > 10 {
+		t.Errorf("%d of 150 old keys found, want at most 10", found)
+	}
+
+	falsePositives := 0
+	for i := 10000; i < 20000; i++ {
+		if f.Contains(rollingKey(i)) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 300 {
+		t.Errorf("%d false positives out of 10000, want about 100", falsePositives)
+	}
+
+	f.Reset()
+	for i := 300; i < 399; i++ {
+		if f.Contains(rollingKey(i)) {
+			t.Fatalf("key %d found after a reset", i)
+		}
+	}
+}
+
+func TestRollingFilterSize(t *testing.T) {
+	tests := []struct {
+		elements  int
+		fpRate    float64
+		hashFuncs uint32
+		words     int
+	}{
+		{100, 0.01, 7, 46},
+		{120000, 0.000001, 20, 161750},
+		{1, 0.5, 1, 2},
+	}
+	for _, test := range tests {
+		f := NewRollingFilter(test.elements, test.fpRate)
+		if f.hashFuncs != test.hashFuncs || len(f.data) != test.words {
+			t.Errorf("NewRollingFilter(%d, %v): got %d hash functions and %d words, "+
+				"want %d and %d", test.elements, test.fpRate, f.hashFuncs,
+				len(f.data), test.hashFuncs, test.words)
+		}
+	}
+}
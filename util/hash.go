@@ -64,11 +64,7 @@ func Sha1(buf []byte) [20]byte {
 }
 
 func (hash *Hash) String() string {
-	bytes := hash.GetCloneBytes()
-	for i := 0; i < Hash256Size/2; i++ {
-		bytes[i], bytes[Hash256Size-1-i] = bytes[Hash256Size-1-i], bytes[i]
-	}
-	return hex.EncodeToString(bytes)
+	return ReverseHex(hash[:])
 }
 
 func (hash *Hash) SerializeSize() uint32 {
@@ -0,0 +1,114 @@
+package util
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// TxID is the hash identifying a transaction. Like BlockHash it is shown to
+// users and read from them in the reverse byte order of its serialization, its
+// String, MarshalJSON and UnmarshalJSON methods take care of the conversion.
+// Converting a Hash to TxID or back does not change the bytes.
+type TxID Hash
+
+// BlockHash is the hash identifying a block, see TxID.
+type BlockHash Hash
+
+// ReverseHex returns the hex encoding of b in reverse byte order, which is
+// how hashes are displayed.
+func ReverseHex(b []byte) string {
+	reversed := make([]byte, len(b))
+	for i := range b {
+		reversed[len(b)-1-i] = b[i]
+	}
+	return hex.EncodeToString(reversed)
+}
+
+// DecodeReverseHex decodes a hash displayed in reverse byte order. Unlike
+// GetHashFromStr it only accepts exactly MaxHashStringSize hex characters.
+func DecodeReverseHex(s string) (Hash, error) {
+	var hash Hash
+	if len(s) != MaxHashStringSize {
+		return hash, fmt.Errorf("hash must be of length %d (not %d, for '%s')", MaxHashStringSize, len(s), s)
+	}
+	decoded, err := hex.DecodeString(s)
+	if err != nil {
+		return hash, fmt.Errorf("hash must be hexadecimal string (not '%s')", s)
+	}
+	for i := range decoded {
+		hash[Hash256Size-1-i] = decoded[i]
+	}
+	return hash, nil
+}
+
+func marshalHashJSON(hash *Hash) ([]byte, error) {
+	return json.Marshal(ReverseHex(hash[:]))
+}
+
+func unmarshalHashJSON(hash *Hash, data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := DecodeReverseHex(s)
+	if err != nil {
+		return err
+	}
+	*hash = decoded
+	return nil
+}
+
+// TxIDFromStr parses a transaction id in display order.
+func TxIDFromStr(s string) (TxID, error) {
+	hash, err := DecodeReverseHex(s)
+	return TxID(hash), err
+}
+
+// Hash returns the hash of the transaction id in serialization order.
+func (id TxID) Hash() Hash {
+	return Hash(id)
+}
+
+// String returns the transaction id in display order.
+func (id TxID) String() string {
+	return ReverseHex(id[:])
+}
+
+// MarshalJSON encodes the transaction id as a string in display order.
+func (id TxID) MarshalJSON() ([]byte, error) {
+	hash := Hash(id)
+	return marshalHashJSON(&hash)
+}
+
+// UnmarshalJSON decodes a transaction id string in display order.
+func (id *TxID) UnmarshalJSON(data []byte) error {
+	return unmarshalHashJSON((*Hash)(id), data)
+}
+
+// BlockHashFromStr parses a block hash in display order.
+func BlockHashFromStr(s string) (BlockHash, error) {
+	hash, err := DecodeReverseHex(s)
+	return BlockHash(hash), err
+}
+
+// Hash returns the block hash in serialization order.
+func (bh BlockHash) Hash() Hash {
+	return Hash(bh)
+}
+
+// String returns the block hash in display order.
+func (bh BlockHash) String() string {
+	return ReverseHex(bh[:])
+}
+
+// MarshalJSON encodes the block hash as a string in display order.
+func (bh BlockHash) MarshalJSON() ([]byte, error) {
+	hash := Hash(bh)
+	return marshalHashJSON(&hash)
+}
+
+// UnmarshalJSON decodes a block hash string in display order.
+func (bh *BlockHash) UnmarshalJSON(data []byte) error {
+	return unmarshalHashJSON((*Hash)(bh), data)
+}
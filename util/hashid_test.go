@@ -0,0 +1,59 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// the genesis block hash, displayed in reverse byte order
+const genesisHashStr = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+
+func TestBlockHashDisplayOrder(t *testing.T) {
+	bh, err := BlockHashFromStr(genesisHashStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bh[0] != 0x6f || bh[31] != 0x00 {
+		t.Errorf("block hash is not stored in serialization order: %x", bh[:])
+	}
+	hash := bh.Hash()
+	if hash.String() != genesisHashStr || bh.String() != genesisHashStr {
+		t.Errorf("block hash displayed as %s", bh.String())
+	}
+	// a value prints in display order too, unlike a util.Hash value
+	if s := fmt.Sprintf("%s", bh); s != genesisHashStr {
+		t.Errorf("formatted block hash %s", s)
+	}
+
+	data, err := json.Marshal(struct{ Hash BlockHash }{bh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"Hash":"`+genesisHashStr+`"}` {
+		t.Errorf("block hash marshalled as %s", data)
+	}
+	var decoded struct{ Hash BlockHash }
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Hash != bh {
+		t.Errorf("block hash unmarshalled as %s, err %v", decoded.Hash, err)
+	}
+}
+
+func TestTxIDFromStr(t *testing.T) {
+	id, err := TxIDFromStr(genesisHashStr)
+	if err != nil || id.String() != genesisHashStr {
+		t.Errorf("txid round trip gave %s, err %v", id, err)
+	}
+
+	for _, s := range []string{"", "19d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f", genesisHashStr + "00",
+		"z00000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"} {
+		if _, err := TxIDFromStr(s); err == nil {
+			t.Errorf("txid %q should be rejected", s)
+		}
+	}
+
+	var id2 TxID
+	if err := json.Unmarshal([]byte(`"abc"`), &id2); err == nil {
+		t.Errorf("short txid should not unmarshal")
+	}
+}